@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"log"
 
@@ -9,12 +10,16 @@ import (
 	"github.com/LavaJover/shvark-api-gateway/internal/delivery/http/handlers"
 	"github.com/LavaJover/shvark-api-gateway/internal/delivery/http/middleware"
 	"github.com/LavaJover/shvark-api-gateway/internal/service"
+	"github.com/LavaJover/shvark-api-gateway/internal/service/bank_catalog"
+	"github.com/LavaJover/shvark-api-gateway/internal/service/deeplink_templates"
+	"github.com/LavaJover/shvark-api-gateway/internal/service/i18n"
+	"github.com/LavaJover/shvark-api-gateway/internal/service/notifications"
+	"github.com/LavaJover/shvark-api-gateway/internal/service/sms_parser"
 	"github.com/LavaJover/shvark-api-gateway/pkg/docs"
 	"github.com/gin-gonic/gin"
 	"github.com/joho/godotenv"
 	"github.com/swaggo/files"
 	ginSwagger "github.com/swaggo/gin-swagger"
-	_ "github.com/LavaJover/shvark-api-gateway/internal/service/deeplink_templates"
 )
 
 // @title 						Shvark API Gateway
@@ -72,7 +77,17 @@ func main() {
 		log.Printf("failed to init device client: %v\n", err)
 	}
 	
-	bankingHandler, err := handlers.NewBankingHandler(ordersAddr)
+	// bank catalog shared by bank lists, deeplink templates and bank detail validation
+	bankCatalog, err := bank_catalog.Load(cfg.BankCatalog.File)
+	if err != nil {
+		log.Printf("failed to load bank catalog %s, using builtin: %v", cfg.BankCatalog.File, err)
+		bankCatalog, err = bank_catalog.Load("")
+		if err != nil {
+			log.Fatalf("failed to load builtin bank catalog: %v", err)
+		}
+	}
+
+	bankingHandler, err := handlers.NewBankingHandler(ordersAddr, bankCatalog)
 	if err != nil {
 		log.Printf("failed to init banking handler")
 	}
@@ -83,8 +98,138 @@ func main() {
 		log.Printf("failed to init wallet client")
 	}
 
-	// init deeplink service
-	deeplinkService := service.NewDeeplinkService(bankingHandler.OrderClient)
+	// init deeplink templates store with hot reload and deeplink service
+	deeplinkTemplates, err := deeplink_templates.NewStore(cfg.DeeplinkTemplates.Dir, bankCatalog)
+	if deeplinkTemplates == nil {
+		log.Fatalf("failed to init deeplink templates: %v", err)
+	}
+	if err != nil {
+		log.Printf("failed to load deeplink templates, using builtin: %v", err)
+	}
+	go deeplinkTemplates.Watch(context.Background(), cfg.DeeplinkTemplates.ReloadInterval)
+	// order-scoped tokens for deeplink pages and pay-in events stream
+	orderTokenService := service.NewOrderTokenService(cfg.OrderToken.Secret, cfg.OrderToken.TTL)
+	// message catalogs for payment pages
+	catalog := i18n.Builtin()
+	deeplinkService := service.NewDeeplinkService(
+		bankingHandler.OrderClient,
+		deeplinkTemplates,
+		orderTokenService,
+		catalog,
+		i18n.MerchantLocales{
+			Default:   cfg.Localization.DefaultLocale,
+			Merchants: cfg.Localization.MerchantLocales,
+		},
+	)
+
+	// init trader events bus shared by producers and the websocket channel
+	traderEventBus := service.NewInMemoryTraderEventBus(cfg.TraderEvents.HistorySize)
+
+	// notifications: trader events are also delivered to telegram, email and webhooks by subscription
+	var traderEventSource service.TraderEventSource = traderEventBus
+	var notifier *notifications.Notifier
+	if cfg.Notifications.Enabled {
+		notificationTemplates, err := notifications.LoadTemplates(cfg.Notifications.TemplatesDir)
+		if err != nil {
+			log.Fatalf("failed to load notification templates: %v", err)
+		}
+		notificationPreferences, err := notifications.NewFilePreferencesStore(cfg.Notifications.PreferencesFile)
+		if err != nil {
+			log.Fatalf("failed to load notification preferences: %v", err)
+		}
+		notifier = notifications.NewNotifier(
+			notificationChannels(cfg.Notifications),
+			notificationTemplates,
+			notificationPreferences,
+			notifications.NewInMemoryDeliveryHistory(cfg.Notifications.HistorySize),
+			notifications.Config{
+				Workers:     cfg.Notifications.Workers,
+				QueueSize:   cfg.Notifications.QueueSize,
+				MaxAttempts: cfg.Notifications.MaxAttempts,
+				RetryDelay:  cfg.Notifications.RetryDelay,
+				SendTimeout: cfg.Notifications.SendTimeout,
+			},
+		)
+		go notifier.Run(context.Background())
+		traderEventSource = service.NewNotifyingTraderEventSource(traderEventBus, notifier)
+
+		if cfg.Notifications.LowBalance.Enabled {
+			lowBalanceWatcher := service.NewLowBalanceWatcher(
+				traderEventSource,
+				notifier,
+				walletHandler.WalletClient,
+				cfg.Notifications.LowBalance.Threshold,
+				cfg.Notifications.LowBalance.CheckInterval,
+			)
+			go lowBalanceWatcher.Run(context.Background())
+		}
+	}
+
+	traderEventPublisher := service.NewTraderEventPublisher(traderEventSource, bankingHandler.OrderClient)
+	traderEventPoller := service.NewTraderEventPoller(
+		traderEventSource,
+		notifier,
+		bankingHandler.OrderClient,
+		deviceClient,
+		cfg.TraderEvents.PollInterval,
+		cfg.TraderEvents.ExpiringThreshold,
+	)
+	go traderEventPoller.Run(context.Background())
+
+	// dispute SLA: stage deadlines, reminders and escalation to admins
+	disputeTimeoutAction, err := service.ParseDisputeTimeoutAction(cfg.DisputeSLA.OnTraderTimeout)
+	if err != nil {
+		log.Fatalf("invalid dispute_sla.on_trader_timeout: %v", err)
+	}
+	disputeSLAStore, err := service.NewFileDisputeSLAStore(cfg.DisputeSLA.File)
+	if err != nil {
+		log.Fatalf("failed to load dispute SLA: %v", err)
+	}
+	disputeSLA := service.NewDisputeSLAService(
+		bankingHandler.OrderClient,
+		traderEventPublisher,
+		disputeSLAStore,
+		service.DisputeSLAConfig{
+			DisputeTTL:      cfg.DisputeSLA.DisputeTTL,
+			TraderResponse:  cfg.DisputeSLA.TraderResponse,
+			AdminReview:     cfg.DisputeSLA.AdminReview,
+			Frozen:          cfg.DisputeSLA.Frozen,
+			RemindBefore:    cfg.DisputeSLA.RemindBefore,
+			OnTraderTimeout: disputeTimeoutAction,
+			CheckInterval:   cfg.DisputeSLA.CheckInterval,
+			SyncInterval:    cfg.DisputeSLA.SyncInterval,
+			OpenStatuses:    cfg.DisputeSLA.OpenStatuses,
+			FrozenStatus:    cfg.DisputeSLA.FrozenStatus,
+			MaxOpen:         cfg.DisputeSLA.MaxOpen,
+		},
+	)
+	go disputeSLA.Run(context.Background())
+	traderEventsHandler := handlers.NewTraderEventsHandler(traderEventBus, cfg.TraderEvents.HeartbeatInterval, cfg.TraderEvents.AllowedOrigins)
+
+	// init merchant service shared by /merchant, /payments and /api/v2/merchant
+	merchantService := service.NewMerchantService(
+		bankingHandler.OrderClient,
+		walletHandler.WalletClient,
+		userHandler.UserClient,
+		authHandler.SSOClient,
+	)
+
+	// merchant sandbox: sbx_ keys are served by the in-memory order simulator, never by upstreams
+	var (
+		sandboxCredentials *service.SandboxCredentialService
+		sandboxBackend     *service.SandboxBackend
+		sandboxMerchants   *service.MerchantService
+	)
+	if cfg.Sandbox.Enabled {
+		sandboxCredentialStore, err := service.NewFileSandboxCredentialStore(cfg.Sandbox.CredentialsFile)
+		if err != nil {
+			log.Fatalf("failed to load sandbox credentials: %v", err)
+		}
+		sandboxCredentials = service.NewSandboxCredentialService(sandboxCredentialStore)
+		sandboxBackend = service.NewSandboxBackend(sandboxConfig(cfg.Sandbox))
+		sandboxMerchants = service.NewMerchantService(sandboxBackend, sandboxBackend, sandboxBackend, nil)
+	}
+	merchantAuth := middleware.MerchantAuthMiddleware(authHandler.SSOClient, sandboxCredentials)
 
 	// init payments handlet
 	paymentHandler, err := handlers.NewPaymentHandler(
@@ -93,11 +238,33 @@ func main() {
 		userHandler.UserClient,
 		authHandler.SSOClient,
 		deeplinkService,
+		merchantService,
+		sandboxMerchants,
+		traderEventPublisher,
+		disputeSLA,
 	)
 	if err != nil {
 		log.Printf("failed to init payment handler")
 	}
 
+	// init pay-in events stream
+	orderWatcher := service.NewOrderWatcher(bankingHandler.OrderClient, cfg.OrderEvents.PollInterval)
+	orderEventsHandler := handlers.NewOrderEventsHandler(merchantService, orderWatcher, orderTokenService, cfg.OrderEvents.HeartbeatInterval)
+
+	// audit log of administrative and financial actions, attached per route group below
+	var auditLog *service.AuditLog
+	if cfg.Audit.Enabled {
+		auditStore, err := service.NewFileAuditStore(cfg.Audit.File, cfg.Audit.MaxEntries)
+		if err != nil {
+			log.Fatalf("failed to load audit log: %v", err)
+		}
+		auditLog = service.NewAuditLog(auditStore, service.NewUserServiceRoles(userHandler.UserClient), service.AuditConfig{
+			MaxBodyBytes: cfg.Audit.MaxBodyBytes,
+			RoleCacheTTL: cfg.Audit.RoleCacheTTL,
+		})
+		service.RegisterAuditSnapshots(auditLog, bankingHandler.OrderClient, walletHandler.WalletClient)
+	}
+
 	r := gin.Default()
 
 	// use middleware
@@ -124,7 +291,7 @@ func main() {
 	r.GET("/api/v1/users/:id", userHandler.GetUserByID)
 
 	// RBAC-service
-	rbacGroup := r.Group("/api/v1/rbac", middleware.AuthMiddleware(authHandler.SSOClient))
+	rbacGroup := r.Group("/api/v1/rbac", middleware.AuditMiddleware(auditLog, "rbac"), middleware.AuthMiddleware(authHandler.SSOClient))
 	{
 		rbacGroup.POST("/roles", authzHandler.AssignRole)
 		rbacGroup.DELETE("/roles", authzHandler.RevokeRole)
@@ -134,7 +301,7 @@ func main() {
 	}
 
 	// banking-service
-	bankingGroup := r.Group("/api/v1/banking", middleware.AuthMiddleware(authHandler.SSOClient))
+	bankingGroup := r.Group("/api/v1/banking", middleware.AuditMiddleware(auditLog, "banking"), middleware.AuthMiddleware(authHandler.SSOClient))
 	{
 		bankingGroup.POST("/details", bankingHandler.CreateBankDetail)
 		bankingGroup.POST("/details/delete", bankingHandler.DeleteBankDetail)
@@ -146,7 +313,7 @@ func main() {
 	}
 
 	// orders-service
-	orderGroup := r.Group("/api/v1/orders", middleware.AuthMiddleware(authHandler.SSOClient))
+	orderGroup := r.Group("/api/v1/orders", middleware.AuditMiddleware(auditLog, "orders"), middleware.AuthMiddleware(authHandler.SSOClient))
 	{
 		orderGroup.POST("/", ordersHandler.CreateOrder)
 		orderGroup.GET("/:uuid", ordersHandler.GetOrderByID)
@@ -159,7 +326,7 @@ func main() {
 	}
 
 	// wallet-service
-	walletGroup := r.Group("/api/v1/wallets", middleware.AuthMiddleware(authHandler.SSOClient))
+	walletGroup := r.Group("/api/v1/wallets", middleware.AuditMiddleware(auditLog, "wallet"), middleware.AuthMiddleware(authHandler.SSOClient))
 	{
 		walletGroup.POST("/create", walletHandler.CreateWallet)
 		walletGroup.POST("/freeze", walletHandler.Freeze)
@@ -176,34 +343,76 @@ func main() {
 	// payments for merchant
 	paymentsGroup := r.Group("/api/v1/payments")
 	{
-		paymentsGroup.POST("/in/h2h", middleware.AuthMiddleware(authHandler.SSOClient), paymentHandler.CreateH2HPayIn)
-		paymentsGroup.GET("/in/h2h/:id", middleware.AuthMiddleware(authHandler.SSOClient), paymentHandler.GetH2HPayInInfo)
+		paymentsGroup.POST("/in/h2h", merchantAuth, paymentHandler.CreateH2HPayIn)
+		paymentsGroup.GET("/in/h2h/:id", merchantAuth, paymentHandler.GetH2HPayInInfo)
+		paymentsGroup.GET("/in/h2h/:id/events", middleware.OrderTokenMiddleware(orderTokenService, service.OrderTokenScopeEvents, "id"), orderEventsHandler.StreamPayInEvents)
+		paymentsGroup.POST("/in/h2h/:id/events/token", middleware.AuthMiddleware(authHandler.SSOClient), orderEventsHandler.IssueEventsToken)
+		paymentsGroup.GET("/in/h2h/:id/deeplinks", middleware.AuthMiddleware(authHandler.SSOClient), paymentHandler.GetPayInDeeplinks)
+		paymentsGroup.POST("/deeplink/redirect", middleware.AuthMiddleware(authHandler.SSOClient), paymentHandler.RedirectToDeeplink)
 		paymentsGroup.POST("/in/h2h/:id/cancel", middleware.AuthMiddleware(authHandler.SSOClient), paymentHandler.CancelPayIn)
 		paymentsGroup.POST("/in/h2h/:id/arbitrage/link", middleware.AuthMiddleware(authHandler.SSOClient), paymentHandler.OpenPayInArbitrageLink)
 		paymentsGroup.GET("/in/h2h/:id/arbitrage/info", middleware.AuthMiddleware(authHandler.SSOClient), paymentHandler.GetPayInArbitrageInfo)
-		paymentsGroup.GET("/accounts/balance", middleware.AuthMiddleware(authHandler.SSOClient), paymentHandler.GetAccountBalance)
-		paymentsGroup.GET("/order/:orderId/status", middleware.AuthMiddleware(authHandler.SSOClient), paymentHandler.GetOrderStatus)
-		paymentsGroup.GET("/order", middleware.AuthMiddleware(authHandler.SSOClient), paymentHandler.GetOrders)
-		paymentsGroup.POST("/accounts/withdraw/create", middleware.AuthMiddleware(authHandler.SSOClient), paymentHandler.Withdraw)
+		paymentsGroup.GET("/accounts/balance", merchantAuth, paymentHandler.GetAccountBalance)
+		paymentsGroup.GET("/order/:orderId/status", merchantAuth, paymentHandler.GetOrderStatus)
+		paymentsGroup.GET("/order", merchantAuth, paymentHandler.GetOrders)
+		paymentsGroup.POST("/accounts/withdraw/create", merchantAuth, paymentHandler.Withdraw)
 		paymentsGroup.POST("/accounts/auth/sign-in", paymentHandler.Login)
 		paymentsGroup.POST("/out/h2h/", middleware.AuthMiddleware(authHandler.SSOClient), paymentHandler.CreateH2HPayOut)
 	}
 
 	// Публичные роуты для диплинков
-	r.GET("/api/v1/payments/deeplink/select", paymentHandler.GetBankSelectionPage)
-	r.GET("/api/v1/payments/deeplink/specific", paymentHandler.GetSpecificDeeplink)
+	deeplinkTokenMiddleware := middleware.OrderTokenQueryMiddleware(orderTokenService, service.OrderTokenScopeDeeplink, "order_id")
+	r.GET("/api/v1/payments/deeplink/select", deeplinkTokenMiddleware, paymentHandler.GetBankSelectionPage)
+	r.GET("/api/v1/payments/deeplink/specific", deeplinkTokenMiddleware, paymentHandler.GetSpecificDeeplink)
+	r.GET("/api/v1/payments/deeplink/requisites", deeplinkTokenMiddleware, paymentHandler.GetDeeplinkRequisites)
+
+	// deeplink funnel analytics
+	deeplinkAnalytics := service.NewDeeplinkAnalytics(
+		service.NewInMemoryDeeplinkEventStore(cfg.DeeplinkAnalytics.Retention, cfg.DeeplinkAnalytics.MaxEvents),
+	)
+	deeplinkAnalyticsHandler := handlers.NewDeeplinkAnalyticsHandler(deeplinkAnalytics)
+	r.POST("/api/v1/payments/deeplink/events", deeplinkTokenMiddleware, deeplinkAnalyticsHandler.Track)
 
 	walletAddr := fmt.Sprintf("%s:%s", cfg.WalletService.Host, cfg.WalletService.Port)
 	walletClient := client.NewHTTPWalletClient(walletAddr)
 
+	// onboarding saga for teams and merchants: every step is persisted, stuck ones are resumed by admins
+	onboardingStore, err := service.NewFileOnboardingStore(cfg.Onboarding.File)
+	if err != nil {
+		log.Fatalf("failed to load onboardings: %v", err)
+	}
+	onboardingService := service.NewOnboardingService(
+		authHandler.SSOClient,
+		userHandler.UserClient,
+		authzHandler.AuthzClient,
+		walletClient,
+		ordersHandler.OrderClient,
+		onboardingStore,
+		service.OnboardingConfig{
+			MaxAttempts:  cfg.Onboarding.MaxAttempts,
+			RetryDelay:   cfg.Onboarding.RetryDelay,
+			StuckAfter:   cfg.Onboarding.StuckAfter,
+			TraderRole:   cfg.Onboarding.TraderRole,
+			MerchantRole: cfg.Onboarding.MerchantRole,
+		},
+	)
 	adminHandler := handlers.NewAdminHandler(
 		authHandler.SSOClient,
 		authzHandler.AuthzClient,
 		ordersHandler.OrderClient,
 		walletClient,
 		userHandler.UserClient,
+		traderEventPublisher,
+		onboardingService,
+		disputeSLA,
+	)
+	adminGroup := r.Group(
+		"/api/v1/admin",
+		middleware.AuditMiddleware(auditLog, "admin"),
+		middleware.AuthMiddleware(authHandler.SSOClient),
+		middleware.RequirePermission(authzHandler.AuthzClient, "admin", "manage"),
+		middleware.AuditSnapshot(auditLog),
 	)
-	adminGroup := r.Group("/api/v1/admin")
 	{
 		adminGroup.POST("/teams/create", adminHandler.CreateTeam)
 		adminGroup.POST("/merchants/create", adminHandler.CreateMerchant)
@@ -224,28 +433,284 @@ func main() {
 		adminGroup.DELETE("/wallets/withdraw/rules/:userId", adminHandler.DeleteUserWithdrawalRules)
 		adminGroup.POST("/teams/relations/create", adminHandler.CreateTeamRelation)
 		adminGroup.PATCH("/teams/relations/update", adminHandler.UpdateRelationParams)
-		adminGroup.GET("/teams/relations/team-lead/:teamLeadID", adminHandler.GetRelationsByTeamLeadID)
 		adminGroup.DELETE("/teams/relations/:relationID/delete", adminHandler.DeleteTeamRelationship)
 		adminGroup.POST("/teams/traders/:traderID/promote-to-teamlead", adminHandler.PromoteToTeamLead)
 		adminGroup.POST("/teams/teamleads/:teamleadID/demote", adminHandler.DemoteTeamLead)
 		adminGroup.GET("/users", adminHandler.GetUsersByRole)
 		adminGroup.GET("/orders/statistics", adminHandler.GetTraderOrderStats)
 	}
+	// тимлид читает свои связи сам, остальным нужно право admin/manage
+	r.GET("/api/v1/admin/teams/relations/team-lead/:teamLeadID",
+		middleware.AuthMiddleware(authHandler.SSOClient),
+		middleware.RequireSelfOr(authzHandler.AuthzClient, "teamLeadID", "admin", "manage"),
+		adminHandler.GetRelationsByTeamLeadID,
+	)
+
+	onboardingHandler := handlers.NewOnboardingHandler(onboardingService)
+	onboardingGroup := r.Group(
+		"/api/v1/admin/onboardings",
+		middleware.AuditMiddleware(auditLog, "admin"),
+		middleware.AuthMiddleware(authHandler.SSOClient),
+		middleware.RequirePermission(authzHandler.AuthzClient, "onboardings", "manage"),
+		middleware.AuditSnapshot(auditLog),
+	)
+	{
+		onboardingGroup.GET("", onboardingHandler.GetOnboardings)
+		onboardingGroup.GET("/:id", onboardingHandler.GetOnboarding)
+		onboardingGroup.POST("/:id/resume", onboardingHandler.ResumeOnboarding)
+		onboardingGroup.POST("/:id/abort", onboardingHandler.AbortOnboarding)
+	}
+
+	// heartbeat watchdog: locks traffic of automatic devices that stopped pinging
+	automaticLockAudit, err := service.NewFileAutomaticLockAudit(cfg.DeviceWatchdog.AuditFile)
+	if err != nil {
+		log.Fatalf("failed to load automatic lock audit: %v", err)
+	}
+	var deviceWatchdog *service.DeviceWatchdog
+	if cfg.DeviceWatchdog.Enabled {
+		watchdogMode, err := service.ParseAutomaticLockMode(cfg.DeviceWatchdog.Mode)
+		if err != nil {
+			log.Fatalf("invalid device_watchdog.mode: %v", err)
+		}
+		deviceWatchdog = service.NewDeviceWatchdog(
+			ordersHandler.OrderClient,
+			traderEventPublisher,
+			automaticLockAudit,
+			watchdogMode,
+			cfg.DeviceWatchdog.Threshold,
+			cfg.DeviceWatchdog.CheckInterval,
+		)
+		go deviceWatchdog.Run(context.Background())
+	}
+
+	// bulk admin operations: traffic for trader x merchant pairs, team traffic locks, withdrawal rules
+	bulkAdminHandler := handlers.NewBulkAdminHandler(service.NewBulkAdminService(
+		ordersHandler.OrderClient,
+		walletClient,
+		traderEventPublisher,
+		deviceWatchdog,
+		service.BulkAdminConfig{
+			Concurrency: cfg.BulkAdmin.Concurrency,
+			MaxItems:    cfg.BulkAdmin.MaxItems,
+		},
+	))
+	bulkAdminGroup := r.Group(
+		"/api/v1/admin/bulk",
+		middleware.AuditMiddleware(auditLog, "admin"),
+		middleware.AuthMiddleware(authHandler.SSOClient),
+		middleware.RequirePermission(authzHandler.AuthzClient, "bulk_admin", "manage"),
+		middleware.AuditSnapshot(auditLog),
+	)
+	{
+		bulkAdminGroup.POST("/traffic", bulkAdminHandler.UpsertTraffic)
+		bulkAdminGroup.POST("/traffic/team-lock", bulkAdminHandler.SetTeamTrafficLock)
+		bulkAdminGroup.POST("/withdrawal-rules", bulkAdminHandler.SetWithdrawalRules)
+	}
+
+	// team lead dashboard: orders, bank details, devices and commission profit of every trader in the team
+	teamLeadHandler := handlers.NewTeamLeadHandler(
+		service.NewTeamStatsService(
+			ordersHandler.OrderClient,
+			deviceClient,
+			walletClient,
+			userHandler.UserClient,
+			cfg.TeamStats.Concurrency,
+			cfg.TeamStats.CacheTTL,
+		),
+		authzHandler.AuthzClient,
+		cfg.TeamStats.MaxRange,
+	)
+	r.GET("/api/v1/team-lead/stats", middleware.AuthMiddleware(authHandler.SSOClient), teamLeadHandler.GetStats)
+
+	disputeSLAHandler := handlers.NewDisputeSLAHandler(disputeSLA)
+	r.GET(
+		"/api/v1/admin/disputes/queue",
+		middleware.AuthMiddleware(authHandler.SSOClient),
+		middleware.RequirePermission(authzHandler.AuthzClient, "disputes", "read"),
+		disputeSLAHandler.GetQueue,
+	)
+
+	// routing simulator: why a merchant order finds no bank details, nothing is changed
+	routingSimulatorHandler := handlers.NewRoutingSimulatorHandler(service.NewRoutingSimulatorService(
+		ordersHandler.OrderClient,
+		deviceClient,
+		service.RoutingSimulatorConfig{
+			Concurrency:         cfg.RoutingSimulator.Concurrency,
+			TrafficPageSize:     int32(cfg.RoutingSimulator.TrafficPageSize),
+			RequireOnlineDevice: cfg.RoutingSimulator.RequireOnlineDevice,
+		},
+	))
+	r.POST(
+		"/api/v1/admin/traffic/simulate",
+		middleware.AuthMiddleware(authHandler.SSOClient),
+		middleware.RequirePermission(authzHandler.AuthzClient, "traffic_simulator", "read"),
+		routingSimulatorHandler.Simulate,
+	)
+
+	if auditLog != nil {
+		auditHandler := handlers.NewAuditHandler(auditLog, cfg.Audit.ExportLimit)
+		auditGroup := r.Group(
+			"/api/v1/admin/audit",
+			middleware.AuthMiddleware(authHandler.SSOClient),
+			middleware.RequirePermission(authzHandler.AuthzClient, "audit", "read"),
+		)
+		{
+			auditGroup.GET("", auditHandler.GetEntries)
+			auditGroup.GET("/export", auditHandler.Export)
+		}
+	}
+
+	// dispute evidence: receipts and screenshots, downloads by signed expiring links
+	evidenceStorage, err := service.NewLocalEvidenceStorage(cfg.Evidence.Dir)
+	if err != nil {
+		log.Fatalf("failed to init evidence storage: %v", err)
+	}
+	evidenceStore, err := service.NewFileEvidenceStore(cfg.Evidence.MetadataFile)
+	if err != nil {
+		log.Fatalf("failed to load evidence metadata: %v", err)
+	}
+	evidenceHandler := handlers.NewEvidenceHandler(service.NewEvidenceService(
+		ordersHandler.OrderClient,
+		evidenceStorage,
+		evidenceStore,
+		orderTokenService,
+		disputeSLA,
+		service.EvidenceConfig{
+			MaxFileSize:  cfg.Evidence.MaxFileSize,
+			AllowedTypes: cfg.Evidence.AllowedTypes,
+			URLTTL:       cfg.Evidence.URLTTL,
+			DownloadPath: "/api/v1/evidence/files",
+		},
+	), authzHandler.AuthzClient)
+	r.GET("/api/v1/evidence/files/:id", evidenceHandler.Download)
+	evidenceGroup := r.Group("/api/v1/evidence", middleware.AuditMiddleware(auditLog, "disputes"), middleware.AuthMiddleware(authHandler.SSOClient))
+	{
+		evidenceGroup.POST("", evidenceHandler.Upload)
+		evidenceGroup.GET("", evidenceHandler.List)
+		evidenceGroup.GET("/:id", evidenceHandler.Get)
+	}
+
+	deeplinkTemplateHandler := handlers.NewDeeplinkTemplateHandler(deeplinkTemplates)
+	deeplinkTemplatesGroup := r.Group(
+		"/api/v1/admin/deeplink-templates",
+		middleware.AuditMiddleware(auditLog, "admin"),
+		middleware.AuthMiddleware(authHandler.SSOClient),
+		middleware.RequirePermission(authzHandler.AuthzClient, "deeplink_templates", "manage"),
+		middleware.AuditSnapshot(auditLog),
+	)
+	{
+		deeplinkTemplatesGroup.GET("", deeplinkTemplateHandler.GetTemplates)
+		deeplinkTemplatesGroup.POST("", deeplinkTemplateHandler.CreateVersion)
+		deeplinkTemplatesGroup.POST("/validate", deeplinkTemplateHandler.Validate)
+		deeplinkTemplatesGroup.POST("/preview", deeplinkTemplateHandler.Preview)
+		deeplinkTemplatesGroup.POST("/reload", deeplinkTemplateHandler.Reload)
+		deeplinkTemplatesGroup.GET("/:bankCode/versions/:version", deeplinkTemplateHandler.GetVersion)
+		deeplinkTemplatesGroup.DELETE("/:bankCode/versions/:version", deeplinkTemplateHandler.DeleteVersion)
+		deeplinkTemplatesGroup.GET("/:bankCode/versions/:version/preview", deeplinkTemplateHandler.PreviewVersion)
+		deeplinkTemplatesGroup.POST("/:bankCode/versions/:version/activate", deeplinkTemplateHandler.Activate)
+	}
+
+	i18nHandler := handlers.NewI18nHandler(catalog)
+	r.GET(
+		"/api/v1/admin/i18n/missing",
+		middleware.AuthMiddleware(authHandler.SSOClient),
+		middleware.RequirePermission(authzHandler.AuthzClient, "i18n", "read"),
+		i18nHandler.GetMissing,
+	)
+
+	r.GET(
+		"/api/v1/admin/deeplink-analytics/stats",
+		middleware.AuthMiddleware(authHandler.SSOClient),
+		middleware.RequirePermission(authzHandler.AuthzClient, "deeplink_analytics", "read"),
+		deeplinkAnalyticsHandler.GetStats,
+	)
+
+	merchantHandler := handlers.NewMerchanHandler(merchantService, sandboxMerchants, bankCatalog)
+	bankCatalogHandler := handlers.NewBankCatalogHandler(bankCatalog)
+	r.GET("/api/v1/banks", bankCatalogHandler.GetBanks)
+	r.GET("/api/v1/banks/:code", bankCatalogHandler.GetBank)
 
-	merchantHandler := handlers.NewMerchanHandler(ordersHandler.OrderClient, walletClient, userHandler.UserClient, authHandler.SSOClient)
 	merchantGroup := r.Group("/api/v1/merchant")
 	{
-		merchantGroup.POST("/order/:accountID/deposit", middleware.AuthMiddleware(authHandler.SSOClient), merchantHandler.CreatePayIn)
-		merchantGroup.GET("/accounts/balance", middleware.AuthMiddleware(authHandler.SSOClient), merchantHandler.GetAccountBalance)
-		merchantGroup.POST("/accounts/withdraw/create", middleware.AuthMiddleware(authHandler.SSOClient), merchantHandler.Withdraw)
+		merchantGroup.POST("/order/:accountID/deposit", merchantAuth, merchantHandler.CreatePayIn)
+		merchantGroup.GET("/accounts/balance", merchantAuth, merchantHandler.GetAccountBalance)
+		merchantGroup.POST("/accounts/withdraw/create", merchantAuth, merchantHandler.Withdraw)
 		merchantGroup.GET("/banks", merchantHandler.GetBanks)
-		merchantGroup.GET("/order/:iternalId/status", middleware.AuthMiddleware(authHandler.SSOClient), merchantHandler.GetOrderStatus)
+		merchantGroup.GET("/order/:iternalId/status", merchantAuth, merchantHandler.GetOrderStatus)
 		merchantGroup.POST("/auth/sign-in", merchantHandler.Login)
-		merchantGroup.GET("/order", middleware.AuthMiddleware(authHandler.SSOClient), merchantHandler.GetOrders)
+		merchantGroup.GET("/order", merchantAuth, merchantHandler.GetOrders)
 	}
 
+	if cfg.Sandbox.Enabled {
+		sandboxHandler := handlers.NewSandboxHandler(sandboxCredentials, sandboxBackend)
+		sandboxGroup := r.Group("/api/v1/sandbox")
+		{
+			// ключи выпускаются по боевому токену мерчанта, статусы меняются по ключу песочницы
+			sandboxGroup.POST("/credentials", middleware.AuthMiddleware(authHandler.SSOClient), sandboxHandler.IssueCredential)
+			sandboxGroup.GET("/credentials", middleware.AuthMiddleware(authHandler.SSOClient), sandboxHandler.GetCredentials)
+			sandboxGroup.DELETE("/credentials/:id", middleware.AuthMiddleware(authHandler.SSOClient), sandboxHandler.RevokeCredential)
+			sandboxGroup.POST("/orders/:id/paid", middleware.SandboxAuthMiddleware(sandboxCredentials), sandboxHandler.MarkPaid)
+			sandboxGroup.POST("/orders/:id/expire", middleware.SandboxAuthMiddleware(sandboxCredentials), sandboxHandler.Expire)
+			sandboxGroup.POST("/orders/:id/dispute", middleware.SandboxAuthMiddleware(sandboxCredentials), sandboxHandler.OpenDispute)
+		}
+	}
+
+	// unified merchant API
+	merchantV2Handler := handlers.NewMerchantV2Handler(merchantService)
+	r.POST("/api/v2/merchant/auth/sign-in", merchantV2Handler.SignIn)
+	r.GET("/api/v2/merchant/banks", merchantHandler.GetBanks)
+	merchantV2Group := r.Group("/api/v2/merchant", middleware.AuthMiddleware(authHandler.SSOClient))
+	{
+		merchantV2Group.POST("/orders", merchantV2Handler.CreatePayIn)
+		merchantV2Group.GET("/orders", merchantV2Handler.GetOrders)
+		merchantV2Group.GET("/orders/:orderId", merchantV2Handler.GetOrder)
+		merchantV2Group.GET("/orders/:orderId/status", merchantV2Handler.GetOrderStatus)
+		merchantV2Group.GET("/merchant-orders/:merchantOrderId/status", merchantV2Handler.GetOrderStatusByMerchantOrderID)
+		merchantV2Group.GET("/accounts/balance", merchantV2Handler.GetAccountBalance)
+		merchantV2Group.POST("/accounts/withdraw", merchantV2Handler.Withdraw)
+	}
+
+	if notifier != nil {
+		notificationHandler := handlers.NewNotificationHandler(notifier)
+		notificationsGroup := r.Group("/api/v1/notifications", middleware.AuthMiddleware(authHandler.SSOClient))
+		{
+			notificationsGroup.GET("/preferences", notificationHandler.GetPreferences)
+			notificationsGroup.PUT("/preferences", notificationHandler.UpdatePreferences)
+			notificationsGroup.GET("/history", notificationHandler.GetHistory)
+		}
+		adminNotificationsGroup := r.Group(
+			"/api/v1/admin/notifications",
+			middleware.AuditMiddleware(auditLog, "admin"),
+			middleware.AuthMiddleware(authHandler.SSOClient),
+			middleware.RequirePermission(authzHandler.AuthzClient, "notifications", "manage"),
+			middleware.AuditSnapshot(auditLog),
+		)
+		{
+			adminNotificationsGroup.PUT("/preferences/:userId", notificationHandler.AdminUpdatePreferences)
+			adminNotificationsGroup.GET("/history", notificationHandler.AdminGetHistory)
+		}
+	}
+
+	// trader dashboard push channel
+	r.GET("/api/v1/traders/events/ws", middleware.WebSocketAuthMiddleware(authHandler.SSOClient), traderEventsHandler.Stream)
+
+	// device pairing: one-time codes exchanged by the phone app for device credentials
+	deviceCredentialStore, err := service.NewFileDeviceCredentialStore(cfg.DevicePairing.CredentialsFile)
+	if err != nil {
+		log.Fatalf("failed to load device credentials: %v", err)
+	}
+	devicePairingService := service.NewDevicePairingService(ordersHandler.OrderClient, deviceCredentialStore, cfg.DevicePairing.CodeTTL)
+
+	automaticLockHandler := handlers.NewAutomaticLockHandler(automaticLockAudit)
+	r.GET(
+		"/api/v1/admin/automatic/locks",
+		middleware.AuthMiddleware(authHandler.SSOClient),
+		middleware.RequirePermission(authzHandler.AuthzClient, "automatic_locks", "read"),
+		automaticLockHandler.GetLocks,
+	)
+
 	// init device handler
-	deviceHandler, err := handlers.NewDeviceHandler(ordersHandler.OrderClient)
+	deviceHandler, err := handlers.NewDeviceHandler(ordersHandler.OrderClient, devicePairingService, deviceWatchdog)
 	if err != nil {
 		log.Printf("failed to init device handler")
 	}
@@ -255,39 +720,106 @@ func main() {
 		deviceGroup.GET("/:traderId", deviceHandler.GetTraderDevices)
 		deviceGroup.PATCH("/:deviceId/edit", deviceHandler.EditDevice)
 		deviceGroup.DELETE("/:deviceId", deviceHandler.DeleteDevice)
+		deviceGroup.POST("/:deviceId/pairing", middleware.AuthMiddleware(authHandler.SSOClient), deviceHandler.StartPairing)
+		deviceGroup.GET("/:traderId/credentials", middleware.AuthMiddleware(authHandler.SSOClient), middleware.RequireSelfOrAdmin(authzHandler.AuthzClient, "traderId"), deviceHandler.GetCredentials)
+		deviceGroup.DELETE("/:deviceId/credential", middleware.AuthMiddleware(authHandler.SSOClient), deviceHandler.RevokeCredential)
 	}
 
-	automaticHandler := handlers.NewAutomaticHandler(adminHandler.OrderClient, deviceClient)
+	// bank notification rules for cross-checking device-parsed sms
+	smsParser, err := sms_parser.Load(cfg.SmsParser.RulesDir)
+	if err != nil {
+		log.Printf("failed to load sms parser rules %s, using builtin: %v", cfg.SmsParser.RulesDir, err)
+		smsParser, err = sms_parser.Load("")
+		if err != nil {
+			log.Fatalf("failed to load builtin sms parser rules: %v", err)
+		}
+	}
+	onMismatch, err := sms_parser.ParseAction(cfg.SmsParser.OnMismatch)
+	if err != nil {
+		log.Fatalf("invalid sms_parser.on_mismatch: %v", err)
+	}
+	onUnmatched, err := sms_parser.ParseAction(cfg.SmsParser.OnUnmatched)
+	if err != nil {
+		log.Fatalf("invalid sms_parser.on_unmatched: %v", err)
+	}
+	// replay protection for incoming sms
+	smsDedup := service.NewSmsDeduplicator(
+		service.NewInMemorySmsReplayStore(),
+		cfg.SmsDedup.TTL,
+		cfg.SmsDedup.MaxAge,
+		cfg.SmsDedup.MaxOfflineAge,
+		cfg.SmsDedup.MaxClockSkew,
+	)
+	// platform-wide automatic stats, collected per trader and cached
+	automaticStats := service.NewAutomaticStatsService(
+		adminHandler.OrderClient,
+		userHandler.UserClient,
+		cfg.AutomaticStats.Concurrency,
+		cfg.AutomaticStats.CacheTTL,
+		cfg.AutomaticStats.MaxLogsPerTrader,
+		cfg.AutomaticStats.MaxDays,
+	)
+	automaticHandler := handlers.NewAutomaticHandler(adminHandler.OrderClient, deviceClient, smsParser, sms_parser.Policy{
+		OnMismatch:      onMismatch,
+		OnUnmatched:     onUnmatched,
+		AmountTolerance: cfg.SmsParser.AmountTolerance,
+	}, smsDedup, cfg.SmsBatch.MaxItems, cfg.SmsBatch.Concurrency, deviceWatchdog, automaticStats)
 	automaticGroup := r.Group("/api/v1/automatic")
 	{
-        automaticGroup.POST("/process-sms", middleware.AutomaticAuthMiddleware(adminHandler.SSOClient), automaticHandler.Sms)
-        automaticGroup.POST("/liveness", middleware.AutomaticAuthMiddleware(adminHandler.SSOClient), automaticHandler.Live)
-        automaticGroup.POST("/auth", middleware.AutomaticAuthMiddleware(adminHandler.SSOClient), automaticHandler.Auth)
+        automaticGroup.POST("/pair", deviceHandler.PairDevice)
+        automaticGroup.POST("/process-sms", middleware.AutomaticAuthMiddleware(devicePairingService), automaticHandler.Sms)
+        automaticGroup.POST("/process-sms/batch", middleware.AutomaticAuthMiddleware(devicePairingService), automaticHandler.SmsBatch)
+        automaticGroup.POST("/liveness", middleware.AutomaticAuthMiddleware(devicePairingService), automaticHandler.Live)
+        automaticGroup.POST("/auth", middleware.AutomaticAuthMiddleware(devicePairingService), automaticHandler.Auth)
         automaticGroup.GET("/logs", middleware.AuthMiddleware(adminHandler.SSOClient), automaticHandler.GetAutomaticLogs)
         automaticGroup.GET("/device-status", middleware.AuthMiddleware(adminHandler.SSOClient), automaticHandler.GetDeviceStatus)
         automaticGroup.GET("/trader-devices-status", middleware.AuthMiddleware(adminHandler.SSOClient), automaticHandler.GetTraderDevicesStatus)
 
 		// Новые endpoints для мониторинга
-		automaticGroup.GET("/stats", automaticHandler.GetAutomaticStats)
+		automaticGroup.GET(
+			"/stats",
+			middleware.AuthMiddleware(adminHandler.SSOClient),
+			// без trader_id статистика собирается по всем трейдерам платформы
+			middleware.RequirePermissionWhen(authzHandler.AuthzClient, "automatic_stats", "read", func(c *gin.Context) bool {
+				return c.Query("trader_id") == ""
+			}),
+			automaticHandler.GetAutomaticStats,
+		)
 		automaticGroup.GET("/recent-activity", automaticHandler.GetRecentAutomaticActivity)
 	}
 
-	trafficHandler := handlers.NewTrafficHandler(adminHandler.OrderClient)
-	trafficGroup := r.Group("/api/v1/traffic")
+	trafficHandler := handlers.NewTrafficHandler(adminHandler.OrderClient, traderEventPublisher, deviceWatchdog)
+	trafficGroup := r.Group(
+		"/api/v1/traffic",
+		middleware.AuditMiddleware(auditLog, "traffic"),
+		middleware.AuthMiddleware(authHandler.SSOClient),
+		middleware.RequirePermission(authzHandler.AuthzClient, "traffic", "manage"),
+		middleware.AuditSnapshot(auditLog),
+	)
 	{
 		trafficGroup.PATCH("/traders/:traderID", trafficHandler.SetTraderLockTrafficStatus)
 		trafficGroup.PATCH("/merchants/:merchantID", trafficHandler.SetMerchantLockTrafficStatus)
 		trafficGroup.PATCH("/:trafficID/manual", trafficHandler.SetManuallyLockTrafficStatus)
 		trafficGroup.PATCH("/antifraud/:traderID", trafficHandler.SetAntifraudLockTrafficStatus)
-		trafficGroup.GET("/:trafficID/lock-statuses", trafficHandler.GetTrafficLockStatuses)
-		trafficGroup.GET("/:trafficID/unlocked", trafficHandler.CheckTrafficUnlocked)
-		trafficGroup.GET("/traders/:traderID", trafficHandler.GetTraderTraffic)
+	}
+	// чтение своего трафика доступно трейдеру, чужого - с правом traffic/manage
+	trafficReadGroup := r.Group("/api/v1/traffic", middleware.AuthMiddleware(authHandler.SSOClient))
+	{
+		trafficReadGroup.GET("/:trafficID/lock-statuses", middleware.RequireOwnerOr(authzHandler.AuthzClient, "traffic", "manage", trafficHandler.OwnsTraffic), trafficHandler.GetTrafficLockStatuses)
+		trafficReadGroup.GET("/:trafficID/unlocked", middleware.RequireOwnerOr(authzHandler.AuthzClient, "traffic", "manage", trafficHandler.OwnsTraffic), trafficHandler.CheckTrafficUnlocked)
+		trafficReadGroup.GET("/traders/:traderID", middleware.RequireSelfOr(authzHandler.AuthzClient, "traderID", "traffic", "manage"), trafficHandler.GetTraderTraffic)
 	}
 
     // Антифрод роуты
-    antiFraudHandler := handlers.NewAntiFraudHandler(adminHandler.OrderClient)
+    antiFraudHandler := handlers.NewAntiFraudHandler(adminHandler.OrderClient, traderEventPublisher)
     
-    antifraud := r.Group("/api/v1/antifraud")
+    antifraud := r.Group(
+        "/api/v1/antifraud",
+        middleware.AuditMiddleware(auditLog, "antifraud"),
+        middleware.AuthMiddleware(authHandler.SSOClient),
+        middleware.RequirePermission(authzHandler.AuthzClient, "antifraud", "manage"),
+        middleware.AuditSnapshot(auditLog),
+    )
     {
         // Проверка трейдеров
         antifraud.POST("/traders/:traderID/check", antiFraudHandler.CheckTrader)
@@ -310,4 +842,52 @@ func main() {
 		antifraud.GET("/traders/:traderID/unlock-history", antiFraudHandler.GetUnlockHistory) // НОВОЕ
     }
 	r.Run(":8080")
-}
\ No newline at end of file
+}
+
+// notificationChannels каналы уведомлений по конфигу: local - заглушка, пустой driver - канал выключен
+func notificationChannels(cfg config.Notifications) []notifications.Channel {
+	telegram := notifications.NewTelegramChannel(cfg.Telegram.APIURL, cfg.Telegram.BotToken, cfg.SendTimeout)
+	email := notifications.NewSMTPChannel(cfg.Email.Host, cfg.Email.Port, cfg.Email.Username, cfg.Email.Password, cfg.Email.From)
+	webhook := notifications.NewWebhookChannel(cfg.Webhook.Secret, cfg.SendTimeout)
+
+	var channels []notifications.Channel
+	for _, channel := range []struct {
+		driver string
+		real   notifications.Channel
+	}{
+		{cfg.Telegram.Driver, telegram},
+		{cfg.Email.Driver, email},
+		{cfg.Webhook.Driver, webhook},
+	} {
+		switch channel.driver {
+		case "":
+		case "local":
+			channels = append(channels, notifications.NewLocalChannel(channel.real))
+		default:
+			channels = append(channels, channel.real)
+		}
+	}
+	return channels
+}
+
+// sandboxConfig параметры симулятора песочницы из конфига
+func sandboxConfig(cfg config.Sandbox) service.SandboxConfig {
+	requisites := make([]service.SandboxRequisite, len(cfg.Requisites))
+	for i, requisite := range cfg.Requisites {
+		requisites[i] = service.SandboxRequisite{
+			BankName:      requisite.BankName,
+			BankCode:      requisite.BankCode,
+			NspkCode:      requisite.NspkCode,
+			PaymentSystem: requisite.PaymentSystem,
+			CardNumber:    requisite.CardNumber,
+			Phone:         requisite.Phone,
+			Owner:         requisite.Owner,
+		}
+	}
+	return service.SandboxConfig{
+		CryptoRubRate:        cfg.CryptoRubRate,
+		MaxOrdersPerMerchant: cfg.MaxOrdersPerMerchant,
+		WebhookTimeout:       cfg.WebhookTimeout,
+		Requisites:           requisites,
+	}
+}
@@ -5,7 +5,9 @@ import (
 	"time"
 
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
 	"google.golang.org/protobuf/types/known/durationpb"
 	"google.golang.org/protobuf/types/known/timestamppb"
 
@@ -92,6 +94,36 @@ func (c *OrderClient) GetOrderByMerchantOrderID(merchantOrderID string) (*orderp
 	)
 }
 
+// GetMerchantOrderByID ордер, если он принадлежит мерчанту. Чужой ордер не отличается от несуществующего.
+func (c *OrderClient) GetMerchantOrderByID(merchantID, orderID string) (*orderpb.GetOrderByIDResponse, error) {
+	response, err := c.GetOrderByID(orderID)
+	if err != nil {
+		return nil, err
+	}
+	if response.GetOrder().GetMerchantId() != merchantID {
+		return nil, status.Error(codes.NotFound, "order not found")
+	}
+	return response, nil
+}
+
+// GetMerchantOrderByMerchantOrderID ордер мерчанта по его ID ордера. ID ордеров мерчантов уникальны
+// только в пределах мерчанта, поэтому поиск идет с фильтром по мерчанту, а не через GetOrderByMerchantOrderID.
+func (c *OrderClient) GetMerchantOrderByMerchantOrderID(merchantID, merchantOrderID string) (*orderpb.GetOrderByMerchantOrderIDResponse, error) {
+	response, err := c.GetAllOrders(&orderpb.GetAllOrdersRequest{
+		Page:            1,
+		Limit:           1,
+		MerchantId:      &merchantID,
+		MerchantOrderId: &merchantOrderID,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(response.Orders) == 0 {
+		return nil, status.Error(codes.NotFound, "order not found")
+	}
+	return &orderpb.GetOrderByMerchantOrderIDResponse{Order: response.Orders[0]}, nil
+}
+
 func (c *OrderClient) GetOrdersByTraderID(request *orderpb.GetOrdersByTraderIDRequest) (*orderpb.GetOrdersByTraderIDResponse, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
 	defer cancel()
@@ -3,6 +3,7 @@ package client
 import (
 	"bytes"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
@@ -12,6 +13,9 @@ import (
 	walletResponse "github.com/LavaJover/shvark-api-gateway/internal/delivery/http/dto/wallet/response"
 )
 
+// ErrWithdrawalRuleNotFound у пользователя нет своих правил вывода
+var ErrWithdrawalRuleNotFound = errors.New("withdrawal rule not found")
+
 type HTTPWalletClient struct {
 	Addr string
 }
@@ -183,6 +187,9 @@ func (c *HTTPWalletClient) GetWithdrawalRules(userID string) (*GetWithdrawalRule
 	}
 	defer resp.Body.Close()
 
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, ErrWithdrawalRuleNotFound
+	}
 	if resp.StatusCode != 200 {
 		return nil, fmt.Errorf("unexpected status code: %d", resp.StatusCode)
 	}
@@ -214,4 +221,37 @@ func (c HTTPWalletClient) DeleteWithdrawalRule(userID string) error {
 	}
 
 	return nil
-}
\ No newline at end of file
+}
+type commissionProfitRequest struct {
+	TraderID string `json:"traderId"`
+	From     string `json:"from"`
+	To       string `json:"to"`
+}
+
+// GetCommissionProfit комиссионный доход трейдера за период
+func (c *HTTPWalletClient) GetCommissionProfit(traderID string, from, to time.Time) (*walletResponse.CommissionProfitResponse, error) {
+	requestBody, err := json.Marshal(commissionProfitRequest{
+		TraderID: traderID,
+		From:     from.UTC().Format(time.RFC3339),
+		To:       to.UTC().Format(time.RFC3339),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := http.Post(fmt.Sprintf("http://%s/wallets/commission-profit", c.Addr), "application/json", bytes.NewBuffer(requestBody))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+	}
+
+	var result walletResponse.CommissionProfitResponse
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+	return &result, nil
+}
@@ -3,6 +3,8 @@ package config
 import (
 	"log"
 	"os"
+	"time"
+
 	"github.com/ilyakaznacheev/cleanenv"
 )
 
@@ -17,6 +19,28 @@ type HttpAPIConfig struct {
 	BankingService `yaml:"banking-service"`
 	WalletService  `yaml:"wallet-service"`
 	SwaggerConfig  `yaml:"swagger_config"`
+	OrderToken 	   `yaml:"order_token"`
+	OrderEvents    `yaml:"order_events"`
+	TraderEvents   `yaml:"trader_events"`
+	DeeplinkTemplates `yaml:"deeplink_templates"`
+	DeeplinkAnalytics `yaml:"deeplink_analytics"`
+	Localization   `yaml:"localization"`
+	BankCatalog    `yaml:"bank_catalog"`
+	SmsParser 	   `yaml:"sms_parser"`
+	DevicePairing  `yaml:"device_pairing"`
+	SmsDedup 	   `yaml:"sms_dedup"`
+	SmsBatch 	   `yaml:"sms_batch"`
+	DeviceWatchdog `yaml:"device_watchdog"`
+	AutomaticStats `yaml:"automatic_stats"`
+	Notifications  `yaml:"notifications"`
+	Sandbox 	   `yaml:"sandbox"`
+	Onboarding 	   `yaml:"onboarding"`
+	BulkAdmin 	   `yaml:"bulk_admin"`
+	Audit 		   `yaml:"audit"`
+	Evidence 	   `yaml:"evidence"`
+	DisputeSLA 	   `yaml:"dispute_sla"`
+	TeamStats 	   `yaml:"team_stats"`
+	RoutingSimulator `yaml:"routing_simulator"`
 }
 
 type HttpAPIServer struct {
@@ -67,6 +91,239 @@ type SwaggerConfig struct {
 	Schemes  string `yaml:"schemes"`
 }
 
+// OrderToken настройки подписанных токенов, привязанных к ордеру
+type OrderToken struct {
+	Secret string 		 `yaml:"secret" env:"ORDER_TOKEN_SECRET"`
+	TTL    time.Duration `yaml:"ttl" env-default:"30m"`
+}
+
+// OrderEvents настройки SSE-стрима статусов ордера
+type OrderEvents struct {
+	PollInterval 	  time.Duration `yaml:"poll_interval" env-default:"3s"`
+	HeartbeatInterval time.Duration `yaml:"heartbeat_interval" env-default:"15s"`
+}
+
+// TraderEvents настройки push-канала трейдеров
+type TraderEvents struct {
+	PollInterval 	  time.Duration `yaml:"poll_interval" env-default:"10s"`
+	ExpiringThreshold time.Duration `yaml:"expiring_threshold" env-default:"3m"`
+	HeartbeatInterval time.Duration `yaml:"heartbeat_interval" env-default:"20s"`
+	HistorySize 	  int 			`yaml:"history_size" env-default:"100"`
+	// AllowedOrigins страницы, которым разрешено открывать WebSocket (scheme://host[:port]).
+	// Запросы с того же хоста и без Origin (не из браузера) пропускаются всегда.
+	AllowedOrigins 	  []string 		`yaml:"allowed_origins"`
+}
+
+// DeeplinkTemplates каталог шаблонов диплинков и период проверки изменений
+type DeeplinkTemplates struct {
+	Dir 		   string 		 `yaml:"dir" env:"DEEPLINK_TEMPLATES_DIR" env-default:"./data/deeplink_templates"`
+	ReloadInterval time.Duration `yaml:"reload_interval" env-default:"30s"`
+}
+
+// DeeplinkAnalytics хранение событий воронки страниц диплинков
+type DeeplinkAnalytics struct {
+	Retention time.Duration `yaml:"retention" env-default:"720h"`
+	MaxEvents int 			`yaml:"max_events" env-default:"500000"`
+}
+
+// Localization язык страниц, если клиент не прислал поддерживаемый: общий и по мерчантам
+type Localization struct {
+	DefaultLocale   string 			  `yaml:"default_locale" env:"DEFAULT_LOCALE" env-default:"ru"`
+	MerchantLocales map[string]string `yaml:"merchant_locales"`
+}
+
+// BankCatalog файл справочника банков, пустой путь - встроенный справочник
+type BankCatalog struct {
+	File string `yaml:"file" env:"BANK_CATALOG_FILE"`
+}
+
+// SmsParser правила разбора банковских уведомлений и реакция на расхождение с разбором устройства.
+// OnMismatch и OnUnmatched: flag - обработать с пометкой в Metadata, reject - не обрабатывать.
+type SmsParser struct {
+	RulesDir 		string 	`yaml:"rules_dir" env:"SMS_PARSER_RULES_DIR"`
+	OnMismatch 		string 	`yaml:"on_mismatch" env-default:"reject"`
+	OnUnmatched 	string 	`yaml:"on_unmatched" env-default:"flag"`
+	AmountTolerance float64 `yaml:"amount_tolerance" env-default:"0.01"`
+}
+
+// DevicePairing срок жизни кода сопряжения и файл учетных данных устройств, пустой путь - только память
+type DevicePairing struct {
+	CodeTTL 		time.Duration `yaml:"code_ttl" env-default:"5m"`
+	CredentialsFile string 		  `yaml:"credentials_file" env:"DEVICE_CREDENTIALS_FILE" env-default:"./data/device_credentials.json"`
+}
+
+// SmsDedup защита от повторов уведомлений: окно времени получения по часам сервера и срок хранения отпечатков
+type SmsDedup struct {
+	TTL 		  time.Duration `yaml:"ttl" env-default:"48h"`
+	MaxAge 		  time.Duration `yaml:"max_age" env-default:"15m"`
+	MaxOfflineAge time.Duration `yaml:"max_offline_age" env-default:"24h"` // для уведомлений из очереди телефона
+	MaxClockSkew  time.Duration `yaml:"max_clock_skew" env-default:"2m"`
+}
+
+// SmsBatch ограничения пачки уведомлений из очереди телефона
+type SmsBatch struct {
+	MaxItems 	int `yaml:"max_items" env-default:"500"`
+	Concurrency int `yaml:"concurrency" env-default:"4"`
+}
+
+// DeviceWatchdog автоматическая блокировка трафика, когда устройство автоматики перестает пинговать.
+// Mode: trader - блокируется весь трафик трейдера, bank_details - только реквизиты устройства
+type DeviceWatchdog struct {
+	Enabled 	  bool 			`yaml:"enabled" env-default:"true"`
+	Threshold 	  time.Duration `yaml:"threshold" env-default:"3m"`
+	CheckInterval time.Duration `yaml:"check_interval" env-default:"30s"`
+	Mode 		  string 		`yaml:"mode" env-default:"bank_details"`
+	AuditFile 	  string 		`yaml:"audit_file" env:"AUTOMATIC_LOCK_AUDIT_FILE" env-default:"./data/automatic_lock_audit.jsonl"`
+}
+
+// AutomaticStats общая статистика автоматики: параллельность обхода трейдеров, срок кеша,
+// лимит логов трейдера для разбивок по устройствам, банкам и платежным системам и максимальный период
+type AutomaticStats struct {
+	Concurrency 	 int 		   `yaml:"concurrency" env-default:"8"`
+	CacheTTL 		 time.Duration `yaml:"cache_ttl" env-default:"1m"`
+	MaxLogsPerTrader int 		   `yaml:"max_logs_per_trader" env-default:"2000"`
+	MaxDays 		 int 		   `yaml:"max_days" env-default:"90"`
+}
+
+// Notifications рассылка событий трейдерам и админам. Driver канала: local - заглушка, которая только
+// пишет сообщения в лог, пустой - канал выключен, иначе настоящий канал
+type Notifications struct {
+	Enabled 		bool 		  `yaml:"enabled" env-default:"true"`
+	Workers 		int 		  `yaml:"workers" env-default:"4"`
+	QueueSize 		int 		  `yaml:"queue_size" env-default:"1000"`
+	MaxAttempts 	int 		  `yaml:"max_attempts" env-default:"3"`
+	RetryDelay 		time.Duration `yaml:"retry_delay" env-default:"5s"`
+	SendTimeout 	time.Duration `yaml:"send_timeout" env-default:"10s"`
+	HistorySize 	int 		  `yaml:"history_size" env-default:"200"` // записей истории на пользователя
+	PreferencesFile string 		  `yaml:"preferences_file" env:"NOTIFICATION_PREFERENCES_FILE" env-default:"./data/notification_preferences.json"`
+	TemplatesDir 	string 		  `yaml:"templates_dir"` // пустой - встроенные шаблоны
+	Telegram 		NotificationsTelegram `yaml:"telegram"`
+	Email 			NotificationsEmail 	  `yaml:"email"`
+	Webhook 		NotificationsWebhook  `yaml:"webhook"`
+	LowBalance 		NotificationsLowBalance `yaml:"low_balance"`
+}
+
+type NotificationsTelegram struct {
+	Driver 	 string `yaml:"driver" env:"NOTIFICATIONS_TELEGRAM_DRIVER" env-default:"local"` // telegram, local
+	APIURL 	 string `yaml:"api_url" env-default:"https://api.telegram.org"`
+	BotToken string `yaml:"bot_token" env:"NOTIFICATIONS_TELEGRAM_BOT_TOKEN"`
+}
+
+type NotificationsEmail struct {
+	Driver 	 string `yaml:"driver" env:"NOTIFICATIONS_EMAIL_DRIVER" env-default:"local"` // smtp, local
+	Host 	 string `yaml:"host" env:"NOTIFICATIONS_SMTP_HOST"`
+	Port 	 int 	`yaml:"port" env:"NOTIFICATIONS_SMTP_PORT" env-default:"587"`
+	Username string `yaml:"username" env:"NOTIFICATIONS_SMTP_USERNAME"`
+	Password string `yaml:"password" env:"NOTIFICATIONS_SMTP_PASSWORD"`
+	From 	 string `yaml:"from" env:"NOTIFICATIONS_SMTP_FROM"`
+}
+
+type NotificationsWebhook struct {
+	Driver string `yaml:"driver" env:"NOTIFICATIONS_WEBHOOK_DRIVER" env-default:"local"` // webhook, local
+	Secret string `yaml:"secret" env:"NOTIFICATIONS_WEBHOOK_SECRET"`
+}
+
+// NotificationsLowBalance опрос балансов кошельков для события low_balance
+type NotificationsLowBalance struct {
+	Enabled 	  bool 			`yaml:"enabled" env-default:"true"`
+	Threshold 	  float64 		`yaml:"threshold" env-default:"100"`
+	CheckInterval time.Duration `yaml:"check_interval" env-default:"5m"`
+}
+
+// Sandbox песочница мерчантов: запросы с ключами sbx_ обслуживает симулятор ордеров в памяти,
+// боевые сервисы не вызываются. Пустые requisites - встроенные тестовые реквизиты
+type Sandbox struct {
+	Enabled 			 bool 				`yaml:"enabled" env-default:"true"`
+	CredentialsFile 	 string 			`yaml:"credentials_file" env:"SANDBOX_CREDENTIALS_FILE" env-default:"./data/sandbox_credentials.json"`
+	CryptoRubRate 		 float64 			`yaml:"crypto_rub_rate" env-default:"95"`
+	MaxOrdersPerMerchant int 				`yaml:"max_orders_per_merchant" env-default:"1000"`
+	WebhookTimeout 		 time.Duration 		`yaml:"webhook_timeout" env-default:"10s"`
+	Requisites 			 []SandboxRequisite `yaml:"requisites"`
+}
+
+type SandboxRequisite struct {
+	BankName 	  string `yaml:"bank_name"`
+	BankCode 	  string `yaml:"bank_code"`
+	NspkCode 	  string `yaml:"nspk_code"`
+	PaymentSystem string `yaml:"payment_system"` // C2C, SBP
+	CardNumber 	  string `yaml:"card_number"`
+	Phone 		  string `yaml:"phone"`
+	Owner 		  string `yaml:"owner"`
+}
+
+// Onboarding сага создания команд и мерчантов: повторы шагов, роли RBAC новых пользователей
+// и файл, в котором сохраняется прогресс саг
+type Onboarding struct {
+	MaxAttempts  int 		   `yaml:"max_attempts" env-default:"3"`
+	RetryDelay 	 time.Duration `yaml:"retry_delay" env-default:"2s"`
+	StuckAfter 	 time.Duration `yaml:"stuck_after" env-default:"5m"`
+	TraderRole 	 string 	   `yaml:"trader_role" env-default:"trader"`
+	MerchantRole string 	   `yaml:"merchant_role" env-default:"merchant"`
+	File 		 string 	   `yaml:"file" env:"ONBOARDING_FILE" env-default:"./data/onboardings.json"`
+}
+
+// BulkAdmin массовые операции админки: сколько запросов в upstream идет параллельно
+// и сколько элементов (пар трейдер x мерчант, трейдеров, пользователей) допускается в одном запросе
+type BulkAdmin struct {
+	Concurrency int `yaml:"concurrency" env-default:"8"`
+	MaxItems 	int `yaml:"max_items" env-default:"1000"`
+}
+
+// Audit журнал административных и финансовых действий: файл JSON Lines, сколько последних
+// записей держать в памяти для выборок, сколько байт тела запроса сохранять
+type Audit struct {
+	Enabled 	 bool 			`yaml:"enabled" env-default:"true"`
+	File 		 string 		`yaml:"file" env:"AUDIT_FILE" env-default:"./data/audit.jsonl"`
+	MaxEntries 	 int 			`yaml:"max_entries" env-default:"100000"`
+	MaxBodyBytes int 			`yaml:"max_body_bytes" env-default:"65536"`
+	RoleCacheTTL time.Duration 	`yaml:"role_cache_ttl" env-default:"5m"`
+	ExportLimit  int 			`yaml:"export_limit" env-default:"50000"`
+}
+
+// Evidence чеки и скриншоты по спорам: каталог файлов, файл метаданных, лимит размера,
+// разрешенные типы и срок подписанной ссылки на скачивание
+type Evidence struct {
+	Dir 		 string 		`yaml:"dir" env:"EVIDENCE_DIR" env-default:"./data/evidence"`
+	MetadataFile string 		`yaml:"metadata_file" env:"EVIDENCE_METADATA_FILE" env-default:"./data/evidence.jsonl"`
+	MaxFileSize  int64 			`yaml:"max_file_size" env-default:"10485760"`
+	AllowedTypes []string 		`yaml:"allowed_types" env-default:"image/jpeg,image/png,application/pdf"`
+	URLTTL 		 time.Duration 	`yaml:"url_ttl" env-default:"15m"`
+}
+
+// DisputeSLA сроки этапов спора: ответ трейдера, решение админа, заморозка; за сколько до срока
+// напоминать и что делать, если трейдер не ответил (freeze или escalate). OpenStatuses и FrozenStatus -
+// статусы споров в order-service, по ним синхронизация находит открытые и замороженные споры.
+type DisputeSLA struct {
+	DisputeTTL 		time.Duration `yaml:"dispute_ttl" env-default:"30m"`
+	TraderResponse 	time.Duration `yaml:"trader_response" env-default:"20m"`
+	AdminReview 	time.Duration `yaml:"admin_review" env-default:"2h"`
+	Frozen 			time.Duration `yaml:"frozen" env-default:"24h"`
+	RemindBefore 	time.Duration `yaml:"remind_before" env-default:"5m"`
+	OnTraderTimeout string 		  `yaml:"on_trader_timeout" env-default:"escalate"`
+	CheckInterval 	time.Duration `yaml:"check_interval" env-default:"30s"`
+	SyncInterval 	time.Duration `yaml:"sync_interval" env-default:"2m"`
+	OpenStatuses 	[]string 	  `yaml:"open_statuses" env-default:"DISPUTE_OPENED,DISPUTE_FROZEN"`
+	FrozenStatus 	string 		  `yaml:"frozen_status" env-default:"DISPUTE_FROZEN"`
+	MaxOpen 		int 		  `yaml:"max_open" env-default:"2000"`
+	File 			string 		  `yaml:"file" env:"DISPUTE_SLA_FILE" env-default:"./data/dispute_sla.json"`
+}
+
+// TeamStats статистика команды тимлида: сколько трейдеров опрашивается параллельно, срок кеша
+// и самый длинный период, за который можно запросить статистику
+type TeamStats struct {
+	Concurrency int 		  `yaml:"concurrency" env-default:"8"`
+	CacheTTL 	time.Duration `yaml:"cache_ttl" env-default:"1m"`
+	MaxRange 	time.Duration `yaml:"max_range" env-default:"2208h"`
+}
+
+// RoutingSimulator симулятор подбора реквизитов: сколько трейдеров проверяется параллельно, размер
+// страницы при чтении трафика и учитывать ли статус устройства, к которому привязан реквизит
+type RoutingSimulator struct {
+	Concurrency 		int  `yaml:"concurrency" env-default:"8"`
+	TrafficPageSize 	int  `yaml:"traffic_page_size" env-default:"1000"`
+	RequireOnlineDevice bool `yaml:"require_online_device" env-default:"true"`
+}
+
 func MustLoad() *HttpAPIConfig {
 
 	// Processing env config variable and file
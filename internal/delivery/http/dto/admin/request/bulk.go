@@ -0,0 +1,30 @@
+package request
+
+// BulkTrafficRequest трафик для всех пар trader_ids x merchant_ids: существующий правится,
+// недостающий создается. Не переданные поля не меняются; для создания обязательны
+// trader_reward, platform_fee, trader_priority и merchant_deals_duration.
+type BulkTrafficRequest struct {
+	TraderIDs             []string `json:"trader_ids" binding:"required,min=1"`
+	MerchantIDs           []string `json:"merchant_ids" binding:"required,min=1"`
+	Name                  *string  `json:"name"`
+	TraderReward          *float64 `json:"trader_reward"`
+	PlatformFee           *float64 `json:"platform_fee"`
+	TraderPriority        *float64 `json:"trader_priority"`
+	Enabled               *bool    `json:"enabled"`
+	AntifraudRequired     *bool    `json:"antifraud_required"`
+	MerchantDealsDuration *string  `json:"merchant_deals_duration"`
+	DryRun                bool     `json:"dry_run"`
+}
+
+// BulkTeamLockRequest блокировка (unlocked=false) или разблокировка трафика всех трейдеров тимлида
+type BulkTeamLockRequest struct {
+	TeamLeadID string `json:"team_lead_id" binding:"required"`
+	Unlocked   bool   `json:"unlocked"`
+	Reason     string `json:"reason"`
+	DryRun     bool   `json:"dry_run"`
+}
+
+type BulkWithdrawalRulesRequest struct {
+	Rules  []SetWithdrawalRulesRequest `json:"rules" binding:"required,min=1,dive"`
+	DryRun bool                        `json:"dry_run"`
+}
@@ -4,4 +4,5 @@ type CreateTeamRequest struct {
 	Username string `json:"username"`
 	Login 	 string `json:"login"`
 	Password string `json:"password"`
+	DefaultTraffic *OnboardingTrafficRequest `json:"default_traffic"` // необязательный трафик по умолчанию
 }
\ No newline at end of file
@@ -0,0 +1,34 @@
+package request
+
+type DeeplinkTemplateRequest struct {
+	BankCode         string                 `json:"bank_code" binding:"required"`
+	BankName         string                 `json:"bank_name" binding:"required"`
+	Icon             string                 `json:"icon"`
+	SupportedSystems []string               `json:"supported_systems" binding:"required"`
+	TransferType     string                 `json:"transfer_type" binding:"required"`
+	Schemes          []string               `json:"schemes"`
+	Links            []DeeplinkTemplateLink `json:"links"`
+	AppStoreURL      string                 `json:"app_store_url"`
+	PlayStoreURL     string                 `json:"play_store_url"`
+	RequisitesPolicy string                 `json:"requisites_policy" binding:"omitempty,oneof=full masked on_demand"`
+	Body             string                 `json:"body" binding:"required"`
+	Comment          string                 `json:"comment"`
+}
+
+type DeeplinkTemplateLink struct {
+	Name           string `json:"name" binding:"required"`
+	Template       string `json:"template" binding:"required"`
+	OS             string `json:"os" binding:"required,oneof=ios android universal"`
+	AndroidPackage string `json:"android_package"`
+}
+
+type PreviewDeeplinkTemplateRequest struct {
+	BankCode string   `json:"bank_code"`
+	Schemes  []string `json:"schemes"`
+	Body     string   `json:"body" binding:"required"`
+	// Необязательные данные платежа вместо тестовых
+	Amount      string `json:"amount"`
+	CardNumber  string `json:"card_number"`
+	PhoneNumber string `json:"phone_number"`
+	Locale      string `json:"locale"`
+}
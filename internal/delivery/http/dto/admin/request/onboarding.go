@@ -0,0 +1,13 @@
+package request
+
+// OnboardingTrafficRequest трафик по умолчанию для нового пользователя. counterpart_id -
+// мерчант для новой команды или трейдер для нового мерчанта.
+type OnboardingTrafficRequest struct {
+	CounterpartID         string  `json:"counterpart_id" binding:"required"`
+	Name                  string  `json:"name"`
+	TraderReward          float64 `json:"trader_reward"`
+	TraderPriority        float64 `json:"trader_priority"`
+	PlatformFee           float64 `json:"platform_fee"`
+	MerchantDealsDuration string  `json:"merchant_deals_duration" binding:"required"`
+	AntifraudRequired     bool    `json:"antifraud_required"`
+}
@@ -0,0 +1,9 @@
+package request
+
+// RoutingSimulationRequest параметры ордера, как их передает мерчант. Пустой bank_code - любой банк.
+type RoutingSimulationRequest struct {
+	MerchantID    string  `json:"merchant_id" binding:"required"`
+	AmountFiat    float64 `json:"amount_fiat" binding:"required,gt=0"`
+	PaymentSystem string  `json:"payment_system" binding:"required"`
+	BankCode      string  `json:"bank_code"`
+}
@@ -0,0 +1,18 @@
+package response
+
+// AutomaticLock запись журнала сторожа устройств: блокировка или разблокировка трафика
+type AutomaticLock struct {
+	At              int64    `json:"at"`
+	Action          string   `json:"action"`
+	Mode            string   `json:"mode"`
+	TraderID        string   `json:"trader_id"`
+	DeviceIDs       []string `json:"device_ids"`
+	BankDetailIDs   []string `json:"bank_detail_ids,omitempty"`
+	LastHeartbeatAt *int64   `json:"last_heartbeat_at,omitempty"`
+	Reason          string   `json:"reason,omitempty"`
+	Error           string   `json:"error,omitempty"`
+}
+
+type GetAutomaticLocksResponse struct {
+	Locks []AutomaticLock `json:"locks"`
+}
@@ -0,0 +1,30 @@
+package response
+
+type BulkChange struct {
+	Field string `json:"field"`
+	From  string `json:"from,omitempty"`
+	To    string `json:"to"`
+}
+
+type BulkItem struct {
+	TraderID   string       `json:"trader_id,omitempty"`
+	MerchantID string       `json:"merchant_id,omitempty"`
+	UserID     string       `json:"user_id,omitempty"`
+	TrafficID  string       `json:"traffic_id,omitempty"`
+	Action     string       `json:"action"`
+	Status     string       `json:"status"`
+	Changes    []BulkChange `json:"changes,omitempty"`
+	Error      string       `json:"error,omitempty"`
+}
+
+// BulkResponse итог массовой операции. Статусы элементов: planned (dry-run), applied,
+// unchanged - состояние уже совпадает с запрошенным, failed - ошибка в error
+type BulkResponse struct {
+	DryRun    bool       `json:"dry_run"`
+	Total     int        `json:"total"`
+	Planned   int        `json:"planned"`
+	Applied   int        `json:"applied"`
+	Unchanged int        `json:"unchanged"`
+	Failed    int        `json:"failed"`
+	Items     []BulkItem `json:"items"`
+}
@@ -4,4 +4,5 @@ type CreateMerchantResponse struct {
 	MerchantID 	  string 	`json:"merchant_id"`
 	AccessToken   string 	`json:"access_token"`
 	WalletAddress string    `json:"wallet_address"`
+	OnboardingID  string 	`json:"onboarding_id"`
 }
\ No newline at end of file
@@ -4,4 +4,5 @@ type CreateTeamResponse struct {
 	TraderID 		string 		`json:"trader_id"` 
 	AccessToken 	string 		`json:"access_token"`
 	WalletAddress 	string 		`json:"wallet_address"`
+	OnboardingID 	string 		`json:"onboarding_id"`
 }
\ No newline at end of file
@@ -0,0 +1,20 @@
+package response
+
+type DeeplinkStatsRow struct {
+	BucketStart string  `json:"bucket_start"`
+	Key         string  `json:"key"`
+	PageViews   int     `json:"page_views"`
+	BankChosen  int     `json:"bank_chosen"`
+	Attempts    int     `json:"attempts"`
+	Opened      int     `json:"opened"`
+	TimedOut    int     `json:"timed_out"`
+	Conversion  float64 `json:"conversion"`
+}
+
+type GetDeeplinkStatsResponse struct {
+	From     string             `json:"from"`
+	To       string             `json:"to"`
+	Interval string             `json:"interval"`
+	GroupBy  string             `json:"group_by"`
+	Rows     []DeeplinkStatsRow `json:"rows"`
+}
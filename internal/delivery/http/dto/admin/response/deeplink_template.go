@@ -0,0 +1,41 @@
+package response
+
+type DeeplinkTemplateVersion struct {
+	BankCode         string                 `json:"bank_code"`
+	Version          int                    `json:"version"`
+	Active           bool                   `json:"active"`
+	BankName         string                 `json:"bank_name"`
+	Icon             string                 `json:"icon,omitempty"`
+	SupportedSystems []string               `json:"supported_systems"`
+	TransferType     string                 `json:"transfer_type"`
+	Schemes          []string               `json:"schemes,omitempty"`
+	Links            []DeeplinkTemplateLink `json:"links,omitempty"`
+	AppStoreURL      string                 `json:"app_store_url,omitempty"`
+	PlayStoreURL     string                 `json:"play_store_url,omitempty"`
+	RequisitesPolicy string                 `json:"requisites_policy"`
+	Comment          string                 `json:"comment,omitempty"`
+	CreatedAt        string                 `json:"created_at"`
+	Body             string                 `json:"body,omitempty"`
+}
+
+type DeeplinkTemplateLink struct {
+	Name           string `json:"name"`
+	Template       string `json:"template"`
+	OS             string `json:"os"`
+	AndroidPackage string `json:"android_package,omitempty"`
+}
+
+type DeeplinkTemplate struct {
+	BankCode      string                    `json:"bank_code"`
+	ActiveVersion int                       `json:"active_version"`
+	Versions      []DeeplinkTemplateVersion `json:"versions"`
+}
+
+type GetDeeplinkTemplatesResponse struct {
+	Templates []DeeplinkTemplate `json:"templates"`
+}
+
+type ValidateDeeplinkTemplateResponse struct {
+	Valid    bool     `json:"valid"`
+	Problems []string `json:"problems,omitempty"`
+}
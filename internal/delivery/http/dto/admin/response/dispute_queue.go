@@ -0,0 +1,35 @@
+package response
+
+import "time"
+
+// DisputeQueueItem открытый спор со сроком текущего этапа. seconds_left отрицательный у просроченных.
+type DisputeQueueItem struct {
+	DisputeID         string     `json:"dispute_id"`
+	OrderID           string     `json:"order_id"`
+	MerchantOrderID   string     `json:"merchant_order_id"`
+	TraderID          string     `json:"trader_id"`
+	DisputeStatus     string     `json:"dispute_status"`
+	DisputeReason     string     `json:"dispute_reason"`
+	ProofUrl          string     `json:"proof_url"`
+	DisputeAmountFiat float64    `json:"dispute_amount_fiat"`
+	OrderAmountFiat   float64    `json:"order_amount_fiat"`
+	Stage             string     `json:"stage"`
+	Urgency           string     `json:"urgency"`
+	OpenedAt          time.Time  `json:"opened_at"`
+	StageStartedAt    time.Time  `json:"stage_started_at"`
+	Deadline          time.Time  `json:"deadline"`
+	SecondsLeft       int64      `json:"seconds_left"`
+	ExpiresAt         *time.Time `json:"expires_at,omitempty"`
+	Reminded          bool       `json:"reminded"`
+	Escalated         bool       `json:"escalated"`
+	EscalatedAt       *time.Time `json:"escalated_at,omitempty"`
+}
+
+// DisputeQueueResponse truncated - открытых споров больше, чем читается за раз, очередь неполная
+type DisputeQueueResponse struct {
+	Disputes  []DisputeQueueItem `json:"disputes"`
+	Total     int                `json:"total"`
+	Offset    int                `json:"offset"`
+	Limit     int                `json:"limit"`
+	Truncated bool               `json:"truncated"`
+}
@@ -0,0 +1,6 @@
+package response
+
+type GetMissingTranslationsResponse struct {
+	Locales []string            `json:"locales"`
+	Missing map[string][]string `json:"missing"`
+}
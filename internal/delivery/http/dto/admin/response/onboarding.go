@@ -0,0 +1,35 @@
+package response
+
+type OnboardingStep struct {
+	Name        string `json:"name"`
+	Status      string `json:"status"`
+	Attempts    int    `json:"attempts"`
+	Error       string `json:"error,omitempty"`
+	CompletedAt int64  `json:"completed_at,omitempty"`
+}
+
+type Onboarding struct {
+	ID            string           `json:"id"`
+	Kind          string           `json:"kind"`
+	Login         string           `json:"login"`
+	Username      string           `json:"username"`
+	UserID        string           `json:"user_id,omitempty"`
+	WalletAddress string           `json:"wallet_address,omitempty"`
+	TrafficID     string           `json:"traffic_id,omitempty"`
+	Status        string           `json:"status"`
+	Error         string           `json:"error,omitempty"`
+	Steps         []OnboardingStep `json:"steps"`
+	CreatedBy     string           `json:"created_by,omitempty"`
+	CreatedAt     int64            `json:"created_at"`
+	UpdatedAt     int64            `json:"updated_at"`
+}
+
+type OnboardingsResponse struct {
+	Onboardings []Onboarding `json:"onboardings"`
+}
+
+// OnboardingErrorResponse онбординг остановился на шаге error, его можно продолжить или отменить по onboarding.id
+type OnboardingErrorResponse struct {
+	Error      string     `json:"error"`
+	Onboarding Onboarding `json:"onboarding"`
+}
@@ -0,0 +1,65 @@
+package response
+
+import "time"
+
+// RoutingLocks флаги разблокировки трафика; unlocked - итог order-service
+type RoutingLocks struct {
+	MerchantUnlocked  bool `json:"merchant_unlocked"`
+	TraderUnlocked    bool `json:"trader_unlocked"`
+	AntifraudUnlocked bool `json:"antifraud_unlocked"`
+	ManuallyUnlocked  bool `json:"manually_unlocked"`
+	Unlocked          bool `json:"unlocked"`
+}
+
+// RoutingBankDetail реквизит и причины, по которым он не примет ордер. Оборот - за текущие сутки и месяц,
+// нулевой лимит - без ограничения.
+type RoutingBankDetail struct {
+	BankDetailID     string   `json:"bank_detail_id"`
+	BankCode         string   `json:"bank_code"`
+	PaymentSystem    string   `json:"payment_system"`
+	DeviceID         string   `json:"device_id,omitempty"`
+	Enabled          bool     `json:"enabled"`
+	MinAmount        float64  `json:"min_amount"`
+	MaxAmount        float64  `json:"max_amount"`
+	MaxAmountDay     float64  `json:"max_amount_day"`
+	MaxAmountMonth   float64  `json:"max_amount_month"`
+	MaxQuantityDay   int64    `json:"max_quantity_day"`
+	MaxQuantityMonth int64    `json:"max_quantity_month"`
+	AmountToday      float64  `json:"amount_today"`
+	AmountMonth      float64  `json:"amount_month"`
+	CountToday       int64    `json:"count_today"`
+	CountMonth       int64    `json:"count_month"`
+	Eligible         bool     `json:"eligible"`
+	Reasons          []string `json:"reasons"`
+}
+
+// RoutingTrader verdict: eligible, excluded или unknown (часть проверок не выполнилась, см. failed_checks)
+type RoutingTrader struct {
+	TraderID            string              `json:"trader_id"`
+	TrafficID           string              `json:"traffic_id"`
+	TraderPriority      float64             `json:"trader_priority"`
+	TraderRewardPercent float64             `json:"trader_reward_percent"`
+	Verdict             string              `json:"verdict"`
+	Reasons             []string            `json:"reasons"`
+	FailedChecks        []string            `json:"failed_checks,omitempty"`
+	AntifraudRequired   bool                `json:"antifraud_required"`
+	FailedRules         []string            `json:"failed_rules,omitempty"`
+	Locks               RoutingLocks        `json:"locks"`
+	EligibleBankDetails int                 `json:"eligible_bank_details"`
+	BankDetails         []RoutingBankDetail `json:"bank_details"`
+}
+
+// RoutingSimulationResponse exclusions - сколько трейдеров исключила каждая причина
+type RoutingSimulationResponse struct {
+	MerchantID    string          `json:"merchant_id"`
+	AmountFiat    float64         `json:"amount_fiat"`
+	PaymentSystem string          `json:"payment_system"`
+	BankCode      string          `json:"bank_code,omitempty"`
+	SimulatedAt   time.Time       `json:"simulated_at"`
+	Traders       int             `json:"traders"`
+	Eligible      int             `json:"eligible"`
+	Excluded      int             `json:"excluded"`
+	Unknown       int             `json:"unknown"`
+	Exclusions    map[string]int  `json:"exclusions"`
+	Results       []RoutingTrader `json:"results"`
+}
@@ -0,0 +1,32 @@
+package audit
+
+import "encoding/json"
+
+type Entry struct {
+	ID         string            `json:"id"`
+	At         int64             `json:"at"`
+	ActorID    string            `json:"actor_id,omitempty"`
+	ActorRole  string            `json:"actor_role,omitempty"`
+	IP         string            `json:"ip"`
+	UserAgent  string            `json:"user_agent,omitempty"`
+	Category   string            `json:"category"`
+	Method     string            `json:"method"`
+	Route      string            `json:"route"`
+	Path       string            `json:"path"`
+	TargetIDs  map[string]string `json:"target_ids,omitempty"`
+	Query      map[string]string `json:"query,omitempty"`
+	Request    json.RawMessage   `json:"request,omitempty" swaggertype:"object"`
+	StatusCode int               `json:"status_code"`
+	Outcome    string            `json:"outcome"`
+	Error      string            `json:"error,omitempty"`
+	Before     json.RawMessage   `json:"before,omitempty" swaggertype:"object"`
+	After      json.RawMessage   `json:"after,omitempty" swaggertype:"object"`
+	DurationMs int64             `json:"duration_ms"`
+}
+
+type EntriesResponse struct {
+	Entries []Entry `json:"entries"`
+	Total   int     `json:"total"`
+	Offset  int     `json:"offset"`
+	Limit   int     `json:"limit"`
+}
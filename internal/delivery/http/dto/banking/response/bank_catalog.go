@@ -0,0 +1,16 @@
+package response
+
+type CatalogBank struct {
+	Code              string            `json:"code"`
+	Name              string            `json:"name"`
+	Names             map[string]string `json:"names,omitempty"`
+	NspkCode          string            `json:"nspk_code"`
+	Country           string            `json:"country"`
+	PaymentSystems    []string          `json:"payment_systems"`
+	DeeplinkTemplates []string          `json:"deeplink_templates,omitempty"`
+	Logo              string            `json:"logo,omitempty"`
+}
+
+type GetCatalogBanksResponse struct {
+	Banks []CatalogBank `json:"banks"`
+}
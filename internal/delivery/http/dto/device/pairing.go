@@ -0,0 +1,42 @@
+package device
+
+// StartPairingResponse одноразовый код сопряжения, qrPayload показывается трейдеру QR-кодом
+type StartPairingResponse struct {
+	DeviceID  string `json:"deviceId"`
+	Code      string `json:"code"`
+	QRPayload string `json:"qrPayload"`
+	ExpiresAt int64  `json:"expiresAt"`
+}
+
+// PairDeviceRequest приложение на телефоне обменивает код на учетные данные устройства
+type PairDeviceRequest struct {
+	Code        string `json:"code" binding:"required"`
+	DeviceModel string `json:"deviceModel"`
+	AppVersion  string `json:"appVersion"`
+}
+
+// PairDeviceResponse учетные данные устройства, отдаются один раз.
+// Приложение присылает их в заголовке Authorization: Device <credential>.
+type PairDeviceResponse struct {
+	Credential string `json:"credential"`
+	DeviceID   string `json:"deviceId"`
+	TraderID   string `json:"traderId"`
+	PairedAt   int64  `json:"pairedAt"`
+}
+
+// DeviceCredential сопряженный телефон устройства без секрета
+type DeviceCredential struct {
+	DeviceID    string `json:"deviceId"`
+	DeviceModel string `json:"deviceModel,omitempty"`
+	AppVersion  string `json:"appVersion,omitempty"`
+	PairedAt    int64  `json:"pairedAt"`
+	LastSeenAt  int64  `json:"lastSeenAt"`
+}
+
+type GetDeviceCredentialsResponse struct {
+	Credentials []DeviceCredential `json:"credentials"`
+}
+
+type RevokeDeviceCredentialResponse struct {
+	DeviceID string `json:"deviceId"`
+}
@@ -0,0 +1,20 @@
+package evidence
+
+// Evidence загруженный файл и подписанная ссылка на скачивание, действующая до url_expires_at
+type Evidence struct {
+	ID           string `json:"id"`
+	OrderID      string `json:"order_id"`
+	DisputeID    string `json:"dispute_id,omitempty"`
+	UploadedBy   string `json:"uploaded_by"`
+	FileName     string `json:"file_name"`
+	ContentType  string `json:"content_type"`
+	Size         int64  `json:"size"`
+	SHA256       string `json:"sha256"`
+	CreatedAt    int64  `json:"created_at"`
+	URL          string `json:"url"`
+	URLExpiresAt int64  `json:"url_expires_at"`
+}
+
+type ListResponse struct {
+	Evidence []Evidence `json:"evidence"`
+}
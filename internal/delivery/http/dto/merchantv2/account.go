@@ -0,0 +1,24 @@
+package merchantv2
+
+type BalanceResponse struct {
+	Balances []Balance `json:"balances"`
+}
+
+type Balance struct {
+	Name     string  `json:"name"`
+	Balance  float64 `json:"balance"`
+	Currency string  `json:"currency"`
+}
+
+type WithdrawRequest struct {
+	Amount    float64 `json:"amount" binding:"required,gt=0"`
+	Currency  string  `json:"currency" binding:"required"`
+	ToAddress string  `json:"toAddress" binding:"required"`
+}
+
+type WithdrawResponse struct {
+	TxHash    string  `json:"txHash"`
+	Amount    float64 `json:"amount"`
+	Currency  string  `json:"currency"`
+	ToAddress string  `json:"toAddress"`
+}
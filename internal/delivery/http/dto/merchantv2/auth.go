@@ -0,0 +1,14 @@
+package merchantv2
+
+import "time"
+
+type SignInRequest struct {
+	Login     string `json:"login" binding:"required"`
+	Password  string `json:"password" binding:"required"`
+	TwoFaCode string `json:"twoFaCode"`
+}
+
+type SignInResponse struct {
+	Token     string    `json:"token"`
+	ExpiresAt time.Time `json:"expiresAt"`
+}
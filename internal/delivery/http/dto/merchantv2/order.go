@@ -0,0 +1,57 @@
+package merchantv2
+
+import "time"
+
+type CreatePayInRequest struct {
+	MerchantOrderID string  `json:"merchantOrderId" binding:"required"`
+	Amount          float64 `json:"amount" binding:"required,gt=0"`
+	Currency        string  `json:"currency" binding:"required"`
+	PaymentSystem   string  `json:"paymentSystem" binding:"required,oneof=SBP C2C"`
+	BankCode        string  `json:"bankCode"`
+	NspkCode        string  `json:"nspkCode"`
+	CallbackURL     string  `json:"callbackUrl"`
+	ClientID        string  `json:"clientId"`
+}
+
+type Order struct {
+	ID              string     `json:"id"`
+	MerchantOrderID string     `json:"merchantOrderId"`
+	Status          string     `json:"status"`
+	Amount          float64    `json:"amount"`
+	AmountCrypto    float64    `json:"amountCrypto"`
+	Currency        string     `json:"currency"`
+	CurrencyRate    float64    `json:"currencyRate"`
+	Recalculated    bool       `json:"recalculated"`
+	PaymentSystem   string     `json:"paymentSystem"`
+	CallbackURL     string     `json:"callbackUrl"`
+	Requisites      Requisites `json:"requisites"`
+	ExpiresAt       time.Time  `json:"expiresAt"`
+}
+
+type Requisites struct {
+	CardNumber string `json:"cardNumber"`
+	Phone      string `json:"phone"`
+	HolderName string `json:"holderName"`
+	BankCode   string `json:"bankCode"`
+	BankName   string `json:"bankName"`
+	NspkCode   string `json:"nspkCode"`
+}
+
+type OrderStatusResponse struct {
+	ID              string `json:"id"`
+	MerchantOrderID string `json:"merchantOrderId"`
+	Status          string `json:"status"`
+}
+
+type GetOrdersParams struct {
+	DealID           *string    `form:"dealId"`
+	Type             *string    `form:"type"`
+	Status           *string    `form:"status"`
+	TimeOpeningStart *time.Time `form:"timeOpeningStart" time_format:"2006-01-02T15:04:05Z"`
+	TimeOpeningEnd   *time.Time `form:"timeOpeningEnd" time_format:"2006-01-02T15:04:05Z"`
+	AmountMin        *float64   `form:"amountMin"`
+	AmountMax        *float64   `form:"amountMax"`
+	Page             *int       `form:"page"`
+	Size             *int       `form:"size"`
+	Sort             *string    `form:"sort"`
+}
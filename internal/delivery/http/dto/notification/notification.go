@@ -0,0 +1,60 @@
+package notification
+
+// Target адрес пользователя в канале: chat_id телеграма, email или https URL вебхука
+type Target struct {
+	Channel string `json:"channel" binding:"required"`
+	Address string `json:"address" binding:"required"`
+	Enabled bool   `json:"enabled"`
+}
+
+// QuietHours время HH:MM в часовом поясе timezone (IANA), start > end - интервал через полночь
+type QuietHours struct {
+	Start    string `json:"start" binding:"required"`
+	End      string `json:"end" binding:"required"`
+	Timezone string `json:"timezone"`
+}
+
+// UpdatePreferencesRequest подписка целиком заменяет предыдущую. Пустой event_types - все события.
+type UpdatePreferencesRequest struct {
+	Targets    []Target    `json:"targets" binding:"dive"`
+	EventTypes []string    `json:"event_types"`
+	QuietHours *QuietHours `json:"quiet_hours"`
+}
+
+// AdminUpdatePreferencesRequest подписка пользователя, которую задает админ.
+// all_traders - получать события всех трейдеров.
+type AdminUpdatePreferencesRequest struct {
+	UpdatePreferencesRequest
+	AllTraders bool `json:"all_traders"`
+}
+
+type PreferencesResponse struct {
+	UserID     string      `json:"user_id"`
+	Targets    []Target    `json:"targets"`
+	EventTypes []string    `json:"event_types"`
+	QuietHours *QuietHours `json:"quiet_hours,omitempty"`
+	AllTraders bool        `json:"all_traders"`
+	UpdatedAt  int64       `json:"updated_at,omitempty"`
+	// доступные каналы и типы событий для формы подписки
+	AvailableChannels   []string `json:"available_channels"`
+	AvailableEventTypes []string `json:"available_event_types"`
+}
+
+type Delivery struct {
+	ID        string `json:"id"`
+	UserID    string `json:"user_id"`
+	EventType string `json:"event_type"`
+	TraderID  string `json:"trader_id,omitempty"`
+	Channel   string `json:"channel"`
+	Address   string `json:"address"`
+	Subject   string `json:"subject"`
+	Body      string `json:"body"`
+	Status    string `json:"status"`
+	Attempts  int    `json:"attempts"`
+	Error     string `json:"error,omitempty"`
+	CreatedAt int64  `json:"created_at"`
+}
+
+type HistoryResponse struct {
+	Deliveries []Delivery `json:"deliveries"`
+}
@@ -4,4 +4,11 @@ type CreateDeeplinkRequest struct {
 	OrderID     string  `json:"order_id" binding:"required"`
 	BankCode    string  `json:"bank_code" binding:"required"`
 	PhoneNumber *string `json:"phone_number,omitempty"`
-}
\ No newline at end of file
+}
+// DeeplinkEventRequest событие воронки, которое страница диплинка отправляет через sendBeacon
+type DeeplinkEventRequest struct {
+	Type     string `json:"type" binding:"required,oneof=page_viewed bank_chosen scheme_attempted app_opened app_timeout"`
+	BankCode string `json:"bank_code,omitempty"`
+	Scheme   string `json:"scheme,omitempty"`
+	Platform string `json:"platform,omitempty"`
+}
@@ -1,7 +1,37 @@
 package response
 
+import "github.com/LavaJover/shvark-api-gateway/internal/domain"
+
 type DeeplinkResponse struct {
 	HTMLContent string `json:"html_content,omitempty"`
 	RedirectURL string `json:"redirect_url,omitempty"`
 	DeeplinkURL string `json:"deeplink_url,omitempty"`
-}
\ No newline at end of file
+}
+// DeeplinkRequisitesResponse полные реквизиты для страниц с политикой on_demand
+type DeeplinkRequisitesResponse struct {
+	CardNumber  string                `json:"card_number,omitempty"`
+	PhoneNumber string                `json:"phone_number,omitempty"`
+	Deeplinks   []domain.DeeplinkLink `json:"deeplinks"`
+}
+
+// DeeplinkOptionsResponse банки и ссылки на их приложения для нативных приложений мерчантов
+type DeeplinkOptionsResponse struct {
+	OrderID       string                 `json:"order_id"`
+	Amount        string                 `json:"amount"`
+	Currency      string                 `json:"currency"`
+	PaymentSystem string                 `json:"payment_system"`
+	Banks         []DeeplinkBankResponse `json:"banks"`
+}
+
+// DeeplinkBankResponse ссылки одного банка. Ссылки платформы пробуются по порядку,
+// если ни одна не открылась - магазин приложений, затем page_url
+type DeeplinkBankResponse struct {
+	BankCode     string                           `json:"bank_code"`
+	BankName     string                           `json:"bank_name"`
+	Icon         string                           `json:"icon,omitempty"`
+	TransferType string                           `json:"transfer_type,omitempty"`
+	AppStoreURL  string                           `json:"app_store_url,omitempty"`
+	PlayStoreURL string                           `json:"play_store_url,omitempty"`
+	PageURL      string                           `json:"page_url"`
+	Platforms    map[string][]domain.DeeplinkLink `json:"platforms"`
+}
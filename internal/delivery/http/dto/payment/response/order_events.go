@@ -0,0 +1,7 @@
+package response
+
+type OrderEventsTokenResponse struct {
+	Token     string `json:"token"`
+	ExpiresAt int64  `json:"expires_at"`
+	EventsURL string `json:"events_url"`
+}
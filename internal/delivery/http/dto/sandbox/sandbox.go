@@ -0,0 +1,40 @@
+package sandbox
+
+type IssueCredentialRequest struct {
+	Name string `json:"name" binding:"max=64"`
+}
+
+// IssueCredentialResponse ключ отдается один раз, дальше доступен только его ID
+type IssueCredentialResponse struct {
+	Key        string     `json:"key"`
+	Credential Credential `json:"credential"`
+}
+
+// Credential ключ песочницы без секрета
+type Credential struct {
+	ID         string `json:"id"`
+	Name       string `json:"name,omitempty"`
+	CreatedAt  int64  `json:"created_at"`
+	LastUsedAt int64  `json:"last_used_at,omitempty"`
+}
+
+type CredentialsResponse struct {
+	Credentials []Credential `json:"credentials"`
+}
+
+// Webhook результат отправки вебхука на callback_url ордера
+type Webhook struct {
+	URL        string `json:"url"`
+	StatusCode int    `json:"status_code,omitempty"`
+	Error      string `json:"error,omitempty"`
+	SentAt     int64  `json:"sent_at"`
+}
+
+type TransitionResponse struct {
+	OrderID         string  `json:"order_id"`
+	MerchantOrderID string  `json:"merchant_order_id"`
+	Status          string  `json:"status"`
+	AmountFiat      float64 `json:"amount_fiat"`
+	AmountCrypto    float64 `json:"amount_crypto"`
+	Webhook         Webhook `json:"webhook"`
+}
@@ -0,0 +1,61 @@
+package teamlead
+
+import "time"
+
+type OrderStats struct {
+	TotalOrders           int64   `json:"total_orders"`
+	SucceedOrders         int64   `json:"succeed_orders"`
+	CanceledOrders        int64   `json:"canceled_orders"`
+	SuccessRate           float64 `json:"success_rate"`
+	ProcessedAmountFiat   float64 `json:"processed_amount_fiat"`
+	ProcessedAmountCrypto float64 `json:"processed_amount_crypto"`
+	CanceledAmountFiat    float64 `json:"canceled_amount_fiat"`
+	CanceledAmountCrypto  float64 `json:"canceled_amount_crypto"`
+	IncomeCrypto          float64 `json:"income_crypto"`
+}
+
+// BankDetailStats счетчики за текущие сутки и месяц, не за период запроса
+type BankDetailStats struct {
+	BankDetails int     `json:"bank_details"`
+	CountToday  int64   `json:"count_today"`
+	CountMonth  int64   `json:"count_month"`
+	AmountToday float64 `json:"amount_today"`
+	AmountMonth float64 `json:"amount_month"`
+}
+
+type DeviceStats struct {
+	Total   int `json:"total"`
+	Online  int `json:"online"`
+	Enabled int `json:"enabled"`
+	Offline int `json:"offline"`
+}
+
+type Summary struct {
+	Traders          int             `json:"traders"`
+	Orders           OrderStats      `json:"orders"`
+	BankDetails      BankDetailStats `json:"bank_details"`
+	Devices          DeviceStats     `json:"devices"`
+	CommissionProfit float64         `json:"commission_profit"`
+}
+
+// TraderStats failed_sections - разделы, которые не удалось получить, они не входят в summary
+type TraderStats struct {
+	TraderID         string          `json:"trader_id"`
+	Commission       float64         `json:"commission"`
+	Orders           OrderStats      `json:"orders"`
+	BankDetails      BankDetailStats `json:"bank_details"`
+	Devices          DeviceStats     `json:"devices"`
+	CommissionProfit float64         `json:"commission_profit"`
+	FailedSections   []string        `json:"failed_sections,omitempty"`
+}
+
+type StatsResponse struct {
+	TeamLeadID  string        `json:"team_lead_id"`
+	From        time.Time     `json:"from"`
+	To          time.Time     `json:"to"`
+	GeneratedAt time.Time     `json:"generated_at"`
+	Cached      bool          `json:"cached"`
+	Partial     bool          `json:"partial"`
+	Summary     Summary       `json:"summary"`
+	Traders     []TraderStats `json:"traders"`
+}
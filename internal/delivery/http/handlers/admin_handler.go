@@ -1,6 +1,8 @@
 package handlers
 
 import (
+	"context"
+	"log"
 	"net/http"
 	"time"
 
@@ -8,6 +10,8 @@ import (
 	adminRequest "github.com/LavaJover/shvark-api-gateway/internal/delivery/http/dto/admin/request"
 	adminResponse "github.com/LavaJover/shvark-api-gateway/internal/delivery/http/dto/admin/response"
 	orderResponse "github.com/LavaJover/shvark-api-gateway/internal/delivery/http/dto/order/response"
+	"github.com/LavaJover/shvark-api-gateway/internal/domain"
+	"github.com/LavaJover/shvark-api-gateway/internal/service"
 	orderpb "github.com/LavaJover/shvark-order-service/proto/gen/order"
 	userpb "github.com/LavaJover/shvark-user-service/proto/gen"
 	"github.com/gin-gonic/gin"
@@ -20,6 +24,9 @@ type AdminHandler struct {
 	OrderClient *client.OrderClient
 	WalletClient *client.HTTPWalletClient
 	UserClient *client.UserClient
+	TraderEvents *service.TraderEventPublisher
+	Onboarding *service.OnboardingService
+	DisputeSLA *service.DisputeSLAService
 }
 
 func NewAdminHandler(
@@ -28,6 +35,9 @@ func NewAdminHandler(
 	orderClient *client.OrderClient,
 	walletClient *client.HTTPWalletClient,
 	userClient *client.UserClient,
+	traderEvents *service.TraderEventPublisher,
+	onboarding *service.OnboardingService,
+	disputeSLA *service.DisputeSLAService,
 ) *AdminHandler {
 	return &AdminHandler{
 		SSOClient: ssoClient,
@@ -35,11 +45,14 @@ func NewAdminHandler(
 		OrderClient: orderClient,
 		WalletClient: walletClient,
 		UserClient: userClient,
+		TraderEvents: traderEvents,
+		Onboarding: onboarding,
+		DisputeSLA: disputeSLA,
 	}
 }
 
 // @Summary Create new team
-// @Description Create new team
+// @Description Create new team through the onboarding saga: register, assign role, create wallet and optional default traffic. On failure the saga stops and can be resumed or aborted at /admin/onboardings.
 // @Tags admin
 // @Security BearerAuth
 // @Accept json
@@ -47,7 +60,7 @@ func NewAdminHandler(
 // @Param input body adminRequest.CreateTeamRequest true "team credentials"
 // @Success 201 {object} adminResponse.CreateTeamResponse
 // @Failure 400 {object} ErrorResponse
-// @Failure 502 {object} ErrorResponse
+// @Failure 502 {object} adminResponse.OnboardingErrorResponse
 // @Router /admin/teams/create [post]
 func (h *AdminHandler) CreateTeam(c *gin.Context) {
 	var request adminRequest.CreateTeamRequest
@@ -55,39 +68,21 @@ func (h *AdminHandler) CreateTeam(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
 		return
 	}
-	// register in sso
-	registerResponse, err := h.SSOClient.Register(
-		request.Login,
-		request.Username,
-		request.Password,
-		"TRADER",
-	)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: err.Error()})
-		return
-	}
-	// login to get access token
-	loginResponse, err := h.SSOClient.Login(request.Login, request.Password, "")
-	if err != nil {
-		c.JSON(http.StatusBadGateway, ErrorResponse{Error: err.Error()})
-		return
-	}
-	// create wallet for new trader
-	walletAddress, err := h.WalletClient.CreateWallet(registerResponse.UserId)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: err.Error()})
+	onboarding, accessToken, ok := h.onboard(c, domain.OnboardingTeam, request.Login, request.Username, request.Password, request.DefaultTraffic)
+	if !ok {
 		return
 	}
 
 	c.JSON(http.StatusCreated, adminResponse.CreateTeamResponse{
-		TraderID: registerResponse.UserId,
-		AccessToken: loginResponse.AccessToken,
-		WalletAddress: walletAddress,
+		TraderID: onboarding.UserID,
+		AccessToken: accessToken,
+		WalletAddress: onboarding.WalletAddress,
+		OnboardingID: onboarding.ID,
 	})
 }
 
 // @Summary Create merchant account
-// @Description Create merchant account
+// @Description Create merchant account through the onboarding saga: register, assign role, create wallet and optional default traffic. On failure the saga stops and can be resumed or aborted at /admin/onboardings.
 // @Tags admin
 // @Security BearerAuth
 // @Accept json
@@ -95,7 +90,7 @@ func (h *AdminHandler) CreateTeam(c *gin.Context) {
 // @Param input body adminRequest.CreateMerchantRequest true "merchant credentials"
 // @Success 201 {object} adminResponse.CreateMerchantResponse
 // @Failure 400 {object} ErrorResponse
-// @Failure 502 {object} ErrorResponse
+// @Failure 502 {object} adminResponse.OnboardingErrorResponse
 // @Router /admin/merchants/create [post]
 func (h *AdminHandler) CreateMerchant(c *gin.Context) {
 	var request adminRequest.CreateMerchantRequest
@@ -103,34 +98,73 @@ func (h *AdminHandler) CreateMerchant(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
 		return
 	}
-	// register in sso
-	registerResponse, err := h.SSOClient.Register(
-		request.Login,
-		request.Username,
-		request.Password,
-		"MERCHANT",
-	)
-	if err != nil {
-		c.JSON(http.StatusBadGateway, ErrorResponse{Error: err.Error()})
+	onboarding, accessToken, ok := h.onboard(c, domain.OnboardingMerchant, request.Login, request.Username, request.Password, request.DefaultTraffic)
+	if !ok {
 		return
 	}
-	loginResponse, err := h.SSOClient.Login(request.Login, request.Password, "")
-	if err != nil {
-		c.JSON(http.StatusBadGateway, ErrorResponse{Error: err.Error()})
-		return
+
+	c.JSON(http.StatusCreated, adminResponse.CreateMerchantResponse{
+		MerchantID: onboarding.UserID,
+		AccessToken: accessToken,
+		WalletAddress: onboarding.WalletAddress,
+		OnboardingID: onboarding.ID,
+	})
+}
+
+// onboard проводит сагу онбординга и выдает токен нового пользователя. Вход не входит в сагу:
+// пароль не сохраняется, а пользователь уже заведен, поэтому ошибка входа не считается ошибкой
+// онбординга и токен просто не возвращается. При ошибке ответ уже отправлен.
+func (h *AdminHandler) onboard(
+	c *gin.Context,
+	kind domain.OnboardingKind,
+	login, username, password string,
+	trafficRequest *adminRequest.OnboardingTrafficRequest,
+) (domain.Onboarding, string, bool) {
+	var traffic *domain.OnboardingTraffic
+	if trafficRequest != nil {
+		duration, err := time.ParseDuration(trafficRequest.MerchantDealsDuration)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "failed to parse deals time parameter"})
+			return domain.Onboarding{}, "", false
+		}
+		traffic = &domain.OnboardingTraffic{
+			CounterpartID: trafficRequest.CounterpartID,
+			Name: trafficRequest.Name,
+			TraderRewardPercent: trafficRequest.TraderReward,
+			PlatformFee: trafficRequest.PlatformFee,
+			TraderPriority: trafficRequest.TraderPriority,
+			MerchantDealsDuration: duration,
+			AntifraudRequired: trafficRequest.AntifraudRequired,
+		}
 	}
-	// create wallet for merchant
-	walletAddress, err := h.WalletClient.CreateWallet(registerResponse.UserId)
+
+	// сага не должна обрываться на середине из-за отключившегося клиента
+	onboarding, err := h.Onboarding.Start(context.WithoutCancel(c.Request.Context()), service.OnboardingParams{
+		Kind: kind,
+		Login: login,
+		Username: username,
+		Password: password,
+		Traffic: traffic,
+		CreatedBy: c.GetString("userID"),
+	})
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: err.Error()})
-		return
+		if onboarding.ID == "" {
+			c.JSON(http.StatusInternalServerError, ErrorResponse{Error: err.Error()})
+			return domain.Onboarding{}, "", false
+		}
+		c.JSON(http.StatusBadGateway, adminResponse.OnboardingErrorResponse{
+			Error: err.Error(),
+			Onboarding: onboardingResponse(onboarding),
+		})
+		return domain.Onboarding{}, "", false
 	}
 
-	c.JSON(http.StatusCreated, adminResponse.CreateMerchantResponse{
-		MerchantID: registerResponse.UserId,
-		AccessToken: loginResponse.AccessToken,
-		WalletAddress: walletAddress,
-	})
+	loginResponse, err := h.SSOClient.Login(login, password, "")
+	if err != nil {
+		log.Printf("onboarding %s completed, but login of %s failed: %v", onboarding.ID, login, err)
+		return onboarding, "", true
+	}
+	return onboarding, loginResponse.AccessToken, true
 }
 
 // @Summary Create new traffic
@@ -257,6 +291,7 @@ func (h *AdminHandler) EditTraffic(c *gin.Context) {
 // @Tags admin
 // @Accept json
 // @Produce json
+// @Security BearerAuth
 // @Param trafficId path string true "traffic ID"
 // @Failure 404 {object} ErrorResponse
 // @Router /admin/traffic/{trafficId} [delete]
@@ -353,10 +388,15 @@ func (h *AdminHandler) CreateDispute(c *gin.Context) {
 		return
 	}
 
-	disputeTtl, err := time.ParseDuration(request.Ttl)
-	if err != nil {
-		c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
-		return
+	// без ttl спор создается со сроком из настроек SLA
+	disputeTtl := h.DisputeSLA.TTL()
+	if request.Ttl != "" {
+		var err error
+		disputeTtl, err = time.ParseDuration(request.Ttl)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+			return
+		}
 	}
 
 	disputeID, err := h.OrderClient.CreateDispute(
@@ -370,6 +410,8 @@ func (h *AdminHandler) CreateDispute(c *gin.Context) {
 		c.JSON(http.StatusBadGateway, ErrorResponse{Error: err.Error()})
 		return
 	}
+	h.TraderEvents.DisputeOpened(request.OrderID, disputeID)
+	h.DisputeSLA.Opened(request.OrderID, disputeID, disputeTtl)
 
 	c.JSON(http.StatusCreated, adminResponse.CreateDisputeResponse{
 		DisputeID: disputeID,
@@ -396,6 +438,7 @@ func (h *AdminHandler) AcceptDispute(c *gin.Context) {
 		c.JSON(http.StatusBadGateway, ErrorResponse{Error: err.Error()})
 		return
 	}
+	h.DisputeSLA.Closed(request.DisputeID)
 	c.JSON(http.StatusOK, adminResponse.AcceptDisputeResponse{})
 }
 
@@ -419,6 +462,7 @@ func (h *AdminHandler) RejectDispute(c *gin.Context) {
 		c.JSON(http.StatusBadGateway, ErrorResponse{Error: err.Error()})
 		return
 	}
+	h.DisputeSLA.Closed(request.DisputeID)
 	c.JSON(http.StatusOK, adminResponse.RejectDisputeResponse{})
 }
 
@@ -427,6 +471,7 @@ func (h *AdminHandler) RejectDispute(c *gin.Context) {
 // @Tags admin
 // @Accept json
 // @Produce json
+// @Security BearerAuth
 // @Param id path string true "dispute ID"
 // @Success 200 {object} adminResponse.GetDisputeInfoResponse
 // @Failure 400 {object} ErrorResponse
@@ -478,6 +523,8 @@ func (h *AdminHandler) FreezeDispute(c *gin.Context) {
 		c.JSON(http.StatusBadGateway, ErrorResponse{Error: err.Error()})
 		return
 	}
+	h.TraderEvents.DisputeFrozen(request.DisputeID)
+	h.DisputeSLA.Frozen(request.DisputeID)
 
 	c.JSON(http.StatusOK, adminResponse.FreezeDisputeResponse{})
 }
@@ -487,6 +534,7 @@ func (h *AdminHandler) FreezeDispute(c *gin.Context) {
 // @Tags admin
 // @Accept json
 // @Produce json
+// @Security BearerAuth
 // @Success 200 {object} adminResponse.GetUsersResponse
 // @Failure 502 {object} ErrorResponse
 // @Router /admin/traders [get]
@@ -518,6 +566,7 @@ func (h *AdminHandler) GetTraders(c *gin.Context) {
 // @Tags admin
 // @Accept json
 // @Produce json
+// @Security BearerAuth
 // @Success 200 {object} adminResponse.GetUsersResponse
 // @Failure 502 {object} ErrorResponse
 // @Router /admin/merchants [get]
@@ -549,6 +598,7 @@ func (h *AdminHandler) GetMerchants(c *gin.Context) {
 // @Tags admin
 // @Accept json
 // @Produce json
+// @Security BearerAuth
 // @Param page query int false "Page number" default(1) minimum(1)
 // @Param limit query int false "Items per page" default(10) minimum(1) maximum(100)
 // @Param status query string false "Filter by status"
@@ -627,6 +677,7 @@ func (h *AdminHandler) GetOrderDisputes(c *gin.Context) {
 // @Tags admin
 // @Accept json
 // @Produce json
+// @Security BearerAuth
 // @Param input body adminRequest.SetWithdrawalRulesRequest true "Withdrawal rules"
 // @Success 200 {object} adminResponse.SetWithdrawalRulesResponse
 // @Failure 500 {object} ErrorResponse
@@ -670,6 +721,7 @@ func (h *AdminHandler) SetWithdrawalRules(c *gin.Context) {
 // @Tags admin
 // @Accept json
 // @Produce json
+// @Security BearerAuth
 // @Param userId path string true "user ID"
 // @Success 200 {object} adminResponse.GetWithdrawalRulesResponse
 // @Success 400 {object} ErrorResponse
@@ -706,6 +758,7 @@ func (h *AdminHandler) GetUserWithdrawalRules(c *gin.Context) {
 // @Tags admin
 // @Accept json
 // @Produce json
+// @Security BearerAuth
 // @Param userId path string true "user ID"
 // @Success 200 {object} adminResponse.DeleteWithdrawalRulesResponse
 // @Failure 500 {object} ErrorResponse
@@ -731,6 +784,7 @@ func (h *AdminHandler) DeleteUserWithdrawalRules(c *gin.Context) {
 // @Tags admin
 // @Accept json
 // @Produce json
+// @Security BearerAuth
 // @Param input body adminRequest.CreateTeamRelationRequest true "new relation"
 // @Success 201 {string} string "Success"
 // @Failure 400 {object} ErrorResponse
@@ -763,6 +817,7 @@ func (h *AdminHandler) CreateTeamRelation(c *gin.Context) {
 // @Tags admin
 // @Accept json
 // @Produce json
+// @Security BearerAuth
 // @Param input body adminRequest.UpdateTeamRelationRequest true "update relation"
 // @Success 200 {string} string "Success"
 // @Failure 400 {object} ErrorResponse
@@ -796,9 +851,11 @@ func (h *AdminHandler) UpdateRelationParams(c *gin.Context) {
 // @Tags admin
 // @Accept json
 // @Produce json
+// @Security BearerAuth
 // @Param teamLeadID path string true "teamLeadID"
 // @Success 200 {object} adminResponse.TeamRelationsResponse
 // @Failure 400 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
 // @Failure 500 {object} ErrorResponse
 // @Router /admin/teams/relations/team-lead/{teamLeadID} [get]
 func (h *AdminHandler) GetRelationsByTeamLeadID(c *gin.Context) {
@@ -840,6 +897,7 @@ func (h *AdminHandler) GetRelationsByTeamLeadID(c *gin.Context) {
 // @Tags admin
 // @Accept json
 // @Produce json
+// @Security BearerAuth
 // @Param relationID path string true "id of relationship"
 // @Success 200 {string} string "Success"
 // @Failure 404 {object} ErrorResponse
@@ -864,6 +922,7 @@ func (h *AdminHandler) DeleteTeamRelationship(c *gin.Context) {
 // @Tags admin
 // @Accept json
 // @Produce json
+// @Security BearerAuth
 // @Param traderID path string true "trader ID to be promoted to teamlead"
 // @Success 200 {string} string "Success"
 // @Failure 404 {object} ErrorResponse
@@ -893,6 +952,7 @@ func (h *AdminHandler) PromoteToTeamLead(c *gin.Context) {
 // @Tags admin
 // @Accept json
 // @Produce json
+// @Security BearerAuth
 // @Param teamleadID path string true "teamlead ID to be demoted"
 // @Success 200 {string} string "Success"
 // @Failure 404 {object} ErrorResponse
@@ -922,6 +982,7 @@ func (h *AdminHandler) DemoteTeamLead(c *gin.Context) {
 // @Tags admin
 // @Accept json
 // @Produce json
+// @Security BearerAuth
 // @Param role query string false "user role"
 // @Success 200 {object} adminResponse.GetUsersResponse
 // @Failure 404 {object} ErrorResponse
@@ -1,11 +1,14 @@
 package handlers
 
 import (
+    "log"
     "net/http"
     "strconv"
+    "strings"
     "time"
 
     "github.com/LavaJover/shvark-api-gateway/internal/client"
+    "github.com/LavaJover/shvark-api-gateway/internal/service"
     antifraudpb "github.com/LavaJover/shvark-order-service/proto/gen/order"
     "github.com/gin-gonic/gin"
     "google.golang.org/protobuf/types/known/structpb"
@@ -13,12 +16,14 @@ import (
 )
 
 type AntiFraudHandler struct {
-    orderClient *client.OrderClient
+    orderClient  *client.OrderClient
+    traderEvents *service.TraderEventPublisher
 }
 
-func NewAntiFraudHandler(orderClient *client.OrderClient) *AntiFraudHandler {
+func NewAntiFraudHandler(orderClient *client.OrderClient, traderEvents *service.TraderEventPublisher) *AntiFraudHandler {
     return &AntiFraudHandler{
-        orderClient: orderClient,
+        orderClient:  orderClient,
+        traderEvents: traderEvents,
     }
 }
 
@@ -29,6 +34,7 @@ func NewAntiFraudHandler(orderClient *client.OrderClient) *AntiFraudHandler {
 // @Tags antifraud
 // @Accept json
 // @Produce json
+// @Security BearerAuth
 // @Param traderID path string true "Trader ID"
 // @Success 200 {object} CheckTraderResponse
 // @Failure 400 {object} ErrorResponse
@@ -53,10 +59,11 @@ func (h *AntiFraudHandler) CheckTrader(c *gin.Context) {
 }
 
 // @Summary Process trader check
-// @Description Check trader and update traffic status
+// @Description Check trader and update traffic status. If the trader fails the check, the traffic is locked and the trader gets an antifraud_locked event with the failed rules as the reason.
 // @Tags antifraud
 // @Accept json
 // @Produce json
+// @Security BearerAuth
 // @Param traderID path string true "Trader ID"
 // @Success 200 {object} ProcessTraderCheckResponse
 // @Failure 400 {object} ErrorResponse
@@ -77,12 +84,31 @@ func (h *AntiFraudHandler) ProcessTraderCheck(c *gin.Context) {
         return
     }
 
+    if response.Success {
+        h.notifyAntifraudLock(traderID)
+    }
+
     c.JSON(http.StatusOK, ProcessTraderCheckResponse{
         Success: response.Success,
         Message: response.Message,
     })
 }
 
+// notifyAntifraudLock сообщает трейдеру о блокировке, если обработанная проверка не пройдена.
+// ProcessTraderCheck не возвращает результат проверки, поэтому он запрашивается отдельно.
+func (h *AntiFraudHandler) notifyAntifraudLock(traderID string) {
+    check, err := h.orderClient.CheckTrader(&antifraudpb.CheckTraderRequest{
+        TraderId: traderID,
+    })
+    if err != nil {
+        log.Printf("antifraud: failed to check trader %s after processing: %v", traderID, err)
+        return
+    }
+    if !check.AllPassed {
+        h.traderEvents.AntifraudLocked(traderID, strings.Join(check.FailedRules, ", "))
+    }
+}
+
 // ============= УПРАВЛЕНИЕ ПРАВИЛАМИ =============
 
 // @Summary Create antifraud rule
@@ -90,6 +116,7 @@ func (h *AntiFraudHandler) ProcessTraderCheck(c *gin.Context) {
 // @Tags antifraud
 // @Accept json
 // @Produce json
+// @Security BearerAuth
 // @Param request body CreateRuleRequest true "Rule data"
 // @Success 200 {object} CreateRuleResponse
 // @Failure 400 {object} ErrorResponse
@@ -127,6 +154,7 @@ func (h *AntiFraudHandler) CreateRule(c *gin.Context) {
 // @Tags antifraud
 // @Accept json
 // @Produce json
+// @Security BearerAuth
 // @Param ruleID path string true "Rule ID"
 // @Param request body UpdateRuleRequest true "Update data"
 // @Success 200 {object} UpdateRuleResponse
@@ -185,6 +213,7 @@ func (h *AntiFraudHandler) UpdateRule(c *gin.Context) {
 // @Tags antifraud
 // @Accept json
 // @Produce json
+// @Security BearerAuth
 // @Param active_only query bool false "Get only active rules"
 // @Success 200 {object} GetRulesResponse
 // @Failure 502 {object} ErrorResponse
@@ -219,6 +248,7 @@ func (h *AntiFraudHandler) GetRules(c *gin.Context) {
 // @Tags antifraud
 // @Accept json
 // @Produce json
+// @Security BearerAuth
 // @Param ruleID path string true "Rule ID"
 // @Success 200 {object} AntiFraudRuleResponse
 // @Failure 400 {object} ErrorResponse
@@ -248,6 +278,7 @@ func (h *AntiFraudHandler) GetRule(c *gin.Context) {
 // @Tags antifraud
 // @Accept json
 // @Produce json
+// @Security BearerAuth
 // @Param ruleID path string true "Rule ID"
 // @Success 200 {object} DeleteRuleResponse
 // @Failure 400 {object} ErrorResponse
@@ -281,6 +312,7 @@ func (h *AntiFraudHandler) DeleteRule(c *gin.Context) {
 // @Tags antifraud
 // @Accept json
 // @Produce json
+// @Security BearerAuth
 // @Param trader_id query string false "Filter by trader ID"
 // @Param from_date query string false "Filter from date (RFC3339)"
 // @Param to_date query string false "Filter to date (RFC3339)"
@@ -359,6 +391,7 @@ func (h *AntiFraudHandler) GetAuditLogs(c *gin.Context) {
 // @Tags antifraud
 // @Accept json
 // @Produce json
+// @Security BearerAuth
 // @Param traderID path string true "Trader ID"
 // @Param limit query int false "Limit results" default(10)
 // @Success 200 {object} GetTraderAuditHistoryResponse
@@ -560,6 +593,7 @@ type ResetGracePeriodResponse struct {
 // @Tags antifraud
 // @Accept json
 // @Produce json
+// @Security BearerAuth
 // @Param traderID path string true "Trader ID"
 // @Param request body ManualUnlockRequest true "Unlock data"
 // @Success 200 {object} ManualUnlockResponse
@@ -607,6 +641,7 @@ func (h *AntiFraudHandler) ManualUnlock(c *gin.Context) {
 // @Tags antifraud
 // @Accept json
 // @Produce json
+// @Security BearerAuth
 // @Param traderID path string true "Trader ID"
 // @Success 200 {object} ResetGracePeriodResponse
 // @Failure 400 {object} ErrorResponse
@@ -653,6 +688,7 @@ type GetUnlockHistoryResponse struct {
 // @Tags antifraud
 // @Accept json
 // @Produce json
+// @Security BearerAuth
 // @Param traderID path string true "Trader ID"
 // @Param limit query int false "Limit results" default(20)
 // @Success 200 {object} GetUnlockHistoryResponse
@@ -0,0 +1,223 @@
+package handlers
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/LavaJover/shvark-api-gateway/internal/delivery/http/dto/audit"
+	"github.com/LavaJover/shvark-api-gateway/internal/domain"
+	"github.com/LavaJover/shvark-api-gateway/internal/service"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	auditDefaultLimit = 100
+	auditMaxLimit     = 1000
+)
+
+type AuditHandler struct {
+	Audit *service.AuditLog
+	// ExportLimit сколько записей максимум выгружается за один экспорт
+	ExportLimit int
+}
+
+func NewAuditHandler(audit *service.AuditLog, exportLimit int) *AuditHandler {
+	return &AuditHandler{
+		Audit:       audit,
+		ExportLimit: exportLimit,
+	}
+}
+
+// @Summary Audit log
+// @Description Administrative and financial actions, newest first
+// @Tags admin
+// @Security BearerAuth
+// @Produce json
+// @Param actor_id query string false "who made the request"
+// @Param category query string false "admin, rbac, traffic, antifraud, banking, wallet, orders"
+// @Param route query string false "gin route, e.g. /api/v1/traffic/traders/:traderID"
+// @Param target_id query string false "ID of the affected object"
+// @Param outcome query string false "success, denied or failure"
+// @Param from query string false "RFC3339 start"
+// @Param to query string false "RFC3339 end"
+// @Param offset query int false "offset" default(0)
+// @Param limit query int false "page size" default(100) maximum(1000)
+// @Success 200 {object} audit.EntriesResponse
+// @Failure 400 {object} ErrorResponse
+// @Router /admin/audit [get]
+func (h *AuditHandler) GetEntries(c *gin.Context) {
+	filter, ok := auditFilter(c)
+	if !ok {
+		return
+	}
+	filter.Offset, _ = strconv.Atoi(c.DefaultQuery("offset", "0"))
+	filter.Limit, _ = strconv.Atoi(c.DefaultQuery("limit", strconv.Itoa(auditDefaultLimit)))
+	if filter.Offset < 0 || filter.Limit < 1 || filter.Limit > auditMaxLimit {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "offset must be >= 0, limit between 1 and 1000"})
+		return
+	}
+
+	entries, total := h.Audit.Query(filter)
+	response := audit.EntriesResponse{
+		Entries: make([]audit.Entry, len(entries)),
+		Total:   total,
+		Offset:  filter.Offset,
+		Limit:   filter.Limit,
+	}
+	for i, entry := range entries {
+		response.Entries[i] = auditEntryResponse(entry)
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// @Summary Export audit log
+// @Description Export filtered audit log as JSON Lines (one entry per line) or CSV. Snapshots and request bodies are embedded as JSON strings in CSV.
+// @Tags admin
+// @Security BearerAuth
+// @Produce plain
+// @Param format query string false "jsonl or csv" default(jsonl)
+// @Param actor_id query string false "who made the request"
+// @Param category query string false "admin, rbac, traffic, antifraud, banking, wallet, orders"
+// @Param route query string false "gin route"
+// @Param target_id query string false "ID of the affected object"
+// @Param outcome query string false "success, denied or failure"
+// @Param from query string false "RFC3339 start"
+// @Param to query string false "RFC3339 end"
+// @Success 200 {string} string
+// @Failure 400 {object} ErrorResponse
+// @Router /admin/audit/export [get]
+func (h *AuditHandler) Export(c *gin.Context) {
+	format := c.DefaultQuery("format", "jsonl")
+	if format != "jsonl" && format != "csv" {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "format must be jsonl or csv"})
+		return
+	}
+	filter, ok := auditFilter(c)
+	if !ok {
+		return
+	}
+	filter.Limit = h.ExportLimit
+
+	entries, total := h.Audit.Query(filter)
+	filename := "audit-" + time.Now().UTC().Format("20060102T150405Z") + "." + format
+	c.Header("Content-Disposition", `attachment; filename="`+filename+`"`)
+	// выгрузка обрезана лимитом: клиент сужает период и выгружает остаток отдельно
+	c.Header("X-Total-Count", strconv.Itoa(total))
+
+	if format == "jsonl" {
+		c.Header("Content-Type", "application/x-ndjson")
+		c.Status(http.StatusOK)
+		encoder := json.NewEncoder(c.Writer)
+		for _, entry := range entries {
+			if err := encoder.Encode(auditEntryResponse(entry)); err != nil {
+				return
+			}
+		}
+		return
+	}
+
+	c.Header("Content-Type", "text/csv; charset=utf-8")
+	c.Status(http.StatusOK)
+	writer := csv.NewWriter(c.Writer)
+	writer.Write([]string{
+		"id", "at", "actor_id", "actor_role", "ip", "category", "method", "route", "path",
+		"target_ids", "query", "request", "status_code", "outcome", "error", "before", "after", "duration_ms",
+	})
+	for _, entry := range entries {
+		writer.Write([]string{
+			entry.ID,
+			entry.At.UTC().Format(time.RFC3339),
+			entry.ActorID,
+			entry.ActorRole,
+			entry.IP,
+			entry.Category,
+			entry.Method,
+			entry.Route,
+			entry.Path,
+			auditCSVMap(entry.TargetIDs),
+			auditCSVMap(entry.Query),
+			string(entry.Request),
+			strconv.Itoa(entry.StatusCode),
+			string(entry.Outcome),
+			entry.Error,
+			string(entry.Before),
+			string(entry.After),
+			strconv.FormatInt(entry.DurationMs, 10),
+		})
+	}
+	writer.Flush()
+}
+
+// auditFilter фильтр журнала из query, при ошибке отвечает 400
+func auditFilter(c *gin.Context) (service.AuditFilter, bool) {
+	filter := service.AuditFilter{
+		ActorID:  c.Query("actor_id"),
+		Category: c.Query("category"),
+		Route:    c.Query("route"),
+		TargetID: c.Query("target_id"),
+	}
+	switch outcome := domain.AuditOutcome(c.Query("outcome")); outcome {
+	case "", domain.AuditSuccess, domain.AuditDenied, domain.AuditFailure:
+		filter.Outcome = outcome
+	default:
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "unknown outcome " + string(outcome)})
+		return filter, false
+	}
+	for _, bound := range []struct {
+		name  string
+		value *time.Time
+	}{
+		{"from", &filter.From},
+		{"to", &filter.To},
+	} {
+		value := c.Query(bound.name)
+		if value == "" {
+			continue
+		}
+		parsed, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, ErrorResponse{Error: "invalid " + bound.name + ": " + err.Error()})
+			return filter, false
+		}
+		*bound.value = parsed
+	}
+	return filter, true
+}
+
+func auditEntryResponse(entry domain.AuditEntry) audit.Entry {
+	return audit.Entry{
+		ID:         entry.ID,
+		At:         entry.At.Unix(),
+		ActorID:    entry.ActorID,
+		ActorRole:  entry.ActorRole,
+		IP:         entry.IP,
+		UserAgent:  entry.UserAgent,
+		Category:   entry.Category,
+		Method:     entry.Method,
+		Route:      entry.Route,
+		Path:       entry.Path,
+		TargetIDs:  entry.TargetIDs,
+		Query:      entry.Query,
+		Request:    entry.Request,
+		StatusCode: entry.StatusCode,
+		Outcome:    string(entry.Outcome),
+		Error:      entry.Error,
+		Before:     entry.Before,
+		After:      entry.After,
+		DurationMs: entry.DurationMs,
+	}
+}
+
+func auditCSVMap(values map[string]string) string {
+	pairs := make([]string, 0, len(values))
+	for key, value := range values {
+		pairs = append(pairs, key+"="+value)
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, ";")
+}
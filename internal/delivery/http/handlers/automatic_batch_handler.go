@@ -0,0 +1,132 @@
+package handlers
+
+import (
+	"log"
+	"net/http"
+	"sort"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+)
+
+// SMSBatchItem уведомление из очереди телефона с идентификатором, который сгенерировало приложение
+type SMSBatchItem struct {
+	ID string `json:"id"`
+	SMSRequest
+}
+
+// SMSBatchRequest уведомления, накопленные телефоном, пока он был без сети
+type SMSBatchRequest struct {
+	Items []SMSBatchItem `json:"items"`
+}
+
+// SmsBatch обрабатывает пачку уведомлений из очереди телефона
+// @Summary Process SMS notifications batch
+// @Description Process notifications buffered by the trader's phone while offline. Items are processed in received_at order
+// @Description with bounded concurrency. Every item gets its own result; items with retryable=true should stay in the
+// @Description phone queue and be sent again, the rest can be removed. Already processed items come back as duplicate.
+// @Tags automatic
+// @Accept json
+// @Produce json
+// @Param batch body SMSBatchRequest true "Buffered notifications"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]string
+// @Router /automatic/process-sms/batch [post]
+func (h *AutomaticHandler) SmsBatch(c *gin.Context) {
+	var req SMSBatchRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid JSON"})
+		return
+	}
+	if len(req.Items) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "items are required"})
+		return
+	}
+	if len(req.Items) > h.batchMaxItems {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "too many items in batch", "max_items": h.batchMaxItems})
+		return
+	}
+
+	seen := make(map[string]struct{}, len(req.Items))
+	for _, item := range req.Items {
+		if item.ID == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "every item needs an id"})
+			return
+		}
+		if _, duplicate := seen[item.ID]; duplicate {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "duplicate item id", "id": item.ID})
+			return
+		}
+		seen[item.ID] = struct{}{}
+	}
+
+	traderID := c.GetString("userID")
+	deviceID := c.GetString("deviceID")
+	log.Printf("📦 [SMS] Batch received: device=%s, items=%d", deviceID, len(req.Items))
+	h.watchdog.Heartbeat(traderID, deviceID)
+
+	// Порядок запуска - по времени получения: более раннее уведомление должно первым найти свой ордер
+	order := make([]int, len(req.Items))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(i, j int) bool {
+		return req.Items[order[i]].ReceivedAt < req.Items[order[j]].ReceivedAt
+	})
+
+	results := make([]gin.H, len(req.Items))
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < h.batchConcurrency; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for index := range jobs {
+				results[index] = h.processBatchItem(c, traderID, deviceID, req.Items[index])
+			}
+		}()
+	}
+	for _, index := range order {
+		jobs <- index
+	}
+	close(jobs)
+	wg.Wait()
+
+	retry := 0
+	for _, result := range results {
+		if result["retryable"] == true {
+			retry++
+		}
+	}
+	log.Printf("📦 [SMS] Batch processed: device=%s, items=%d, retry=%d", deviceID, len(results), retry)
+
+	c.JSON(http.StatusOK, gin.H{
+		"results": results,
+		"total":   len(results),
+		"retry":   retry,
+	})
+}
+
+func (h *AutomaticHandler) processBatchItem(c *gin.Context, traderID, deviceID string, item SMSBatchItem) gin.H {
+	// Учетные данные привязаны к одному устройству, чужие уведомления в пачке не обрабатываются
+	if item.Group != deviceID {
+		return gin.H{
+			"id":        item.ID,
+			"status":    "rejected",
+			"reason":    "credential is bound to another device",
+			"retryable": false,
+		}
+	}
+
+	outcome := h.processSMS(c.Request.Context(), traderID, item.SMSRequest, true)
+	result := gin.H{}
+	for key, value := range outcome.body {
+		result[key] = value
+	}
+	if outcome.httpStatus != http.StatusOK {
+		result["status"] = "failed"
+	}
+	result["id"] = item.ID
+	result["retryable"] = outcome.retryable
+	return result
+}
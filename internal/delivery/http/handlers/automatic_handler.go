@@ -8,6 +8,9 @@ import (
     "time"
 
     "github.com/LavaJover/shvark-api-gateway/internal/client"
+    "github.com/LavaJover/shvark-api-gateway/internal/domain"
+    "github.com/LavaJover/shvark-api-gateway/internal/service"
+    "github.com/LavaJover/shvark-api-gateway/internal/service/sms_parser"
     orderpb "github.com/LavaJover/shvark-order-service/proto/gen/order"
     "github.com/gin-gonic/gin"
     "google.golang.org/grpc/codes"
@@ -17,15 +20,39 @@ import (
 type AutomaticHandler struct {
     orderService *client.OrderClient
 	deviceService *client.DeviceClient
+	parser *sms_parser.Parser
+	parserPolicy sms_parser.Policy
+	dedup *service.SmsDeduplicator
+	batchMaxItems int
+	batchConcurrency int
+	watchdog *service.DeviceWatchdog
+	platformStats *service.AutomaticStatsService
 }
 
 func NewAutomaticHandler(
 	orderService *client.OrderClient,
 	deviceService *client.DeviceClient,
+	parser *sms_parser.Parser,
+	parserPolicy sms_parser.Policy,
+	dedup *service.SmsDeduplicator,
+	batchMaxItems int,
+	batchConcurrency int,
+	watchdog *service.DeviceWatchdog,
+	platformStats *service.AutomaticStatsService,
 ) *AutomaticHandler {
+    if batchConcurrency < 1 {
+        batchConcurrency = 1
+    }
     return &AutomaticHandler{
         orderService: orderService,
 		deviceService: deviceService,
+		parser: parser,
+		parserPolicy: parserPolicy,
+		dedup: dedup,
+		batchMaxItems: batchMaxItems,
+		batchConcurrency: batchConcurrency,
+		watchdog: watchdog,
+		platformStats: platformStats,
     }
 }
 
@@ -55,6 +82,8 @@ type SMSRequest struct {
 // @Accept json
 // @Produce json
 // @Param sms body SMSRequest true "SMS data"
+// @Description Text and title are parsed by the gateway and cross-checked with the device-reported amount,
+// @Description direction, balance and payment system; mismatches are rejected or flagged by the sms_parser config.
 // @Success 200 {object} map[string]interface{}
 // @Failure 400 {object} map[string]string
 // @Router /automatic/process-sms [post]
@@ -80,20 +109,71 @@ func (h *AutomaticHandler) Sms(c *gin.Context) {
         return
     }
 
+    // Устройство присылает уведомления только от своего имени
+    if !h.ownDevice(c, req.Group) {
+        return
+    }
+
+    h.watchdog.Heartbeat(traderID, req.Group)
+
     log.Printf("📱 [SMS] Received SMS: device=%s, amount=%.2f, payment_system=%s, direction=%s, userID=%s",
         req.Group, req.Amount, req.PaymentSystem, req.Direction, traderID)
 
+    outcome := h.processSMS(c.Request.Context(), traderID, req, false)
+    c.JSON(outcome.httpStatus, outcome.body)
+}
+
+// smsOutcome результат обработки одного уведомления: ответ одиночной ручки и признак,
+// что уведомление стоит прислать еще раз
+type smsOutcome struct {
+    httpStatus int
+    body       gin.H
+    retryable  bool
+}
+
+// processSMS проверяет, сверяет и передает уведомление в order-service.
+// buffered - уведомление пришло пачкой из очереди телефона, для него шире окно времени получения.
+func (h *AutomaticHandler) processSMS(parent context.Context, traderID string, req SMSRequest, buffered bool) smsOutcome {
     // Валидация входящего уведомления
     if !h.validateSMS(req) {
         log.Printf("⚠️  [SMS] Validation failed for device=%s: success=%v, blocked=%v, too_old=%v, unknown=%v",
             req.Group, req.Success, req.Blocked, req.TooOld, req.Unknown)
-        c.JSON(http.StatusOK, gin.H{
+        return smsOutcome{httpStatus: http.StatusOK, body: gin.H{
             "status": "ignored",
             "reason": "validation failed",
-        })
-        return
+        }}
     }
 
+    // Время получения проверяем по часам сервера: флагу too_old от клиента при повторе верить нельзя
+    if err := h.dedup.CheckReceivedAt(req.ReceivedAt, time.Now(), buffered); err != nil {
+        log.Printf("⚠️  [SMS] Rejected received_at=%d for device=%s: %v", req.ReceivedAt, req.Group, err)
+        return smsOutcome{httpStatus: http.StatusOK, body: gin.H{
+            "status": "rejected",
+            "reason": err.Error(),
+        }}
+    }
+
+    // Сверяем разбор устройства с собственным разбором текста уведомления
+    verdict := h.parser.Verify(req.Title, req.Text, sms_parser.Reported{
+        Amount:        req.Amount,
+        PaymentSystem: req.PaymentSystem,
+        Direction:     req.Direction,
+        Balance:       req.Balance,
+    }, h.parserPolicy)
+    if verdict.Reject {
+        log.Printf("⚠️  [SMS] Rejected by parser for device=%s: status=%s, mismatches=%v",
+            req.Group, verdict.Status, verdict.Mismatches)
+        return smsOutcome{httpStatus: http.StatusOK, body: gin.H{
+            "status":     "rejected",
+            "reason":     "parser " + string(verdict.Status),
+            "mismatches": verdict.Mismatches,
+        }}
+    }
+
+    metadata := verdict.Metadata()
+    metadata["title"] = req.Title
+    metadata["balance"] = strconv.FormatFloat(req.Balance, 'f', 2, 64)
+
     // Подготовка данных для gRPC вызова
     grpcReq := &orderpb.ProcessAutomaticPaymentRequest{
         Group:         req.Group,
@@ -104,14 +184,24 @@ func (h *AutomaticHandler) Sms(c *gin.Context) {
         ReceivedAt:    req.ReceivedAt,
         Text:          req.Text,
         TraderId: traderID,
-        Metadata: map[string]string{
-            "title":   req.Title,
-            "balance": strconv.FormatFloat(req.Balance, 'f', 2, 64),
-        },
+        Metadata: metadata,
+    }
+
+    // Повтор уже обработанного уведомления не должен второй раз подтверждать ордер
+    fingerprint := h.dedup.Fingerprint(req.Group, req.ReceivedAt, req.Text, req.Amount)
+    if original, first := h.dedup.Begin(fingerprint); !first {
+        log.Printf("🔁 [SMS] Duplicate notification: device=%s, fingerprint=%s, original_order=%s",
+            req.Group, fingerprint, original.OrderID)
+        // пока первая обработка идет, ее результат неизвестен: повтор может понадобиться, если она не удастся
+        return smsOutcome{httpStatus: http.StatusOK, retryable: !original.Done, body: gin.H{
+            "status":      "duplicate",
+            "fingerprint": fingerprint,
+            "original":    duplicateOriginal(original),
+        }}
     }
 
     // Вызов order-service с retry логикой
-    ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+    ctx, cancel := context.WithTimeout(parent, 10*time.Second)
     defer cancel()
 
     response, err := h.withRetry(ctx, 3, func() (*orderpb.ProcessAutomaticPaymentResponse, error) {
@@ -119,24 +209,49 @@ func (h *AutomaticHandler) Sms(c *gin.Context) {
     })
 
     if err != nil {
+        h.dedup.Abort(fingerprint)
         log.Printf("❌ [SMS] Processing error: %v", err)
-        c.JSON(http.StatusInternalServerError, gin.H{
+        return smsOutcome{httpStatus: http.StatusInternalServerError, retryable: true, body: gin.H{
             "error":  "processing failed",
             "detail": err.Error(),
-        })
-        return
+        }}
     }
 
+    h.dedup.Complete(service.SmsProcessingRecord{
+        Fingerprint: fingerprint,
+        OrderID:     response.OrderId,
+        Action:      response.Action,
+        Processed:   response.Success,
+    })
+
     log.Printf("✅ [SMS] Processed: device=%s, action=%s, success=%v, orders=%d",
         req.Group, response.Action, response.Success, len(response.Results))
 
-    c.JSON(http.StatusOK, gin.H{
-        "status":    "processed",
-        "order_id":  response.OrderId,
-        "action":    response.Action,
-        "processed": response.Success,
-        "results":   response.Results,
-    })
+    return smsOutcome{httpStatus: http.StatusOK, body: gin.H{
+        "status":      "processed",
+        "fingerprint": fingerprint,
+        "order_id":    response.OrderId,
+        "action":      response.Action,
+        "processed":   response.Success,
+        "results":     response.Results,
+    }}
+}
+
+// duplicateOriginal результат первой обработки для ответа на повтор
+func duplicateOriginal(record service.SmsProcessingRecord) gin.H {
+    if !record.Done {
+        return gin.H{
+            "status":     "processing",
+            "started_at": record.StartedAt.Unix(),
+        }
+    }
+    return gin.H{
+        "status":       "processed",
+        "order_id":     record.OrderID,
+        "action":       record.Action,
+        "processed":    record.Processed,
+        "processed_at": record.ProcessedAt.Unix(),
+    }
 }
 
 // validateSMS валидирует входящее SMS уведомление
@@ -218,7 +333,12 @@ func (h *AutomaticHandler) Live(c *gin.Context) {
         return
     }
     
+    if !h.ownDevice(c, group) {
+        return
+    }
+
     log.Printf("💓 [LIVENESS] Ping received: device=%s", group)
+    h.watchdog.Heartbeat(c.GetString("userID"), group)
     
     // Вызываем order-service для обновления статуса
     ctx, cancel := context.WithTimeout(c.Request.Context(), 3*time.Second)
@@ -241,9 +361,9 @@ func (h *AutomaticHandler) Live(c *gin.Context) {
 
 // ==================== DEVICE AUTH ====================
 
-// Auth обрабатывает авторизацию устройства (QR код сканирование)
+// Auth проверяет учетные данные устройства, полученные при сопряжении по QR-коду
 // @Summary Device authorization
-// @Description Authorize device via QR code scan from trader account
+// @Description Check the device credential issued by /automatic/pair
 // @Tags automatic
 // @Accept json
 // @Produce json
@@ -269,21 +389,30 @@ func (h *AutomaticHandler) Auth(c *gin.Context) {
 
     log.Printf("🔐 [AUTH] Auth request: device=%s", group)
 
-    // Можно добавить проверку токена в заголовке Authorization
-    authToken := c.GetHeader("Authorization")
-    if authToken == "" {
-        log.Printf("⚠️  [AUTH] Missing authorization token")
-        c.JSON(http.StatusUnauthorized, gin.H{"error": "Authorization header required"})
+    if !h.ownDevice(c, group) {
         return
     }
 
+    credential := c.MustGet("deviceCredential").(domain.DeviceCredential)
     c.JSON(http.StatusOK, gin.H{
         "status":    "authorized",
         "device":    group,
+        "trader_id": credential.TraderID,
+        "paired_at": credential.PairedAt.Unix(),
         "timestamp": time.Now().Unix(),
     })
 }
 
+// ownDevice сверяет устройство из запроса с устройством, к которому привязаны учетные данные
+func (h *AutomaticHandler) ownDevice(c *gin.Context, group string) bool {
+    if group != c.GetString("deviceID") {
+        log.Printf("⚠️  [AUTH] Device mismatch: credential device=%s, request device=%s", c.GetString("deviceID"), group)
+        c.JSON(http.StatusForbidden, gin.H{"error": "credential is bound to another device"})
+        return false
+    }
+    return true
+}
+
 // ==================== AUTOMATIC LOGS ====================
 
 // GetAutomaticLogs получает логи автоматической обработки платежей
@@ -557,11 +686,16 @@ func maskCardNumber(card string) string {
 // @Tags automatic
 // @Accept json
 // @Produce json
-// @Param trader_id query string true "Trader ID"
-// @Param days query integer false "Number of days for statistics (default 7)" default(7)
+// @Param trader_id query string false "Trader ID, all traders if omitted"
+// @Param days query integer false "Number of days for statistics (default 7, at most automatic_stats.max_days)" default(7)
+// @Param group_by query string false "Platform-wide only: comma-separated breakdowns bank, payment_system, team_lead, device"
+// @Param top_failing query integer false "Platform-wide only: number of top failing devices (default 10)" default(10)
 // @Success 200 {object} map[string]interface{}
 // @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string "platform-wide stats require automatic_stats read permission"
 // @Failure 500 {object} map[string]string
+// @Security BearerAuth
 // @Router /automatic/stats [get]
 func (h *AutomaticHandler) GetAutomaticStats(c *gin.Context) {
     traderID := c.Query("trader_id")
@@ -571,26 +705,16 @@ func (h *AutomaticHandler) GetAutomaticStats(c *gin.Context) {
     if err != nil || days <= 0 {
         days = 7
     }
+    if days > h.platformStats.MaxDays() {
+        c.JSON(http.StatusBadRequest, gin.H{"error": "days must be at most " + strconv.Itoa(h.platformStats.MaxDays())})
+        return
+    }
     
     log.Printf("📊 [STATS] Request: trader_id=%s, days=%d", traderID, days)
     
-    // Если trader_id не указан, возвращаем общую статистику
+    // Если trader_id не указан, возвращаем общую статистику по всем трейдерам
     if traderID == "" {
-        // TODO: Реализовать общую статистику для всех трейдеров
-        c.JSON(http.StatusOK, gin.H{
-            "trader_id": "all",
-            "period_days": days,
-            "overview": map[string]interface{}{
-                "total_attempts": 0,
-                "successful_attempts": 0,
-                "success_rate": 0,
-                "approved_orders": 0,
-                "not_found_count": 0,
-                "failed_count": 0,
-                "avg_processing_time_ms": 0,
-            },
-            "device_stats": map[string]interface{}{},
-        })
+        h.getPlatformAutomaticStats(c, days)
         return
     }
     
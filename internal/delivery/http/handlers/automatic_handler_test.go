@@ -0,0 +1,85 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/LavaJover/shvark-api-gateway/internal/service"
+	"github.com/LavaJover/shvark-api-gateway/internal/service/sms_parser"
+)
+
+const testSmsText = "Перевод по СБП *1234 от Иван И. на 1 500,00 RUR. Баланс: 25 340,50 RUR"
+
+func newTestAutomaticHandler(t *testing.T) *AutomaticHandler {
+	t.Helper()
+	parser, err := sms_parser.Load("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	dedup := service.NewSmsDeduplicator(service.NewInMemorySmsReplayStore(), 25*time.Hour, 10*time.Minute, 24*time.Hour, 2*time.Minute)
+	return NewAutomaticHandler(nil, nil, parser, sms_parser.Policy{
+		OnMismatch:  sms_parser.ActionFlag,
+		OnUnmatched: sms_parser.ActionFlag,
+	}, dedup, 100, 1, nil, nil)
+}
+
+func testSmsRequest(receivedAt time.Time, text string) SMSRequest {
+	return SMSRequest{
+		Success:       true,
+		PaymentSystem: "SBP",
+		Amount:        1500,
+		Balance:       25340.5,
+		Group:         "device-1",
+		Direction:     "in",
+		Text:          text,
+		Title:         "Alfa-Bank",
+		ReceivedAt:    receivedAt.UnixMilli(),
+	}
+}
+
+// Уведомления, прошедшие проверку времени, в тесте доходят только до проверки повтора:
+// отпечаток первой обработки занят заранее, поэтому order-service не вызывается.
+func TestProcessSMSReplayProtection(t *testing.T) {
+	h := newTestAutomaticHandler(t)
+	now := time.Now()
+	processed := testSmsRequest(now.Add(-time.Minute), testSmsText)
+	h.dedup.Begin(h.dedup.Fingerprint(processed.Group, processed.ReceivedAt, processed.Text, processed.Amount))
+
+	stale := testSmsRequest(now.Add(-time.Hour), testSmsText)
+	h.dedup.Begin(h.dedup.Fingerprint(stale.Group, stale.ReceivedAt, stale.Text, stale.Amount))
+
+	tests := []struct {
+		name       string
+		req        SMSRequest
+		buffered   bool
+		wantStatus string
+		wantReason string
+	}{
+		{name: "duplicate", req: processed, wantStatus: "duplicate"},
+		{name: "near duplicate", req: testSmsRequest(now.Add(-time.Minute), "  перевод по сбп *1234 от ИВАН И.\nна 1 500,00 rur. баланс: 25 340,50 rur"), wantStatus: "duplicate"},
+		{name: "stale", req: stale, wantStatus: "rejected", wantReason: service.ErrSmsTooOld.Error()},
+		{name: "stale from phone queue", req: stale, buffered: true, wantStatus: "duplicate"},
+		{name: "future skewed", req: testSmsRequest(now.Add(5*time.Minute), testSmsText), wantStatus: "rejected", wantReason: service.ErrSmsFromFuture.Error()},
+		{name: "client too_old flag", req: func() SMSRequest {
+			req := processed
+			req.TooOld = true
+			return req
+		}(), wantStatus: "ignored", wantReason: "validation failed"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			outcome := h.processSMS(context.Background(), "trader-1", tt.req, tt.buffered)
+			if outcome.httpStatus != http.StatusOK {
+				t.Fatalf("http status = %d, want 200", outcome.httpStatus)
+			}
+			if outcome.body["status"] != tt.wantStatus {
+				t.Fatalf("status = %v, want %s (%v)", outcome.body["status"], tt.wantStatus, outcome.body)
+			}
+			if tt.wantReason != "" && outcome.body["reason"] != tt.wantReason {
+				t.Fatalf("reason = %v, want %s", outcome.body["reason"], tt.wantReason)
+			}
+		})
+	}
+}
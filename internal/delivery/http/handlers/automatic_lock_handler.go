@@ -0,0 +1,71 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	adminResponse "github.com/LavaJover/shvark-api-gateway/internal/delivery/http/dto/admin/response"
+	"github.com/LavaJover/shvark-api-gateway/internal/service"
+	"github.com/gin-gonic/gin"
+)
+
+type AutomaticLockHandler struct {
+	Audit service.AutomaticLockAudit
+}
+
+func NewAutomaticLockHandler(audit service.AutomaticLockAudit) *AutomaticLockHandler {
+	return &AutomaticLockHandler{
+		Audit: audit,
+	}
+}
+
+// @Summary Get automatic traffic locks
+// @Description Audit of traffic locks and unlocks made by the device heartbeat watchdog, newest first
+// @Tags admin
+// @Security BearerAuth
+// @Produce json
+// @Param trader_id query string false "trader ID"
+// @Param device_id query string false "device ID"
+// @Param limit query int false "max entries" default(100)
+// @Success 200 {object} adminResponse.GetAutomaticLocksResponse
+// @Failure 400 {object} ErrorResponse
+// @Router /admin/automatic/locks [get]
+func (h *AutomaticLockHandler) GetLocks(c *gin.Context) {
+	limit := 100
+	if value := c.Query("limit"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed <= 0 {
+			c.JSON(http.StatusBadRequest, ErrorResponse{Error: "limit must be a positive number"})
+			return
+		}
+		limit = parsed
+	}
+
+	entries := h.Audit.List(service.AutomaticLockAuditFilter{
+		TraderID: c.Query("trader_id"),
+		DeviceID: c.Query("device_id"),
+		Limit:    limit,
+	})
+
+	locks := make([]adminResponse.AutomaticLock, len(entries))
+	for i, entry := range entries {
+		locks[i] = adminResponse.AutomaticLock{
+			At:            entry.At.Unix(),
+			Action:        string(entry.Action),
+			Mode:          string(entry.Mode),
+			TraderID:      entry.TraderID,
+			DeviceIDs:     entry.DeviceIDs,
+			BankDetailIDs: entry.BankDetailIDs,
+			Reason:        entry.Reason,
+			Error:         entry.Error,
+		}
+		if entry.LastHeartbeatAt != nil {
+			lastHeartbeatAt := entry.LastHeartbeatAt.Unix()
+			locks[i].LastHeartbeatAt = &lastHeartbeatAt
+		}
+	}
+
+	c.JSON(http.StatusOK, adminResponse.GetAutomaticLocksResponse{
+		Locks: locks,
+	})
+}
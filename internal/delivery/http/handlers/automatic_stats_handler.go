@@ -0,0 +1,122 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/LavaJover/shvark-api-gateway/internal/domain"
+	"github.com/LavaJover/shvark-api-gateway/internal/service"
+	"github.com/gin-gonic/gin"
+)
+
+// группировки общей статистики, которые можно запросить через group_by
+var platformStatsGroups = map[string]func(*domain.PlatformAutomaticStats) []domain.AutomaticStatsGroup{
+	"bank":           func(s *domain.PlatformAutomaticStats) []domain.AutomaticStatsGroup { return s.Banks },
+	"payment_system": func(s *domain.PlatformAutomaticStats) []domain.AutomaticStatsGroup { return s.PaymentSystems },
+	"team_lead":      func(s *domain.PlatformAutomaticStats) []domain.AutomaticStatsGroup { return s.TeamLeads },
+	"device":         func(s *domain.PlatformAutomaticStats) []domain.AutomaticStatsGroup { return s.Devices },
+}
+
+// getPlatformAutomaticStats статистика автоматики по всем трейдерам в формате статистики одного трейдера
+// с разбивками group_by и top_failing устройствами
+func (h *AutomaticHandler) getPlatformAutomaticStats(c *gin.Context, days int) {
+	var groupBy []string
+	if value := c.Query("group_by"); value != "" {
+		for _, group := range strings.Split(value, ",") {
+			group = strings.TrimSpace(group)
+			if _, ok := platformStatsGroups[group]; !ok {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "unknown group_by: " + group})
+				return
+			}
+			groupBy = append(groupBy, group)
+		}
+	}
+
+	topFailing := 10
+	if value := c.Query("top_failing"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed < 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "top_failing must be a non-negative number"})
+			return
+		}
+		topFailing = parsed
+	}
+
+	stats, cached, err := h.platformStats.PlatformStats(c.Request.Context(), days)
+	if err != nil {
+		log.Printf("❌ [STATS] Error collecting platform stats: %v", err)
+		if errors.Is(err, service.ErrAutomaticStatsPeriod) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		var upstreamErr *service.UpstreamError
+		if errors.As(err, &upstreamErr) {
+			c.JSON(http.StatusBadGateway, gin.H{"error": "Failed to fetch traders"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch statistics"})
+		return
+	}
+
+	deviceStats := make(map[string]interface{}, len(stats.Devices))
+	for _, device := range stats.Devices {
+		deviceStats[device.Key] = statsGroupJSON(device)
+	}
+
+	groups := make(map[string]interface{}, len(groupBy))
+	for _, group := range groupBy {
+		items := platformStatsGroups[group](stats)
+		result := make([]gin.H, len(items))
+		for i, item := range items {
+			result[i] = statsGroupJSON(item)
+		}
+		groups[group] = result
+	}
+
+	failing := service.TopFailingDevices(stats, topFailing)
+	topFailingDevices := make([]gin.H, len(failing))
+	for i, device := range failing {
+		topFailingDevices[i] = statsGroupJSON(device)
+	}
+
+	log.Printf("✅ [STATS] Platform stats: %d traders (%d failed), %d attempts, cached=%v",
+		stats.TradersTotal, len(stats.TradersFailed), stats.Overview.TotalAttempts, cached)
+
+	c.JSON(http.StatusOK, gin.H{
+		"trader_id":           "all",
+		"period_days":         days,
+		"overview":            statsOverviewJSON(stats.Overview),
+		"device_stats":        deviceStats,
+		"groups":              groups,
+		"top_failing_devices": topFailingDevices,
+		"traders_total":       stats.TradersTotal,
+		"traders_failed":      stats.TradersFailed,
+		"logs_truncated":      stats.LogsTruncated,
+		"generated_at":        stats.GeneratedAt.Unix(),
+		"cached":              cached,
+	})
+}
+
+func statsOverviewJSON(counters domain.AutomaticStatsCounters) map[string]interface{} {
+	return map[string]interface{}{
+		"total_attempts":         counters.TotalAttempts,
+		"successful_attempts":    counters.SuccessfulAttempts,
+		"success_rate":           counters.SuccessRate(),
+		"approved_orders":        counters.ApprovedOrders,
+		"not_found_count":        counters.NotFoundCount,
+		"failed_count":           counters.FailedCount,
+		"avg_processing_time_ms": counters.AvgProcessingTime(),
+	}
+}
+
+func statsGroupJSON(group domain.AutomaticStatsGroup) gin.H {
+	result := gin.H(statsOverviewJSON(group.AutomaticStatsCounters))
+	result["key"] = group.Key
+	if group.TraderID != "" {
+		result["trader_id"] = group.TraderID
+	}
+	return result
+}
@@ -0,0 +1,80 @@
+package handlers
+
+import (
+	"net/http"
+
+	bankingResponse "github.com/LavaJover/shvark-api-gateway/internal/delivery/http/dto/banking/response"
+	"github.com/LavaJover/shvark-api-gateway/internal/service/bank_catalog"
+	"github.com/gin-gonic/gin"
+)
+
+type BankCatalogHandler struct {
+	Banks *bank_catalog.Catalog
+}
+
+func NewBankCatalogHandler(banks *bank_catalog.Catalog) *BankCatalogHandler {
+	return &BankCatalogHandler{
+		Banks: banks,
+	}
+}
+
+// @Summary Get banks from catalog
+// @Description Filtered list of banks from the bank catalog
+// @Tags banks
+// @Produce json
+// @Param country query string false "country code, e.g. RU"
+// @Param payment_system query string false "payment system, e.g. SBP"
+// @Param nspk_code query string false "NSPK code"
+// @Param q query string false "substring of bank code or name"
+// @Success 200 {object} bankingResponse.GetCatalogBanksResponse
+// @Router /banks [get]
+func (h *BankCatalogHandler) GetBanks(c *gin.Context) {
+	banks := h.Banks.Banks(bank_catalog.Filter{
+		Country:       c.Query("country"),
+		PaymentSystem: c.Query("payment_system"),
+		NspkCode:      c.Query("nspk_code"),
+		Query:         c.Query("q"),
+	})
+
+	response := bankingResponse.GetCatalogBanksResponse{
+		Banks: make([]bankingResponse.CatalogBank, len(banks)),
+	}
+	for i, bank := range banks {
+		response.Banks[i] = toCatalogBankResponse(bank)
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// @Summary Get bank by code
+// @Description Lookup bank in the bank catalog by bank code
+// @Tags banks
+// @Produce json
+// @Param code path string true "bank code"
+// @Success 200 {object} bankingResponse.CatalogBank
+// @Failure 404 {object} ErrorResponse
+// @Router /banks/{code} [get]
+func (h *BankCatalogHandler) GetBank(c *gin.Context) {
+	bank, err := h.Banks.ByCode(c.Param("code"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, ErrorResponse{Error: err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, toCatalogBankResponse(bank))
+}
+
+func toCatalogBankResponse(bank bank_catalog.Bank) bankingResponse.CatalogBank {
+	templates := make([]string, len(bank.DeeplinkTemplates))
+	for i, template := range bank.DeeplinkTemplates {
+		templates[i] = template.Code
+	}
+	return bankingResponse.CatalogBank{
+		Code:              bank.Code,
+		Name:              bank.Name,
+		Names:             bank.Names,
+		NspkCode:          bank.NspkCode,
+		Country:           bank.Country,
+		PaymentSystems:    bank.PaymentSystems,
+		DeeplinkTemplates: templates,
+		Logo:              bank.Logo,
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"time"
 
 	"github.com/LavaJover/shvark-api-gateway/internal/client"
+	"github.com/LavaJover/shvark-api-gateway/internal/service/bank_catalog"
 	orderpb "github.com/LavaJover/shvark-order-service/proto/gen/order"
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
@@ -16,9 +17,10 @@ import (
 
 type BankingHandler struct {
 	OrderClient *client.OrderClient
+	Banks 		*bank_catalog.Catalog
 }
 
-func NewBankingHandler(addr string) (*BankingHandler, error) {
+func NewBankingHandler(addr string, banks *bank_catalog.Catalog) (*BankingHandler, error) {
 	orderClient, err := client.NewOrderClient(addr)
 	if err != nil {
 		return nil, err
@@ -26,6 +28,7 @@ func NewBankingHandler(addr string) (*BankingHandler, error) {
 
 	return &BankingHandler{
 		OrderClient: orderClient,
+		Banks: banks,
 	}, nil
 }
 
@@ -54,6 +57,12 @@ func (h *BankingHandler) CreateBankDetail(c *gin.Context) {
 		return
 	}
 
+	bankCode, nspkCode, err := h.Banks.Resolve(request.BankCode, request.NspkCode)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
 	bankDetailRequest := orderpb.CreateBankDetailRequest{
 		TraderId: request.TraderID,
 		Country: request.Country,
@@ -74,8 +83,8 @@ func (h *BankingHandler) CreateBankDetail(c *gin.Context) {
 		MaxQuantityDay: float64(request.MaxQuantityDay),
 		MaxQuantityMonth: float64(request.MaxQuantityMonth),
 		DeviceId: request.DeviceID,
-		BankCode: request.BankCode,
-		NspkCode: request.NspkCode,
+		BankCode: bankCode,
+		NspkCode: nspkCode,
 	}
 
 	response, err := h.OrderClient.CreateBankDetail(&bankDetailRequest)
@@ -168,6 +177,12 @@ func (h *BankingHandler) UpdateBankDetail(c *gin.Context) {
 		return
 	}
 
+	bankCode, nspkCode, err := h.Banks.Resolve(request.BankDetail.BankCode, request.BankDetail.NspkCode)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
 	bankDetailRequest := orderpb.UpdateBankDetailRequest{
 		BankDetail: &orderpb.BankDetail{
 			BankDetailId: request.BankDetail.ID,
@@ -190,8 +205,8 @@ func (h *BankingHandler) UpdateBankDetail(c *gin.Context) {
 			MaxQuantityMonth: float64(request.BankDetail.MaxQuantityMonth),
 			DeviceId: request.BankDetail.DeviceID,
 			InflowCurrency: request.BankDetail.InflowCurrency,
-			BankCode: request.BankDetail.BankCode,
-			NspkCode: request.BankDetail.NspkCode,
+			BankCode: bankCode,
+			NspkCode: nspkCode,
 		},
 	}
 
@@ -0,0 +1,173 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	adminRequest "github.com/LavaJover/shvark-api-gateway/internal/delivery/http/dto/admin/request"
+	adminResponse "github.com/LavaJover/shvark-api-gateway/internal/delivery/http/dto/admin/response"
+	"github.com/LavaJover/shvark-api-gateway/internal/domain"
+	"github.com/LavaJover/shvark-api-gateway/internal/service"
+	"github.com/gin-gonic/gin"
+)
+
+type BulkAdminHandler struct {
+	Bulk *service.BulkAdminService
+}
+
+func NewBulkAdminHandler(bulk *service.BulkAdminService) *BulkAdminHandler {
+	return &BulkAdminHandler{
+		Bulk: bulk,
+	}
+}
+
+// @Summary Bulk create or edit traffic
+// @Description Create or edit traffic for every trader x merchant pair. Existing records get only the passed fields, missing ones are created. With dry_run the diff is returned without changes.
+// @Tags admin
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param input body adminRequest.BulkTrafficRequest true "pairs and traffic fields"
+// @Success 200 {object} adminResponse.BulkResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 502 {object} ErrorResponse
+// @Router /admin/bulk/traffic [post]
+func (h *BulkAdminHandler) UpsertTraffic(c *gin.Context) {
+	var request adminRequest.BulkTrafficRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	params := service.BulkTrafficParams{
+		TraderIDs:   request.TraderIDs,
+		MerchantIDs: request.MerchantIDs,
+		Patch: service.BulkTrafficPatch{
+			Name:                request.Name,
+			TraderRewardPercent: request.TraderReward,
+			PlatformFee:         request.PlatformFee,
+			TraderPriority:      request.TraderPriority,
+			Enabled:             request.Enabled,
+			AntifraudRequired:   request.AntifraudRequired,
+		},
+		DryRun: request.DryRun,
+	}
+	if request.MerchantDealsDuration != nil {
+		duration, err := time.ParseDuration(*request.MerchantDealsDuration)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, ErrorResponse{Error: "failed to parse deals time parameter"})
+			return
+		}
+		params.Patch.MerchantDealsDuration = &duration
+	}
+
+	result, err := h.Bulk.UpsertTraffic(params)
+	h.respond(c, result, err)
+}
+
+// @Summary Bulk lock team traffic
+// @Description Lock (unlocked=false) or unlock trader traffic of every trader in the team lead's team. With dry_run the diff is returned without changes.
+// @Tags admin
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param input body adminRequest.BulkTeamLockRequest true "team lead and lock status"
+// @Success 200 {object} adminResponse.BulkResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 502 {object} ErrorResponse
+// @Router /admin/bulk/traffic/team-lock [post]
+func (h *BulkAdminHandler) SetTeamTrafficLock(c *gin.Context) {
+	var request adminRequest.BulkTeamLockRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	result, err := h.Bulk.SetTeamTrafficLock(service.BulkTeamLockParams{
+		TeamLeadID: request.TeamLeadID,
+		Unlocked:   request.Unlocked,
+		Reason:     request.Reason,
+		DryRun:     request.DryRun,
+	})
+	h.respond(c, result, err)
+}
+
+// @Summary Bulk set withdrawal rules
+// @Description Set withdrawal rules for many users, each with its own values. With dry_run the diff against current rules is returned without changes.
+// @Tags admin
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param input body adminRequest.BulkWithdrawalRulesRequest true "rules per user"
+// @Success 200 {object} adminResponse.BulkResponse
+// @Failure 400 {object} ErrorResponse
+// @Router /admin/bulk/withdrawal-rules [post]
+func (h *BulkAdminHandler) SetWithdrawalRules(c *gin.Context) {
+	var request adminRequest.BulkWithdrawalRulesRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	rules := make([]service.BulkWithdrawalRule, len(request.Rules))
+	for i, rule := range request.Rules {
+		if rule.UserID == "" {
+			c.JSON(http.StatusBadRequest, ErrorResponse{Error: "user_id is required"})
+			return
+		}
+		rules[i] = service.BulkWithdrawalRule{
+			UserID:          rule.UserID,
+			FixedFee:        rule.FixedFee,
+			MinAmount:       rule.MinAmount,
+			CooldownSeconds: rule.CooldownSeconds,
+		}
+	}
+
+	result, err := h.Bulk.SetWithdrawalRules(rules, request.DryRun)
+	h.respond(c, result, err)
+}
+
+func (h *BulkAdminHandler) respond(c *gin.Context, result domain.BulkResult, err error) {
+	switch {
+	case err == nil:
+		c.JSON(http.StatusOK, bulkResponse(result))
+	case errors.Is(err, service.ErrBulkEmpty),
+		errors.Is(err, service.ErrBulkTooLarge),
+		errors.Is(err, service.ErrBulkDuplicate):
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+	default:
+		c.JSON(http.StatusBadGateway, ErrorResponse{Error: err.Error()})
+	}
+}
+
+func bulkResponse(result domain.BulkResult) adminResponse.BulkResponse {
+	response := adminResponse.BulkResponse{
+		DryRun:    result.DryRun,
+		Total:     result.Total,
+		Planned:   result.Planned,
+		Applied:   result.Applied,
+		Unchanged: result.Unchanged,
+		Failed:    result.Failed,
+		Items:     make([]adminResponse.BulkItem, len(result.Items)),
+	}
+	for i, item := range result.Items {
+		response.Items[i] = adminResponse.BulkItem{
+			TraderID:   item.TraderID,
+			MerchantID: item.MerchantID,
+			UserID:     item.UserID,
+			TrafficID:  item.TrafficID,
+			Action:     string(item.Action),
+			Status:     string(item.Status),
+			Error:      item.Error,
+		}
+		for _, change := range item.Changes {
+			response.Items[i].Changes = append(response.Items[i].Changes, adminResponse.BulkChange{
+				Field: change.Field,
+				From:  change.From,
+				To:    change.To,
+			})
+		}
+	}
+	return response
+}
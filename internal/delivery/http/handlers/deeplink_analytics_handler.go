@@ -0,0 +1,155 @@
+package handlers
+
+import (
+	"net/http"
+	"time"
+
+	adminResponse "github.com/LavaJover/shvark-api-gateway/internal/delivery/http/dto/admin/response"
+	paymentRequest "github.com/LavaJover/shvark-api-gateway/internal/delivery/http/dto/payment/request"
+	"github.com/LavaJover/shvark-api-gateway/internal/domain"
+	"github.com/LavaJover/shvark-api-gateway/internal/service"
+	"github.com/gin-gonic/gin"
+)
+
+// maxDeeplinkStatsBuckets ограничивает число интервалов в одном запросе статистики
+const maxDeeplinkStatsBuckets = 1000
+
+type DeeplinkAnalyticsHandler struct {
+	Analytics *service.DeeplinkAnalytics
+}
+
+func NewDeeplinkAnalyticsHandler(analytics *service.DeeplinkAnalytics) *DeeplinkAnalyticsHandler {
+	return &DeeplinkAnalyticsHandler{
+		Analytics: analytics,
+	}
+}
+
+// @Summary Track deeplink funnel event
+// @Description Beacon endpoint for deeplink pages: page viewed, bank chosen, scheme attempted, app opened or timed out
+// @Tags payments
+// @Accept json
+// @Param order_id query string true "Order ID"
+// @Param token query string true "Order-scoped deeplink token"
+// @Param input body paymentRequest.DeeplinkEventRequest true "funnel event"
+// @Success 204
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Router /payments/deeplink/events [post]
+func (h *DeeplinkAnalyticsHandler) Track(c *gin.Context) {
+	// sendBeacon отправляет строку как text/plain, поэтому тело разбирается как JSON без проверки Content-Type
+	var request paymentRequest.DeeplinkEventRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	platform, ok := domain.ParsePlatform(request.Platform)
+	if !ok {
+		platform = service.DetectPlatform(
+			c.GetHeader("User-Agent"),
+			c.GetHeader("Sec-CH-UA-Platform"),
+			c.GetHeader("Sec-CH-UA-Mobile"),
+		)
+	}
+
+	err := h.Analytics.Track(domain.DeeplinkEvent{
+		Type:     domain.DeeplinkEventType(request.Type),
+		OrderID:  c.Query("order_id"),
+		BankCode: request.BankCode,
+		Scheme:   request.Scheme,
+		Platform: platform,
+	})
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// @Summary Get deeplink funnel stats
+// @Description Deeplink conversion (app opened / scheme attempted) by bank, scheme or OS over time
+// @Tags admin
+// @Security BearerAuth
+// @Produce json
+// @Param from query string false "RFC3339 start, default 24h ago"
+// @Param to query string false "RFC3339 end, default now"
+// @Param interval query string false "bucket size, e.g. 1h, 24h" default(1h)
+// @Param group_by query string false "breakdown" Enums(bank, scheme, os) default(bank)
+// @Success 200 {object} adminResponse.GetDeeplinkStatsResponse
+// @Failure 400 {object} ErrorResponse
+// @Router /admin/deeplink-analytics/stats [get]
+func (h *DeeplinkAnalyticsHandler) GetStats(c *gin.Context) {
+	to := time.Now()
+	if value := c.Query("to"); value != "" {
+		parsed, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, ErrorResponse{Error: "invalid to: " + err.Error()})
+			return
+		}
+		to = parsed
+	}
+	from := to.Add(-24 * time.Hour)
+	if value := c.Query("from"); value != "" {
+		parsed, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, ErrorResponse{Error: "invalid from: " + err.Error()})
+			return
+		}
+		from = parsed
+	}
+	if !from.Before(to) {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "from must be before to"})
+		return
+	}
+
+	interval := time.Hour
+	if value := c.Query("interval"); value != "" {
+		parsed, err := time.ParseDuration(value)
+		if err != nil || parsed <= 0 {
+			c.JSON(http.StatusBadRequest, ErrorResponse{Error: "invalid interval"})
+			return
+		}
+		interval = parsed
+	}
+	if to.Sub(from)/interval > maxDeeplinkStatsBuckets {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "too many intervals, increase interval or shorten period"})
+		return
+	}
+
+	groupBy := service.DeeplinkStatsGroup(c.DefaultQuery("group_by", string(service.DeeplinkStatsByBank)))
+	switch groupBy {
+	case service.DeeplinkStatsByBank, service.DeeplinkStatsByScheme, service.DeeplinkStatsByOS:
+	default:
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "group_by must be one of bank, scheme, os"})
+		return
+	}
+
+	stats := h.Analytics.Stats(service.DeeplinkStatsQuery{
+		From:     from,
+		To:       to,
+		Interval: interval,
+		GroupBy:  groupBy,
+	})
+
+	rows := make([]adminResponse.DeeplinkStatsRow, len(stats))
+	for i, row := range stats {
+		rows[i] = adminResponse.DeeplinkStatsRow{
+			BucketStart: row.BucketStart.Format(time.RFC3339),
+			Key:         row.Key,
+			PageViews:   row.PageViews,
+			BankChosen:  row.BankChosen,
+			Attempts:    row.Attempts,
+			Opened:      row.Opened,
+			TimedOut:    row.TimedOut,
+			Conversion:  row.Conversion,
+		}
+	}
+
+	c.JSON(http.StatusOK, adminResponse.GetDeeplinkStatsResponse{
+		From:     from.Format(time.RFC3339),
+		To:       to.Format(time.RFC3339),
+		Interval: interval.String(),
+		GroupBy:  string(groupBy),
+		Rows:     rows,
+	})
+}
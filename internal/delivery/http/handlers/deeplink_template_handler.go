@@ -0,0 +1,392 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	adminRequest "github.com/LavaJover/shvark-api-gateway/internal/delivery/http/dto/admin/request"
+	adminResponse "github.com/LavaJover/shvark-api-gateway/internal/delivery/http/dto/admin/response"
+	"github.com/LavaJover/shvark-api-gateway/internal/domain"
+	"github.com/LavaJover/shvark-api-gateway/internal/service"
+	"github.com/LavaJover/shvark-api-gateway/internal/service/deeplink_templates"
+	"github.com/LavaJover/shvark-api-gateway/internal/service/i18n"
+	"github.com/gin-gonic/gin"
+)
+
+type DeeplinkTemplateHandler struct {
+	Templates *deeplink_templates.Store
+}
+
+func NewDeeplinkTemplateHandler(templates *deeplink_templates.Store) *DeeplinkTemplateHandler {
+	return &DeeplinkTemplateHandler{
+		Templates: templates,
+	}
+}
+
+// @Summary Get deeplink templates
+// @Description Get all bank deeplink templates with their versions (without bodies)
+// @Tags admin
+// @Security BearerAuth
+// @Produce json
+// @Success 200 {object} adminResponse.GetDeeplinkTemplatesResponse
+// @Router /admin/deeplink-templates [get]
+func (h *DeeplinkTemplateHandler) GetTemplates(c *gin.Context) {
+	bankCodes := h.Templates.BankCodes()
+	templates := make([]adminResponse.DeeplinkTemplate, 0, len(bankCodes))
+	for _, bankCode := range bankCodes {
+		versions, err := h.Templates.ListVersions(bankCode)
+		if err != nil {
+			continue
+		}
+		template := adminResponse.DeeplinkTemplate{
+			BankCode: bankCode,
+			Versions: make([]adminResponse.DeeplinkTemplateVersion, len(versions)),
+		}
+		for i, version := range versions {
+			template.Versions[i] = toDeeplinkTemplateVersionResponse(version, false)
+			if version.Active {
+				template.ActiveVersion = version.Version
+			}
+		}
+		templates = append(templates, template)
+	}
+	c.JSON(http.StatusOK, adminResponse.GetDeeplinkTemplatesResponse{Templates: templates})
+}
+
+// @Summary Get deeplink template version
+// @Description Get bank deeplink template version with body
+// @Tags admin
+// @Security BearerAuth
+// @Produce json
+// @Param bankCode path string true "bank code"
+// @Param version path int true "template version"
+// @Success 200 {object} adminResponse.DeeplinkTemplateVersion
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Router /admin/deeplink-templates/{bankCode}/versions/{version} [get]
+func (h *DeeplinkTemplateHandler) GetVersion(c *gin.Context) {
+	version, ok := deeplinkTemplateVersionParam(c)
+	if !ok {
+		return
+	}
+	templateVersion, err := h.Templates.GetVersion(c.Param("bankCode"), version)
+	if err != nil {
+		respondDeeplinkTemplateError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, toDeeplinkTemplateVersionResponse(templateVersion, true))
+}
+
+// @Summary Create deeplink template version
+// @Description Validate and store new inactive version of bank deeplink template. Schemes are available in template body as .Schemes
+// @Tags admin
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param input body adminRequest.DeeplinkTemplateRequest true "template version"
+// @Success 201 {object} adminResponse.DeeplinkTemplateVersion
+// @Failure 400 {object} ErrorResponse
+// @Failure 422 {object} adminResponse.ValidateDeeplinkTemplateResponse
+// @Failure 503 {object} ErrorResponse
+// @Router /admin/deeplink-templates [post]
+func (h *DeeplinkTemplateHandler) CreateVersion(c *gin.Context) {
+	var request adminRequest.DeeplinkTemplateRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	templateVersion, err := h.Templates.CreateVersion(toDeeplinkTemplateMeta(request), request.Body)
+	if err != nil {
+		respondDeeplinkTemplateError(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, toDeeplinkTemplateVersionResponse(templateVersion, false))
+}
+
+// @Summary Validate deeplink template
+// @Description Check template metadata and try to render body with sample payment data
+// @Tags admin
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param input body adminRequest.DeeplinkTemplateRequest true "template version"
+// @Success 200 {object} adminResponse.ValidateDeeplinkTemplateResponse
+// @Failure 400 {object} ErrorResponse
+// @Router /admin/deeplink-templates/validate [post]
+func (h *DeeplinkTemplateHandler) Validate(c *gin.Context) {
+	var request adminRequest.DeeplinkTemplateRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	err := deeplink_templates.Validate(toDeeplinkTemplateMeta(request), request.Body)
+	var validationErr *deeplink_templates.ValidationError
+	if errors.As(err, &validationErr) {
+		c.JSON(http.StatusOK, adminResponse.ValidateDeeplinkTemplateResponse{
+			Valid:    false,
+			Problems: validationErr.Problems,
+		})
+		return
+	}
+	c.JSON(http.StatusOK, adminResponse.ValidateDeeplinkTemplateResponse{Valid: true})
+}
+
+// @Summary Preview deeplink template
+// @Description Render unsaved template body with sample or provided payment data
+// @Tags admin
+// @Security BearerAuth
+// @Accept json
+// @Produce html
+// @Param input body adminRequest.PreviewDeeplinkTemplateRequest true "template body"
+// @Success 200 {string} string "HTML page"
+// @Failure 400 {object} ErrorResponse
+// @Failure 422 {object} ErrorResponse
+// @Router /admin/deeplink-templates/preview [post]
+func (h *DeeplinkTemplateHandler) Preview(c *gin.Context) {
+	var request adminRequest.PreviewDeeplinkTemplateRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	data := deeplink_templates.SampleData()
+	data["BankCode"] = request.BankCode
+	if request.Amount != "" {
+		data["Amount"] = request.Amount
+	}
+	if request.CardNumber != "" {
+		data["CardNumber"] = request.CardNumber
+		data["MaskedCardNumber"] = service.MaskCardNumber(request.CardNumber)
+	}
+	if request.PhoneNumber != "" {
+		data["PhoneNumber"] = request.PhoneNumber
+		data["MaskedPhoneNumber"] = service.MaskPhone(request.PhoneNumber)
+	}
+	localizePreviewData(data, request.Locale)
+
+	html, err := deeplink_templates.Preview(request.BankCode, request.Body, request.Schemes, data)
+	if err != nil {
+		c.JSON(http.StatusUnprocessableEntity, ErrorResponse{Error: err.Error()})
+		return
+	}
+	c.Header("Content-Type", "text/html; charset=utf-8")
+	c.String(http.StatusOK, html)
+}
+
+// @Summary Preview stored deeplink template version
+// @Description Render stored template version with sample payment data
+// @Tags admin
+// @Security BearerAuth
+// @Produce html
+// @Param bankCode path string true "bank code"
+// @Param version path int true "template version"
+// @Param platform query string false "client platform to select links for" Enums(ios, android, desktop, unknown)
+// @Param lang query string false "page language" Enums(ru, en, kk, uz)
+// @Success 200 {string} string "HTML page"
+// @Failure 404 {object} ErrorResponse
+// @Failure 422 {object} ErrorResponse
+// @Router /admin/deeplink-templates/{bankCode}/versions/{version}/preview [get]
+func (h *DeeplinkTemplateHandler) PreviewVersion(c *gin.Context) {
+	version, ok := deeplinkTemplateVersionParam(c)
+	if !ok {
+		return
+	}
+	templateVersion, err := h.Templates.GetVersion(c.Param("bankCode"), version)
+	if err != nil {
+		respondDeeplinkTemplateError(c, err)
+		return
+	}
+
+	platform, _ := domain.ParsePlatform(c.Query("platform"))
+	data := deeplink_templates.SampleData()
+	data["BankCode"] = templateVersion.BankCode
+	data["BankName"] = templateVersion.BankName
+	data["AppStoreURL"] = templateVersion.AppStoreURL
+	data["PlayStoreURL"] = templateVersion.PlayStoreURL
+	data["Platform"] = platform
+	localizePreviewData(data, c.Query("lang"))
+	data["Deeplinks"] = service.SelectDeeplinks(
+		templateVersion.Links,
+		platform,
+		deeplink_templates.LinkValues(data),
+		templateVersion.PlayStoreURL,
+	)
+
+	html, err := deeplink_templates.Preview(templateVersion.BankCode, templateVersion.Body, templateVersion.Schemes, data)
+	if err != nil {
+		c.JSON(http.StatusUnprocessableEntity, ErrorResponse{Error: err.Error()})
+		return
+	}
+	c.Header("Content-Type", "text/html; charset=utf-8")
+	c.String(http.StatusOK, html)
+}
+
+// @Summary Activate deeplink template version
+// @Description Make template version active, deeplink pages switch to it immediately
+// @Tags admin
+// @Security BearerAuth
+// @Produce json
+// @Param bankCode path string true "bank code"
+// @Param version path int true "template version"
+// @Success 200 {object} adminResponse.DeeplinkTemplateVersion
+// @Failure 404 {object} ErrorResponse
+// @Failure 422 {object} adminResponse.ValidateDeeplinkTemplateResponse
+// @Failure 503 {object} ErrorResponse
+// @Router /admin/deeplink-templates/{bankCode}/versions/{version}/activate [post]
+func (h *DeeplinkTemplateHandler) Activate(c *gin.Context) {
+	version, ok := deeplinkTemplateVersionParam(c)
+	if !ok {
+		return
+	}
+	bankCode := c.Param("bankCode")
+	if err := h.Templates.Activate(bankCode, version); err != nil {
+		respondDeeplinkTemplateError(c, err)
+		return
+	}
+
+	templateVersion, err := h.Templates.GetVersion(bankCode, version)
+	if err != nil {
+		respondDeeplinkTemplateError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, toDeeplinkTemplateVersionResponse(templateVersion, false))
+}
+
+// @Summary Delete deeplink template version
+// @Description Delete inactive template version
+// @Tags admin
+// @Security BearerAuth
+// @Produce json
+// @Param bankCode path string true "bank code"
+// @Param version path int true "template version"
+// @Success 200
+// @Failure 404 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
+// @Router /admin/deeplink-templates/{bankCode}/versions/{version} [delete]
+func (h *DeeplinkTemplateHandler) DeleteVersion(c *gin.Context) {
+	version, ok := deeplinkTemplateVersionParam(c)
+	if !ok {
+		return
+	}
+	if err := h.Templates.DeleteVersion(c.Param("bankCode"), version); err != nil {
+		respondDeeplinkTemplateError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "OK"})
+}
+
+// @Summary Reload deeplink templates
+// @Description Re-read templates directory. On error previous templates stay active
+// @Tags admin
+// @Security BearerAuth
+// @Produce json
+// @Success 200
+// @Failure 422 {object} ErrorResponse
+// @Failure 503 {object} ErrorResponse
+// @Router /admin/deeplink-templates/reload [post]
+func (h *DeeplinkTemplateHandler) Reload(c *gin.Context) {
+	if err := h.Templates.Reload(); err != nil {
+		respondDeeplinkTemplateError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "OK"})
+}
+
+func deeplinkTemplateVersionParam(c *gin.Context) (int, bool) {
+	version, err := strconv.Atoi(c.Param("version"))
+	if err != nil || version <= 0 {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "version must be a positive integer"})
+		return 0, false
+	}
+	return version, true
+}
+
+// localizePreviewData переводит тестовые данные на язык предпросмотра, пустой язык - исходный
+func localizePreviewData(data map[string]interface{}, locale string) {
+	localizer := i18n.Builtin().Localizer(locale)
+	amount, _ := strconv.ParseFloat(data["Amount"].(string), 64)
+	data["L"] = localizer
+	data["Locale"] = localizer.Locale()
+	data["AmountFormatted"] = localizer.Amount(amount, "RUB")
+}
+
+func respondDeeplinkTemplateError(c *gin.Context, err error) {
+	var validationErr *deeplink_templates.ValidationError
+	switch {
+	case errors.As(err, &validationErr):
+		c.JSON(http.StatusUnprocessableEntity, adminResponse.ValidateDeeplinkTemplateResponse{
+			Valid:    false,
+			Problems: validationErr.Problems,
+		})
+	case errors.Is(err, deeplink_templates.ErrTemplateNotFound), errors.Is(err, deeplink_templates.ErrTemplateVersionNotFound):
+		c.JSON(http.StatusNotFound, ErrorResponse{Error: err.Error()})
+	case errors.Is(err, deeplink_templates.ErrActiveVersion):
+		c.JSON(http.StatusConflict, ErrorResponse{Error: err.Error()})
+	case errors.Is(err, deeplink_templates.ErrStoreReadOnly):
+		c.JSON(http.StatusServiceUnavailable, ErrorResponse{Error: err.Error()})
+	default:
+		c.JSON(http.StatusUnprocessableEntity, ErrorResponse{Error: err.Error()})
+	}
+}
+
+func toDeeplinkTemplateMeta(request adminRequest.DeeplinkTemplateRequest) deeplink_templates.TemplateMeta {
+	links := make([]domain.DeeplinkScheme, len(request.Links))
+	for i, link := range request.Links {
+		links[i] = domain.DeeplinkScheme{
+			Name:           link.Name,
+			Template:       link.Template,
+			OS:             link.OS,
+			AndroidPackage: link.AndroidPackage,
+		}
+	}
+	return deeplink_templates.TemplateMeta{
+		BankCode:         request.BankCode,
+		BankName:         request.BankName,
+		Icon:             request.Icon,
+		SupportedSystems: request.SupportedSystems,
+		TransferType:     request.TransferType,
+		Schemes:          request.Schemes,
+		Links:            links,
+		AppStoreURL:      request.AppStoreURL,
+		PlayStoreURL:     request.PlayStoreURL,
+		RequisitesPolicy: request.RequisitesPolicy,
+		Comment:          request.Comment,
+	}
+}
+
+func toDeeplinkTemplateVersionResponse(version deeplink_templates.TemplateVersion, withBody bool) adminResponse.DeeplinkTemplateVersion {
+	response := adminResponse.DeeplinkTemplateVersion{
+		BankCode:         version.BankCode,
+		Version:          version.Version,
+		Active:           version.Active,
+		BankName:         version.BankName,
+		Icon:             version.Icon,
+		SupportedSystems: version.SupportedSystems,
+		TransferType:     version.TransferType,
+		Schemes:          version.Schemes,
+		AppStoreURL:      version.AppStoreURL,
+		PlayStoreURL:     version.PlayStoreURL,
+		RequisitesPolicy: version.RequisitesPolicy,
+		Comment:          version.Comment,
+		CreatedAt:        version.CreatedAt.Format(time.RFC3339),
+	}
+	if response.RequisitesPolicy == "" {
+		response.RequisitesPolicy = deeplink_templates.RequisitesFull
+	}
+	for _, link := range version.Links {
+		response.Links = append(response.Links, adminResponse.DeeplinkTemplateLink{
+			Name:           link.Name,
+			Template:       link.Template,
+			OS:             link.OS,
+			AndroidPackage: link.AndroidPackage,
+		})
+	}
+	if withBody {
+		response.Body = version.Body
+	}
+	return response
+}
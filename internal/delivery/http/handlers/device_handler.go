@@ -1,21 +1,27 @@
 package handlers
 
 import (
+	"log"
 	"net/http"
 
 	"github.com/LavaJover/shvark-api-gateway/internal/client"
 	"github.com/LavaJover/shvark-api-gateway/internal/delivery/http/dto/device"
+	"github.com/LavaJover/shvark-api-gateway/internal/service"
 	orderpb "github.com/LavaJover/shvark-order-service/proto/gen/order"
 	"github.com/gin-gonic/gin"
 )
 
 type DeviceHandler struct {
 	OrderClient *client.OrderClient
+	Pairing *service.DevicePairingService
+	Watchdog *service.DeviceWatchdog
 }
 
-func NewDeviceHandler(orderClient *client.OrderClient) (*DeviceHandler, error) {
+func NewDeviceHandler(orderClient *client.OrderClient, pairing *service.DevicePairingService, watchdog *service.DeviceWatchdog) (*DeviceHandler, error) {
 	return &DeviceHandler{
 		OrderClient: orderClient,
+		Pairing: pairing,
+		Watchdog: watchdog,
 	}, nil
 }
 
@@ -104,6 +110,11 @@ func (h *DeviceHandler) DeleteDevice(c *gin.Context) {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
+	// телефон удаленного устройства больше не должен присылать уведомления
+	if err := h.Pairing.RevokeDevice(deviceID); err != nil && err != service.ErrDeviceNotPaired {
+		log.Printf("failed to revoke credentials of deleted device %s: %v", deviceID, err)
+	}
+	h.Watchdog.Forget(deviceID)
 	c.JSON(http.StatusOK, device.DeleteDeviceResponse{})
 }
 
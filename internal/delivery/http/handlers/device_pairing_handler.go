@@ -0,0 +1,166 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/LavaJover/shvark-api-gateway/internal/delivery/http/dto/device"
+	"github.com/LavaJover/shvark-api-gateway/internal/service"
+	"github.com/gin-gonic/gin"
+)
+
+// @Summary Start device pairing
+// @Description Issue a one-time pairing code for the trader's device. The code is shown as QR and exchanged by the phone app at /automatic/pair.
+// @Tags devices
+// @Produce json
+// @Security BearerAuth
+// @Param deviceId path string true "device ID"
+// @Success 201 {object} device.StartPairingResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 502 {object} ErrorResponse
+// @Router /devices/{deviceId}/pairing [post]
+func (h *DeviceHandler) StartPairing(c *gin.Context) {
+	traderID, ok := traderFromContext(c)
+	if !ok {
+		return
+	}
+
+	code, err := h.Pairing.StartPairing(traderID, c.Param("deviceId"))
+	if err != nil {
+		writePairingError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, device.StartPairingResponse{
+		DeviceID:  code.DeviceID,
+		Code:      code.Code,
+		QRPayload: code.QRPayload,
+		ExpiresAt: code.ExpiresAt.Unix(),
+	})
+}
+
+// @Summary Pair device
+// @Description Exchange a pairing code for a device credential. The credential is returned once and is sent as "Authorization: Device <credential>" to /automatic/*.
+// @Tags automatic
+// @Accept json
+// @Produce json
+// @Param input body device.PairDeviceRequest true "pairing code and phone info"
+// @Success 201 {object} device.PairDeviceResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Router /automatic/pair [post]
+func (h *DeviceHandler) PairDevice(c *gin.Context) {
+	var request device.PairDeviceRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	credential, paired, err := h.Pairing.Pair(request.Code, service.DeviceInfo{
+		Model:      request.DeviceModel,
+		AppVersion: request.AppVersion,
+	})
+	if errors.Is(err, service.ErrPairingCodeInvalid) {
+		c.JSON(http.StatusUnauthorized, ErrorResponse{Error: err.Error()})
+		return
+	}
+	if err != nil {
+		log.Printf("failed to pair device: %v", err)
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "failed to pair device"})
+		return
+	}
+
+	log.Printf("device %s paired for trader %s", paired.DeviceID, paired.TraderID)
+	c.JSON(http.StatusCreated, device.PairDeviceResponse{
+		Credential: credential,
+		DeviceID:   paired.DeviceID,
+		TraderID:   paired.TraderID,
+		PairedAt:   paired.PairedAt.Unix(),
+	})
+}
+
+// @Summary Get paired devices
+// @Description Active device credentials of the trader with last-seen time
+// @Tags devices
+// @Produce json
+// @Security BearerAuth
+// @Param traderId path string true "trader ID"
+// @Success 200 {object} device.GetDeviceCredentialsResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Router /devices/{traderId}/credentials [get]
+func (h *DeviceHandler) GetCredentials(c *gin.Context) {
+	credentials := h.Pairing.TraderCredentials(c.Param("traderId"))
+
+	result := make([]device.DeviceCredential, len(credentials))
+	for i, credential := range credentials {
+		result[i] = device.DeviceCredential{
+			DeviceID:    credential.DeviceID,
+			DeviceModel: credential.DeviceModel,
+			AppVersion:  credential.AppVersion,
+			PairedAt:    credential.PairedAt.Unix(),
+			LastSeenAt:  credential.LastSeenAt.Unix(),
+		}
+	}
+
+	c.JSON(http.StatusOK, device.GetDeviceCredentialsResponse{
+		Credentials: result,
+	})
+}
+
+// @Summary Revoke device credential
+// @Description Revoke the credential of the trader's device, the phone must be paired again
+// @Tags devices
+// @Produce json
+// @Security BearerAuth
+// @Param deviceId path string true "device ID"
+// @Success 200 {object} device.RevokeDeviceCredentialResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 502 {object} ErrorResponse
+// @Router /devices/{deviceId}/credential [delete]
+func (h *DeviceHandler) RevokeCredential(c *gin.Context) {
+	traderID, ok := traderFromContext(c)
+	if !ok {
+		return
+	}
+
+	deviceID := c.Param("deviceId")
+	if err := h.Pairing.Revoke(traderID, deviceID); err != nil {
+		writePairingError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, device.RevokeDeviceCredentialResponse{
+		DeviceID: deviceID,
+	})
+}
+
+func traderFromContext(c *gin.Context) (string, bool) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, ErrorResponse{Error: "userID not found in context"})
+		return "", false
+	}
+	traderID, ok := userID.(string)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, ErrorResponse{Error: "invalid user ID"})
+		return "", false
+	}
+	return traderID, true
+}
+
+func writePairingError(c *gin.Context, err error) {
+	var upstreamErr *service.UpstreamError
+	switch {
+	case errors.Is(err, service.ErrDeviceNotOwned), errors.Is(err, service.ErrDeviceNotPaired):
+		c.JSON(http.StatusNotFound, ErrorResponse{Error: err.Error()})
+	case errors.As(err, &upstreamErr):
+		c.JSON(http.StatusBadGateway, ErrorResponse{Error: "devices are unavailable now"})
+	default:
+		log.Printf("device pairing error: %v", err)
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "internal error"})
+	}
+}
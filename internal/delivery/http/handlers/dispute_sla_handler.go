@@ -0,0 +1,117 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	adminResponse "github.com/LavaJover/shvark-api-gateway/internal/delivery/http/dto/admin/response"
+	"github.com/LavaJover/shvark-api-gateway/internal/domain"
+	"github.com/LavaJover/shvark-api-gateway/internal/service"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	disputeQueueDefaultLimit = 50
+	disputeQueueMaxLimit     = 500
+)
+
+type DisputeSLAHandler struct {
+	DisputeSLA *service.DisputeSLAService
+}
+
+func NewDisputeSLAHandler(disputeSLA *service.DisputeSLAService) *DisputeSLAHandler {
+	return &DisputeSLAHandler{DisputeSLA: disputeSLA}
+}
+
+// @Summary Dispute queue
+// @Description Open disputes sorted by urgency: overdue first, then by the deadline of the current stage, larger disputes first on equal deadlines. Stages: trader_response, admin_review, frozen.
+// @Tags admin
+// @Security BearerAuth
+// @Produce json
+// @Param stage query string false "trader_response, admin_review or frozen"
+// @Param urgency query string false "overdue, due_soon or on_track"
+// @Param trader_id query string false "trader of the order"
+// @Param offset query int false "offset" default(0)
+// @Param limit query int false "page size" default(50) maximum(500)
+// @Success 200 {object} adminResponse.DisputeQueueResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 502 {object} ErrorResponse
+// @Router /admin/disputes/queue [get]
+func (h *DisputeSLAHandler) GetQueue(c *gin.Context) {
+	filter := service.DisputeQueueFilter{
+		Stage:    domain.DisputeStage(c.Query("stage")),
+		Urgency:  domain.DisputeUrgency(c.Query("urgency")),
+		TraderID: c.Query("trader_id"),
+	}
+	switch filter.Stage {
+	case "", domain.DisputeStageTraderResponse, domain.DisputeStageAdminReview, domain.DisputeStageFrozen:
+	default:
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "unknown stage " + string(filter.Stage)})
+		return
+	}
+	switch filter.Urgency {
+	case "", domain.DisputeUrgencyOverdue, domain.DisputeUrgencyDueSoon, domain.DisputeUrgencyOnTrack:
+	default:
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "unknown urgency " + string(filter.Urgency)})
+		return
+	}
+	filter.Offset, _ = strconv.Atoi(c.DefaultQuery("offset", "0"))
+	filter.Limit, _ = strconv.Atoi(c.DefaultQuery("limit", strconv.Itoa(disputeQueueDefaultLimit)))
+	if filter.Offset < 0 || filter.Limit < 1 || filter.Limit > disputeQueueMaxLimit {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "offset must be >= 0, limit between 1 and 500"})
+		return
+	}
+
+	queue, err := h.DisputeSLA.Queue(filter)
+	if err != nil {
+		var upstream *service.UpstreamError
+		if errors.As(err, &upstream) {
+			c.JSON(http.StatusBadGateway, ErrorResponse{Error: err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	now := time.Now()
+	response := adminResponse.DisputeQueueResponse{
+		Disputes:  make([]adminResponse.DisputeQueueItem, len(queue.Items)),
+		Total:     queue.Total,
+		Offset:    filter.Offset,
+		Limit:     filter.Limit,
+		Truncated: queue.Truncated,
+	}
+	for i, item := range queue.Items {
+		response.Disputes[i] = adminResponse.DisputeQueueItem{
+			DisputeID:         item.DisputeID,
+			OrderID:           item.OrderID,
+			MerchantOrderID:   item.MerchantOrderID,
+			TraderID:          item.TraderID,
+			DisputeStatus:     item.Status,
+			DisputeReason:     item.Reason,
+			ProofUrl:          item.ProofURL,
+			DisputeAmountFiat: item.AmountFiat,
+			OrderAmountFiat:   item.OrderAmountFiat,
+			Stage:             string(item.SLA.Stage),
+			Urgency:           string(item.Urgency),
+			OpenedAt:          item.SLA.OpenedAt,
+			StageStartedAt:    item.SLA.StageStartedAt,
+			Deadline:          item.SLA.Deadline,
+			SecondsLeft:       int64(item.SLA.Deadline.Sub(now) / time.Second),
+			ExpiresAt:         optionalTime(item.SLA.ExpiresAt),
+			Reminded:          item.SLA.Reminded,
+			Escalated:         item.SLA.Escalated,
+			EscalatedAt:       optionalTime(item.SLA.EscalatedAt),
+		}
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+func optionalTime(t time.Time) *time.Time {
+	if t.IsZero() {
+		return nil
+	}
+	return &t
+}
@@ -0,0 +1,223 @@
+package handlers
+
+import (
+	"errors"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+
+	"github.com/LavaJover/shvark-api-gateway/internal/client"
+	"github.com/LavaJover/shvark-api-gateway/internal/delivery/http/dto/evidence"
+	"github.com/LavaJover/shvark-api-gateway/internal/domain"
+	"github.com/LavaJover/shvark-api-gateway/internal/service"
+	"github.com/gin-gonic/gin"
+)
+
+// evidenceFormOverhead запас на поля и границы multipart сверх лимита самого файла
+const evidenceFormOverhead = 64 << 10
+
+type EvidenceHandler struct {
+	Evidence    *service.EvidenceService
+	AuthzClient *client.AuthzClient
+}
+
+func NewEvidenceHandler(evidence *service.EvidenceService, authzClient *client.AuthzClient) *EvidenceHandler {
+	return &EvidenceHandler{
+		Evidence:    evidence,
+		AuthzClient: authzClient,
+	}
+}
+
+// @Summary Upload dispute evidence
+// @Description Upload a receipt or screenshot for an order or its dispute. Allowed: JPEG, PNG, PDF; the type is detected from the content. EXIF and other image metadata is removed. Only the order merchant, trader and admins can upload and download.
+// @Tags evidence
+// @Security BearerAuth
+// @Accept multipart/form-data
+// @Produce json
+// @Param file formData file true "receipt or screenshot"
+// @Param order_id formData string false "order ID, required without dispute_id"
+// @Param dispute_id formData string false "dispute ID"
+// @Success 201 {object} evidence.Evidence
+// @Failure 400 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 413 {object} ErrorResponse
+// @Failure 415 {object} ErrorResponse
+// @Failure 502 {object} ErrorResponse
+// @Router /evidence [post]
+func (h *EvidenceHandler) Upload(c *gin.Context) {
+	actor, ok := h.actor(c)
+	if !ok {
+		return
+	}
+
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, h.Evidence.MaxFileSize()+evidenceFormOverhead)
+	header, err := c.FormFile("file")
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			c.JSON(http.StatusRequestEntityTooLarge, ErrorResponse{Error: service.ErrEvidenceTooLarge.Error()})
+			return
+		}
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "file is required"})
+		return
+	}
+	if header.Size > h.Evidence.MaxFileSize() {
+		c.JSON(http.StatusRequestEntityTooLarge, ErrorResponse{Error: service.ErrEvidenceTooLarge.Error()})
+		return
+	}
+	file, err := header.Open()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+		return
+	}
+	defer file.Close()
+	data, err := io.ReadAll(file)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	uploaded, err := h.Evidence.Upload(actor, c.PostForm("order_id"), c.PostForm("dispute_id"), header.Filename, data)
+	if err != nil {
+		h.fail(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, h.evidenceResponse(uploaded))
+}
+
+// @Summary List dispute evidence
+// @Description Evidence of an order or of one of its disputes, with fresh download links
+// @Tags evidence
+// @Security BearerAuth
+// @Produce json
+// @Param order_id query string false "order ID, required without dispute_id"
+// @Param dispute_id query string false "dispute ID"
+// @Success 200 {object} evidence.ListResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 502 {object} ErrorResponse
+// @Router /evidence [get]
+func (h *EvidenceHandler) List(c *gin.Context) {
+	actor, ok := h.actor(c)
+	if !ok {
+		return
+	}
+	list, err := h.Evidence.List(actor, c.Query("order_id"), c.Query("dispute_id"))
+	if err != nil {
+		h.fail(c, err)
+		return
+	}
+	response := evidence.ListResponse{Evidence: make([]evidence.Evidence, len(list))}
+	for i, item := range list {
+		response.Evidence[i] = h.evidenceResponse(item)
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// @Summary Get dispute evidence link
+// @Description Evidence metadata with a fresh signed download link
+// @Tags evidence
+// @Security BearerAuth
+// @Produce json
+// @Param id path string true "evidence ID"
+// @Success 200 {object} evidence.Evidence
+// @Failure 403 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 502 {object} ErrorResponse
+// @Router /evidence/{id} [get]
+func (h *EvidenceHandler) Get(c *gin.Context) {
+	actor, ok := h.actor(c)
+	if !ok {
+		return
+	}
+	item, err := h.Evidence.Get(actor, c.Param("id"))
+	if err != nil {
+		h.fail(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, h.evidenceResponse(item))
+}
+
+// @Summary Download dispute evidence
+// @Description Download by a signed link from upload, list or get. No authorization header needed: the link expires.
+// @Tags evidence
+// @Produce octet-stream
+// @Param id path string true "evidence ID"
+// @Param token query string true "signed token from the link"
+// @Success 200 {file} file
+// @Failure 403 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Router /evidence/files/{id} [get]
+func (h *EvidenceHandler) Download(c *gin.Context) {
+	item, file, err := h.Evidence.Open(c.Param("id"), c.Query("token"))
+	if err != nil {
+		h.fail(c, err)
+		return
+	}
+	defer file.Close()
+
+	c.DataFromReader(http.StatusOK, item.Size, item.ContentType, file, map[string]string{
+		"Content-Disposition":    mime.FormatMediaType("attachment", map[string]string{"filename": item.FileName}),
+		"X-Content-Type-Options": "nosniff",
+		"Cache-Control":          "private, no-store",
+	})
+}
+
+// actor текущий пользователь; админ - с правом на доказательства любых ордеров
+func (h *EvidenceHandler) actor(c *gin.Context) (service.EvidenceActor, bool) {
+	userID, ok := traderFromContext(c)
+	if !ok {
+		return service.EvidenceActor{}, false
+	}
+	resp, err := h.AuthzClient.CheckPermission(userID, "dispute_evidence", "manage")
+	if err != nil {
+		c.JSON(http.StatusBadGateway, ErrorResponse{Error: "authz error"})
+		return service.EvidenceActor{}, false
+	}
+	return service.EvidenceActor{UserID: userID, Admin: resp.Allowed}, true
+}
+
+func (h *EvidenceHandler) fail(c *gin.Context, err error) {
+	var upstream *service.UpstreamError
+	switch {
+	case errors.Is(err, service.ErrEvidenceTarget),
+		errors.Is(err, service.ErrEvidenceDisputeMismatch),
+		errors.Is(err, service.ErrEvidenceMalformed):
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+	case errors.Is(err, service.ErrEvidenceForbidden),
+		errors.Is(err, service.ErrEvidenceLinkInvalid):
+		c.JSON(http.StatusForbidden, ErrorResponse{Error: err.Error()})
+	case errors.Is(err, service.ErrEvidenceNotFound),
+		errors.Is(err, service.ErrEvidenceOrderNotFound):
+		c.JSON(http.StatusNotFound, ErrorResponse{Error: err.Error()})
+	case errors.Is(err, service.ErrEvidenceTooLarge):
+		c.JSON(http.StatusRequestEntityTooLarge, ErrorResponse{Error: err.Error()})
+	case errors.Is(err, service.ErrEvidenceType):
+		c.JSON(http.StatusUnsupportedMediaType, ErrorResponse{Error: err.Error()})
+	case errors.As(err, &upstream):
+		c.JSON(http.StatusBadGateway, ErrorResponse{Error: err.Error()})
+	default:
+		log.Printf("evidence: %v", err)
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "failed to process evidence"})
+	}
+}
+
+func (h *EvidenceHandler) evidenceResponse(item domain.DisputeEvidence) evidence.Evidence {
+	url, expiresAt := h.Evidence.SignedURL(item)
+	return evidence.Evidence{
+		ID:           item.ID,
+		OrderID:      item.OrderID,
+		DisputeID:    item.DisputeID,
+		UploadedBy:   item.UploadedBy,
+		FileName:     item.FileName,
+		ContentType:  item.ContentType,
+		Size:         item.Size,
+		SHA256:       item.SHA256,
+		CreatedAt:    item.CreatedAt.Unix(),
+		URL:          url,
+		URLExpiresAt: expiresAt.Unix(),
+	}
+}
@@ -0,0 +1,33 @@
+package handlers
+
+import (
+	"net/http"
+
+	adminResponse "github.com/LavaJover/shvark-api-gateway/internal/delivery/http/dto/admin/response"
+	"github.com/LavaJover/shvark-api-gateway/internal/service/i18n"
+	"github.com/gin-gonic/gin"
+)
+
+type I18nHandler struct {
+	Catalog *i18n.Catalog
+}
+
+func NewI18nHandler(catalog *i18n.Catalog) *I18nHandler {
+	return &I18nHandler{
+		Catalog: catalog,
+	}
+}
+
+// @Summary Get missing translations
+// @Description Untranslated message keys by locale, found in catalogs at startup and while rendering pages
+// @Tags admin
+// @Security BearerAuth
+// @Produce json
+// @Success 200 {object} adminResponse.GetMissingTranslationsResponse
+// @Router /admin/i18n/missing [get]
+func (h *I18nHandler) GetMissing(c *gin.Context) {
+	c.JSON(http.StatusOK, adminResponse.GetMissingTranslationsResponse{
+		Locales: h.Catalog.Locales(),
+		Missing: h.Catalog.Missing(),
+	})
+}
@@ -1,37 +1,33 @@
 package handlers
 
 import (
+	"errors"
 	"net/http"
 	"strconv"
 	"time"
 
-	"github.com/LavaJover/shvark-api-gateway/internal/client"
 	"github.com/LavaJover/shvark-api-gateway/internal/delivery/http/dto/merchant"
+	"github.com/LavaJover/shvark-api-gateway/internal/service"
+	"github.com/LavaJover/shvark-api-gateway/internal/service/bank_catalog"
 	orderpb "github.com/LavaJover/shvark-order-service/proto/gen/order"
 	"github.com/gin-gonic/gin"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
-	"google.golang.org/protobuf/types/known/timestamppb"
 )
 
+// MerchantHandler legacy-ручки /merchant поверх service.MerchantService.
+// Форматы запросов и ответов заморожены ради существующих интеграций.
 type MerchantHandler struct {
-	OrderClient *client.OrderClient
-	WalletClient *client.HTTPWalletClient
-	UserClient *client.UserClient
-	SsoClient *client.SSOClient
+	MerchantService *service.MerchantService
+	Sandbox 		*service.MerchantService // запросы с ключом песочницы, nil - песочница выключена
+	Banks 			*bank_catalog.Catalog
 }
 
-func NewMerchanHandler(
-	orderClient *client.OrderClient,
-	walletClient *client.HTTPWalletClient,
-	userClient *client.UserClient,
-	ssoClient *client.SSOClient,
-) *MerchantHandler {
+func NewMerchanHandler(merchantService, sandbox *service.MerchantService, banks *bank_catalog.Catalog) *MerchantHandler {
 	return &MerchantHandler{
-		OrderClient: orderClient,
-		WalletClient: walletClient,
-		UserClient: userClient,
-		SsoClient: ssoClient,
+		MerchantService: merchantService,
+		Sandbox: sandbox,
+		Banks: banks,
 	}
 }
 
@@ -54,46 +50,46 @@ func (h *MerchantHandler) CreatePayIn(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "accountID path param missed"})
 		return
 	}
+	if c.GetBool("sandbox") {
+		// ордера песочницы создаются только на мерчанта ключа
+		merchantID = c.GetString("userID")
+	}
 	var request merchant.CreatePayInRequest
 	if err := c.ShouldBindJSON(&request); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
-	orderServiceRequest := orderpb.CreatePayInOrderRequest{
-		MerchantId: merchantID,
+	params := service.PayInParams{
+		MerchantID: merchantID,
+		MerchantOrderID: request.IternalID,
 		AmountFiat: request.Amount,
 		Currency: request.Currency,
 		Country: "Russia",
-		ClientId: "",
-		ExpiresAt: timestamppb.New(time.Now().Add(20*time.Minute)),
-		MerchantOrderId: request.IternalID,
-		Shuffle: 0,
-		CallbackUrl: request.CallbackUrl,
 		BankCode: request.Issuer,
 		NspkCode: request.NspkCode,
-		Type: "DEPOSIT",
+		CallbackURL: request.CallbackUrl,
 	}
 	if request.IsSbp {
-		orderServiceRequest.PaymentSystem = "SBP"
+		params.PaymentSystem = "SBP"
 	}else {
-		orderServiceRequest.PaymentSystem = "C2C"
+		params.PaymentSystem = "C2C"
 	}
-	orderServiceResponse, err := h.OrderClient.CreatePayInOrder(&orderServiceRequest)
+	order, err := merchantServiceFor(c, h.MerchantService, h.Sandbox).CreatePayIn(params)
 	if err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
 		return
 	}
 	c.JSON(http.StatusCreated, merchant.CreatePayInResponse{
-		OrderID: orderServiceResponse.Order.OrderId,
-		CardNumber: orderServiceResponse.Order.BankDetail.CardNumber,
-		PhoneNumber: orderServiceResponse.Order.BankDetail.Phone,
-		HolderName: orderServiceResponse.Order.BankDetail.Owner,
-		Issuer: orderServiceResponse.Order.BankDetail.BankCode,
-		NspkCode: orderServiceResponse.Order.BankDetail.NspkCode,
-		Amount: orderServiceResponse.Order.AmountFiat,
-		AmountByCurrency: orderServiceResponse.Order.AmountCrypto,
-		CurrencyRate: orderServiceResponse.Order.CryptoRubRate,
-		TimeExpires: orderServiceResponse.Order.ExpiresAt.AsTime().UTC().Format("2006-01-02T15:04:05Z"),
+		OrderID: order.ID,
+		CardNumber: order.BankDetail.CardNumber,
+		PhoneNumber: order.BankDetail.Phone,
+		HolderName: order.BankDetail.Owner,
+		Issuer: order.BankDetail.BankCode,
+		NspkCode: order.BankDetail.NspkCode,
+		Amount: order.AmountFiat,
+		AmountByCurrency: order.AmountCrypto,
+		CurrencyRate: order.CryptoRubRate,
+		TimeExpires: order.ExpiresAt.UTC().Format("2006-01-02T15:04:05Z"),
 	})
 }
 
@@ -138,73 +134,57 @@ func (h *MerchantHandler) GetOrders(c *gin.Context) {
 		return
 	}
 
-	userResp, err := h.UserClient.GetUserByID(merchantIDstr)
+	orders, err := merchantServiceFor(c, h.MerchantService, h.Sandbox).ListOrders(merchantIDstr, service.OrdersFilter{
+		DealID:           params.DealID,
+		Type:             params.Type,
+		Status:           params.Status,
+		TimeOpeningStart: params.TimeOpeningStart,
+		TimeOpeningEnd:   params.TimeOpeningEnd,
+		AmountMin:        params.AmountMin,
+		AmountMax:        params.AmountMax,
+		Page:             params.Page,
+		Size:             params.Size,
+		Sort:             params.Sort,
+	})
 	if err != nil {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "failed to find user"})
+		respondLegacyOrdersError(c, err)
 		return
 	}
 
-    // Обрабатываем значения по умолчанию для page и size
-    page := 0
-    if params.Page != nil {
-        page = *params.Page
-    }
-
-    size := 10 // значение по умолчанию
-    if params.Size != nil {
-        size = *params.Size
-    }
-
-    // Ограничиваем максимальный размер страницы
-    if size > 100 {
-        size = 100
-    }
-
-    // Формируем gRPC запрос
-    grpcReq := &orderpb.GetOrdersRequest{
-        MerchantId: merchantID.(string),
-        DealId:     params.DealID,
-        Type:       params.Type,
-        Status:     params.Status,
-        AmountMin:  params.AmountMin,
-        AmountMax:  params.AmountMax,
-        Sort:       params.Sort,
-        Page:       int32(page),
-        Size:       int32(size),
-    }
-
-    // Преобразуем временные параметры
-    if params.TimeOpeningStart != nil {
-        grpcReq.TimeOpeningStart = timestamppb.New(*params.TimeOpeningStart)
-    }
-    if params.TimeOpeningEnd != nil {
-        grpcReq.TimeOpeningEnd = timestamppb.New(*params.TimeOpeningEnd)
-    }
-    
-    grpcResp, err := h.OrderClient.GetOrders(grpcReq)
-    if err != nil {
-        // Обработка ошибок gRPC
-        st, ok := status.FromError(err)
-        if ok {
-            switch st.Code() {
-            case codes.InvalidArgument:
-                c.JSON(http.StatusBadRequest, gin.H{"error": st.Message()})
-            case codes.NotFound:
-                c.JSON(http.StatusNotFound, gin.H{"error": st.Message()})
-            default:
-                c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
-            }
-        } else {
-            c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get orders"})
-        }
-        return
-    }
-
     // Преобразуем gRPC ответ в HTTP ответ
-    response := toHTTPResponse(grpcResp, userResp.Login, userResp.Username)
+    response := toHTTPResponse(orders.Page, orders.Login, orders.Username)
     c.JSON(http.StatusOK, response)
 }
 
+// respondLegacyOrdersError отдает ошибки списка ордеров в формате /merchant и /payments
+func respondLegacyOrdersError(c *gin.Context, err error) {
+	var upstreamErr *service.UpstreamError
+	if errors.As(err, &upstreamErr) {
+		if upstreamErr.Upstream == service.UpstreamUser {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "failed to find user"})
+			return
+		}
+		// status.FromError на обертке подставляет в сообщение err.Error() целиком,
+		// а legacy-формат отдает только описание из статуса
+		err = upstreamErr.Err
+	}
+
+	// Обработка ошибок gRPC
+	st, ok := status.FromError(err)
+	if ok {
+		switch st.Code() {
+		case codes.InvalidArgument:
+			c.JSON(http.StatusBadRequest, gin.H{"error": st.Message()})
+		case codes.NotFound:
+			c.JSON(http.StatusNotFound, gin.H{"error": st.Message()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		}
+	} else {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get orders"})
+	}
+}
+
 // Структуры для Swagger
 
 // GetOrdersResponse структура успешного ответа
@@ -354,13 +334,13 @@ func toHTTPSort(s *orderpb.Sort) Sort {
 // @Router /merchant/order/{iternalId}/status [get]
 func (h *MerchantHandler) GetOrderStatus(c *gin.Context) {
 	iternalID := c.Param("iternalId")
-	orderResponse, err := h.OrderClient.GetOrderByMerchantOrderID(iternalID)
+	order, err := orderByMerchantOrderIDFor(c, h.MerchantService, h.Sandbox, iternalID)
 	if err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "failed to find order"})
 		return
 	}
 	c.JSON(http.StatusOK, merchant.GetOrderStatusResponse{
-		Status: orderResponse.Order.Status,
+		Status: string(order.Status),
 	})
 }
 
@@ -388,30 +368,34 @@ func (h *MerchantHandler) GetAccountBalance(c *gin.Context) {
 		return
 	}
 
-	balance, err := h.WalletClient.GetBalance(userIDstr)
-	if err != nil {
-		c.JSON(http.StatusBadGateway, gin.H{"error": err.Error()})
-		return
-	}
-
-	userResp, err := h.UserClient.GetUserByID(userIDstr)
+	balance, err := merchantServiceFor(c, h.MerchantService, h.Sandbox).GetBalance(userIDstr)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get account info"})
+		respondLegacyBalanceError(c, err)
 		return
 	}
 
 	c.JSON(http.StatusOK, merchant.GetAccountBalanceResponse{
 		Balances: []merchant.AccountBalance{
 			merchant.AccountBalance{
-				Name: userResp.Username,
-				Balance: strconv.FormatFloat(balance, 'f', 6, 64),
-				Currency: "USDT",
+				Name: balance.AccountName,
+				Balance: strconv.FormatFloat(balance.Balance, 'f', 6, 64),
+				Currency: balance.Currency,
 			},
 		},
 	})
 
 }
 
+// respondLegacyBalanceError отдает ошибки баланса в формате /merchant и /payments
+func respondLegacyBalanceError(c *gin.Context, err error) {
+	var upstreamErr *service.UpstreamError
+	if errors.As(err, &upstreamErr) && upstreamErr.Upstream == service.UpstreamUser {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get account info"})
+		return
+	}
+	c.JSON(http.StatusBadGateway, gin.H{"error": err.Error()})
+}
+
 // @Summary Withdraw crypto from account wallet
 // @Description Withdraw USDT
 // @Tags merchant
@@ -441,7 +425,7 @@ func (h *MerchantHandler) Withdraw(c *gin.Context) {
 		return
 	}
 
-	txHash, err := h.WalletClient.Withdraw(
+	txHash, err := merchantServiceFor(c, h.MerchantService, h.Sandbox).Withdraw(
 		userIDstr,
 		withdrawRequest.ToAddress,
 		withdrawRequest.Amount,
@@ -477,7 +461,7 @@ func (h *MerchantHandler) Login(c *gin.Context) {
 		c.JSON(http.StatusOK, gin.H{"error": err.Error()})
 		return
 	}
-	loginResponse, err := h.SsoClient.Login(
+	session, err := h.MerchantService.SignIn(
 		request.Email,
 		request.Password,
 		request.TwoFaCode,
@@ -487,8 +471,8 @@ func (h *MerchantHandler) Login(c *gin.Context) {
 		return
 	}
 	c.JSON(http.StatusOK, merchant.LoginResponse{
-		Token: loginResponse.AccessToken,
-		DateTimeExpires: loginResponse.TimeExp.AsTime().Format("2006-01-02 15:04:05"),
+		Token: session.Token,
+		DateTimeExpires: session.ExpiresAt.Format("2006-01-02 15:04:05"),
 	})
 }
 
@@ -498,1040 +482,22 @@ func (h *MerchantHandler) Login(c *gin.Context) {
 // @Tags merchant
 // @Accept json
 // @Produce json
-// @Success 200
+// @Param country query string false "country code, e.g. RU"
+// @Param payment_system query string false "payment system, e.g. SBP"
+// @Success 200 {array} merchant.Bank
 // @Router /merchant/banks [get]
 func (h *MerchantHandler) GetBanks(c *gin.Context) {
-	banks := []merchant.Bank{
-		// {
-		// 	Code: "MTS-SIM",
-		// 	Name: "МТС SIM",
-		// 	NspkCode: "",
-		// },
-		// {
-		// 	Code: "BEELINE-SIM",
-		// 	Name: "Билайн SIM",
-		// 	NspkCode: "",
-		// },
-		{
-			Code: "sberbank",
-			Name: "Сбербанк",
-			NspkCode: "100000000111",
-		  },
-		  {
-			Code: "tinkoff",
-			Name: "Т-Банк",
-			NspkCode: "100000000004",
-		  },
-		  {
-			Code: "vtb",
-			Name: "Банк ВТБ",
-			NspkCode: "110000000005",
-		  },
-		  {
-			Code: "alfabank",
-			Name: "АЛЬФА-БАНК",
-			NspkCode: "100000000008",
-		  },
-		  {
-			Code: "raiffeisenbank",
-			Name: "Райффайзенбанк",
-			NspkCode: "100000000007",
-		  },
-		  {
-			Code: "bank_open",
-			Name: "Банк ОТКРЫТИЕ",
-			NspkCode: "100000000015",
-		  },
-		  {
-			Code: "gazprombank",
-			Name: "Газпромбанк",
-			NspkCode: "100000000001",
-		  },
-		  {
-			Code: "promsvyaz",
-			Name: "Промсвязьбанк",
-			NspkCode: "100000000010",
-		  },
-		  {
-			Code: "sovkom",
-			Name: "Совкомбанк",
-			NspkCode: "100000000013",
-		  },
-		  {
-			Code: "ros_bank",
-			Name: "РОСБАНК",
-			NspkCode: "100000000012",
-		  },
-		  {
-			Code: "rsb",
-			Name: "Банк Русский Стандарт",
-			NspkCode: "100000000014",
-		  },
-		  {
-			Code: "absolute_bank",
-			Name: "АКБ Абсолют Банк",
-			NspkCode: "100000000047",
-		  },
-		  {
-			Code: "home_bank",
-			Name: "Хоум кредит",
-			NspkCode: "100000000024",
-		  },
-		  {
-			Code: "otp_bank",
-			Name: "ОТП Банк",
-			NspkCode: "100000000018",
-		  },
-		  {
-			Code: "uralsib",
-			Name: "БАНК УРАЛСИБ",
-			NspkCode: "100000000026",
-		  },
-		  {
-			Code: "ak_bars_bank",
-			Name: "АК БАРС БАНК",
-			NspkCode: "100000000006",
-		  },
-		  {
-			Code: "fora",
-			Name: "АКБ ФОРА-БАНК",
-			NspkCode: "100000000217",
-		  },
-		  {
-			Code: "rost_finance",
-			Name: "КБ РостФинанс",
-			NspkCode: "100000000098",
-		  },
-		  {
-			Code: "ozon",
-			Name: "Озон Банк (Ozon)",
-			NspkCode: "100000000273",
-		  },
-		  {
-			Code: "unistream",
-			Name: "КБ ЮНИСТРИМ",
-			NspkCode: "100000000042",
-		  },
-		  {
-			Code: "mts",
-			Name: "МТС-Банк",
-			NspkCode: "100000000017",
-		  },
-		  {
-			Code: "tkb",
-			Name: "ТрансКапиталБанк",
-			NspkCode: "100000000034",
-		  },
-		  {
-			Code: "pochta",
-			Name: "Почта Банк",
-			NspkCode: "100000000016",
-		  },
-		  {
-			Code: "rncb",
-			Name: "РНКБ Банк",
-			NspkCode: "100000000011",
-		  },
-		  {
-			Code: "genbank",
-			Name: "ГЕНБАНК",
-			NspkCode: "100000000037",
-		  },
-		  {
-			Code: "cifra",
-			Name: "Цифра банк",
-			NspkCode: "100000000265",
-		  },
-		  {
-			Code: "ingo",
-			Name: "Ингосстрах Банк",
-			NspkCode: "100000000078",
-		  },
-		  {
-			Code: "svoi",
-			Name: "Свой Банк",
-			NspkCode: "100000000006",
-		  },
-		  {
-			Code: "avangard",
-			Name: "АКБ АВАНГАРД",
-			NspkCode: "100000000028",
-		  },
-		  {
-			Code: "rencredit",
-			Name: "КБ Ренессанс Кредит",
-			NspkCode: "100000000032",
-		  },
-		  {
-			Code: "solid",
-			Name: "КБ Солидарность",
-			NspkCode: "100000000121",
-		  },
-		  {
-			Code: "pucbr",
-			Name: "ПУ Банк России",
-			NspkCode: "100000000027",
-		  },
-		  {
-			Code: "expobank",
-			Name: "Экспобанк",
-			NspkCode: "100000000044",
-		  },
-		  {
-			Code: "apkbank",
-			Name: "КБ АГРОПРОМКРЕДИТ",
-			NspkCode: "100000000118",
-		  },
-		  {
-			Code: "bktb",
-			Name: "Кубаньторгбанк",
-			NspkCode: "100000000180",
-		  },
-		  {
-			Code: "bystrobank",
-			Name: "БыстроБанк",
-			NspkCode: "100000000092",
-		  },
-		  {
-			Code: "nico-bank",
-			Name: "НИКО-БАНК",
-			NspkCode: "100000000115",
-		  },
-		  {
-			Code: "okbank",
-			Name: "Банк Объединенный капитал",
-			NspkCode: "100000000182",
-		  },
-		  {
-			Code: "pscb",
-			Name: "Банк ПСКБ",
-			NspkCode: "100000000087",
-		  },
-		  {
-			Code: "zarech",
-			Name: "Банк Заречье",
-			NspkCode: "100000000205",
-		  },
-		  {
-			Code: "zemsky",
-			Name: "Земский банк",
-			NspkCode: "100000000066",
-		  },
-		  {
-			Code: "abr",
-			Name: "АБ РОССИЯ",
-			NspkCode: "100000000095",
-		  },
-		  {
-			Code: "bksbank",
-			Name: "БКС Банк",
-			NspkCode: "100000000041",
-		  },
-		  {
-			Code: "chelinvest",
-			Name: "ЧЕЛЯБИНВЕСТБАНК",
-			NspkCode: "100000000094",
-		  },
-		  {
-			Code: "databank",
-			Name: "Датабанк",
-			NspkCode: "100000000070",
-		  },
-		  {
-			Code: "domrfbank",
-			Name: "Банк ДОМ.РФ",
-			NspkCode: "100000000082",
-		  },
-		  {
-			Code: "energobank",
-			Name: "АКБ Энергобанк",
-			NspkCode: "100000000159",
-		  },
-		  {
-			Code: "forshtadt",
-			Name: "АКБ Форштадт",
-			NspkCode: "100000000081",
-		  },
-		  {
-			Code: "gaztransbank",
-			Name: "Газтрансбанк",
-			NspkCode: "100000000183",
-		  },
-		  {
-			Code: "gebank",
-			Name: "Газэнергобанк",
-			NspkCode: "100000000043",
-		  },
-		  {
-			Code: "in-bank",
-			Name: "Инбанк",
-			NspkCode: "100000000196",
-		  },
-		  {
-			Code: "iturupbank",
-			Name: "Банк ИТУРУП",
-			NspkCode: "100000000158",
-		  },
-		  {
-			Code: "kbb",
-			Name: "Кузнецкбизнесбанк",
-			NspkCode: "100000000195",
-		  },
-		  {
-			Code: "kbhmb",
-			Name: "Хакасский муниципальный банк",
-			NspkCode: "100000000127",
-		  },
-		  {
-			Code: "kkbank",
-			Name: "КБ Кубань Кредит",
-			NspkCode: "100000000050",
-		  },
-		  {
-			Code: "koshelev-bank",
-			Name: "КОШЕЛЕВ-БАНК",
-			NspkCode: "100000000146",
-		  },
-		  {
-			Code: "kremlinbank",
-			Name: "Банк Кремлевский",
-			NspkCode: "100000000201",
-		  },
-		  {
-			Code: "maritimebank",
-			Name: "МОРСКОЙ БАНК",
-			NspkCode: "100000000171",
-		  },
-		  {
-			Code: "mcbank",
-			Name: "БАНК МОСКВА-СИТИ",
-			NspkCode: "100000000234",
-		  },
-		  {
-			Code: "metallinvestbank",
-			Name: "Металлинвестбанк",
-			NspkCode: "100000000046",
-		  },
-		  {
-			Code: "akibank",
-			Name: "АКИБАНК",
-			NspkCode: "100000000107",
-		  },
-		  {
-			Code: "modulbank",
-			Name: "КБ Модульбанк",
-			NspkCode: "100000000099",
-		  },
-		  {
-			Code: "mp-bank",
-			Name: "МП Банк",
-			NspkCode: "100000000169",
-		  },
-		  {
-			Code: "nkbank",
-			Name: "НК Банк",
-			NspkCode: "100000000233",
-		  },
-		  {
-			Code: "norvikbank",
-			Name: "Норвик Банк",
-			NspkCode: "100000000202",
-		  },
-		  {
-			Code: "novikom",
-			Name: "Банк НОВИКОМ (НОВИКОМБАНК)",
-			NspkCode: "100000000177",
-		  },
-		  {
-			Code: "novobank",
-			Name: "УКБ Новобанк",
-			NspkCode: "100000000222",
-		  },
-		  {
-			Code: "nsbank",
-			Name: "НС Банк",
-			NspkCode: "100000000071",
-		  },
-		  {
-			Code: "orbank",
-			Name: "БАНК ОРЕНБУРГ",
-			NspkCode: "100000000124",
-		  },
-		  {
-			Code: "crediteurope",
-			Name: "Кредит Европа Банк (Россия)",
-			NspkCode: "100000000027",
-		  },
-		  {
-			Code: "pskb",
-			Name: "СКБ Приморья Примсоцбанк",
-			NspkCode: "100000000088",
-		  },
-		  {
-			Code: "realistbank",
-			Name: "РЕАЛИСТ БАНК",
-			NspkCode: "100000000232",
-		  },
-		  {
-			Code: "resocreditbank",
-			Name: "Банк РЕСО Кредит",
-			NspkCode: "100000000187",
-		  },
-		  {
-			Code: "sinko-bank",
-			Name: "КБ СИНКО-БАНК",
-			NspkCode: "100000000148",
-		  },
-		  {
-			Code: "socium-bank",
-			Name: "СОЦИУМ-БАНК",
-			NspkCode: "100000000223",
-		  },
-		  {
-			Code: "tatsotsbank",
-			Name: "ТАТСОЦБАНК",
-			NspkCode: "100000000189",
-		  },
-		  {
-			Code: "timerbank",
-			Name: "Тимер Банк",
-			NspkCode: "100000000144",
-		  },
-		  {
-			Code: "transstroybank",
-			Name: "АКБ Трансстройбанк",
-			NspkCode: "100000000197",
-		  },
-		  {
-			Code: "agros",
-			Name: "Банк Агророс",
-			NspkCode: "100000000102",
-		  },
-		  {
-			Code: "akcept",
-			Name: "Банк Акцепт",
-			NspkCode: "100000000135",
-		  },
-		  {
-			Code: "alefbank",
-			Name: "АКБ Алеф-Банк",
-			NspkCode: "100000000113",
-		  },
-		  {
-			Code: "aresbank",
-			Name: "КБ АРЕСБАНК",
-			NspkCode: "100000000129",
-		  },
-		  {
-			Code: "bancaintesa",
-			Name: "Банк Интеза",
-			NspkCode: "100000000170",
-		  },
-		  {
-			Code: "bank-hlynov",
-			Name: "КБ Хлынов",
-			NspkCode: "100000000056",
-		  },
-		  {
-			Code: "bankofkazan",
-			Name: "КБЭР Банк Казани",
-			NspkCode: "100000000191",
-		  },
-		  {
-			Code: "bspb",
-			Name: "Банк Санкт-Петербург",
-			NspkCode: "100000000029",
-		  },
-		  {
-			Code: "centrinvest",
-			Name: "КБ Центр-инвест",
-			NspkCode: "100000000059",
-		  },
-		  {
-			Code: "cfb",
-			Name: "Банк БКФ",
-			NspkCode: "100000000227",
-		  },
-		  {
-			Code: "chelindbank",
-			Name: "ЧЕЛИНДБАНК",
-			NspkCode: "100000000106",
-		  },
-		  {
-			Code: "coalmetbank",
-			Name: "Углеметбанк",
-			NspkCode: "100000000093",
-		  },
-		  {
-			Code: "creditural",
-			Name: "Кредит Урал Банк",
-			NspkCode: "100000000064",
-		  },
-		  {
-			Code: "dcapital",
-			Name: "Банк Развитие-Столица",
-			NspkCode: "100000000172",
-		  },
-		  {
-			Code: "dvbank",
-			Name: "Дальневосточный банк",
-			NspkCode: "100000000083",
-		  },
-		  {
-			Code: "el-plat",
-			Name: "ПНКО ЭЛПЛАТ",
-			NspkCode: "100000000086",
-		  },
-		  {
-			Code: "energotransbank",
-			Name: "КБ ЭНЕРГОТРАНСБАНК",
-			NspkCode: "100000000139",
-		  },
-		  {
-			Code: "finam",
-			Name: "Банк ФИНАМ",
-			NspkCode: "100000000040",
-		  },
-		  {
-			Code: "gibank",
-			Name: "КБ Гарант-Инвест",
-			NspkCode: "100000000112",
-		  },
-		  {
-			Code: "gorbank",
-			Name: "ГОРБАНК",
-			NspkCode: "100000000125",
-		  },
-		  {
-			Code: "gutabank",
-			Name: "ГУТА-БАНК",
-			NspkCode: "100000000149",
-		  },
-		  {
-			Code: "icbru",
-			Name: "ИК Банк",
-			NspkCode: "100000000122",
-		  },
-		  {
-			Code: "lanta",
-			Name: "АКБ Ланта-Банк",
-			NspkCode: "100000000245",
-		  },
-		  {
-			Code: "lockobank",
-			Name: "КБ ЛОКО-Банк",
-			NspkCode: "100000000161",
-		  },
-		  {
-			Code: "mcbankrus",
-			Name: "МС Банк Рус",
-			NspkCode: "100000000229",
-		  },
-		  {
-			Code: "metcom",
-			Name: "МЕТКОМБАНК",
-			NspkCode: "100000000136",
-		  },
-		  {
-			Code: "mspbank",
-			Name: "МС Примбанк",
-			NspkCode: "100000000255",
-		  },
-		  {
-			Code: "nipbank",
-			Name: "Нацинвестпромбанк",
-			NspkCode: "100000000185",
-		  },
-		  {
-			Code: "ns-bank",
-			Name: "Банк Национальный стандарт",
-			NspkCode: "100000000243",
-		  },
-		  {
-			Code: "nskbl",
-			Name: "Банк Левобережный",
-			NspkCode: "100000000052",
-		  },
-		  {
-			Code: "poidem",
-			Name: "КБ Пойдём",
-			NspkCode: "100000000103",
-		  },
-		  {
-			Code: "primbank",
-			Name: "АКБ Приморье",
-			NspkCode: "100000000226",
-		  },
-		  {
-			Code: "probank",
-			Name: "ПроБанк",
-			NspkCode: "100000000117",
-		  },
-		  {
-			Code: "rdb",
-			Name: "РосДорБанк",
-			NspkCode: "100000000084",
-		  },
-		  {
-			Code: "sdm",
-			Name: "СДМ-Банк",
-			NspkCode: "100000000069",
-		  },
-		  {
-			Code: "sevnb",
-			Name: "Северный Народный Банк",
-			NspkCode: "100000000208",
-		  },
-		  {
-			Code: "sibsoc",
-			Name: "СИБСОЦБАНК",
-			NspkCode: "100000000166",
-		  },
-		  {
-			Code: "sngb",
-			Name: "БАНК СНГБ",
-			NspkCode: "100000000091",
-		  },
-		  {
-			Code: "tavrich",
-			Name: "Таврический Банк",
-			NspkCode: "100000000173",
-		  },
-		  {
-			Code: "tenderbank",
-			Name: "АКБ ТЕНДЕР-БАНК",
-			NspkCode: "100000000175",
-		  },
-		  {
-			Code: "thbank",
-			Name: "Тольяттихимбанк",
-			NspkCode: "100000000152",
-		  },
-		  {
-			Code: "tpsbank",
-			Name: "Томскпромстройбанк",
-			NspkCode: "100000000206",
-		  },
-		  {
-			Code: "unicreditbank",
-			Name: "ЮниКредит Банк",
-			NspkCode: "100000000030",
-		  },
-		  {
-			Code: "uralfd",
-			Name: "КБ Урал ФД",
-			NspkCode: "100000000151",
-		  },
-		  {
-			Code: "vbrr",
-			Name: "Банк ВБРР",
-			NspkCode: "100000000049",
-		  },
-		  {
-			Code: "venets-bank",
-			Name: "Банк Венец",
-			NspkCode: "100000000153",
-		  },
-		  {
-			Code: "vfbank",
-			Name: "КБ ВНЕШФИНБАНК",
-			NspkCode: "100000000248",
-		  },
-		  {
-			Code: "zenit",
-			Name: "Банк ЗЕНИТ",
-			NspkCode: "100000000045",
-		  },
-		  {
-			Code: "yoomoney",
-			Name: "НКО ЮМани",
-			NspkCode: "100000000022",
-		  },
-		  {
-			Code: "avtofinbank",
-			Name: "Авто Финанс Банк",
-			NspkCode: "100000000253",
-		  },
-		  {
-			Code: "avtotorgbank",
-			Name: "Автоторгбанк",
-			NspkCode: "100000000181",
-		  },
-		  {
-			Code: "aikb-enisejskij-obedinennyj-bank",
-			Name: "АИКБ Енисейский объединенный банк",
-			NspkCode: "100000000258",
-		  },
-		  {
-			Code: "bank-sinara",
-			Name: "Банк Синара",
-			NspkCode: "100000000003",
-		  },
-		  {
-			Code: "bank-uralfinans",
-			Name: "Уралфинанс",
-			NspkCode: "100000000096",
-		  },
-		  {
-			Code: "bank-centrokredit",
-			Name: "ЦентроКредит",
-			NspkCode: "100000000231",
-		  },
-		  {
-			Code: "is-bank",
-			Name: "ИС Банк",
-			NspkCode: "100000000239",
-		  },
-		  {
-			Code: "kb-dolinsk",
-			Name: "КБ Долинск",
-			NspkCode: "100000000270",
-		  },
-		  {
-			Code: "novyj-vek",
-			Name: "КБ Новый век",
-			NspkCode: "100000000067",
-		  },
-		  {
-			Code: "ukb-belgorodsocbank",
-			Name: "УКБ Белгородсоцбанк",
-			NspkCode: "100000000225",
-		  },
-		  {
-			Code: "avtogradbank",
-			Name: "Автоградбанк",
-			NspkCode: "100000000130",
-		  },
-		  {
-			Code: "bank-ekaterinburg",
-			Name: "Банк Екатеринбург",
-			NspkCode: "100000000090",
-		  },
-		  {
-			Code: "bank-sgb",
-			Name: "БАНК СГБ",
-			NspkCode: "100000000219",
-		  },
-		  {
-			Code: "bank-siab",
-			Name: "Банк СИАБ",
-			NspkCode: "100000000278",
-		  },
-		  {
-			Code: "dzhej-jend-ti-bank",
-			Name: "Джей энд Ти Банк (АО)",
-			NspkCode: "100000000213",
-		  },
-		  {
-			Code: "mb-bank",
-			Name: "МБ Банк",
-			NspkCode: "100000000140",
-		  },
-		  {
-			Code: "smp-bank",
-			Name: "СМП Банк",
-			NspkCode: "100000000036",
-		  },
-		  {
-			Code: "tochka-otkrytie",
-			Name: "ТОЧКА (ФК Открытие)",
-			NspkCode: "100000000284",
-		  },
-		  {
-			Code: "bsdbank",
-			Name: "Черноморский банк развития",
-			NspkCode: "100000000215",
-		  },
-		  {
-			Code: "klookva",
-			Name: "Клюква",
-			NspkCode: "100000000154",
-		  },
-		  {
-			Code: "blanc",
-			Name: "Бланк банк",
-			NspkCode: "100000000053",
-		  },
-		  {
-			Code: "tkbbank",
-			Name: "ТКБ БАНК",
-			NspkCode: "100000000034",
-		  },
-		  {
-			Code: "severgazbank",
-			Name: "Севергазбанк",
-			NspkCode: "100000000219",
-		  },
-		  {
-			Code: "nrb",
-			Name: "АКБ НРБанк",
-			NspkCode: "100000000184",
-		  },
-		  {
-			Code: "finstarbank",
-			Name: "ФИНСТАР БАНК",
-			NspkCode: "100000000278",
-		  },
-		  {
-			Code: "round",
-			Name: "банк Раунд",
-			NspkCode: "100000000247",
-		  },
-		  {
-			Code: "dtb1",
-			Name: "Первый Дортрансбанк",
-			NspkCode: "100000000137",
-		  },
-		  {
-			Code: "cmrbank",
-			Name: "ЦМРБанк",
-			NspkCode: "100000000282",
-		  },
-		  {
-			Code: "plait",
-			Name: "Плайт",
-			NspkCode: "100000000296",
-		  },
-		  {
-			Code: "bankorange",
-			Name: "Банк Оранжевый",
-			NspkCode: "100000000286",
-		  },
-		  {
-			Code: "yarinterbank",
-			Name: "ИКБР ЯРИНТЕРБАНК",
-			NspkCode: "100000000293",
-		  },
-		  {
-			Code: "yandexbank",
-			Name: "Яндекс Банк",
-			NspkCode: "100000000150",
-		  },
-		  {
-			Code: "bank-mba-moskva",
-			Name: "Банк МБА МОСКВА",
-			NspkCode: "100000000192",
-		  },
-		  {
-			Code: "bank-ipb",
-			Name: "Банк ИПБ",
-			NspkCode: "100000000236",
-		  },
-		  {
-			Code: "bank-jelita",
-			Name: "банк Элита",
-			NspkCode: "100000000266",
-		  },
-		  {
-			Code: "bank-vologzhanin",
-			Name: "Банк Вологжанин",
-			NspkCode: "100000000257",
-		  },
-		  {
-			Code: "drajv-klik-bank",
-			Name: "Драйв Клик Банк",
-			NspkCode: "100000000250",
-		  },
-		  {
-			Code: "rosselhozbank",
-			Name: "Россельхозбанк",
-			NspkCode: "100000000020",
-		  },
-		  {
-			Code: "hajs",
-			Name: "Хайс",
-			NspkCode: "100000000272",
-		  },
-		  {
-			Code: "jes-bi-aj-bank",
-			Name: "Эс-Би-Ай Банк",
-			NspkCode: "100000000105",
-		  },
-		  {
-			Code: "rusnarbank",
-			Name: "РУСНАРБАНК",
-			NspkCode: "100000000194",
-		  },
-		  {
-			Code: "bank-saratov",
-			Name: "Банк Саратов",
-			NspkCode: "100000000126",
-		  },
-		  {
-			Code: "pervyj-investicionnyj-bank",
-			Name: "Первый Инвестиционный Банк",
-			NspkCode: "100000000174",
-		  },
-		  {
-			Code: "akb-derzhava",
-			Name: "АКБ Держава",
-			NspkCode: "100000000235",
-		  },
-		  {
-			Code: "kb-strojlesbank",
-			Name: "КБ Стройлесбанк",
-			NspkCode: "100000000193",
-		  },
-		  {
-			Code: "tojota-bank",
-			Name: "Тойота Банк",
-			NspkCode: "100000000138",
-		  },
-		  {
-			Code: "kb-moskommercbank",
-			Name: "КБ Москоммерцбанк",
-			NspkCode: "100000000110",
-		  },
-		  {
-			Code: "uralprombank",
-			Name: "УРАЛПРОМБАНК",
-			NspkCode: "100000000142",
-		  },
-		  {
-			Code: "sitibank",
-			Name: "Ситибанк",
-			NspkCode: "100000000128",
-		  },
-		  {
-			Code: "bank-aleksandrovskij",
-			Name: "Банк АЛЕКСАНДРОВСКИЙ",
-			NspkCode: "100000000211",
-		  },
-		  {
-			Code: "mezhdunarodnyj-finansovyj-klub",
-			Name: "МЕЖДУНАРОДНЫЙ ФИНАНСОВЫЙ КЛУБ",
-			NspkCode: "100000000203",
-		  },
-		  {
-			Code: "mkb",
-			Name: "Московский кредитный банк",
-			NspkCode: "100000000025",
-		  },
-		  {
-			Code: "nbd-bank",
-			Name: "НБД-Банк",
-			NspkCode: "100000000134",
-		  },
-		  {
-			Code: "jandeks-bank",
-			Name: "Яндекс Банк",
-			NspkCode: "100000000150",
-		  },
-		  {
-			Code: "nokssbank",
-			Name: "НОКССБАНК",
-			NspkCode: "100000000062",
-		  },
-		  {
-			Code: "vuz-bank",
-			Name: "ВУЗ-банк",
-			NspkCode: "100000000215",
-		  },
-		  {
-			Code: "bank-bzhf",
-			Name: "Банк БЖФ",
-			NspkCode: "100000000260",
-		  },
-		  {
-			Code: "jug-investbank",
-			Name: "ЮГ-Инвестбанк",
-			NspkCode: "100000000160",
-		  },
-		  {
-			Code: "kb-krokus-bank",
-			Name: "КБ Крокус Банк",
-			NspkCode: "100000000212",
-		  },
-		  {
-			Code: "vladbiznesbank",
-			Name: "ВЛАДБИЗНЕСБАНК",
-			NspkCode: "100000000058",
-		  },
-		  {
-			Code: "bank-avers",
-			Name: "Банк Аверс",
-			NspkCode: "100000000154",
-		  },
-		  {
-			Code: "wbbank",
-			Name: "Вайлдберриз Банк",
-			NspkCode: "100000000259",
-		  },
-		  {
-			Code: "ishbank",
-			Name: "ИШБАНК",
-			NspkCode: "100000000199",
-		  },
-		  {
-			Code: "almazjergijenbank",
-			Name: "Алмазэргиэнбанк",
-			NspkCode: "",
-		  },
-		  {
-			Code: "aziatsko-tihookeanskij-bank",
-			Name: "Азиатско-Тихоокеанский Банк",
-			NspkCode: "100000000108",
-		  },
-		  {
-			Code: "agroros",
-			Name: "Банк Агророс",
-			NspkCode: "100000000102",
-		  },
-		  {
-			Code: "bbr-bank",
-			Name: "ББР Банк",
-			NspkCode: "100000000133",
-		  },
-		  {
-			Code: "solid-bank",
-			Name: "Солид Банк",
-			NspkCode: "100000000230",
-		  },
-		  {
-			Code: "rus-universalbank",
-			Name: "Русьуниверсалбанк",
-			NspkCode: "100000000165",
-		  },
-		  {
-			Code: "akb-slavija",
-			Name: "АКБ СЛАВИЯ",
-			NspkCode: "100000000200",
-		  },
-		  {
-			Code: "akb-evrofinans-mosnarbank",
-			Name: "АКБ ЕВРОФИНАНС МОСНАРБАНК",
-			NspkCode: "100000000167",
-		  },
-		  {
-			Code: "ubrib",
-			Name: "Банк УБРиР",
-			NspkCode: "100000000031",
-		  },
-		  {
-			Code: "bank-raund",
-			Name: "банк Раунд",
-			NspkCode: "100000000247",
-		  },
-		  {
-			Code: "tochka-bank",
-			Name: "Точка Банк",
-			NspkCode: "100000000284",
-		  },
-		  {
-			Code: "prio-vneshtorgbank",
-			Name: "Прио-Внешторгбанк",
-			NspkCode: "100000000228",
-		  },
-		  {
-			Code: "bank-snezhinskij",
-			Name: "Банк Снежинский",
-			NspkCode: "100000000163",
-		  },
-		  {
-			Code: "moskombank",
-			Name: "МОСКОМБАНК",
-			NspkCode: "100000000176",
-		  },
+	catalogBanks := h.Banks.Banks(bank_catalog.Filter{
+		Country: c.Query("country"),
+		PaymentSystem: c.Query("payment_system"),
+	})
+	banks := make([]merchant.Bank, len(catalogBanks))
+	for i, bank := range catalogBanks {
+		banks[i] = merchant.Bank{
+			Code: bank.Code,
+			Name: bank.Name,
+			NspkCode: bank.NspkCode,
+		}
 	}
 	c.JSON(http.StatusOK, banks)
 }
\ No newline at end of file
@@ -0,0 +1,348 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/LavaJover/shvark-api-gateway/internal/service"
+	orderpb "github.com/LavaJover/shvark-order-service/proto/gen/order"
+	userpb "github.com/LavaJover/shvark-user-service/proto/gen"
+	"github.com/gin-gonic/gin"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// Контрактные тесты legacy-ручек /merchant и /payments: ответы сравниваются с эталонами
+// в testdata/legacy. Форматы заморожены ради существующих интеграций, поэтому любое
+// расхождение - поломка контракта. Эталоны перезаписываются через go test -run Legacy -update.
+
+var updateGolden = flag.Bool("update", false, "rewrite golden responses in testdata")
+
+const legacyMerchantID = "merchant-1"
+
+// legacyOrdersPage страница GetOrders в JSON-представлении protobuf
+const legacyOrdersPage = `{
+	"content": [{
+		"id": "ord-1",
+		"timeOpening": "2026-01-02T14:44:05Z",
+		"timeExpires": "2026-01-02T15:04:05Z",
+		"timeComplete": "2026-01-02T14:50:00Z",
+		"type": "DEPOSIT",
+		"status": "COMPLETED",
+		"currencyRate": 80,
+		"sumInvoice": {"amount": 1500, "currency": "RUB"},
+		"sumDeal": {"amount": 18.75, "currency": "USDT"},
+		"requisites": {
+			"issuer": "sberbank",
+			"holderName": "Ivan Ivanov",
+			"phoneNumber": "+79990000000",
+			"cardNumber": "2200000000000001"
+		}
+	}],
+	"pageable": {
+		"sort": {"unsorted": false, "sorted": true, "empty": false},
+		"pageNumber": 0,
+		"pageSize": 10,
+		"offset": 0,
+		"paged": true,
+		"unpaged": false
+	},
+	"totalElements": 1,
+	"totalPages": 1,
+	"last": true,
+	"numberOfElements": 1,
+	"size": 10,
+	"number": 0,
+	"sort": {"unsorted": false, "sorted": true, "empty": false},
+	"first": true,
+	"empty": false
+}`
+
+// fakeMerchantBackend order-service, кошелек и user-service мерчанта в памяти
+type fakeMerchantBackend struct {
+	orders      map[string]*orderpb.Order
+	createErr   error
+	ordersErr   error
+	balance     float64
+	balanceErr  error
+	withdrawErr error
+	userErr     error
+}
+
+func newFakeMerchantBackend() *fakeMerchantBackend {
+	return &fakeMerchantBackend{
+		orders: map[string]*orderpb.Order{
+			"ord-1": {
+				OrderId:         "ord-1",
+				MerchantId:      legacyMerchantID,
+				MerchantOrderId: "inv-1",
+				AmountFiat:      1500,
+				AmountCrypto:    18.75,
+				CryptoRubRate:   80,
+				PlatformFee:     0.02,
+				CallbackUrl:     "https://shop.example/callback",
+				Status:          "CREATED",
+				ExpiresAt:       timestamppb.New(time.Date(2026, 1, 2, 15, 4, 5, 0, time.UTC)),
+				BankDetail: &orderpb.BankDetail{
+					BankDetailId:  "bd-1",
+					Currency:      "RUB",
+					Country:       "Russia",
+					BankName:      "Sberbank",
+					BankCode:      "sberbank",
+					NspkCode:      "100000000111",
+					PaymentSystem: "C2C",
+					CardNumber:    "2200000000000001",
+					Phone:         "+79990000000",
+					Owner:         "Ivan Ivanov",
+				},
+			},
+		},
+		balance: 125.5,
+	}
+}
+
+func (b *fakeMerchantBackend) CreatePayInOrder(request *orderpb.CreatePayInOrderRequest) (*orderpb.CreatePayInOrderResponse, error) {
+	if b.createErr != nil {
+		return nil, b.createErr
+	}
+	return &orderpb.CreatePayInOrderResponse{Order: b.orders["ord-1"]}, nil
+}
+
+func (b *fakeMerchantBackend) GetOrderByID(orderID string) (*orderpb.GetOrderByIDResponse, error) {
+	order, ok := b.orders[orderID]
+	if !ok {
+		return nil, status.Error(codes.NotFound, "order not found")
+	}
+	return &orderpb.GetOrderByIDResponse{Order: order}, nil
+}
+
+func (b *fakeMerchantBackend) GetOrderByMerchantOrderID(merchantOrderID string) (*orderpb.GetOrderByMerchantOrderIDResponse, error) {
+	for _, order := range b.orders {
+		if order.MerchantOrderId == merchantOrderID {
+			return &orderpb.GetOrderByMerchantOrderIDResponse{Order: order}, nil
+		}
+	}
+	return nil, status.Error(codes.NotFound, "order not found")
+}
+
+func (b *fakeMerchantBackend) GetMerchantOrderByID(merchantID, orderID string) (*orderpb.GetOrderByIDResponse, error) {
+	order, ok := b.orders[orderID]
+	if !ok || order.MerchantId != merchantID {
+		return nil, status.Error(codes.NotFound, "order not found")
+	}
+	return &orderpb.GetOrderByIDResponse{Order: order}, nil
+}
+
+func (b *fakeMerchantBackend) GetMerchantOrderByMerchantOrderID(merchantID, merchantOrderID string) (*orderpb.GetOrderByMerchantOrderIDResponse, error) {
+	for _, order := range b.orders {
+		if order.MerchantId == merchantID && order.MerchantOrderId == merchantOrderID {
+			return &orderpb.GetOrderByMerchantOrderIDResponse{Order: order}, nil
+		}
+	}
+	return nil, status.Error(codes.NotFound, "order not found")
+}
+
+func (b *fakeMerchantBackend) GetOrders(request *orderpb.GetOrdersRequest) (*orderpb.GetOrdersResponse, error) {
+	if b.ordersErr != nil {
+		return nil, b.ordersErr
+	}
+	var response orderpb.GetOrdersResponse
+	if err := protojson.Unmarshal([]byte(legacyOrdersPage), &response); err != nil {
+		return nil, err
+	}
+	return &response, nil
+}
+
+func (b *fakeMerchantBackend) GetBalance(userID string) (float64, error) {
+	return b.balance, b.balanceErr
+}
+
+func (b *fakeMerchantBackend) Withdraw(userID, toAddress string, amount float64) (string, error) {
+	if b.withdrawErr != nil {
+		return "", b.withdrawErr
+	}
+	return "0xabc", nil
+}
+
+func (b *fakeMerchantBackend) GetUserByID(userID string) (*userpb.GetUserByIDResponse, error) {
+	if b.userErr != nil {
+		return nil, b.userErr
+	}
+	return &userpb.GetUserByIDResponse{Login: "shop-login", Username: "Test Shop"}, nil
+}
+
+// legacyRouter маршруты /merchant и /payments как в main.go; авторизация заменена
+// middleware, которое кладет мерчанта в контекст
+func legacyRouter(backend *fakeMerchantBackend) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	merchantService := service.NewMerchantService(backend, backend, backend, nil)
+	merchantHandler := NewMerchanHandler(merchantService, nil, nil)
+	paymentHandler := &PaymentHandler{MerchantService: merchantService}
+
+	r := gin.New()
+	auth := func(c *gin.Context) {
+		c.Set("userID", legacyMerchantID)
+		c.Next()
+	}
+
+	merchantGroup := r.Group("/api/v1/merchant", auth)
+	merchantGroup.POST("/order/:accountID/deposit", merchantHandler.CreatePayIn)
+	merchantGroup.GET("/order/:iternalId/status", merchantHandler.GetOrderStatus)
+	merchantGroup.GET("/order", merchantHandler.GetOrders)
+	merchantGroup.GET("/accounts/balance", merchantHandler.GetAccountBalance)
+	merchantGroup.POST("/accounts/withdraw/create", merchantHandler.Withdraw)
+
+	paymentsGroup := r.Group("/api/v1/payments", auth)
+	paymentsGroup.POST("/in/h2h", paymentHandler.CreateH2HPayIn)
+	paymentsGroup.GET("/in/h2h/:id", paymentHandler.GetH2HPayInInfo)
+	paymentsGroup.GET("/order/:orderId/status", paymentHandler.GetOrderStatus)
+	paymentsGroup.GET("/order", paymentHandler.GetOrders)
+	paymentsGroup.GET("/accounts/balance", paymentHandler.GetAccountBalance)
+	paymentsGroup.POST("/accounts/withdraw/create", paymentHandler.Withdraw)
+	return r
+}
+
+func TestLegacyMerchantContract(t *testing.T) {
+	cases := []struct {
+		golden string
+		method string
+		path   string
+		body   string
+		setup  func(b *fakeMerchantBackend)
+		status int
+	}{
+		// /merchant
+		{golden: "merchant/create_pay_in", method: http.MethodPost, path: "/api/v1/merchant/order/merchant-1/deposit",
+			body:   `{"isSbp":false,"amount":1500,"currency":"RUB","issuer":"sberbank","callbackUrl":"https://shop.example/callback","iternalId":"inv-1"}`,
+			status: http.StatusCreated},
+		{golden: "merchant/create_pay_in_error", method: http.MethodPost, path: "/api/v1/merchant/order/merchant-1/deposit",
+			body:   `{"isSbp":true,"amount":1500,"currency":"RUB","iternalId":"inv-2"}`,
+			setup:  func(b *fakeMerchantBackend) { b.createErr = errors.New("no available bank details") },
+			status: http.StatusNotFound},
+		{golden: "merchant/order_status", method: http.MethodGet, path: "/api/v1/merchant/order/inv-1/status", status: http.StatusOK},
+		{golden: "merchant/order_status_not_found", method: http.MethodGet, path: "/api/v1/merchant/order/inv-404/status", status: http.StatusNotFound},
+		{golden: "merchant/orders", method: http.MethodGet, path: "/api/v1/merchant/order?page=0&size=10", status: http.StatusOK},
+		{golden: "merchant/orders_user_error", method: http.MethodGet, path: "/api/v1/merchant/order",
+			setup:  func(b *fakeMerchantBackend) { b.userErr = errors.New("user service is down") },
+			status: http.StatusUnauthorized},
+		{golden: "merchant/orders_invalid_argument", method: http.MethodGet, path: "/api/v1/merchant/order?sort=unknown",
+			setup:  func(b *fakeMerchantBackend) { b.ordersErr = status.Error(codes.InvalidArgument, "unknown sort field") },
+			status: http.StatusBadRequest},
+		{golden: "merchant/orders_not_found", method: http.MethodGet, path: "/api/v1/merchant/order",
+			setup:  func(b *fakeMerchantBackend) { b.ordersErr = status.Error(codes.NotFound, "merchant not found") },
+			status: http.StatusNotFound},
+		{golden: "merchant/orders_internal", method: http.MethodGet, path: "/api/v1/merchant/order",
+			setup:  func(b *fakeMerchantBackend) { b.ordersErr = status.Error(codes.Unavailable, "connection refused") },
+			status: http.StatusInternalServerError},
+		{golden: "merchant/balance", method: http.MethodGet, path: "/api/v1/merchant/accounts/balance", status: http.StatusOK},
+		{golden: "merchant/balance_wallet_error", method: http.MethodGet, path: "/api/v1/merchant/accounts/balance",
+			setup:  func(b *fakeMerchantBackend) { b.balanceErr = errors.New("wallet is unavailable") },
+			status: http.StatusBadGateway},
+		{golden: "merchant/balance_user_error", method: http.MethodGet, path: "/api/v1/merchant/accounts/balance",
+			setup:  func(b *fakeMerchantBackend) { b.userErr = errors.New("user service is down") },
+			status: http.StatusInternalServerError},
+		{golden: "merchant/withdraw", method: http.MethodPost, path: "/api/v1/merchant/accounts/withdraw/create",
+			body: `{"amount":10,"currency":"USDT","toAddress":"TXyz"}`, status: http.StatusCreated},
+		{golden: "merchant/withdraw_error", method: http.MethodPost, path: "/api/v1/merchant/accounts/withdraw/create",
+			body:   `{"amount":10,"currency":"USDT","toAddress":"TXyz"}`,
+			setup:  func(b *fakeMerchantBackend) { b.withdrawErr = errors.New("insufficient funds") },
+			status: http.StatusBadGateway},
+
+		// /payments
+		{golden: "payments/create_pay_in_no_bank_details", method: http.MethodPost, path: "/api/v1/payments/in/h2h",
+			body:   `{"merchantId":"merchant-1","currency":"RUB","paymentSystem":"C2C","amountFiat":1500,"merchantOrderId":"inv-2"}`,
+			setup:  func(b *fakeMerchantBackend) { b.createErr = status.Error(codes.NotFound, "no available bank details") },
+			status: http.StatusConflict},
+		{golden: "payments/pay_in_info", method: http.MethodGet, path: "/api/v1/payments/in/h2h/ord-1", status: http.StatusOK},
+		{golden: "payments/pay_in_info_not_found", method: http.MethodGet, path: "/api/v1/payments/in/h2h/ord-404", status: http.StatusNotFound},
+		{golden: "payments/order_status", method: http.MethodGet, path: "/api/v1/payments/order/ord-1/status", status: http.StatusOK},
+		{golden: "payments/order_status_not_found", method: http.MethodGet, path: "/api/v1/payments/order/ord-404/status", status: http.StatusNotFound},
+		{golden: "payments/orders", method: http.MethodGet, path: "/api/v1/payments/order", status: http.StatusOK},
+		{golden: "payments/orders_user_error", method: http.MethodGet, path: "/api/v1/payments/order",
+			setup:  func(b *fakeMerchantBackend) { b.userErr = errors.New("user service is down") },
+			status: http.StatusUnauthorized},
+		{golden: "payments/orders_invalid_argument", method: http.MethodGet, path: "/api/v1/payments/order?sort=unknown",
+			setup:  func(b *fakeMerchantBackend) { b.ordersErr = status.Error(codes.InvalidArgument, "unknown sort field") },
+			status: http.StatusBadRequest},
+		{golden: "payments/balance", method: http.MethodGet, path: "/api/v1/payments/accounts/balance", status: http.StatusOK},
+		{golden: "payments/balance_wallet_error", method: http.MethodGet, path: "/api/v1/payments/accounts/balance",
+			setup:  func(b *fakeMerchantBackend) { b.balanceErr = errors.New("wallet is unavailable") },
+			status: http.StatusBadGateway},
+		{golden: "payments/withdraw", method: http.MethodPost, path: "/api/v1/payments/accounts/withdraw/create",
+			body: `{"amount":10,"currency":"USDT","toAddress":"TXyz"}`, status: http.StatusCreated},
+		{golden: "payments/withdraw_error", method: http.MethodPost, path: "/api/v1/payments/accounts/withdraw/create",
+			body:   `{"amount":10,"currency":"USDT","toAddress":"TXyz"}`,
+			setup:  func(b *fakeMerchantBackend) { b.withdrawErr = errors.New("insufficient funds") },
+			status: http.StatusBadGateway},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.golden, func(t *testing.T) {
+			backend := newFakeMerchantBackend()
+			if tc.setup != nil {
+				tc.setup(backend)
+			}
+			request := httptest.NewRequest(tc.method, tc.path, strings.NewReader(tc.body))
+			request.Header.Set("Content-Type", "application/json")
+			recorder := httptest.NewRecorder()
+			legacyRouter(backend).ServeHTTP(recorder, request)
+
+			if recorder.Code != tc.status {
+				t.Fatalf("status = %d, want %d, body %s", recorder.Code, tc.status, recorder.Body.String())
+			}
+			assertGolden(t, filepath.Join("testdata", "legacy", tc.golden+".json"), recorder.Body.Bytes())
+		})
+	}
+}
+
+// assertGolden сравнивает JSON без учета форматирования и порядка ключей
+func assertGolden(t *testing.T, path string, got []byte) {
+	t.Helper()
+	gotCanonical, err := canonicalJSON(got)
+	if err != nil {
+		t.Fatalf("response is not JSON: %v: %s", err, got)
+	}
+	if *updateGolden {
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, gotCanonical, 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("read golden %s: %v", path, err)
+	}
+	wantCanonical, err := canonicalJSON(want)
+	if err != nil {
+		t.Fatalf("golden %s is not JSON: %v", path, err)
+	}
+	if !bytes.Equal(gotCanonical, wantCanonical) {
+		t.Errorf("response differs from %s\ngot:\n%s\nwant:\n%s", path, gotCanonical, wantCanonical)
+	}
+}
+
+func canonicalJSON(data []byte) ([]byte, error) {
+	var value interface{}
+	if err := json.Unmarshal(data, &value); err != nil {
+		return nil, err
+	}
+	canonical, err := json.MarshalIndent(value, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append(canonical, '\n'), nil
+}
@@ -0,0 +1,360 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/LavaJover/shvark-api-gateway/internal/common"
+	"github.com/LavaJover/shvark-api-gateway/internal/delivery/http/dto/merchantv2"
+	"github.com/LavaJover/shvark-api-gateway/internal/domain"
+	"github.com/LavaJover/shvark-api-gateway/internal/service"
+	"github.com/gin-gonic/gin"
+	"google.golang.org/grpc/status"
+)
+
+// MerchantV2Handler единый API мерчанта /api/v2/merchant.
+// Ошибки всегда отдаются в формате common.HTTPError.
+type MerchantV2Handler struct {
+	MerchantService *service.MerchantService
+}
+
+func NewMerchantV2Handler(merchantService *service.MerchantService) *MerchantV2Handler {
+	return &MerchantV2Handler{
+		MerchantService: merchantService,
+	}
+}
+
+// @Summary Merchant sign-in
+// @Description Get merchant access token
+// @Tags merchant-v2
+// @Accept json
+// @Produce json
+// @Param input body merchantv2.SignInRequest true "merchant credentials"
+// @Success 200 {object} merchantv2.SignInResponse
+// @Failure 400 {object} common.HTTPError
+// @Failure 401 {object} common.HTTPError
+// @Router /api/v2/merchant/auth/sign-in [post]
+func (h *MerchantV2Handler) SignIn(c *gin.Context) {
+	var request merchantv2.SignInRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		common.RespondWithError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	session, err := h.MerchantService.SignIn(request.Login, request.Password, request.TwoFaCode)
+	if err != nil {
+		respondMerchantV2Error(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, merchantv2.SignInResponse{
+		Token:     session.Token,
+		ExpiresAt: session.ExpiresAt.UTC(),
+	})
+}
+
+// @Summary Create pay-in order
+// @Description Create pay-in order for authenticated merchant
+// @Tags merchant-v2
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param input body merchantv2.CreatePayInRequest true "pay-in details"
+// @Success 201 {object} merchantv2.Order
+// @Failure 400 {object} common.HTTPError
+// @Failure 404 {object} common.HTTPError
+// @Failure 502 {object} common.HTTPError
+// @Router /api/v2/merchant/orders [post]
+func (h *MerchantV2Handler) CreatePayIn(c *gin.Context) {
+	merchantID, ok := merchantV2ID(c)
+	if !ok {
+		return
+	}
+
+	var request merchantv2.CreatePayInRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		common.RespondWithError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	order, err := h.MerchantService.CreatePayIn(service.PayInParams{
+		MerchantID:      merchantID,
+		MerchantOrderID: request.MerchantOrderID,
+		ClientID:        request.ClientID,
+		AmountFiat:      request.Amount,
+		Currency:        request.Currency,
+		PaymentSystem:   request.PaymentSystem,
+		BankCode:        request.BankCode,
+		NspkCode:        request.NspkCode,
+		CallbackURL:     request.CallbackURL,
+	})
+	if err != nil {
+		respondMerchantV2Error(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, toMerchantV2Order(order))
+}
+
+// @Summary Get orders
+// @Description Get merchant orders with filters and pagination
+// @Tags merchant-v2
+// @Produce json
+// @Security BearerAuth
+// @Param dealId query string false "deal ID"
+// @Param type query string false "order type" Enums(DEPOSIT, WITHDRAWAL, PAYOUT)
+// @Param status query string false "order status"
+// @Param timeOpeningStart query string false "created from (2006-01-02T15:04:05Z)"
+// @Param timeOpeningEnd query string false "created to (2006-01-02T15:04:05Z)"
+// @Param amountMin query number false "min amount"
+// @Param amountMax query number false "max amount"
+// @Param page query int false "page number, from 0" default(0)
+// @Param size query int false "page size, up to 100" default(10)
+// @Param sort query string false "sort field"
+// @Success 200 {object} GetOrdersResponse
+// @Failure 400 {object} common.HTTPError
+// @Failure 502 {object} common.HTTPError
+// @Router /api/v2/merchant/orders [get]
+func (h *MerchantV2Handler) GetOrders(c *gin.Context) {
+	merchantID, ok := merchantV2ID(c)
+	if !ok {
+		return
+	}
+
+	var params merchantv2.GetOrdersParams
+	if err := c.ShouldBindQuery(&params); err != nil {
+		common.RespondWithError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	orders, err := h.MerchantService.ListOrders(merchantID, service.OrdersFilter{
+		DealID:           params.DealID,
+		Type:             params.Type,
+		Status:           params.Status,
+		TimeOpeningStart: params.TimeOpeningStart,
+		TimeOpeningEnd:   params.TimeOpeningEnd,
+		AmountMin:        params.AmountMin,
+		AmountMax:        params.AmountMax,
+		Page:             params.Page,
+		Size:             params.Size,
+		Sort:             params.Sort,
+	})
+	if err != nil {
+		respondMerchantV2Error(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, toHTTPResponse(orders.Page, orders.Login, orders.Username))
+}
+
+// @Summary Get order
+// @Description Get merchant order by gateway order ID
+// @Tags merchant-v2
+// @Produce json
+// @Security BearerAuth
+// @Param orderId path string true "order ID"
+// @Success 200 {object} merchantv2.Order
+// @Failure 404 {object} common.HTTPError
+// @Router /api/v2/merchant/orders/{orderId} [get]
+func (h *MerchantV2Handler) GetOrder(c *gin.Context) {
+	merchantID, ok := merchantV2ID(c)
+	if !ok {
+		return
+	}
+
+	order, err := h.MerchantService.GetOwnOrder(merchantID, c.Param("orderId"))
+	if err != nil {
+		respondMerchantV2Error(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, toMerchantV2Order(order))
+}
+
+// @Summary Get order status
+// @Description Get merchant order status by gateway order ID
+// @Tags merchant-v2
+// @Produce json
+// @Security BearerAuth
+// @Param orderId path string true "order ID"
+// @Success 200 {object} merchantv2.OrderStatusResponse
+// @Failure 404 {object} common.HTTPError
+// @Router /api/v2/merchant/orders/{orderId}/status [get]
+func (h *MerchantV2Handler) GetOrderStatus(c *gin.Context) {
+	merchantID, ok := merchantV2ID(c)
+	if !ok {
+		return
+	}
+
+	order, err := h.MerchantService.GetOwnOrder(merchantID, c.Param("orderId"))
+	if err != nil {
+		respondMerchantV2Error(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, toMerchantV2OrderStatus(order))
+}
+
+// @Summary Get order status by merchant order ID
+// @Description Get merchant order status by merchant-side order ID
+// @Tags merchant-v2
+// @Produce json
+// @Security BearerAuth
+// @Param merchantOrderId path string true "merchant order ID"
+// @Success 200 {object} merchantv2.OrderStatusResponse
+// @Failure 404 {object} common.HTTPError
+// @Router /api/v2/merchant/merchant-orders/{merchantOrderId}/status [get]
+func (h *MerchantV2Handler) GetOrderStatusByMerchantOrderID(c *gin.Context) {
+	merchantID, ok := merchantV2ID(c)
+	if !ok {
+		return
+	}
+
+	order, err := h.MerchantService.GetOwnOrderByMerchantOrderID(merchantID, c.Param("merchantOrderId"))
+	if err != nil {
+		respondMerchantV2Error(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, toMerchantV2OrderStatus(order))
+}
+
+// @Summary Get balance
+// @Description Get merchant account balance
+// @Tags merchant-v2
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} merchantv2.BalanceResponse
+// @Failure 502 {object} common.HTTPError
+// @Router /api/v2/merchant/accounts/balance [get]
+func (h *MerchantV2Handler) GetAccountBalance(c *gin.Context) {
+	merchantID, ok := merchantV2ID(c)
+	if !ok {
+		return
+	}
+
+	balance, err := h.MerchantService.GetBalance(merchantID)
+	if err != nil {
+		respondMerchantV2Error(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, merchantv2.BalanceResponse{
+		Balances: []merchantv2.Balance{
+			{
+				Name:     balance.AccountName,
+				Balance:  balance.Balance,
+				Currency: balance.Currency,
+			},
+		},
+	})
+}
+
+// @Summary Withdraw
+// @Description Withdraw USDT from merchant account wallet. currency must be USDT, other assets are rejected.
+// @Tags merchant-v2
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param input body merchantv2.WithdrawRequest true "withdraw data"
+// @Success 201 {object} merchantv2.WithdrawResponse
+// @Failure 400 {object} common.HTTPError
+// @Failure 502 {object} common.HTTPError
+// @Router /api/v2/merchant/accounts/withdraw [post]
+func (h *MerchantV2Handler) Withdraw(c *gin.Context) {
+	merchantID, ok := merchantV2ID(c)
+	if !ok {
+		return
+	}
+
+	var request merchantv2.WithdrawRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		common.RespondWithError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	if !strings.EqualFold(request.Currency, service.MerchantBalanceAsset) {
+		common.RespondWithError(c, http.StatusBadRequest, "unsupported currency, only "+service.MerchantBalanceAsset+" can be withdrawn")
+		return
+	}
+
+	txHash, err := h.MerchantService.Withdraw(merchantID, request.ToAddress, request.Amount)
+	if err != nil {
+		respondMerchantV2Error(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, merchantv2.WithdrawResponse{
+		TxHash:    txHash,
+		Amount:    request.Amount,
+		Currency:  service.MerchantBalanceAsset,
+		ToAddress: request.ToAddress,
+	})
+}
+
+func merchantV2ID(c *gin.Context) (string, bool) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		common.RespondWithError(c, http.StatusUnauthorized, "merchant not authenticated")
+		return "", false
+	}
+	merchantID, ok := userID.(string)
+	if !ok || merchantID == "" {
+		common.RespondWithError(c, http.StatusUnauthorized, "invalid merchant ID")
+		return "", false
+	}
+	return merchantID, true
+}
+
+func respondMerchantV2Error(c *gin.Context, err error) {
+	var upstreamErr *service.UpstreamError
+	if !errors.As(err, &upstreamErr) {
+		common.RespondWithError(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	switch upstreamErr.Upstream {
+	case service.UpstreamSSO:
+		common.RespondWithError(c, http.StatusUnauthorized, "invalid credentials")
+	case service.UpstreamOrder:
+		st, _ := status.FromError(upstreamErr.Err)
+		common.RespondWithError(c, common.GrpcCodeToHTTP(st.Code()), st.Message())
+	default:
+		common.RespondWithError(c, http.StatusBadGateway, upstreamErr.Upstream+" service is unavailable")
+	}
+}
+
+func toMerchantV2Order(order *domain.Order) merchantv2.Order {
+	response := merchantv2.Order{
+		ID:              order.ID,
+		MerchantOrderID: order.MerchantOrderID,
+		Status:          string(order.Status),
+		Amount:          order.AmountFiat,
+		AmountCrypto:    order.AmountCrypto,
+		Currency:        order.Currency,
+		CurrencyRate:    order.CryptoRubRate,
+		Recalculated:    order.Recalculated,
+		PaymentSystem:   order.PaymentSystem,
+		CallbackURL:     order.CallbackURL,
+		ExpiresAt:       order.ExpiresAt.UTC(),
+	}
+	if bd := order.BankDetail; bd != nil {
+		response.Requisites = merchantv2.Requisites{
+			CardNumber: bd.CardNumber,
+			Phone:      bd.Phone,
+			HolderName: bd.Owner,
+			BankCode:   bd.BankCode,
+			BankName:   bd.BankName,
+			NspkCode:   bd.NspkCode,
+		}
+	}
+	return response
+}
+
+func toMerchantV2OrderStatus(order *domain.Order) merchantv2.OrderStatusResponse {
+	return merchantv2.OrderStatusResponse{
+		ID:              order.ID,
+		MerchantOrderID: order.MerchantOrderID,
+		Status:          string(order.Status),
+	}
+}
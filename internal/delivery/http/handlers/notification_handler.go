@@ -0,0 +1,240 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/LavaJover/shvark-api-gateway/internal/delivery/http/dto/notification"
+	"github.com/LavaJover/shvark-api-gateway/internal/domain"
+	"github.com/LavaJover/shvark-api-gateway/internal/service/notifications"
+	"github.com/gin-gonic/gin"
+)
+
+type NotificationHandler struct {
+	Notifier *notifications.Notifier
+}
+
+func NewNotificationHandler(notifier *notifications.Notifier) *NotificationHandler {
+	return &NotificationHandler{
+		Notifier: notifier,
+	}
+}
+
+// @Summary Get notification preferences
+// @Description Channels, subscribed event types and quiet hours of the current user, with available channels and event types
+// @Tags notifications
+// @Security BearerAuth
+// @Produce json
+// @Success 200 {object} notification.PreferencesResponse
+// @Failure 401 {object} ErrorResponse
+// @Router /notifications/preferences [get]
+func (h *NotificationHandler) GetPreferences(c *gin.Context) {
+	userID, ok := traderFromContext(c)
+	if !ok {
+		return
+	}
+	c.JSON(http.StatusOK, h.preferencesResponse(h.Notifier.Preferences(userID)))
+}
+
+// @Summary Update notification preferences
+// @Description Replace notification preferences of the current user. Empty event_types subscribes to all events.
+// @Tags notifications
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param input body notification.UpdatePreferencesRequest true "preferences"
+// @Success 200 {object} notification.PreferencesResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Router /notifications/preferences [put]
+func (h *NotificationHandler) UpdatePreferences(c *gin.Context) {
+	userID, ok := traderFromContext(c)
+	if !ok {
+		return
+	}
+	var request notification.UpdatePreferencesRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	// подписку на события всех трейдеров меняет только админ
+	preferences := preferencesFromRequest(userID, request)
+	preferences.AllTraders = h.Notifier.Preferences(userID).AllTraders
+	h.savePreferences(c, preferences)
+}
+
+// @Summary Get notification history
+// @Description Delivery history of the current user's notifications, newest first
+// @Tags notifications
+// @Security BearerAuth
+// @Produce json
+// @Param channel query string false "telegram, email or webhook"
+// @Param status query string false "sent, failed or quiet_hours"
+// @Param limit query int false "max entries" default(50)
+// @Success 200 {object} notification.HistoryResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Router /notifications/history [get]
+func (h *NotificationHandler) GetHistory(c *gin.Context) {
+	userID, ok := traderFromContext(c)
+	if !ok {
+		return
+	}
+	h.writeHistory(c, userID)
+}
+
+// @Summary Update user notification preferences
+// @Description Replace notification preferences of any user. all_traders subscribes the user to events of every trader.
+// @Tags admin
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param userId path string true "user ID"
+// @Param input body notification.AdminUpdatePreferencesRequest true "preferences"
+// @Success 200 {object} notification.PreferencesResponse
+// @Failure 400 {object} ErrorResponse
+// @Router /admin/notifications/preferences/{userId} [put]
+func (h *NotificationHandler) AdminUpdatePreferences(c *gin.Context) {
+	var request notification.AdminUpdatePreferencesRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	preferences := preferencesFromRequest(c.Param("userId"), request.UpdatePreferencesRequest)
+	preferences.AllTraders = request.AllTraders
+	h.savePreferences(c, preferences)
+}
+
+// @Summary Get notification history of all users
+// @Description Delivery history of notifications, newest first
+// @Tags admin
+// @Security BearerAuth
+// @Produce json
+// @Param user_id query string false "user ID"
+// @Param channel query string false "telegram, email or webhook"
+// @Param status query string false "sent, failed or quiet_hours"
+// @Param limit query int false "max entries" default(50)
+// @Success 200 {object} notification.HistoryResponse
+// @Failure 400 {object} ErrorResponse
+// @Router /admin/notifications/history [get]
+func (h *NotificationHandler) AdminGetHistory(c *gin.Context) {
+	h.writeHistory(c, c.Query("user_id"))
+}
+
+func (h *NotificationHandler) savePreferences(c *gin.Context, preferences domain.NotificationPreferences) {
+	saved, err := h.Notifier.SavePreferences(preferences)
+	if errors.Is(err, notifications.ErrInvalidPreferences) {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+		return
+	}
+	if err != nil {
+		log.Printf("failed to save notification preferences of %s: %v", preferences.UserID, err)
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "failed to save preferences"})
+		return
+	}
+	c.JSON(http.StatusOK, h.preferencesResponse(saved))
+}
+
+func (h *NotificationHandler) writeHistory(c *gin.Context, userID string) {
+	limit := 50
+	if value := c.Query("limit"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed <= 0 {
+			c.JSON(http.StatusBadRequest, ErrorResponse{Error: "limit must be a positive number"})
+			return
+		}
+		limit = parsed
+	}
+
+	deliveries := h.Notifier.History(notifications.HistoryFilter{
+		UserID:  userID,
+		Channel: domain.NotificationChannel(c.Query("channel")),
+		Status:  domain.NotificationStatus(c.Query("status")),
+		Limit:   limit,
+	})
+	result := make([]notification.Delivery, len(deliveries))
+	for i, delivery := range deliveries {
+		result[i] = notification.Delivery{
+			ID:        delivery.ID,
+			UserID:    delivery.UserID,
+			EventType: string(delivery.EventType),
+			TraderID:  delivery.TraderID,
+			Channel:   string(delivery.Channel),
+			Address:   delivery.Address,
+			Subject:   delivery.Subject,
+			Body:      delivery.Body,
+			Status:    string(delivery.Status),
+			Attempts:  delivery.Attempts,
+			Error:     delivery.Error,
+			CreatedAt: delivery.CreatedAt.Unix(),
+		}
+	}
+	c.JSON(http.StatusOK, notification.HistoryResponse{
+		Deliveries: result,
+	})
+}
+
+func (h *NotificationHandler) preferencesResponse(preferences domain.NotificationPreferences) notification.PreferencesResponse {
+	response := notification.PreferencesResponse{
+		UserID:     preferences.UserID,
+		Targets:    make([]notification.Target, len(preferences.Targets)),
+		EventTypes: make([]string, len(preferences.EventTypes)),
+		AllTraders: preferences.AllTraders,
+	}
+	for i, target := range preferences.Targets {
+		response.Targets[i] = notification.Target{
+			Channel: string(target.Channel),
+			Address: target.Address,
+			Enabled: target.Enabled,
+		}
+	}
+	for i, eventType := range preferences.EventTypes {
+		response.EventTypes[i] = string(eventType)
+	}
+	if preferences.QuietHours != nil {
+		response.QuietHours = &notification.QuietHours{
+			Start:    preferences.QuietHours.Start,
+			End:      preferences.QuietHours.End,
+			Timezone: preferences.QuietHours.Timezone,
+		}
+	}
+	if !preferences.UpdatedAt.IsZero() {
+		response.UpdatedAt = preferences.UpdatedAt.Unix()
+	}
+	for _, channel := range h.Notifier.Channels() {
+		response.AvailableChannels = append(response.AvailableChannels, string(channel))
+	}
+	for _, eventType := range h.Notifier.EventTypes() {
+		response.AvailableEventTypes = append(response.AvailableEventTypes, string(eventType))
+	}
+	return response
+}
+
+func preferencesFromRequest(userID string, request notification.UpdatePreferencesRequest) domain.NotificationPreferences {
+	preferences := domain.NotificationPreferences{
+		UserID:  userID,
+		Targets: make([]domain.NotificationTarget, len(request.Targets)),
+	}
+	for i, target := range request.Targets {
+		preferences.Targets[i] = domain.NotificationTarget{
+			Channel: domain.NotificationChannel(target.Channel),
+			Address: target.Address,
+			Enabled: target.Enabled,
+		}
+	}
+	for _, eventType := range request.EventTypes {
+		preferences.EventTypes = append(preferences.EventTypes, domain.TraderEventType(eventType))
+	}
+	if request.QuietHours != nil {
+		preferences.QuietHours = &domain.QuietHours{
+			Start:    request.QuietHours.Start,
+			End:      request.QuietHours.End,
+			Timezone: request.QuietHours.Timezone,
+		}
+	}
+	return preferences
+}
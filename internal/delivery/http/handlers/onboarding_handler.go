@@ -0,0 +1,152 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	adminResponse "github.com/LavaJover/shvark-api-gateway/internal/delivery/http/dto/admin/response"
+	"github.com/LavaJover/shvark-api-gateway/internal/domain"
+	"github.com/LavaJover/shvark-api-gateway/internal/service"
+	"github.com/gin-gonic/gin"
+)
+
+type OnboardingHandler struct {
+	Onboarding *service.OnboardingService
+}
+
+func NewOnboardingHandler(onboarding *service.OnboardingService) *OnboardingHandler {
+	return &OnboardingHandler{
+		Onboarding: onboarding,
+	}
+}
+
+// @Summary List onboardings
+// @Description Onboarding sagas of teams and merchants, newest first. By default only stuck ones: stopped after a failed step or interrupted by a restart.
+// @Tags admin
+// @Security BearerAuth
+// @Produce json
+// @Param status query string false "stuck, all, running, completed, failed or compensated" default(stuck)
+// @Success 200 {object} adminResponse.OnboardingsResponse
+// @Failure 400 {object} ErrorResponse
+// @Router /admin/onboardings [get]
+func (h *OnboardingHandler) GetOnboardings(c *gin.Context) {
+	var onboardings []domain.Onboarding
+	switch filter := c.DefaultQuery("status", "stuck"); filter {
+	case "stuck":
+		onboardings = h.Onboarding.Stuck()
+	case "all":
+		onboardings = h.Onboarding.List("")
+	case string(domain.OnboardingRunning), string(domain.OnboardingCompleted),
+		string(domain.OnboardingFailed), string(domain.OnboardingCompensated):
+		onboardings = h.Onboarding.List(domain.OnboardingStatus(filter))
+	default:
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "unknown status " + filter})
+		return
+	}
+
+	result := make([]adminResponse.Onboarding, len(onboardings))
+	for i, onboarding := range onboardings {
+		result[i] = onboardingResponse(onboarding)
+	}
+	c.JSON(http.StatusOK, adminResponse.OnboardingsResponse{Onboardings: result})
+}
+
+// @Summary Get onboarding
+// @Tags admin
+// @Security BearerAuth
+// @Produce json
+// @Param id path string true "onboarding ID"
+// @Success 200 {object} adminResponse.Onboarding
+// @Failure 404 {object} ErrorResponse
+// @Router /admin/onboardings/{id} [get]
+func (h *OnboardingHandler) GetOnboarding(c *gin.Context) {
+	onboarding, err := h.Onboarding.Get(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, ErrorResponse{Error: err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, onboardingResponse(onboarding))
+}
+
+// @Summary Resume onboarding
+// @Description Continue a stuck onboarding from the first unfinished step. Onboardings that did not pass registration cannot be resumed: the password is not stored.
+// @Tags admin
+// @Security BearerAuth
+// @Produce json
+// @Param id path string true "onboarding ID"
+// @Success 200 {object} adminResponse.Onboarding
+// @Failure 404 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
+// @Failure 502 {object} adminResponse.OnboardingErrorResponse
+// @Router /admin/onboardings/{id}/resume [post]
+func (h *OnboardingHandler) ResumeOnboarding(c *gin.Context) {
+	onboarding, err := h.Onboarding.Resume(context.WithoutCancel(c.Request.Context()), c.Param("id"))
+	h.respond(c, onboarding, err)
+}
+
+// @Summary Abort onboarding
+// @Description Abort a stuck onboarding and compensate finished steps in reverse order. Registration and wallet cannot be removed upstream and are reported as not_compensable.
+// @Tags admin
+// @Security BearerAuth
+// @Produce json
+// @Param id path string true "onboarding ID"
+// @Success 200 {object} adminResponse.Onboarding
+// @Failure 404 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
+// @Failure 502 {object} adminResponse.OnboardingErrorResponse
+// @Router /admin/onboardings/{id}/abort [post]
+func (h *OnboardingHandler) AbortOnboarding(c *gin.Context) {
+	onboarding, err := h.Onboarding.Abort(c.Param("id"))
+	h.respond(c, onboarding, err)
+}
+
+func (h *OnboardingHandler) respond(c *gin.Context, onboarding domain.Onboarding, err error) {
+	switch {
+	case err == nil:
+		c.JSON(http.StatusOK, onboardingResponse(onboarding))
+	case errors.Is(err, service.ErrOnboardingNotFound):
+		c.JSON(http.StatusNotFound, ErrorResponse{Error: err.Error()})
+	case errors.Is(err, service.ErrOnboardingInProgress),
+		errors.Is(err, service.ErrOnboardingFinished),
+		errors.Is(err, service.ErrOnboardingNotResumable):
+		c.JSON(http.StatusConflict, ErrorResponse{Error: err.Error()})
+	default:
+		c.JSON(http.StatusBadGateway, adminResponse.OnboardingErrorResponse{
+			Error:      err.Error(),
+			Onboarding: onboardingResponse(onboarding),
+		})
+	}
+}
+
+func onboardingResponse(onboarding domain.Onboarding) adminResponse.Onboarding {
+	response := adminResponse.Onboarding{
+		ID:            onboarding.ID,
+		Kind:          string(onboarding.Kind),
+		Login:         onboarding.Login,
+		Username:      onboarding.Username,
+		UserID:        onboarding.UserID,
+		WalletAddress: onboarding.WalletAddress,
+		Status:        string(onboarding.Status),
+		Error:         onboarding.Error,
+		Steps:         make([]adminResponse.OnboardingStep, len(onboarding.Steps)),
+		CreatedBy:     onboarding.CreatedBy,
+		CreatedAt:     onboarding.CreatedAt.Unix(),
+		UpdatedAt:     onboarding.UpdatedAt.Unix(),
+	}
+	if onboarding.Traffic != nil {
+		response.TrafficID = onboarding.Traffic.TrafficID
+	}
+	for i, step := range onboarding.Steps {
+		response.Steps[i] = adminResponse.OnboardingStep{
+			Name:     string(step.Name),
+			Status:   string(step.Status),
+			Attempts: step.Attempts,
+			Error:    step.Error,
+		}
+		if step.CompletedAt != nil {
+			response.Steps[i].CompletedAt = step.CompletedAt.Unix()
+		}
+	}
+	return response
+}
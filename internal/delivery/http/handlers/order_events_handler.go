@@ -0,0 +1,124 @@
+package handlers
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	paymentResponse "github.com/LavaJover/shvark-api-gateway/internal/delivery/http/dto/payment/response"
+	"github.com/LavaJover/shvark-api-gateway/internal/service"
+	"github.com/gin-gonic/gin"
+)
+
+type OrderEventsHandler struct {
+	MerchantService   *service.MerchantService
+	OrderWatcher      *service.OrderWatcher
+	OrderTokens       *service.OrderTokenService
+	heartbeatInterval time.Duration
+}
+
+func NewOrderEventsHandler(
+	merchantService *service.MerchantService,
+	orderWatcher *service.OrderWatcher,
+	orderTokens *service.OrderTokenService,
+	heartbeatInterval time.Duration,
+) *OrderEventsHandler {
+	return &OrderEventsHandler{
+		MerchantService:   merchantService,
+		OrderWatcher:      orderWatcher,
+		OrderTokens:       orderTokens,
+		heartbeatInterval: heartbeatInterval,
+	}
+}
+
+// @Summary Issue pay-in events token
+// @Description Issue short-lived order-scoped token for the pay-in SSE stream
+// @Tags payments
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "order ID"
+// @Success 201 {object} paymentResponse.OrderEventsTokenResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Router /payments/in/h2h/{id}/events/token [post]
+func (h *OrderEventsHandler) IssueEventsToken(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, ErrorResponse{Error: "userID not found in context"})
+		return
+	}
+	merchantID, ok := userID.(string)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, ErrorResponse{Error: "invalid merchant ID"})
+		return
+	}
+
+	order, err := h.MerchantService.GetOwnOrder(merchantID, c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, ErrorResponse{Error: "order not found"})
+		return
+	}
+
+	expiresAt := time.Now().Add(h.OrderTokens.TTL())
+	token := h.OrderTokens.Issue(service.OrderTokenScopeEvents, order.ID, expiresAt)
+
+	c.JSON(http.StatusCreated, paymentResponse.OrderEventsTokenResponse{
+		Token:     token,
+		ExpiresAt: expiresAt.Unix(),
+		EventsURL: fmt.Sprintf("/api/v1/payments/in/h2h/%s/events?token=%s", order.ID, token),
+	})
+}
+
+// @Summary Pay-in status stream
+// @Description Server-Sent Events stream of pay-in order status. A canceled order can still move to a dispute, so the stream ends only when the order succeeds or the token expires.
+// @Description Events: status, recalculated, expiry, ping, done. done carries reason "final" or "token_expired"; on token_expired request a new token and reconnect.
+// @Tags payments
+// @Produce text/event-stream
+// @Param id path string true "order ID"
+// @Param token query string true "order-scoped events token"
+// @Success 200 {object} domain.OrderEvent
+// @Failure 401 {object} ErrorResponse
+// @Router /payments/in/h2h/{id}/events [get]
+func (h *OrderEventsHandler) StreamPayInEvents(c *gin.Context) {
+	orderID := c.Param("id")
+	// токен уже проверен middleware, здесь нужен только его срок
+	tokenExpiresAt, err := h.OrderTokens.ExpiresAt(c.Query("token"), service.OrderTokenScopeEvents, orderID)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no")
+
+	events, unsubscribe := h.OrderWatcher.Subscribe(orderID)
+	defer unsubscribe()
+
+	heartbeat := time.NewTicker(h.heartbeatInterval)
+	defer heartbeat.Stop()
+	tokenExpired := time.NewTimer(time.Until(tokenExpiresAt))
+	defer tokenExpired.Stop()
+
+	c.Stream(func(w io.Writer) bool {
+		select {
+		case <-c.Request.Context().Done():
+			return false
+		case event, ok := <-events:
+			if !ok {
+				c.SSEvent("done", gin.H{"order_id": orderID, "reason": "final"})
+				return false
+			}
+			c.SSEvent(string(event.Type), event)
+			return true
+		case <-heartbeat.C:
+			c.SSEvent("ping", time.Now().Unix())
+			return true
+		case <-tokenExpired.C:
+			c.SSEvent("done", gin.H{"order_id": orderID, "reason": "token_expired"})
+			return false
+		}
+	})
+}
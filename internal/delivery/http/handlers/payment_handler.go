@@ -1,9 +1,10 @@
 package handlers
 
 import (
-	"fmt"
+	"errors"
 	"log"
 	"net/http"
+	"net/url"
 	"strconv"
 	"time"
 
@@ -11,13 +12,14 @@ import (
 	"github.com/LavaJover/shvark-api-gateway/internal/delivery/http/dto/payment/request"
 	paymentRequest "github.com/LavaJover/shvark-api-gateway/internal/delivery/http/dto/payment/request"
 	"github.com/LavaJover/shvark-api-gateway/internal/service"
+	"github.com/LavaJover/shvark-api-gateway/internal/service/i18n"
 	orderpb "github.com/LavaJover/shvark-order-service/proto/gen/order"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
 	"google.golang.org/protobuf/types/known/timestamppb"
 
 	paymentResponse "github.com/LavaJover/shvark-api-gateway/internal/delivery/http/dto/payment/response"
-	// "github.com/LavaJover/shvark-api-gateway/internal/domain"
+	"github.com/LavaJover/shvark-api-gateway/internal/domain"
 	"github.com/gin-gonic/gin"
 )
 
@@ -27,6 +29,10 @@ type PaymentHandler struct {
 	UserClient *client.UserClient
 	SsoClient *client.SSOClient
 	DeeplinkService *service.DeeplinkService
+	MerchantService *service.MerchantService
+	Sandbox *service.MerchantService // запросы с ключом песочницы, nil - песочница выключена
+	TraderEvents *service.TraderEventPublisher
+	DisputeSLA *service.DisputeSLAService
 }
 
 func NewPaymentHandler(
@@ -35,6 +41,10 @@ func NewPaymentHandler(
 	userClient *client.UserClient,
 	ssoClient *client.SSOClient,
 	deeplinkService *service.DeeplinkService,
+	merchantService *service.MerchantService,
+	sandbox *service.MerchantService,
+	traderEvents *service.TraderEventPublisher,
+	disputeSLA *service.DisputeSLAService,
 ) (*PaymentHandler, error) {
 	return &PaymentHandler{
 		OrderClient: orderClient,
@@ -42,6 +52,10 @@ func NewPaymentHandler(
 		UserClient: userClient,
 		SsoClient: ssoClient,
 		DeeplinkService: deeplinkService,
+		MerchantService: merchantService,
+		Sandbox: sandbox,
+		TraderEvents: traderEvents,
+		DisputeSLA: disputeSLA,
 	}, nil
 }
 
@@ -61,7 +75,7 @@ func (h *PaymentHandler) Login(c *gin.Context) {
 		c.JSON(http.StatusOK, gin.H{"error": err.Error()})
 		return
 	}
-	loginResponse, err := h.SsoClient.Login(
+	session, err := h.MerchantService.SignIn(
 		request.Email,
 		request.Password,
 		request.TwoFaCode,
@@ -71,8 +85,8 @@ func (h *PaymentHandler) Login(c *gin.Context) {
 		return
 	}
 	c.JSON(http.StatusOK, paymentResponse.LoginResponse{
-		Token: loginResponse.AccessToken,
-		DateTimeExpires: loginResponse.TimeExp.AsTime().Format("2006-01-02 15:04:05"),
+		Token: session.Token,
+		DateTimeExpires: session.ExpiresAt.Format("2006-01-02 15:04:05"),
 	})
 }
 
@@ -95,61 +109,61 @@ func (h *PaymentHandler) CreateH2HPayIn(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
-	response, err := h.OrderClient.CreatePayInOrder(&orderpb.CreatePayInOrderRequest{
-		MerchantId: payInRequest.MerchantID,
+	merchantID := payInRequest.MerchantID
+	if c.GetBool("sandbox") {
+		// ордера песочницы создаются только на мерчанта ключа
+		merchantID = c.GetString("userID")
+	}
+	order, err := merchantServiceFor(c, h.MerchantService, h.Sandbox).CreatePayIn(service.PayInParams{
+		MerchantID: merchantID,
+		MerchantOrderID: payInRequest.MerchantOrderID,
+		ClientID: payInRequest.ClientID,
 		AmountFiat: payInRequest.AmountFiat,
 		Currency: payInRequest.Currency,
-		ClientId: payInRequest.ClientID,
 		PaymentSystem: payInRequest.PaymentSystem,
-		ExpiresAt: timestamppb.New(time.Now().Add(20*time.Minute)),
-		MerchantOrderId: payInRequest.MerchantOrderID,
-		Shuffle: payInRequest.Shuffle,
-		CallbackUrl: payInRequest.CallbackURL,
-		Type: "DEPOSIT",
 		BankCode: payInRequest.Issuer,
-		NspkCode: "",
-		Country: "",
+		CallbackURL: payInRequest.CallbackURL,
+		Shuffle: payInRequest.Shuffle,
 	})
 	if err != nil  {
-		if status, ok := status.FromError(err); ok {
-			if status.Code() == codes.NotFound {
-				c.JSON(http.StatusConflict, paymentResponse.NoBankDetailsErrorResponse{Error: err.Error()})
-				return
-			}else if status.Code() == codes.FailedPrecondition {
-				c.JSON(http.StatusConflict, paymentResponse.ErrorResponse{Error: err.Error()})
-				return
-			}else {
-				c.JSON(http.StatusConflict, paymentResponse.ErrorResponse{Error: err.Error()})
-			}
+		if status, ok := status.FromError(err); ok && status.Code() == codes.NotFound {
+			c.JSON(http.StatusConflict, paymentResponse.NoBankDetailsErrorResponse{Error: err.Error()})
+			return
 		}
+		c.JSON(http.StatusConflict, paymentResponse.ErrorResponse{Error: err.Error()})
+		return
 	}
 
-	deeplinkURL := fmt.Sprintf("/api/v1/payments/deeplink/select?order_id=%s", response.Order.OrderId)
+	// страница выбора банка читает ордер из order-service, ордеров песочницы там нет
+	deeplinkURL := ""
+	if !c.GetBool("sandbox") {
+		deeplinkURL = h.DeeplinkService.SelectionURL(order.ID, order.ExpiresAt)
+	}
 
 	c.JSON(http.StatusCreated, paymentResponse.CreateH2HPayInResponse{
-		OrderID: response.Order.OrderId,
-		AmountFiat: response.Order.AmountFiat,
-		AmountCrypto: response.Order.AmountCrypto,
-		Currency: response.Order.BankDetail.Currency,
-		PaymentSystem: response.Order.BankDetail.PaymentSystem,
-		Status: response.Order.Status,
-		MerchantOrderID: response.Order.MerchantOrderId,
-		CallbackURL: response.Order.CallbackUrl,
+		OrderID: order.ID,
+		AmountFiat: order.AmountFiat,
+		AmountCrypto: order.AmountCrypto,
+		Currency: order.BankDetail.Currency,
+		PaymentSystem: order.BankDetail.PaymentSystem,
+		Status: string(order.Status),
+		MerchantOrderID: order.MerchantOrderID,
+		CallbackURL: order.CallbackURL,
 		TPayLink: "tpay/link",
 		DeeplinkHTML: deeplinkURL,
-		Recalculated: response.Order.Recalculated,
-		CryptoRubRate: response.Order.CryptoRubRate,
+		Recalculated: order.Recalculated,
+		CryptoRubRate: order.CryptoRubRate,
 		PaymentDetails: paymentResponse.PaymentDetails{
-			CardNumber: response.Order.BankDetail.CardNumber,
-			Owner: response.Order.BankDetail.Owner,
-			Phone: response.Order.BankDetail.Phone,
-			BankID: response.Order.BankDetail.BankCode,
-			Bank: response.Order.BankDetail.BankCode,
-			BankName: response.Order.BankDetail.BankName,
+			CardNumber: order.BankDetail.CardNumber,
+			Owner: order.BankDetail.Owner,
+			Phone: order.BankDetail.Phone,
+			BankID: order.BankDetail.BankCode,
+			Bank: order.BankDetail.BankCode,
+			BankName: order.BankDetail.BankName,
 		},
-		ExpiresAt: response.Order.ExpiresAt.Seconds,
-		UsdRate: response.Order.CryptoRubRate,
-		MerchantIncome: response.Order.AmountCrypto - response.Order.AmountCrypto * response.Order.PlatformFee,
+		ExpiresAt: order.ExpiresAt.Unix(),
+		UsdRate: order.CryptoRubRate,
+		MerchantIncome: order.AmountCrypto - order.AmountCrypto * order.PlatformFee,
 	})
 }
 
@@ -232,32 +246,32 @@ func (h *PaymentHandler) GetH2HPayInInfo(c *gin.Context) {
 		return
 	}
 
-	response, err := h.OrderClient.GetOrderByID(orderID)
+	order, err := orderFor(c, h.MerchantService, h.Sandbox, orderID)
 	if err != nil {
 		c.JSON(http.StatusNotFound, paymentResponse.ErrorResponse{Error: "Order info is unavailable now"})
 		return
 	}
 
 	c.JSON(http.StatusOK, paymentResponse.GetH2HPayInInfoResponse{
-		OrderID: response.Order.OrderId,
-		AmountFiat: response.Order.AmountFiat,
-		AmountCrypto: response.Order.AmountCrypto,
-		Currency: response.Order.BankDetail.Currency,
-		PaymentSystem: response.Order.BankDetail.PaymentSystem,
-		Status: response.Order.Status,
-		MerchantOrderID: response.Order.MerchantOrderId,
-		CallbackURL: response.Order.CallbackUrl,
-		Recalculated: response.Order.Recalculated,
-		CryptoRubRate: response.Order.CryptoRubRate,
+		OrderID: order.ID,
+		AmountFiat: order.AmountFiat,
+		AmountCrypto: order.AmountCrypto,
+		Currency: order.BankDetail.Currency,
+		PaymentSystem: order.BankDetail.PaymentSystem,
+		Status: string(order.Status),
+		MerchantOrderID: order.MerchantOrderID,
+		CallbackURL: order.CallbackURL,
+		Recalculated: order.Recalculated,
+		CryptoRubRate: order.CryptoRubRate,
 		PaymentDetails: paymentResponse.PaymentDetails{
-			CardNumber: response.Order.BankDetail.CardNumber,
-			Owner: response.Order.BankDetail.Owner,
-			Phone: response.Order.BankDetail.Phone,
-			BankID: response.Order.BankDetail.BankCode,
-			Bank: response.Order.BankDetail.BankName,
-			BankName: response.Order.BankDetail.BankName,
+			CardNumber: order.BankDetail.CardNumber,
+			Owner: order.BankDetail.Owner,
+			Phone: order.BankDetail.Phone,
+			BankID: order.BankDetail.BankCode,
+			Bank: order.BankDetail.BankName,
+			BankName: order.BankDetail.BankName,
 		},
-		ExpiresAt: response.Order.ExpiresAt.Seconds,
+		ExpiresAt: order.ExpiresAt.Unix(),
 		TPayLink: "tpay-link",
 	})
 
@@ -314,7 +328,7 @@ func (h *PaymentHandler) OpenPayInArbitrageLink(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
 		return
 	}
-	disputeTtl := 30*time.Minute
+	disputeTtl := h.DisputeSLA.TTL()
 	disputeID, err := h.OrderClient.CreateDispute(
 		orderID,
 		requestBody.ProofUrl,
@@ -326,6 +340,8 @@ func (h *PaymentHandler) OpenPayInArbitrageLink(c *gin.Context) {
 		c.JSON(http.StatusNotFound, ErrorResponse{Error: err.Error()})
 		return
 	}
+	h.TraderEvents.DisputeOpened(orderID, disputeID)
+	h.DisputeSLA.Opened(orderID, disputeID, disputeTtl)
 
 	c.JSON(http.StatusCreated, paymentResponse.CreateDisputeResponse{
 		DisputeID: disputeID,
@@ -389,24 +405,18 @@ func (h *PaymentHandler) GetAccountBalance(c *gin.Context) {
 		return
 	}
 
-	balance, err := h.WalletClient.GetBalance(userIDstr)
-	if err != nil {
-		c.JSON(http.StatusBadGateway, gin.H{"error": err.Error()})
-		return
-	}
-
-	userResp, err := h.UserClient.GetUserByID(userIDstr)
+	balance, err := merchantServiceFor(c, h.MerchantService, h.Sandbox).GetBalance(userIDstr)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get account info"})
+		respondLegacyBalanceError(c, err)
 		return
 	}
 
 	c.JSON(http.StatusOK, paymentResponse.GetAccountBalanceResponse{
 		Balances: []paymentResponse.AccountBalance{
 			paymentResponse.AccountBalance{
-				Name: userResp.Username,
-				Balance: strconv.FormatFloat(balance, 'f', 6, 64),
-				Currency: "USDT",
+				Name: balance.AccountName,
+				Balance: strconv.FormatFloat(balance.Balance, 'f', 6, 64),
+				Currency: balance.Currency,
 			},
 		},
 	})
@@ -432,12 +442,12 @@ func (h *PaymentHandler) GetRedirectPayInInfo(c *gin.Context) {
 // @Router /payments/order/{orderId}/status [get]
 func (h *PaymentHandler) GetOrderStatus(c *gin.Context) {
 	orderID := c.Param("orderId")
-	orderResponse, err := h.OrderClient.GetOrderByID(orderID)
+	order, err := orderFor(c, h.MerchantService, h.Sandbox, orderID)
 	if err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "failed to find order"})
 		return
 	}
-	c.JSON(http.StatusOK, gin.H{"status": orderResponse.Order.Status})
+	c.JSON(http.StatusOK, gin.H{"status": string(order.Status)})
 }
 
 // @Summary Получить список ордеров
@@ -481,70 +491,25 @@ func (h *PaymentHandler) GetOrders(c *gin.Context) {
 		return
 	}
 
-	userResp, err := h.UserClient.GetUserByID(merchantIDstr)
+	orders, err := merchantServiceFor(c, h.MerchantService, h.Sandbox).ListOrders(merchantIDstr, service.OrdersFilter{
+		DealID:           params.DealID,
+		Type:             params.Type,
+		Status:           params.Status,
+		TimeOpeningStart: params.TimeOpeningStart,
+		TimeOpeningEnd:   params.TimeOpeningEnd,
+		AmountMin:        params.AmountMin,
+		AmountMax:        params.AmountMax,
+		Page:             params.Page,
+		Size:             params.Size,
+		Sort:             params.Sort,
+	})
 	if err != nil {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "failed to find user"})
+		respondLegacyOrdersError(c, err)
 		return
 	}
 
-    // Обрабатываем значения по умолчанию для page и size
-    page := 0
-    if params.Page != nil {
-        page = *params.Page
-    }
-
-    size := 10 // значение по умолчанию
-    if params.Size != nil {
-        size = *params.Size
-    }
-
-    // Ограничиваем максимальный размер страницы
-    if size > 100 {
-        size = 100
-    }
-
-    // Формируем gRPC запрос
-    grpcReq := &orderpb.GetOrdersRequest{
-        MerchantId: merchantID.(string),
-        DealId:     params.DealID,
-        Type:       params.Type,
-        Status:     params.Status,
-        AmountMin:  params.AmountMin,
-        AmountMax:  params.AmountMax,
-        Sort:       params.Sort,
-        Page:       int32(page),
-        Size:       int32(size),
-    }
-
-    // Преобразуем временные параметры
-    if params.TimeOpeningStart != nil {
-        grpcReq.TimeOpeningStart = timestamppb.New(*params.TimeOpeningStart)
-    }
-    if params.TimeOpeningEnd != nil {
-        grpcReq.TimeOpeningEnd = timestamppb.New(*params.TimeOpeningEnd)
-    }
-    
-    grpcResp, err := h.OrderClient.GetOrders(grpcReq)
-    if err != nil {
-        // Обработка ошибок gRPC
-        st, ok := status.FromError(err)
-        if ok {
-            switch st.Code() {
-            case codes.InvalidArgument:
-                c.JSON(http.StatusBadRequest, gin.H{"error": st.Message()})
-            case codes.NotFound:
-                c.JSON(http.StatusNotFound, gin.H{"error": st.Message()})
-            default:
-                c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
-            }
-        } else {
-            c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get orders"})
-        }
-        return
-    }
-
     // Преобразуем gRPC ответ в HTTP ответ
-    response := toHTTPResponse(grpcResp, userResp.Login, userResp.Username)
+    response := toHTTPResponse(orders.Page, orders.Login, orders.Username)
     c.JSON(http.StatusOK, response)
 }
 
@@ -577,7 +542,7 @@ func (h *PaymentHandler) Withdraw(c *gin.Context) {
 		return
 	}
 
-	txHash, err := h.WalletClient.Withdraw(
+	txHash, err := merchantServiceFor(c, h.MerchantService, h.Sandbox).Withdraw(
 		userIDstr,
 		withdrawRequest.ToAddress,
 		withdrawRequest.Amount,
@@ -614,24 +579,124 @@ func (h *PaymentHandler) RedirectToDeeplink(c *gin.Context) {
         return
     }
 
-    // Здесь можно реализовать логику прямого редиректа
-    // Пока возвращаем JSON с URL для фронтенда
-    redirectURL := fmt.Sprintf("/payments/deeplink/html?order_id=%s&bank=%s", 
-        request.OrderID, request.BankCode)
-    
+    order, ok := h.merchantOrder(c, request.OrderID)
+    if !ok {
+        return
+    }
+
+    // Страница банка открывается без авторизации мерчанта, доступ к ней дает токен ордера
+    redirectURL := h.DeeplinkService.SpecificURL(order.ID, request.BankCode, order.ExpiresAt)
+    if request.PhoneNumber != nil && *request.PhoneNumber != "" {
+        redirectURL += "&phone=" + url.QueryEscape(*request.PhoneNumber)
+    }
+
     c.JSON(http.StatusOK, paymentResponse.DeeplinkResponse{
         RedirectURL: redirectURL,
     })
 }
 
+// @Summary Get pay-in deeplinks
+// @Description Banks eligible for the order and, for each bank, app links per platform in priority order.
+// @Description Links are tried in order, then the store link, then page_url as the last resort.
+// @Tags payments
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "order ID"
+// @Param platform query string false "Only links for this platform, default ios, android and desktop" Enums(ios, android, desktop)
+// @Param phone query string false "Phone number for phone transfers"
+// @Success 200 {object} paymentResponse.DeeplinkOptionsResponse
+// @Failure 400 {object} paymentResponse.ErrorResponse
+// @Failure 401 {object} paymentResponse.ErrorResponse
+// @Failure 404 {object} paymentResponse.ErrorResponse
+// @Router /payments/in/h2h/{id}/deeplinks [get]
+func (h *PaymentHandler) GetPayInDeeplinks(c *gin.Context) {
+    order, ok := h.merchantOrder(c, c.Param("id"))
+    if !ok {
+        return
+    }
+
+    platforms := []domain.Platform{domain.PlatformIOS, domain.PlatformAndroid, domain.PlatformDesktop}
+    if value := c.Query("platform"); value != "" {
+        platform, ok := domain.ParsePlatform(value)
+        if !ok || platform == domain.PlatformUnknown {
+            c.JSON(http.StatusBadRequest, paymentResponse.ErrorResponse{Error: "platform must be one of ios, android, desktop"})
+            return
+        }
+        platforms = []domain.Platform{platform}
+    }
+
+    var phonePtr *string
+    if phone := c.Query("phone"); phone != "" {
+        phonePtr = &phone
+    }
+
+    options, err := h.DeeplinkService.GenerateDeeplinkOptions(order.ID, phonePtr, platforms)
+    if err != nil {
+        log.Printf("Error generating deeplink options for order %s: %v", order.ID, err)
+        c.JSON(http.StatusBadGateway, paymentResponse.ErrorResponse{Error: "deeplinks are unavailable now"})
+        return
+    }
+
+    banks := make([]paymentResponse.DeeplinkBankResponse, len(options.Banks))
+    for i, bank := range options.Banks {
+        links := make(map[string][]domain.DeeplinkLink, len(bank.Platforms))
+        for platform, platformLinks := range bank.Platforms {
+            links[string(platform)] = platformLinks
+        }
+        banks[i] = paymentResponse.DeeplinkBankResponse{
+            BankCode:     bank.BankCode,
+            BankName:     bank.BankName,
+            Icon:         bank.Icon,
+            TransferType: bank.TransferType,
+            AppStoreURL:  bank.AppStoreURL,
+            PlayStoreURL: bank.PlayStoreURL,
+            PageURL:      bank.PageURL,
+            Platforms:    links,
+        }
+    }
+
+    c.Header("Cache-Control", "no-store")
+    c.JSON(http.StatusOK, paymentResponse.DeeplinkOptionsResponse{
+        OrderID:       options.OrderID,
+        Amount:        options.Amount,
+        Currency:      options.Currency,
+        PaymentSystem: options.PaymentSystem,
+        Banks:         banks,
+    })
+}
+
+// merchantOrder ордер мерчанта из контекста авторизации, при ошибке ответ уже отправлен
+func (h *PaymentHandler) merchantOrder(c *gin.Context, orderID string) (*domain.Order, bool) {
+    userID, exists := c.Get("userID")
+    if !exists {
+        c.JSON(http.StatusUnauthorized, paymentResponse.ErrorResponse{Error: "userID not found in context"})
+        return nil, false
+    }
+    merchantID, ok := userID.(string)
+    if !ok {
+        c.JSON(http.StatusUnauthorized, paymentResponse.ErrorResponse{Error: "invalid merchant ID"})
+        return nil, false
+    }
+
+    order, err := h.MerchantService.GetOwnOrder(merchantID, orderID)
+    if err != nil {
+        c.JSON(http.StatusNotFound, paymentResponse.ErrorResponse{Error: "order not found"})
+        return nil, false
+    }
+    return order, true
+}
+
 // @Summary Get bank selection page
 // @Description Get HTML page with bank selection for deeplinks
 // @Tags payments
 // @Accept json
 // @Produce html
 // @Param order_id query string true "Order ID"
+// @Param token query string true "Order-scoped deeplink token"
+// @Param lang query string false "Page language, overrides Accept-Language" Enums(ru, en, kk, uz)
 // @Success 200 {string} string "HTML content"
 // @Failure 400 {object} paymentResponse.ErrorResponse
+// @Failure 401 {object} paymentResponse.ErrorResponse
 // @Router /payments/deeplink/select [get]
 func (h *PaymentHandler) GetBankSelectionPage(c *gin.Context) {
     orderID := c.Query("order_id")
@@ -640,13 +705,16 @@ func (h *PaymentHandler) GetBankSelectionPage(c *gin.Context) {
         return
     }
 
-    deeplinkData, err := h.DeeplinkService.GenerateBankSelectionPage(orderID)
+    deeplinkData, err := h.DeeplinkService.GenerateBankSelectionPage(orderID, c.Query("token"), pageLanguages(c))
     if err != nil {
         log.Printf("Error generating bank selection page for order %s: %v", orderID, err)
         c.JSON(http.StatusBadRequest, paymentResponse.ErrorResponse{Error: err.Error()})
         return
     }
 
+    c.Header("Accept-CH", "Sec-CH-UA-Platform, Sec-CH-UA-Mobile")
+    c.Header("Vary", "User-Agent, Sec-CH-UA-Platform, Sec-CH-UA-Mobile, Accept-Language")
+    c.Header("Content-Language", deeplinkData.Locale)
     c.Header("Content-Type", "text/html; charset=utf-8")
     c.String(http.StatusOK, deeplinkData.HTMLContent)
 }
@@ -659,8 +727,12 @@ func (h *PaymentHandler) GetBankSelectionPage(c *gin.Context) {
 // @Param order_id query string true "Order ID"
 // @Param bank query string true "Bank code"
 // @Param phone query string false "Phone number"
+// @Param platform query string false "Force client platform instead of User-Agent detection" Enums(ios, android, desktop, unknown)
+// @Param token query string true "Order-scoped deeplink token"
+// @Param lang query string false "Page language, overrides Accept-Language" Enums(ru, en, kk, uz)
 // @Success 200 {string} string "HTML content"
 // @Failure 400 {object} paymentResponse.ErrorResponse
+// @Failure 401 {object} paymentResponse.ErrorResponse
 // @Router /payments/deeplink/specific [get]
 func (h *PaymentHandler) GetSpecificDeeplink(c *gin.Context) {
     orderID := c.Query("order_id")
@@ -682,13 +754,96 @@ func (h *PaymentHandler) GetSpecificDeeplink(c *gin.Context) {
         phonePtr = &phone
     }
 
-    deeplinkData, err := h.DeeplinkService.GenerateSpecificDeeplink(orderID, bankCode, phonePtr)
+    platform := service.DetectPlatform(
+        c.GetHeader("User-Agent"),
+        c.GetHeader("Sec-CH-UA-Platform"),
+        c.GetHeader("Sec-CH-UA-Mobile"),
+    )
+    if forced, ok := domain.ParsePlatform(c.Query("platform")); ok {
+        platform = forced
+    }
+
+    deeplinkData, err := h.DeeplinkService.GenerateSpecificDeeplink(orderID, bankCode, phonePtr, platform, c.Query("token"), pageLanguages(c))
     if err != nil {
         log.Printf("Error generating specific deeplink for order %s, bank %s: %v", orderID, bankCode, err)
         c.JSON(http.StatusBadRequest, paymentResponse.ErrorResponse{Error: err.Error()})
         return
     }
 
+    c.Header("Accept-CH", "Sec-CH-UA-Platform, Sec-CH-UA-Mobile")
+    c.Header("Vary", "User-Agent, Sec-CH-UA-Platform, Sec-CH-UA-Mobile, Accept-Language")
+    c.Header("Content-Language", deeplinkData.Locale)
     c.Header("Content-Type", "text/html; charset=utf-8")
     c.String(http.StatusOK, deeplinkData.HTMLContent)
-}
\ No newline at end of file
+}
+
+// @Summary Reveal deeplink requisites
+// @Description Full requisites and app links for bank pages with on_demand requisites policy
+// @Tags payments
+// @Produce json
+// @Param order_id query string true "Order ID"
+// @Param bank query string true "Bank code"
+// @Param phone query string false "Phone number"
+// @Param platform query string false "Client platform" Enums(ios, android, desktop, unknown)
+// @Param token query string true "Order-scoped deeplink token"
+// @Success 200 {object} paymentResponse.DeeplinkRequisitesResponse
+// @Failure 400 {object} paymentResponse.ErrorResponse
+// @Failure 401 {object} paymentResponse.ErrorResponse
+// @Failure 403 {object} paymentResponse.ErrorResponse "active bank template policy is not on_demand"
+// @Router /payments/deeplink/requisites [get]
+func (h *PaymentHandler) GetDeeplinkRequisites(c *gin.Context) {
+    orderID := c.Query("order_id")
+    bankCode := c.Query("bank")
+    phone := c.Query("phone")
+
+    if orderID == "" {
+        c.JSON(http.StatusBadRequest, paymentResponse.ErrorResponse{Error: "order_id parameter is required"})
+        return
+    }
+
+    if bankCode == "" {
+        c.JSON(http.StatusBadRequest, paymentResponse.ErrorResponse{Error: "bank parameter is required"})
+        return
+    }
+
+    var phonePtr *string
+    if phone != "" {
+        phonePtr = &phone
+    }
+
+    platform := service.DetectPlatform(
+        c.GetHeader("User-Agent"),
+        c.GetHeader("Sec-CH-UA-Platform"),
+        c.GetHeader("Sec-CH-UA-Mobile"),
+    )
+    if forced, ok := domain.ParsePlatform(c.Query("platform")); ok {
+        platform = forced
+    }
+
+    requisites, err := h.DeeplinkService.RevealRequisites(orderID, bankCode, phonePtr, platform)
+    if errors.Is(err, service.ErrRequisitesNotOnDemand) {
+        c.JSON(http.StatusForbidden, paymentResponse.ErrorResponse{Error: err.Error()})
+        return
+    }
+    if err != nil {
+        log.Printf("Error revealing requisites for order %s, bank %s: %v", orderID, bankCode, err)
+        c.JSON(http.StatusBadRequest, paymentResponse.ErrorResponse{Error: err.Error()})
+        return
+    }
+
+    c.Header("Cache-Control", "no-store")
+    c.JSON(http.StatusOK, paymentResponse.DeeplinkRequisitesResponse{
+        CardNumber:  requisites.CardNumber,
+        PhoneNumber: requisites.PhoneNumber,
+        Deeplinks:   requisites.Deeplinks,
+    })
+}
+
+// pageLanguages языки клиента для страниц в порядке приоритета: параметр lang, затем Accept-Language
+func pageLanguages(c *gin.Context) []string {
+    languages := i18n.ParseAcceptLanguage(c.GetHeader("Accept-Language"))
+    if lang := c.Query("lang"); lang != "" {
+        languages = append([]string{lang}, languages...)
+    }
+    return languages
+}
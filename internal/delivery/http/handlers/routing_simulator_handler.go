@@ -0,0 +1,127 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+
+	adminRequest "github.com/LavaJover/shvark-api-gateway/internal/delivery/http/dto/admin/request"
+	adminResponse "github.com/LavaJover/shvark-api-gateway/internal/delivery/http/dto/admin/response"
+	"github.com/LavaJover/shvark-api-gateway/internal/domain"
+	"github.com/LavaJover/shvark-api-gateway/internal/service"
+	"github.com/gin-gonic/gin"
+)
+
+type RoutingSimulatorHandler struct {
+	Simulator *service.RoutingSimulatorService
+}
+
+func NewRoutingSimulatorHandler(simulator *service.RoutingSimulatorService) *RoutingSimulatorHandler {
+	return &RoutingSimulatorHandler{Simulator: simulator}
+}
+
+// @Summary Simulate bank detail selection
+// @Description Explains why a merchant order gets "no bank details": for every trader with traffic on the merchant checks that the traffic is enabled and unlocked, antifraud, bank details of the payment system and bank, their amount and turnover limits and the devices they are bound to. Nothing is changed. Reasons: traffic_disabled, merchant_locked, trader_locked, antifraud_locked, traffic_locked, antifraud_failed, no_bank_details, no_eligible_bank_details; per bank detail: bank_detail_disabled, amount_below_min, amount_above_max, day_amount_limit, month_amount_limit, day_quantity_limit, month_quantity_limit, device_not_found, device_disabled, device_offline. Order queues on bank details and delays between orders are not simulated.
+// @Tags admin
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param input body adminRequest.RoutingSimulationRequest true "order parameters"
+// @Success 200 {object} adminResponse.RoutingSimulationResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 502 {object} ErrorResponse
+// @Router /admin/traffic/simulate [post]
+func (h *RoutingSimulatorHandler) Simulate(c *gin.Context) {
+	var request adminRequest.RoutingSimulationRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	simulation, err := h.Simulator.Simulate(c.Request.Context(), service.RoutingSimulationParams{
+		MerchantID:    request.MerchantID,
+		AmountFiat:    request.AmountFiat,
+		PaymentSystem: request.PaymentSystem,
+		BankCode:      request.BankCode,
+	})
+	if err != nil {
+		var upstream *service.UpstreamError
+		switch {
+		case errors.As(err, &upstream):
+			c.JSON(http.StatusBadGateway, ErrorResponse{Error: err.Error()})
+		case errors.Is(err, service.ErrRoutingMerchantRequired),
+			errors.Is(err, service.ErrRoutingAmount),
+			errors.Is(err, service.ErrRoutingPaymentSystem):
+			c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+		default:
+			log.Printf("routing simulation: %v", err)
+			c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "failed to simulate routing"})
+		}
+		return
+	}
+
+	response := adminResponse.RoutingSimulationResponse{
+		MerchantID:    simulation.MerchantID,
+		AmountFiat:    simulation.AmountFiat,
+		PaymentSystem: simulation.PaymentSystem,
+		BankCode:      simulation.BankCode,
+		SimulatedAt:   simulation.SimulatedAt,
+		Traders:       len(simulation.Traders),
+		Eligible:      simulation.Eligible,
+		Excluded:      simulation.Excluded,
+		Unknown:       simulation.Unknown,
+		Exclusions:    make(map[string]int, len(simulation.Exclusions)),
+		Results:       make([]adminResponse.RoutingTrader, len(simulation.Traders)),
+	}
+	for reason, count := range simulation.Exclusions {
+		response.Exclusions[string(reason)] = count
+	}
+	for i, trader := range simulation.Traders {
+		result := adminResponse.RoutingTrader{
+			TraderID:            trader.TraderID,
+			TrafficID:           trader.TrafficID,
+			TraderPriority:      trader.TraderPriority,
+			TraderRewardPercent: trader.TraderRewardPercent,
+			Verdict:             string(trader.Verdict()),
+			Reasons:             routingReasons(trader.Reasons),
+			FailedChecks:        trader.FailedChecks,
+			AntifraudRequired:   trader.AntifraudRequired,
+			FailedRules:         trader.FailedRules,
+			Locks:               adminResponse.RoutingLocks(trader.Locks),
+			EligibleBankDetails: trader.EligibleBankDetails(),
+			BankDetails:         make([]adminResponse.RoutingBankDetail, len(trader.BankDetails)),
+		}
+		for j, bankDetail := range trader.BankDetails {
+			result.BankDetails[j] = adminResponse.RoutingBankDetail{
+				BankDetailID:     bankDetail.BankDetailID,
+				BankCode:         bankDetail.BankCode,
+				PaymentSystem:    bankDetail.PaymentSystem,
+				DeviceID:         bankDetail.DeviceID,
+				Enabled:          bankDetail.Enabled,
+				MinAmount:        bankDetail.MinAmount,
+				MaxAmount:        bankDetail.MaxAmount,
+				MaxAmountDay:     bankDetail.MaxAmountDay,
+				MaxAmountMonth:   bankDetail.MaxAmountMonth,
+				MaxQuantityDay:   bankDetail.MaxQuantityDay,
+				MaxQuantityMonth: bankDetail.MaxQuantityMonth,
+				AmountToday:      bankDetail.AmountToday,
+				AmountMonth:      bankDetail.AmountMonth,
+				CountToday:       bankDetail.CountToday,
+				CountMonth:       bankDetail.CountMonth,
+				Eligible:         bankDetail.Eligible(),
+				Reasons:          routingReasons(bankDetail.Reasons),
+			}
+		}
+		response.Results[i] = result
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// routingReasons причины строками; пустой список вместо null, чтобы клиентам не нужно было проверять оба случая
+func routingReasons(reasons []domain.RoutingExclusion) []string {
+	result := make([]string, len(reasons))
+	for i, reason := range reasons {
+		result[i] = string(reason)
+	}
+	return result
+}
@@ -0,0 +1,227 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/LavaJover/shvark-api-gateway/internal/delivery/http/dto/sandbox"
+	"github.com/LavaJover/shvark-api-gateway/internal/domain"
+	"github.com/LavaJover/shvark-api-gateway/internal/service"
+	"github.com/gin-gonic/gin"
+)
+
+// SandboxHandler ключи песочницы мерчанта и ручки смены статуса ордеров песочницы
+type SandboxHandler struct {
+	Credentials *service.SandboxCredentialService
+	Backend     *service.SandboxBackend
+}
+
+func NewSandboxHandler(credentials *service.SandboxCredentialService, backend *service.SandboxBackend) *SandboxHandler {
+	return &SandboxHandler{
+		Credentials: credentials,
+		Backend:     backend,
+	}
+}
+
+// merchantServiceFor сервис мерчанта для запроса: с ключом песочницы - симулятор, иначе боевой
+func merchantServiceFor(c *gin.Context, live, sandbox *service.MerchantService) *service.MerchantService {
+	if c.GetBool("sandbox") && sandbox != nil {
+		return sandbox
+	}
+	return live
+}
+
+// orderFor ордер по ID. Ключ песочницы видит только ордера своего мерчанта.
+func orderFor(c *gin.Context, live, sandbox *service.MerchantService, orderID string) (*domain.Order, error) {
+	if c.GetBool("sandbox") && sandbox != nil {
+		return sandbox.GetOwnOrder(c.GetString("userID"), orderID)
+	}
+	return live.GetOrder(orderID)
+}
+
+// orderByMerchantOrderIDFor то же, что orderFor, но по ID ордера мерчанта
+func orderByMerchantOrderIDFor(c *gin.Context, live, sandbox *service.MerchantService, merchantOrderID string) (*domain.Order, error) {
+	if c.GetBool("sandbox") && sandbox != nil {
+		return sandbox.GetOwnOrderByMerchantOrderID(c.GetString("userID"), merchantOrderID)
+	}
+	return live.GetOrderByMerchantOrderID(merchantOrderID)
+}
+
+// @Summary Issue sandbox key
+// @Description Issue a sandbox key for the current merchant. The key is returned once and is sent as "Authorization: Bearer <key>" to /merchant and /payments; such requests are served by the simulated order backend.
+// @Tags sandbox
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param input body sandbox.IssueCredentialRequest false "key name"
+// @Success 201 {object} sandbox.IssueCredentialResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Router /sandbox/credentials [post]
+func (h *SandboxHandler) IssueCredential(c *gin.Context) {
+	merchantID, ok := traderFromContext(c)
+	if !ok {
+		return
+	}
+	var request sandbox.IssueCredentialRequest
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&request); err != nil {
+			c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+			return
+		}
+	}
+
+	key, credential, err := h.Credentials.Issue(merchantID, request.Name)
+	if err != nil {
+		log.Printf("failed to issue sandbox key for merchant %s: %v", merchantID, err)
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "failed to issue sandbox key"})
+		return
+	}
+	c.JSON(http.StatusCreated, sandbox.IssueCredentialResponse{
+		Key:        key,
+		Credential: sandboxCredentialResponse(credential),
+	})
+}
+
+// @Summary List sandbox keys
+// @Description Active sandbox keys of the current merchant, without secrets
+// @Tags sandbox
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} sandbox.CredentialsResponse
+// @Failure 401 {object} ErrorResponse
+// @Router /sandbox/credentials [get]
+func (h *SandboxHandler) GetCredentials(c *gin.Context) {
+	merchantID, ok := traderFromContext(c)
+	if !ok {
+		return
+	}
+	credentials := h.Credentials.MerchantCredentials(merchantID)
+	result := make([]sandbox.Credential, len(credentials))
+	for i, credential := range credentials {
+		result[i] = sandboxCredentialResponse(credential)
+	}
+	c.JSON(http.StatusOK, sandbox.CredentialsResponse{Credentials: result})
+}
+
+// @Summary Revoke sandbox key
+// @Tags sandbox
+// @Security BearerAuth
+// @Param id path string true "sandbox key ID"
+// @Success 204
+// @Failure 401 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Router /sandbox/credentials/{id} [delete]
+func (h *SandboxHandler) RevokeCredential(c *gin.Context) {
+	merchantID, ok := traderFromContext(c)
+	if !ok {
+		return
+	}
+	err := h.Credentials.Revoke(merchantID, c.Param("id"))
+	if errors.Is(err, service.ErrSandboxCredentialNotFound) {
+		c.JSON(http.StatusNotFound, ErrorResponse{Error: err.Error()})
+		return
+	}
+	if err != nil {
+		log.Printf("failed to revoke sandbox key %s: %v", c.Param("id"), err)
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "failed to revoke sandbox key"})
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// @Summary Mark sandbox order paid
+// @Description Simulate payment confirmation: the order becomes SUCCEED, the sandbox balance grows by the crypto amount and a webhook is sent to the order callback_url
+// @Tags sandbox
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "sandbox order ID"
+// @Success 200 {object} sandbox.TransitionResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
+// @Router /sandbox/orders/{id}/paid [post]
+func (h *SandboxHandler) MarkPaid(c *gin.Context) {
+	h.transition(c, h.Backend.MarkPaid)
+}
+
+// @Summary Expire sandbox order
+// @Description Simulate order expiration: the unpaid order becomes CANCELED and a webhook is sent to the order callback_url
+// @Tags sandbox
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "sandbox order ID"
+// @Success 200 {object} sandbox.TransitionResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
+// @Router /sandbox/orders/{id}/expire [post]
+func (h *SandboxHandler) Expire(c *gin.Context) {
+	h.transition(c, h.Backend.Expire)
+}
+
+// @Summary Open sandbox dispute
+// @Description Simulate a dispute on a pending or canceled order: the order becomes DISPUTE_CREATED and a webhook is sent to the order callback_url
+// @Tags sandbox
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "sandbox order ID"
+// @Success 200 {object} sandbox.TransitionResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
+// @Router /sandbox/orders/{id}/dispute [post]
+func (h *SandboxHandler) OpenDispute(c *gin.Context) {
+	h.transition(c, h.Backend.OpenDispute)
+}
+
+func (h *SandboxHandler) transition(
+	c *gin.Context,
+	apply func(merchantID, orderID string) (*domain.Order, domain.SandboxWebhook, error),
+) {
+	merchantID, ok := traderFromContext(c)
+	if !ok {
+		return
+	}
+
+	order, webhook, err := apply(merchantID, c.Param("id"))
+	if errors.Is(err, service.ErrSandboxOrderNotFound) {
+		c.JSON(http.StatusNotFound, ErrorResponse{Error: err.Error()})
+		return
+	}
+	if errors.Is(err, service.ErrSandboxTransition) {
+		c.JSON(http.StatusConflict, ErrorResponse{Error: err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, sandbox.TransitionResponse{
+		OrderID:         order.ID,
+		MerchantOrderID: order.MerchantOrderID,
+		Status:          string(order.Status),
+		AmountFiat:      order.AmountFiat,
+		AmountCrypto:    order.AmountCrypto,
+		Webhook: sandbox.Webhook{
+			URL:        webhook.URL,
+			StatusCode: webhook.StatusCode,
+			Error:      webhook.Error,
+			SentAt:     webhook.SentAt.Unix(),
+		},
+	})
+}
+
+func sandboxCredentialResponse(credential domain.SandboxCredential) sandbox.Credential {
+	response := sandbox.Credential{
+		ID:        credential.ID,
+		Name:      credential.Name,
+		CreatedAt: credential.CreatedAt.Unix(),
+	}
+	if !credential.LastUsedAt.IsZero() {
+		response.LastUsedAt = credential.LastUsedAt.Unix()
+	}
+	return response
+}
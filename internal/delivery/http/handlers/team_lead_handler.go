@@ -0,0 +1,167 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/LavaJover/shvark-api-gateway/internal/client"
+	"github.com/LavaJover/shvark-api-gateway/internal/delivery/http/dto/teamlead"
+	"github.com/LavaJover/shvark-api-gateway/internal/domain"
+	"github.com/LavaJover/shvark-api-gateway/internal/service"
+	"github.com/gin-gonic/gin"
+)
+
+// teamStatsDefaultPeriod период статистики команды, если from не указан
+const teamStatsDefaultPeriod = 24 * time.Hour
+
+type TeamLeadHandler struct {
+	Stats       *service.TeamStatsService
+	AuthzClient *client.AuthzClient
+	// MaxRange самый длинный период, за который можно запросить статистику
+	MaxRange time.Duration
+}
+
+func NewTeamLeadHandler(stats *service.TeamStatsService, authzClient *client.AuthzClient, maxRange time.Duration) *TeamLeadHandler {
+	return &TeamLeadHandler{
+		Stats:       stats,
+		AuthzClient: authzClient,
+		MaxRange:    maxRange,
+	}
+}
+
+// @Summary Team performance
+// @Description Team summary and per-trader breakdown for every trader in the team lead's relations: order statistics and commission profit for the period, bank detail turnover for the current day and month, device status right now. Team leads see their own team, admins any team; other users get 403. Results are cached for a short time.
+// @Tags team-lead
+// @Security BearerAuth
+// @Produce json
+// @Param team_lead_id query string false "team lead ID, defaults to the current user"
+// @Param from query string false "RFC3339 start, defaults to 24 hours before to"
+// @Param to query string false "RFC3339 end, defaults to now"
+// @Success 200 {object} teamlead.StatsResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 502 {object} ErrorResponse
+// @Router /team-lead/stats [get]
+func (h *TeamLeadHandler) GetStats(c *gin.Context) {
+	userID, ok := traderFromContext(c)
+	if !ok {
+		return
+	}
+	teamLeadID := c.DefaultQuery("team_lead_id", userID)
+	if teamLeadID != userID {
+		resp, err := h.AuthzClient.CheckPermission(userID, "team_stats", "read")
+		if err != nil {
+			c.JSON(http.StatusBadGateway, ErrorResponse{Error: "authz error"})
+			return
+		}
+		if !resp.Allowed {
+			c.JSON(http.StatusForbidden, ErrorResponse{Error: "only admins can see other teams"})
+			return
+		}
+	}
+
+	from, to, ok := h.period(c)
+	if !ok {
+		return
+	}
+
+	stats, cached, err := h.Stats.TeamStats(c.Request.Context(), teamLeadID, from, to)
+	if errors.Is(err, service.ErrNotTeamLead) {
+		if teamLeadID == userID {
+			c.JSON(http.StatusForbidden, ErrorResponse{Error: "team stats are available to team leads only"})
+			return
+		}
+		c.JSON(http.StatusNotFound, ErrorResponse{Error: err.Error()})
+		return
+	}
+	if err != nil {
+		var upstream *service.UpstreamError
+		if errors.As(err, &upstream) {
+			c.JSON(http.StatusBadGateway, ErrorResponse{Error: err.Error()})
+			return
+		}
+		log.Printf("team stats: %v", err)
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "failed to collect team stats"})
+		return
+	}
+
+	response := teamlead.StatsResponse{
+		TeamLeadID:  stats.TeamLeadID,
+		From:        stats.From,
+		To:          stats.To,
+		GeneratedAt: stats.GeneratedAt,
+		Cached:      cached,
+		Partial:     stats.Partial,
+		Summary: teamlead.Summary{
+			Traders:          stats.Summary.Traders,
+			Orders:           teamOrderStats(stats.Summary.Orders),
+			BankDetails:      teamlead.BankDetailStats(stats.Summary.BankDetails),
+			Devices:          teamlead.DeviceStats(stats.Summary.Devices),
+			CommissionProfit: stats.Summary.CommissionProfit,
+		},
+		Traders: make([]teamlead.TraderStats, len(stats.Traders)),
+	}
+	for i, trader := range stats.Traders {
+		response.Traders[i] = teamlead.TraderStats{
+			TraderID:         trader.TraderID,
+			Commission:       trader.Commission,
+			Orders:           teamOrderStats(trader.Orders),
+			BankDetails:      teamlead.BankDetailStats(trader.BankDetails),
+			Devices:          teamlead.DeviceStats(trader.Devices),
+			CommissionProfit: trader.CommissionProfit,
+			FailedSections:   trader.FailedSections,
+		}
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// period период из query, по умолчанию последние сутки. Границы округляются до минуты,
+// чтобы повторные запросы без from и to попадали в кеш.
+func (h *TeamLeadHandler) period(c *gin.Context) (time.Time, time.Time, bool) {
+	to := time.Now()
+	if value := c.Query("to"); value != "" {
+		parsed, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, ErrorResponse{Error: "invalid to format, expected RFC3339"})
+			return time.Time{}, time.Time{}, false
+		}
+		to = parsed
+	}
+	from := to.Add(-teamStatsDefaultPeriod)
+	if value := c.Query("from"); value != "" {
+		parsed, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, ErrorResponse{Error: "invalid from format, expected RFC3339"})
+			return time.Time{}, time.Time{}, false
+		}
+		from = parsed
+	}
+	from, to = from.Truncate(time.Minute), to.Truncate(time.Minute)
+
+	if !from.Before(to) {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "from must be before to"})
+		return time.Time{}, time.Time{}, false
+	}
+	if h.MaxRange > 0 && to.Sub(from) > h.MaxRange {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "period is longer than " + h.MaxRange.String()})
+		return time.Time{}, time.Time{}, false
+	}
+	return from, to, true
+}
+
+func teamOrderStats(stats domain.TeamOrderStats) teamlead.OrderStats {
+	return teamlead.OrderStats{
+		TotalOrders:           stats.TotalOrders,
+		SucceedOrders:         stats.SucceedOrders,
+		CanceledOrders:        stats.CanceledOrders,
+		SuccessRate:           stats.SuccessRate(),
+		ProcessedAmountFiat:   stats.ProcessedAmountFiat,
+		ProcessedAmountCrypto: stats.ProcessedAmountCrypto,
+		CanceledAmountFiat:    stats.CanceledAmountFiat,
+		CanceledAmountCrypto:  stats.CanceledAmountCrypto,
+		IncomeCrypto:          stats.IncomeCrypto,
+	}
+}
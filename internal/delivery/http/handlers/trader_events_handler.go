@@ -0,0 +1,149 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/LavaJover/shvark-api-gateway/internal/domain"
+	"github.com/LavaJover/shvark-api-gateway/internal/service"
+	"github.com/gin-gonic/gin"
+	"golang.org/x/net/websocket"
+)
+
+type TraderEventsHandler struct {
+	Events            service.TraderEventSource
+	heartbeatInterval time.Duration
+	allowedOrigins    map[string]struct{}
+}
+
+func NewTraderEventsHandler(events service.TraderEventSource, heartbeatInterval time.Duration, allowedOrigins []string) *TraderEventsHandler {
+	origins := make(map[string]struct{}, len(allowedOrigins))
+	for _, origin := range allowedOrigins {
+		origins[strings.ToLower(strings.TrimSuffix(origin, "/"))] = struct{}{}
+	}
+	return &TraderEventsHandler{
+		Events:            events,
+		heartbeatInterval: heartbeatInterval,
+		allowedOrigins:    origins,
+	}
+}
+
+// @Summary Trader events stream
+// @Description WebSocket push channel for trader dashboard.
+// @Description Events: order_assigned, order_expiring, dispute_opened, dispute_frozen, device_offline, antifraud_locked, traffic_locked, heartbeat.
+// @Description Pass last_event_id (or Last-Event-ID header) to receive events missed since reconnect.
+// @Description Browser connections are accepted only from the gateway host and configured allowed origins.
+// @Tags traders
+// @Security BearerAuth
+// @Param token query string false "access token, if Authorization header can not be set"
+// @Param last_event_id query int false "ID of the last received event"
+// @Success 101 {object} domain.TraderEvent
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 "origin is not allowed"
+// @Router /traders/events/ws [get]
+func (h *TraderEventsHandler) Stream(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, ErrorResponse{Error: "userID not found in context"})
+		return
+	}
+	traderID, ok := userID.(string)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, ErrorResponse{Error: "invalid trader ID"})
+		return
+	}
+
+	var lastEventID uint64
+	lastEventIDStr := c.Query("last_event_id")
+	if lastEventIDStr == "" {
+		lastEventIDStr = c.GetHeader("Last-Event-ID")
+	}
+	if lastEventIDStr != "" {
+		parsed, err := strconv.ParseUint(lastEventIDStr, 10, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, ErrorResponse{Error: "last_event_id must be a positive integer"})
+			return
+		}
+		lastEventID = parsed
+	}
+
+	server := websocket.Server{
+		// CORS на установку WebSocket не распространяется, а токен может прийти в query,
+		// поэтому чужие страницы отсекаются по Origin. При ошибке сервер отвечает 403.
+		Handshake: func(config *websocket.Config, r *http.Request) error { return h.checkOrigin(r) },
+		Handler: func(conn *websocket.Conn) {
+			h.serve(conn, traderID, lastEventID)
+		},
+	}
+	server.ServeHTTP(c.Writer, c.Request)
+}
+
+// checkOrigin пропускает запросы без Origin (их шлют не браузеры), со страниц самого шлюза
+// и из списка разрешенных
+func (h *TraderEventsHandler) checkOrigin(r *http.Request) error {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return nil
+	}
+	parsed, err := url.Parse(origin)
+	if err != nil || parsed.Host == "" {
+		return errors.New("invalid origin")
+	}
+	if strings.EqualFold(parsed.Host, r.Host) {
+		return nil
+	}
+	if _, ok := h.allowedOrigins[strings.ToLower(parsed.Scheme+"://"+parsed.Host)]; ok {
+		return nil
+	}
+	return errors.New("origin is not allowed")
+}
+
+func (h *TraderEventsHandler) serve(conn *websocket.Conn, traderID string, lastEventID uint64) {
+	defer conn.Close()
+
+	events, unsubscribe := h.Events.Subscribe(traderID, lastEventID)
+	defer unsubscribe()
+
+	// клиент ничего не присылает, чтение нужно только чтобы заметить закрытие соединения
+	closed := make(chan struct{})
+	go func() {
+		defer close(closed)
+		var message string
+		for {
+			if err := websocket.Message.Receive(conn, &message); err != nil {
+				return
+			}
+		}
+	}()
+
+	heartbeat := time.NewTicker(h.heartbeatInterval)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-closed:
+			return
+		case event, ok := <-events:
+			if !ok {
+				return
+			}
+			if err := websocket.JSON.Send(conn, event); err != nil {
+				return
+			}
+		case <-heartbeat.C:
+			ping := domain.TraderEvent{
+				Type:     domain.TraderEventHeartbeat,
+				TraderID: traderID,
+				At:       time.Now(),
+			}
+			if err := websocket.JSON.Send(conn, ping); err != nil {
+				return
+			}
+		}
+	}
+}
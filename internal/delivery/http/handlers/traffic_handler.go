@@ -5,29 +5,56 @@ import (
 	"strconv"
 
 	"github.com/LavaJover/shvark-api-gateway/internal/client"
+	"github.com/LavaJover/shvark-api-gateway/internal/service"
 	orderpb "github.com/LavaJover/shvark-order-service/proto/gen/order"
 	"github.com/gin-gonic/gin"
 )
 
 type TrafficHandler struct {
-	orderClient *client.OrderClient
+	orderClient  *client.OrderClient
+	traderEvents *service.TraderEventPublisher
+	watchdog     *service.DeviceWatchdog
 }
 
 func NewTrafficHandler(
 	orderClient *client.OrderClient,
+	traderEvents *service.TraderEventPublisher,
+	watchdog *service.DeviceWatchdog,
 ) *TrafficHandler {
 	return &TrafficHandler{
-		orderClient: orderClient,
+		orderClient:  orderClient,
+		traderEvents: traderEvents,
+		watchdog:     watchdog,
 	}
 }
 
+// OwnsTraffic принадлежит ли запись трафика из параметра trafficID трейдеру userID:
+// для ручек чтения, которые трейдер вызывает по своему трафику
+func (h *TrafficHandler) OwnsTraffic(c *gin.Context, userID string) (bool, error) {
+	response, err := h.orderClient.GetTraderTraffic(&orderpb.GetTraderTrafficRequest{
+		TraderId: userID,
+	})
+	if err != nil {
+		return false, err
+	}
+	trafficID := c.Param("trafficID")
+	for _, record := range response.Records {
+		if record.Id == trafficID {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
 // @Summary Set trader traffic lock status
-// @Description On/Off trader traffic lock status
+// @Description On/Off trader traffic lock status. On lock the trader gets a traffic_locked event with the reason.
 // @Tags traffic
 // @Accept json
 // @Produce json
+// @Security BearerAuth
 // @Param traderID path string true "trader ID"
 // @Param unlocked query bool true "is unlocked"
+// @Param reason query string false "lock reason sent to the trader"
 // @Success 200
 // @Failure 400 {object} ErrorResponse
 // @Failure 502 {object} ErrorResponse
@@ -54,6 +81,11 @@ func (h *TrafficHandler) SetTraderLockTrafficStatus(c *gin.Context) {
 		c.JSON(http.StatusBadGateway, ErrorResponse{Error: err.Error()})
 		return
 	}
+	// блокировка теперь ручная: сторож устройств не должен снимать ее сам
+	h.watchdog.ReleaseTrader(traderID)
+	if !unlocked {
+		h.traderEvents.TrafficLocked(traderID, c.Query("reason"))
+	}
 
 	c.JSON(http.StatusOK, gin.H{"message": "OK"})
 }
@@ -63,6 +95,7 @@ func (h *TrafficHandler) SetTraderLockTrafficStatus(c *gin.Context) {
 // @Tags traffic
 // @Accept json
 // @Produce json
+// @Security BearerAuth
 // @Param merchantID path string true "merchant ID"
 // @Param unlocked query bool true "is unlocked"
 // @Success 200
@@ -100,6 +133,7 @@ func (h *TrafficHandler) SetMerchantLockTrafficStatus(c *gin.Context) {
 // @Tags traffic
 // @Accept json
 // @Produce json
+// @Security BearerAuth
 // @Param trafficID path string true "traffic record ID"
 // @Param unlocked query bool true "is unlocked"
 // @Success 200
@@ -137,8 +171,10 @@ func (h *TrafficHandler) SetManuallyLockTrafficStatus(c *gin.Context) {
 // @Tags traffic
 // @Accept json
 // @Produce json
+// @Security BearerAuth
 // @Param traderID path string true "trader ID"
 // @Param unlocked query bool true "is unlocked"
+// @Param reason query string false "lock reason shown to the trader"
 // @Success 200
 // @Failure 400 {object} ErrorResponse
 // @Failure 502 {object} ErrorResponse
@@ -165,6 +201,9 @@ func (h *TrafficHandler) SetAntifraudLockTrafficStatus(c *gin.Context) {
 		c.JSON(http.StatusBadGateway, ErrorResponse{Error: err.Error()})
 		return
 	}
+	if !unlocked {
+		h.traderEvents.AntifraudLocked(traderID, c.Query("reason"))
+	}
 
 	c.JSON(http.StatusOK, gin.H{"message": "OK"})
 }
@@ -174,9 +213,11 @@ func (h *TrafficHandler) SetAntifraudLockTrafficStatus(c *gin.Context) {
 // @Tags traffic
 // @Accept json
 // @Produce json
+// @Security BearerAuth
 // @Param trafficID path string true "traffic record ID"
 // @Success 200 {object} LockStatusesResponse
 // @Failure 400 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
 // @Failure 502 {object} ErrorResponse
 // @Router /traffic/{trafficID}/lock-statuses [get]
 func (h *TrafficHandler) GetTrafficLockStatuses(c *gin.Context) {
@@ -208,9 +249,11 @@ func (h *TrafficHandler) GetTrafficLockStatuses(c *gin.Context) {
 // @Tags traffic
 // @Accept json
 // @Produce json
+// @Security BearerAuth
 // @Param trafficID path string true "traffic record ID"
 // @Success 200 {object} TrafficUnlockedResponse
 // @Failure 400 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
 // @Failure 502 {object} ErrorResponse
 // @Router /traffic/{trafficID}/unlocked [get]
 func (h *TrafficHandler) CheckTrafficUnlocked(c *gin.Context) {
@@ -280,9 +323,11 @@ type GetTraderTrafficResponse struct {
 // @Tags traffic
 // @Accept json
 // @Produce json
+// @Security BearerAuth
 // @Param traderID path string true "Trader ID"
 // @Success 200 {object} GetTraderTrafficResponse
 // @Failure 400 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
 // @Failure 502 {object} ErrorResponse
 // @Router /traffic/traders/{traderID} [get]
 func (h *TrafficHandler) GetTraderTraffic(c *gin.Context) {
@@ -0,0 +1,197 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/LavaJover/shvark-api-gateway/internal/domain"
+	"github.com/LavaJover/shvark-api-gateway/internal/service"
+	"github.com/gin-gonic/gin"
+)
+
+// auditResponseLimit сколько байт ответа хранится, чтобы достать из него текст ошибки
+const auditResponseLimit = 4096
+
+// auditResponseWriter копирует начало ответа для поля error записи аудита
+type auditResponseWriter struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (w *auditResponseWriter) Write(data []byte) (int, error) {
+	if free := auditResponseLimit - w.body.Len(); free > 0 {
+		w.body.Write(data[:min(free, len(data))])
+	}
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *auditResponseWriter) WriteString(data string) (int, error) {
+	if free := auditResponseLimit - w.body.Len(); free > 0 {
+		w.body.WriteString(data[:min(free, len(data))])
+	}
+	return w.ResponseWriter.WriteString(data)
+}
+
+// ключи контекста, через которые AuditSnapshot передает снимок до изменения в AuditMiddleware
+const (
+	auditTargetsKey = "auditTargets"
+	auditBeforeKey  = "auditBefore"
+)
+
+// AuditMiddleware пишет в журнал аудита каждый изменяющий запрос группы: кто, откуда, над чем,
+// с каким телом и чем закончилось. Ставится в группу перед AuthMiddleware, чтобы попытки
+// без прав тоже попадали в журнал: автор берется из контекста уже после обработки запроса.
+// Снимки состояния до и после снимаются, только если запрос прошел AuditSnapshot.
+// audit == nil - аудит выключен.
+func AuditMiddleware(audit *service.AuditLog, category string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if audit == nil {
+			c.Next()
+			return
+		}
+		switch c.Request.Method {
+		case http.MethodGet, http.MethodHead, http.MethodOptions:
+			c.Next()
+			return
+		}
+
+		start := time.Now()
+		body := readAuditBody(c, audit.MaxBodyBytes())
+		targets := auditTargets(c, body)
+		route := c.FullPath()
+		c.Set(auditTargetsKey, targets)
+
+		writer := &auditResponseWriter{ResponseWriter: c.Writer}
+		c.Writer = writer
+		c.Next()
+
+		var before json.RawMessage
+		if value, ok := c.Get(auditBeforeKey); ok {
+			before = value.(json.RawMessage)
+		}
+
+		entry := domain.AuditEntry{
+			At:         start,
+			ActorID:    c.GetString("userID"),
+			IP:         c.ClientIP(),
+			UserAgent:  c.Request.UserAgent(),
+			Category:   category,
+			Method:     c.Request.Method,
+			Route:      route,
+			Path:       c.Request.URL.Path,
+			TargetIDs:  targets,
+			Query:      auditQuery(c),
+			Request:    service.MaskAuditBody(body),
+			StatusCode: writer.Status(),
+			Before:     before,
+			DurationMs: time.Since(start).Milliseconds(),
+		}
+		switch status := writer.Status(); {
+		case status < http.StatusBadRequest:
+			entry.Outcome = domain.AuditSuccess
+			if before != nil {
+				entry.After = audit.Snapshot(c.Request.Method, route, targets)
+			}
+		case status == http.StatusUnauthorized || status == http.StatusForbidden:
+			entry.Outcome = domain.AuditDenied
+			entry.Error = auditResponseError(writer.body.Bytes())
+		default:
+			entry.Outcome = domain.AuditFailure
+			entry.Error = auditResponseError(writer.body.Bytes())
+		}
+		audit.Record(entry)
+	}
+}
+
+// AuditSnapshot снимает состояние цели запроса до изменения для записи AuditMiddleware.
+// Ставится после AuthMiddleware и проверки прав: запрос без прав не должен читать
+// состояние из сервисов и класть его в журнал.
+func AuditSnapshot(audit *service.AuditLog) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if audit == nil || c.GetString("userID") == "" {
+			c.Next()
+			return
+		}
+		if targets, ok := c.Get(auditTargetsKey); ok {
+			if before := audit.Snapshot(c.Request.Method, c.FullPath(), targets.(map[string]string)); before != nil {
+				c.Set(auditBeforeKey, before)
+			}
+		}
+		c.Next()
+	}
+}
+
+// readAuditBody читает не больше limit байт тела и возвращает тело обработчику целиком
+func readAuditBody(c *gin.Context, limit int) []byte {
+	if c.Request.Body == nil || limit <= 0 {
+		return nil
+	}
+	head, err := io.ReadAll(io.LimitReader(c.Request.Body, int64(limit)))
+	if err != nil {
+		return nil
+	}
+	c.Request.Body = struct {
+		io.Reader
+		io.Closer
+	}{io.MultiReader(bytes.NewReader(head), c.Request.Body), c.Request.Body}
+	return head
+}
+
+// auditTargets идентификаторы цели: параметры пути и строковые поля id и *_id тела запроса,
+// вложенные объекты - через точку, например bank_detail.id
+func auditTargets(c *gin.Context, body []byte) map[string]string {
+	targets := make(map[string]string)
+	for _, param := range c.Params {
+		targets[param.Key] = param.Value
+	}
+	var object map[string]any
+	if json.Unmarshal(body, &object) == nil {
+		collectAuditTargets(targets, "", object, 2)
+	}
+	if len(targets) == 0 {
+		return nil
+	}
+	return targets
+}
+
+func collectAuditTargets(targets map[string]string, prefix string, object map[string]any, depth int) {
+	for key, value := range object {
+		switch typed := value.(type) {
+		case string:
+			if typed != "" && (key == "id" || strings.HasSuffix(key, "_id")) {
+				targets[prefix+key] = typed
+			}
+		case map[string]any:
+			if depth > 1 {
+				collectAuditTargets(targets, prefix+key+".", typed, depth-1)
+			}
+		}
+	}
+}
+
+func auditQuery(c *gin.Context) map[string]string {
+	values := c.Request.URL.Query()
+	if len(values) == 0 {
+		return nil
+	}
+	query := make(map[string]string, len(values))
+	for key := range values {
+		query[key] = service.MaskAuditValue(key, values.Get(key))
+	}
+	return query
+}
+
+// auditResponseError текст ошибки из ответа {"error": "..."}, иначе пусто
+func auditResponseError(body []byte) string {
+	var response struct {
+		Error string `json:"error"`
+	}
+	if json.Unmarshal(body, &response) != nil {
+		return ""
+	}
+	return response.Error
+}
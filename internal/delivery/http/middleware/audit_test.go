@@ -0,0 +1,92 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/LavaJover/shvark-api-gateway/internal/domain"
+	"github.com/LavaJover/shvark-api-gateway/internal/service"
+	"github.com/gin-gonic/gin"
+)
+
+// Снимок состояния снимается только после проверки прав, а попытки без прав все равно попадают в журнал
+func TestAuditSnapshotAfterPermission(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	store, err := service.NewFileAuditStore("", 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	audit := service.NewAuditLog(store, nil, service.AuditConfig{MaxBodyBytes: 1024})
+	unlocked := false
+	snapshots := 0
+	audit.RegisterSnapshot(http.MethodPatch, "/traffic/traders/:traderID", func(map[string]string) (any, error) {
+		snapshots++
+		return map[string]bool{"unlocked": unlocked}, nil
+	})
+
+	// заглушки AuthMiddleware и RequirePermission: пользователь из заголовка, право только у admin-1
+	auth := func(c *gin.Context) {
+		userID := c.GetHeader("X-User")
+		if userID == "" {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+			return
+		}
+		c.Set("userID", userID)
+	}
+	permission := func(c *gin.Context) {
+		if c.GetString("userID") != "admin-1" {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "access denied"})
+		}
+	}
+	r := gin.New()
+	group := r.Group("/traffic", AuditMiddleware(audit, "traffic"), auth, permission, AuditSnapshot(audit))
+	group.PATCH("/traders/:traderID", func(c *gin.Context) {
+		unlocked = true
+		c.Status(http.StatusOK)
+	})
+
+	tests := []struct {
+		name          string
+		userID        string
+		wantStatus    int
+		wantOutcome   domain.AuditOutcome
+		wantSnapshots int
+	}{
+		{name: "no token", wantStatus: http.StatusUnauthorized, wantOutcome: domain.AuditDenied},
+		{name: "no permission", userID: "trader-1", wantStatus: http.StatusForbidden, wantOutcome: domain.AuditDenied},
+		{name: "admin", userID: "admin-1", wantStatus: http.StatusOK, wantOutcome: domain.AuditSuccess, wantSnapshots: 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			snapshots = 0
+			request := httptest.NewRequest(http.MethodPatch, "/traffic/traders/trader-1", nil)
+			if tt.userID != "" {
+				request.Header.Set("X-User", tt.userID)
+			}
+			recorder := httptest.NewRecorder()
+			r.ServeHTTP(recorder, request)
+
+			if recorder.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d", recorder.Code, tt.wantStatus)
+			}
+			if snapshots != tt.wantSnapshots {
+				t.Fatalf("snapshots = %d, want %d", snapshots, tt.wantSnapshots)
+			}
+			entries, _ := audit.Query(service.AuditFilter{Limit: 1})
+			if len(entries) != 1 {
+				t.Fatalf("got %d audit entries, want 1", len(entries))
+			}
+			entry := entries[0]
+			if entry.Outcome != tt.wantOutcome || entry.ActorID != tt.userID {
+				t.Fatalf("entry outcome = %s, actor = %q", entry.Outcome, entry.ActorID)
+			}
+			if tt.wantSnapshots == 0 && (entry.Before != nil || entry.After != nil) {
+				t.Fatalf("denied entry has snapshots: before = %s, after = %s", entry.Before, entry.After)
+			}
+			if tt.wantSnapshots == 2 && (string(entry.Before) != `{"unlocked":false}` || string(entry.After) != `{"unlocked":true}`) {
+				t.Fatalf("before = %s, after = %s", entry.Before, entry.After)
+			}
+		})
+	}
+}
@@ -8,7 +8,18 @@ import (
 )
 
 func RequirePermission(authzClient *client.AuthzClient, object string, action string) gin.HandlerFunc {
+	return RequirePermissionWhen(authzClient, object, action, func(c *gin.Context) bool { return true })
+}
+
+// RequirePermissionWhen проверяет право только для запросов, на которых when возвращает true:
+// для ручек, где одна ветка доступна любому пользователю, а другая только с правом
+func RequirePermissionWhen(authzClient *client.AuthzClient, object string, action string, when func(c *gin.Context) bool) gin.HandlerFunc {
 	return func(c *gin.Context) {
+		if !when(c) {
+			c.Next()
+			return
+		}
+
 		userIDAny, exists := c.Get("userID")
 		if !exists {
 			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
@@ -34,4 +45,4 @@ func RequirePermission(authzClient *client.AuthzClient, object string, action st
 
 		c.Next()
 	}
-}
\ No newline at end of file
+}
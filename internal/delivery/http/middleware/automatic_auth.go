@@ -1,38 +1,44 @@
 package middleware
 
 import (
-	"log/slog"
+	"errors"
 	"net/http"
 	"strings"
 
-	"github.com/LavaJover/shvark-api-gateway/internal/client"
+	"github.com/LavaJover/shvark-api-gateway/internal/service"
 	"github.com/gin-gonic/gin"
 )
 
-func AutomaticAuthMiddleware(ssoClient *client.SSOClient) gin.HandlerFunc {
+// AutomaticAuthMiddleware пускает на /automatic/* только сопряженные устройства.
+// Пользовательские токены трейдера не принимаются. Схема Token оставлена для совместимости
+// с уже установленными приложениями, новые присылают Device.
+func AutomaticAuthMiddleware(pairing *service.DevicePairingService) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		tokenHeader := c.GetHeader("Authorization")
-		slog.Info(tokenHeader)
 		if tokenHeader == "" {
 			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "authorization header is required"})
 			return
 		}
 
 		parts := strings.Split(tokenHeader, " ")
-		if len(parts) != 2 || parts[0] != "Token" {
+		if len(parts) != 2 || (parts[0] != "Device" && parts[0] != "Token") {
 			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "invalid token format"})
 			return
 		}
 
-		token := parts[1]
-
-		response, err := ssoClient.ValidateToken(token)
+		credential, err := pairing.Authenticate(parts[1])
+		if errors.Is(err, service.ErrDeviceCredentialRevoked) {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "device credential revoked, pair the device again"})
+			return
+		}
 		if err != nil {
-			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "invalid token"})
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "invalid device credential"})
 			return
 		}
 
-		c.Set("userID", response.UserId)
+		c.Set("userID", credential.TraderID)
+		c.Set("deviceID", credential.DeviceID)
+		c.Set("deviceCredential", credential)
 		c.Next()
 	}
-}
\ No newline at end of file
+}
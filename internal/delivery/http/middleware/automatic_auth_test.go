@@ -0,0 +1,82 @@
+package middleware
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/LavaJover/shvark-api-gateway/internal/domain"
+	"github.com/LavaJover/shvark-api-gateway/internal/service"
+	"github.com/gin-gonic/gin"
+)
+
+func saveDeviceCredential(t *testing.T, store service.DeviceCredentialStore, id, secret string, revoked bool) {
+	t.Helper()
+	sum := sha256.Sum256([]byte(secret))
+	credential := domain.DeviceCredential{
+		ID:         id,
+		DeviceID:   "device-" + id,
+		TraderID:   "trader-1",
+		SecretHash: hex.EncodeToString(sum[:]),
+		PairedAt:   time.Now(),
+	}
+	if revoked {
+		now := time.Now()
+		credential.RevokedAt = &now
+	}
+	if err := store.Save(credential); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestAutomaticAuthMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	store, err := service.NewFileDeviceCredentialStore("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	saveDeviceCredential(t, store, "active", "secret", false)
+	saveDeviceCredential(t, store, "revoked", "secret", true)
+	pairing := service.NewDevicePairingService(nil, store, time.Minute)
+
+	r := gin.New()
+	r.GET("/automatic/ping", AutomaticAuthMiddleware(pairing), func(c *gin.Context) {
+		c.String(http.StatusOK, c.GetString("userID")+"/"+c.GetString("deviceID"))
+	})
+
+	tests := []struct {
+		name          string
+		authorization string
+		wantStatus    int
+		wantBody      string
+	}{
+		{name: "device scheme", authorization: "Device dvc_active.secret", wantStatus: http.StatusOK, wantBody: "trader-1/device-active"},
+		{name: "legacy token scheme", authorization: "Token dvc_active.secret", wantStatus: http.StatusOK, wantBody: "trader-1/device-active"},
+		{name: "no header", wantStatus: http.StatusUnauthorized, wantBody: "authorization header is required"},
+		{name: "bearer user token", authorization: "Bearer dvc_active.secret", wantStatus: http.StatusUnauthorized, wantBody: "invalid token format"},
+		{name: "wrong secret", authorization: "Device dvc_active.other", wantStatus: http.StatusUnauthorized, wantBody: "invalid device credential"},
+		{name: "unknown credential", authorization: "Device dvc_unknown.secret", wantStatus: http.StatusUnauthorized, wantBody: "invalid device credential"},
+		{name: "revoked credential", authorization: "Device dvc_revoked.secret", wantStatus: http.StatusUnauthorized, wantBody: "pair the device again"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			request := httptest.NewRequest(http.MethodGet, "/automatic/ping", nil)
+			if tt.authorization != "" {
+				request.Header.Set("Authorization", tt.authorization)
+			}
+			recorder := httptest.NewRecorder()
+			r.ServeHTTP(recorder, request)
+
+			if recorder.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d (%s)", recorder.Code, tt.wantStatus, recorder.Body.String())
+			}
+			if !strings.Contains(recorder.Body.String(), tt.wantBody) {
+				t.Fatalf("body = %s, want %q", recorder.Body.String(), tt.wantBody)
+			}
+		})
+	}
+}
@@ -0,0 +1,45 @@
+package middleware
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/LavaJover/shvark-api-gateway/internal/service"
+	"github.com/gin-gonic/gin"
+)
+
+// OrderTokenMiddleware пропускает запрос по токену, выпущенному для ордера из path-параметра.
+// Токен передается в query-параметре token, чтобы его можно было использовать из браузера.
+func OrderTokenMiddleware(orderTokens *service.OrderTokenService, scope, paramName string) gin.HandlerFunc {
+	return orderTokenMiddleware(orderTokens, scope, func(c *gin.Context) string {
+		return c.Param(paramName)
+	})
+}
+
+// OrderTokenQueryMiddleware то же, что OrderTokenMiddleware, но ордер берется из query-параметра
+func OrderTokenQueryMiddleware(orderTokens *service.OrderTokenService, scope, queryName string) gin.HandlerFunc {
+	return orderTokenMiddleware(orderTokens, scope, func(c *gin.Context) string {
+		return c.Query(queryName)
+	})
+}
+
+func orderTokenMiddleware(orderTokens *service.OrderTokenService, scope string, orderID func(c *gin.Context) string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		token := c.Query("token")
+		if token == "" {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "token query param is required"})
+			return
+		}
+
+		if err := orderTokens.Validate(token, scope, orderID(c)); err != nil {
+			if errors.Is(err, service.ErrOrderTokenExpired) {
+				c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "token expired"})
+				return
+			}
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "invalid token"})
+			return
+		}
+
+		c.Next()
+	}
+}
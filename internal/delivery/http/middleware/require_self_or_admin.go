@@ -8,6 +8,19 @@ import (
 )
 
 func RequireSelfOrAdmin(authzClient *client.AuthzClient, paramName string) gin.HandlerFunc {
+	return RequireSelfOr(authzClient, paramName, "*", "*")
+}
+
+// RequireSelfOr пускает пользователя, чей ID в параметре пути paramName, остальным нужно право object/action
+func RequireSelfOr(authzClient *client.AuthzClient, paramName string, object string, action string) gin.HandlerFunc {
+	return RequireOwnerOr(authzClient, object, action, func(c *gin.Context, userID string) (bool, error) {
+		return c.Param(paramName) == userID, nil
+	})
+}
+
+// RequireOwnerOr пускает владельца ресурса запроса, остальным нужно право object/action.
+// owns решает, принадлежит ли ресурс пользователю, например по записи в order-service.
+func RequireOwnerOr(authzClient *client.AuthzClient, object string, action string, owns func(c *gin.Context, userID string) (bool, error)) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		userIDAny, exists := c.Get("userID")
 		if !exists {
@@ -21,23 +34,28 @@ func RequireSelfOrAdmin(authzClient *client.AuthzClient, paramName string) gin.H
 			return
 		}
 
-		param := c.Param(paramName)
-		if param == userID {
+		// при ошибке проверки владельца запрос еще может пройти по праву
+		owner, ownerErr := owns(c, userID)
+		if ownerErr == nil && owner {
 			c.Next()
 			return
 		}
 
-		resp, err := authzClient.CheckPermission(userID, "*", "*")
+		resp, err := authzClient.CheckPermission(userID, object, action)
 		if err != nil {
 			c.AbortWithStatusJSON(http.StatusBadGateway, gin.H{"error": "Authorization service is unavailable now"})
 			return
 		}
 
 		if !resp.Allowed {
+			if ownerErr != nil {
+				c.AbortWithStatusJSON(http.StatusBadGateway, gin.H{"error": "failed to check resource owner"})
+				return
+			}
 			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "access denied"})
 			return
 		}
 
 		c.Next()
 	}
-}
\ No newline at end of file
+}
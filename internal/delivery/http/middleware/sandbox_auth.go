@@ -0,0 +1,64 @@
+package middleware
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/LavaJover/shvark-api-gateway/internal/client"
+	"github.com/LavaJover/shvark-api-gateway/internal/service"
+	"github.com/gin-gonic/gin"
+)
+
+// MerchantAuthMiddleware как AuthMiddleware, но дополнительно принимает ключи песочницы.
+// Запрос с ключом песочницы помечается sandbox=true, ручки обслуживают его симулятором.
+// sandbox == nil - песочница выключена, ключи sbx_ отклоняются как невалидные токены.
+func MerchantAuthMiddleware(ssoClient *client.SSOClient, sandbox *service.SandboxCredentialService) gin.HandlerFunc {
+	auth := AuthMiddleware(ssoClient)
+	sandboxAuth := SandboxAuthMiddleware(sandbox)
+	return func(c *gin.Context) {
+		token := strings.TrimPrefix(c.GetHeader("Authorization"), "Bearer ")
+		if sandbox != nil && service.IsSandboxKey(token) {
+			sandboxAuth(c)
+			return
+		}
+		auth(c)
+	}
+}
+
+// SandboxAuthMiddleware пускает только с ключом песочницы. Ручки под ним работают с симулятором
+// и не должны принимать боевые токены.
+func SandboxAuthMiddleware(sandbox *service.SandboxCredentialService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		tokenHeader := c.GetHeader("Authorization")
+		if tokenHeader == "" {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "authorization header is required"})
+			return
+		}
+
+		parts := strings.Split(tokenHeader, " ")
+		if len(parts) != 2 || parts[0] != "Bearer" {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "invalid token format"})
+			return
+		}
+		if sandbox == nil {
+			c.AbortWithStatusJSON(http.StatusNotFound, gin.H{"error": "sandbox is disabled"})
+			return
+		}
+
+		credential, err := sandbox.Authenticate(parts[1])
+		if errors.Is(err, service.ErrSandboxCredentialRevoked) {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "sandbox key revoked"})
+			return
+		}
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "invalid sandbox key"})
+			return
+		}
+
+		c.Set("userID", credential.MerchantID)
+		c.Set("sandbox", true)
+		c.Set("sandboxCredential", credential)
+		c.Next()
+	}
+}
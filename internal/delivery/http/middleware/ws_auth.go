@@ -0,0 +1,38 @@
+package middleware
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/LavaJover/shvark-api-gateway/internal/client"
+	"github.com/gin-gonic/gin"
+)
+
+// WebSocketAuthMiddleware то же, что AuthMiddleware, но дополнительно принимает токен
+// из query-параметра token: браузер не умеет ставить заголовки при открытии WebSocket.
+func WebSocketAuthMiddleware(ssoClient *client.SSOClient) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		token := c.Query("token")
+		if tokenHeader := c.GetHeader("Authorization"); tokenHeader != "" {
+			parts := strings.Split(tokenHeader, " ")
+			if len(parts) != 2 || parts[0] != "Bearer" {
+				c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "invalid token format"})
+				return
+			}
+			token = parts[1]
+		}
+		if token == "" {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "authorization header or token query param is required"})
+			return
+		}
+
+		response, err := ssoClient.ValidateToken(token)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "invalid token"})
+			return
+		}
+
+		c.Set("userID", response.UserId)
+		c.Next()
+	}
+}
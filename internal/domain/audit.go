@@ -0,0 +1,50 @@
+package domain
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// AuditOutcome чем закончился аудируемый запрос
+type AuditOutcome string
+
+const (
+	AuditSuccess AuditOutcome = "success"
+	AuditDenied  AuditOutcome = "denied" // 401 или 403: действие не было выполнено
+	AuditFailure AuditOutcome = "failure"
+)
+
+// AuditEntry запись журнала аудита административного или финансового действия.
+// Query и Request - параметры и тело запроса с замаскированными секретами и персональными данными,
+// Before и After - состояние цели до и после действия, если для маршрута есть снимок.
+type AuditEntry struct {
+	ID         string            `json:"id"`
+	At         time.Time         `json:"at"`
+	ActorID    string            `json:"actor_id,omitempty"`
+	ActorRole  string            `json:"actor_role,omitempty"`
+	IP         string            `json:"ip"`
+	UserAgent  string            `json:"user_agent,omitempty"`
+	Category   string            `json:"category"`
+	Method     string            `json:"method"`
+	Route      string            `json:"route"`
+	Path       string            `json:"path"`
+	TargetIDs  map[string]string `json:"target_ids,omitempty"`
+	Query      map[string]string `json:"query,omitempty"`
+	Request    json.RawMessage   `json:"request,omitempty"`
+	StatusCode int               `json:"status_code"`
+	Outcome    AuditOutcome      `json:"outcome"`
+	Error      string            `json:"error,omitempty"`
+	Before     json.RawMessage   `json:"before,omitempty"`
+	After      json.RawMessage   `json:"after,omitempty"`
+	DurationMs int64             `json:"duration_ms"`
+}
+
+// HasTarget true, если id встречается среди идентификаторов цели
+func (e AuditEntry) HasTarget(id string) bool {
+	for _, target := range e.TargetIDs {
+		if target == id {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,36 @@
+package domain
+
+import "time"
+
+// AutomaticLockAction действие сторожа устройств над трафиком
+type AutomaticLockAction string
+
+const (
+	AutomaticLockLocked       AutomaticLockAction = "lock"
+	AutomaticLockUnlocked     AutomaticLockAction = "unlock"
+	AutomaticLockSkipped      AutomaticLockAction = "lock_skipped" // трафик уже заблокирован не сторожем
+	AutomaticLockFailed       AutomaticLockAction = "lock_failed"
+	AutomaticLockUnlockFailed AutomaticLockAction = "unlock_failed"
+	AutomaticLockForgotten    AutomaticLockAction = "forgotten" // блокировка больше не отслеживается: устройство удалено или блокировку трейдера сменили без сторожа
+)
+
+// AutomaticLockMode что блокирует сторож, когда устройство замолчало
+type AutomaticLockMode string
+
+const (
+	AutomaticLockModeTrader      AutomaticLockMode = "trader"       // весь трафик трейдера
+	AutomaticLockModeBankDetails AutomaticLockMode = "bank_details" // реквизиты, привязанные к устройству
+)
+
+// AutomaticLockAuditEntry запись журнала автоматических блокировок и разблокировок
+type AutomaticLockAuditEntry struct {
+	At              time.Time           `json:"at"`
+	Action          AutomaticLockAction `json:"action"`
+	Mode            AutomaticLockMode   `json:"mode"`
+	TraderID        string              `json:"trader_id"`
+	DeviceIDs       []string            `json:"device_ids"`
+	BankDetailIDs   []string            `json:"bank_detail_ids,omitempty"`
+	LastHeartbeatAt *time.Time          `json:"last_heartbeat_at,omitempty"`
+	Reason          string              `json:"reason,omitempty"`
+	Error           string              `json:"error,omitempty"`
+}
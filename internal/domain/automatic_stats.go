@@ -0,0 +1,60 @@
+package domain
+
+import "time"
+
+// AutomaticStatsCounters счетчики обработки уведомлений автоматикой
+type AutomaticStatsCounters struct {
+	TotalAttempts      int64
+	SuccessfulAttempts int64
+	ApprovedOrders     int64
+	NotFoundCount      int64
+	FailedCount        int64
+	// ProcessingTimeTotal сумма времени обработки в миллисекундах, среднее считается по TotalAttempts
+	ProcessingTimeTotal float64
+}
+
+func (c *AutomaticStatsCounters) Add(other AutomaticStatsCounters) {
+	c.TotalAttempts += other.TotalAttempts
+	c.SuccessfulAttempts += other.SuccessfulAttempts
+	c.ApprovedOrders += other.ApprovedOrders
+	c.NotFoundCount += other.NotFoundCount
+	c.FailedCount += other.FailedCount
+	c.ProcessingTimeTotal += other.ProcessingTimeTotal
+}
+
+func (c AutomaticStatsCounters) SuccessRate() float64 {
+	if c.TotalAttempts == 0 {
+		return 0
+	}
+	return float64(c.SuccessfulAttempts) / float64(c.TotalAttempts) * 100
+}
+
+func (c AutomaticStatsCounters) AvgProcessingTime() float64 {
+	if c.TotalAttempts == 0 {
+		return 0
+	}
+	return c.ProcessingTimeTotal / float64(c.TotalAttempts)
+}
+
+// AutomaticStatsGroup счетчики одной группы разбивки: банка, платежной системы, тимлида или устройства
+type AutomaticStatsGroup struct {
+	Key      string
+	TraderID string // только для устройств
+	AutomaticStatsCounters
+}
+
+// PlatformAutomaticStats статистика автоматики по всем трейдерам за период
+type PlatformAutomaticStats struct {
+	PeriodDays  int
+	GeneratedAt time.Time
+	Overview    AutomaticStatsCounters
+	// Разбивки считаются по логам автоматики, у каждого трейдера берется не больше лимита логов
+	Devices        []AutomaticStatsGroup
+	Banks          []AutomaticStatsGroup
+	PaymentSystems []AutomaticStatsGroup
+	TeamLeads      []AutomaticStatsGroup
+	TradersTotal   int
+	TradersFailed  []string // трейдеры, по которым не удалось получить статистику
+	// LogsTruncated у части трейдеров логов за период больше лимита, разбивки неполные
+	LogsTruncated bool
+}
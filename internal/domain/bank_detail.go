@@ -10,6 +10,8 @@ type BankDetail struct {
 	MinAmount 				float32
 	MaxAmount 				float32
 	BankName 				string
+	BankCode 				string
+	NspkCode 				string
 	PaymentSystem 			string
 	Delay					time.Duration
 	Enabled 				bool
@@ -0,0 +1,95 @@
+package domain
+
+// BulkAction что массовая операция делает с элементом
+type BulkAction string
+
+const (
+	BulkActionCreate BulkAction = "create"
+	BulkActionUpdate BulkAction = "update"
+	BulkActionLock   BulkAction = "lock"
+	BulkActionUnlock BulkAction = "unlock"
+	BulkActionSet    BulkAction = "set"
+)
+
+type BulkItemStatus string
+
+const (
+	BulkItemPlanned   BulkItemStatus = "planned" // dry-run: изменение будет применено
+	BulkItemApplied   BulkItemStatus = "applied"
+	BulkItemUnchanged BulkItemStatus = "unchanged" // текущее состояние уже совпадает с запрошенным
+	BulkItemFailed    BulkItemStatus = "failed"
+)
+
+// BulkChange изменение одного поля: From пустой, если значения еще нет
+type BulkChange struct {
+	Field string `json:"field"`
+	From  string `json:"from,omitempty"`
+	To    string `json:"to"`
+}
+
+// BulkItemResult результат по одному элементу: паре трейдер-мерчант, трейдеру команды или пользователю
+type BulkItemResult struct {
+	TraderID   string         `json:"trader_id,omitempty"`
+	MerchantID string         `json:"merchant_id,omitempty"`
+	UserID     string         `json:"user_id,omitempty"`
+	TrafficID  string         `json:"traffic_id,omitempty"`
+	Action     BulkAction     `json:"action"`
+	Status     BulkItemStatus `json:"status"`
+	Changes    []BulkChange   `json:"changes,omitempty"`
+	Error      string         `json:"error,omitempty"`
+}
+
+type BulkResult struct {
+	DryRun    bool             `json:"dry_run"`
+	Total     int              `json:"total"`
+	Planned   int              `json:"planned"`
+	Applied   int              `json:"applied"`
+	Unchanged int              `json:"unchanged"`
+	Failed    int              `json:"failed"`
+	Items     []BulkItemResult `json:"items"`
+}
+
+// NewBulkResult сводит результаты элементов в итог операции
+func NewBulkResult(dryRun bool, items []BulkItemResult) BulkResult {
+	result := BulkResult{
+		DryRun: dryRun,
+		Total:  len(items),
+		Items:  items,
+	}
+	for _, item := range items {
+		switch item.Status {
+		case BulkItemPlanned:
+			result.Planned++
+		case BulkItemApplied:
+			result.Applied++
+		case BulkItemUnchanged:
+			result.Unchanged++
+		case BulkItemFailed:
+			result.Failed++
+		}
+	}
+	return result
+}
+
+func (r *BulkItemResult) AddChange(field, from, to string) {
+	r.Changes = append(r.Changes, BulkChange{Field: field, From: from, To: to})
+}
+
+func (r *BulkItemResult) Fail(err error) {
+	r.Status = BulkItemFailed
+	r.Error = err.Error()
+}
+
+// Plan выставляет статус по собранному diff и сообщает, нужно ли применять изменения:
+// без изменений элемент unchanged, в dry-run - planned
+func (r *BulkItemResult) Plan(dryRun bool) bool {
+	switch {
+	case len(r.Changes) == 0:
+		r.Status = BulkItemUnchanged
+		return false
+	case dryRun:
+		r.Status = BulkItemPlanned
+		return false
+	}
+	return true
+}
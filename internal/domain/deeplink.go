@@ -6,6 +6,8 @@ type DeeplinkData struct {
     DeeplinkType string
     BankCode    string
     OrderID     string
+    Platform    Platform
+    Locale      string
 }
 
 type DeeplinkTemplate struct {
@@ -15,7 +17,69 @@ type DeeplinkTemplate struct {
 }
 
 type DeeplinkScheme struct {
-    Name     string
-    Template string
-    OS       string // ios, android, universal
-}
\ No newline at end of file
+    Name     string `json:"name"`
+    Template string `json:"template"` // плейсхолдеры {cardNumber}, {phoneNumber}, {amount}, {orderId}
+    OS       string `json:"os"`       // ios, android, universal
+    AndroidPackage string `json:"android_package,omitempty"` // пакет приложения для intent:// ссылок
+}
+
+// DeeplinkOptions банки и ссылки на их приложения для нативных приложений мерчантов
+type DeeplinkOptions struct {
+    OrderID       string
+    Amount        string
+    Currency      string
+    PaymentSystem string
+    Banks         []DeeplinkBankOption
+}
+
+// DeeplinkBankOption ссылки одного банка: по платформам в порядке приоритета,
+// при неудаче - следующая ссылка, затем магазин приложений или HTML-страница банка
+type DeeplinkBankOption struct {
+    BankCode     string
+    BankName     string
+    Icon         string
+    TransferType string
+    AppStoreURL  string
+    PlayStoreURL string
+    PageURL      string
+    Platforms    map[Platform][]DeeplinkLink
+}
+
+// DeeplinkRequisites полные реквизиты, отдаются по явному действию клиента
+type DeeplinkRequisites struct {
+    CardNumber  string
+    PhoneNumber string
+    Deeplinks   []DeeplinkLink
+}
+
+// DeeplinkLink готовая ссылка для платформы клиента
+type DeeplinkLink struct {
+    Name string `json:"name"`
+    URL  string `json:"url"`
+    OS   string `json:"os"`
+}
+
+const (
+    DeeplinkOSIOS       = "ios"
+    DeeplinkOSAndroid   = "android"
+    DeeplinkOSUniversal = "universal"
+)
+
+// Platform платформа клиента, определенная по User-Agent и client hints
+type Platform string
+
+const (
+    PlatformIOS     Platform = "ios"
+    PlatformAndroid Platform = "android"
+    PlatformDesktop Platform = "desktop"
+    PlatformUnknown Platform = "unknown"
+)
+
+// ParsePlatform разбирает явно переданную платформу
+func ParsePlatform(value string) (Platform, bool) {
+    switch Platform(value) {
+    case PlatformIOS, PlatformAndroid, PlatformDesktop, PlatformUnknown:
+        return Platform(value), true
+    }
+    return PlatformUnknown, false
+}
@@ -0,0 +1,35 @@
+package domain
+
+import "time"
+
+// DeeplinkEventType шаг воронки страниц диплинков
+type DeeplinkEventType string
+
+const (
+	DeeplinkEventPageViewed      DeeplinkEventType = "page_viewed"
+	DeeplinkEventBankChosen      DeeplinkEventType = "bank_chosen"
+	DeeplinkEventSchemeAttempted DeeplinkEventType = "scheme_attempted"
+	DeeplinkEventAppOpened       DeeplinkEventType = "app_opened"
+	DeeplinkEventAppTimeout      DeeplinkEventType = "app_timeout"
+)
+
+// ParseDeeplinkEventType проверяет тип события, присланного страницей
+func ParseDeeplinkEventType(value string) (DeeplinkEventType, bool) {
+	switch eventType := DeeplinkEventType(value); eventType {
+	case DeeplinkEventPageViewed, DeeplinkEventBankChosen, DeeplinkEventSchemeAttempted,
+		DeeplinkEventAppOpened, DeeplinkEventAppTimeout:
+		return eventType, true
+	}
+	return "", false
+}
+
+// DeeplinkEvent событие воронки. BankCode пустой для страницы выбора банка,
+// Scheme заполнен только у событий попытки открыть приложение и ее результата.
+type DeeplinkEvent struct {
+	Type     DeeplinkEventType
+	OrderID  string
+	BankCode string
+	Scheme   string
+	Platform Platform
+	At       time.Time
+}
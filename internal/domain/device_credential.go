@@ -0,0 +1,22 @@
+package domain
+
+import "time"
+
+// DeviceCredential учетные данные устройства автоматики, выданные при сопряжении по коду.
+// Секрет хранится только в виде хеша, устройству он отдается один раз.
+type DeviceCredential struct {
+	ID          string     `json:"id"`
+	DeviceID    string     `json:"device_id"`
+	TraderID    string     `json:"trader_id"`
+	SecretHash  string     `json:"secret_hash"`
+	DeviceModel string     `json:"device_model,omitempty"`
+	AppVersion  string     `json:"app_version,omitempty"`
+	PairedAt    time.Time  `json:"paired_at"`
+	LastSeenAt  time.Time  `json:"last_seen_at"`
+	RevokedAt   *time.Time `json:"revoked_at,omitempty"`
+}
+
+// Active учетные данные не отозваны
+func (c DeviceCredential) Active() bool {
+	return c.RevokedAt == nil
+}
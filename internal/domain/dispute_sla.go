@@ -0,0 +1,56 @@
+package domain
+
+import "time"
+
+// DisputeStage этап спора, у каждого этапа свой срок
+type DisputeStage string
+
+const (
+	// DisputeStageTraderResponse спор открыт, трейдер должен ответить: загрузить чек или выписку
+	DisputeStageTraderResponse DisputeStage = "trader_response"
+	// DisputeStageAdminReview трейдер ответил или передал решение админам по истечении срока
+	DisputeStageAdminReview DisputeStage = "admin_review"
+	// DisputeStageFrozen спор заморожен до выяснения обстоятельств
+	DisputeStageFrozen DisputeStage = "frozen"
+)
+
+// DisputeTimeoutAction что делать, если трейдер не ответил в срок
+type DisputeTimeoutAction string
+
+const (
+	DisputeTimeoutFreeze   DisputeTimeoutAction = "freeze"
+	DisputeTimeoutEscalate DisputeTimeoutAction = "escalate"
+)
+
+// DisputeSLA сроки открытого спора. Deadline - срок текущего этапа, ExpiresAt - срок спора
+// в order-service, после которого он решается автоматически (нулевой - неизвестен).
+type DisputeSLA struct {
+	DisputeID      string       `json:"dispute_id"`
+	OrderID        string       `json:"order_id"`
+	Stage          DisputeStage `json:"stage"`
+	OpenedAt       time.Time    `json:"opened_at"`
+	StageStartedAt time.Time    `json:"stage_started_at"`
+	Deadline       time.Time    `json:"deadline"`
+	ExpiresAt      time.Time    `json:"expires_at,omitempty"`
+	// Reminded напоминание о сроке текущего этапа отправлено
+	Reminded bool `json:"reminded"`
+	// Expired истечение срока текущего этапа обработано: спор заморожен или передан админам
+	Expired bool `json:"expired"`
+	// Escalated спор передан админам, потому что один из сроков истек
+	Escalated   bool      `json:"escalated"`
+	EscalatedAt time.Time `json:"escalated_at,omitempty"`
+}
+
+// Overdue истек ли срок текущего этапа
+func (s DisputeSLA) Overdue(now time.Time) bool {
+	return !s.Deadline.IsZero() && now.After(s.Deadline)
+}
+
+// DisputeUrgency срочность спора в очереди админов
+type DisputeUrgency string
+
+const (
+	DisputeUrgencyOverdue DisputeUrgency = "overdue"  // срок этапа истек
+	DisputeUrgencyDueSoon DisputeUrgency = "due_soon" // срок истекает, напоминание уже положено
+	DisputeUrgencyOnTrack DisputeUrgency = "on_track"
+)
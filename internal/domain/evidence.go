@@ -0,0 +1,19 @@
+package domain
+
+import "time"
+
+// DisputeEvidence файл-доказательство по ордеру или спору: чек, скриншот перевода, выписка.
+// Сам файл лежит в хранилище по StorageKey, скачивается только по подписанной ссылке.
+type DisputeEvidence struct {
+	ID         string `json:"id"`
+	OrderID    string `json:"order_id"`
+	DisputeID  string `json:"dispute_id,omitempty"`
+	UploadedBy string `json:"uploaded_by"`
+	FileName   string `json:"file_name"`
+	// ContentType определяется по содержимому файла, а не по заголовку клиента
+	ContentType string    `json:"content_type"`
+	Size        int64     `json:"size"`
+	SHA256      string    `json:"sha256"`
+	StorageKey  string    `json:"storage_key"`
+	CreatedAt   time.Time `json:"created_at"`
+}
@@ -0,0 +1,81 @@
+package domain
+
+import "time"
+
+// NotificationChannel канал доставки уведомлений
+type NotificationChannel string
+
+const (
+	NotificationChannelTelegram NotificationChannel = "telegram"
+	NotificationChannelEmail    NotificationChannel = "email"
+	NotificationChannelWebhook  NotificationChannel = "webhook"
+)
+
+// NotificationStatus результат доставки уведомления в канал
+type NotificationStatus string
+
+const (
+	NotificationSent       NotificationStatus = "sent"
+	NotificationFailed     NotificationStatus = "failed"
+	NotificationQuietHours NotificationStatus = "quiet_hours" // не отправлено: у получателя тихие часы
+)
+
+// NotificationEventLowBalance низкий баланс кошелька трейдера, публикует LowBalanceWatcher.
+// Остальные типы уведомлений - типы TraderEvent.
+const NotificationEventLowBalance TraderEventType = "low_balance"
+
+// NotificationTarget адрес пользователя в канале: chat_id телеграма, email или URL вебхука
+type NotificationTarget struct {
+	Channel NotificationChannel `json:"channel"`
+	Address string              `json:"address"`
+	Enabled bool                `json:"enabled"`
+}
+
+// QuietHours интервал, в который уведомления не отправляются. Start > End - интервал через полночь.
+type QuietHours struct {
+	Start    string `json:"start"` // HH:MM
+	End      string `json:"end"`   // HH:MM
+	Timezone string `json:"timezone"`
+}
+
+// NotificationPreferences подписка пользователя на уведомления
+type NotificationPreferences struct {
+	UserID  string               `json:"user_id"`
+	Targets []NotificationTarget `json:"targets"`
+	// EventTypes типы событий, на которые подписан пользователь, пустой список - все
+	EventTypes []TraderEventType `json:"event_types,omitempty"`
+	QuietHours *QuietHours       `json:"quiet_hours,omitempty"`
+	// AllTraders получать события всех трейдеров, а не только свои. Включает только админ.
+	AllTraders bool      `json:"all_traders"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
+
+// Subscribed подписан ли пользователь на тип события
+func (p NotificationPreferences) Subscribed(eventType TraderEventType) bool {
+	if len(p.EventTypes) == 0 {
+		return true
+	}
+	for _, subscribed := range p.EventTypes {
+		if subscribed == eventType {
+			return true
+		}
+	}
+	return false
+}
+
+// NotificationDelivery запись истории доставки одного уведомления в один канал
+type NotificationDelivery struct {
+	ID        string              `json:"id"`
+	UserID    string              `json:"user_id"`
+	EventType TraderEventType     `json:"event_type"`
+	EventID   uint64              `json:"event_id,omitempty"`
+	TraderID  string              `json:"trader_id,omitempty"`
+	Channel   NotificationChannel `json:"channel"`
+	Address   string              `json:"address"`
+	Subject   string              `json:"subject"`
+	Body      string              `json:"body"`
+	Status    NotificationStatus  `json:"status"`
+	Attempts  int                 `json:"attempts"`
+	Error     string              `json:"error,omitempty"`
+	CreatedAt time.Time           `json:"created_at"`
+}
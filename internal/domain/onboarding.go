@@ -0,0 +1,89 @@
+package domain
+
+import "time"
+
+// OnboardingKind что заводит онбординг: команду (трейдера) или мерчанта
+type OnboardingKind string
+
+const (
+	OnboardingTeam     OnboardingKind = "team"
+	OnboardingMerchant OnboardingKind = "merchant"
+)
+
+type OnboardingStatus string
+
+const (
+	OnboardingRunning     OnboardingStatus = "running"
+	OnboardingCompleted   OnboardingStatus = "completed"
+	OnboardingFailed      OnboardingStatus = "failed"      // шаг не прошел после повторов, можно продолжить или отменить
+	OnboardingCompensated OnboardingStatus = "compensated" // отменен, выполненные шаги откачены
+)
+
+type OnboardingStepName string
+
+const (
+	OnboardingStepRegister      OnboardingStepName = "register"
+	OnboardingStepAssignRole    OnboardingStepName = "assign_role"
+	OnboardingStepCreateWallet  OnboardingStepName = "create_wallet"
+	OnboardingStepCreateTraffic OnboardingStepName = "create_traffic"
+)
+
+type OnboardingStepStatus string
+
+const (
+	OnboardingStepPending     OnboardingStepStatus = "pending"
+	OnboardingStepDone        OnboardingStepStatus = "done"
+	OnboardingStepFailed      OnboardingStepStatus = "failed"
+	OnboardingStepCompensated OnboardingStepStatus = "compensated"
+	// шаг выполнен, но upstream не умеет его откатывать: нужна ручная очистка
+	OnboardingStepNotCompensable OnboardingStepStatus = "not_compensable"
+)
+
+type OnboardingStep struct {
+	Name        OnboardingStepName   `json:"name"`
+	Status      OnboardingStepStatus `json:"status"`
+	Attempts    int                  `json:"attempts"`
+	Error       string               `json:"error,omitempty"`
+	CompletedAt *time.Time           `json:"completed_at,omitempty"`
+}
+
+// OnboardingTraffic трафик по умолчанию, который создается последним шагом.
+// CounterpartID - мерчант для команды или трейдер для мерчанта.
+type OnboardingTraffic struct {
+	CounterpartID         string        `json:"counterpart_id"`
+	Name                  string        `json:"name"`
+	TraderRewardPercent   float64       `json:"trader_reward_percent"`
+	PlatformFee           float64       `json:"platform_fee"`
+	TraderPriority        float64       `json:"trader_priority"`
+	MerchantDealsDuration time.Duration `json:"merchant_deals_duration"`
+	AntifraudRequired     bool          `json:"antifraud_required"`
+	TrafficID             string        `json:"traffic_id,omitempty"`
+}
+
+// Onboarding сага заведения пользователя. Пароль не сохраняется: шаг регистрации
+// выполняется только в исходном запросе, продолжить можно сагу, которая его прошла.
+type Onboarding struct {
+	ID            string             `json:"id"`
+	Kind          OnboardingKind     `json:"kind"`
+	Login         string             `json:"login"`
+	Username      string             `json:"username"`
+	UserID        string             `json:"user_id,omitempty"`
+	WalletAddress string             `json:"wallet_address,omitempty"`
+	Traffic       *OnboardingTraffic `json:"traffic,omitempty"`
+	Steps         []OnboardingStep   `json:"steps"`
+	Status        OnboardingStatus   `json:"status"`
+	Error         string             `json:"error,omitempty"`
+	CreatedBy     string             `json:"created_by,omitempty"`
+	CreatedAt     time.Time          `json:"created_at"`
+	UpdatedAt     time.Time          `json:"updated_at"`
+}
+
+// Step шаг саги по имени, nil - шага в этой саге нет
+func (o *Onboarding) Step(name OnboardingStepName) *OnboardingStep {
+	for i := range o.Steps {
+		if o.Steps[i].Name == name {
+			return &o.Steps[i]
+		}
+	}
+	return nil
+}
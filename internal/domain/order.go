@@ -13,17 +13,34 @@ const (
 )
 
 type Order struct {
-	ID 			  string
-	MerchantID 	  string
-	AmountFiat 	  float64
-	AmountCrypto  float64
-	Currency 	  string
-	Country 	  string
-	ClientEmail   string
-	MetadataJSON  string
-	Status 		  OrderStatus
-	PaymentSystem string
-	BankDetailsID string
-	BankDetail    *BankDetail
-	ExpiresAt	  time.Time
-}
\ No newline at end of file
+	ID 			    string
+	MerchantID 	    string
+	MerchantOrderID string
+	AmountFiat 	    float64
+	AmountCrypto    float64
+	CryptoRubRate   float64
+	PlatformFee     float64
+	Recalculated    bool
+	Currency 	    string
+	Country 	    string
+	ClientEmail     string
+	MetadataJSON    string
+	CallbackURL     string
+	Status 		    OrderStatus
+	PaymentSystem   string
+	BankDetailsID   string
+	BankDetail      *BankDetail
+	ExpiresAt	    time.Time
+}
+
+// IsTerminal сообщает, что ордер завершен: оплачен или отменен. Отмененный ордер еще может
+// перейти в спор, поэтому для подписок на изменения используется IsFinal.
+func (s OrderStatus) IsTerminal() bool {
+	return s == StatusSucceed || s == StatusCanceled
+}
+
+// IsFinal сообщает, что статус ордера больше не изменится. Спор открывается только по
+// неоплаченному или отмененному ордеру, поэтому окончательным считается лишь успешный.
+func (s OrderStatus) IsFinal() bool {
+	return s == StatusSucceed
+}
@@ -0,0 +1,25 @@
+package domain
+
+import "time"
+
+type OrderEventType string
+
+const (
+	OrderEventStatus       OrderEventType = "status"
+	OrderEventRecalculated OrderEventType = "recalculated"
+	OrderEventExpiry       OrderEventType = "expiry"
+)
+
+// OrderEvent изменение состояния ордера, которое отдается подписчикам
+type OrderEvent struct {
+	Type             OrderEventType `json:"type"`
+	OrderID          string         `json:"order_id"`
+	Status           OrderStatus    `json:"status"`
+	AmountFiat       float64        `json:"amount_fiat"`
+	AmountCrypto     float64        `json:"amount_crypto"`
+	CryptoRubRate    float64        `json:"crypto_rub_rate"`
+	Recalculated     bool           `json:"recalculated"`
+	ExpiresAt        int64          `json:"expires_at"`
+	RemainingSeconds int64          `json:"remaining_seconds"`
+	At               time.Time      `json:"at"`
+}
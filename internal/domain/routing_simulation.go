@@ -0,0 +1,139 @@
+package domain
+
+import "time"
+
+// RoutingExclusion причина, по которой трейдер или его реквизит не получит ордер
+type RoutingExclusion string
+
+// Причины уровня трафика трейдера
+const (
+	RoutingExclusionTrafficDisabled RoutingExclusion = "traffic_disabled"
+	RoutingExclusionMerchantLocked  RoutingExclusion = "merchant_locked"
+	RoutingExclusionTraderLocked    RoutingExclusion = "trader_locked"
+	RoutingExclusionAntifraudLocked RoutingExclusion = "antifraud_locked"
+	// RoutingExclusionTrafficLocked трафик заблокирован, но ни один из флагов блокировок этого не объясняет
+	RoutingExclusionTrafficLocked         RoutingExclusion = "traffic_locked"
+	RoutingExclusionAntifraudFailed       RoutingExclusion = "antifraud_failed"
+	RoutingExclusionNoBankDetails         RoutingExclusion = "no_bank_details"
+	RoutingExclusionNoEligibleBankDetails RoutingExclusion = "no_eligible_bank_details"
+)
+
+// Причины уровня реквизита
+const (
+	RoutingExclusionBankDetailDisabled RoutingExclusion = "bank_detail_disabled"
+	RoutingExclusionAmountBelowMin     RoutingExclusion = "amount_below_min"
+	RoutingExclusionAmountAboveMax     RoutingExclusion = "amount_above_max"
+	RoutingExclusionDayAmountLimit     RoutingExclusion = "day_amount_limit"
+	RoutingExclusionMonthAmountLimit   RoutingExclusion = "month_amount_limit"
+	RoutingExclusionDayQuantityLimit   RoutingExclusion = "day_quantity_limit"
+	RoutingExclusionMonthQuantityLimit RoutingExclusion = "month_quantity_limit"
+	RoutingExclusionDeviceNotFound     RoutingExclusion = "device_not_found"
+	RoutingExclusionDeviceDisabled     RoutingExclusion = "device_disabled"
+	RoutingExclusionDeviceOffline      RoutingExclusion = "device_offline"
+)
+
+// Проверки, которые могли не выполниться из-за ошибки upstream
+const (
+	RoutingCheckLockStatuses    = "lock_statuses"
+	RoutingCheckAntifraud       = "antifraud"
+	RoutingCheckBankDetails     = "bank_details"
+	RoutingCheckBankDetailStats = "bank_detail_stats"
+	RoutingCheckDevices         = "devices"
+)
+
+// RoutingVerdict итог по трейдеру
+type RoutingVerdict string
+
+const (
+	RoutingVerdictEligible RoutingVerdict = "eligible"
+	RoutingVerdictExcluded RoutingVerdict = "excluded"
+	// RoutingVerdictUnknown причин исключения не найдено, но часть проверок не выполнилась
+	RoutingVerdictUnknown RoutingVerdict = "unknown"
+)
+
+// RoutingLocks флаги разблокировки трафика. Unlocked - итог order-service с учетом ручной разблокировки.
+type RoutingLocks struct {
+	MerchantUnlocked  bool
+	TraderUnlocked    bool
+	AntifraudUnlocked bool
+	ManuallyUnlocked  bool
+	Unlocked          bool
+}
+
+// RoutingBankDetail реквизит трейдера, подходящий по платежной системе и банку, и его текущие лимиты.
+// Счетчики оборота - за текущие сутки и месяц; нулевой лимит означает отсутствие ограничения.
+type RoutingBankDetail struct {
+	BankDetailID     string
+	BankCode         string
+	PaymentSystem    string
+	DeviceID         string
+	Enabled          bool
+	MinAmount        float64
+	MaxAmount        float64
+	MaxAmountDay     float64
+	MaxAmountMonth   float64
+	MaxQuantityDay   int64
+	MaxQuantityMonth int64
+	AmountToday      float64
+	AmountMonth      float64
+	CountToday       int64
+	CountMonth       int64
+	Reasons          []RoutingExclusion
+}
+
+func (b RoutingBankDetail) Eligible() bool {
+	return len(b.Reasons) == 0
+}
+
+// RoutingTrader разбор одного трейдера с трафиком на мерчанта
+type RoutingTrader struct {
+	TraderID            string
+	TrafficID           string
+	TraderPriority      float64
+	TraderRewardPercent float64
+	AntifraudRequired   bool
+	Locks               RoutingLocks
+	// FailedRules правила антифрода, которые трейдер не прошел
+	FailedRules []string
+	BankDetails []RoutingBankDetail
+	Reasons     []RoutingExclusion
+	// FailedChecks проверки, которые не удалось выполнить: без них вердикт не окончательный
+	FailedChecks []string
+}
+
+func (t RoutingTrader) Verdict() RoutingVerdict {
+	switch {
+	case len(t.Reasons) > 0:
+		return RoutingVerdictExcluded
+	case len(t.FailedChecks) > 0:
+		return RoutingVerdictUnknown
+	default:
+		return RoutingVerdictEligible
+	}
+}
+
+// EligibleBankDetails сколько реквизитов трейдера могут принять ордер
+func (t RoutingTrader) EligibleBankDetails() int {
+	count := 0
+	for _, bankDetail := range t.BankDetails {
+		if bankDetail.Eligible() {
+			count++
+		}
+	}
+	return count
+}
+
+// RoutingSimulation результат симуляции подбора реквизитов для ордера мерчанта
+type RoutingSimulation struct {
+	MerchantID    string
+	AmountFiat    float64
+	PaymentSystem string
+	BankCode      string
+	SimulatedAt   time.Time
+	Traders       []RoutingTrader
+	Eligible      int
+	Excluded      int
+	Unknown       int
+	// Exclusions сколько трейдеров исключила каждая причина
+	Exclusions map[RoutingExclusion]int
+}
@@ -0,0 +1,28 @@
+package domain
+
+import "time"
+
+// SandboxCredential ключ песочницы мерчанта. Запросы с ним обслуживает симулятор ордеров,
+// боевые сервисы не вызываются. Секрет хранится только в виде хеша, мерчанту он отдается один раз.
+type SandboxCredential struct {
+	ID         string     `json:"id"`
+	MerchantID string     `json:"merchant_id"`
+	Name       string     `json:"name,omitempty"`
+	SecretHash string     `json:"secret_hash"`
+	CreatedAt  time.Time  `json:"created_at"`
+	LastUsedAt time.Time  `json:"last_used_at"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty"`
+}
+
+// Active ключ не отозван
+func (c SandboxCredential) Active() bool {
+	return c.RevokedAt == nil
+}
+
+// SandboxWebhook результат отправки вебхука о смене статуса ордера песочницы
+type SandboxWebhook struct {
+	URL        string
+	StatusCode int
+	Error      string
+	SentAt     time.Time
+}
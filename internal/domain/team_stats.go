@@ -0,0 +1,108 @@
+package domain
+
+import "time"
+
+// Разделы статистики трейдера, которые могли не загрузиться
+const (
+	TeamStatsSectionOrders           = "orders"
+	TeamStatsSectionBankDetails      = "bank_details"
+	TeamStatsSectionDevices          = "devices"
+	TeamStatsSectionCommissionProfit = "commission_profit"
+)
+
+// TeamOrderStats статистика ордеров за период
+type TeamOrderStats struct {
+	TotalOrders           int64
+	SucceedOrders         int64
+	CanceledOrders        int64
+	ProcessedAmountFiat   float64
+	ProcessedAmountCrypto float64
+	CanceledAmountFiat    float64
+	CanceledAmountCrypto  float64
+	IncomeCrypto          float64
+}
+
+func (s *TeamOrderStats) Add(other TeamOrderStats) {
+	s.TotalOrders += other.TotalOrders
+	s.SucceedOrders += other.SucceedOrders
+	s.CanceledOrders += other.CanceledOrders
+	s.ProcessedAmountFiat += other.ProcessedAmountFiat
+	s.ProcessedAmountCrypto += other.ProcessedAmountCrypto
+	s.CanceledAmountFiat += other.CanceledAmountFiat
+	s.CanceledAmountCrypto += other.CanceledAmountCrypto
+	s.IncomeCrypto += other.IncomeCrypto
+}
+
+// SuccessRate доля успешных ордеров в процентах
+func (s TeamOrderStats) SuccessRate() float64 {
+	if s.TotalOrders == 0 {
+		return 0
+	}
+	return float64(s.SucceedOrders) / float64(s.TotalOrders) * 100
+}
+
+// TeamBankDetailStats оборот реквизитов. Счетчики order-service - за текущие сутки и месяц, не за период запроса.
+type TeamBankDetailStats struct {
+	BankDetails int
+	CountToday  int64
+	CountMonth  int64
+	AmountToday float64
+	AmountMonth float64
+}
+
+func (s *TeamBankDetailStats) Add(other TeamBankDetailStats) {
+	s.BankDetails += other.BankDetails
+	s.CountToday += other.CountToday
+	s.CountMonth += other.CountMonth
+	s.AmountToday += other.AmountToday
+	s.AmountMonth += other.AmountMonth
+}
+
+// TeamDeviceStats устройства автоматики на момент запроса
+type TeamDeviceStats struct {
+	Total   int
+	Online  int
+	Enabled int
+	// Offline включенные устройства, которые не на связи
+	Offline int
+}
+
+func (s *TeamDeviceStats) Add(other TeamDeviceStats) {
+	s.Total += other.Total
+	s.Online += other.Online
+	s.Enabled += other.Enabled
+	s.Offline += other.Offline
+}
+
+// TeamTraderStats статистика одного трейдера команды. Commission - ставка тимлида из связи,
+// FailedSections - разделы, которые не удалось получить: они не входят в сводку команды.
+type TeamTraderStats struct {
+	TraderID         string
+	Commission       float64
+	Orders           TeamOrderStats
+	BankDetails      TeamBankDetailStats
+	Devices          TeamDeviceStats
+	CommissionProfit float64
+	FailedSections   []string
+}
+
+// TeamStatsSummary сводка по всей команде
+type TeamStatsSummary struct {
+	Traders          int
+	Orders           TeamOrderStats
+	BankDetails      TeamBankDetailStats
+	Devices          TeamDeviceStats
+	CommissionProfit float64
+}
+
+// TeamStats статистика команды тимлида за период
+type TeamStats struct {
+	TeamLeadID  string
+	From        time.Time
+	To          time.Time
+	GeneratedAt time.Time
+	Summary     TeamStatsSummary
+	Traders     []TeamTraderStats
+	// Partial у части трейдеров не загрузились отдельные разделы, сводка неполная
+	Partial bool
+}
@@ -0,0 +1,32 @@
+package domain
+
+import "time"
+
+type TraderEventType string
+
+const (
+	TraderEventOrderAssigned    TraderEventType = "order_assigned"
+	TraderEventOrderExpiring    TraderEventType = "order_expiring"
+	TraderEventDisputeOpened    TraderEventType = "dispute_opened"
+	TraderEventDisputeFrozen    TraderEventType = "dispute_frozen"
+	TraderEventDisputeReminder  TraderEventType = "dispute_deadline_reminder"
+	TraderEventDisputeEscalated TraderEventType = "dispute_escalated"
+	TraderEventDeviceOffline    TraderEventType = "device_offline"
+	TraderEventAntifraudLocked  TraderEventType = "antifraud_locked"
+	TraderEventTrafficLocked    TraderEventType = "traffic_locked"
+	TraderEventDeviceLocked     TraderEventType = "device_traffic_locked"
+	TraderEventDeviceRestored   TraderEventType = "device_traffic_restored"
+	TraderEventHeartbeat        TraderEventType = "heartbeat"
+)
+
+// TraderEvent событие, которое отправляется трейдеру в реальном времени
+type TraderEvent struct {
+	ID        uint64            `json:"id,omitempty"`
+	Type      TraderEventType   `json:"type"`
+	TraderID  string            `json:"trader_id,omitempty"`
+	OrderID   string            `json:"order_id,omitempty"`
+	DisputeID string            `json:"dispute_id,omitempty"`
+	DeviceID  string            `json:"device_id,omitempty"`
+	Data      map[string]string `json:"data,omitempty"`
+	At        time.Time         `json:"at"`
+}
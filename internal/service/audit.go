@@ -0,0 +1,143 @@
+package service
+
+import (
+	"encoding/json"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/LavaJover/shvark-api-gateway/internal/client"
+	"github.com/LavaJover/shvark-api-gateway/internal/domain"
+)
+
+// AuditRoleResolver роль пользователя для записи журнала аудита
+type AuditRoleResolver interface {
+	UserRole(userID string) (string, error)
+}
+
+// AuditSnapshot снимок состояния цели действия по ее идентификаторам (параметры пути и *_id
+// из тела запроса). nil без ошибки - цели нет, например правила вывода еще не заданы.
+type AuditSnapshot func(targets map[string]string) (any, error)
+
+// AuditConfig MaxBodyBytes - сколько байт тела запроса читается для журнала, RoleCacheTTL -
+// сколько помнить роль пользователя, чтобы не ходить в user-service на каждый запрос
+type AuditConfig struct {
+	MaxBodyBytes int
+	RoleCacheTTL time.Duration
+}
+
+type auditRole struct {
+	role      string
+	expiresAt time.Time
+}
+
+// AuditLog журнал административных и финансовых действий. Запись не должна ломать
+// само действие: ошибки хранилища и снимков только логируются.
+type AuditLog struct {
+	store  AuditStore
+	roles  AuditRoleResolver
+	config AuditConfig
+
+	snapshots map[string]AuditSnapshot
+
+	mu        sync.Mutex
+	roleCache map[string]auditRole
+}
+
+func NewAuditLog(store AuditStore, roles AuditRoleResolver, config AuditConfig) *AuditLog {
+	return &AuditLog{
+		store:     store,
+		roles:     roles,
+		config:    config,
+		snapshots: make(map[string]AuditSnapshot),
+		roleCache: make(map[string]auditRole),
+	}
+}
+
+// RegisterSnapshot снимок состояния для маршрута gin, например PATCH /api/v1/traffic/traders/:traderID.
+// Регистрировать до запуска сервера: карта снимков читается без блокировки. Снимок снимается,
+// только если группа маршрута ставит middleware.AuditSnapshot после проверки прав.
+func (a *AuditLog) RegisterSnapshot(method, route string, snapshot AuditSnapshot) {
+	a.snapshots[method+" "+route] = snapshot
+}
+
+func (a *AuditLog) MaxBodyBytes() int {
+	return a.config.MaxBodyBytes
+}
+
+// Snapshot снимок цели для маршрута, nil - снимка для маршрута нет.
+// Ошибка снимка попадает в сам снимок, чтобы было видно, почему состояния нет.
+func (a *AuditLog) Snapshot(method, route string, targets map[string]string) json.RawMessage {
+	snapshot, ok := a.snapshots[method+" "+route]
+	if !ok {
+		return nil
+	}
+	value, err := snapshot(targets)
+	if err != nil {
+		value = map[string]string{"error": err.Error()}
+	}
+	raw, err := json.Marshal(value)
+	if err != nil {
+		log.Printf("audit: failed to marshal snapshot of %s %s: %v", method, route, err)
+		return nil
+	}
+	return raw
+}
+
+// Record дописывает запись в журнал, проставляя идентификатор и роль автора
+func (a *AuditLog) Record(entry domain.AuditEntry) {
+	id, err := randomHex(8)
+	if err != nil {
+		log.Printf("audit: failed to generate entry id: %v", err)
+	}
+	entry.ID = id
+	if entry.ActorID != "" && entry.ActorRole == "" {
+		entry.ActorRole = a.actorRole(entry.ActorID)
+	}
+	if err := a.store.Append(entry); err != nil {
+		log.Printf("audit: failed to append %s %s by %q: %v", entry.Method, entry.Path, entry.ActorID, err)
+	}
+}
+
+func (a *AuditLog) Query(filter AuditFilter) ([]domain.AuditEntry, int) {
+	return a.store.Query(filter)
+}
+
+func (a *AuditLog) actorRole(userID string) string {
+	if a.roles == nil {
+		return ""
+	}
+	a.mu.Lock()
+	cached, ok := a.roleCache[userID]
+	a.mu.Unlock()
+	if ok && time.Now().Before(cached.expiresAt) {
+		return cached.role
+	}
+
+	role, err := a.roles.UserRole(userID)
+	if err != nil {
+		log.Printf("audit: failed to resolve role of %s: %v", userID, err)
+		return ""
+	}
+	a.mu.Lock()
+	a.roleCache[userID] = auditRole{role: role, expiresAt: time.Now().Add(a.config.RoleCacheTTL)}
+	a.mu.Unlock()
+	return role
+}
+
+// UserServiceRoles роль пользователя из user-service
+type UserServiceRoles struct {
+	userClient *client.UserClient
+}
+
+func NewUserServiceRoles(userClient *client.UserClient) *UserServiceRoles {
+	return &UserServiceRoles{userClient: userClient}
+}
+
+func (r *UserServiceRoles) UserRole(userID string) (string, error) {
+	response, err := r.userClient.GetUserByID(userID)
+	if err != nil {
+		return "", upstreamError(UpstreamUser, err)
+	}
+	return response.Role, nil
+}
@@ -0,0 +1,79 @@
+package service
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+const auditMask = "***"
+
+// auditSecretKeys подстроки имен полей, значения которых в журнал аудита не попадают
+var auditSecretKeys = []string{"password", "secret", "token", "otp", "2fa", "cvv", "pin_code", "private_key", "api_key", "authorization"}
+
+// MaskAuditBody маскирует тело запроса для журнала аудита: секреты заменяются на ***,
+// номера карт и телефоны маскируются как на платежной странице. Тело не в JSON в журнал
+// не попадает, вместо него записывается только его размер.
+func MaskAuditBody(body []byte) json.RawMessage {
+	if len(body) == 0 {
+		return nil
+	}
+	var value any
+	if err := json.Unmarshal(body, &value); err != nil {
+		masked, _ := json.Marshal(fmt.Sprintf("not json, %d bytes", len(body)))
+		return masked
+	}
+	masked, err := json.Marshal(maskAuditValue("", value))
+	if err != nil {
+		return nil
+	}
+	return masked
+}
+
+// MaskAuditValue маскирует одно значение по имени поля, например параметр запроса
+func MaskAuditValue(key, value string) string {
+	masked, _ := maskAuditValue(key, value).(string)
+	return masked
+}
+
+func maskAuditValue(key string, value any) any {
+	switch typed := value.(type) {
+	case map[string]any:
+		for k, v := range typed {
+			typed[k] = maskAuditValue(k, v)
+		}
+		return typed
+	case []any:
+		for i, v := range typed {
+			typed[i] = maskAuditValue(key, v)
+		}
+		return typed
+	case string:
+		key = strings.ToLower(key)
+		switch {
+		case isAuditSecretKey(key):
+			return auditMask
+		case strings.Contains(key, "card"):
+			return MaskCardNumber(typed)
+		case strings.Contains(key, "phone"):
+			return MaskPhone(typed)
+		}
+		return typed
+	case nil:
+		return nil
+	default:
+		if isAuditSecretKey(strings.ToLower(key)) {
+			return auditMask
+		}
+		return typed
+	}
+}
+
+func isAuditSecretKey(key string) bool {
+	for _, secret := range auditSecretKeys {
+		if strings.Contains(key, secret) {
+			return true
+		}
+	}
+	return false
+}
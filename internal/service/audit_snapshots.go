@@ -0,0 +1,82 @@
+package service
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/LavaJover/shvark-api-gateway/internal/client"
+	orderpb "github.com/LavaJover/shvark-order-service/proto/gen/order"
+)
+
+// auditTrafficState блокировки одной записи трафика в снимке аудита
+type auditTrafficState struct {
+	TrafficID         string `json:"traffic_id"`
+	MerchantID        string `json:"merchant_id,omitempty"`
+	Enabled           bool   `json:"enabled"`
+	MerchantUnlocked  bool   `json:"merchant_unlocked"`
+	TraderUnlocked    bool   `json:"trader_unlocked"`
+	AntifraudUnlocked bool   `json:"antifraud_unlocked"`
+	ManuallyUnlocked  bool   `json:"manually_unlocked"`
+}
+
+// RegisterAuditSnapshots снимки до и после для действий, состояние которых можно прочитать:
+// блокировки трафика и правила вывода. Реквизиты не снимаются, в них полные номера карт.
+func RegisterAuditSnapshots(audit *AuditLog, orderClient *client.OrderClient, walletClient *client.HTTPWalletClient) {
+	traderTraffic := func(targets map[string]string) (any, error) {
+		response, err := orderClient.GetTraderTraffic(&orderpb.GetTraderTrafficRequest{
+			TraderId: targets["traderID"],
+		})
+		if err != nil {
+			return nil, upstreamError(UpstreamOrder, err)
+		}
+		states := make([]auditTrafficState, len(response.Records))
+		for i, record := range response.Records {
+			states[i] = auditTrafficState{
+				TrafficID:         record.Id,
+				MerchantID:        record.MerchantId,
+				Enabled:           record.Enabled,
+				MerchantUnlocked:  record.GetActivityParams().GetMerchantUnlocked(),
+				TraderUnlocked:    record.GetActivityParams().GetTraderUnlocked(),
+				AntifraudUnlocked: record.GetActivityParams().GetAntifraudUnlocked(),
+				ManuallyUnlocked:  record.GetActivityParams().GetManuallyUnlocked(),
+			}
+		}
+		return states, nil
+	}
+	audit.RegisterSnapshot(http.MethodPatch, "/api/v1/traffic/traders/:traderID", traderTraffic)
+	audit.RegisterSnapshot(http.MethodPatch, "/api/v1/traffic/antifraud/:traderID", traderTraffic)
+	audit.RegisterSnapshot(http.MethodPost, "/api/v1/antifraud/traders/:traderID/manual-unlock", traderTraffic)
+
+	audit.RegisterSnapshot(http.MethodPatch, "/api/v1/traffic/:trafficID/manual", func(targets map[string]string) (any, error) {
+		response, err := orderClient.GetTrafficLockStatuses(&orderpb.GetTrafficLockStatusesRequest{
+			TrafficId: targets["trafficID"],
+		})
+		if err != nil {
+			return nil, upstreamError(UpstreamOrder, err)
+		}
+		return auditTrafficState{
+			TrafficID:         response.TrafficId,
+			MerchantUnlocked:  response.MerchantUnlocked,
+			TraderUnlocked:    response.TraderUnlocked,
+			AntifraudUnlocked: response.AntifraudUnlocked,
+			ManuallyUnlocked:  response.ManuallyUnlocked,
+		}, nil
+	})
+
+	withdrawalRules := func(userID string) (any, error) {
+		rule, err := walletClient.GetWithdrawalRules(userID)
+		if errors.Is(err, client.ErrWithdrawalRuleNotFound) {
+			return nil, nil
+		}
+		if err != nil {
+			return nil, upstreamError(UpstreamWallet, err)
+		}
+		return rule, nil
+	}
+	audit.RegisterSnapshot(http.MethodPost, "/api/v1/admin/wallets/withdraw/rules", func(targets map[string]string) (any, error) {
+		return withdrawalRules(targets["user_id"])
+	})
+	audit.RegisterSnapshot(http.MethodDelete, "/api/v1/admin/wallets/withdraw/rules/:userId", func(targets map[string]string) (any, error) {
+		return withdrawalRules(targets["userId"])
+	})
+}
@@ -0,0 +1,110 @@
+package service
+
+import (
+	"log"
+	"sync"
+	"time"
+
+	"github.com/LavaJover/shvark-api-gateway/internal/domain"
+	"github.com/LavaJover/shvark-api-gateway/internal/storage"
+)
+
+// AuditStore хранилище журнала аудита. Только дописывание: записи не меняются и не удаляются
+// через API, другие реализации (база, внешний сборщик логов) подключаются через этот интерфейс.
+type AuditStore interface {
+	Append(entry domain.AuditEntry) error
+	// Query возвращает записи от новых к старым и общее число подходящих под фильтр
+	Query(filter AuditFilter) ([]domain.AuditEntry, int)
+}
+
+// AuditFilter условия выборки журнала, пустые поля не ограничивают выборку
+type AuditFilter struct {
+	ActorID  string
+	Category string
+	Route    string
+	TargetID string
+	Outcome  domain.AuditOutcome
+	From     time.Time
+	To       time.Time
+	Offset   int
+	Limit    int
+}
+
+func (f AuditFilter) match(entry domain.AuditEntry) bool {
+	switch {
+	case f.ActorID != "" && entry.ActorID != f.ActorID,
+		f.Category != "" && entry.Category != f.Category,
+		f.Route != "" && entry.Route != f.Route,
+		f.TargetID != "" && !entry.HasTarget(f.TargetID),
+		f.Outcome != "" && entry.Outcome != f.Outcome,
+		!f.From.IsZero() && entry.At.Before(f.From),
+		!f.To.IsZero() && !entry.At.Before(f.To):
+		return false
+	}
+	return true
+}
+
+// FileAuditStore дописывает журнал в JSON Lines файл и держит последние maxEntries записей
+// в памяти для выборок, пустой путь - только память. Файл не обрезается: старые записи
+// остаются в нем, даже когда вытеснены из памяти.
+type FileAuditStore struct {
+	path       string
+	maxEntries int
+
+	mu      sync.RWMutex
+	entries []domain.AuditEntry
+}
+
+func NewFileAuditStore(path string, maxEntries int) (*FileAuditStore, error) {
+	store := &FileAuditStore{path: path, maxEntries: maxEntries}
+	if path == "" {
+		log.Printf("audit file is not configured: audit log will not survive restart")
+		return store, nil
+	}
+
+	err := storage.ReadJSONLines(path, func(entry domain.AuditEntry) {
+		store.entries = append(store.entries, entry)
+	})
+	store.trim()
+	return store, err
+}
+
+func (s *FileAuditStore) Append(entry domain.AuditEntry) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.entries = append(s.entries, entry)
+	s.trim()
+	if s.path == "" {
+		return nil
+	}
+
+	return storage.AppendJSONLine(s.path, entry)
+}
+
+func (s *FileAuditStore) Query(filter AuditFilter) ([]domain.AuditEntry, int) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var (
+		result []domain.AuditEntry
+		total  int
+	)
+	for i := len(s.entries) - 1; i >= 0; i-- {
+		if !filter.match(s.entries[i]) {
+			continue
+		}
+		total++
+		if total <= filter.Offset || (filter.Limit > 0 && len(result) >= filter.Limit) {
+			continue
+		}
+		result = append(result, s.entries[i])
+	}
+	return result, total
+}
+
+func (s *FileAuditStore) trim() {
+	if s.maxEntries > 0 && len(s.entries) > s.maxEntries {
+		s.entries = append([]domain.AuditEntry(nil), s.entries[len(s.entries)-s.maxEntries:]...)
+	}
+}
@@ -0,0 +1,99 @@
+package service
+
+import (
+	"log"
+	"sync"
+
+	"github.com/LavaJover/shvark-api-gateway/internal/domain"
+	"github.com/LavaJover/shvark-api-gateway/internal/storage"
+)
+
+// AutomaticLockAudit журнал автоматических блокировок трафика. Он же источник состояния сторожа
+// после перезапуска: блокировка без последующей разблокировки считается действующей.
+type AutomaticLockAudit interface {
+	Append(entry domain.AutomaticLockAuditEntry) error
+	// List возвращает записи от новых к старым, пустой фильтр не ограничивает выборку
+	List(filter AutomaticLockAuditFilter) []domain.AutomaticLockAuditEntry
+	// All возвращает все записи в порядке добавления
+	All() []domain.AutomaticLockAuditEntry
+}
+
+// AutomaticLockAuditFilter условия выборки журнала
+type AutomaticLockAuditFilter struct {
+	TraderID string
+	DeviceID string
+	Limit    int
+}
+
+// FileAutomaticLockAudit дописывает журнал в JSON Lines файл и держит его копию в памяти,
+// пустой путь - только память
+type FileAutomaticLockAudit struct {
+	path string
+
+	mu      sync.RWMutex
+	entries []domain.AutomaticLockAuditEntry
+}
+
+func NewFileAutomaticLockAudit(path string) (*FileAutomaticLockAudit, error) {
+	audit := &FileAutomaticLockAudit{path: path}
+	if path == "" {
+		log.Printf("automatic lock audit file is not configured: audit and watchdog locks will not survive restart")
+		return audit, nil
+	}
+
+	err := storage.ReadJSONLines(path, func(entry domain.AutomaticLockAuditEntry) {
+		audit.entries = append(audit.entries, entry)
+	})
+	return audit, err
+}
+
+func (a *FileAutomaticLockAudit) Append(entry domain.AutomaticLockAuditEntry) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.entries = append(a.entries, entry)
+	if a.path == "" {
+		return nil
+	}
+
+	return storage.AppendJSONLine(a.path, entry)
+}
+
+func (a *FileAutomaticLockAudit) List(filter AutomaticLockAuditFilter) []domain.AutomaticLockAuditEntry {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+
+	var result []domain.AutomaticLockAuditEntry
+	for i := len(a.entries) - 1; i >= 0; i-- {
+		entry := a.entries[i]
+		if filter.TraderID != "" && entry.TraderID != filter.TraderID {
+			continue
+		}
+		if filter.DeviceID != "" && !containsString(entry.DeviceIDs, filter.DeviceID) {
+			continue
+		}
+		result = append(result, entry)
+		if filter.Limit > 0 && len(result) >= filter.Limit {
+			break
+		}
+	}
+	return result
+}
+
+func (a *FileAutomaticLockAudit) All() []domain.AutomaticLockAuditEntry {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+
+	result := make([]domain.AutomaticLockAuditEntry, len(a.entries))
+	copy(result, a.entries)
+	return result
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,372 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/LavaJover/shvark-api-gateway/internal/client"
+	"github.com/LavaJover/shvark-api-gateway/internal/domain"
+	orderpb "github.com/LavaJover/shvark-order-service/proto/gen/order"
+	userpb "github.com/LavaJover/shvark-user-service/proto/gen"
+)
+
+const (
+	teamLeadRole = "TEAM_LEAD"
+	// automaticLogsPageSize максимальная страница GetAutomaticLogs
+	automaticLogsPageSize = 200
+)
+
+// ErrAutomaticStatsPeriod период статистики вне допустимого диапазона
+var ErrAutomaticStatsPeriod = errors.New("invalid automatic stats period")
+
+type platformStatsEntry struct {
+	stats     *domain.PlatformAutomaticStats
+	expiresAt time.Time
+}
+
+// AutomaticStatsService собирает статистику автоматики по всей платформе: обходит трейдеров из user-service,
+// параллельно (не больше concurrency запросов) запрашивает их статистику и логи в order-service и сводит разбивки.
+// Сбор дорогой, результат кешируется на cacheTTL отдельно для каждого периода; период ограничен maxDays,
+// чтобы кеш не рос от произвольных значений.
+type AutomaticStatsService struct {
+	orderClient      *client.OrderClient
+	userClient       *client.UserClient
+	concurrency      int
+	cacheTTL         time.Duration
+	maxLogsPerTrader int
+	maxDays          int
+
+	// collectMu не дает нескольким запросам собирать одну и ту же статистику одновременно
+	collectMu sync.Mutex
+	mu        sync.Mutex
+	cache     map[int]platformStatsEntry
+}
+
+func NewAutomaticStatsService(
+	orderClient *client.OrderClient,
+	userClient *client.UserClient,
+	concurrency int,
+	cacheTTL time.Duration,
+	maxLogsPerTrader int,
+	maxDays int,
+) *AutomaticStatsService {
+	if concurrency < 1 {
+		concurrency = 1
+	}
+	if maxDays < 1 {
+		maxDays = 1
+	}
+	return &AutomaticStatsService{
+		orderClient:      orderClient,
+		userClient:       userClient,
+		concurrency:      concurrency,
+		cacheTTL:         cacheTTL,
+		maxLogsPerTrader: maxLogsPerTrader,
+		maxDays:          maxDays,
+		cache:            make(map[int]platformStatsEntry),
+	}
+}
+
+// MaxDays максимальный период статистики в днях
+func (s *AutomaticStatsService) MaxDays() int {
+	return s.maxDays
+}
+
+// PlatformStats возвращает статистику за days дней и признак того, что она взята из кеша
+func (s *AutomaticStatsService) PlatformStats(ctx context.Context, days int) (*domain.PlatformAutomaticStats, bool, error) {
+	if days < 1 || days > s.maxDays {
+		return nil, false, fmt.Errorf("%w: days must be between 1 and %d", ErrAutomaticStatsPeriod, s.maxDays)
+	}
+	if stats, ok := s.cached(days); ok {
+		return stats, true, nil
+	}
+
+	s.collectMu.Lock()
+	defer s.collectMu.Unlock()
+
+	// пока ждали, статистику мог собрать другой запрос
+	if stats, ok := s.cached(days); ok {
+		return stats, true, nil
+	}
+
+	// результат уходит в кеш, поэтому отключение клиента не должно обрывать сбор на середине
+	stats, err := s.collect(context.WithoutCancel(ctx), days)
+	if err != nil {
+		return nil, false, err
+	}
+
+	now := time.Now()
+	s.mu.Lock()
+	for cachedDays, entry := range s.cache {
+		if now.After(entry.expiresAt) {
+			delete(s.cache, cachedDays)
+		}
+	}
+	s.cache[days] = platformStatsEntry{stats: stats, expiresAt: now.Add(s.cacheTTL)}
+	s.mu.Unlock()
+	return stats, false, nil
+}
+
+func (s *AutomaticStatsService) cached(days int) (*domain.PlatformAutomaticStats, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entry, ok := s.cache[days]
+	if !ok || time.Now().After(entry.expiresAt) {
+		return nil, false
+	}
+	return entry.stats, true
+}
+
+// traderStats статистика одного трейдера
+type traderStats struct {
+	traderID       string
+	overview       domain.AutomaticStatsCounters
+	devices        map[string]*domain.AutomaticStatsCounters
+	banks          map[string]*domain.AutomaticStatsCounters
+	paymentSystems map[string]*domain.AutomaticStatsCounters
+	logsTruncated  bool
+	err            error
+}
+
+func (s *AutomaticStatsService) collect(ctx context.Context, days int) (*domain.PlatformAutomaticStats, error) {
+	tradersResponse, err := s.userClient.GetTraders()
+	if err != nil {
+		return nil, upstreamError(UpstreamUser, err)
+	}
+	teamLeads := s.teamLeadsByTrader()
+
+	since := time.Now().AddDate(0, 0, -days)
+	results := make([]traderStats, len(tradersResponse.Traders))
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < s.concurrency; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for index := range jobs {
+				results[index] = s.collectTrader(ctx, tradersResponse.Traders[index].UserId, days, since)
+			}
+		}()
+	}
+	for index := range tradersResponse.Traders {
+		jobs <- index
+	}
+	close(jobs)
+	wg.Wait()
+
+	stats := &domain.PlatformAutomaticStats{
+		PeriodDays:   days,
+		GeneratedAt:  time.Now(),
+		TradersTotal: len(results),
+	}
+	devices := make(map[string]*domain.AutomaticStatsGroup)
+	banks := make(map[string]*domain.AutomaticStatsCounters)
+	paymentSystems := make(map[string]*domain.AutomaticStatsCounters)
+	byTeamLead := make(map[string]*domain.AutomaticStatsCounters)
+	for _, result := range results {
+		if result.err != nil {
+			log.Printf("automatic stats: trader %s: %v", result.traderID, result.err)
+			stats.TradersFailed = append(stats.TradersFailed, result.traderID)
+			continue
+		}
+		stats.Overview.Add(result.overview)
+		stats.LogsTruncated = stats.LogsTruncated || result.logsTruncated
+
+		for deviceID, counters := range result.devices {
+			group, ok := devices[deviceID]
+			if !ok {
+				group = &domain.AutomaticStatsGroup{Key: deviceID, TraderID: result.traderID}
+				devices[deviceID] = group
+			}
+			group.Add(*counters)
+		}
+		mergeCounters(banks, result.banks)
+		mergeCounters(paymentSystems, result.paymentSystems)
+
+		leads := teamLeads[result.traderID]
+		if len(leads) == 0 {
+			leads = []string{""}
+		}
+		for _, teamLeadID := range leads {
+			statsCounters(byTeamLead, teamLeadID).Add(result.overview)
+		}
+	}
+
+	for _, group := range devices {
+		stats.Devices = append(stats.Devices, *group)
+	}
+	sortGroups(stats.Devices)
+	stats.Banks = groupsOf(banks)
+	stats.PaymentSystems = groupsOf(paymentSystems)
+	stats.TeamLeads = groupsOf(byTeamLead)
+	return stats, nil
+}
+
+func (s *AutomaticStatsService) collectTrader(ctx context.Context, traderID string, days int, since time.Time) traderStats {
+	result := traderStats{
+		traderID:       traderID,
+		devices:        make(map[string]*domain.AutomaticStatsCounters),
+		banks:          make(map[string]*domain.AutomaticStatsCounters),
+		paymentSystems: make(map[string]*domain.AutomaticStatsCounters),
+	}
+
+	statsCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	response, err := s.orderClient.GetAutomaticStats(statsCtx, &orderpb.GetAutomaticStatsRequest{
+		TraderId: traderID,
+		Days:     int32(days),
+	})
+	cancel()
+	if err != nil {
+		result.err = fmt.Errorf("get automatic stats: %w", err)
+		return result
+	}
+	// без статистики трейдер попадает в TradersFailed, а не в нули
+	if response.GetStats() == nil {
+		result.err = errors.New("get automatic stats: empty response")
+		return result
+	}
+	result.overview = domain.AutomaticStatsCounters{
+		TotalAttempts:       response.Stats.TotalAttempts,
+		SuccessfulAttempts:  response.Stats.SuccessfulAttempts,
+		ApprovedOrders:      response.Stats.ApprovedOrders,
+		NotFoundCount:       response.Stats.NotFoundCount,
+		FailedCount:         response.Stats.FailedCount,
+		ProcessingTimeTotal: float64(response.Stats.AvgProcessingTime) * float64(response.Stats.TotalAttempts),
+	}
+	if result.overview.TotalAttempts == 0 {
+		return result
+	}
+
+	// Логи приходят от новых к старым, читаем страницы до начала периода или до лимита
+	collected := 0
+	for offset := 0; ; offset += automaticLogsPageSize {
+		logsResponse, err := s.orderClient.GetAutomaticLogs(ctx, &orderpb.GetAutomaticLogsRequest{
+			Filter: &orderpb.AutomaticLogFilter{
+				TraderId: traderID,
+				Limit:    automaticLogsPageSize,
+				Offset:   int32(offset),
+			},
+		})
+		if err != nil {
+			result.err = fmt.Errorf("get automatic logs: %w", err)
+			return result
+		}
+
+		for _, entry := range logsResponse.Logs {
+			if entry.CreatedAt.AsTime().Before(since) {
+				return result
+			}
+			if collected >= s.maxLogsPerTrader {
+				result.logsTruncated = true
+				return result
+			}
+			collected++
+
+			attempt := domain.AutomaticStatsCounters{
+				TotalAttempts:       1,
+				ProcessingTimeTotal: float64(entry.ProcessingTime),
+			}
+			if entry.Success {
+				attempt.SuccessfulAttempts = 1
+			}
+			switch entry.Action {
+			case "approved":
+				attempt.ApprovedOrders = 1
+			case "not_found":
+				attempt.NotFoundCount = 1
+			case "failed", "search_error":
+				attempt.FailedCount = 1
+			}
+			statsCounters(result.devices, entry.DeviceId).Add(attempt)
+			statsCounters(result.banks, entry.BankName).Add(attempt)
+			statsCounters(result.paymentSystems, entry.PaymentSystem).Add(attempt)
+		}
+
+		if len(logsResponse.Logs) < automaticLogsPageSize {
+			return result
+		}
+	}
+}
+
+// teamLeadsByTrader тимлиды каждого трейдера. Без связей статистика по тимлидам уходит в группу без тимлида.
+func (s *AutomaticStatsService) teamLeadsByTrader() map[string][]string {
+	result := make(map[string][]string)
+
+	response, err := s.userClient.GetUsersByRole(&userpb.GetUsersByRoleRequest{
+		Role: teamLeadRole,
+	})
+	if err != nil {
+		log.Printf("automatic stats: failed to get team leads: %v", err)
+		return result
+	}
+	for _, teamLead := range response.Users {
+		relations, err := s.orderClient.GetTeamRelationsByTeamLeadID(&orderpb.GetRelationsByTeamLeadIDRequest{
+			TeamLeadId: teamLead.UserId,
+		})
+		if err != nil {
+			log.Printf("automatic stats: failed to get relations of team lead %s: %v", teamLead.UserId, err)
+			continue
+		}
+		for _, relation := range relations.TeamRelations {
+			result[relation.TraderId] = append(result[relation.TraderId], relation.TeamLeadId)
+		}
+	}
+	return result
+}
+
+func statsCounters(groups map[string]*domain.AutomaticStatsCounters, key string) *domain.AutomaticStatsCounters {
+	group, ok := groups[key]
+	if !ok {
+		group = &domain.AutomaticStatsCounters{}
+		groups[key] = group
+	}
+	return group
+}
+
+func mergeCounters(into, from map[string]*domain.AutomaticStatsCounters) {
+	for key, value := range from {
+		statsCounters(into, key).Add(*value)
+	}
+}
+
+func groupsOf(groups map[string]*domain.AutomaticStatsCounters) []domain.AutomaticStatsGroup {
+	result := make([]domain.AutomaticStatsGroup, 0, len(groups))
+	for key, value := range groups {
+		result = append(result, domain.AutomaticStatsGroup{Key: key, AutomaticStatsCounters: *value})
+	}
+	sortGroups(result)
+	return result
+}
+
+// sortGroups по числу попыток, самые нагруженные первыми
+func sortGroups(groups []domain.AutomaticStatsGroup) {
+	sort.Slice(groups, func(i, j int) bool {
+		if groups[i].TotalAttempts != groups[j].TotalAttempts {
+			return groups[i].TotalAttempts > groups[j].TotalAttempts
+		}
+		return groups[i].Key < groups[j].Key
+	})
+}
+
+// TopFailingDevices n устройств с наибольшим числом неудачных попыток
+func TopFailingDevices(stats *domain.PlatformAutomaticStats, n int) []domain.AutomaticStatsGroup {
+	var failing []domain.AutomaticStatsGroup
+	for _, device := range stats.Devices {
+		if device.TotalAttempts > device.SuccessfulAttempts {
+			failing = append(failing, device)
+		}
+	}
+	sort.SliceStable(failing, func(i, j int) bool {
+		return failing[i].TotalAttempts-failing[i].SuccessfulAttempts > failing[j].TotalAttempts-failing[j].SuccessfulAttempts
+	})
+	if len(failing) > n {
+		failing = failing[:n]
+	}
+	return failing
+}
@@ -0,0 +1,239 @@
+package bank_catalog
+
+import (
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Справочник, встроенный в бинарник, используется, если файл справочника не задан
+//
+//go:embed builtin/banks.json
+var builtinBanks []byte
+
+// AllPaymentSystems платежная система шаблона диплинка, который подходит к любой системе
+const AllPaymentSystems = "all"
+
+var (
+	ErrBankNotFound     = errors.New("bank not found")
+	ErrUnknownBankCode  = errors.New("unknown bank_code")
+	ErrUnknownNspkCode  = errors.New("unknown nspk_code")
+	ErrNspkCodeMismatch = errors.New("nspk_code does not belong to bank_code")
+)
+
+var (
+	bankCodePattern = regexp.MustCompile(`^[a-z0-9_-]{2,64}$`)
+	nspkCodePattern = regexp.MustCompile(`^[0-9]{12}$`)
+)
+
+// DeeplinkTemplate шаблон диплинка банка и платежные системы, для которых он показывается
+type DeeplinkTemplate struct {
+	Code           string   `json:"code"`
+	PaymentSystems []string `json:"payment_systems"`
+}
+
+// Bank запись справочника банков
+type Bank struct {
+	Code              string             `json:"code"`
+	Name              string             `json:"name"`
+	Names             map[string]string  `json:"names,omitempty"` // названия на других языках
+	NspkCode          string             `json:"nspk_code"`
+	Country           string             `json:"country"`
+	PaymentSystems    []string           `json:"payment_systems"`
+	DeeplinkTemplates []DeeplinkTemplate `json:"deeplink_templates,omitempty"`
+	Logo              string             `json:"logo,omitempty"`
+}
+
+// LocalizedName название банка на языке locale, если перевода нет - основное
+func (b Bank) LocalizedName(locale string) string {
+	if name, ok := b.Names[locale]; ok && name != "" {
+		return name
+	}
+	return b.Name
+}
+
+// SupportsPaymentSystem сообщает, принимает ли банк платежи указанной системы
+func (b Bank) SupportsPaymentSystem(paymentSystem string) bool {
+	return containsFold(b.PaymentSystems, paymentSystem)
+}
+
+// Filter условия отбора банков, пустое поле не ограничивает выборку
+type Filter struct {
+	Country       string
+	PaymentSystem string
+	NspkCode      string
+	Query         string // подстрока кода или названия
+}
+
+// Catalog неизменяемый справочник банков
+type Catalog struct {
+	banks  []Bank
+	byCode map[string]Bank
+	byNspk map[string][]Bank // у некоторых банков в справочнике общий код НСПК (переименования, слияния)
+}
+
+type catalogFile struct {
+	Banks []Bank `json:"banks"`
+}
+
+// Load загружает справочник из файла, пустой путь - встроенный справочник
+func Load(path string) (*Catalog, error) {
+	raw := builtinBanks
+	if path != "" {
+		var err error
+		raw, err = os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var file catalogFile
+	if err := json.Unmarshal(raw, &file); err != nil {
+		return nil, fmt.Errorf("parse bank catalog: %w", err)
+	}
+	return newCatalog(file.Banks)
+}
+
+func newCatalog(banks []Bank) (*Catalog, error) {
+	catalog := &Catalog{
+		banks:  banks,
+		byCode: make(map[string]Bank, len(banks)),
+		byNspk: make(map[string][]Bank, len(banks)),
+	}
+
+	var problems []string
+	for _, bank := range banks {
+		switch {
+		case !bankCodePattern.MatchString(bank.Code):
+			problems = append(problems, fmt.Sprintf("invalid bank code %q", bank.Code))
+			continue
+		case bank.Name == "":
+			problems = append(problems, fmt.Sprintf("%s: name is required", bank.Code))
+		case bank.NspkCode != "" && !nspkCodePattern.MatchString(bank.NspkCode):
+			problems = append(problems, fmt.Sprintf("%s: nspk_code must be 12 digits", bank.Code))
+		}
+		if _, exists := catalog.byCode[bank.Code]; exists {
+			problems = append(problems, fmt.Sprintf("duplicate bank code %q", bank.Code))
+			continue
+		}
+		catalog.byCode[bank.Code] = bank
+		if bank.NspkCode != "" {
+			catalog.byNspk[bank.NspkCode] = append(catalog.byNspk[bank.NspkCode], bank)
+		}
+	}
+	if len(problems) > 0 {
+		return nil, fmt.Errorf("invalid bank catalog: %s", strings.Join(problems, "; "))
+	}
+	return catalog, nil
+}
+
+// Banks возвращает банки, подходящие под фильтр, в порядке справочника
+func (c *Catalog) Banks(filter Filter) []Bank {
+	query := strings.ToLower(strings.TrimSpace(filter.Query))
+	result := make([]Bank, 0, len(c.banks))
+	for _, bank := range c.banks {
+		if filter.Country != "" && !strings.EqualFold(bank.Country, filter.Country) {
+			continue
+		}
+		if filter.PaymentSystem != "" && !bank.SupportsPaymentSystem(filter.PaymentSystem) {
+			continue
+		}
+		if filter.NspkCode != "" && bank.NspkCode != filter.NspkCode {
+			continue
+		}
+		if query != "" && !bankMatches(bank, query) {
+			continue
+		}
+		result = append(result, bank)
+	}
+	return result
+}
+
+// ByCode ищет банк по коду
+func (c *Catalog) ByCode(code string) (Bank, error) {
+	bank, ok := c.byCode[code]
+	if !ok {
+		return Bank{}, ErrBankNotFound
+	}
+	return bank, nil
+}
+
+// ByNspkCode ищет банки по коду НСПК
+func (c *Catalog) ByNspkCode(nspkCode string) ([]Bank, error) {
+	banks, ok := c.byNspk[nspkCode]
+	if !ok {
+		return nil, ErrBankNotFound
+	}
+	return banks, nil
+}
+
+// Resolve проверяет пару bank_code/nspk_code из запроса. Пустые значения допустимы,
+// непустые должны быть в справочнике и относиться к одному банку.
+// Для известного bank_code без nspk_code код НСПК берется из справочника.
+func (c *Catalog) Resolve(bankCode, nspkCode string) (string, string, error) {
+	if nspkCode != "" {
+		if _, ok := c.byNspk[nspkCode]; !ok {
+			return "", "", ErrUnknownNspkCode
+		}
+	}
+	if bankCode == "" {
+		return bankCode, nspkCode, nil
+	}
+
+	bank, ok := c.byCode[bankCode]
+	if !ok {
+		return "", "", ErrUnknownBankCode
+	}
+	if nspkCode == "" {
+		return bankCode, bank.NspkCode, nil
+	}
+	if bank.NspkCode != nspkCode {
+		return "", "", ErrNspkCodeMismatch
+	}
+	return bankCode, nspkCode, nil
+}
+
+// TemplateCodes коды шаблонов диплинков, которые показываются для платежной системы
+func (c *Catalog) TemplateCodes(paymentSystem string) []string {
+	seen := make(map[string]bool)
+	var codes []string
+	for _, bank := range c.banks {
+		for _, template := range bank.DeeplinkTemplates {
+			if seen[template.Code] {
+				continue
+			}
+			if containsFold(template.PaymentSystems, paymentSystem) || containsFold(template.PaymentSystems, AllPaymentSystems) {
+				seen[template.Code] = true
+				codes = append(codes, template.Code)
+			}
+		}
+	}
+	sort.Strings(codes)
+	return codes
+}
+
+func bankMatches(bank Bank, query string) bool {
+	if strings.Contains(strings.ToLower(bank.Code), query) || strings.Contains(strings.ToLower(bank.Name), query) {
+		return true
+	}
+	for _, name := range bank.Names {
+		if strings.Contains(strings.ToLower(name), query) {
+			return true
+		}
+	}
+	return false
+}
+
+func containsFold(values []string, value string) bool {
+	for _, v := range values {
+		if strings.EqualFold(v, value) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,413 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/LavaJover/shvark-api-gateway/internal/client"
+	"github.com/LavaJover/shvark-api-gateway/internal/domain"
+	orderpb "github.com/LavaJover/shvark-order-service/proto/gen/order"
+	"google.golang.org/protobuf/types/known/durationpb"
+)
+
+var (
+	ErrBulkEmpty     = errors.New("bulk operation has no items")
+	ErrBulkTooLarge  = errors.New("too many items in bulk operation")
+	ErrBulkDuplicate = errors.New("duplicate item in bulk operation")
+)
+
+// BulkAdminConfig параллельность массовых операций и лимит элементов в одном запросе
+type BulkAdminConfig struct {
+	Concurrency int
+	MaxItems    int
+}
+
+// BulkAdminService массовые операции админки: трафик по парам трейдер x мерчант, блокировка трафика
+// всей команды тимлида и правила вывода для многих пользователей. Каждая операция сначала читает
+// текущее состояние и считает diff, в dry-run на этом останавливается, иначе применяет только
+// элементы с изменениями. Запросы в upstream идут не больше чем в concurrency потоков.
+type BulkAdminService struct {
+	orderClient  *client.OrderClient
+	walletClient *client.HTTPWalletClient
+	traderEvents *TraderEventPublisher
+	watchdog     *DeviceWatchdog
+	concurrency  int
+	maxItems     int
+}
+
+func NewBulkAdminService(
+	orderClient *client.OrderClient,
+	walletClient *client.HTTPWalletClient,
+	traderEvents *TraderEventPublisher,
+	watchdog *DeviceWatchdog,
+	config BulkAdminConfig,
+) *BulkAdminService {
+	if config.Concurrency < 1 {
+		config.Concurrency = 1
+	}
+	return &BulkAdminService{
+		orderClient:  orderClient,
+		walletClient: walletClient,
+		traderEvents: traderEvents,
+		watchdog:     watchdog,
+		concurrency:  config.Concurrency,
+		maxItems:     config.MaxItems,
+	}
+}
+
+// BulkTrafficPatch поля трафика, nil - поле не меняется. Для создания недостающего трафика
+// обязательны вознаграждение трейдера, комиссия платформы, приоритет и длительность сделок.
+type BulkTrafficPatch struct {
+	Name                  *string
+	TraderRewardPercent   *float64
+	PlatformFee           *float64
+	TraderPriority        *float64
+	Enabled               *bool
+	AntifraudRequired     *bool
+	MerchantDealsDuration *time.Duration
+}
+
+type BulkTrafficParams struct {
+	TraderIDs   []string
+	MerchantIDs []string
+	Patch       BulkTrafficPatch
+	DryRun      bool
+}
+
+// UpsertTraffic для каждой пары трейдер x мерчант правит существующий трафик или создает новый
+func (s *BulkAdminService) UpsertTraffic(params BulkTrafficParams) (domain.BulkResult, error) {
+	traderIDs := uniqueIDs(params.TraderIDs)
+	merchantIDs := uniqueIDs(params.MerchantIDs)
+	if err := s.checkSize(len(traderIDs) * len(merchantIDs)); err != nil {
+		return domain.BulkResult{}, err
+	}
+
+	// текущий трафик читается один раз на трейдера, а не на каждую пару
+	type traderTraffic struct {
+		byMerchant map[string]*orderpb.Traffic
+		err        error
+	}
+	traffic := make([]traderTraffic, len(traderIDs))
+	s.run(len(traderIDs), func(index int) {
+		response, err := s.orderClient.GetTraderTraffic(&orderpb.GetTraderTrafficRequest{
+			TraderId: traderIDs[index],
+		})
+		if err != nil {
+			traffic[index].err = upstreamError(UpstreamOrder, err)
+			return
+		}
+		traffic[index].byMerchant = make(map[string]*orderpb.Traffic, len(response.Records))
+		for _, record := range response.Records {
+			traffic[index].byMerchant[record.MerchantId] = record
+		}
+	})
+
+	items := make([]domain.BulkItemResult, len(traderIDs)*len(merchantIDs))
+	s.run(len(items), func(index int) {
+		trader := index / len(merchantIDs)
+		item := &items[index]
+		item.TraderID = traderIDs[trader]
+		item.MerchantID = merchantIDs[index%len(merchantIDs)]
+		if err := traffic[trader].err; err != nil {
+			item.Action = domain.BulkActionUpdate
+			item.Fail(err)
+			return
+		}
+		if record, ok := traffic[trader].byMerchant[item.MerchantID]; ok {
+			s.editTraffic(item, record, params.Patch, params.DryRun)
+			return
+		}
+		s.createTraffic(item, params.Patch, params.DryRun)
+	})
+	return domain.NewBulkResult(params.DryRun, items), nil
+}
+
+func (s *BulkAdminService) editTraffic(item *domain.BulkItemResult, record *orderpb.Traffic, patch BulkTrafficPatch, dryRun bool) {
+	item.Action = domain.BulkActionUpdate
+	item.TrafficID = record.Id
+
+	request := &orderpb.EditTrafficRequest{Id: record.Id}
+	if patch.Name != nil && *patch.Name != record.Name {
+		item.AddChange("name", record.Name, *patch.Name)
+		request.Name = patch.Name
+	}
+	if patch.TraderRewardPercent != nil && *patch.TraderRewardPercent != record.TraderRewardPercent {
+		item.AddChange("trader_reward_percent", formatFloat(record.TraderRewardPercent), formatFloat(*patch.TraderRewardPercent))
+		request.TraderReward = patch.TraderRewardPercent
+	}
+	if patch.PlatformFee != nil && *patch.PlatformFee != record.PlatformFee {
+		item.AddChange("platform_fee", formatFloat(record.PlatformFee), formatFloat(*patch.PlatformFee))
+		request.PlatformFee = patch.PlatformFee
+	}
+	if patch.TraderPriority != nil && *patch.TraderPriority != record.TraderPriority {
+		item.AddChange("trader_priority", formatFloat(record.TraderPriority), formatFloat(*patch.TraderPriority))
+		request.TraderProirity = patch.TraderPriority
+	}
+	if patch.Enabled != nil && *patch.Enabled != record.Enabled {
+		item.AddChange("enabled", strconv.FormatBool(record.Enabled), strconv.FormatBool(*patch.Enabled))
+		request.Enabled = patch.Enabled
+	}
+	if current := record.GetAntifraudParams().GetAntifraudRequired(); patch.AntifraudRequired != nil && *patch.AntifraudRequired != current {
+		item.AddChange("antifraud_required", strconv.FormatBool(current), strconv.FormatBool(*patch.AntifraudRequired))
+		request.AntifraudParams = &orderpb.TrafficAntifraudParameters{
+			AntifraudRequired: *patch.AntifraudRequired,
+		}
+	}
+	if current := record.GetBusinessParams().GetMerchantDealsDuration().AsDuration(); patch.MerchantDealsDuration != nil && *patch.MerchantDealsDuration != current {
+		item.AddChange("merchant_deals_duration", current.String(), patch.MerchantDealsDuration.String())
+		request.BusinessParams = &orderpb.TrafficBusinessParameters{
+			MerchantDealsDuration: durationpb.New(*patch.MerchantDealsDuration),
+		}
+	}
+
+	if !item.Plan(dryRun) {
+		return
+	}
+	if err := s.orderClient.EditTraffic(request); err != nil {
+		item.Fail(upstreamError(UpstreamOrder, err))
+		return
+	}
+	item.Status = domain.BulkItemApplied
+}
+
+func (s *BulkAdminService) createTraffic(item *domain.BulkItemResult, patch BulkTrafficPatch, dryRun bool) {
+	item.Action = domain.BulkActionCreate
+	if patch.TraderRewardPercent == nil || patch.PlatformFee == nil ||
+		patch.TraderPriority == nil || patch.MerchantDealsDuration == nil {
+		item.Fail(errors.New("no traffic for this pair: trader_reward_percent, platform_fee, trader_priority and merchant_deals_duration are required to create it"))
+		return
+	}
+
+	request := &orderpb.AddTrafficRequest{
+		MerchantId:          item.MerchantID,
+		TraderId:            item.TraderID,
+		TraderRewardPercent: *patch.TraderRewardPercent,
+		TraderPriority:      *patch.TraderPriority,
+		Enabled:             true,
+		PlatformFee:         *patch.PlatformFee,
+		ActivityParams: &orderpb.TrafficActivityParameters{
+			MerchantUnlocked:  true,
+			TraderUnlocked:    true,
+			ManuallyUnlocked:  true,
+			AntifraudUnlocked: true,
+		},
+		AntifraudParams: &orderpb.TrafficAntifraudParameters{},
+		BusinessParams: &orderpb.TrafficBusinessParameters{
+			MerchantDealsDuration: durationpb.New(*patch.MerchantDealsDuration),
+		},
+	}
+	if patch.Name != nil {
+		request.Name = *patch.Name
+		item.AddChange("name", "", *patch.Name)
+	}
+	if patch.Enabled != nil {
+		request.Enabled = *patch.Enabled
+	}
+	if patch.AntifraudRequired != nil {
+		request.AntifraudParams.AntifraudRequired = *patch.AntifraudRequired
+	}
+	item.AddChange("trader_reward_percent", "", formatFloat(request.TraderRewardPercent))
+	item.AddChange("platform_fee", "", formatFloat(request.PlatformFee))
+	item.AddChange("trader_priority", "", formatFloat(request.TraderPriority))
+	item.AddChange("enabled", "", strconv.FormatBool(request.Enabled))
+	item.AddChange("antifraud_required", "", strconv.FormatBool(request.AntifraudParams.AntifraudRequired))
+	item.AddChange("merchant_deals_duration", "", patch.MerchantDealsDuration.String())
+
+	if !item.Plan(dryRun) {
+		return
+	}
+	if err := s.orderClient.AddTraffic(request); err != nil {
+		item.Fail(upstreamError(UpstreamOrder, err))
+		return
+	}
+	item.Status = domain.BulkItemApplied
+}
+
+type BulkTeamLockParams struct {
+	TeamLeadID string
+	Unlocked   bool
+	// Reason причина блокировки, уходит трейдерам в событии
+	Reason string
+	DryRun bool
+}
+
+// SetTeamTrafficLock блокирует или разблокирует трафик всех трейдеров команды тимлида
+func (s *BulkAdminService) SetTeamTrafficLock(params BulkTeamLockParams) (domain.BulkResult, error) {
+	relations, err := s.orderClient.GetTeamRelationsByTeamLeadID(&orderpb.GetRelationsByTeamLeadIDRequest{
+		TeamLeadId: params.TeamLeadID,
+	})
+	if err != nil {
+		return domain.BulkResult{}, upstreamError(UpstreamOrder, err)
+	}
+	traderIDs := make([]string, 0, len(relations.TeamRelations))
+	for _, relation := range relations.TeamRelations {
+		traderIDs = append(traderIDs, relation.TraderId)
+	}
+	traderIDs = uniqueIDs(traderIDs)
+	if err := s.checkSize(len(traderIDs)); err != nil {
+		return domain.BulkResult{}, err
+	}
+
+	action := domain.BulkActionLock
+	if params.Unlocked {
+		action = domain.BulkActionUnlock
+	}
+	items := make([]domain.BulkItemResult, len(traderIDs))
+	s.run(len(items), func(index int) {
+		item := &items[index]
+		item.TraderID = traderIDs[index]
+		item.Action = action
+
+		response, err := s.orderClient.GetTraderTraffic(&orderpb.GetTraderTrafficRequest{
+			TraderId: item.TraderID,
+		})
+		if err != nil {
+			item.Fail(upstreamError(UpstreamOrder, err))
+			return
+		}
+		for _, record := range response.Records {
+			if current := record.GetActivityParams().GetTraderUnlocked(); current != params.Unlocked {
+				item.AddChange(
+					fmt.Sprintf("traffic %s (merchant %s) trader_unlocked", record.Id, record.MerchantId),
+					strconv.FormatBool(current),
+					strconv.FormatBool(params.Unlocked),
+				)
+			}
+		}
+
+		if !item.Plan(params.DryRun) {
+			return
+		}
+		_, err = s.orderClient.SetTraderLockTrafficStatus(&orderpb.SetTraderLockTrafficStatusRequest{
+			TraderId: item.TraderID,
+			Unlocked: params.Unlocked,
+		})
+		if err != nil {
+			item.Fail(upstreamError(UpstreamOrder, err))
+			return
+		}
+		s.watchdog.ReleaseTrader(item.TraderID)
+		if !params.Unlocked {
+			s.traderEvents.TrafficLocked(item.TraderID, params.Reason)
+		}
+		item.Status = domain.BulkItemApplied
+	})
+	return domain.NewBulkResult(params.DryRun, items), nil
+}
+
+// BulkWithdrawalRule правила вывода одного пользователя
+type BulkWithdrawalRule struct {
+	UserID          string
+	FixedFee        float64
+	MinAmount       float64
+	CooldownSeconds int64
+}
+
+// SetWithdrawalRules задает правила вывода для списка пользователей, у каждого свои
+func (s *BulkAdminService) SetWithdrawalRules(rules []BulkWithdrawalRule, dryRun bool) (domain.BulkResult, error) {
+	if err := s.checkSize(len(rules)); err != nil {
+		return domain.BulkResult{}, err
+	}
+	seen := make(map[string]struct{}, len(rules))
+	for _, rule := range rules {
+		if _, ok := seen[rule.UserID]; ok {
+			return domain.BulkResult{}, fmt.Errorf("%w: user %s", ErrBulkDuplicate, rule.UserID)
+		}
+		seen[rule.UserID] = struct{}{}
+	}
+
+	items := make([]domain.BulkItemResult, len(rules))
+	s.run(len(items), func(index int) {
+		rule := rules[index]
+		item := &items[index]
+		item.UserID = rule.UserID
+		item.Action = domain.BulkActionSet
+
+		current, err := s.walletClient.GetWithdrawalRules(rule.UserID)
+		switch {
+		case errors.Is(err, client.ErrWithdrawalRuleNotFound):
+			item.AddChange("fixed_fee", "", formatFloat(rule.FixedFee))
+			item.AddChange("min_amount", "", formatFloat(rule.MinAmount))
+			item.AddChange("cooldown_seconds", "", strconv.FormatInt(rule.CooldownSeconds, 10))
+		case err != nil:
+			item.Fail(upstreamError(UpstreamWallet, err))
+			return
+		default:
+			if current.FixedFee != rule.FixedFee {
+				item.AddChange("fixed_fee", formatFloat(current.FixedFee), formatFloat(rule.FixedFee))
+			}
+			if current.MinAmount != rule.MinAmount {
+				item.AddChange("min_amount", formatFloat(current.MinAmount), formatFloat(rule.MinAmount))
+			}
+			if current.CooldownSeconds != rule.CooldownSeconds {
+				item.AddChange("cooldown_seconds", strconv.FormatInt(current.CooldownSeconds, 10), strconv.FormatInt(rule.CooldownSeconds, 10))
+			}
+		}
+
+		if !item.Plan(dryRun) {
+			return
+		}
+		_, err = s.walletClient.SetWithdrawalRules(&client.SetWithdrawalRulesRequest{
+			TraderID:        rule.UserID,
+			FixedFee:        rule.FixedFee,
+			MinAmount:       rule.MinAmount,
+			CooldownSeconds: rule.CooldownSeconds,
+		})
+		if err != nil {
+			item.Fail(upstreamError(UpstreamWallet, err))
+			return
+		}
+		item.Status = domain.BulkItemApplied
+	})
+	return domain.NewBulkResult(dryRun, items), nil
+}
+
+func (s *BulkAdminService) checkSize(n int) error {
+	if n == 0 {
+		return ErrBulkEmpty
+	}
+	if s.maxItems > 0 && n > s.maxItems {
+		return fmt.Errorf("%w: %d items, limit is %d", ErrBulkTooLarge, n, s.maxItems)
+	}
+	return nil
+}
+
+// run вызывает fn для индексов 0..n-1 не больше чем в concurrency потоков
+func (s *BulkAdminService) run(n int, fn func(index int)) {
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < min(s.concurrency, n); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for index := range jobs {
+				fn(index)
+			}
+		}()
+	}
+	for index := 0; index < n; index++ {
+		jobs <- index
+	}
+	close(jobs)
+	wg.Wait()
+}
+
+func uniqueIDs(ids []string) []string {
+	seen := make(map[string]struct{}, len(ids))
+	result := make([]string, 0, len(ids))
+	for _, id := range ids {
+		if _, ok := seen[id]; ok || id == "" {
+			continue
+		}
+		seen[id] = struct{}{}
+		result = append(result, id)
+	}
+	return result
+}
+
+func formatFloat(value float64) string {
+	return strconv.FormatFloat(value, 'f', -1, 64)
+}
@@ -0,0 +1,203 @@
+package service
+
+import (
+	"errors"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/LavaJover/shvark-api-gateway/internal/domain"
+)
+
+var ErrUnknownDeeplinkEvent = errors.New("unknown deeplink event type")
+
+// DeeplinkStatsGroup разрез статистики воронки диплинков
+type DeeplinkStatsGroup string
+
+const (
+	DeeplinkStatsByBank   DeeplinkStatsGroup = "bank"
+	DeeplinkStatsByScheme DeeplinkStatsGroup = "scheme"
+	DeeplinkStatsByOS     DeeplinkStatsGroup = "os"
+)
+
+// DeeplinkEventStore хранилище событий воронки. Реализация по умолчанию держит события в памяти процесса.
+type DeeplinkEventStore interface {
+	// Append сохраняет событие, события приходят в порядке времени
+	Append(event domain.DeeplinkEvent)
+	// Range возвращает события в интервале [from, to)
+	Range(from, to time.Time) []domain.DeeplinkEvent
+}
+
+// InMemoryDeeplinkEventStore хранит события не дольше retention и не больше maxEvents
+type InMemoryDeeplinkEventStore struct {
+	retention time.Duration
+	maxEvents int
+
+	mu     sync.RWMutex
+	events []domain.DeeplinkEvent
+}
+
+func NewInMemoryDeeplinkEventStore(retention time.Duration, maxEvents int) *InMemoryDeeplinkEventStore {
+	return &InMemoryDeeplinkEventStore{
+		retention: retention,
+		maxEvents: maxEvents,
+	}
+}
+
+func (s *InMemoryDeeplinkEventStore) Append(event domain.DeeplinkEvent) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.events = append(s.events, event)
+
+	drop := 0
+	if s.retention > 0 {
+		cutoff := event.At.Add(-s.retention)
+		drop = sort.Search(len(s.events), func(i int) bool {
+			return !s.events[i].At.Before(cutoff)
+		})
+	}
+	if s.maxEvents > 0 && len(s.events)-drop > s.maxEvents {
+		drop = len(s.events) - s.maxEvents
+	}
+	if drop > 0 {
+		s.events = append(s.events[:0:0], s.events[drop:]...)
+	}
+}
+
+func (s *InMemoryDeeplinkEventStore) Range(from, to time.Time) []domain.DeeplinkEvent {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	start := sort.Search(len(s.events), func(i int) bool {
+		return !s.events[i].At.Before(from)
+	})
+	end := sort.Search(len(s.events), func(i int) bool {
+		return !s.events[i].At.Before(to)
+	})
+	if start >= end {
+		return nil
+	}
+	result := make([]domain.DeeplinkEvent, end-start)
+	copy(result, s.events[start:end])
+	return result
+}
+
+// DeeplinkFunnel счетчики шагов воронки. Conversion - доля попыток открыть приложение,
+// после которых страница ушла в фон, то есть приложение действительно открылось.
+type DeeplinkFunnel struct {
+	PageViews  int
+	BankChosen int
+	Attempts   int
+	Opened     int
+	TimedOut   int
+	Conversion float64
+}
+
+// DeeplinkStatsRow воронка одного значения разреза за один интервал
+type DeeplinkStatsRow struct {
+	BucketStart time.Time
+	Key         string
+	DeeplinkFunnel
+}
+
+// DeeplinkStatsQuery параметры агрегации: интервал [From, To), шаг Interval и разрез GroupBy
+type DeeplinkStatsQuery struct {
+	From     time.Time
+	To       time.Time
+	Interval time.Duration
+	GroupBy  DeeplinkStatsGroup
+}
+
+// DeeplinkAnalytics принимает события воронки со страниц диплинков и считает конверсию
+type DeeplinkAnalytics struct {
+	store DeeplinkEventStore
+}
+
+func NewDeeplinkAnalytics(store DeeplinkEventStore) *DeeplinkAnalytics {
+	return &DeeplinkAnalytics{
+		store: store,
+	}
+}
+
+// Track сохраняет событие, время проставляется сервером: часам клиента верить нельзя
+func (a *DeeplinkAnalytics) Track(event domain.DeeplinkEvent) error {
+	if _, ok := domain.ParseDeeplinkEventType(string(event.Type)); !ok {
+		return ErrUnknownDeeplinkEvent
+	}
+	if event.Platform == "" {
+		event.Platform = domain.PlatformUnknown
+	}
+	event.At = time.Now()
+	a.store.Append(event)
+	return nil
+}
+
+// Stats агрегирует воронку по интервалам и разрезу. События, у которых нет значения разреза
+// (например, просмотр страницы выбора банка в разрезе по банку), в статистику разреза не попадают.
+func (a *DeeplinkAnalytics) Stats(query DeeplinkStatsQuery) []DeeplinkStatsRow {
+	type rowKey struct {
+		bucket int64
+		key    string
+	}
+
+	rows := make(map[rowKey]*DeeplinkStatsRow)
+	for _, event := range a.store.Range(query.From, query.To) {
+		key := deeplinkStatsKey(event, query.GroupBy)
+		if key == "" {
+			continue
+		}
+		bucket := int64(event.At.Sub(query.From) / query.Interval)
+		row, exists := rows[rowKey{bucket, key}]
+		if !exists {
+			row = &DeeplinkStatsRow{
+				BucketStart: query.From.Add(time.Duration(bucket) * query.Interval),
+				Key:         key,
+			}
+			rows[rowKey{bucket, key}] = row
+		}
+
+		switch event.Type {
+		case domain.DeeplinkEventPageViewed:
+			row.PageViews++
+		case domain.DeeplinkEventBankChosen:
+			row.BankChosen++
+		case domain.DeeplinkEventSchemeAttempted:
+			row.Attempts++
+		case domain.DeeplinkEventAppOpened:
+			row.Opened++
+		case domain.DeeplinkEventAppTimeout:
+			row.TimedOut++
+		}
+	}
+
+	result := make([]DeeplinkStatsRow, 0, len(rows))
+	for _, row := range rows {
+		if row.Attempts > 0 {
+			row.Conversion = float64(row.Opened) / float64(row.Attempts)
+		}
+		result = append(result, *row)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if !result[i].BucketStart.Equal(result[j].BucketStart) {
+			return result[i].BucketStart.Before(result[j].BucketStart)
+		}
+		return result[i].Key < result[j].Key
+	})
+	return result
+}
+
+func deeplinkStatsKey(event domain.DeeplinkEvent, groupBy DeeplinkStatsGroup) string {
+	switch groupBy {
+	case DeeplinkStatsByScheme:
+		if event.BankCode == "" || event.Scheme == "" {
+			return ""
+		}
+		// одинаковые имена схем встречаются у разных банков
+		return event.BankCode + "/" + event.Scheme
+	case DeeplinkStatsByOS:
+		return string(event.Platform)
+	default:
+		return event.BankCode
+	}
+}
@@ -0,0 +1,31 @@
+package service
+
+import "strings"
+
+// MaskCardNumber оставляет первые и последние 4 цифры номера карты: 2200 •••• •••• 1234
+func MaskCardNumber(cardNumber string) string {
+	digits := onlyDigits(cardNumber)
+	if len(digits) < 12 {
+		return strings.Repeat("•", len(digits))
+	}
+	return digits[:4] + " •••• •••• " + digits[len(digits)-4:]
+}
+
+// MaskPhone оставляет код страны с оператором и последние 2 цифры: +7 900 •••-••-67
+func MaskPhone(phone string) string {
+	digits := onlyDigits(phone)
+	if len(digits) < 7 {
+		return strings.Repeat("•", len(digits))
+	}
+	return "+" + digits[:1] + " " + digits[1:4] + " •••-••-" + digits[len(digits)-2:]
+}
+
+func onlyDigits(value string) string {
+	var b strings.Builder
+	for _, r := range value {
+		if r >= '0' && r <= '9' {
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,124 @@
+package service
+
+import (
+	"net/url"
+	"regexp"
+	"strings"
+
+	"github.com/LavaJover/shvark-api-gateway/internal/domain"
+)
+
+var deeplinkPlaceholder = regexp.MustCompile(`\{(\w+)\}`)
+
+// DetectPlatform определяет платформу клиента. Client hints (Sec-CH-UA-Platform, Sec-CH-UA-Mobile)
+// приоритетнее User-Agent: Chrome на Android сокращает User-Agent, а хинты отдает точно.
+func DetectPlatform(userAgent, platformHint, mobileHint string) domain.Platform {
+	switch strings.ToLower(strings.Trim(platformHint, `" `)) {
+	case "android":
+		return domain.PlatformAndroid
+	case "ios":
+		return domain.PlatformIOS
+	case "windows", "macos", "linux", "chrome os", "chromium os":
+		if mobileHint != "?1" {
+			return domain.PlatformDesktop
+		}
+	}
+
+	ua := strings.ToLower(userAgent)
+	switch {
+	case ua == "":
+		return domain.PlatformUnknown
+	case strings.Contains(ua, "windows phone"):
+		return domain.PlatformUnknown
+	case strings.Contains(ua, "android"):
+		return domain.PlatformAndroid
+	case strings.Contains(ua, "iphone"), strings.Contains(ua, "ipad"), strings.Contains(ua, "ipod"):
+		return domain.PlatformIOS
+	case strings.Contains(ua, "macintosh") && strings.Contains(ua, "mobile/"):
+		// iPadOS 13+ представляется Mac и не отдает client hints, но WebKit оставляет
+		// номер сборки Mobile/, которого нет в User-Agent настоящего macOS
+		return domain.PlatformIOS
+	case strings.Contains(ua, "windows nt"), strings.Contains(ua, "macintosh"),
+		strings.Contains(ua, "x11"), strings.Contains(ua, "cros"):
+		return domain.PlatformDesktop
+	}
+	return domain.PlatformUnknown
+}
+
+// SelectDeeplinks оставляет ссылки, подходящие платформе, в порядке из шаблона и подставляет реквизиты.
+// Ссылки, для которых не хватает реквизитов (например, номер карты при оплате по телефону), пропускаются.
+// На Android собственные схемы оборачиваются в intent:// с переходом в магазин приложений, если приложения нет.
+func SelectDeeplinks(schemes []domain.DeeplinkScheme, platform domain.Platform, values map[string]string, playStoreURL string) []domain.DeeplinkLink {
+	links := make([]domain.DeeplinkLink, 0, len(schemes))
+	for _, scheme := range schemes {
+		if !deeplinkFitsPlatform(scheme.OS, platform) {
+			continue
+		}
+		link, ok := fillDeeplink(scheme.Template, values)
+		if !ok {
+			continue
+		}
+		if platform == domain.PlatformAndroid && scheme.OS == domain.DeeplinkOSAndroid {
+			fallback := playStoreURL
+			if fallback == "" && scheme.AndroidPackage != "" {
+				fallback = "https://play.google.com/store/apps/details?id=" + url.QueryEscape(scheme.AndroidPackage)
+			}
+			link = androidIntentURL(link, scheme.AndroidPackage, fallback)
+		}
+		links = append(links, domain.DeeplinkLink{
+			Name: scheme.Name,
+			URL:  link,
+			OS:   scheme.OS,
+		})
+	}
+	return links
+}
+
+func deeplinkFitsPlatform(os string, platform domain.Platform) bool {
+	switch platform {
+	case domain.PlatformIOS:
+		return os == domain.DeeplinkOSIOS || os == domain.DeeplinkOSUniversal
+	case domain.PlatformAndroid:
+		return os == domain.DeeplinkOSAndroid || os == domain.DeeplinkOSUniversal
+	case domain.PlatformDesktop:
+		// приложений банков на десктопе нет, остаются только веб-ссылки
+		return os == domain.DeeplinkOSUniversal
+	}
+	return true
+}
+
+func fillDeeplink(template string, values map[string]string) (string, bool) {
+	ok := true
+	link := deeplinkPlaceholder.ReplaceAllStringFunc(template, func(placeholder string) string {
+		value := values[strings.Trim(placeholder, "{}")]
+		if value == "" {
+			ok = false
+		}
+		return url.QueryEscape(value)
+	})
+	return link, ok
+}
+
+// androidIntentURL превращает scheme://path в intent://path#Intent;scheme=...;end
+func androidIntentURL(link, androidPackage, fallbackURL string) string {
+	scheme, rest, found := strings.Cut(link, "://")
+	if !found || scheme == "http" || scheme == "https" || scheme == "intent" {
+		return link
+	}
+
+	var b strings.Builder
+	b.WriteString("intent://")
+	b.WriteString(rest)
+	b.WriteString("#Intent;scheme=")
+	b.WriteString(scheme)
+	if androidPackage != "" {
+		b.WriteString(";package=")
+		b.WriteString(androidPackage)
+	}
+	if fallbackURL != "" {
+		b.WriteString(";S.browser_fallback_url=")
+		b.WriteString(url.QueryEscape(fallbackURL))
+	}
+	b.WriteString(";end")
+	return b.String()
+}
@@ -0,0 +1,227 @@
+package service
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/LavaJover/shvark-api-gateway/internal/domain"
+)
+
+func TestDetectPlatform(t *testing.T) {
+	tests := []struct {
+		name         string
+		userAgent    string
+		platformHint string
+		mobileHint   string
+		want         domain.Platform
+	}{
+		{
+			name:      "iPhone Safari",
+			userAgent: "Mozilla/5.0 (iPhone; CPU iPhone OS 17_4 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/17.4 Mobile/15E148 Safari/604.1",
+			want:      domain.PlatformIOS,
+		},
+		{
+			name:      "iPhone Chrome",
+			userAgent: "Mozilla/5.0 (iPhone; CPU iPhone OS 17_4 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) CriOS/123.0.6312.52 Mobile/15E148 Safari/604.1",
+			want:      domain.PlatformIOS,
+		},
+		{
+			name:      "iPhone Instagram in-app browser",
+			userAgent: "Mozilla/5.0 (iPhone; CPU iPhone OS 17_3_1 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Mobile/15E148 Instagram 321.0.2.10.105 (iPhone14,5; iOS 17_3_1; ru_RU; ru; scale=3.00; 1170x2532; 583226797)",
+			want:      domain.PlatformIOS,
+		},
+		{
+			name:      "iPad iOS 12 Safari",
+			userAgent: "Mozilla/5.0 (iPad; CPU OS 12_5_7 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/12.1.2 Mobile/15E148 Safari/604.1",
+			want:      domain.PlatformIOS,
+		},
+		{
+			name:      "iPadOS 17 Safari",
+			userAgent: "Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/17.4 Mobile/15E148 Safari/604.1",
+			want:      domain.PlatformIOS,
+		},
+		{
+			name:      "iPadOS in-app WebView",
+			userAgent: "Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) AppleWebKit/605.1.15 (KHTML, like Gecko) Mobile/15E148",
+			want:      domain.PlatformIOS,
+		},
+		{
+			name:      "macOS Safari",
+			userAgent: "Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/17.4 Safari/605.1.15",
+			want:      domain.PlatformDesktop,
+		},
+		{
+			name:         "macOS Chrome",
+			userAgent:    "Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/123.0.0.0 Safari/537.36",
+			platformHint: `"macOS"`,
+			mobileHint:   "?0",
+			want:         domain.PlatformDesktop,
+		},
+		{
+			name:      "Android Chrome with reduced User-Agent",
+			userAgent: "Mozilla/5.0 (Linux; Android 10; K) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/123.0.0.0 Mobile Safari/537.36",
+			want:      domain.PlatformAndroid,
+		},
+		{
+			name:         "Android Chrome in desktop mode",
+			userAgent:    "Mozilla/5.0 (X11; Linux x86_64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/123.0.0.0 Safari/537.36",
+			platformHint: `"Android"`,
+			mobileHint:   "?0",
+			want:         domain.PlatformAndroid,
+		},
+		{
+			name:      "Samsung Internet",
+			userAgent: "Mozilla/5.0 (Linux; Android 14; SM-S918B) AppleWebKit/537.36 (KHTML, like Gecko) SamsungBrowser/24.0 Chrome/117.0.0.0 Mobile Safari/537.36",
+			want:      domain.PlatformAndroid,
+		},
+		{
+			name:      "Yandex Browser on Android",
+			userAgent: "Mozilla/5.0 (Linux; arm_64; Android 13; SM-A525F) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/122.0.6261.128 YaBrowser/24.4.1.80.00 SA/3 Mobile Safari/537.36",
+			want:      domain.PlatformAndroid,
+		},
+		{
+			name:      "Firefox on Android",
+			userAgent: "Mozilla/5.0 (Android 14; Mobile; rv:124.0) Gecko/124.0 Firefox/124.0",
+			want:      domain.PlatformAndroid,
+		},
+		{
+			name:      "Android WebView",
+			userAgent: "Mozilla/5.0 (Linux; Android 13; Pixel 7 Build/TQ3A.230901.001; wv) AppleWebKit/537.36 (KHTML, like Gecko) Version/4.0 Chrome/123.0.6312.40 Mobile Safari/537.36",
+			want:      domain.PlatformAndroid,
+		},
+		{
+			name:         "Windows Chrome",
+			userAgent:    "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/123.0.0.0 Safari/537.36",
+			platformHint: `"Windows"`,
+			mobileHint:   "?0",
+			want:         domain.PlatformDesktop,
+		},
+		{
+			name:      "Windows Edge without hints",
+			userAgent: "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/123.0.0.0 Safari/537.36 Edg/123.0.2420.65",
+			want:      domain.PlatformDesktop,
+		},
+		{
+			name:      "Linux Firefox",
+			userAgent: "Mozilla/5.0 (X11; Linux x86_64; rv:124.0) Gecko/20100101 Firefox/124.0",
+			want:      domain.PlatformDesktop,
+		},
+		{
+			name:         "ChromeOS",
+			userAgent:    "Mozilla/5.0 (X11; CrOS x86_64 14541.0.0) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/123.0.0.0 Safari/537.36",
+			platformHint: `"Chrome OS"`,
+			mobileHint:   "?0",
+			want:         domain.PlatformDesktop,
+		},
+		{
+			name:      "Windows Phone",
+			userAgent: "Mozilla/5.0 (Windows Phone 10.0; Android 6.0.1; Microsoft; Lumia 950) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/52.0.2743.116 Mobile Safari/537.36 Edge/15.14977",
+			want:      domain.PlatformUnknown,
+		},
+		{
+			name:      "curl",
+			userAgent: "curl/8.4.0",
+			want:      domain.PlatformUnknown,
+		},
+		{
+			name: "no User-Agent",
+			want: domain.PlatformUnknown,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := DetectPlatform(tt.userAgent, tt.platformHint, tt.mobileHint); got != tt.want {
+				t.Errorf("DetectPlatform() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSelectDeeplinks(t *testing.T) {
+	schemes := []domain.DeeplinkScheme{
+		{Name: "bank-ios", Template: "bank://pay?card={cardNumber}&amount={amount}", OS: domain.DeeplinkOSIOS},
+		{Name: "bank-android", Template: "bank://pay?card={cardNumber}&amount={amount}", OS: domain.DeeplinkOSAndroid, AndroidPackage: "ru.bank.app"},
+		{Name: "bank-phone", Template: "bank://sbp?phone={phoneNumber}", OS: domain.DeeplinkOSAndroid},
+		{Name: "bank-web", Template: "https://bank.ru/pay?amount={amount}", OS: domain.DeeplinkOSUniversal},
+	}
+	values := map[string]string{"cardNumber": "2200 0000", "amount": "1500"}
+
+	tests := []struct {
+		name         string
+		platform     domain.Platform
+		playStoreURL string
+		want         []domain.DeeplinkLink
+	}{
+		{
+			name:     "iOS gets app and universal links",
+			platform: domain.PlatformIOS,
+			want: []domain.DeeplinkLink{
+				{Name: "bank-ios", URL: "bank://pay?card=2200+0000&amount=1500", OS: domain.DeeplinkOSIOS},
+				{Name: "bank-web", URL: "https://bank.ru/pay?amount=1500", OS: domain.DeeplinkOSUniversal},
+			},
+		},
+		{
+			name:         "Android gets intent with store fallback",
+			platform:     domain.PlatformAndroid,
+			playStoreURL: "https://play.google.com/store/apps/details?id=ru.bank.app",
+			want: []domain.DeeplinkLink{
+				{
+					Name: "bank-android",
+					URL:  "intent://pay?card=2200+0000&amount=1500#Intent;scheme=bank;package=ru.bank.app;S.browser_fallback_url=https%3A%2F%2Fplay.google.com%2Fstore%2Fapps%2Fdetails%3Fid%3Dru.bank.app;end",
+					OS:   domain.DeeplinkOSAndroid,
+				},
+				{Name: "bank-web", URL: "https://bank.ru/pay?amount=1500", OS: domain.DeeplinkOSUniversal},
+			},
+		},
+		{
+			name:     "Android fallback is built from package",
+			platform: domain.PlatformAndroid,
+			want: []domain.DeeplinkLink{
+				{
+					Name: "bank-android",
+					URL:  "intent://pay?card=2200+0000&amount=1500#Intent;scheme=bank;package=ru.bank.app;S.browser_fallback_url=https%3A%2F%2Fplay.google.com%2Fstore%2Fapps%2Fdetails%3Fid%3Dru.bank.app;end",
+					OS:   domain.DeeplinkOSAndroid,
+				},
+				{Name: "bank-web", URL: "https://bank.ru/pay?amount=1500", OS: domain.DeeplinkOSUniversal},
+			},
+		},
+		{
+			name:     "desktop gets only universal links",
+			platform: domain.PlatformDesktop,
+			want: []domain.DeeplinkLink{
+				{Name: "bank-web", URL: "https://bank.ru/pay?amount=1500", OS: domain.DeeplinkOSUniversal},
+			},
+		},
+		{
+			name:     "unknown platform gets every link as is",
+			platform: domain.PlatformUnknown,
+			want: []domain.DeeplinkLink{
+				{Name: "bank-ios", URL: "bank://pay?card=2200+0000&amount=1500", OS: domain.DeeplinkOSIOS},
+				{Name: "bank-android", URL: "bank://pay?card=2200+0000&amount=1500", OS: domain.DeeplinkOSAndroid},
+				{Name: "bank-web", URL: "https://bank.ru/pay?amount=1500", OS: domain.DeeplinkOSUniversal},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := SelectDeeplinks(schemes, tt.platform, values, tt.playStoreURL)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("SelectDeeplinks() =\n%+v\nwant\n%+v", got, tt.want)
+			}
+		})
+	}
+}
+
+// Платформа из User-Agent iPad должна давать те же ссылки, что и iPhone
+func TestSelectDeeplinksForIPadOS(t *testing.T) {
+	schemes := []domain.DeeplinkScheme{
+		{Name: "bank-ios", Template: "bank://pay?amount={amount}", OS: domain.DeeplinkOSIOS},
+		{Name: "bank-android", Template: "bank://pay?amount={amount}", OS: domain.DeeplinkOSAndroid},
+	}
+	platform := DetectPlatform("Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/17.4 Mobile/15E148 Safari/604.1", "", "")
+
+	got := SelectDeeplinks(schemes, platform, map[string]string{"amount": "100"}, "")
+	if len(got) != 1 || got[0].Name != "bank-ios" {
+		t.Fatalf("SelectDeeplinks() = %+v, want only bank-ios", got)
+	}
+}
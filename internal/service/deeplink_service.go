@@ -2,48 +2,85 @@ package service
 
 import (
     "bytes"
+    "errors"
     "fmt"
     "html/template"
     "log"
+    "net/url"
     "time"
 
     "github.com/LavaJover/shvark-api-gateway/internal/client"
     "github.com/LavaJover/shvark-api-gateway/internal/domain"
     "github.com/LavaJover/shvark-api-gateway/internal/service/deeplink_templates"
+    "github.com/LavaJover/shvark-api-gateway/internal/service/i18n"
     orderpb "github.com/LavaJover/shvark-order-service/proto/gen/order"
 )
 
+// ErrRequisitesNotOnDemand полные реквизиты по запросу отдаются только для шаблонов с политикой on_demand:
+// при masked клиент не должен видеть их вовсе, а при full они уже есть на странице
+var ErrRequisitesNotOnDemand = errors.New("requisites can not be revealed for this bank page")
+
 type DeeplinkService struct {
     orderClient *client.OrderClient
+    templates   *deeplink_templates.Store
+    orderTokens *OrderTokenService
+    catalog     *i18n.Catalog
+    locales     i18n.MerchantLocales
 }
 
-func NewDeeplinkService(orderClient *client.OrderClient) *DeeplinkService {
+func NewDeeplinkService(
+    orderClient *client.OrderClient,
+    templates *deeplink_templates.Store,
+    orderTokens *OrderTokenService,
+    catalog *i18n.Catalog,
+    locales i18n.MerchantLocales,
+) *DeeplinkService {
     return &DeeplinkService{
         orderClient: orderClient,
+        templates:   templates,
+        orderTokens: orderTokens,
+        catalog:     catalog,
+        locales:     locales,
     }
 }
 
-// GenerateBankSelectionPage генерирует страницу выбора банков
-func (ds *DeeplinkService) GenerateBankSelectionPage(orderID string) (*domain.DeeplinkData, error) {
+// SelectionURL ссылка на страницу выбора банка с токеном, который живет столько же, сколько ордер
+func (ds *DeeplinkService) SelectionURL(orderID string, expiresAt time.Time) string {
+    token := ds.issueToken(orderID, expiresAt)
+    return fmt.Sprintf("/api/v1/payments/deeplink/select?order_id=%s&token=%s", url.QueryEscape(orderID), url.QueryEscape(token))
+}
+
+// SpecificURL ссылка на страницу конкретного банка с токеном ордера
+func (ds *DeeplinkService) SpecificURL(orderID, bankCode string, expiresAt time.Time) string {
+    query := url.Values{}
+    query.Set("order_id", orderID)
+    query.Set("bank", bankCode)
+    query.Set("token", ds.issueToken(orderID, expiresAt))
+    return "/api/v1/payments/deeplink/specific?" + query.Encode()
+}
+
+func (ds *DeeplinkService) issueToken(orderID string, expiresAt time.Time) string {
+    if expiresAt.IsZero() {
+        expiresAt = time.Now().Add(ds.orderTokens.TTL())
+    }
+    return ds.orderTokens.Issue(OrderTokenScopeDeeplink, orderID, expiresAt)
+}
+
+// GenerateBankSelectionPage генерирует страницу выбора банков. Реквизиты на ней всегда замаскированы,
+// token и выбранный язык передаются дальше в ссылки на страницы банков.
+// languages - языки клиента в порядке приоритета, после них идет язык мерчанта по умолчанию.
+func (ds *DeeplinkService) GenerateBankSelectionPage(orderID, token string, languages []string) (*domain.DeeplinkData, error) {
     order, err := ds.orderClient.GetOrderByID(orderID)
     if err != nil {
         return nil, fmt.Errorf("failed to get order: %w", err)
     }
 
-    paymentSystem := "all"
-    if order.Order.BankDetail != nil {
-        paymentSystem = order.Order.BankDetail.PaymentSystem
-    }
+    paymentSystem, availableTemplates := ds.eligibleTemplates(order)
 
-    // Получаем доступные шаблоны для платежной системы
-    availableTemplates := deeplink_templates.GetTemplatesForSystem(paymentSystem)
-    
-    // Если нет специфичных шаблонов, используем все
-    if len(availableTemplates) == 0 {
-        availableTemplates = deeplink_templates.GetAllTemplates()
-    }
-
-    templateData := ds.prepareTemplateData(order, nil, "bank_selection")
+    templateData := ds.prepareTemplateData(order, nil, languages)
+    maskRequisites(templateData)
+    templateData["Token"] = token
+    templateData["BeaconURL"] = beaconURL(orderID, token)
     templateData["AvailableBanks"] = availableTemplates
     templateData["PaymentSystem"] = paymentSystem
 
@@ -57,24 +94,47 @@ func (ds *DeeplinkService) GenerateBankSelectionPage(orderID string) (*domain.De
         DeeplinkType: "bank_selection",
         BankCode:     "multiple",
         OrderID:      orderID,
+        Locale:       templateData["Locale"].(string),
     }, nil
 }
 
-// GenerateSpecificDeeplink генерирует диплинк для конкретного банка
-func (ds *DeeplinkService) GenerateSpecificDeeplink(orderID, bankCode string, phoneNumber *string) (*domain.DeeplinkData, error) {
+// GenerateSpecificDeeplink генерирует диплинк для конкретного банка.
+// На страницу попадают только ссылки для платформы клиента в порядке приоритета из шаблона,
+// полнота реквизитов определяется политикой шаблона.
+func (ds *DeeplinkService) GenerateSpecificDeeplink(orderID, bankCode string, phoneNumber *string, platform domain.Platform, token string, languages []string) (*domain.DeeplinkData, error) {
     order, err := ds.orderClient.GetOrderByID(orderID)
     if err != nil {
         return nil, fmt.Errorf("failed to get order: %w", err)
     }
 
     // Получаем конфигурацию шаблона
-    templateConfig, exists := deeplink_templates.BankTemplates[bankCode]
+    templateConfig, exists := ds.templates.GetTemplate(bankCode)
     if !exists {
         return nil, fmt.Errorf("bank template not found: %s", bankCode)
     }
 
-    templateData := ds.prepareTemplateData(order, phoneNumber, bankCode)
-    htmlContent, err := ds.renderSpecificTemplate(templateConfig.Template, templateData)
+    templateData := ds.prepareTemplateData(order, phoneNumber, languages)
+    templateData["Platform"] = platform
+    templateData["Token"] = token
+    templateData["BankCode"] = bankCode
+    templateData["BeaconURL"] = beaconURL(orderID, token)
+    templateData["Deeplinks"] = SelectDeeplinks(
+        templateConfig.Links,
+        platform,
+        deeplink_templates.LinkValues(templateData),
+        templateConfig.PlayStoreURL,
+    )
+
+    switch templateConfig.RequisitesPolicy {
+    case deeplink_templates.RequisitesMasked:
+        maskRequisites(templateData)
+    case deeplink_templates.RequisitesOnDemand:
+        maskRequisites(templateData)
+        templateData["Deeplinks"] = []domain.DeeplinkLink{}
+        templateData["RevealURL"] = revealURL(orderID, bankCode, phoneNumber, platform, token)
+    }
+
+    htmlContent, err := templateConfig.Render(templateData)
     if err != nil {
         return nil, fmt.Errorf("failed to render template for bank %s: %w", bankCode, err)
     }
@@ -84,28 +144,169 @@ func (ds *DeeplinkService) GenerateSpecificDeeplink(orderID, bankCode string, ph
         DeeplinkType: bankCode,
         BankCode:     bankCode,
         OrderID:      orderID,
+        Platform:     platform,
+        Locale:       templateData["Locale"].(string),
     }, nil
 }
 
-func (ds *DeeplinkService) prepareTemplateData(order *orderpb.GetOrderByIDResponse, phoneNumber *string, deeplinkType string) map[string]interface{} {
+// GenerateDeeplinkOptions собирает для нативных приложений мерчантов банки, подходящие ордеру,
+// и для каждого банка ссылки на приложение по платформам в порядке приоритета.
+// Ссылки строятся из тех же шаблонов, что и страницы; банки без единой ссылки пропускаются.
+func (ds *DeeplinkService) GenerateDeeplinkOptions(orderID string, phoneNumber *string, platforms []domain.Platform) (*domain.DeeplinkOptions, error) {
+    order, err := ds.orderClient.GetOrderByID(orderID)
+    if err != nil {
+        return nil, fmt.Errorf("failed to get order: %w", err)
+    }
+
+    paymentSystem, availableTemplates := ds.eligibleTemplates(order)
+    templateData := ds.prepareTemplateData(order, phoneNumber, nil)
+    values := deeplink_templates.LinkValues(templateData)
+
+    var expiresAt time.Time
+    if order.Order.ExpiresAt != nil {
+        expiresAt = order.Order.ExpiresAt.AsTime()
+    }
+
+    options := &domain.DeeplinkOptions{
+        OrderID:       orderID,
+        Amount:        templateData["Amount"].(string),
+        Currency:      templateData["Currency"].(string),
+        PaymentSystem: paymentSystem,
+        Banks:         make([]domain.DeeplinkBankOption, 0, len(availableTemplates)),
+    }
+    for _, templateConfig := range availableTemplates {
+        bank := domain.DeeplinkBankOption{
+            BankCode:     templateConfig.BankCode,
+            BankName:     templateConfig.BankName,
+            Icon:         templateConfig.Icon,
+            TransferType: templateConfig.TransferType,
+            AppStoreURL:  templateConfig.AppStoreURL,
+            PlayStoreURL: templateConfig.PlayStoreURL,
+            Platforms:    make(map[domain.Platform][]domain.DeeplinkLink, len(platforms)),
+        }
+        hasLinks := false
+        for _, platform := range platforms {
+            links := SelectDeeplinks(templateConfig.Links, platform, values, templateConfig.PlayStoreURL)
+            bank.Platforms[platform] = links
+            hasLinks = hasLinks || len(links) > 0
+        }
+        if !hasLinks {
+            continue
+        }
+        bank.PageURL = ds.SpecificURL(orderID, templateConfig.BankCode, expiresAt)
+        options.Banks = append(options.Banks, bank)
+    }
+    return options, nil
+}
+
+// eligibleTemplates шаблоны банков для платежной системы ордера, если подходящих нет - все
+func (ds *DeeplinkService) eligibleTemplates(order *orderpb.GetOrderByIDResponse) (string, []deeplink_templates.BankTemplateConfig) {
+    paymentSystem := "all"
+    if order.Order.BankDetail != nil {
+        paymentSystem = order.Order.BankDetail.PaymentSystem
+    }
+
+    availableTemplates := ds.templates.GetTemplatesForSystem(paymentSystem)
+    if len(availableTemplates) == 0 {
+        availableTemplates = ds.templates.GetAllTemplates()
+    }
+    return paymentSystem, availableTemplates
+}
+
+// RevealRequisites отдает полные реквизиты и ссылки на приложение по явному действию клиента
+// на странице банка с политикой on_demand
+func (ds *DeeplinkService) RevealRequisites(orderID, bankCode string, phoneNumber *string, platform domain.Platform) (*domain.DeeplinkRequisites, error) {
+    templateConfig, exists := ds.templates.GetTemplate(bankCode)
+    if !exists {
+        return nil, fmt.Errorf("bank template not found: %s", bankCode)
+    }
+    if templateConfig.RequisitesPolicy != deeplink_templates.RequisitesOnDemand {
+        return nil, ErrRequisitesNotOnDemand
+    }
+
+    order, err := ds.orderClient.GetOrderByID(orderID)
+    if err != nil {
+        return nil, fmt.Errorf("failed to get order: %w", err)
+    }
+
+    templateData := ds.prepareTemplateData(order, phoneNumber, nil)
+    log.Printf("Requisites revealed for order %s, bank %s", orderID, bankCode)
+
+    return &domain.DeeplinkRequisites{
+        CardNumber:  templateData["CardNumber"].(string),
+        PhoneNumber: templateData["PhoneNumber"].(string),
+        Deeplinks: SelectDeeplinks(
+            templateConfig.Links,
+            platform,
+            deeplink_templates.LinkValues(templateData),
+            templateConfig.PlayStoreURL,
+        ),
+    }, nil
+}
+
+func revealURL(orderID, bankCode string, phoneNumber *string, platform domain.Platform, token string) string {
+    query := url.Values{}
+    query.Set("order_id", orderID)
+    query.Set("bank", bankCode)
+    query.Set("platform", string(platform))
+    query.Set("token", token)
+    if phoneNumber != nil {
+        query.Set("phone", *phoneNumber)
+    }
+    return "/api/v1/payments/deeplink/requisites?" + query.Encode()
+}
+
+// beaconURL адрес, на который страницы отправляют события воронки
+func beaconURL(orderID, token string) string {
+    query := url.Values{}
+    query.Set("order_id", orderID)
+    query.Set("token", token)
+    return "/api/v1/payments/deeplink/events?" + query.Encode()
+}
+
+// maskRequisites заменяет реквизиты для отображения на замаскированные
+func maskRequisites(data map[string]interface{}) {
+    data["CardNumber"] = data["MaskedCardNumber"]
+    data["PhoneNumber"] = data["MaskedPhoneNumber"]
+}
+
+func (ds *DeeplinkService) prepareTemplateData(order *orderpb.GetOrderByIDResponse, phoneNumber *string, languages []string) map[string]interface{} {
+    // язык: параметр lang и Accept-Language клиента, затем язык мерчанта по умолчанию
+    candidates := append(append([]string{}, languages...), ds.locales.For(order.Order.MerchantId))
+    locale := ds.catalog.Negotiate(candidates...)
+    localizer := ds.catalog.Localizer(locale)
+
+    currency := "RUB"
+    if order.Order.BankDetail != nil && order.Order.BankDetail.Currency != "" {
+        currency = order.Order.BankDetail.Currency
+    }
+
     data := map[string]interface{}{
-        "Amount":        fmt.Sprintf("%.2f", order.Order.AmountFiat),
+        "Amount":        fmt.Sprintf("%.2f", order.Order.AmountFiat), // для подстановки в ссылки на приложения
+        "AmountFormatted": localizer.Amount(order.Order.AmountFiat, currency),
+        "Currency":      currency,
+        "Locale":        locale,
+        "L":             localizer,
         "OrderID":       order.Order.OrderId,
         "PhoneNumber":   "",
         "Timestamp":     time.Now().Format("2006-01-02 15:04:05"),
         "PaymentSystem": "",
         "CardNumber":    "",
-        "MaskedCardNumber": "", // Теперь тоже будет содержать полный номер
+        "MaskedCardNumber": "",
+        "MaskedPhoneNumber": "",
+        "RevealURL":     "",
+        "BankCode":      "",
+        "BeaconURL":     "",
     }
 
     if order.Order.BankDetail != nil {
         data["PaymentSystem"] = order.Order.BankDetail.PaymentSystem
         
-        // Обрабатываем номер карты - БЕЗ МАСКИРОВКИ
+        // Полный номер показывается или скрывается политикой шаблона, маска есть всегда
         if order.Order.BankDetail.CardNumber != "" {
             cardNumber := order.Order.BankDetail.CardNumber
             data["CardNumber"] = cardNumber
-            data["MaskedCardNumber"] = cardNumber // Убираем маскировку - показываем полный номер
+            data["MaskedCardNumber"] = MaskCardNumber(cardNumber)
         }
         
         // Обрабатываем телефон
@@ -117,21 +318,22 @@ func (ds *DeeplinkService) prepareTemplateData(order *orderpb.GetOrderByIDRespon
     if phoneNumber != nil {
         data["PhoneNumber"] = *phoneNumber
     }
+    data["MaskedPhoneNumber"] = MaskPhone(data["PhoneNumber"].(string))
 
-    log.Printf("Template data prepared - Card: %s, Phone: %s, Amount: %s", 
-        data["CardNumber"], data["PhoneNumber"], data["Amount"])
+    log.Printf("Template data prepared - Card: %s, Phone: %s, Amount: %s",
+        data["MaskedCardNumber"], data["MaskedPhoneNumber"], data["Amount"])
 
     return data
 }
 
-func (ds *DeeplinkService) renderBankSelectionTemplate(data map[string]interface{}) (string, error) {
-    tmpl := template.Must(template.New("bank_selection").Parse(`
+// bankSelectionTemplate разбирается один раз при старте, а не на каждый запрос
+var bankSelectionTemplate = template.Must(template.New("bank_selection").Parse(`
 <!DOCTYPE html>
-<html lang="ru">
+<html lang="{{.Locale}}">
 <head>
     <meta charset="UTF-8">
     <meta name="viewport" content="width=device-width, initial-scale=1.0">
-    <title>Выберите способ оплаты</title>
+    <title>{{.L.T "selection.title"}}</title>
     <style>
         body { font-family: Arial, sans-serif; max-width: 800px; margin: 0 auto; padding: 20px; background: linear-gradient(135deg, #667eea 0%, #764ba2 100%); }
         .container { background: white; padding: 30px; border-radius: 15px; box-shadow: 0 10px 30px rgba(0,0,0,0.2); }
@@ -151,23 +353,23 @@ func (ds *DeeplinkService) renderBankSelectionTemplate(data map[string]interface
 <body>
     <div class="container">
         <div class="header">
-            <h1>🎯 Выберите способ оплаты</h1>
-            <p class="info-text">Выберите банк для быстрой оплаты через мобильное приложение</p>
+            <h1>🎯 {{.L.T "selection.title"}}</h1>
+            <p class="info-text">{{.L.T "selection.subtitle"}}</p>
         </div>
 
         <div class="payment-info">
-            <h3>Данные платежа:</h3>
+            <h3>{{.L.T "payment.details"}}</h3>
             {{if .MaskedCardNumber}}
-            <p><strong>Номер карты:</strong> {{.CardNumber}}</p>
+            <p><strong>{{.L.T "payment.card_number"}}</strong> {{.CardNumber}}</p>
             {{end}}
             {{if .PhoneNumber}}
-            <p><strong>Телефон:</strong> {{.PhoneNumber}}</p>
+            <p><strong>{{.L.T "payment.phone"}}</strong> {{.PhoneNumber}}</p>
             {{end}}
-            <p class="amount">{{.Amount}} ₽</p>
-            <p class="info-text">Order ID: {{.OrderID}}</p>
+            <p class="amount">{{.AmountFormatted}}</p>
+            <p class="info-text">{{.L.T "payment.order_id"}} {{.OrderID}}</p>
             {{if eq .PaymentSystem "C2C"}}
             <p class="info-text" style="color: #28a745; margin-top: 10px;">
-                💡 <strong>Рекомендуется Tinkoff</strong> - оптимальный выбор для C2C переводов
+                💡 <strong>{{.L.T "selection.recommended_title"}}</strong> - {{.L.T "selection.recommended_text"}}
             </p>
             {{end}}
         </div>
@@ -175,46 +377,54 @@ func (ds *DeeplinkService) renderBankSelectionTemplate(data map[string]interface
         <div class="bank-grid">
             {{range .AvailableBanks}}
             <div class="bank-card {{if and (eq .BankCode "tinkoff_card") (eq $.PaymentSystem "C2C")}}recommended{{end}}" onclick="selectBank('{{.BankCode}}')">
-                <div class="bank-icon">
-                    {{if eq .BankCode "sberbank"}}🏦
-                    {{else if eq .BankCode "tinkoff_card"}}💳
-                    {{else if eq .BankCode "tinkoff_phone"}}📱
-                    {{else if eq .BankCode "vtb"}}🔵
-                    {{else}}🏦{{end}}
-                </div>
+                <div class="bank-icon">{{if .Icon}}{{.Icon}}{{else}}🏦{{end}}</div>
                 <div class="bank-name">
                     {{.BankName}}
                     {{if and (eq .BankCode "tinkoff_card") (eq $.PaymentSystem "C2C")}}
-                    <span class="recommended-badge">рекомендуется</span>
+                    <span class="recommended-badge">{{$.L.T "selection.recommended_badge"}}</span>
                     {{end}}
                 </div>
-                <div class="info-text">Нажмите для оплаты</div>
+                <div class="info-text">{{$.L.T "selection.tap_to_pay"}}</div>
             </div>
             {{end}}
         </div>
 
         <div style="text-align: center; margin-top: 20px;">
-            <p class="info-text">После выбора банка откроется приложение для завершения платежа</p>
+            <p class="info-text">{{.L.T "selection.footer"}}</p>
         </div>
     </div>
 
     <script>
+        const beaconURL = {{.BeaconURL}};
+
+        // События воронки уходят через sendBeacon, чтобы не теряться при переходе на другую страницу
+        function track(type, bankCode) {
+            if (!beaconURL || !navigator.sendBeacon) {
+                return;
+            }
+            navigator.sendBeacon(beaconURL, JSON.stringify({ type: type, bank_code: bankCode || '' }));
+        }
+
+        track('page_viewed');
+
         function selectBank(bankCode) {
+            track('bank_chosen', bankCode);
+
             // Показываем индикатор загрузки
             const card = event.currentTarget;
             const originalContent = card.innerHTML;
             card.style.background = '#f8f9fa';
-            card.innerHTML = '<div style="padding: 20px;">⏳ Загрузка...</div>';
+            card.innerHTML = '<div style="padding: 20px;">⏳ {{.L.T "common.loading"}}</div>';
             
             // Перенаправляем на конкретный диплинк
-            window.location.href = '/api/v1/payments/deeplink/specific?order_id={{.OrderID}}&bank=' + bankCode;
+            window.location.href = '/api/v1/payments/deeplink/specific?order_id={{.OrderID}}&bank=' + bankCode + '&token={{.Token}}&lang={{.Locale}}';
             
             // В случае ошибки возвращаем оригинальный контент
             setTimeout(() => {
                 if (!document.hidden) {
                     card.innerHTML = originalContent;
                     card.style.background = 'white';
-                    alert('Не удалось открыть приложение. Убедитесь, что приложение банка установлено.');
+                    alert({{.L.T "selection.open_failed"}});
                 }
             }, 3000);
         }
@@ -225,16 +435,9 @@ func (ds *DeeplinkService) renderBankSelectionTemplate(data map[string]interface
 </html>
 `))
 
+func (ds *DeeplinkService) renderBankSelectionTemplate(data map[string]interface{}) (string, error) {
     var buf bytes.Buffer
-    if err := tmpl.Execute(&buf, data); err != nil {
-        return "", err
-    }
-    return buf.String(), nil
-}
-
-func (ds *DeeplinkService) renderSpecificTemplate(tmpl *template.Template, data map[string]interface{}) (string, error) {
-    var buf bytes.Buffer
-    if err := tmpl.Execute(&buf, data); err != nil {
+    if err := bankSelectionTemplate.Execute(&buf, data); err != nil {
         return "", err
     }
     return buf.String(), nil
@@ -0,0 +1,674 @@
+package deeplink_templates
+
+import (
+	"context"
+	"embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html/template"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/LavaJover/shvark-api-gateway/internal/domain"
+	"github.com/LavaJover/shvark-api-gateway/internal/storage"
+)
+
+// Встроенные шаблоны: по одной текущей версии на банк. builtin/<bank_code>.json - метаданные,
+// builtin/page.html - общее для всех банков тело страницы.
+//
+//go:embed builtin
+var builtinFS embed.FS
+
+const (
+	activeFileName  = "active"
+	builtinFileName = "builtin"
+	builtinBodyName = "page.html"
+)
+
+// legacyBuiltinRevisions ревизии встроенных шаблонов, которые копировались в каталог до появления
+// builtin_revision. Время создания у них фиксированное, у версий из админки - момент сохранения.
+var legacyBuiltinRevisions = map[string]int{
+	"2025-01-01T00:00:00Z": 1,
+	"2025-02-01T00:00:00Z": 2,
+	"2025-03-01T00:00:00Z": 3,
+	"2025-03-15T00:00:00Z": 4,
+	"2025-04-01T00:00:00Z": 5,
+}
+
+var (
+	ErrTemplateNotFound        = errors.New("deeplink template not found")
+	ErrTemplateVersionNotFound = errors.New("deeplink template version not found")
+	ErrActiveVersion           = errors.New("active template version can not be deleted")
+	ErrStoreReadOnly           = errors.New("deeplink templates directory is not configured")
+)
+
+var (
+	bankCodePattern    = regexp.MustCompile(`^[a-z0-9_]{2,32}$`)
+	transferTypes      = map[string]bool{"card": true, "phone": true, "both": true}
+	requisitesPolicies = map[string]bool{
+		"":                 true,
+		RequisitesFull:     true,
+		RequisitesMasked:   true,
+		RequisitesOnDemand: true,
+	}
+)
+
+// ValidationError список проблем, найденных в шаблоне
+type ValidationError struct {
+	Problems []string
+}
+
+func (e *ValidationError) Error() string {
+	return "invalid deeplink template: " + strings.Join(e.Problems, "; ")
+}
+
+// Store хранит шаблоны диплинков в каталоге вида
+//
+//	<dir>/<bank_code>/v<N>.html  - тело шаблона
+//	<dir>/<bank_code>/v<N>.json  - метаданные (TemplateMeta)
+//	<dir>/<bank_code>/active     - номер активной версии
+//	<dir>/<bank_code>/builtin    - ревизия встроенного шаблона, уже перенесенная в каталог
+//
+// Читатели работают с неизменяемым снимком, перезагрузка подменяет его атомарно
+// и только если все активные шаблоны разобрались без ошибок.
+type Store struct {
+	dir   string
+	banks BankCatalog
+
+	mu           sync.Mutex
+	lastModified time.Time
+	current      atomic.Pointer[registry]
+}
+
+// BankCatalog справочник банков, по нему шаблоны подбираются к платежной системе
+type BankCatalog interface {
+	TemplateCodes(paymentSystem string) []string
+}
+
+// NewStore загружает шаблоны из dir. Встроенные шаблоны переносятся в каталог при первом запуске
+// и при обновлении их ревизии (см. seedDir).
+// Пустой dir означает работу только со встроенными шаблонами без возможности изменения.
+// Без справочника банков шаблоны подбираются к платежной системе по supported_systems из метаданных.
+// Store nil только если не загрузились встроенные шаблоны; при ошибке каталога возвращается
+// рабочий Store со встроенными шаблонами вместе с ошибкой.
+func NewStore(dir string, banks BankCatalog) (*Store, error) {
+	s := &Store{dir: dir, banks: banks}
+
+	builtin, err := builtinRegistry()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load builtin deeplink templates: %w", err)
+	}
+	s.current.Store(builtin)
+
+	if dir == "" {
+		return s, nil
+	}
+	if err := seedDir(dir); err != nil {
+		return s, fmt.Errorf("failed to seed deeplink templates dir: %w", err)
+	}
+	if err := s.Reload(); err != nil {
+		return s, err
+	}
+	return s, nil
+}
+
+// Reload перечитывает каталог шаблонов. При ошибке остается прежний снимок.
+func (s *Store) Reload() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.reloadLocked()
+}
+
+func (s *Store) reloadLocked() error {
+	if s.dir == "" {
+		return ErrStoreReadOnly
+	}
+
+	modified, err := latestModTime(s.dir)
+	if err != nil {
+		return fmt.Errorf("failed to stat deeplink templates dir: %w", err)
+	}
+	reg, err := loadRegistry(os.DirFS(s.dir))
+	if err != nil {
+		return err
+	}
+	s.current.Store(reg)
+	s.lastModified = modified
+	log.Printf("deeplink templates: loaded %d active templates from %s", len(reg.active), s.dir)
+	return nil
+}
+
+// Watch перезагружает шаблоны при изменении файлов в каталоге, пока не отменен контекст
+func (s *Store) Watch(ctx context.Context, interval time.Duration) {
+	if s.dir == "" || interval <= 0 {
+		return
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			modified, err := latestModTime(s.dir)
+			if err != nil {
+				log.Printf("deeplink templates: failed to stat %s: %v", s.dir, err)
+				continue
+			}
+			s.mu.Lock()
+			if modified.After(s.lastModified) {
+				if err := s.reloadLocked(); err != nil {
+					log.Printf("deeplink templates: reload failed, keeping previous templates: %v", err)
+					// не пытаемся перечитывать тот же сломанный файл на каждом тике
+					s.lastModified = modified
+				}
+			}
+			s.mu.Unlock()
+		}
+	}
+}
+
+// Validate проверяет метаданные и тело шаблона, в том числе рендер на тестовых данных
+func Validate(meta TemplateMeta, body string) error {
+	var problems []string
+	if !bankCodePattern.MatchString(meta.BankCode) {
+		problems = append(problems, "bank_code must match "+bankCodePattern.String())
+	}
+	if strings.TrimSpace(meta.BankName) == "" {
+		problems = append(problems, "bank_name is required")
+	}
+	if len(meta.SupportedSystems) == 0 {
+		problems = append(problems, "supported_systems must not be empty")
+	}
+	if !transferTypes[meta.TransferType] {
+		problems = append(problems, "transfer_type must be one of card, phone, both")
+	}
+	if !requisitesPolicies[meta.RequisitesPolicy] {
+		problems = append(problems, "requisites_policy must be one of full, masked, on_demand")
+	}
+	if len(meta.Schemes) == 0 && len(meta.Links) == 0 {
+		problems = append(problems, "schemes or links must not be empty")
+	}
+	for _, scheme := range meta.Schemes {
+		if strings.TrimSpace(scheme) == "" {
+			problems = append(problems, "schemes must not contain empty values")
+			break
+		}
+	}
+	for i, link := range meta.Links {
+		problems = append(problems, validateLink(i, link)...)
+	}
+
+	if _, err := Preview(meta.BankCode, body, meta.Schemes, SampleData()); err != nil {
+		problems = append(problems, err.Error())
+	}
+
+	if len(problems) > 0 {
+		return &ValidationError{Problems: problems}
+	}
+	return nil
+}
+
+func validateLink(i int, link domain.DeeplinkScheme) []string {
+	var problems []string
+	prefix := fmt.Sprintf("links[%d]: ", i)
+	if strings.TrimSpace(link.Name) == "" {
+		problems = append(problems, prefix+"name is required")
+	}
+	scheme, _, found := strings.Cut(link.Template, "://")
+	if !found || scheme == "" {
+		problems = append(problems, prefix+"template must be an URL with scheme")
+	}
+	switch link.OS {
+	case domain.DeeplinkOSUniversal:
+		if scheme != "https" {
+			problems = append(problems, prefix+"universal link must use https")
+		}
+	case domain.DeeplinkOSIOS, domain.DeeplinkOSAndroid:
+	default:
+		problems = append(problems, prefix+"os must be one of ios, android, universal")
+	}
+	return problems
+}
+
+// Preview разбирает и рендерит произвольное тело шаблона без сохранения
+func Preview(bankCode, body string, schemes []string, data map[string]interface{}) (string, error) {
+	if strings.TrimSpace(body) == "" {
+		return "", errors.New("template body is required")
+	}
+	tmpl, err := template.New(bankCode).Parse(body)
+	if err != nil {
+		return "", fmt.Errorf("parse: %w", err)
+	}
+	html, err := render(tmpl, schemes, data)
+	if err != nil {
+		return "", fmt.Errorf("execute: %w", err)
+	}
+	return html, nil
+}
+
+// CreateVersion сохраняет новую неактивную версию шаблона банка
+func (s *Store) CreateVersion(meta TemplateMeta, body string) (TemplateVersion, error) {
+	if err := Validate(meta, body); err != nil {
+		return TemplateVersion{}, err
+	}
+	if s.dir == "" {
+		return TemplateVersion{}, ErrStoreReadOnly
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	bankDir := filepath.Join(s.dir, meta.BankCode)
+	if err := os.MkdirAll(bankDir, 0o755); err != nil {
+		return TemplateVersion{}, err
+	}
+	versions, err := versionNumbers(os.DirFS(s.dir), meta.BankCode)
+	if err != nil {
+		return TemplateVersion{}, err
+	}
+	next := 1
+	if len(versions) > 0 {
+		next = versions[len(versions)-1] + 1
+	}
+
+	meta.CreatedAt = time.Now().UTC()
+	// ревизию встроенного шаблона выставляет только seedDir, иначе версию из админки
+	// перезаписало бы следующее обновление
+	meta.BuiltinRevision = 0
+	metaJSON, err := json.MarshalIndent(meta, "", "  ")
+	if err != nil {
+		return TemplateVersion{}, err
+	}
+	// тело пишется первым: версия без метаданных при загрузке не видна
+	if err := storage.WriteFile(filepath.Join(bankDir, versionFileName(next, ".html")), []byte(body), 0o644); err != nil {
+		return TemplateVersion{}, err
+	}
+	if err := storage.WriteFile(filepath.Join(bankDir, versionFileName(next, ".json")), metaJSON, 0o644); err != nil {
+		return TemplateVersion{}, err
+	}
+	if err := s.reloadLocked(); err != nil {
+		return TemplateVersion{}, err
+	}
+
+	return TemplateVersion{TemplateMeta: meta, Version: next, Body: body}, nil
+}
+
+// Activate делает версию активной, страницы банка начинают отдаваться по ней сразу
+func (s *Store) Activate(bankCode string, version int) error {
+	if s.dir == "" {
+		return ErrStoreReadOnly
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	v, err := s.GetVersion(bankCode, version)
+	if err != nil {
+		return err
+	}
+	if err := Validate(v.TemplateMeta, v.Body); err != nil {
+		return err
+	}
+
+	path := filepath.Join(s.dir, bankCode, activeFileName)
+	if err := storage.WriteFile(path, []byte(strconv.Itoa(version)+"\n"), 0o644); err != nil {
+		return err
+	}
+	return s.reloadLocked()
+}
+
+// DeleteVersion удаляет неактивную версию шаблона
+func (s *Store) DeleteVersion(bankCode string, version int) error {
+	if s.dir == "" {
+		return ErrStoreReadOnly
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	v, err := s.GetVersion(bankCode, version)
+	if err != nil {
+		return err
+	}
+	if v.Active {
+		return ErrActiveVersion
+	}
+
+	bankDir := filepath.Join(s.dir, bankCode)
+	for _, ext := range []string{".json", ".html"} {
+		if err := os.Remove(filepath.Join(bankDir, versionFileName(version, ext))); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return err
+		}
+	}
+	return s.reloadLocked()
+}
+
+func loadRegistry(fsys fs.FS) (*registry, error) {
+	reg := newRegistry()
+
+	entries, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return nil, err
+	}
+	for _, entry := range entries {
+		bankCode := entry.Name()
+		if !entry.IsDir() || !bankCodePattern.MatchString(bankCode) {
+			continue
+		}
+
+		numbers, err := versionNumbers(fsys, bankCode)
+		if err != nil {
+			return nil, err
+		}
+		if len(numbers) == 0 {
+			continue
+		}
+
+		activeVersion, err := readActiveVersion(fsys, bankCode)
+		if err != nil {
+			return nil, err
+		}
+
+		versions := make([]TemplateVersion, 0, len(numbers))
+		for _, number := range numbers {
+			v, err := readVersion(fsys, bankCode, number)
+			if err != nil {
+				return nil, err
+			}
+			v.Active = number == activeVersion
+			versions = append(versions, v)
+		}
+		if err := reg.add(bankCode, versions); err != nil {
+			return nil, err
+		}
+		if activeVersion != 0 {
+			if _, ok := reg.active[bankCode]; !ok {
+				return nil, fmt.Errorf("%s: active version %d not found", bankCode, activeVersion)
+			}
+		}
+	}
+	return reg, nil
+}
+
+// builtinRegistry снимок из одних встроенных шаблонов, у каждого банка единственная активная версия 1
+func builtinRegistry() (*registry, error) {
+	templates, err := builtinTemplates()
+	if err != nil {
+		return nil, err
+	}
+	reg := newRegistry()
+	for _, t := range templates {
+		version := TemplateVersion{TemplateMeta: t.meta, Version: 1, Body: string(t.body), Active: true}
+		if err := reg.add(t.meta.BankCode, []TemplateVersion{version}); err != nil {
+			return nil, err
+		}
+	}
+	return reg, nil
+}
+
+func newRegistry() *registry {
+	return &registry{
+		active:   make(map[string]BankTemplateConfig),
+		versions: make(map[string][]TemplateVersion),
+	}
+}
+
+// add регистрирует версии банка и разбирает тело активной
+func (reg *registry) add(bankCode string, versions []TemplateVersion) error {
+	for _, v := range versions {
+		if !v.Active {
+			continue
+		}
+		tmpl, err := template.New(bankCode).Parse(v.Body)
+		if err != nil {
+			return fmt.Errorf("%s v%d: %w", bankCode, v.Version, err)
+		}
+		reg.active[bankCode] = BankTemplateConfig{
+			BankCode:         bankCode,
+			BankName:         v.BankName,
+			Icon:             v.Icon,
+			Template:         tmpl,
+			SupportedSystems: v.SupportedSystems,
+			TransferType:     v.TransferType,
+			Schemes:          v.Schemes,
+			Links:            v.Links,
+			AppStoreURL:      v.AppStoreURL,
+			PlayStoreURL:     v.PlayStoreURL,
+			RequisitesPolicy: v.RequisitesPolicy,
+			Version:          v.Version,
+		}
+	}
+	reg.versions[bankCode] = versions
+	return nil
+}
+
+// readActiveVersion номер активной версии банка, 0 - активной версии нет
+func readActiveVersion(fsys fs.FS, bankCode string) (int, error) {
+	raw, err := fs.ReadFile(fsys, bankCode+"/"+activeFileName)
+	if errors.Is(err, fs.ErrNotExist) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	version, err := strconv.Atoi(strings.TrimSpace(string(raw)))
+	if err != nil {
+		return 0, fmt.Errorf("%s: invalid active version: %w", bankCode, err)
+	}
+	return version, nil
+}
+
+func readVersion(fsys fs.FS, bankCode string, number int) (TemplateVersion, error) {
+	rawMeta, err := fs.ReadFile(fsys, bankCode+"/"+versionFileName(number, ".json"))
+	if err != nil {
+		return TemplateVersion{}, err
+	}
+	var meta TemplateMeta
+	if err := json.Unmarshal(rawMeta, &meta); err != nil {
+		return TemplateVersion{}, fmt.Errorf("%s v%d: invalid metadata: %w", bankCode, number, err)
+	}
+	meta.BankCode = bankCode
+
+	body, err := fs.ReadFile(fsys, bankCode+"/"+versionFileName(number, ".html"))
+	if err != nil {
+		return TemplateVersion{}, err
+	}
+	return TemplateVersion{TemplateMeta: meta, Version: number, Body: string(body)}, nil
+}
+
+// versionNumbers возвращает номера версий банка по возрастанию, версия определяется по файлу метаданных
+func versionNumbers(fsys fs.FS, bankCode string) ([]int, error) {
+	entries, err := fs.ReadDir(fsys, bankCode)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var numbers []int
+	for _, entry := range entries {
+		name := entry.Name()
+		if !strings.HasPrefix(name, "v") || !strings.HasSuffix(name, ".json") {
+			continue
+		}
+		number, err := strconv.Atoi(strings.TrimSuffix(strings.TrimPrefix(name, "v"), ".json"))
+		if err != nil || number <= 0 {
+			continue
+		}
+		numbers = append(numbers, number)
+	}
+	sort.Ints(numbers)
+	return numbers, nil
+}
+
+func versionFileName(version int, ext string) string {
+	return "v" + strconv.Itoa(version) + ext
+}
+
+// builtinTemplate встроенный шаблон банка в том виде, в каком он записывается в каталог
+type builtinTemplate struct {
+	meta     TemplateMeta
+	metaJSON []byte
+	body     []byte
+}
+
+func builtinTemplates() ([]builtinTemplate, error) {
+	root, err := fs.Sub(builtinFS, "builtin")
+	if err != nil {
+		return nil, err
+	}
+	body, err := fs.ReadFile(root, builtinBodyName)
+	if err != nil {
+		return nil, err
+	}
+	entries, err := fs.ReadDir(root, ".")
+	if err != nil {
+		return nil, err
+	}
+
+	var templates []builtinTemplate
+	for _, entry := range entries {
+		bankCode, ok := strings.CutSuffix(entry.Name(), ".json")
+		if !ok || entry.IsDir() {
+			continue
+		}
+		metaJSON, err := fs.ReadFile(root, entry.Name())
+		if err != nil {
+			return nil, err
+		}
+		var meta TemplateMeta
+		if err := json.Unmarshal(metaJSON, &meta); err != nil {
+			return nil, fmt.Errorf("builtin %s: invalid metadata: %w", bankCode, err)
+		}
+		if meta.BankCode != bankCode || meta.BuiltinRevision <= 0 {
+			return nil, fmt.Errorf("builtin %s: bank_code must match file name and builtin_revision must be set", bankCode)
+		}
+		templates = append(templates, builtinTemplate{meta: meta, metaJSON: metaJSON, body: body})
+	}
+	return templates, nil
+}
+
+// builtinRevision ревизия встроенного шаблона, из которого создана версия; 0 - версия из админки
+func builtinRevision(meta TemplateMeta) int {
+	if meta.BuiltinRevision > 0 {
+		return meta.BuiltinRevision
+	}
+	return legacyBuiltinRevisions[meta.CreatedAt.UTC().Format(time.RFC3339Nano)]
+}
+
+// seedDir переносит встроенные шаблоны в каталог. Новый банк получает встроенный шаблон активной версией 1.
+// У существующего банка новая ревизия встроенного шаблона добавляется следующей версией один раз,
+// о чем остается отметка в файле builtin. Активной она становится, только если активна одна из
+// прежних встроенных версий: выбор версии из админки обновление не меняет, а откат на старую
+// встроенную версию после обновления сохраняется.
+func seedDir(dir string) error {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+	templates, err := builtinTemplates()
+	if err != nil {
+		return err
+	}
+	for _, t := range templates {
+		if err := seedBank(dir, t); err != nil {
+			return fmt.Errorf("%s: %w", t.meta.BankCode, err)
+		}
+	}
+	return nil
+}
+
+func seedBank(dir string, t builtinTemplate) error {
+	bankCode := t.meta.BankCode
+	revision := t.meta.BuiltinRevision
+	bankDir := filepath.Join(dir, bankCode)
+	fsys := os.DirFS(dir)
+
+	if raw, err := os.ReadFile(filepath.Join(bankDir, builtinFileName)); err == nil {
+		seeded, err := strconv.Atoi(strings.TrimSpace(string(raw)))
+		if err == nil && seeded >= revision {
+			return nil
+		}
+	} else if !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	if err := os.MkdirAll(bankDir, 0o755); err != nil {
+		return err
+	}
+
+	numbers, err := versionNumbers(fsys, bankCode)
+	if err != nil {
+		return err
+	}
+	activeVersion, err := readActiveVersion(fsys, bankCode)
+	if err != nil {
+		return err
+	}
+
+	// версия с этой ревизией могла остаться от прежнего способа заполнения каталога
+	version, activeRevision := 0, 0
+	for _, number := range numbers {
+		v, err := readVersion(fsys, bankCode, number)
+		if err != nil {
+			return err
+		}
+		if builtinRevision(v.TemplateMeta) == revision {
+			version = number
+		}
+		if number == activeVersion {
+			activeRevision = builtinRevision(v.TemplateMeta)
+		}
+	}
+	if version == 0 {
+		version = 1
+		if len(numbers) > 0 {
+			version = numbers[len(numbers)-1] + 1
+		}
+		// тело пишется первым: версия без метаданных при загрузке не видна
+		if err := storage.WriteFile(filepath.Join(bankDir, versionFileName(version, ".html")), t.body, 0o644); err != nil {
+			return err
+		}
+		if err := storage.WriteFile(filepath.Join(bankDir, versionFileName(version, ".json")), t.metaJSON, 0o644); err != nil {
+			return err
+		}
+		log.Printf("deeplink templates: seeded %s v%d from builtin revision %d", bankCode, version, revision)
+	}
+
+	if len(numbers) == 0 || (activeRevision > 0 && activeRevision < revision) {
+		if err := storage.WriteFile(filepath.Join(bankDir, activeFileName), []byte(strconv.Itoa(version)+"\n"), 0o644); err != nil {
+			return err
+		}
+		log.Printf("deeplink templates: activated %s v%d (builtin revision %d)", bankCode, version, revision)
+	}
+	return storage.WriteFile(filepath.Join(bankDir, builtinFileName), []byte(strconv.Itoa(revision)+"\n"), 0o644)
+}
+
+func latestModTime(dir string) (time.Time, error) {
+	var latest time.Time
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		if info.ModTime().After(latest) {
+			latest = info.ModTime()
+		}
+		return nil
+	})
+	return latest, err
+}
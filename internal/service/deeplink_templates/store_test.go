@@ -0,0 +1,211 @@
+package deeplink_templates
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func readActive(t *testing.T, dir, bankCode string) int {
+	t.Helper()
+	version, err := readActiveVersion(os.DirFS(dir), bankCode)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return version
+}
+
+func currentBuiltin(t *testing.T, bankCode string) builtinTemplate {
+	t.Helper()
+	templates, err := builtinTemplates()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tmpl := range templates {
+		if tmpl.meta.BankCode == bankCode {
+			return tmpl
+		}
+	}
+	t.Fatalf("builtin template %s not found", bankCode)
+	return builtinTemplate{}
+}
+
+// writeVersion кладет в каталог версию в том виде, в каком ее оставил бы прежний запуск
+func writeVersion(t *testing.T, dir string, version int, meta TemplateMeta, body string) {
+	t.Helper()
+	bankDir := filepath.Join(dir, meta.BankCode)
+	if err := os.MkdirAll(bankDir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	metaJSON, err := json.MarshalIndent(meta, "", "  ")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(bankDir, versionFileName(version, ".json")), metaJSON, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(bankDir, versionFileName(version, ".html")), []byte(body), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func writeActive(t *testing.T, dir, bankCode string, version int) {
+	t.Helper()
+	if err := os.WriteFile(filepath.Join(dir, bankCode, activeFileName), []byte(strconv.Itoa(version)+"\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// seedLegacy повторяет каталог, заполненный до builtin_revision: встроенные версии 1..5, активна первая
+func seedLegacy(t *testing.T, dir, bankCode string) {
+	t.Helper()
+	builtin := currentBuiltin(t, bankCode)
+	for createdAt, revision := range legacyBuiltinRevisions {
+		meta := builtin.meta
+		meta.BuiltinRevision = 0
+		meta.CreatedAt, _ = time.Parse(time.RFC3339, createdAt)
+		writeVersion(t, dir, revision, meta, string(builtin.body))
+	}
+	writeActive(t, dir, bankCode, 1)
+}
+
+func versionCount(t *testing.T, dir, bankCode string) int {
+	t.Helper()
+	numbers, err := versionNumbers(os.DirFS(dir), bankCode)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return len(numbers)
+}
+
+func TestSeedDirFreshInstall(t *testing.T) {
+	dir := t.TempDir()
+	store, err := NewStore(dir, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	templates, err := builtinTemplates()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, builtin := range templates {
+		bankCode := builtin.meta.BankCode
+		if got := versionCount(t, dir, bankCode); got != 1 {
+			t.Errorf("%s: %d versions, want 1", bankCode, got)
+		}
+		if got := readActive(t, dir, bankCode); got != 1 {
+			t.Errorf("%s: active version %d, want 1", bankCode, got)
+		}
+		if _, ok := store.GetTemplate(bankCode); !ok {
+			t.Errorf("%s: template is not active", bankCode)
+		}
+	}
+
+	// повторный запуск ничего не добавляет
+	if err := seedDir(dir); err != nil {
+		t.Fatal(err)
+	}
+	for _, builtin := range templates {
+		if got := versionCount(t, dir, builtin.meta.BankCode); got != 1 {
+			t.Errorf("%s: %d versions after restart, want 1", builtin.meta.BankCode, got)
+		}
+	}
+}
+
+func TestSeedDirActivatesNewBuiltinOnUpgrade(t *testing.T) {
+	dir := t.TempDir()
+	seedLegacy(t, dir, "sberbank")
+
+	if err := seedDir(dir); err != nil {
+		t.Fatal(err)
+	}
+
+	// версия с текущей ревизией уже была в каталоге: она активируется без дубликата
+	if got := versionCount(t, dir, "sberbank"); got != len(legacyBuiltinRevisions) {
+		t.Fatalf("%d versions, want %d", got, len(legacyBuiltinRevisions))
+	}
+	if got := readActive(t, dir, "sberbank"); got != 5 {
+		t.Fatalf("active version %d, want 5", got)
+	}
+
+	// откат админом на старую встроенную версию переживает перезапуск
+	writeActive(t, dir, "sberbank", 3)
+	if err := seedDir(dir); err != nil {
+		t.Fatal(err)
+	}
+	if got := readActive(t, dir, "sberbank"); got != 3 {
+		t.Fatalf("active version %d after restart, want 3", got)
+	}
+}
+
+func TestSeedDirKeepsAdminVersionActive(t *testing.T) {
+	dir := t.TempDir()
+	seedLegacy(t, dir, "vtb")
+
+	custom := currentBuiltin(t, "vtb").meta
+	custom.BuiltinRevision = 0
+	custom.CreatedAt = time.Now().UTC()
+	custom.Comment = "своя версия"
+	writeVersion(t, dir, 6, custom, "<html>{{.BankName}}</html>")
+	writeActive(t, dir, "vtb", 6)
+
+	if err := seedDir(dir); err != nil {
+		t.Fatal(err)
+	}
+	if got := readActive(t, dir, "vtb"); got != 6 {
+		t.Fatalf("active version %d, want admin version 6", got)
+	}
+}
+
+func TestSeedDirAddsNewRevisionAsNextVersion(t *testing.T) {
+	dir := t.TempDir()
+	builtin := currentBuiltin(t, "tinkoff_card")
+
+	// в каталоге только предыдущая встроенная ревизия
+	previous := builtin.meta
+	previous.BuiltinRevision = builtin.meta.BuiltinRevision - 1
+	writeVersion(t, dir, 1, previous, string(builtin.body))
+	writeActive(t, dir, "tinkoff_card", 1)
+
+	if err := seedDir(dir); err != nil {
+		t.Fatal(err)
+	}
+	if got := readActive(t, dir, "tinkoff_card"); got != 2 {
+		t.Fatalf("active version %d, want 2", got)
+	}
+	v, err := readVersion(os.DirFS(dir), "tinkoff_card", 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v.BuiltinRevision != builtin.meta.BuiltinRevision {
+		t.Fatalf("builtin revision %d, want %d", v.BuiltinRevision, builtin.meta.BuiltinRevision)
+	}
+	marker, err := os.ReadFile(filepath.Join(dir, "tinkoff_card", builtinFileName))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.TrimSpace(string(marker)) != strconv.Itoa(builtin.meta.BuiltinRevision) {
+		t.Fatalf("builtin marker %q", marker)
+	}
+}
+
+func TestCreateVersionDropsBuiltinRevision(t *testing.T) {
+	store, err := NewStore(t.TempDir(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	builtin := currentBuiltin(t, "sberbank")
+
+	v, err := store.CreateVersion(builtin.meta, string(builtin.body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v.BuiltinRevision != 0 {
+		t.Fatalf("builtin revision %d, want 0 for admin version", v.BuiltinRevision)
+	}
+}
@@ -1,43 +1,215 @@
 package deeplink_templates
 
-import "html/template"
+import (
+	"bytes"
+	"html/template"
+	"sort"
+	"time"
 
-// BankTemplateConfig конфигурация шаблона банка
+	"github.com/LavaJover/shvark-api-gateway/internal/domain"
+	"github.com/LavaJover/shvark-api-gateway/internal/service/i18n"
+)
+
+// Политики показа реквизитов на странице банка
+const (
+	RequisitesFull     = "full"      // полные реквизиты на странице и в ссылках
+	RequisitesMasked   = "masked"    // на странице маска, полные реквизиты только внутри ссылок на приложение
+	RequisitesOnDemand = "on_demand" // маска, реквизиты и ссылки отдаются только после явного действия клиента
+)
+
+// BankTemplateConfig конфигурация активного шаблона банка
 type BankTemplateConfig struct {
-    BankCode         string
-    BankName         string
-    Template         *template.Template
-    SupportedSystems []string // Поддерживаемые платежные системы
-    TransferType     string   // "card", "phone", "both"
-}
-
-// BankTemplates глобальная конфигурация всех шаблонов
-var BankTemplates = make(map[string]BankTemplateConfig)
-
-// RegisterTemplate регистрирует новый шаблон
-func RegisterTemplate(config BankTemplateConfig) {
-    BankTemplates[config.BankCode] = config
-}
-
-// GetTemplatesForSystem возвращает шаблоны для указанной платежной системы
-func GetTemplatesForSystem(paymentSystem string) []BankTemplateConfig {
-    var result []BankTemplateConfig
-    for _, config := range BankTemplates {
-        for _, system := range config.SupportedSystems {
-            if system == paymentSystem || system == "all" {
-                result = append(result, config)
-                break
-            }
-        }
-    }
-    return result
-}
-
-// GetAllTemplates возвращает все зарегистрированные шаблоны
-func GetAllTemplates() []BankTemplateConfig {
-    var result []BankTemplateConfig
-    for _, config := range BankTemplates {
-        result = append(result, config)
-    }
-    return result
-}
\ No newline at end of file
+	BankCode         string
+	BankName         string
+	Icon             string
+	Template         *template.Template
+	SupportedSystems []string                // Поддерживаемые платежные системы
+	TransferType     string                  // "card", "phone", "both"
+	Schemes          []string                // Схемы диплинков, которые перебирает страница банка (шаблоны v1)
+	Links            []domain.DeeplinkScheme // Ссылки по платформам в порядке приоритета
+	AppStoreURL      string
+	PlayStoreURL     string
+	RequisitesPolicy string
+	Version          int
+}
+
+// Render рендерит страницу банка, список схем и данные банка подставляются из метаданных шаблона
+func (c BankTemplateConfig) Render(data map[string]interface{}) (string, error) {
+	values := make(map[string]interface{}, len(data)+3)
+	for key, value := range data {
+		values[key] = value
+	}
+	values["BankName"] = c.BankName
+	values["AppStoreURL"] = c.AppStoreURL
+	values["PlayStoreURL"] = c.PlayStoreURL
+	return render(c.Template, c.Schemes, values)
+}
+
+// TemplateMeta метаданные версии шаблона, хранятся рядом с HTML в v<N>.json
+type TemplateMeta struct {
+	BankCode         string                  `json:"bank_code"`
+	BankName         string                  `json:"bank_name"`
+	Icon             string                  `json:"icon,omitempty"`
+	SupportedSystems []string                `json:"supported_systems"`
+	TransferType     string                  `json:"transfer_type"`
+	Schemes          []string                `json:"schemes,omitempty"`
+	Links            []domain.DeeplinkScheme `json:"links,omitempty"`
+	AppStoreURL      string                  `json:"app_store_url,omitempty"`
+	PlayStoreURL     string                  `json:"play_store_url,omitempty"`
+	RequisitesPolicy string                  `json:"requisites_policy,omitempty"` // пусто - full, как у шаблонов до появления политики
+	BuiltinRevision  int                     `json:"builtin_revision,omitempty"`  // ревизия встроенного шаблона; у версий из админки 0
+	CreatedAt        time.Time               `json:"created_at"`
+	Comment          string                  `json:"comment,omitempty"`
+}
+
+// TemplateVersion версия шаблона банка
+type TemplateVersion struct {
+	TemplateMeta
+	Version int
+	Body    string
+	Active  bool
+}
+
+// registry неизменяемый снимок шаблонов, заменяется целиком при перезагрузке
+type registry struct {
+	active   map[string]BankTemplateConfig
+	versions map[string][]TemplateVersion // версии по возрастанию
+}
+
+// GetTemplate возвращает активный шаблон банка
+func (s *Store) GetTemplate(bankCode string) (BankTemplateConfig, bool) {
+	config, exists := s.current.Load().active[bankCode]
+	return config, exists
+}
+
+// GetTemplatesForSystem возвращает шаблоны для указанной платежной системы.
+// Если задан справочник банков, набор шаблонов определяет он.
+func (s *Store) GetTemplatesForSystem(paymentSystem string) []BankTemplateConfig {
+	var result []BankTemplateConfig
+	if s.banks != nil {
+		codes := make(map[string]bool)
+		for _, code := range s.banks.TemplateCodes(paymentSystem) {
+			codes[code] = true
+		}
+		for _, config := range s.GetAllTemplates() {
+			if codes[config.BankCode] {
+				result = append(result, config)
+			}
+		}
+		return result
+	}
+
+	for _, config := range s.GetAllTemplates() {
+		for _, system := range config.SupportedSystems {
+			if system == paymentSystem || system == "all" {
+				result = append(result, config)
+				break
+			}
+		}
+	}
+	return result
+}
+
+// GetAllTemplates возвращает все активные шаблоны, отсортированные по коду банка
+func (s *Store) GetAllTemplates() []BankTemplateConfig {
+	active := s.current.Load().active
+	result := make([]BankTemplateConfig, 0, len(active))
+	for _, config := range active {
+		result = append(result, config)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].BankCode < result[j].BankCode
+	})
+	return result
+}
+
+// BankCodes возвращает коды всех банков, у которых есть хотя бы одна версия шаблона
+func (s *Store) BankCodes() []string {
+	versions := s.current.Load().versions
+	codes := make([]string, 0, len(versions))
+	for bankCode := range versions {
+		codes = append(codes, bankCode)
+	}
+	sort.Strings(codes)
+	return codes
+}
+
+// ListVersions возвращает все версии шаблона банка
+func (s *Store) ListVersions(bankCode string) ([]TemplateVersion, error) {
+	versions, exists := s.current.Load().versions[bankCode]
+	if !exists {
+		return nil, ErrTemplateNotFound
+	}
+	return versions, nil
+}
+
+// GetVersion возвращает конкретную версию шаблона банка
+func (s *Store) GetVersion(bankCode string, version int) (TemplateVersion, error) {
+	versions, err := s.ListVersions(bankCode)
+	if err != nil {
+		return TemplateVersion{}, err
+	}
+	for _, v := range versions {
+		if v.Version == version {
+			return v, nil
+		}
+	}
+	return TemplateVersion{}, ErrTemplateVersionNotFound
+}
+
+// SampleData тестовые данные платежа для предпросмотра и проверки шаблонов
+func SampleData() map[string]interface{} {
+	localizer := i18n.Builtin().Localizer(i18n.SourceLocale)
+	return map[string]interface{}{
+		"Amount":            "1500.00",
+		"AmountFormatted":   localizer.Amount(1500, "RUB"),
+		"Locale":            localizer.Locale(),
+		"L":                 localizer,
+		"OrderID":           "00000000-0000-0000-0000-000000000000",
+		"PhoneNumber":       "+79001234567",
+		"Timestamp":         time.Now().Format("2006-01-02 15:04:05"),
+		"PaymentSystem":     "C2C",
+		"CardNumber":        "2200000000000000",
+		"MaskedCardNumber":  "2200 •••• •••• 0000",
+		"MaskedPhoneNumber": "+7 900 •••-••-67",
+		"BankName":          "Банк",
+		"Platform":          domain.PlatformUnknown,
+		"Deeplinks":         []domain.DeeplinkLink{},
+		"AppStoreURL":       "",
+		"PlayStoreURL":      "",
+		"Token":             "",
+		"RevealURL":         "",
+		"BankCode":          "",
+		"BeaconURL":         "", // пустой адрес отключает отправку событий в предпросмотре
+	}
+}
+
+// LinkValues значения плейсхолдеров ссылок из данных страницы
+func LinkValues(data map[string]interface{}) map[string]string {
+	values := make(map[string]string, 4)
+	for placeholder, key := range map[string]string{
+		"cardNumber":  "CardNumber",
+		"phoneNumber": "PhoneNumber",
+		"amount":      "Amount",
+		"orderId":     "OrderID",
+	} {
+		if value, ok := data[key].(string); ok {
+			values[placeholder] = value
+		}
+	}
+	return values
+}
+
+func render(tmpl *template.Template, schemes []string, data map[string]interface{}) (string, error) {
+	values := make(map[string]interface{}, len(data)+1)
+	for key, value := range data {
+		values[key] = value
+	}
+	values["Schemes"] = schemes
+
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, values); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
@@ -0,0 +1,132 @@
+package service
+
+import (
+	"log"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/LavaJover/shvark-api-gateway/internal/domain"
+	"github.com/LavaJover/shvark-api-gateway/internal/storage"
+)
+
+// DeviceCredentialStore хранилище учетных данных устройств
+type DeviceCredentialStore interface {
+	// Save создает или заменяет учетные данные по ID
+	Save(credential domain.DeviceCredential) error
+	ByID(id string) (domain.DeviceCredential, bool)
+	ByDevice(deviceID string) []domain.DeviceCredential
+	ByTrader(traderID string) []domain.DeviceCredential
+	// Touch отмечает время последнего запроса устройства
+	Touch(id string, at time.Time)
+}
+
+// FileDeviceCredentialStore держит учетные данные в памяти и сохраняет их в JSON-файл при каждом изменении,
+// чтобы устройства не приходилось сопрягать заново после перезапуска. Время последнего запроса
+// пишется в файл вместе со следующим изменением, а не на каждый запрос.
+type FileDeviceCredentialStore struct {
+	path string
+
+	mu          sync.RWMutex
+	credentials map[string]domain.DeviceCredential
+}
+
+// NewFileDeviceCredentialStore загружает учетные данные из файла, пустой путь - только память
+func NewFileDeviceCredentialStore(path string) (*FileDeviceCredentialStore, error) {
+	store := &FileDeviceCredentialStore{
+		path:        path,
+		credentials: make(map[string]domain.DeviceCredential),
+	}
+	if path == "" {
+		log.Printf("device credentials file is not configured: devices must be paired again after restart")
+		return store, nil
+	}
+
+	var credentials []domain.DeviceCredential
+	if err := storage.ReadJSON(path, &credentials); err != nil {
+		return nil, err
+	}
+	for _, credential := range credentials {
+		store.credentials[credential.ID] = credential
+	}
+	return store, nil
+}
+
+func (s *FileDeviceCredentialStore) Save(credential domain.DeviceCredential) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	previous, existed := s.credentials[credential.ID]
+	s.credentials[credential.ID] = credential
+	if err := s.persist(); err != nil {
+		if existed {
+			s.credentials[credential.ID] = previous
+		} else {
+			delete(s.credentials, credential.ID)
+		}
+		return err
+	}
+	return nil
+}
+
+func (s *FileDeviceCredentialStore) ByID(id string) (domain.DeviceCredential, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	credential, ok := s.credentials[id]
+	return credential, ok
+}
+
+func (s *FileDeviceCredentialStore) ByDevice(deviceID string) []domain.DeviceCredential {
+	return s.filter(func(credential domain.DeviceCredential) bool {
+		return credential.DeviceID == deviceID
+	})
+}
+
+func (s *FileDeviceCredentialStore) ByTrader(traderID string) []domain.DeviceCredential {
+	return s.filter(func(credential domain.DeviceCredential) bool {
+		return credential.TraderID == traderID
+	})
+}
+
+func (s *FileDeviceCredentialStore) Touch(id string, at time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if credential, ok := s.credentials[id]; ok {
+		credential.LastSeenAt = at
+		s.credentials[id] = credential
+	}
+}
+
+func (s *FileDeviceCredentialStore) filter(match func(domain.DeviceCredential) bool) []domain.DeviceCredential {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var result []domain.DeviceCredential
+	for _, credential := range s.credentials {
+		if match(credential) {
+			result = append(result, credential)
+		}
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].PairedAt.Before(result[j].PairedAt)
+	})
+	return result
+}
+
+// persist перезаписывает файл снимком всех записей
+func (s *FileDeviceCredentialStore) persist() error {
+	if s.path == "" {
+		return nil
+	}
+
+	credentials := make([]domain.DeviceCredential, 0, len(s.credentials))
+	for _, credential := range s.credentials {
+		credentials = append(credentials, credential)
+	}
+	sort.Slice(credentials, func(i, j int) bool {
+		return credentials[i].ID < credentials[j].ID
+	})
+	return storage.WriteJSON(s.path, credentials)
+}
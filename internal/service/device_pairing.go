@@ -0,0 +1,268 @@
+package service
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/LavaJover/shvark-api-gateway/internal/client"
+	"github.com/LavaJover/shvark-api-gateway/internal/domain"
+	orderpb "github.com/LavaJover/shvark-order-service/proto/gen/order"
+)
+
+var (
+	ErrPairingCodeInvalid      = errors.New("invalid or expired pairing code")
+	ErrDeviceNotOwned          = errors.New("device does not belong to trader")
+	ErrDeviceNotPaired         = errors.New("device is not paired")
+	ErrDeviceCredentialInvalid = errors.New("invalid device credential")
+	ErrDeviceCredentialRevoked = errors.New("device credential revoked")
+)
+
+const (
+	deviceCredentialPrefix = "dvc_"
+	// в коде нет похожих символов (0/O, 1/I), его набирают вручную, если камера не читает QR
+	pairingCodeAlphabet = "ABCDEFGHJKLMNPQRSTUVWXYZ23456789"
+	pairingCodeLength   = 8
+)
+
+// PairingCode одноразовый код сопряжения устройства и его представление для QR
+type PairingCode struct {
+	Code      string
+	QRPayload string
+	DeviceID  string
+	ExpiresAt time.Time
+}
+
+// DeviceInfo сведения о телефоне, которые приложение присылает при сопряжении
+type DeviceInfo struct {
+	Model      string
+	AppVersion string
+}
+
+type pendingPairing struct {
+	deviceID  string
+	traderID  string
+	expiresAt time.Time
+}
+
+// DevicePairingService сопрягает телефоны с устройствами автоматики трейдера: трейдер получает
+// одноразовый код, приложение обменивает его на учетные данные, привязанные к устройству.
+type DevicePairingService struct {
+	orderClient *client.OrderClient
+	store       DeviceCredentialStore
+	codeTTL     time.Duration
+
+	mu    sync.Mutex
+	codes map[string]pendingPairing
+}
+
+func NewDevicePairingService(orderClient *client.OrderClient, store DeviceCredentialStore, codeTTL time.Duration) *DevicePairingService {
+	return &DevicePairingService{
+		orderClient: orderClient,
+		store:       store,
+		codeTTL:     codeTTL,
+		codes:       make(map[string]pendingPairing),
+	}
+}
+
+// StartPairing выпускает код сопряжения для устройства трейдера. Коды живут только в памяти:
+// они короткие и после перезапуска проще выпустить новый.
+func (s *DevicePairingService) StartPairing(traderID, deviceID string) (PairingCode, error) {
+	if err := s.checkOwnership(traderID, deviceID); err != nil {
+		return PairingCode{}, err
+	}
+
+	code, err := randomPairingCode()
+	if err != nil {
+		return PairingCode{}, err
+	}
+	expiresAt := time.Now().Add(s.codeTTL)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.dropExpiredCodes()
+	// новый код для устройства отменяет предыдущий неиспользованный
+	for existing, pending := range s.codes {
+		if pending.deviceID == deviceID {
+			delete(s.codes, existing)
+		}
+	}
+	s.codes[code] = pendingPairing{
+		deviceID:  deviceID,
+		traderID:  traderID,
+		expiresAt: expiresAt,
+	}
+
+	return PairingCode{
+		Code:      code,
+		QRPayload: "shvark://pair?code=" + code,
+		DeviceID:  deviceID,
+		ExpiresAt: expiresAt,
+	}, nil
+}
+
+// Pair обменивает код на учетные данные устройства. Код одноразовый, прежние учетные данные
+// устройства отзываются: сопряженным остается только последний телефон.
+func (s *DevicePairingService) Pair(code string, info DeviceInfo) (string, domain.DeviceCredential, error) {
+	code = strings.ToUpper(strings.TrimSpace(code))
+
+	s.mu.Lock()
+	pending, ok := s.codes[code]
+	delete(s.codes, code)
+	s.mu.Unlock()
+
+	if !ok || time.Now().After(pending.expiresAt) {
+		return "", domain.DeviceCredential{}, ErrPairingCodeInvalid
+	}
+
+	if err := s.RevokeDevice(pending.deviceID); err != nil && !errors.Is(err, ErrDeviceNotPaired) {
+		return "", domain.DeviceCredential{}, err
+	}
+
+	id, err := randomHex(12)
+	if err != nil {
+		return "", domain.DeviceCredential{}, err
+	}
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return "", domain.DeviceCredential{}, err
+	}
+	encodedSecret := base64.RawURLEncoding.EncodeToString(secret)
+
+	now := time.Now()
+	credential := domain.DeviceCredential{
+		ID:          id,
+		DeviceID:    pending.deviceID,
+		TraderID:    pending.traderID,
+		SecretHash:  hashDeviceSecret(encodedSecret),
+		DeviceModel: info.Model,
+		AppVersion:  info.AppVersion,
+		PairedAt:    now,
+		LastSeenAt:  now,
+	}
+	if err := s.store.Save(credential); err != nil {
+		return "", domain.DeviceCredential{}, fmt.Errorf("save device credential: %w", err)
+	}
+	return deviceCredentialPrefix + id + "." + encodedSecret, credential, nil
+}
+
+// Authenticate проверяет учетные данные из заголовка устройства и отмечает время запроса
+func (s *DevicePairingService) Authenticate(token string) (domain.DeviceCredential, error) {
+	id, secret, ok := strings.Cut(strings.TrimPrefix(token, deviceCredentialPrefix), ".")
+	if !ok || !strings.HasPrefix(token, deviceCredentialPrefix) {
+		return domain.DeviceCredential{}, ErrDeviceCredentialInvalid
+	}
+
+	credential, exists := s.store.ByID(id)
+	if !exists || subtle.ConstantTimeCompare([]byte(credential.SecretHash), []byte(hashDeviceSecret(secret))) != 1 {
+		return domain.DeviceCredential{}, ErrDeviceCredentialInvalid
+	}
+	if !credential.Active() {
+		return domain.DeviceCredential{}, ErrDeviceCredentialRevoked
+	}
+
+	now := time.Now()
+	s.store.Touch(credential.ID, now)
+	credential.LastSeenAt = now
+	return credential, nil
+}
+
+// Revoke отзывает учетные данные устройства трейдера
+func (s *DevicePairingService) Revoke(traderID, deviceID string) error {
+	if err := s.checkOwnership(traderID, deviceID); err != nil {
+		return err
+	}
+	return s.RevokeDevice(deviceID)
+}
+
+// RevokeDevice отзывает все действующие учетные данные устройства без проверки владельца,
+// например при удалении устройства
+func (s *DevicePairingService) RevokeDevice(deviceID string) error {
+	revoked := false
+	now := time.Now()
+	for _, credential := range s.store.ByDevice(deviceID) {
+		if !credential.Active() {
+			continue
+		}
+		credential.RevokedAt = &now
+		if err := s.store.Save(credential); err != nil {
+			return fmt.Errorf("revoke device credential: %w", err)
+		}
+		revoked = true
+	}
+	if !revoked {
+		return ErrDeviceNotPaired
+	}
+	return nil
+}
+
+// TraderCredentials действующие учетные данные устройств трейдера
+func (s *DevicePairingService) TraderCredentials(traderID string) []domain.DeviceCredential {
+	var active []domain.DeviceCredential
+	for _, credential := range s.store.ByTrader(traderID) {
+		if credential.Active() {
+			active = append(active, credential)
+		}
+	}
+	return active
+}
+
+func (s *DevicePairingService) checkOwnership(traderID, deviceID string) error {
+	response, err := s.orderClient.GetTraderDevices(&orderpb.GetTraderDevicesRequest{
+		TraderId: traderID,
+	})
+	if err != nil {
+		return upstreamError(UpstreamOrder, err)
+	}
+	for _, device := range response.Devices {
+		if device.DeviceId == deviceID {
+			return nil
+		}
+	}
+	return ErrDeviceNotOwned
+}
+
+// dropExpiredCodes вызывается под s.mu
+func (s *DevicePairingService) dropExpiredCodes() {
+	now := time.Now()
+	for code, pending := range s.codes {
+		if now.After(pending.expiresAt) {
+			delete(s.codes, code)
+		}
+	}
+}
+
+func randomPairingCode() (string, error) {
+	var b strings.Builder
+	max := big.NewInt(int64(len(pairingCodeAlphabet)))
+	for i := 0; i < pairingCodeLength; i++ {
+		n, err := rand.Int(rand.Reader, max)
+		if err != nil {
+			return "", err
+		}
+		b.WriteByte(pairingCodeAlphabet[n.Int64()])
+	}
+	return b.String(), nil
+}
+
+func randomHex(size int) (string, error) {
+	raw := make([]byte, size)
+	if _, err := rand.Read(raw); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(raw), nil
+}
+
+func hashDeviceSecret(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,132 @@
+package service
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newTestPairing(t *testing.T) *DevicePairingService {
+	t.Helper()
+	store, err := NewFileDeviceCredentialStore("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return NewDevicePairingService(nil, store, time.Minute)
+}
+
+// issueCode кладет код так же, как StartPairing, но без проверки владельца устройства в order-service
+func issueCode(s *DevicePairingService, code, deviceID string, expiresAt time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.codes[code] = pendingPairing{deviceID: deviceID, traderID: "trader-1", expiresAt: expiresAt}
+}
+
+func TestDevicePairingPair(t *testing.T) {
+	s := newTestPairing(t)
+	issueCode(s, "ABCD2345", "device-1", time.Now().Add(time.Minute))
+
+	// код набирают вручную: регистр и пробелы не важны
+	token, credential, err := s.Pair(" abcd2345 ", DeviceInfo{Model: "Pixel 7", AppVersion: "1.4.0"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(token, deviceCredentialPrefix+credential.ID+".") {
+		t.Fatalf("token %q is not dvc_<id>.<secret>", token)
+	}
+	if credential.DeviceID != "device-1" || credential.TraderID != "trader-1" || credential.DeviceModel != "Pixel 7" {
+		t.Fatalf("credential = %+v", credential)
+	}
+
+	authenticated, err := s.Authenticate(token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if authenticated.ID != credential.ID {
+		t.Fatalf("authenticated credential %s, want %s", authenticated.ID, credential.ID)
+	}
+
+	if _, _, err := s.Pair("ABCD2345", DeviceInfo{}); !errors.Is(err, ErrPairingCodeInvalid) {
+		t.Fatalf("reused code error = %v, want ErrPairingCodeInvalid", err)
+	}
+}
+
+func TestDevicePairingExpiredCode(t *testing.T) {
+	s := newTestPairing(t)
+	issueCode(s, "ABCD2345", "device-1", time.Now().Add(-time.Second))
+
+	if _, _, err := s.Pair("ABCD2345", DeviceInfo{}); !errors.Is(err, ErrPairingCodeInvalid) {
+		t.Fatalf("expired code error = %v, want ErrPairingCodeInvalid", err)
+	}
+	if _, _, err := s.Pair("UNKNOWN2", DeviceInfo{}); !errors.Is(err, ErrPairingCodeInvalid) {
+		t.Fatalf("unknown code error = %v, want ErrPairingCodeInvalid", err)
+	}
+	if len(s.TraderCredentials("trader-1")) != 0 {
+		t.Fatal("credential is issued for an invalid code")
+	}
+}
+
+func TestDevicePairingRepairRevokesPrevious(t *testing.T) {
+	s := newTestPairing(t)
+	issueCode(s, "FIRST234", "device-1", time.Now().Add(time.Minute))
+	first, _, err := s.Pair("FIRST234", DeviceInfo{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	issueCode(s, "SECOND23", "device-1", time.Now().Add(time.Minute))
+	second, _, err := s.Pair("SECOND23", DeviceInfo{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := s.Authenticate(first); !errors.Is(err, ErrDeviceCredentialRevoked) {
+		t.Fatalf("previous credential error = %v, want ErrDeviceCredentialRevoked", err)
+	}
+	if _, err := s.Authenticate(second); err != nil {
+		t.Fatalf("new credential error = %v", err)
+	}
+	if active := s.TraderCredentials("trader-1"); len(active) != 1 {
+		t.Fatalf("%d active credentials, want 1", len(active))
+	}
+}
+
+func TestDevicePairingAuthenticate(t *testing.T) {
+	s := newTestPairing(t)
+	issueCode(s, "ABCD2345", "device-1", time.Now().Add(time.Minute))
+	token, credential, err := s.Pair("ABCD2345", DeviceInfo{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	issueCode(s, "REVOKED2", "device-2", time.Now().Add(time.Minute))
+	revoked, _, err := s.Pair("REVOKED2", DeviceInfo{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.RevokeDevice("device-2"); err != nil {
+		t.Fatal(err)
+	}
+	_, secret, _ := strings.Cut(token, ".")
+
+	tests := []struct {
+		name    string
+		token   string
+		wantErr error
+	}{
+		{name: "valid", token: token},
+		{name: "wrong secret", token: deviceCredentialPrefix + credential.ID + ".wrong", wantErr: ErrDeviceCredentialInvalid},
+		{name: "unknown id", token: deviceCredentialPrefix + "unknown." + secret, wantErr: ErrDeviceCredentialInvalid},
+		{name: "no prefix", token: strings.TrimPrefix(token, deviceCredentialPrefix), wantErr: ErrDeviceCredentialInvalid},
+		{name: "no secret", token: deviceCredentialPrefix + credential.ID, wantErr: ErrDeviceCredentialInvalid},
+		{name: "empty", token: "", wantErr: ErrDeviceCredentialInvalid},
+		{name: "revoked", token: revoked, wantErr: ErrDeviceCredentialRevoked},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := s.Authenticate(tt.token)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Authenticate() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,545 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/LavaJover/shvark-api-gateway/internal/client"
+	"github.com/LavaJover/shvark-api-gateway/internal/domain"
+	orderpb "github.com/LavaJover/shvark-order-service/proto/gen/order"
+)
+
+// ParseAutomaticLockMode разбирает режим сторожа из конфига
+func ParseAutomaticLockMode(value string) (domain.AutomaticLockMode, error) {
+	switch mode := domain.AutomaticLockMode(value); mode {
+	case domain.AutomaticLockModeTrader, domain.AutomaticLockModeBankDetails:
+		return mode, nil
+	}
+	return "", fmt.Errorf("unknown device watchdog mode %q", value)
+}
+
+type watchedDevice struct {
+	traderID      string
+	lastHeartbeat time.Time
+	// режим bank_details: устройство заблокировано и реквизиты, которые выключил сторож
+	locked        bool
+	bankDetailIDs []string
+}
+
+type traderLock struct {
+	locked  bool
+	skipped bool // трафик уже был заблокирован не сторожем, его не трогаем до восстановления устройств
+}
+
+// DeviceWatchdog следит за пингами устройств автоматики. Если устройство молчит дольше threshold,
+// сторож блокирует трафик трейдера или выключает реквизиты устройства, сообщает об этом трейдеру
+// и возвращает все как было, когда пинги возобновятся. Каждое действие пишется в журнал.
+//
+// Сторож знает только устройства, которые пинговали после запуска шлюза, и устройства
+// из действующих блокировок журнала.
+type DeviceWatchdog struct {
+	orderClient  *client.OrderClient
+	traderEvents *TraderEventPublisher
+	audit        AutomaticLockAudit
+	mode         domain.AutomaticLockMode
+	threshold    time.Duration
+	interval     time.Duration
+
+	mu      sync.Mutex
+	devices map[string]*watchedDevice
+	traders map[string]*traderLock
+}
+
+func NewDeviceWatchdog(
+	orderClient *client.OrderClient,
+	traderEvents *TraderEventPublisher,
+	audit AutomaticLockAudit,
+	mode domain.AutomaticLockMode,
+	threshold time.Duration,
+	interval time.Duration,
+) *DeviceWatchdog {
+	w := &DeviceWatchdog{
+		orderClient:  orderClient,
+		traderEvents: traderEvents,
+		audit:        audit,
+		mode:         mode,
+		threshold:    threshold,
+		interval:     interval,
+		devices:      make(map[string]*watchedDevice),
+		traders:      make(map[string]*traderLock),
+	}
+	w.restore()
+	return w
+}
+
+// restore поднимает действующие блокировки из журнала. Время пинга у таких устройств неизвестно,
+// поэтому трафик вернется только после нового пинга.
+func (w *DeviceWatchdog) restore() {
+	for _, entry := range w.audit.All() {
+		if entry.Mode != w.mode {
+			continue
+		}
+		switch entry.Action {
+		case domain.AutomaticLockLocked:
+			for _, deviceID := range entry.DeviceIDs {
+				device := w.device(deviceID, entry.TraderID)
+				device.lastHeartbeat = time.Time{}
+				if w.mode == domain.AutomaticLockModeBankDetails {
+					device.locked = true
+					device.bankDetailIDs = entry.BankDetailIDs
+				}
+			}
+			if w.mode == domain.AutomaticLockModeTrader {
+				w.traders[entry.TraderID] = &traderLock{locked: true}
+			}
+		case domain.AutomaticLockUnlocked, domain.AutomaticLockForgotten:
+			// в режиме trader устройства не блокируются, забытой бывает только блокировка трейдера
+			if w.mode == domain.AutomaticLockModeTrader {
+				delete(w.traders, entry.TraderID)
+			}
+			for _, deviceID := range entry.DeviceIDs {
+				if device, ok := w.devices[deviceID]; ok {
+					device.locked = false
+					device.bankDetailIDs = nil
+				}
+			}
+		}
+	}
+}
+
+// Heartbeat отмечает, что устройство на связи: пинг или уведомление от него.
+// У выключенного в конфиге сторожа (nil) ничего не делает.
+func (w *DeviceWatchdog) Heartbeat(traderID, deviceID string) {
+	if w == nil || deviceID == "" {
+		return
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	device := w.device(deviceID, traderID)
+	device.lastHeartbeat = time.Now()
+}
+
+// Forget перестает следить за удаленным устройством. Выключенные реквизиты удаленного устройства
+// не включаются обратно, блокировка трейдера снимется на следующей проверке, если остальные устройства на связи.
+func (w *DeviceWatchdog) Forget(deviceID string) {
+	if w == nil {
+		return
+	}
+	w.mu.Lock()
+	device, ok := w.devices[deviceID]
+	delete(w.devices, deviceID)
+	w.mu.Unlock()
+
+	if ok && device.locked {
+		w.record(domain.AutomaticLockAuditEntry{
+			Action:        domain.AutomaticLockForgotten,
+			TraderID:      device.traderID,
+			DeviceIDs:     []string{deviceID},
+			BankDetailIDs: device.bankDetailIDs,
+			Reason:        "device deleted",
+		})
+	}
+}
+
+// ReleaseTrader вызывается, когда блокировку трафика трейдера меняет админ. Блокировка сторожа
+// после этого ему больше не принадлежит: сторож не снимет ее, когда устройства вернутся на связь.
+func (w *DeviceWatchdog) ReleaseTrader(traderID string) {
+	if w == nil || w.mode != domain.AutomaticLockModeTrader {
+		return
+	}
+	w.mu.Lock()
+	state, ok := w.traders[traderID]
+	owned := ok && state.locked
+	delete(w.traders, traderID)
+	w.mu.Unlock()
+
+	if owned {
+		w.record(domain.AutomaticLockAuditEntry{
+			Action:   domain.AutomaticLockForgotten,
+			TraderID: traderID,
+			Reason:   "trader traffic lock changed by admin",
+		})
+	}
+}
+
+// device вызывается под w.mu
+func (w *DeviceWatchdog) device(deviceID, traderID string) *watchedDevice {
+	device, ok := w.devices[deviceID]
+	if !ok {
+		device = &watchedDevice{}
+		w.devices[deviceID] = device
+	}
+	if traderID != "" {
+		device.traderID = traderID
+	}
+	return device
+}
+
+// Run проверяет устройства раз в interval до отмены контекста
+func (w *DeviceWatchdog) Run(ctx context.Context) {
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			w.check(time.Now())
+		}
+	}
+}
+
+type deviceSnapshot struct {
+	deviceID      string
+	traderID      string
+	lastHeartbeat time.Time
+	silent        bool
+	locked        bool
+	bankDetailIDs []string
+}
+
+func (w *DeviceWatchdog) snapshot(now time.Time) []deviceSnapshot {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	result := make([]deviceSnapshot, 0, len(w.devices))
+	for deviceID, device := range w.devices {
+		result = append(result, deviceSnapshot{
+			deviceID:      deviceID,
+			traderID:      device.traderID,
+			lastHeartbeat: device.lastHeartbeat,
+			silent:        now.Sub(device.lastHeartbeat) > w.threshold,
+			locked:        device.locked,
+			bankDetailIDs: device.bankDetailIDs,
+		})
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].deviceID < result[j].deviceID
+	})
+	return result
+}
+
+func (w *DeviceWatchdog) check(now time.Time) {
+	devices := w.snapshot(now)
+	if w.mode == domain.AutomaticLockModeTrader {
+		w.checkTraders(devices)
+		return
+	}
+	for _, device := range devices {
+		switch {
+		case device.silent && !device.locked:
+			w.lockBankDetails(device)
+		case !device.silent && device.locked:
+			w.restoreBankDetails(device)
+		}
+	}
+}
+
+func (w *DeviceWatchdog) lockBankDetails(device deviceSnapshot) {
+	entry := domain.AutomaticLockAuditEntry{
+		TraderID:        device.traderID,
+		DeviceIDs:       []string{device.deviceID},
+		LastHeartbeatAt: heartbeatTime(device.lastHeartbeat),
+		Reason:          fmt.Sprintf("no heartbeat for more than %v", w.threshold),
+	}
+
+	response, err := w.orderClient.GetBankDetailsByTraderID(&orderpb.GetBankDetailsByTraderIDRequest{
+		TraderId: device.traderID,
+	})
+	if err != nil {
+		w.recordFailure(entry, domain.AutomaticLockFailed, err)
+		return
+	}
+
+	var disabled []string
+	var failures []string
+	for _, bankDetail := range response.BankDetails {
+		if bankDetail.DeviceId != device.deviceID || !bankDetail.Enabled {
+			continue
+		}
+		if err := w.setBankDetailEnabled(bankDetail, false); err != nil {
+			failures = append(failures, fmt.Sprintf("%s: %v", bankDetail.BankDetailId, err))
+			continue
+		}
+		disabled = append(disabled, bankDetail.BankDetailId)
+	}
+
+	// устройство считается заблокированным, даже если включенных реквизитов не было:
+	// иначе сторож будет пытаться заблокировать его на каждой проверке
+	w.mu.Lock()
+	if state, ok := w.devices[device.deviceID]; ok {
+		state.locked = true
+		state.bankDetailIDs = disabled
+	}
+	w.mu.Unlock()
+
+	entry.BankDetailIDs = disabled
+	if len(failures) > 0 {
+		entry.Error = strings.Join(failures, "; ")
+	}
+	entry.Action = domain.AutomaticLockLocked
+	w.record(entry)
+	w.traderEvents.DeviceTrafficLocked(device.traderID, device.deviceID, w.mode)
+}
+
+func (w *DeviceWatchdog) restoreBankDetails(device deviceSnapshot) {
+	entry := domain.AutomaticLockAuditEntry{
+		TraderID:        device.traderID,
+		DeviceIDs:       []string{device.deviceID},
+		BankDetailIDs:   device.bankDetailIDs,
+		LastHeartbeatAt: heartbeatTime(device.lastHeartbeat),
+		Reason:          "heartbeat resumed",
+	}
+
+	var failed []string
+	var failures []string
+	for _, bankDetailID := range device.bankDetailIDs {
+		response, err := w.orderClient.GetBankDetailByID(&orderpb.GetBankDetailByIDRequest{
+			BankDetailId: bankDetailID,
+		})
+		if err == nil {
+			err = w.setBankDetailEnabled(response.BankDetail, true)
+		}
+		if err != nil {
+			failed = append(failed, bankDetailID)
+			failures = append(failures, fmt.Sprintf("%s: %v", bankDetailID, err))
+		}
+	}
+
+	if len(failed) > 0 {
+		// невключенные реквизиты остаются за сторожем, попытка повторится на следующей проверке
+		w.mu.Lock()
+		if state, ok := w.devices[device.deviceID]; ok {
+			state.bankDetailIDs = failed
+		}
+		w.mu.Unlock()
+		entry.Error = strings.Join(failures, "; ")
+		w.recordFailure(entry, domain.AutomaticLockUnlockFailed, nil)
+		return
+	}
+
+	w.mu.Lock()
+	if state, ok := w.devices[device.deviceID]; ok {
+		state.locked = false
+		state.bankDetailIDs = nil
+	}
+	w.mu.Unlock()
+
+	entry.Action = domain.AutomaticLockUnlocked
+	w.record(entry)
+	w.traderEvents.DeviceTrafficRestored(device.traderID, device.deviceID, w.mode)
+}
+
+func (w *DeviceWatchdog) setBankDetailEnabled(bankDetail *orderpb.BankDetail, enabled bool) error {
+	if bankDetail == nil {
+		return fmt.Errorf("bank detail not found")
+	}
+	bankDetail.Enabled = enabled
+	_, err := w.orderClient.EditBankDetail(&orderpb.UpdateBankDetailRequest{
+		BankDetail: bankDetail,
+	})
+	return err
+}
+
+// checkTraders режим trader: трафик трейдера блокируется, если молчит хотя бы одно его устройство,
+// и разблокируется, когда на связи все
+func (w *DeviceWatchdog) checkTraders(devices []deviceSnapshot) {
+	silentByTrader := make(map[string][]deviceSnapshot)
+	traders := make(map[string]struct{})
+	for _, device := range devices {
+		traders[device.traderID] = struct{}{}
+		if device.silent {
+			silentByTrader[device.traderID] = append(silentByTrader[device.traderID], device)
+		}
+	}
+
+	w.mu.Lock()
+	for traderID := range w.traders {
+		traders[traderID] = struct{}{}
+	}
+	w.mu.Unlock()
+
+	for traderID := range traders {
+		silent := silentByTrader[traderID]
+
+		w.mu.Lock()
+		state, exists := w.traders[traderID]
+		if !exists {
+			state = &traderLock{}
+			w.traders[traderID] = state
+		}
+		locked, skipped := state.locked, state.skipped
+		w.mu.Unlock()
+
+		switch {
+		case len(silent) > 0 && !locked && !skipped:
+			w.lockTrader(traderID, silent)
+		case len(silent) == 0 && locked:
+			w.unlockTrader(traderID)
+		case len(silent) == 0 && skipped:
+			w.mu.Lock()
+			state.skipped = false
+			w.mu.Unlock()
+		}
+	}
+}
+
+func (w *DeviceWatchdog) lockTrader(traderID string, silent []deviceSnapshot) {
+	deviceIDs := make([]string, len(silent))
+	var lastHeartbeat time.Time
+	for i, device := range silent {
+		deviceIDs[i] = device.deviceID
+		if device.lastHeartbeat.After(lastHeartbeat) {
+			lastHeartbeat = device.lastHeartbeat
+		}
+	}
+	entry := domain.AutomaticLockAuditEntry{
+		TraderID:        traderID,
+		DeviceIDs:       deviceIDs,
+		LastHeartbeatAt: heartbeatTime(lastHeartbeat),
+		Reason:          fmt.Sprintf("no heartbeat for more than %v", w.threshold),
+	}
+
+	// Трафик, заблокированный вручную или антифродом, сторож не трогает: иначе он снимет чужую блокировку
+	traffic, err := w.orderClient.GetTraderTraffic(&orderpb.GetTraderTrafficRequest{
+		TraderId: traderID,
+	})
+	if err != nil {
+		w.recordFailure(entry, domain.AutomaticLockFailed, err)
+		return
+	}
+	for _, record := range traffic.Records {
+		if record.ActivityParams != nil && !record.ActivityParams.TraderUnlocked {
+			w.setTraderState(traderID, traderLock{skipped: true})
+			entry.Action = domain.AutomaticLockSkipped
+			entry.Reason = "trader traffic is already locked"
+			w.record(entry)
+			return
+		}
+	}
+
+	if _, err := w.orderClient.SetTraderLockTrafficStatus(&orderpb.SetTraderLockTrafficStatusRequest{
+		TraderId: traderID,
+		Unlocked: false,
+	}); err != nil {
+		w.recordFailure(entry, domain.AutomaticLockFailed, err)
+		return
+	}
+
+	w.setTraderState(traderID, traderLock{locked: true})
+	entry.Action = domain.AutomaticLockLocked
+	w.record(entry)
+	for _, deviceID := range deviceIDs {
+		w.traderEvents.DeviceTrafficLocked(traderID, deviceID, w.mode)
+	}
+}
+
+func (w *DeviceWatchdog) unlockTrader(traderID string) {
+	entry := domain.AutomaticLockAuditEntry{
+		TraderID:  traderID,
+		DeviceIDs: w.traderDevices(traderID),
+		Reason:    "heartbeat resumed",
+	}
+
+	// как и при блокировке, сначала смотрим текущее состояние: трафик, который уже разблокировали
+	// без сторожа, больше не его, и повторная разблокировка ничего не вернет
+	traffic, err := w.orderClient.GetTraderTraffic(&orderpb.GetTraderTrafficRequest{
+		TraderId: traderID,
+	})
+	if err != nil {
+		w.recordFailure(entry, domain.AutomaticLockUnlockFailed, err)
+		return
+	}
+	stillLocked := false
+	for _, record := range traffic.Records {
+		if record.ActivityParams != nil && !record.ActivityParams.TraderUnlocked {
+			stillLocked = true
+			break
+		}
+	}
+
+	w.mu.Lock()
+	state, owned := w.traders[traderID]
+	owned = owned && state.locked
+	if !stillLocked {
+		delete(w.traders, traderID)
+	}
+	w.mu.Unlock()
+	if !owned {
+		// блокировку за время запроса передали админу (ReleaseTrader)
+		return
+	}
+	if !stillLocked {
+		entry.Action = domain.AutomaticLockForgotten
+		entry.Reason = "trader traffic is already unlocked"
+		w.record(entry)
+		return
+	}
+
+	if _, err := w.orderClient.SetTraderLockTrafficStatus(&orderpb.SetTraderLockTrafficStatusRequest{
+		TraderId: traderID,
+		Unlocked: true,
+	}); err != nil {
+		w.recordFailure(entry, domain.AutomaticLockUnlockFailed, err)
+		return
+	}
+
+	w.mu.Lock()
+	delete(w.traders, traderID)
+	w.mu.Unlock()
+
+	entry.Action = domain.AutomaticLockUnlocked
+	w.record(entry)
+	w.traderEvents.DeviceTrafficRestored(traderID, "", w.mode)
+}
+
+func (w *DeviceWatchdog) setTraderState(traderID string, state traderLock) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.traders[traderID] = &state
+}
+
+func (w *DeviceWatchdog) traderDevices(traderID string) []string {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	var deviceIDs []string
+	for deviceID, device := range w.devices {
+		if device.traderID == traderID {
+			deviceIDs = append(deviceIDs, deviceID)
+		}
+	}
+	sort.Strings(deviceIDs)
+	return deviceIDs
+}
+
+func (w *DeviceWatchdog) recordFailure(entry domain.AutomaticLockAuditEntry, action domain.AutomaticLockAction, err error) {
+	entry.Action = action
+	if err != nil {
+		entry.Error = err.Error()
+	}
+	log.Printf("device watchdog: %s for trader %s, devices %v: %s", action, entry.TraderID, entry.DeviceIDs, entry.Error)
+	w.record(entry)
+}
+
+func (w *DeviceWatchdog) record(entry domain.AutomaticLockAuditEntry) {
+	entry.At = time.Now()
+	entry.Mode = w.mode
+	if err := w.audit.Append(entry); err != nil {
+		log.Printf("device watchdog: failed to write audit entry %s for trader %s: %v", entry.Action, entry.TraderID, err)
+	}
+}
+
+func heartbeatTime(at time.Time) *time.Time {
+	if at.IsZero() {
+		return nil
+	}
+	return &at
+}
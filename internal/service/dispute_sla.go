@@ -0,0 +1,468 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/LavaJover/shvark-api-gateway/internal/domain"
+	orderpb "github.com/LavaJover/shvark-order-service/proto/gen/order"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// disputeSLAPageSize сколько споров запрашивается у order-service за одну страницу
+const disputeSLAPageSize = 100
+
+// ParseDisputeTimeoutAction разбирает действие по истечении срока ответа трейдера из конфига
+func ParseDisputeTimeoutAction(value string) (domain.DisputeTimeoutAction, error) {
+	switch action := domain.DisputeTimeoutAction(value); action {
+	case domain.DisputeTimeoutFreeze, domain.DisputeTimeoutEscalate:
+		return action, nil
+	}
+	return "", fmt.Errorf("unknown dispute timeout action %q", value)
+}
+
+// DisputeSLAOrders споры в order-service
+type DisputeSLAOrders interface {
+	GetOrderDisputes(r *orderpb.GetOrderDisputesRequest) (*orderpb.GetOrderDisputesResponse, error)
+	FreeezeDispute(disputeID string) error
+}
+
+// DisputeSLAConfig сроки этапов спора. DisputeTTL - срок, с которым спор создается в order-service,
+// RemindBefore - за сколько до срока этапа отправить напоминание, OnTraderTimeout - заморозить спор
+// или передать админам, если трейдер не ответил. OpenStatuses - статусы открытых споров в order-service,
+// FrozenStatus - статус замороженного, MaxOpen - сколько открытых споров максимум читается за синхронизацию.
+type DisputeSLAConfig struct {
+	DisputeTTL      time.Duration
+	TraderResponse  time.Duration
+	AdminReview     time.Duration
+	Frozen          time.Duration
+	RemindBefore    time.Duration
+	OnTraderTimeout domain.DisputeTimeoutAction
+	CheckInterval   time.Duration
+	SyncInterval    time.Duration
+	OpenStatuses    []string
+	FrozenStatus    string
+	MaxOpen         int
+}
+
+// OpenDispute открытый спор из order-service
+type OpenDispute struct {
+	DisputeID       string
+	OrderID         string
+	MerchantOrderID string
+	TraderID        string
+	Status          string
+	Reason          string
+	ProofURL        string
+	AmountFiat      float64
+	OrderAmountFiat float64
+	AcceptAt        time.Time
+}
+
+// DisputeQueueItem открытый спор со сроками и срочностью
+type DisputeQueueItem struct {
+	OpenDispute
+	SLA     domain.DisputeSLA
+	Urgency domain.DisputeUrgency
+}
+
+// DisputeQueueFilter фильтр очереди, пустые поля не фильтруют
+type DisputeQueueFilter struct {
+	Stage    domain.DisputeStage
+	Urgency  domain.DisputeUrgency
+	TraderID string
+	Offset   int
+	Limit    int
+}
+
+// DisputeQueue страница очереди. Truncated - открытых споров больше MaxOpen, очередь неполная.
+type DisputeQueue struct {
+	Items     []DisputeQueueItem
+	Total     int
+	Truncated bool
+}
+
+// DisputeSLAService следит за сроками споров: у каждого этапа (ответ трейдера, решение админа,
+// заморозка) свой срок. Перед истечением срока трейдеру и подписанным админам уходит напоминание,
+// после - спор замораживается или передается админам. Споры, открытые через шлюз, отслеживаются
+// с момента открытия, остальные - с момента, когда их нашла синхронизация с order-service.
+type DisputeSLAService struct {
+	orders DisputeSLAOrders
+	events *TraderEventPublisher
+	store  DisputeSLAStore
+	config DisputeSLAConfig
+
+	// mu сериализует изменения сроков из ручек, проверки и синхронизации
+	mu sync.Mutex
+}
+
+func NewDisputeSLAService(
+	orders DisputeSLAOrders,
+	events *TraderEventPublisher,
+	store DisputeSLAStore,
+	config DisputeSLAConfig,
+) *DisputeSLAService {
+	return &DisputeSLAService{
+		orders: orders,
+		events: events,
+		store:  store,
+		config: config,
+	}
+}
+
+// TTL срок, с которым споры создаются в order-service
+func (s *DisputeSLAService) TTL() time.Duration {
+	return s.config.DisputeTTL
+}
+
+// Opened начинает отслеживать спор, открытый через шлюз с TTL ttl: первый этап - ответ трейдера
+func (s *DisputeSLAService) Opened(orderID, disputeID string, ttl time.Duration) {
+	if s == nil || disputeID == "" {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	sla := domain.DisputeSLA{
+		DisputeID: disputeID,
+		OrderID:   orderID,
+		OpenedAt:  now,
+		ExpiresAt: now.Add(ttl),
+	}
+	s.enter(&sla, domain.DisputeStageTraderResponse, now)
+	s.save(sla)
+}
+
+// TraderResponded трейдер ответил на спор: решение переходит к админам. Без disputeID
+// ответ относится к спору ордера, который ждет ответа трейдера.
+func (s *DisputeSLAService) TraderResponded(orderID, disputeID string) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	sla, ok := s.find(orderID, disputeID)
+	if !ok || sla.Stage != domain.DisputeStageTraderResponse {
+		return
+	}
+	s.enter(&sla, domain.DisputeStageAdminReview, time.Now())
+	s.save(sla)
+}
+
+// Frozen спор заморожен админом
+func (s *DisputeSLAService) Frozen(disputeID string) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	sla, ok := s.store.ByID(disputeID)
+	if !ok || sla.Stage == domain.DisputeStageFrozen {
+		return
+	}
+	s.enter(&sla, domain.DisputeStageFrozen, time.Now())
+	s.save(sla)
+}
+
+// Closed спор принят или отклонен, сроки больше не нужны
+func (s *DisputeSLAService) Closed(disputeID string) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.forget(disputeID)
+}
+
+// Run проверяет сроки каждые CheckInterval и синхронизируется с order-service каждые SyncInterval
+func (s *DisputeSLAService) Run(ctx context.Context) {
+	check := time.NewTicker(s.config.CheckInterval)
+	defer check.Stop()
+	resync := time.NewTicker(s.config.SyncInterval)
+	defer resync.Stop()
+
+	s.sync()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-resync.C:
+			s.sync()
+		case now := <-check.C:
+			s.check(now)
+		}
+	}
+}
+
+// Queue открытые споры по срочности: сначала просроченные, затем по возрастанию срока этапа,
+// при равных сроках - более крупные. Открытые споры читаются из order-service при каждом запросе.
+func (s *DisputeSLAService) Queue(filter DisputeQueueFilter) (DisputeQueue, error) {
+	open, complete, err := s.fetchOpen()
+	if err != nil {
+		return DisputeQueue{}, err
+	}
+
+	s.mu.Lock()
+	now := time.Now()
+	s.reconcile(open, complete, now)
+	items := make([]DisputeQueueItem, 0, len(open))
+	for _, dispute := range open {
+		sla, ok := s.store.ByID(dispute.DisputeID)
+		if !ok {
+			continue
+		}
+		item := DisputeQueueItem{
+			OpenDispute: dispute,
+			SLA:         sla,
+			Urgency:     s.urgency(sla, now),
+		}
+		if filter.matches(item) {
+			items = append(items, item)
+		}
+	}
+	s.mu.Unlock()
+
+	sort.SliceStable(items, func(i, j int) bool {
+		if !items[i].SLA.Deadline.Equal(items[j].SLA.Deadline) {
+			return items[i].SLA.Deadline.Before(items[j].SLA.Deadline)
+		}
+		return items[i].AmountFiat > items[j].AmountFiat
+	})
+
+	queue := DisputeQueue{Total: len(items), Truncated: !complete}
+	if filter.Offset < len(items) {
+		end := len(items)
+		if filter.Limit > 0 && filter.Offset+filter.Limit < end {
+			end = filter.Offset + filter.Limit
+		}
+		queue.Items = items[filter.Offset:end]
+	}
+	return queue, nil
+}
+
+func (f DisputeQueueFilter) matches(item DisputeQueueItem) bool {
+	if f.Stage != "" && item.SLA.Stage != f.Stage {
+		return false
+	}
+	if f.Urgency != "" && item.Urgency != f.Urgency {
+		return false
+	}
+	if f.TraderID != "" && item.TraderID != f.TraderID {
+		return false
+	}
+	return true
+}
+
+func (s *DisputeSLAService) urgency(sla domain.DisputeSLA, now time.Time) domain.DisputeUrgency {
+	switch {
+	case sla.Overdue(now):
+		return domain.DisputeUrgencyOverdue
+	case !now.Before(sla.Deadline.Add(-s.config.RemindBefore)):
+		return domain.DisputeUrgencyDueSoon
+	default:
+		return domain.DisputeUrgencyOnTrack
+	}
+}
+
+// check отправляет напоминания и обрабатывает истекшие сроки
+func (s *DisputeSLAService) check(now time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, sla := range s.store.All() {
+		switch {
+		case sla.Expired:
+		case sla.Overdue(now):
+			s.expire(sla, now)
+		case !sla.Reminded && s.config.RemindBefore > 0 && !now.Before(sla.Deadline.Add(-s.config.RemindBefore)):
+			sla.Reminded = true
+			s.save(sla)
+			s.events.DisputeReminder(sla.OrderID, sla.DisputeID, sla.Stage, sla.Deadline)
+		}
+	}
+}
+
+// expire трейдер не ответил - спор замораживается или передается админам; истекший срок
+// решения админа или заморозки помечает спор просроченным и еще раз сообщает админам
+func (s *DisputeSLAService) expire(sla domain.DisputeSLA, now time.Time) {
+	expiredStage := sla.Stage
+	action := "overdue"
+	if sla.Stage == domain.DisputeStageTraderResponse {
+		switch s.config.OnTraderTimeout {
+		case domain.DisputeTimeoutFreeze:
+			if err := s.orders.FreeezeDispute(sla.DisputeID); err != nil {
+				if status.Code(err) == codes.NotFound {
+					s.forget(sla.DisputeID)
+					return
+				}
+				// повтор на следующей проверке
+				log.Printf("dispute SLA: failed to freeze dispute %s: %v", sla.DisputeID, err)
+				return
+			}
+			s.events.DisputeFrozen(sla.DisputeID)
+			s.enter(&sla, domain.DisputeStageFrozen, now)
+			action = "frozen"
+		default:
+			s.enter(&sla, domain.DisputeStageAdminReview, now)
+			action = "escalated"
+		}
+	} else {
+		sla.Expired = true
+	}
+	if !sla.Escalated {
+		sla.Escalated = true
+		sla.EscalatedAt = now
+	}
+	s.save(sla)
+	s.events.DisputeEscalated(sla.OrderID, sla.DisputeID, expiredStage, action)
+}
+
+// sync подхватывает споры, открытые не через шлюз, и забывает закрытые
+func (s *DisputeSLAService) sync() {
+	open, complete, err := s.fetchOpen()
+	if err != nil {
+		log.Printf("dispute SLA: sync failed: %v", err)
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.reconcile(open, complete, time.Now())
+}
+
+// reconcile сверяет отслеживаемые споры с открытыми в order-service, вызывается под s.mu.
+// Закрытые забываются только по полному списку открытых.
+func (s *DisputeSLAService) reconcile(open []OpenDispute, complete bool, now time.Time) {
+	seen := make(map[string]bool, len(open))
+	for _, dispute := range open {
+		seen[dispute.DisputeID] = true
+		frozen := s.config.FrozenStatus != "" && dispute.Status == s.config.FrozenStatus
+
+		sla, tracked := s.store.ByID(dispute.DisputeID)
+		switch {
+		case !tracked:
+			sla = domain.DisputeSLA{
+				DisputeID: dispute.DisputeID,
+				OrderID:   dispute.OrderID,
+				OpenedAt:  now,
+				ExpiresAt: dispute.AcceptAt,
+			}
+			stage := domain.DisputeStageTraderResponse
+			if frozen {
+				stage = domain.DisputeStageFrozen
+			}
+			s.enter(&sla, stage, now)
+		case frozen && sla.Stage != domain.DisputeStageFrozen:
+			// заморожен в обход шлюза
+			s.enter(&sla, domain.DisputeStageFrozen, now)
+		default:
+			continue
+		}
+		s.save(sla)
+	}
+	if !complete {
+		return
+	}
+	for _, sla := range s.store.All() {
+		if !seen[sla.DisputeID] {
+			s.forget(sla.DisputeID)
+		}
+	}
+}
+
+// fetchOpen открытые споры по всем OpenStatuses; false - споров больше MaxOpen и список неполный
+func (s *DisputeSLAService) fetchOpen() ([]OpenDispute, bool, error) {
+	var result []OpenDispute
+	for _, openStatus := range s.config.OpenStatuses {
+		for page := 1; ; page++ {
+			disputeStatus := openStatus
+			response, err := s.orders.GetOrderDisputes(&orderpb.GetOrderDisputesRequest{
+				Page:   int64(page),
+				Limit:  disputeSLAPageSize,
+				Status: &disputeStatus,
+			})
+			if err != nil {
+				return nil, false, upstreamError(UpstreamOrder, err)
+			}
+			for _, dispute := range response.GetDisputes() {
+				if s.config.MaxOpen > 0 && len(result) >= s.config.MaxOpen {
+					return result, false, nil
+				}
+				open := OpenDispute{
+					DisputeID:       dispute.GetDisputeId(),
+					OrderID:         dispute.GetOrderId(),
+					MerchantOrderID: dispute.GetOrder().GetMerchantOrderId(),
+					TraderID:        dispute.GetOrder().GetBankDetail().GetTraderId(),
+					Status:          dispute.GetDisputeStatus(),
+					Reason:          dispute.GetDisputeReason(),
+					ProofURL:        dispute.GetProofUrl(),
+					AmountFiat:      dispute.GetDisputeAmountFiat(),
+					OrderAmountFiat: dispute.GetOrder().GetAmountFiat(),
+				}
+				if acceptAt := dispute.GetAcceptAt(); acceptAt != nil {
+					open.AcceptAt = acceptAt.AsTime()
+				}
+				result = append(result, open)
+			}
+			if len(response.GetDisputes()) == 0 || int64(page) >= int64(response.GetPagination().GetTotalPages()) {
+				break
+			}
+		}
+	}
+	return result, true, nil
+}
+
+// enter переводит спор на этап и считает срок этапа. Срок ответа трейдера и решения админа
+// не позже срока спора в order-service: после него спор решится сам, без админа.
+func (s *DisputeSLAService) enter(sla *domain.DisputeSLA, stage domain.DisputeStage, now time.Time) {
+	sla.Stage = stage
+	sla.StageStartedAt = now
+	sla.Reminded = false
+	sla.Expired = false
+
+	switch stage {
+	case domain.DisputeStageTraderResponse:
+		sla.Deadline = now.Add(s.config.TraderResponse)
+	case domain.DisputeStageAdminReview:
+		sla.Deadline = now.Add(s.config.AdminReview)
+	case domain.DisputeStageFrozen:
+		sla.Deadline = now.Add(s.config.Frozen)
+		return
+	}
+	if !sla.ExpiresAt.IsZero() && sla.ExpiresAt.After(now) && sla.ExpiresAt.Before(sla.Deadline) {
+		sla.Deadline = sla.ExpiresAt
+	}
+}
+
+// find спор по ID или, без ID, спор ордера на этапе ответа трейдера. Вызывается под s.mu.
+func (s *DisputeSLAService) find(orderID, disputeID string) (domain.DisputeSLA, bool) {
+	if disputeID != "" {
+		return s.store.ByID(disputeID)
+	}
+	for _, sla := range s.store.All() {
+		if sla.OrderID == orderID && sla.Stage == domain.DisputeStageTraderResponse {
+			return sla, true
+		}
+	}
+	return domain.DisputeSLA{}, false
+}
+
+func (s *DisputeSLAService) save(sla domain.DisputeSLA) {
+	if err := s.store.Save(sla); err != nil {
+		log.Printf("dispute SLA: failed to save dispute %s: %v", sla.DisputeID, err)
+	}
+}
+
+func (s *DisputeSLAService) forget(disputeID string) {
+	if err := s.store.Delete(disputeID); err != nil {
+		log.Printf("dispute SLA: failed to forget dispute %s: %v", disputeID, err)
+	}
+}
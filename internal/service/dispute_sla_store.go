@@ -0,0 +1,125 @@
+package service
+
+import (
+	"log"
+	"sort"
+	"sync"
+
+	"github.com/LavaJover/shvark-api-gateway/internal/domain"
+	"github.com/LavaJover/shvark-api-gateway/internal/storage"
+)
+
+// DisputeSLAStore сроки открытых споров
+type DisputeSLAStore interface {
+	// Save создает или заменяет сроки спора по ID спора
+	Save(sla domain.DisputeSLA) error
+	// Delete перестает отслеживать закрытый спор
+	Delete(disputeID string) error
+	ByID(disputeID string) (domain.DisputeSLA, bool)
+	// All сроки всех отслеживаемых споров по возрастанию срока этапа
+	All() []domain.DisputeSLA
+}
+
+// FileDisputeSLAStore держит сроки в памяти и перезаписывает JSON-файл после каждого изменения,
+// чтобы после перезапуска шлюза сроки не начинались заново
+type FileDisputeSLAStore struct {
+	path string
+
+	mu       sync.RWMutex
+	disputes map[string]domain.DisputeSLA
+}
+
+// NewFileDisputeSLAStore загружает сроки из файла, пустой путь - только память
+func NewFileDisputeSLAStore(path string) (*FileDisputeSLAStore, error) {
+	store := &FileDisputeSLAStore{
+		path:     path,
+		disputes: make(map[string]domain.DisputeSLA),
+	}
+	if path == "" {
+		log.Printf("dispute SLA file is not configured: deadlines will restart after reboot")
+		return store, nil
+	}
+
+	var disputes []domain.DisputeSLA
+	if err := storage.ReadJSON(path, &disputes); err != nil {
+		return nil, err
+	}
+	for _, sla := range disputes {
+		store.disputes[sla.DisputeID] = sla
+	}
+	return store, nil
+}
+
+func (s *FileDisputeSLAStore) Save(sla domain.DisputeSLA) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	previous, existed := s.disputes[sla.DisputeID]
+	s.disputes[sla.DisputeID] = sla
+	if err := s.persist(); err != nil {
+		if existed {
+			s.disputes[sla.DisputeID] = previous
+		} else {
+			delete(s.disputes, sla.DisputeID)
+		}
+		return err
+	}
+	return nil
+}
+
+func (s *FileDisputeSLAStore) Delete(disputeID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	previous, existed := s.disputes[disputeID]
+	if !existed {
+		return nil
+	}
+	delete(s.disputes, disputeID)
+	if err := s.persist(); err != nil {
+		s.disputes[disputeID] = previous
+		return err
+	}
+	return nil
+}
+
+func (s *FileDisputeSLAStore) ByID(disputeID string) (domain.DisputeSLA, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	sla, ok := s.disputes[disputeID]
+	return sla, ok
+}
+
+func (s *FileDisputeSLAStore) All() []domain.DisputeSLA {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	result := make([]domain.DisputeSLA, 0, len(s.disputes))
+	for _, sla := range s.disputes {
+		result = append(result, sla)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if !result[i].Deadline.Equal(result[j].Deadline) {
+			return result[i].Deadline.Before(result[j].Deadline)
+		}
+		return result[i].DisputeID < result[j].DisputeID
+	})
+	return result
+}
+
+// persist перезаписывает файл снимком всех записей
+func (s *FileDisputeSLAStore) persist() error {
+	if s.path == "" {
+		return nil
+	}
+
+	disputes := make([]domain.DisputeSLA, 0, len(s.disputes))
+	for _, sla := range s.disputes {
+		disputes = append(disputes, sla)
+	}
+	sort.Slice(disputes, func(i, j int) bool {
+		return disputes[i].DisputeID < disputes[j].DisputeID
+	})
+	return storage.WriteJSON(s.path, disputes)
+}
@@ -0,0 +1,287 @@
+package service
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"path/filepath"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/LavaJover/shvark-api-gateway/internal/client"
+	"github.com/LavaJover/shvark-api-gateway/internal/domain"
+	orderpb "github.com/LavaJover/shvark-order-service/proto/gen/order"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+var (
+	ErrEvidenceTarget          = errors.New("order_id or dispute_id is required")
+	ErrEvidenceDisputeMismatch = errors.New("dispute belongs to another order")
+	ErrEvidenceOrderNotFound   = errors.New("order not found")
+	ErrEvidenceForbidden       = errors.New("only the order merchant, trader or admins can access its evidence")
+	ErrEvidenceNotFound        = errors.New("evidence not found")
+	ErrEvidenceTooLarge        = errors.New("file is too large")
+	ErrEvidenceType            = errors.New("file type is not allowed")
+	ErrEvidenceLinkInvalid     = errors.New("evidence link is invalid or expired")
+)
+
+// evidenceExtensions расширение файла в хранилище по типу содержимого
+var evidenceExtensions = map[string]string{
+	"image/jpeg":      ".jpg",
+	"image/png":       ".png",
+	"application/pdf": ".pdf",
+}
+
+// EvidenceOrders ордера и споры, к которым привязываются доказательства
+type EvidenceOrders interface {
+	GetOrderByID(orderID string) (*orderpb.GetOrderByIDResponse, error)
+	GetDisputeInfo(disputeID string) (*client.Dispute, error)
+}
+
+// DisputeResponseTracker отмечает ответ трейдера на спор: загрузка доказательств трейдером и есть ответ
+type DisputeResponseTracker interface {
+	TraderResponded(orderID, disputeID string)
+}
+
+// EvidenceActor кто загружает или запрашивает доказательства. Admin - есть право
+// на доказательства любых ордеров, проверяется в RBAC до вызова сервиса.
+type EvidenceActor struct {
+	UserID string
+	Admin  bool
+}
+
+// EvidenceConfig MaxFileSize - лимит файла в байтах, AllowedTypes - разрешенные типы содержимого
+// (jpeg, png, pdf), URLTTL - срок подписанной ссылки, DownloadPath - путь ручки скачивания
+type EvidenceConfig struct {
+	MaxFileSize  int64
+	AllowedTypes []string
+	URLTTL       time.Duration
+	DownloadPath string
+}
+
+// EvidenceService загрузка чеков и скриншотов к ордерам и спорам. Доступ к доказательствам
+// ордера есть только у его мерчанта, трейдера и админов; скачивание - по подписанной ссылке
+// с коротким сроком, чтобы ее можно было открыть в браузере без заголовка авторизации.
+type EvidenceService struct {
+	orders   EvidenceOrders
+	storage  EvidenceStorage
+	store    EvidenceStore
+	tokens   *OrderTokenService
+	disputes DisputeResponseTracker
+	config   EvidenceConfig
+}
+
+func NewEvidenceService(
+	orders EvidenceOrders,
+	storage EvidenceStorage,
+	store EvidenceStore,
+	tokens *OrderTokenService,
+	disputes DisputeResponseTracker,
+	config EvidenceConfig,
+) *EvidenceService {
+	return &EvidenceService{
+		orders:   orders,
+		storage:  storage,
+		store:    store,
+		tokens:   tokens,
+		disputes: disputes,
+		config:   config,
+	}
+}
+
+func (s *EvidenceService) MaxFileSize() int64 {
+	return s.config.MaxFileSize
+}
+
+// Upload проверяет доступ к ордеру, тип и размер файла, вырезает метаданные изображений и сохраняет файл.
+// Загрузка трейдером ордера считается его ответом на спор.
+func (s *EvidenceService) Upload(actor EvidenceActor, orderID, disputeID, fileName string, data []byte) (domain.DisputeEvidence, error) {
+	if int64(len(data)) > s.config.MaxFileSize {
+		return domain.DisputeEvidence{}, fmt.Errorf("%w: limit is %d bytes", ErrEvidenceTooLarge, s.config.MaxFileSize)
+	}
+	contentType := strings.TrimSpace(strings.Split(http.DetectContentType(data), ";")[0])
+	if !s.allowed(contentType) {
+		return domain.DisputeEvidence{}, fmt.Errorf("%w: %s", ErrEvidenceType, contentType)
+	}
+
+	orderID, err := s.resolveOrder(orderID, disputeID)
+	if err != nil {
+		return domain.DisputeEvidence{}, err
+	}
+	trader, err := s.authorize(actor, orderID)
+	if err != nil {
+		return domain.DisputeEvidence{}, err
+	}
+
+	data, err = StripImageMetadata(contentType, data)
+	if err != nil {
+		return domain.DisputeEvidence{}, err
+	}
+	id, err := randomHex(12)
+	if err != nil {
+		return domain.DisputeEvidence{}, err
+	}
+	sum := sha256.Sum256(data)
+	evidence := domain.DisputeEvidence{
+		ID:          id,
+		OrderID:     orderID,
+		DisputeID:   disputeID,
+		UploadedBy:  actor.UserID,
+		FileName:    evidenceFileName(fileName, contentType),
+		ContentType: contentType,
+		Size:        int64(len(data)),
+		SHA256:      hex.EncodeToString(sum[:]),
+		StorageKey:  orderID + "/" + id + evidenceExtensions[contentType],
+		CreatedAt:   time.Now(),
+	}
+	if err := s.storage.Put(evidence.StorageKey, data); err != nil {
+		return domain.DisputeEvidence{}, fmt.Errorf("failed to store evidence: %w", err)
+	}
+	if err := s.store.Save(evidence); err != nil {
+		return domain.DisputeEvidence{}, fmt.Errorf("failed to save evidence: %w", err)
+	}
+	if trader && s.disputes != nil {
+		s.disputes.TraderResponded(orderID, disputeID)
+	}
+	return evidence, nil
+}
+
+// List доказательства ордера или спора
+func (s *EvidenceService) List(actor EvidenceActor, orderID, disputeID string) ([]domain.DisputeEvidence, error) {
+	orderID, err := s.resolveOrder(orderID, disputeID)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := s.authorize(actor, orderID); err != nil {
+		return nil, err
+	}
+	evidence := s.store.ByOrder(orderID)
+	if disputeID == "" {
+		return evidence, nil
+	}
+	result := make([]domain.DisputeEvidence, 0, len(evidence))
+	for _, e := range evidence {
+		if e.DisputeID == disputeID {
+			result = append(result, e)
+		}
+	}
+	return result, nil
+}
+
+// Get доказательство по ID с проверкой доступа к его ордеру
+func (s *EvidenceService) Get(actor EvidenceActor, id string) (domain.DisputeEvidence, error) {
+	evidence, ok := s.store.ByID(id)
+	if !ok {
+		return domain.DisputeEvidence{}, ErrEvidenceNotFound
+	}
+	if _, err := s.authorize(actor, evidence.OrderID); err != nil {
+		return domain.DisputeEvidence{}, err
+	}
+	return evidence, nil
+}
+
+// SignedURL подписанная ссылка на скачивание и время, до которого она действует
+func (s *EvidenceService) SignedURL(evidence domain.DisputeEvidence) (string, time.Time) {
+	expiresAt := time.Now().Add(s.config.URLTTL)
+	token := s.tokens.Issue(OrderTokenScopeEvidence, evidence.ID, expiresAt)
+	return s.config.DownloadPath + "/" + evidence.ID + "?token=" + url.QueryEscape(token), expiresAt
+}
+
+// Open файл по подписанной ссылке. Доступ проверен при выпуске ссылки, здесь - только подпись и срок.
+func (s *EvidenceService) Open(id, token string) (domain.DisputeEvidence, io.ReadCloser, error) {
+	if err := s.tokens.Validate(token, OrderTokenScopeEvidence, id); err != nil {
+		return domain.DisputeEvidence{}, nil, ErrEvidenceLinkInvalid
+	}
+	evidence, ok := s.store.ByID(id)
+	if !ok {
+		return domain.DisputeEvidence{}, nil, ErrEvidenceNotFound
+	}
+	file, err := s.storage.Open(evidence.StorageKey)
+	if err != nil {
+		return domain.DisputeEvidence{}, nil, fmt.Errorf("failed to open evidence: %w", err)
+	}
+	return evidence, file, nil
+}
+
+// resolveOrder ордер, к которому относится загрузка: по спору, если он указан
+func (s *EvidenceService) resolveOrder(orderID, disputeID string) (string, error) {
+	if disputeID != "" {
+		dispute, err := s.orders.GetDisputeInfo(disputeID)
+		if err != nil {
+			if status.Code(err) == codes.NotFound {
+				return "", ErrEvidenceOrderNotFound
+			}
+			return "", upstreamError(UpstreamOrder, err)
+		}
+		if orderID != "" && orderID != dispute.OrderID {
+			return "", ErrEvidenceDisputeMismatch
+		}
+		orderID = dispute.OrderID
+	}
+	if orderID == "" {
+		return "", ErrEvidenceTarget
+	}
+	return orderID, nil
+}
+
+// authorize доступ есть у админов, мерчанта ордера и трейдера, чьи реквизиты выданы в ордере.
+// true - пользователь трейдер ордера.
+func (s *EvidenceService) authorize(actor EvidenceActor, orderID string) (bool, error) {
+	if actor.Admin {
+		return false, nil
+	}
+	response, err := s.orders.GetOrderByID(orderID)
+	if err != nil {
+		if status.Code(err) == codes.NotFound {
+			return false, ErrEvidenceOrderNotFound
+		}
+		return false, upstreamError(UpstreamOrder, err)
+	}
+	order := response.GetOrder()
+	if actor.UserID == "" {
+		return false, ErrEvidenceForbidden
+	}
+	if actor.UserID == order.GetBankDetail().GetTraderId() {
+		return true, nil
+	}
+	if actor.UserID == order.GetMerchantId() {
+		return false, nil
+	}
+	return false, ErrEvidenceForbidden
+}
+
+func (s *EvidenceService) allowed(contentType string) bool {
+	for _, allowed := range s.config.AllowedTypes {
+		if allowed == contentType {
+			_, known := evidenceExtensions[contentType]
+			return known
+		}
+	}
+	return false
+}
+
+// evidenceFileName имя файла для Content-Disposition: без пути и управляющих символов,
+// с расширением, соответствующим реальному типу
+func evidenceFileName(name, contentType string) string {
+	name = filepath.Base(strings.ReplaceAll(name, "\\", "/"))
+	name = strings.Map(func(r rune) rune {
+		if unicode.IsControl(r) || r == '"' {
+			return -1
+		}
+		return r
+	}, name)
+	name = strings.TrimSuffix(name, filepath.Ext(name))
+	if name == "" || name == "." || name == "/" {
+		name = "evidence"
+	}
+	if runes := []rune(name); len(runes) > 100 {
+		name = string(runes[:100])
+	}
+	return name + evidenceExtensions[contentType]
+}
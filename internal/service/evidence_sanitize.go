@@ -0,0 +1,117 @@
+package service
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+)
+
+var ErrEvidenceMalformed = errors.New("file is damaged or does not match its type")
+
+var pngSignature = []byte("\x89PNG\r\n\x1a\n")
+
+// pngMetadataChunks чанки PNG с метаданными: EXIF, текстовые комментарии и время изменения
+var pngMetadataChunks = map[string]bool{
+	"eXIf": true,
+	"tEXt": true,
+	"zTXt": true,
+	"iTXt": true,
+	"tIME": true,
+}
+
+// StripImageMetadata удаляет из JPEG сегменты APP1 (EXIF, XMP), APP13 (IPTC) и комментарии,
+// из PNG - чанки метаданных. Геопозиция, модель телефона и время съемки не должны уходить
+// другой стороне спора. Пиксели не перекодируются, поэтому вместе с EXIF теряется и ориентация.
+func StripImageMetadata(contentType string, data []byte) ([]byte, error) {
+	switch contentType {
+	case "image/jpeg":
+		return stripJPEGMetadata(data)
+	case "image/png":
+		return stripPNGMetadata(data)
+	}
+	return data, nil
+}
+
+func stripJPEGMetadata(data []byte) ([]byte, error) {
+	if len(data) < 4 || data[0] != 0xFF || data[1] != 0xD8 {
+		return nil, ErrEvidenceMalformed
+	}
+	var out bytes.Buffer
+	out.Grow(len(data))
+	out.Write(data[:2])
+
+	pos := 2
+	for pos < len(data) {
+		if data[pos] != 0xFF {
+			return nil, ErrEvidenceMalformed
+		}
+		// маркеру может предшествовать любое число байт-заполнителей 0xFF
+		for pos < len(data) && data[pos] == 0xFF {
+			pos++
+		}
+		if pos >= len(data) {
+			return nil, ErrEvidenceMalformed
+		}
+		marker := data[pos]
+		pos++
+
+		switch {
+		case marker == 0xD9: // EOI
+			out.Write([]byte{0xFF, marker})
+			return out.Bytes(), nil
+		case marker == 0x01 || (marker >= 0xD0 && marker <= 0xD7): // без длины
+			out.Write([]byte{0xFF, marker})
+			continue
+		}
+
+		if pos+2 > len(data) {
+			return nil, ErrEvidenceMalformed
+		}
+		length := int(binary.BigEndian.Uint16(data[pos:]))
+		if length < 2 || pos+length > len(data) {
+			return nil, ErrEvidenceMalformed
+		}
+		segment := data[pos : pos+length]
+		pos += length
+
+		if marker == 0xDA { // SOS: дальше сжатые данные до конца файла, копируются как есть
+			out.Write([]byte{0xFF, marker})
+			out.Write(segment)
+			out.Write(data[pos:])
+			return out.Bytes(), nil
+		}
+		if marker == 0xE1 || marker == 0xED || marker == 0xFE {
+			continue
+		}
+		out.Write([]byte{0xFF, marker})
+		out.Write(segment)
+	}
+	return nil, ErrEvidenceMalformed
+}
+
+func stripPNGMetadata(data []byte) ([]byte, error) {
+	if !bytes.HasPrefix(data, pngSignature) {
+		return nil, ErrEvidenceMalformed
+	}
+	var out bytes.Buffer
+	out.Grow(len(data))
+	out.Write(pngSignature)
+
+	pos := len(pngSignature)
+	for pos+8 <= len(data) {
+		length := int(binary.BigEndian.Uint32(data[pos:]))
+		chunkType := string(data[pos+4 : pos+8])
+		end := pos + 12 + length // длина, тип, данные и CRC
+		if end > len(data) {
+			return nil, ErrEvidenceMalformed
+		}
+		if !pngMetadataChunks[chunkType] {
+			out.Write(data[pos:end])
+		}
+		pos = end
+		if chunkType == "IEND" {
+			return out.Bytes(), nil
+		}
+	}
+	return nil, ErrEvidenceMalformed
+}
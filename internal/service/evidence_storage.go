@@ -0,0 +1,137 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/LavaJover/shvark-api-gateway/internal/domain"
+	"github.com/LavaJover/shvark-api-gateway/internal/storage"
+)
+
+// EvidenceStorage хранилище файлов доказательств. Ключ - относительный путь вида orderID/evidenceID.ext.
+// Локальная файловая система - первая реализация, S3-совместимое хранилище подключается через этот же интерфейс.
+type EvidenceStorage interface {
+	Put(key string, data []byte) error
+	Open(key string) (io.ReadCloser, error)
+}
+
+// LocalEvidenceStorage файлы в каталоге на диске шлюза
+type LocalEvidenceStorage struct {
+	dir string
+}
+
+func NewLocalEvidenceStorage(dir string) (*LocalEvidenceStorage, error) {
+	if dir == "" {
+		return nil, errors.New("evidence storage dir is not configured")
+	}
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, err
+	}
+	return &LocalEvidenceStorage{dir: dir}, nil
+}
+
+func (s *LocalEvidenceStorage) Put(key string, data []byte) error {
+	path, err := s.path(key)
+	if err != nil {
+		return err
+	}
+	return storage.WriteFile(path, data, 0o600)
+}
+
+func (s *LocalEvidenceStorage) Open(key string) (io.ReadCloser, error) {
+	path, err := s.path(key)
+	if err != nil {
+		return nil, err
+	}
+	return os.Open(path)
+}
+
+// path путь файла внутри каталога хранилища: ключ не должен выводить за его пределы
+func (s *LocalEvidenceStorage) path(key string) (string, error) {
+	clean := filepath.Clean(filepath.FromSlash(key))
+	if filepath.IsAbs(clean) || clean == "." || strings.HasPrefix(clean, "..") {
+		return "", fmt.Errorf("invalid evidence key %q", key)
+	}
+	return filepath.Join(s.dir, clean), nil
+}
+
+// EvidenceStore метаданные загруженных доказательств
+type EvidenceStore interface {
+	Save(evidence domain.DisputeEvidence) error
+	ByID(id string) (domain.DisputeEvidence, bool)
+	// ByOrder доказательства ордера в порядке загрузки
+	ByOrder(orderID string) []domain.DisputeEvidence
+}
+
+// FileEvidenceStore дописывает метаданные в JSON Lines файл и держит их в памяти,
+// пустой путь - только память
+type FileEvidenceStore struct {
+	path string
+
+	mu      sync.RWMutex
+	byID    map[string]domain.DisputeEvidence
+	byOrder map[string][]string
+}
+
+func NewFileEvidenceStore(path string) (*FileEvidenceStore, error) {
+	store := &FileEvidenceStore{
+		path:    path,
+		byID:    make(map[string]domain.DisputeEvidence),
+		byOrder: make(map[string][]string),
+	}
+	if path == "" {
+		log.Printf("evidence metadata file is not configured: uploaded evidence will be lost on restart")
+		return store, nil
+	}
+
+	err := storage.ReadJSONLines(path, func(evidence domain.DisputeEvidence) {
+		store.add(evidence)
+	})
+	return store, err
+}
+
+func (s *FileEvidenceStore) Save(evidence domain.DisputeEvidence) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.path != "" {
+		if err := storage.AppendJSONLine(s.path, evidence); err != nil {
+			return err
+		}
+	}
+	s.add(evidence)
+	return nil
+}
+
+func (s *FileEvidenceStore) ByID(id string) (domain.DisputeEvidence, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	evidence, ok := s.byID[id]
+	return evidence, ok
+}
+
+func (s *FileEvidenceStore) ByOrder(orderID string) []domain.DisputeEvidence {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	ids := s.byOrder[orderID]
+	result := make([]domain.DisputeEvidence, len(ids))
+	for i, id := range ids {
+		result[i] = s.byID[id]
+	}
+	return result
+}
+
+func (s *FileEvidenceStore) add(evidence domain.DisputeEvidence) {
+	if _, exists := s.byID[evidence.ID]; !exists {
+		s.byOrder[evidence.OrderID] = append(s.byOrder[evidence.OrderID], evidence.ID)
+	}
+	s.byID[evidence.ID] = evidence
+}
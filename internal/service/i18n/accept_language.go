@@ -0,0 +1,65 @@
+package i18n
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ParseAcceptLanguage возвращает языки из заголовка Accept-Language по убыванию q.
+// Языки с q=0 и "*" отбрасываются: выбор по умолчанию делает вызывающий.
+func ParseAcceptLanguage(header string) []string {
+	type weighted struct {
+		tag string
+		q   float64
+	}
+
+	var tags []weighted
+	for _, part := range strings.Split(header, ",") {
+		tag, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		tag = strings.TrimSpace(tag)
+		if tag == "" || tag == "*" {
+			continue
+		}
+
+		q := 1.0
+		for _, param := range strings.Split(params, ";") {
+			name, value, found := strings.Cut(strings.TrimSpace(param), "=")
+			if !found || strings.TrimSpace(name) != "q" {
+				continue
+			}
+			parsed, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+			if err == nil {
+				q = parsed
+			}
+		}
+		if q <= 0 {
+			continue
+		}
+		tags = append(tags, weighted{tag: tag, q: q})
+	}
+
+	sort.SliceStable(tags, func(i, j int) bool {
+		return tags[i].q > tags[j].q
+	})
+	result := make([]string, len(tags))
+	for i, tag := range tags {
+		result[i] = tag.tag
+	}
+	return result
+}
+
+// MerchantLocales язык страниц по умолчанию: общий и переопределения для мерчантов.
+// Используется, когда ни параметр lang, ни Accept-Language не дали поддерживаемого языка.
+type MerchantLocales struct {
+	Default   string
+	Merchants map[string]string
+}
+
+// For язык по умолчанию для мерчанта
+func (m MerchantLocales) For(merchantID string) string {
+	if locale, ok := m.Merchants[merchantID]; ok && locale != "" {
+		return locale
+	}
+	return m.Default
+}
@@ -0,0 +1,259 @@
+package i18n
+
+import (
+	"embed"
+	"encoding/json"
+	"fmt"
+	"log"
+	"math"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// SourceLocale язык, на котором написаны исходные тексты. Остальные каталоги переводят его ключи,
+// непереведенные сообщения берутся из него.
+const SourceLocale = "ru"
+
+//go:embed locales/*.json
+var builtinLocales embed.FS
+
+// localeData каталог одного языка, хранится в locales/<locale>.json
+type localeData struct {
+	Name            string            `json:"name"`
+	Decimal         string            `json:"decimal"`
+	Group           string            `json:"group"`
+	CurrencyPattern string            `json:"currency_pattern"` // {amount} и {symbol}
+	CurrencySymbols map[string]string `json:"currency_symbols"`
+	Messages        map[string]string `json:"messages"`
+}
+
+// Catalog каталоги сообщений и правила форматирования сумм по языкам
+type Catalog struct {
+	locales map[string]localeData
+
+	mu      sync.Mutex
+	missing map[string]map[string]struct{}
+}
+
+var (
+	builtinOnce    sync.Once
+	builtinCatalog *Catalog
+)
+
+// Builtin каталог, встроенный в бинарник. Ошибка в файлах каталога - ошибка сборки, поэтому паника.
+func Builtin() *Catalog {
+	builtinOnce.Do(func() {
+		catalog, err := load()
+		if err != nil {
+			panic(fmt.Sprintf("i18n: %v", err))
+		}
+		builtinCatalog = catalog
+	})
+	return builtinCatalog
+}
+
+func load() (*Catalog, error) {
+	entries, err := builtinLocales.ReadDir("locales")
+	if err != nil {
+		return nil, err
+	}
+
+	catalog := &Catalog{
+		locales: make(map[string]localeData, len(entries)),
+		missing: make(map[string]map[string]struct{}),
+	}
+	for _, entry := range entries {
+		raw, err := builtinLocales.ReadFile(path.Join("locales", entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+		var data localeData
+		if err := json.Unmarshal(raw, &data); err != nil {
+			return nil, fmt.Errorf("%s: %w", entry.Name(), err)
+		}
+		catalog.locales[strings.TrimSuffix(entry.Name(), ".json")] = data
+	}
+
+	source, exists := catalog.locales[SourceLocale]
+	if !exists {
+		return nil, fmt.Errorf("source locale %s not found", SourceLocale)
+	}
+	// о непереведенных ключах сообщаем сразу при загрузке, а не при первом показе страницы
+	for locale, data := range catalog.locales {
+		for key := range source.Messages {
+			if _, ok := data.Messages[key]; !ok {
+				catalog.reportMissing(locale, key)
+			}
+		}
+	}
+	return catalog, nil
+}
+
+// Locales поддерживаемые языки
+func (c *Catalog) Locales() []string {
+	locales := make([]string, 0, len(c.locales))
+	for locale := range c.locales {
+		locales = append(locales, locale)
+	}
+	sort.Strings(locales)
+	return locales
+}
+
+// Negotiate выбирает первый поддерживаемый язык из кандидатов в порядке приоритета,
+// если ни один не подходит - SourceLocale
+func (c *Catalog) Negotiate(candidates ...string) string {
+	for _, candidate := range candidates {
+		if locale, ok := c.match(candidate); ok {
+			return locale
+		}
+	}
+	return SourceLocale
+}
+
+// match находит каталог для языка или его базового языка (kk-KZ -> kk)
+func (c *Catalog) match(tag string) (string, bool) {
+	tag = strings.ToLower(strings.TrimSpace(strings.ReplaceAll(tag, "_", "-")))
+	if tag == "" {
+		return "", false
+	}
+	if _, ok := c.locales[tag]; ok {
+		return tag, true
+	}
+	base, _, _ := strings.Cut(tag, "-")
+	if _, ok := c.locales[base]; ok {
+		return base, true
+	}
+	return "", false
+}
+
+// Message возвращает перевод ключа. Непереведенное сообщение берется из SourceLocale,
+// неизвестный ключ возвращается как есть; оба случая попадают в Missing.
+func (c *Catalog) Message(locale, key string) string {
+	if message, ok := c.locales[locale].Messages[key]; ok {
+		return message
+	}
+	c.reportMissing(locale, key)
+	if message, ok := c.locales[SourceLocale].Messages[key]; ok {
+		return message
+	}
+	if locale != SourceLocale {
+		c.reportMissing(SourceLocale, key)
+	}
+	return key
+}
+
+func (c *Catalog) reportMissing(locale, key string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	keys, exists := c.missing[locale]
+	if !exists {
+		keys = make(map[string]struct{})
+		c.missing[locale] = keys
+	}
+	if _, reported := keys[key]; reported {
+		return
+	}
+	keys[key] = struct{}{}
+	log.Printf("i18n: missing translation %q for locale %s", key, locale)
+}
+
+// Missing непереведенные ключи по языкам, найденные при загрузке и при рендеринге страниц
+func (c *Catalog) Missing() map[string][]string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	result := make(map[string][]string, len(c.missing))
+	for locale, keys := range c.missing {
+		list := make([]string, 0, len(keys))
+		for key := range keys {
+			list = append(list, key)
+		}
+		sort.Strings(list)
+		result[locale] = list
+	}
+	return result
+}
+
+// FormatAmount форматирует сумму по правилам языка: разделители разрядов и дробной части,
+// символ валюты и его положение. Символ неизвестной валюты заменяется ее кодом.
+func (c *Catalog) FormatAmount(locale string, amount float64, currency string) string {
+	data, ok := c.locales[locale]
+	if !ok {
+		data = c.locales[SourceLocale]
+	}
+
+	pattern := data.CurrencyPattern
+	if pattern == "" {
+		pattern = "{amount} {symbol}"
+	}
+
+	currency = strings.ToUpper(currency)
+	symbol, ok := data.CurrencySymbols[currency]
+	if !ok {
+		symbol = currency
+		if strings.Contains(pattern, "{symbol}{amount}") {
+			symbol += "\u00a0"
+		}
+	}
+	formatted := strings.ReplaceAll(pattern, "{amount}", formatNumber(math.Abs(amount), data.Decimal, data.Group))
+	formatted = strings.ReplaceAll(formatted, "{symbol}", symbol)
+	if amount < 0 {
+		formatted = "-" + formatted
+	}
+	return strings.TrimSpace(formatted)
+}
+
+func formatNumber(amount float64, decimal, group string) string {
+	if decimal == "" {
+		decimal = "."
+	}
+	integer, fraction, _ := strings.Cut(strconv.FormatFloat(amount, 'f', 2, 64), ".")
+
+	var b strings.Builder
+	for i, digit := range integer {
+		if i > 0 && (len(integer)-i)%3 == 0 {
+			b.WriteString(group)
+		}
+		b.WriteRune(digit)
+	}
+	b.WriteString(decimal)
+	b.WriteString(fraction)
+	return b.String()
+}
+
+// Localizer переводы и форматирование для одного языка, передается в шаблоны страниц как .L
+type Localizer struct {
+	catalog *Catalog
+	locale  string
+}
+
+// Localizer возвращает локализатор языка, неподдерживаемый язык заменяется SourceLocale
+func (c *Catalog) Localizer(locale string) *Localizer {
+	return &Localizer{
+		catalog: c,
+		locale:  c.Negotiate(locale),
+	}
+}
+
+// Locale код языка для атрибута lang и заголовка Content-Language
+func (l *Localizer) Locale() string {
+	return l.locale
+}
+
+// T перевод ключа, аргументы подставляются через fmt.Sprintf
+func (l *Localizer) T(key string, args ...interface{}) string {
+	message := l.catalog.Message(l.locale, key)
+	if len(args) > 0 {
+		return fmt.Sprintf(message, args...)
+	}
+	return message
+}
+
+// Amount сумма с валютой по правилам языка
+func (l *Localizer) Amount(amount float64, currency string) string {
+	return l.catalog.FormatAmount(l.locale, amount, currency)
+}
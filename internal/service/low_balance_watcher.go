@@ -0,0 +1,98 @@
+package service
+
+import (
+	"context"
+	"log"
+	"strconv"
+	"time"
+
+	"github.com/LavaJover/shvark-api-gateway/internal/client"
+	"github.com/LavaJover/shvark-api-gateway/internal/domain"
+	"github.com/LavaJover/shvark-api-gateway/internal/service/notifications"
+)
+
+// LowBalanceWatcher опрашивает баланс кошельков трейдеров, подписанных на low_balance или
+// подключенных к push-каналу, и публикует low_balance, когда баланс опускается ниже порога.
+// Повторно событие публикуется только после того, как баланс поднимется до порога.
+type LowBalanceWatcher struct {
+	source       TraderEventSource
+	notifier     *notifications.Notifier
+	walletClient *client.HTTPWalletClient
+	threshold    float64
+	interval     time.Duration
+
+	low map[string]bool
+}
+
+func NewLowBalanceWatcher(
+	source TraderEventSource,
+	notifier *notifications.Notifier,
+	walletClient *client.HTTPWalletClient,
+	threshold float64,
+	interval time.Duration,
+) *LowBalanceWatcher {
+	return &LowBalanceWatcher{
+		source:       source,
+		notifier:     notifier,
+		walletClient: walletClient,
+		threshold:    threshold,
+		interval:     interval,
+		low:          make(map[string]bool),
+	}
+}
+
+// Run опрашивает до отмены контекста
+func (w *LowBalanceWatcher) Run(ctx context.Context) {
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			w.check()
+		}
+	}
+}
+
+func (w *LowBalanceWatcher) check() {
+	watched := make(map[string]struct{})
+	for _, traderID := range w.notifier.Subscribers(domain.NotificationEventLowBalance) {
+		watched[traderID] = struct{}{}
+	}
+	for _, traderID := range w.source.ActiveTraders() {
+		watched[traderID] = struct{}{}
+	}
+
+	for traderID := range watched {
+		balance, err := w.walletClient.GetBalance(traderID)
+		if err != nil {
+			log.Printf("low balance: failed to get balance of trader %s: %v", traderID, err)
+			continue
+		}
+		if balance >= w.threshold {
+			delete(w.low, traderID)
+			continue
+		}
+		if w.low[traderID] {
+			continue
+		}
+		w.low[traderID] = true
+		w.source.Publish(domain.TraderEvent{
+			Type:     domain.NotificationEventLowBalance,
+			TraderID: traderID,
+			Data: map[string]string{
+				"balance":   strconv.FormatFloat(balance, 'f', 2, 64),
+				"threshold": strconv.FormatFloat(w.threshold, 'f', 2, 64),
+			},
+		})
+	}
+
+	// трейдеры, которых больше не опрашиваем, получат событие заново при следующем падении баланса
+	for traderID := range w.low {
+		if _, ok := watched[traderID]; !ok {
+			delete(w.low, traderID)
+		}
+	}
+}
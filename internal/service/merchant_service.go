@@ -0,0 +1,346 @@
+package service
+
+import (
+	"errors"
+	"time"
+
+	"github.com/LavaJover/shvark-api-gateway/internal/client"
+	"github.com/LavaJover/shvark-api-gateway/internal/domain"
+	orderpb "github.com/LavaJover/shvark-order-service/proto/gen/order"
+	userpb "github.com/LavaJover/shvark-user-service/proto/gen"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+const (
+	defaultPayInTTL   = 20 * time.Minute
+	defaultOrdersPage = 0
+	defaultOrdersSize = 10
+	maxOrdersSize     = 100
+)
+
+// MerchantBalanceAsset единственный актив кошелька мерчанта: баланс и вывод только в нем
+const MerchantBalanceAsset = "USDT"
+
+// Upstream names used in UpstreamError
+const (
+	UpstreamOrder  = "order"
+	UpstreamWallet = "wallet"
+	UpstreamUser   = "user"
+	UpstreamSSO    = "sso"
+	UpstreamAuthz  = "authz"
+)
+
+var (
+	ErrSignInUnavailable = errors.New("sign-in is not available")
+)
+
+// UpstreamError помечает, какой из upstream-сервисов вернул ошибку.
+// Error() возвращает исходное сообщение, поэтому legacy-ручки отдают клиентам тот же текст, что и раньше.
+type UpstreamError struct {
+	Upstream string
+	Err      error
+}
+
+func (e *UpstreamError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *UpstreamError) Unwrap() error {
+	return e.Err
+}
+
+func upstreamError(upstream string, err error) error {
+	return &UpstreamError{Upstream: upstream, Err: err}
+}
+
+// PayInParams параметры создания pay-in ордера
+type PayInParams struct {
+	MerchantID      string
+	MerchantOrderID string
+	ClientID        string
+	AmountFiat      float64
+	Currency        string
+	Country         string
+	PaymentSystem   string
+	BankCode        string
+	NspkCode        string
+	CallbackURL     string
+	Shuffle         int32
+	TTL             time.Duration
+}
+
+// OrdersFilter фильтры списка ордеров мерчанта
+type OrdersFilter struct {
+	DealID           *string
+	Type             *string
+	Status           *string
+	TimeOpeningStart *time.Time
+	TimeOpeningEnd   *time.Time
+	AmountMin        *float64
+	AmountMax        *float64
+	Page             *int
+	Size             *int
+	Sort             *string
+}
+
+// MerchantOrders страница ордеров вместе с данными аккаунта мерчанта
+type MerchantOrders struct {
+	Page     *orderpb.GetOrdersResponse
+	Login    string
+	Username string
+}
+
+// MerchantBalance баланс аккаунта мерчанта
+type MerchantBalance struct {
+	AccountName string
+	Balance     float64
+	Currency    string
+}
+
+// MerchantSession результат входа мерчанта
+type MerchantSession struct {
+	Token     string
+	ExpiresAt time.Time
+}
+
+// MerchantOrderBackend ордера мерчанта: client.OrderClient или симулятор песочницы
+type MerchantOrderBackend interface {
+	CreatePayInOrder(request *orderpb.CreatePayInOrderRequest) (*orderpb.CreatePayInOrderResponse, error)
+	GetOrderByID(orderID string) (*orderpb.GetOrderByIDResponse, error)
+	GetOrderByMerchantOrderID(merchantOrderID string) (*orderpb.GetOrderByMerchantOrderIDResponse, error)
+	// GetMerchantOrderByID и GetMerchantOrderByMerchantOrderID ищут только среди ордеров мерчанта,
+	// чужой ордер - NotFound. ID ордера мерчанта у разных мерчантов может совпадать.
+	GetMerchantOrderByID(merchantID, orderID string) (*orderpb.GetOrderByIDResponse, error)
+	GetMerchantOrderByMerchantOrderID(merchantID, merchantOrderID string) (*orderpb.GetOrderByMerchantOrderIDResponse, error)
+	GetOrders(request *orderpb.GetOrdersRequest) (*orderpb.GetOrdersResponse, error)
+}
+
+// MerchantWallet кошелек мерчанта
+type MerchantWallet interface {
+	GetBalance(userID string) (float64, error)
+	Withdraw(userID, toAddress string, amount float64) (string, error)
+}
+
+// MerchantAccounts данные аккаунта мерчанта
+type MerchantAccounts interface {
+	GetUserByID(userID string) (*userpb.GetUserByIDResponse, error)
+}
+
+// MerchantService единая реализация операций мерчанта.
+// Ручки /merchant, /payments и /api/v2/merchant являются тонкими адаптерами над ним.
+type MerchantService struct {
+	orderClient  MerchantOrderBackend
+	walletClient MerchantWallet
+	userClient   MerchantAccounts
+	ssoClient    *client.SSOClient
+}
+
+// NewMerchantService ssoClient может быть nil, если вход по логину не нужен (песочница)
+func NewMerchantService(
+	orderClient MerchantOrderBackend,
+	walletClient MerchantWallet,
+	userClient MerchantAccounts,
+	ssoClient *client.SSOClient,
+) *MerchantService {
+	return &MerchantService{
+		orderClient:  orderClient,
+		walletClient: walletClient,
+		userClient:   userClient,
+		ssoClient:    ssoClient,
+	}
+}
+
+// SignIn выдает токен мерчанта
+func (s *MerchantService) SignIn(login, password, twoFaCode string) (*MerchantSession, error) {
+	if s.ssoClient == nil {
+		return nil, ErrSignInUnavailable
+	}
+	response, err := s.ssoClient.Login(login, password, twoFaCode)
+	if err != nil {
+		return nil, upstreamError(UpstreamSSO, err)
+	}
+	return &MerchantSession{
+		Token:     response.AccessToken,
+		ExpiresAt: response.TimeExp.AsTime(),
+	}, nil
+}
+
+// CreatePayIn создает pay-in ордер
+func (s *MerchantService) CreatePayIn(params PayInParams) (*domain.Order, error) {
+	ttl := params.TTL
+	if ttl <= 0 {
+		ttl = defaultPayInTTL
+	}
+	response, err := s.orderClient.CreatePayInOrder(&orderpb.CreatePayInOrderRequest{
+		MerchantId:      params.MerchantID,
+		AmountFiat:      params.AmountFiat,
+		Currency:        params.Currency,
+		Country:         params.Country,
+		ClientId:        params.ClientID,
+		PaymentSystem:   params.PaymentSystem,
+		ExpiresAt:       timestamppb.New(time.Now().Add(ttl)),
+		MerchantOrderId: params.MerchantOrderID,
+		Shuffle:         params.Shuffle,
+		CallbackUrl:     params.CallbackURL,
+		Type:            "DEPOSIT",
+		BankCode:        params.BankCode,
+		NspkCode:        params.NspkCode,
+	})
+	if err != nil {
+		return nil, upstreamError(UpstreamOrder, err)
+	}
+	return orderToDomain(response.Order), nil
+}
+
+// GetOrder возвращает ордер по ID шлюза
+func (s *MerchantService) GetOrder(orderID string) (*domain.Order, error) {
+	response, err := s.orderClient.GetOrderByID(orderID)
+	if err != nil {
+		return nil, upstreamError(UpstreamOrder, err)
+	}
+	return orderToDomain(response.Order), nil
+}
+
+// GetOrderByMerchantOrderID возвращает ордер по ID ордера на стороне мерчанта
+func (s *MerchantService) GetOrderByMerchantOrderID(merchantOrderID string) (*domain.Order, error) {
+	response, err := s.orderClient.GetOrderByMerchantOrderID(merchantOrderID)
+	if err != nil {
+		return nil, upstreamError(UpstreamOrder, err)
+	}
+	return orderToDomain(response.Order), nil
+}
+
+// GetOwnOrder возвращает ордер, только если он принадлежит мерчанту
+func (s *MerchantService) GetOwnOrder(merchantID, orderID string) (*domain.Order, error) {
+	response, err := s.orderClient.GetMerchantOrderByID(merchantID, orderID)
+	if err != nil {
+		return nil, upstreamError(UpstreamOrder, err)
+	}
+	return orderToDomain(response.Order), nil
+}
+
+// GetOwnOrderByMerchantOrderID то же, что GetOwnOrder, но по ID ордера мерчанта
+func (s *MerchantService) GetOwnOrderByMerchantOrderID(merchantID, merchantOrderID string) (*domain.Order, error) {
+	response, err := s.orderClient.GetMerchantOrderByMerchantOrderID(merchantID, merchantOrderID)
+	if err != nil {
+		return nil, upstreamError(UpstreamOrder, err)
+	}
+	return orderToDomain(response.Order), nil
+}
+
+// ListOrders возвращает страницу ордеров мерчанта
+func (s *MerchantService) ListOrders(merchantID string, filter OrdersFilter) (*MerchantOrders, error) {
+	userResp, err := s.userClient.GetUserByID(merchantID)
+	if err != nil {
+		return nil, upstreamError(UpstreamUser, err)
+	}
+
+	page := defaultOrdersPage
+	if filter.Page != nil {
+		page = *filter.Page
+	}
+	size := defaultOrdersSize
+	if filter.Size != nil {
+		size = *filter.Size
+	}
+	if size > maxOrdersSize {
+		size = maxOrdersSize
+	}
+
+	grpcReq := &orderpb.GetOrdersRequest{
+		MerchantId: merchantID,
+		DealId:     filter.DealID,
+		Type:       filter.Type,
+		Status:     filter.Status,
+		AmountMin:  filter.AmountMin,
+		AmountMax:  filter.AmountMax,
+		Sort:       filter.Sort,
+		Page:       int32(page),
+		Size:       int32(size),
+	}
+	if filter.TimeOpeningStart != nil {
+		grpcReq.TimeOpeningStart = timestamppb.New(*filter.TimeOpeningStart)
+	}
+	if filter.TimeOpeningEnd != nil {
+		grpcReq.TimeOpeningEnd = timestamppb.New(*filter.TimeOpeningEnd)
+	}
+
+	grpcResp, err := s.orderClient.GetOrders(grpcReq)
+	if err != nil {
+		return nil, upstreamError(UpstreamOrder, err)
+	}
+
+	return &MerchantOrders{
+		Page:     grpcResp,
+		Login:    userResp.Login,
+		Username: userResp.Username,
+	}, nil
+}
+
+// GetBalance возвращает баланс кошелька мерчанта
+func (s *MerchantService) GetBalance(merchantID string) (*MerchantBalance, error) {
+	balance, err := s.walletClient.GetBalance(merchantID)
+	if err != nil {
+		return nil, upstreamError(UpstreamWallet, err)
+	}
+
+	userResp, err := s.userClient.GetUserByID(merchantID)
+	if err != nil {
+		return nil, upstreamError(UpstreamUser, err)
+	}
+
+	return &MerchantBalance{
+		AccountName: userResp.Username,
+		Balance:     balance,
+		Currency:    MerchantBalanceAsset,
+	}, nil
+}
+
+// Withdraw выводит USDT с кошелька мерчанта и возвращает хеш транзакции
+func (s *MerchantService) Withdraw(merchantID, toAddress string, amount float64) (string, error) {
+	txHash, err := s.walletClient.Withdraw(merchantID, toAddress, amount)
+	if err != nil {
+		return "", upstreamError(UpstreamWallet, err)
+	}
+	return txHash, nil
+}
+
+func orderToDomain(o *orderpb.Order) *domain.Order {
+	order := &domain.Order{
+		ID:              o.OrderId,
+		MerchantID:      o.MerchantId,
+		MerchantOrderID: o.MerchantOrderId,
+		AmountFiat:      o.AmountFiat,
+		AmountCrypto:    o.AmountCrypto,
+		CryptoRubRate:   o.CryptoRubRate,
+		PlatformFee:     o.PlatformFee,
+		Recalculated:    o.Recalculated,
+		CallbackURL:     o.CallbackUrl,
+		Status:          domain.OrderStatus(o.Status),
+	}
+	if o.ExpiresAt != nil {
+		order.ExpiresAt = o.ExpiresAt.AsTime()
+	}
+	if bd := o.BankDetail; bd != nil {
+		order.Currency = bd.Currency
+		order.Country = bd.Country
+		order.PaymentSystem = bd.PaymentSystem
+		order.BankDetailsID = bd.BankDetailId
+		order.BankDetail = &domain.BankDetail{
+			ID:            bd.BankDetailId,
+			TraderID:      bd.TraderId,
+			Country:       bd.Country,
+			Currency:      bd.Currency,
+			BankName:      bd.BankName,
+			BankCode:      bd.BankCode,
+			NspkCode:      bd.NspkCode,
+			PaymentSystem: bd.PaymentSystem,
+			Enabled:       bd.Enabled,
+			CardNumber:    bd.CardNumber,
+			Phone:         bd.Phone,
+			Owner:         bd.Owner,
+			DeviceID:      bd.DeviceId,
+		}
+	}
+	return order
+}
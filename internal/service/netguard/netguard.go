@@ -0,0 +1,90 @@
+package netguard
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"syscall"
+	"time"
+)
+
+// ErrNotPublic адрес ведет во внутреннюю сеть: loopback, RFC1918, link-local и т.п.
+var ErrNotPublic = errors.New("address is not public")
+
+const resolveTimeout = 5 * time.Second
+
+// sharedAddressSpace 100.64.0.0/10 (RFC6598, CGNAT) - net.IP.IsPrivate его не включает
+var sharedAddressSpace = &net.IPNet{IP: net.IPv4(100, 64, 0, 0), Mask: net.CIDRMask(10, 32)}
+
+// IsPublic можно ли отправлять запросы пользователя на этот адрес
+func IsPublic(ip net.IP) bool {
+	if ip == nil {
+		return false
+	}
+	return !ip.IsLoopback() &&
+		!ip.IsPrivate() &&
+		!ip.IsLinkLocalUnicast() &&
+		!ip.IsLinkLocalMulticast() &&
+		!ip.IsInterfaceLocalMulticast() &&
+		!ip.IsMulticast() &&
+		!ip.IsUnspecified() &&
+		!sharedAddressSpace.Contains(ip)
+}
+
+// CheckURL проверяет, что хост URL и все его DNS-адреса публичные. Проверка при сохранении
+// адреса не защищает от смены DNS, поэтому отправлять запросы нужно клиентом из NewClient.
+func CheckURL(ctx context.Context, rawURL string) error {
+	parsed, err := url.Parse(rawURL)
+	if err != nil {
+		return err
+	}
+	host := parsed.Hostname()
+	if host == "" {
+		return fmt.Errorf("url has no host")
+	}
+	if ip := net.ParseIP(host); ip != nil {
+		if !IsPublic(ip) {
+			return fmt.Errorf("%w: %s", ErrNotPublic, host)
+		}
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, resolveTimeout)
+	defer cancel()
+	addrs, err := net.DefaultResolver.LookupIPAddr(ctx, host)
+	if err != nil {
+		return fmt.Errorf("failed to resolve %s: %w", host, err)
+	}
+	for _, addr := range addrs {
+		if !IsPublic(addr.IP) {
+			return fmt.Errorf("%w: %s resolves to %s", ErrNotPublic, host, addr.IP)
+		}
+	}
+	return nil
+}
+
+// NewClient HTTP-клиент, который не соединяется с внутренними адресами. Адрес проверяется
+// при каждом соединении, в том числе после редиректа и смены DNS. Прокси из окружения
+// не используется: иначе проверялся бы адрес прокси, а не получателя.
+func NewClient(timeout time.Duration) *http.Client {
+	dialer := &net.Dialer{
+		Timeout: 10 * time.Second,
+		Control: func(_, address string, _ syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			if !IsPublic(net.ParseIP(host)) {
+				return fmt.Errorf("%w: %s", ErrNotPublic, host)
+			}
+			return nil
+		},
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+	return &http.Client{Timeout: timeout, Transport: transport}
+}
@@ -0,0 +1,265 @@
+package notifications
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"net/smtp"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/LavaJover/shvark-api-gateway/internal/domain"
+	"github.com/LavaJover/shvark-api-gateway/internal/service/netguard"
+)
+
+// Message уведомление для отправки в канал
+type Message struct {
+	To      string // адрес в канале: chat_id, email, URL
+	Subject string
+	Body    string
+	Event   domain.TraderEvent
+}
+
+// Channel адаптер канала доставки
+type Channel interface {
+	Name() domain.NotificationChannel
+	// ValidateAddress проверяет адрес пользователя при сохранении подписки
+	ValidateAddress(address string) error
+	Send(ctx context.Context, message Message) error
+}
+
+// TelegramChannel отправляет сообщения через Telegram Bot API
+type TelegramChannel struct {
+	apiURL   string
+	botToken string
+	client   *http.Client
+}
+
+func NewTelegramChannel(apiURL, botToken string, timeout time.Duration) *TelegramChannel {
+	return &TelegramChannel{
+		apiURL:   strings.TrimRight(apiURL, "/"),
+		botToken: botToken,
+		client:   &http.Client{Timeout: timeout},
+	}
+}
+
+func (c *TelegramChannel) Name() domain.NotificationChannel {
+	return domain.NotificationChannelTelegram
+}
+
+func (c *TelegramChannel) ValidateAddress(address string) error {
+	if _, err := strconv.ParseInt(address, 10, 64); err != nil {
+		return fmt.Errorf("telegram chat_id must be a number")
+	}
+	return nil
+}
+
+func (c *TelegramChannel) Send(ctx context.Context, message Message) error {
+	payload, err := json.Marshal(map[string]string{
+		"chat_id": message.To,
+		"text":    message.Subject + "\n\n" + message.Body,
+	})
+	if err != nil {
+		return err
+	}
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost,
+		c.apiURL+"/bot"+c.botToken+"/sendMessage", bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	request.Header.Set("Content-Type", "application/json")
+
+	response, err := c.client.Do(request)
+	if err != nil {
+		// в ошибке клиента URL с токеном бота, в историю доставки он попасть не должен
+		return fmt.Errorf("telegram request failed: %w", unwrapURLError(err))
+	}
+	defer response.Body.Close()
+
+	var result struct {
+		OK          bool   `json:"ok"`
+		Description string `json:"description"`
+	}
+	if err := json.NewDecoder(io.LimitReader(response.Body, 64*1024)).Decode(&result); err != nil {
+		return fmt.Errorf("telegram responded %d", response.StatusCode)
+	}
+	if !result.OK {
+		return fmt.Errorf("telegram responded %d: %s", response.StatusCode, result.Description)
+	}
+	return nil
+}
+
+// SMTPChannel отправляет письма через SMTP-сервер
+type SMTPChannel struct {
+	addr string
+	auth smtp.Auth
+	from string
+}
+
+func NewSMTPChannel(host string, port int, username, password, from string) *SMTPChannel {
+	var auth smtp.Auth
+	if username != "" {
+		auth = smtp.PlainAuth("", username, password, host)
+	}
+	return &SMTPChannel{
+		addr: net.JoinHostPort(host, strconv.Itoa(port)),
+		auth: auth,
+		from: from,
+	}
+}
+
+func (c *SMTPChannel) Name() domain.NotificationChannel {
+	return domain.NotificationChannelEmail
+}
+
+func (c *SMTPChannel) ValidateAddress(address string) error {
+	if strings.ContainsAny(address, "\r\n") || !strings.Contains(address, "@") {
+		return fmt.Errorf("invalid email address")
+	}
+	return nil
+}
+
+// Send net/smtp не принимает контекст, письмо отправляется с таймаутом соединения сервера
+func (c *SMTPChannel) Send(_ context.Context, message Message) error {
+	subject := strings.NewReplacer("\r", " ", "\n", " ").Replace(message.Subject)
+	var body bytes.Buffer
+	fmt.Fprintf(&body, "From: %s\r\n", c.from)
+	fmt.Fprintf(&body, "To: %s\r\n", message.To)
+	fmt.Fprintf(&body, "Subject: =?UTF-8?B?%s?=\r\n", base64.StdEncoding.EncodeToString([]byte(subject)))
+	body.WriteString("MIME-Version: 1.0\r\n")
+	body.WriteString("Content-Type: text/plain; charset=UTF-8\r\n")
+	body.WriteString("\r\n")
+	body.WriteString(strings.ReplaceAll(message.Body, "\n", "\r\n"))
+	body.WriteString("\r\n")
+
+	return smtp.SendMail(c.addr, c.auth, c.from, []string{message.To}, body.Bytes())
+}
+
+// WebhookChannel отправляет событие JSON-ом на URL пользователя. Тело подписывается HMAC-SHA256
+// общим секретом, подпись передается в заголовке X-Signature. Адреса внутренней сети запрещены
+// и при сохранении подписки, и при каждом соединении.
+type WebhookChannel struct {
+	secret string
+	client *http.Client
+}
+
+func NewWebhookChannel(secret string, timeout time.Duration) *WebhookChannel {
+	return &WebhookChannel{
+		secret: secret,
+		client: netguard.NewClient(timeout),
+	}
+}
+
+func (c *WebhookChannel) Name() domain.NotificationChannel {
+	return domain.NotificationChannelWebhook
+}
+
+func (c *WebhookChannel) ValidateAddress(address string) error {
+	parsed, err := url.Parse(address)
+	if err != nil || parsed.Scheme != "https" || parsed.Host == "" {
+		return fmt.Errorf("webhook url must be an absolute https url")
+	}
+	if err := netguard.CheckURL(context.Background(), address); err != nil {
+		return fmt.Errorf("webhook url is not allowed: %v", err)
+	}
+	return nil
+}
+
+func (c *WebhookChannel) Send(ctx context.Context, message Message) error {
+	payload, err := json.Marshal(map[string]interface{}{
+		"event":   message.Event,
+		"subject": message.Subject,
+		"text":    message.Body,
+	})
+	if err != nil {
+		return err
+	}
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, message.To, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	request.Header.Set("Content-Type", "application/json")
+	if c.secret != "" {
+		mac := hmac.New(sha256.New, []byte(c.secret))
+		mac.Write(payload)
+		request.Header.Set("X-Signature", hex.EncodeToString(mac.Sum(nil)))
+	}
+
+	response, err := c.client.Do(request)
+	if err != nil {
+		return fmt.Errorf("webhook request failed: %w", unwrapURLError(err))
+	}
+	defer response.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(response.Body, 64*1024))
+
+	if response.StatusCode < 200 || response.StatusCode >= 300 {
+		return fmt.Errorf("webhook responded %d", response.StatusCode)
+	}
+	return nil
+}
+
+// LocalChannel заменяет настоящий канал локально и в тестах: ничего не отправляет,
+// пишет сообщение в лог и запоминает последние сообщения
+type LocalChannel struct {
+	name     domain.NotificationChannel
+	validate func(address string) error
+	limit    int
+
+	mu   sync.Mutex
+	sent []Message
+}
+
+// NewLocalChannel заглушка канала real, адреса проверяются так же, как в настоящем канале
+func NewLocalChannel(real Channel) *LocalChannel {
+	return &LocalChannel{
+		name:     real.Name(),
+		validate: real.ValidateAddress,
+		limit:    100,
+	}
+}
+
+func (c *LocalChannel) Name() domain.NotificationChannel {
+	return c.name
+}
+
+func (c *LocalChannel) ValidateAddress(address string) error {
+	return c.validate(address)
+}
+
+func (c *LocalChannel) Send(_ context.Context, message Message) error {
+	log.Printf("notifications [%s local] to=%s: %s", c.name, message.To, message.Subject)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.sent = append(c.sent, message)
+	if len(c.sent) > c.limit {
+		c.sent = c.sent[len(c.sent)-c.limit:]
+	}
+	return nil
+}
+
+// Sent последние отправленные сообщения
+func (c *LocalChannel) Sent() []Message {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]Message(nil), c.sent...)
+}
+
+func unwrapURLError(err error) error {
+	if urlErr, ok := err.(*url.Error); ok {
+		return urlErr.Err
+	}
+	return err
+}
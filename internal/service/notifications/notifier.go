@@ -0,0 +1,332 @@
+package notifications
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"time"
+
+	"github.com/LavaJover/shvark-api-gateway/internal/domain"
+)
+
+var ErrInvalidPreferences = errors.New("invalid notification preferences")
+
+// Config очередь и повторы доставки
+type Config struct {
+	Workers     int
+	QueueSize   int
+	MaxAttempts int
+	RetryDelay  time.Duration
+	SendTimeout time.Duration
+}
+
+// Notifier рассылает события трейдеров по подпискам: трейдеру - его события, подписчикам
+// с AllTraders (админам) - события всех трейдеров. Отправка асинхронная, через очередь
+// и пул воркеров, каждая доставка попадает в историю.
+type Notifier struct {
+	channels    map[domain.NotificationChannel]Channel
+	templates   *Templates
+	preferences PreferencesStore
+	history     DeliveryHistory
+	config      Config
+	queue       chan domain.TraderEvent
+}
+
+func NewNotifier(
+	channels []Channel,
+	templates *Templates,
+	preferences PreferencesStore,
+	history DeliveryHistory,
+	config Config,
+) *Notifier {
+	if config.Workers < 1 {
+		config.Workers = 1
+	}
+	if config.MaxAttempts < 1 {
+		config.MaxAttempts = 1
+	}
+	notifier := &Notifier{
+		channels:    make(map[domain.NotificationChannel]Channel, len(channels)),
+		templates:   templates,
+		preferences: preferences,
+		history:     history,
+		config:      config,
+		queue:       make(chan domain.TraderEvent, config.QueueSize),
+	}
+	for _, channel := range channels {
+		notifier.channels[channel.Name()] = channel
+	}
+	return notifier
+}
+
+// Notify ставит событие в очередь рассылки. События без шаблона пропускаются,
+// при переполненной очереди событие теряется, чтобы не задерживать продюсера.
+func (n *Notifier) Notify(event domain.TraderEvent) {
+	if n == nil {
+		return
+	}
+	if !n.templates.Has(event.Type) {
+		return
+	}
+	if event.At.IsZero() {
+		event.At = time.Now()
+	}
+	select {
+	case n.queue <- event:
+	default:
+		log.Printf("notifications: queue is full, %s event of trader %s dropped", event.Type, event.TraderID)
+	}
+}
+
+// Run запускает воркеров и ждет отмены контекста
+func (n *Notifier) Run(ctx context.Context) {
+	for w := 0; w < n.config.Workers; w++ {
+		go func() {
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case event := <-n.queue:
+					n.deliver(ctx, event)
+				}
+			}
+		}()
+	}
+	<-ctx.Done()
+}
+
+// Channels подключенные каналы
+func (n *Notifier) Channels() []domain.NotificationChannel {
+	channels := make([]domain.NotificationChannel, 0, len(n.channels))
+	for name := range n.channels {
+		channels = append(channels, name)
+	}
+	sort.Slice(channels, func(i, j int) bool { return channels[i] < channels[j] })
+	return channels
+}
+
+// EventTypes типы событий, на которые можно подписаться
+func (n *Notifier) EventTypes() []domain.TraderEventType {
+	return n.templates.EventTypes()
+}
+
+// Subscribers трейдеры, которые получают свои события типа eventType хотя бы в один канал.
+// Подписки на события всех трейдеров (админские) не учитываются. Нужен продюсерам,
+// которые опрашивают сервисы только для подписанных трейдеров.
+func (n *Notifier) Subscribers(eventType domain.TraderEventType) []string {
+	if n == nil {
+		return nil
+	}
+	var result []string
+	for _, preferences := range n.preferences.All() {
+		if preferences.AllTraders || !preferences.Subscribed(eventType) {
+			continue
+		}
+		for _, target := range preferences.Targets {
+			if _, ok := n.channels[target.Channel]; target.Enabled && ok {
+				result = append(result, preferences.UserID)
+				break
+			}
+		}
+	}
+	sort.Strings(result)
+	return result
+}
+
+func (n *Notifier) Preferences(userID string) domain.NotificationPreferences {
+	preferences, ok := n.preferences.Get(userID)
+	if !ok {
+		return domain.NotificationPreferences{UserID: userID, Targets: []domain.NotificationTarget{}}
+	}
+	return preferences
+}
+
+// SavePreferences проверяет и сохраняет подписку
+func (n *Notifier) SavePreferences(preferences domain.NotificationPreferences) (domain.NotificationPreferences, error) {
+	if err := n.validate(preferences); err != nil {
+		return domain.NotificationPreferences{}, err
+	}
+	preferences.UpdatedAt = time.Now()
+	if err := n.preferences.Save(preferences); err != nil {
+		return domain.NotificationPreferences{}, err
+	}
+	return preferences, nil
+}
+
+func (n *Notifier) History(filter HistoryFilter) []domain.NotificationDelivery {
+	return n.history.List(filter)
+}
+
+func (n *Notifier) validate(preferences domain.NotificationPreferences) error {
+	seen := make(map[domain.NotificationChannel]struct{}, len(preferences.Targets))
+	for _, target := range preferences.Targets {
+		channel, ok := n.channels[target.Channel]
+		if !ok {
+			return fmt.Errorf("%w: channel %q is not available", ErrInvalidPreferences, target.Channel)
+		}
+		if _, duplicate := seen[target.Channel]; duplicate {
+			return fmt.Errorf("%w: channel %q is set twice", ErrInvalidPreferences, target.Channel)
+		}
+		seen[target.Channel] = struct{}{}
+		if err := channel.ValidateAddress(target.Address); err != nil {
+			return fmt.Errorf("%w: %s: %v", ErrInvalidPreferences, target.Channel, err)
+		}
+	}
+
+	known := make(map[domain.TraderEventType]struct{})
+	for _, eventType := range n.templates.EventTypes() {
+		known[eventType] = struct{}{}
+	}
+	for _, eventType := range preferences.EventTypes {
+		if _, ok := known[eventType]; !ok {
+			return fmt.Errorf("%w: unknown event type %q", ErrInvalidPreferences, eventType)
+		}
+	}
+
+	if preferences.QuietHours != nil {
+		if _, err := inQuietHours(*preferences.QuietHours, time.Now()); err != nil {
+			return fmt.Errorf("%w: quiet hours: %v", ErrInvalidPreferences, err)
+		}
+	}
+	return nil
+}
+
+// recipients трейдер события и подписчики на события всех трейдеров, без повторов
+func (n *Notifier) recipients(event domain.TraderEvent) []domain.NotificationPreferences {
+	var result []domain.NotificationPreferences
+	seen := make(map[string]struct{})
+	if event.TraderID != "" {
+		if preferences, ok := n.preferences.Get(event.TraderID); ok {
+			result = append(result, preferences)
+			seen[preferences.UserID] = struct{}{}
+		}
+	}
+	for _, preferences := range n.preferences.AllTraders() {
+		if _, ok := seen[preferences.UserID]; !ok {
+			result = append(result, preferences)
+			seen[preferences.UserID] = struct{}{}
+		}
+	}
+	return result
+}
+
+func (n *Notifier) deliver(ctx context.Context, event domain.TraderEvent) {
+	subject, body, ok, err := n.templates.Render(event)
+	if !ok {
+		return
+	}
+	if err != nil {
+		log.Printf("notifications: failed to render %s event: %v", event.Type, err)
+		return
+	}
+
+	for _, preferences := range n.recipients(event) {
+		if !preferences.Subscribed(event.Type) {
+			continue
+		}
+		quiet := false
+		if preferences.QuietHours != nil {
+			// подписка проверена при сохранении, ошибка тут возможна только при смене базы часовых поясов
+			quiet, _ = inQuietHours(*preferences.QuietHours, time.Now())
+		}
+
+		for _, target := range preferences.Targets {
+			channel, ok := n.channels[target.Channel]
+			if !target.Enabled || !ok {
+				continue
+			}
+			delivery := domain.NotificationDelivery{
+				ID:        newDeliveryID(),
+				UserID:    preferences.UserID,
+				EventType: event.Type,
+				EventID:   event.ID,
+				TraderID:  event.TraderID,
+				Channel:   target.Channel,
+				Address:   target.Address,
+				Subject:   subject,
+				Body:      body,
+				CreatedAt: time.Now(),
+			}
+			if quiet {
+				delivery.Status = domain.NotificationQuietHours
+				n.history.Add(delivery)
+				continue
+			}
+
+			message := Message{To: target.Address, Subject: subject, Body: body, Event: event}
+			delivery.Status, delivery.Attempts, err = n.send(ctx, channel, message)
+			if err != nil {
+				delivery.Error = err.Error()
+				log.Printf("notifications: %s to user %s failed after %d attempts: %v",
+					target.Channel, preferences.UserID, delivery.Attempts, err)
+			}
+			n.history.Add(delivery)
+		}
+	}
+}
+
+func (n *Notifier) send(ctx context.Context, channel Channel, message Message) (domain.NotificationStatus, int, error) {
+	var err error
+	for attempt := 1; attempt <= n.config.MaxAttempts; attempt++ {
+		sendCtx, cancel := context.WithTimeout(ctx, n.config.SendTimeout)
+		err = channel.Send(sendCtx, message)
+		cancel()
+		if err == nil {
+			return domain.NotificationSent, attempt, nil
+		}
+		if attempt == n.config.MaxAttempts {
+			break
+		}
+		select {
+		case <-ctx.Done():
+			return domain.NotificationFailed, attempt, err
+		case <-time.After(n.config.RetryDelay * time.Duration(attempt)):
+		}
+	}
+	return domain.NotificationFailed, n.config.MaxAttempts, err
+}
+
+// inQuietHours попадает ли now в тихие часы в часовом поясе пользователя
+func inQuietHours(quiet domain.QuietHours, now time.Time) (bool, error) {
+	location := time.UTC
+	if quiet.Timezone != "" {
+		loaded, err := time.LoadLocation(quiet.Timezone)
+		if err != nil {
+			return false, fmt.Errorf("unknown timezone %q", quiet.Timezone)
+		}
+		location = loaded
+	}
+	start, err := minuteOfDay(quiet.Start)
+	if err != nil {
+		return false, err
+	}
+	end, err := minuteOfDay(quiet.End)
+	if err != nil {
+		return false, err
+	}
+
+	local := now.In(location)
+	minute := local.Hour()*60 + local.Minute()
+	if start <= end {
+		return minute >= start && minute < end, nil
+	}
+	return minute >= start || minute < end, nil
+}
+
+func minuteOfDay(value string) (int, error) {
+	parsed, err := time.Parse("15:04", value)
+	if err != nil {
+		return 0, fmt.Errorf("time %q must be HH:MM", value)
+	}
+	return parsed.Hour()*60 + parsed.Minute(), nil
+}
+
+func newDeliveryID() string {
+	raw := make([]byte, 8)
+	rand.Read(raw)
+	return hex.EncodeToString(raw)
+}
@@ -0,0 +1,187 @@
+package notifications
+
+import (
+	"log"
+	"sort"
+	"sync"
+
+	"github.com/LavaJover/shvark-api-gateway/internal/domain"
+	"github.com/LavaJover/shvark-api-gateway/internal/storage"
+)
+
+// PreferencesStore хранилище подписок пользователей
+type PreferencesStore interface {
+	Get(userID string) (domain.NotificationPreferences, bool)
+	Save(preferences domain.NotificationPreferences) error
+	// AllTraders подписки с включенными событиями всех трейдеров
+	AllTraders() []domain.NotificationPreferences
+	// All все подписки
+	All() []domain.NotificationPreferences
+}
+
+// FilePreferencesStore держит подписки в памяти и перезаписывает JSON-файл при каждом изменении,
+// пустой путь - только память
+type FilePreferencesStore struct {
+	path string
+
+	mu          sync.RWMutex
+	preferences map[string]domain.NotificationPreferences
+}
+
+func NewFilePreferencesStore(path string) (*FilePreferencesStore, error) {
+	store := &FilePreferencesStore{
+		path:        path,
+		preferences: make(map[string]domain.NotificationPreferences),
+	}
+	if path == "" {
+		log.Printf("notification preferences file is not configured: subscriptions will not survive restart")
+		return store, nil
+	}
+
+	var preferences []domain.NotificationPreferences
+	if err := storage.ReadJSON(path, &preferences); err != nil {
+		return nil, err
+	}
+	for _, item := range preferences {
+		store.preferences[item.UserID] = item
+	}
+	return store, nil
+}
+
+func (s *FilePreferencesStore) Get(userID string) (domain.NotificationPreferences, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	preferences, ok := s.preferences[userID]
+	return preferences, ok
+}
+
+func (s *FilePreferencesStore) Save(preferences domain.NotificationPreferences) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	previous, existed := s.preferences[preferences.UserID]
+	s.preferences[preferences.UserID] = preferences
+	if err := s.persist(); err != nil {
+		if existed {
+			s.preferences[preferences.UserID] = previous
+		} else {
+			delete(s.preferences, preferences.UserID)
+		}
+		return err
+	}
+	return nil
+}
+
+func (s *FilePreferencesStore) AllTraders() []domain.NotificationPreferences {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var result []domain.NotificationPreferences
+	for _, preferences := range s.preferences {
+		if preferences.AllTraders {
+			result = append(result, preferences)
+		}
+	}
+	return result
+}
+
+func (s *FilePreferencesStore) All() []domain.NotificationPreferences {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	result := make([]domain.NotificationPreferences, 0, len(s.preferences))
+	for _, preferences := range s.preferences {
+		result = append(result, preferences)
+	}
+	return result
+}
+
+// persist перезаписывает файл снимком всех записей
+func (s *FilePreferencesStore) persist() error {
+	if s.path == "" {
+		return nil
+	}
+
+	preferences := make([]domain.NotificationPreferences, 0, len(s.preferences))
+	for _, item := range s.preferences {
+		preferences = append(preferences, item)
+	}
+	sort.Slice(preferences, func(i, j int) bool {
+		return preferences[i].UserID < preferences[j].UserID
+	})
+	return storage.WriteJSON(s.path, preferences)
+}
+
+// HistoryFilter условия выборки истории доставки
+type HistoryFilter struct {
+	UserID  string
+	Channel domain.NotificationChannel
+	Status  domain.NotificationStatus
+	Limit   int
+}
+
+// DeliveryHistory история доставки уведомлений
+type DeliveryHistory interface {
+	Add(delivery domain.NotificationDelivery)
+	// List возвращает записи от новых к старым
+	List(filter HistoryFilter) []domain.NotificationDelivery
+}
+
+// InMemoryDeliveryHistory хранит последние записи каждого пользователя
+type InMemoryDeliveryHistory struct {
+	perUser int
+
+	mu      sync.RWMutex
+	history map[string][]domain.NotificationDelivery
+}
+
+func NewInMemoryDeliveryHistory(perUser int) *InMemoryDeliveryHistory {
+	return &InMemoryDeliveryHistory{
+		perUser: perUser,
+		history: make(map[string][]domain.NotificationDelivery),
+	}
+}
+
+func (h *InMemoryDeliveryHistory) Add(delivery domain.NotificationDelivery) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	history := append(h.history[delivery.UserID], delivery)
+	if len(history) > h.perUser {
+		history = history[len(history)-h.perUser:]
+	}
+	h.history[delivery.UserID] = history
+}
+
+func (h *InMemoryDeliveryHistory) List(filter HistoryFilter) []domain.NotificationDelivery {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	var deliveries []domain.NotificationDelivery
+	if filter.UserID != "" {
+		deliveries = h.history[filter.UserID]
+	} else {
+		for _, history := range h.history {
+			deliveries = append(deliveries, history...)
+		}
+	}
+
+	result := make([]domain.NotificationDelivery, 0, len(deliveries))
+	for _, delivery := range deliveries {
+		if filter.Channel != "" && delivery.Channel != filter.Channel {
+			continue
+		}
+		if filter.Status != "" && delivery.Status != filter.Status {
+			continue
+		}
+		result = append(result, delivery)
+	}
+	sort.SliceStable(result, func(i, j int) bool {
+		return result[i].CreatedAt.After(result[j].CreatedAt)
+	})
+	if filter.Limit > 0 && len(result) > filter.Limit {
+		result = result[:filter.Limit]
+	}
+	return result
+}
@@ -0,0 +1,107 @@
+package notifications
+
+import (
+	"bytes"
+	"embed"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"text/template"
+
+	"github.com/LavaJover/shvark-api-gateway/internal/domain"
+)
+
+//go:embed builtin
+var builtinFS embed.FS
+
+// Templates шаблоны уведомлений, по файлу <event_type>.tmpl на тип события.
+// Файл определяет шаблоны "subject" и "body", данные - domain.TraderEvent.
+// На события без шаблона уведомления не отправляются.
+type Templates struct {
+	templates map[domain.TraderEventType]*template.Template
+}
+
+// LoadTemplates загружает шаблоны из каталога, пустой каталог - встроенные. Шаблоны каталога
+// заменяют встроенные с тем же именем, остальные встроенные остаются.
+func LoadTemplates(dir string) (*Templates, error) {
+	builtin, err := fs.Sub(builtinFS, "builtin")
+	if err != nil {
+		return nil, err
+	}
+	templates := &Templates{templates: make(map[domain.TraderEventType]*template.Template)}
+	if err := templates.load(builtin); err != nil {
+		return nil, fmt.Errorf("builtin templates: %w", err)
+	}
+	if dir != "" {
+		if err := templates.load(os.DirFS(dir)); err != nil {
+			return nil, fmt.Errorf("templates %s: %w", dir, err)
+		}
+	}
+	return templates, nil
+}
+
+func (t *Templates) load(fsys fs.FS) error {
+	names, err := fs.Glob(fsys, "*.tmpl")
+	if err != nil {
+		return err
+	}
+	for _, name := range names {
+		raw, err := fs.ReadFile(fsys, name)
+		if err != nil {
+			return err
+		}
+		tmpl, err := template.New(name).Option("missingkey=zero").Parse(string(raw))
+		if err != nil {
+			return err
+		}
+		if tmpl.Lookup("subject") == nil || tmpl.Lookup("body") == nil {
+			return fmt.Errorf("%s: subject and body templates are required", name)
+		}
+		// шаблон проверяется на пустом событии, чтобы ошибки находились при загрузке, а не при отправке
+		if _, _, err := render(tmpl, domain.TraderEvent{}); err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+		t.templates[domain.TraderEventType(strings.TrimSuffix(name, path.Ext(name)))] = tmpl
+	}
+	return nil
+}
+
+// EventTypes типы событий, для которых есть шаблоны
+func (t *Templates) EventTypes() []domain.TraderEventType {
+	types := make([]domain.TraderEventType, 0, len(t.templates))
+	for eventType := range t.templates {
+		types = append(types, eventType)
+	}
+	sort.Slice(types, func(i, j int) bool { return types[i] < types[j] })
+	return types
+}
+
+// Has есть ли шаблон для типа события
+func (t *Templates) Has(eventType domain.TraderEventType) bool {
+	_, ok := t.templates[eventType]
+	return ok
+}
+
+// Render возвращает тему и текст уведомления, false - для события нет шаблона
+func (t *Templates) Render(event domain.TraderEvent) (string, string, bool, error) {
+	tmpl, ok := t.templates[event.Type]
+	if !ok {
+		return "", "", false, nil
+	}
+	subject, body, err := render(tmpl, event)
+	return subject, body, true, err
+}
+
+func render(tmpl *template.Template, event domain.TraderEvent) (string, string, error) {
+	var subject, body bytes.Buffer
+	if err := tmpl.ExecuteTemplate(&subject, "subject", event); err != nil {
+		return "", "", err
+	}
+	if err := tmpl.ExecuteTemplate(&body, "body", event); err != nil {
+		return "", "", err
+	}
+	return strings.TrimSpace(subject.String()), strings.TrimSpace(body.String()), nil
+}
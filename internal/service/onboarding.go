@@ -0,0 +1,519 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/LavaJover/shvark-api-gateway/internal/client"
+	"github.com/LavaJover/shvark-api-gateway/internal/domain"
+	orderpb "github.com/LavaJover/shvark-order-service/proto/gen/order"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/durationpb"
+)
+
+var (
+	ErrOnboardingNotFound     = errors.New("onboarding not found")
+	ErrOnboardingInProgress   = errors.New("onboarding is in progress")
+	ErrOnboardingNotResumable = errors.New("onboarding cannot be resumed")
+	ErrOnboardingFinished     = errors.New("onboarding is already finished")
+)
+
+// OnboardingParams данные нового пользователя. Traffic - необязательный трафик по умолчанию.
+type OnboardingParams struct {
+	Kind      domain.OnboardingKind
+	Login     string
+	Username  string
+	Password  string
+	Traffic   *domain.OnboardingTraffic
+	CreatedBy string
+}
+
+// OnboardingConfig повторы шагов и роли RBAC, которые выдаются новым пользователям
+type OnboardingConfig struct {
+	MaxAttempts  int
+	RetryDelay   time.Duration
+	StuckAfter   time.Duration // сага в статусе running без изменений дольше этого считается зависшей
+	TraderRole   string
+	MerchantRole string
+}
+
+// OnboardingService заводит команды и мерчантов сагой: регистрация в SSO, роль в authz, кошелек
+// и, если задан, трафик по умолчанию. Каждый шаг сохраняется, при ошибке повторяется, а после
+// исчерпания повторов сага останавливается: админ продолжает ее с упавшего шага или отменяет,
+// и тогда выполненные шаги откатываются в обратном порядке.
+type OnboardingService struct {
+	ssoClient    *client.SSOClient
+	userClient   *client.UserClient
+	authzClient  *client.AuthzClient
+	walletClient *client.HTTPWalletClient
+	orderClient  *client.OrderClient
+	store        OnboardingStore
+	config       OnboardingConfig
+
+	mu     sync.Mutex
+	active map[string]struct{} // саги, которые выполняются прямо сейчас
+}
+
+func NewOnboardingService(
+	ssoClient *client.SSOClient,
+	userClient *client.UserClient,
+	authzClient *client.AuthzClient,
+	walletClient *client.HTTPWalletClient,
+	orderClient *client.OrderClient,
+	store OnboardingStore,
+	config OnboardingConfig,
+) *OnboardingService {
+	if config.MaxAttempts < 1 {
+		config.MaxAttempts = 1
+	}
+	return &OnboardingService{
+		ssoClient:    ssoClient,
+		userClient:   userClient,
+		authzClient:  authzClient,
+		walletClient: walletClient,
+		orderClient:  orderClient,
+		store:        store,
+		config:       config,
+		active:       make(map[string]struct{}),
+	}
+}
+
+// Start создает сагу и выполняет ее до конца или до шага, который не прошел после повторов.
+// Сага возвращается и при ошибке, чтобы вызывающий видел, на каком шаге она остановилась.
+func (s *OnboardingService) Start(ctx context.Context, params OnboardingParams) (domain.Onboarding, error) {
+	id, err := randomHex(8)
+	if err != nil {
+		return domain.Onboarding{}, err
+	}
+	now := time.Now()
+	onboarding := domain.Onboarding{
+		ID:        id,
+		Kind:      params.Kind,
+		Login:     params.Login,
+		Username:  params.Username,
+		Traffic:   params.Traffic,
+		Status:    domain.OnboardingRunning,
+		CreatedBy: params.CreatedBy,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	steps := []domain.OnboardingStepName{
+		domain.OnboardingStepRegister,
+		domain.OnboardingStepAssignRole,
+		domain.OnboardingStepCreateWallet,
+	}
+	if params.Traffic != nil {
+		steps = append(steps, domain.OnboardingStepCreateTraffic)
+	}
+	for _, name := range steps {
+		onboarding.Steps = append(onboarding.Steps, domain.OnboardingStep{
+			Name:   name,
+			Status: domain.OnboardingStepPending,
+		})
+	}
+	if err := s.store.Save(onboarding); err != nil {
+		return domain.Onboarding{}, fmt.Errorf("save onboarding: %w", err)
+	}
+
+	s.acquire(onboarding.ID)
+	defer s.release(onboarding.ID)
+	err = s.run(ctx, &onboarding, params.Password)
+	return onboarding, err
+}
+
+// Resume продолжает остановленную сагу с первого невыполненного шага. Сагу, не прошедшую
+// регистрацию, продолжить нельзя: пароль не сохраняется, ее нужно создать заново.
+func (s *OnboardingService) Resume(ctx context.Context, id string) (domain.Onboarding, error) {
+	onboarding, err := s.stopped(id)
+	if err != nil {
+		return domain.Onboarding{}, err
+	}
+	defer s.release(id)
+
+	if step := onboarding.Step(domain.OnboardingStepRegister); step == nil || step.Status != domain.OnboardingStepDone {
+		return onboarding, fmt.Errorf("%w: user was not registered, create it again", ErrOnboardingNotResumable)
+	}
+	onboarding.Status = domain.OnboardingRunning
+	onboarding.Error = ""
+	err = s.run(ctx, &onboarding, "")
+	return onboarding, err
+}
+
+// Abort отменяет остановленную сагу: выполненные шаги откатываются в обратном порядке.
+// Регистрацию и кошелек upstream-сервисы удалять не умеют, такие шаги помечаются not_compensable.
+func (s *OnboardingService) Abort(id string) (domain.Onboarding, error) {
+	onboarding, err := s.stopped(id)
+	if err != nil {
+		return domain.Onboarding{}, err
+	}
+	defer s.release(id)
+
+	for i := len(onboarding.Steps) - 1; i >= 0; i-- {
+		step := &onboarding.Steps[i]
+		if step.Status != domain.OnboardingStepDone {
+			continue
+		}
+		compensable, err := s.compensate(&onboarding, step.Name)
+		if err != nil {
+			step.Error = "compensation: " + err.Error()
+			onboarding.Error = fmt.Sprintf("compensate %s: %v", step.Name, err)
+			s.save(&onboarding)
+			return onboarding, fmt.Errorf("compensate %s: %w", step.Name, err)
+		}
+		if compensable {
+			step.Status = domain.OnboardingStepCompensated
+		} else {
+			step.Status = domain.OnboardingStepNotCompensable
+		}
+		s.save(&onboarding)
+	}
+
+	onboarding.Status = domain.OnboardingCompensated
+	onboarding.Error = ""
+	s.save(&onboarding)
+	return onboarding, nil
+}
+
+func (s *OnboardingService) Get(id string) (domain.Onboarding, error) {
+	onboarding, ok := s.store.ByID(id)
+	if !ok {
+		return domain.Onboarding{}, ErrOnboardingNotFound
+	}
+	return onboarding, nil
+}
+
+// Stuck саги, которые ждут админа: остановленные после ошибки и оборванные перезапуском
+func (s *OnboardingService) Stuck() []domain.Onboarding {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var result []domain.Onboarding
+	for _, onboarding := range s.store.List() {
+		if s.isStuck(onboarding) {
+			result = append(result, onboarding)
+		}
+	}
+	return result
+}
+
+// List все саги, status - фильтр по статусу
+func (s *OnboardingService) List(filter domain.OnboardingStatus) []domain.Onboarding {
+	var result []domain.Onboarding
+	for _, onboarding := range s.store.List() {
+		if filter == "" || onboarding.Status == filter {
+			result = append(result, onboarding)
+		}
+	}
+	return result
+}
+
+// isStuck вызывается под s.mu
+func (s *OnboardingService) isStuck(onboarding domain.Onboarding) bool {
+	if _, running := s.active[onboarding.ID]; running {
+		return false
+	}
+	switch onboarding.Status {
+	case domain.OnboardingFailed:
+		return true
+	case domain.OnboardingRunning:
+		return time.Since(onboarding.UpdatedAt) > s.config.StuckAfter
+	}
+	return false
+}
+
+// stopped загружает сагу, которую можно продолжить или отменить, и помечает ее выполняемой
+func (s *OnboardingService) stopped(id string) (domain.Onboarding, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	onboarding, ok := s.store.ByID(id)
+	if !ok {
+		return domain.Onboarding{}, ErrOnboardingNotFound
+	}
+	if _, running := s.active[id]; running {
+		return domain.Onboarding{}, ErrOnboardingInProgress
+	}
+	if onboarding.Status == domain.OnboardingCompleted || onboarding.Status == domain.OnboardingCompensated {
+		return domain.Onboarding{}, ErrOnboardingFinished
+	}
+	if !s.isStuck(onboarding) {
+		return domain.Onboarding{}, ErrOnboardingInProgress
+	}
+	s.active[id] = struct{}{}
+	return onboarding, nil
+}
+
+func (s *OnboardingService) acquire(id string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.active[id] = struct{}{}
+}
+
+func (s *OnboardingService) release(id string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.active, id)
+}
+
+func (s *OnboardingService) run(ctx context.Context, onboarding *domain.Onboarding, password string) error {
+	for i := range onboarding.Steps {
+		step := &onboarding.Steps[i]
+		if step.Status == domain.OnboardingStepDone {
+			continue
+		}
+		if err := s.runStep(ctx, onboarding, step, password); err != nil {
+			onboarding.Status = domain.OnboardingFailed
+			onboarding.Error = fmt.Sprintf("%s: %v", step.Name, err)
+			s.save(onboarding)
+			log.Printf("onboarding %s (%s %s) stopped at %s: %v", onboarding.ID, onboarding.Kind, onboarding.Login, step.Name, err)
+			return fmt.Errorf("%s: %w", step.Name, err)
+		}
+	}
+	onboarding.Status = domain.OnboardingCompleted
+	s.save(onboarding)
+	return nil
+}
+
+// runStep выполняет шаг с повторами. Ошибки, которые не исправятся повтором (неверные данные,
+// логин занят), останавливают сагу сразу.
+func (s *OnboardingService) runStep(ctx context.Context, onboarding *domain.Onboarding, step *domain.OnboardingStep, password string) error {
+	var err error
+	// ambiguous: Register оборвался по сети, и SSO мог создать пользователя, не успев ответить
+	ambiguous := false
+	for attempt := 1; attempt <= s.config.MaxAttempts; attempt++ {
+		step.Attempts++
+		err = s.execute(onboarding, step.Name, password, ambiguous)
+		if err == nil {
+			now := time.Now()
+			step.Status = domain.OnboardingStepDone
+			step.Error = ""
+			step.CompletedAt = &now
+			s.save(onboarding)
+			return nil
+		}
+		step.Status = domain.OnboardingStepFailed
+		step.Error = err.Error()
+		s.save(onboarding)
+		if !retryableOnboardingError(err) || attempt == s.config.MaxAttempts {
+			break
+		}
+		ambiguous = ambiguous || ambiguousRegisterError(err)
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(s.config.RetryDelay * time.Duration(attempt)):
+		}
+	}
+	return err
+}
+
+func (s *OnboardingService) execute(onboarding *domain.Onboarding, name domain.OnboardingStepName, password string, ambiguous bool) error {
+	switch name {
+	case domain.OnboardingStepRegister:
+		return s.register(onboarding, password, ambiguous)
+
+	case domain.OnboardingStepAssignRole:
+		if _, err := s.authzClient.AssignRole(onboarding.UserID, s.authzRole(onboarding.Kind)); err != nil {
+			return upstreamError(UpstreamAuthz, err)
+		}
+		return nil
+
+	case domain.OnboardingStepCreateWallet:
+		address, err := s.walletClient.CreateWallet(onboarding.UserID)
+		if err != nil {
+			return upstreamError(UpstreamWallet, err)
+		}
+		onboarding.WalletAddress = address
+		return nil
+
+	case domain.OnboardingStepCreateTraffic:
+		return s.createTraffic(onboarding)
+	}
+	return fmt.Errorf("unknown onboarding step %q", name)
+}
+
+// register регистрирует пользователя в SSO. Register не идемпотентен: если прошлая попытка
+// оборвалась по сети, пользователь мог быть создан, поэтому повтор сначала ищет его по логину,
+// а AlreadyExists после такой попытки означает, что регистрация уже прошла. Чтобы не принять
+// за своего пользователя, который был до саги, первая попытка проверяет, что логин свободен.
+func (s *OnboardingService) register(onboarding *domain.Onboarding, password string, ambiguous bool) error {
+	userID, err := s.userIDByLogin(onboarding.Login)
+	if err != nil {
+		return err
+	}
+	if userID != "" {
+		if !ambiguous {
+			return upstreamError(UpstreamSSO, status.Error(codes.AlreadyExists, "user with this login already exists"))
+		}
+		onboarding.UserID = userID
+		return nil
+	}
+
+	response, err := s.ssoClient.Register(onboarding.Login, onboarding.Username, password, ssoRole(onboarding.Kind))
+	if err != nil {
+		if ambiguous && status.Code(err) == codes.AlreadyExists {
+			// пользователь появился между поиском и регистрацией: предыдущий запрос дошел до SSO позже
+			if userID, lookupErr := s.userIDByLogin(onboarding.Login); lookupErr == nil && userID != "" {
+				onboarding.UserID = userID
+				return nil
+			}
+		}
+		return upstreamError(UpstreamSSO, err)
+	}
+	onboarding.UserID = response.UserId
+	return nil
+}
+
+// userIDByLogin ID пользователя с логином, пустой - логин свободен
+func (s *OnboardingService) userIDByLogin(login string) (string, error) {
+	response, err := s.userClient.GetUserByLogin(login)
+	if status.Code(err) == codes.NotFound {
+		return "", nil
+	}
+	if err != nil {
+		return "", upstreamError(UpstreamUser, err)
+	}
+	return response.UserId, nil
+}
+
+// createTraffic идемпотентен: предыдущая попытка могла создать трафик и не дождаться ответа
+func (s *OnboardingService) createTraffic(onboarding *domain.Onboarding) error {
+	traffic := onboarding.Traffic
+	merchantID, traderID := onboardingTrafficParties(onboarding)
+	existing, err := s.findTraffic(merchantID, traderID)
+	if err != nil {
+		return err
+	}
+	if existing != "" {
+		traffic.TrafficID = existing
+		return nil
+	}
+
+	err = s.orderClient.AddTraffic(&orderpb.AddTrafficRequest{
+		MerchantId:          merchantID,
+		TraderId:            traderID,
+		TraderRewardPercent: traffic.TraderRewardPercent,
+		TraderPriority:      traffic.TraderPriority,
+		Enabled:             true,
+		PlatformFee:         traffic.PlatformFee,
+		Name:                traffic.Name,
+		ActivityParams: &orderpb.TrafficActivityParameters{
+			MerchantUnlocked:  true,
+			TraderUnlocked:    true,
+			ManuallyUnlocked:  true,
+			AntifraudUnlocked: true,
+		},
+		AntifraudParams: &orderpb.TrafficAntifraudParameters{
+			AntifraudRequired: traffic.AntifraudRequired,
+		},
+		BusinessParams: &orderpb.TrafficBusinessParameters{
+			MerchantDealsDuration: durationpb.New(traffic.MerchantDealsDuration),
+		},
+	})
+	if err != nil {
+		return upstreamError(UpstreamOrder, err)
+	}
+	// AddTraffic не возвращает ID, а он нужен для отката
+	id, err := s.findTraffic(merchantID, traderID)
+	if err != nil {
+		return err
+	}
+	traffic.TrafficID = id
+	return nil
+}
+
+func (s *OnboardingService) findTraffic(merchantID, traderID string) (string, error) {
+	response, err := s.orderClient.GetTraderTraffic(&orderpb.GetTraderTrafficRequest{
+		TraderId: traderID,
+	})
+	if err != nil {
+		return "", upstreamError(UpstreamOrder, err)
+	}
+	for _, record := range response.Records {
+		if record.MerchantId == merchantID {
+			return record.Id, nil
+		}
+	}
+	return "", nil
+}
+
+// compensate откатывает шаг, false - шаг откатить нельзя
+func (s *OnboardingService) compensate(onboarding *domain.Onboarding, name domain.OnboardingStepName) (bool, error) {
+	switch name {
+	case domain.OnboardingStepCreateTraffic:
+		if onboarding.Traffic == nil || onboarding.Traffic.TrafficID == "" {
+			return false, nil
+		}
+		if err := s.orderClient.DeleteTraffic(onboarding.Traffic.TrafficID); err != nil {
+			return false, upstreamError(UpstreamOrder, err)
+		}
+		return true, nil
+
+	case domain.OnboardingStepAssignRole:
+		if _, err := s.authzClient.RevokeRole(onboarding.UserID, s.authzRole(onboarding.Kind)); err != nil {
+			return false, upstreamError(UpstreamAuthz, err)
+		}
+		return true, nil
+	}
+	return false, nil
+}
+
+// save сохраняет прогресс саги. Ошибка хранилища не прерывает сагу: шаги в upstream уже выполнены,
+// а состояние в памяти останется верным до следующего успешного сохранения.
+func (s *OnboardingService) save(onboarding *domain.Onboarding) {
+	onboarding.UpdatedAt = time.Now()
+	if err := s.store.Save(*onboarding); err != nil {
+		log.Printf("failed to save onboarding %s: %v", onboarding.ID, err)
+	}
+}
+
+func (s *OnboardingService) authzRole(kind domain.OnboardingKind) string {
+	if kind == domain.OnboardingMerchant {
+		return s.config.MerchantRole
+	}
+	return s.config.TraderRole
+}
+
+// ssoRole роль пользователя в SSO, как ее передавали ручки создания команды и мерчанта
+func ssoRole(kind domain.OnboardingKind) string {
+	if kind == domain.OnboardingMerchant {
+		return "MERCHANT"
+	}
+	return "TRADER"
+}
+
+func onboardingTrafficParties(onboarding *domain.Onboarding) (merchantID, traderID string) {
+	if onboarding.Kind == domain.OnboardingMerchant {
+		return onboarding.UserID, onboarding.Traffic.CounterpartID
+	}
+	return onboarding.Traffic.CounterpartID, onboarding.UserID
+}
+
+// ambiguousRegisterError ошибка транспорта при вызове Register: неизвестно, создан ли пользователь.
+// Ошибки поиска по логину в user-service сюда не относятся, до Register дело не дошло.
+func ambiguousRegisterError(err error) bool {
+	var upstream *UpstreamError
+	if !errors.As(err, &upstream) || upstream.Upstream != UpstreamSSO {
+		return false
+	}
+	switch status.Code(upstream.Err) {
+	case codes.Unavailable, codes.DeadlineExceeded:
+		return true
+	}
+	return false
+}
+
+func retryableOnboardingError(err error) bool {
+	if st, ok := status.FromError(errors.Unwrap(err)); ok {
+		switch st.Code() {
+		case codes.InvalidArgument, codes.AlreadyExists, codes.PermissionDenied, codes.FailedPrecondition, codes.Unauthenticated:
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,116 @@
+package service
+
+import (
+	"log"
+	"sort"
+	"sync"
+
+	"github.com/LavaJover/shvark-api-gateway/internal/domain"
+	"github.com/LavaJover/shvark-api-gateway/internal/storage"
+)
+
+// OnboardingStore хранилище саг онбординга
+type OnboardingStore interface {
+	// Save создает или заменяет сагу по ID
+	Save(onboarding domain.Onboarding) error
+	ByID(id string) (domain.Onboarding, bool)
+	// List саги от новых к старым
+	List() []domain.Onboarding
+}
+
+// FileOnboardingStore держит саги в памяти и перезаписывает JSON-файл после каждого шага,
+// чтобы незавершенный онбординг можно было продолжить после перезапуска
+type FileOnboardingStore struct {
+	path string
+
+	mu          sync.RWMutex
+	onboardings map[string]domain.Onboarding
+}
+
+// NewFileOnboardingStore загружает саги из файла, пустой путь - только память
+func NewFileOnboardingStore(path string) (*FileOnboardingStore, error) {
+	store := &FileOnboardingStore{
+		path:        path,
+		onboardings: make(map[string]domain.Onboarding),
+	}
+	if path == "" {
+		log.Printf("onboarding file is not configured: stuck onboardings will be lost on restart")
+		return store, nil
+	}
+
+	var onboardings []domain.Onboarding
+	if err := storage.ReadJSON(path, &onboardings); err != nil {
+		return nil, err
+	}
+	for _, onboarding := range onboardings {
+		store.onboardings[onboarding.ID] = onboarding
+	}
+	return store, nil
+}
+
+func (s *FileOnboardingStore) Save(onboarding domain.Onboarding) error {
+	onboarding = cloneOnboarding(onboarding)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	previous, existed := s.onboardings[onboarding.ID]
+	s.onboardings[onboarding.ID] = onboarding
+	if err := s.persist(); err != nil {
+		if existed {
+			s.onboardings[onboarding.ID] = previous
+		} else {
+			delete(s.onboardings, onboarding.ID)
+		}
+		return err
+	}
+	return nil
+}
+
+func (s *FileOnboardingStore) ByID(id string) (domain.Onboarding, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	onboarding, ok := s.onboardings[id]
+	return cloneOnboarding(onboarding), ok
+}
+
+func (s *FileOnboardingStore) List() []domain.Onboarding {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	result := make([]domain.Onboarding, 0, len(s.onboardings))
+	for _, onboarding := range s.onboardings {
+		result = append(result, cloneOnboarding(onboarding))
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].CreatedAt.After(result[j].CreatedAt)
+	})
+	return result
+}
+
+// persist перезаписывает файл снимком всех записей
+func (s *FileOnboardingStore) persist() error {
+	if s.path == "" {
+		return nil
+	}
+
+	onboardings := make([]domain.Onboarding, 0, len(s.onboardings))
+	for _, onboarding := range s.onboardings {
+		onboardings = append(onboardings, onboarding)
+	}
+	sort.Slice(onboardings, func(i, j int) bool {
+		return onboardings[i].ID < onboardings[j].ID
+	})
+	return storage.WriteJSON(s.path, onboardings)
+}
+
+// cloneOnboarding копирует шаги и трафик, чтобы сага в хранилище не менялась вместе с сагой вызывающего
+func cloneOnboarding(onboarding domain.Onboarding) domain.Onboarding {
+	onboarding.Steps = append([]domain.OnboardingStep(nil), onboarding.Steps...)
+	if onboarding.Traffic != nil {
+		traffic := *onboarding.Traffic
+		onboarding.Traffic = &traffic
+	}
+	return onboarding
+}
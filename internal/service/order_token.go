@@ -0,0 +1,100 @@
+package service
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Области действия токенов ордера
+const (
+	OrderTokenScopeEvents   = "events"
+	OrderTokenScopeDeeplink = "deeplink"
+	// ссылки на файлы доказательств: вместо ID ордера в токене ID файла
+	OrderTokenScopeEvidence = "evidence"
+)
+
+var (
+	ErrOrderTokenInvalid = errors.New("invalid order token")
+	ErrOrderTokenExpired = errors.New("order token expired")
+)
+
+// OrderTokenService выпускает и проверяет короткоживущие HMAC-токены,
+// привязанные к одному ордеру. Такие токены можно передавать в query-параметре из браузера.
+type OrderTokenService struct {
+	secret []byte
+	ttl    time.Duration
+}
+
+func NewOrderTokenService(secret string, ttl time.Duration) *OrderTokenService {
+	key := []byte(secret)
+	if len(key) == 0 {
+		key = make([]byte, 32)
+		if _, err := rand.Read(key); err != nil {
+			log.Fatalf("failed to generate order token secret: %v", err)
+		}
+		log.Printf("order token secret is not configured, using random secret: tokens will not survive restart")
+	}
+	return &OrderTokenService{
+		secret: key,
+		ttl:    ttl,
+	}
+}
+
+// TTL время жизни токена по умолчанию
+func (s *OrderTokenService) TTL() time.Duration {
+	return s.ttl
+}
+
+// Issue выпускает токен для ордера с указанной областью действия
+func (s *OrderTokenService) Issue(scope, orderID string, expiresAt time.Time) string {
+	payload := fmt.Sprintf("%s|%s|%d", scope, orderID, expiresAt.Unix())
+	encoded := base64.RawURLEncoding.EncodeToString([]byte(payload))
+	return encoded + "." + s.sign(encoded)
+}
+
+// Validate проверяет подпись, область действия, ордер и срок действия токена
+func (s *OrderTokenService) Validate(token, scope, orderID string) error {
+	_, err := s.ExpiresAt(token, scope, orderID)
+	return err
+}
+
+// ExpiresAt проверяет токен так же, как Validate, и возвращает срок его действия:
+// по нему долгие соединения закрываются, когда токен истекает
+func (s *OrderTokenService) ExpiresAt(token, scope, orderID string) (time.Time, error) {
+	encoded, signature, ok := strings.Cut(token, ".")
+	if !ok || !hmac.Equal([]byte(signature), []byte(s.sign(encoded))) {
+		return time.Time{}, ErrOrderTokenInvalid
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return time.Time{}, ErrOrderTokenInvalid
+	}
+	parts := strings.Split(string(payload), "|")
+	if len(parts) != 3 || parts[0] != scope || parts[1] != orderID {
+		return time.Time{}, ErrOrderTokenInvalid
+	}
+
+	expiresAt, err := strconv.ParseInt(parts[2], 10, 64)
+	if err != nil {
+		return time.Time{}, ErrOrderTokenInvalid
+	}
+	if time.Now().Unix() > expiresAt {
+		return time.Time{}, ErrOrderTokenExpired
+	}
+	return time.Unix(expiresAt, 0), nil
+}
+
+func (s *OrderTokenService) sign(encoded string) string {
+	mac := hmac.New(sha256.New, s.secret)
+	mac.Write([]byte(encoded))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
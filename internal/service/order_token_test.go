@@ -0,0 +1,83 @@
+package service
+
+import (
+	"encoding/base64"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestOrderTokenValidate(t *testing.T) {
+	tokens := NewOrderTokenService("secret", time.Minute)
+	other := NewOrderTokenService("other-secret", time.Minute)
+	valid := tokens.Issue(OrderTokenScopeEvents, "ord-1", time.Now().Add(time.Minute))
+
+	encoded, signature, _ := strings.Cut(valid, ".")
+	forged := base64.RawURLEncoding.EncodeToString([]byte("events|ord-2|9999999999"))
+
+	tests := []struct {
+		name    string
+		token   string
+		scope   string
+		orderID string
+		wantErr error
+	}{
+		{name: "valid", token: valid, scope: OrderTokenScopeEvents, orderID: "ord-1"},
+		{
+			name:    "expired",
+			token:   tokens.Issue(OrderTokenScopeEvents, "ord-1", time.Now().Add(-time.Second)),
+			scope:   OrderTokenScopeEvents,
+			orderID: "ord-1",
+			wantErr: ErrOrderTokenExpired,
+		},
+		{name: "deeplink scope", token: valid, scope: OrderTokenScopeDeeplink, orderID: "ord-1", wantErr: ErrOrderTokenInvalid},
+		{name: "evidence scope", token: valid, scope: OrderTokenScopeEvidence, orderID: "ord-1", wantErr: ErrOrderTokenInvalid},
+		{name: "other order", token: valid, scope: OrderTokenScopeEvents, orderID: "ord-2", wantErr: ErrOrderTokenInvalid},
+		{name: "other secret", token: other.Issue(OrderTokenScopeEvents, "ord-1", time.Now().Add(time.Minute)), scope: OrderTokenScopeEvents, orderID: "ord-1", wantErr: ErrOrderTokenInvalid},
+		{name: "payload swapped", token: forged + "." + signature, scope: OrderTokenScopeEvents, orderID: "ord-2", wantErr: ErrOrderTokenInvalid},
+		{name: "signature tampered", token: encoded + "." + strings.Repeat("A", len(signature)), scope: OrderTokenScopeEvents, orderID: "ord-1", wantErr: ErrOrderTokenInvalid},
+		{name: "no signature", token: encoded, scope: OrderTokenScopeEvents, orderID: "ord-1", wantErr: ErrOrderTokenInvalid},
+		{name: "empty", token: "", scope: OrderTokenScopeEvents, orderID: "ord-1", wantErr: ErrOrderTokenInvalid},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tokens.Validate(tt.token, tt.scope, tt.orderID)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Validate() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestOrderTokenExpiresAt(t *testing.T) {
+	tokens := NewOrderTokenService("secret", time.Minute)
+	expiresAt := time.Now().Add(time.Minute).Truncate(time.Second)
+
+	got, err := tokens.ExpiresAt(tokens.Issue(OrderTokenScopeEvents, "ord-1", expiresAt), OrderTokenScopeEvents, "ord-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !got.Equal(expiresAt) {
+		t.Fatalf("ExpiresAt() = %s, want %s", got, expiresAt)
+	}
+}
+
+// Без секрета сервис берет случайный ключ: токены другого экземпляра не принимаются
+func TestOrderTokenEmptySecret(t *testing.T) {
+	first := NewOrderTokenService("", time.Minute)
+	second := NewOrderTokenService("", time.Minute)
+	token := first.Issue(OrderTokenScopeDeeplink, "ord-1", time.Now().Add(time.Minute))
+
+	if err := first.Validate(token, OrderTokenScopeDeeplink, "ord-1"); err != nil {
+		t.Fatalf("Validate() by issuer error = %v", err)
+	}
+	if err := second.Validate(token, OrderTokenScopeDeeplink, "ord-1"); !errors.Is(err, ErrOrderTokenInvalid) {
+		t.Fatalf("Validate() by other instance error = %v, want ErrOrderTokenInvalid", err)
+	}
+	// токен, подписанный пустым ключом, не проходит
+	empty := &OrderTokenService{}
+	if err := first.Validate(empty.Issue(OrderTokenScopeDeeplink, "ord-1", time.Now().Add(time.Minute)), OrderTokenScopeDeeplink, "ord-1"); !errors.Is(err, ErrOrderTokenInvalid) {
+		t.Fatalf("Validate() of token signed with empty key error = %v, want ErrOrderTokenInvalid", err)
+	}
+}
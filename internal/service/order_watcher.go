@@ -0,0 +1,169 @@
+package service
+
+import (
+	"log"
+	"sync"
+	"time"
+
+	"github.com/LavaJover/shvark-api-gateway/internal/client"
+	"github.com/LavaJover/shvark-api-gateway/internal/domain"
+)
+
+const orderEventsBuffer = 16
+
+// OrderWatcher опрашивает order-service и раздает изменения ордера подписчикам.
+// На один ордер запускается один опрос, сколько бы клиентов его ни слушало.
+type OrderWatcher struct {
+	orderClient  *client.OrderClient
+	pollInterval time.Duration
+
+	mu      sync.Mutex
+	watches map[string]*orderWatch
+}
+
+type orderWatch struct {
+	orderID     string
+	subscribers map[chan domain.OrderEvent]struct{}
+	last        *domain.OrderEvent
+	stop        chan struct{}
+}
+
+func NewOrderWatcher(orderClient *client.OrderClient, pollInterval time.Duration) *OrderWatcher {
+	return &OrderWatcher{
+		orderClient:  orderClient,
+		pollInterval: pollInterval,
+		watches:      make(map[string]*orderWatch),
+	}
+}
+
+// Subscribe подписывает на события ордера. Канал закрывается, когда статус ордера
+// становится окончательным (IsFinal): отмененный ордер продолжает отслеживаться,
+// чтобы подписчики увидели переход в спор. Функция отписки должна быть вызвана всегда.
+func (w *OrderWatcher) Subscribe(orderID string) (<-chan domain.OrderEvent, func()) {
+	ch := make(chan domain.OrderEvent, orderEventsBuffer)
+
+	w.mu.Lock()
+	watch, exists := w.watches[orderID]
+	if !exists {
+		watch = &orderWatch{
+			orderID:     orderID,
+			subscribers: make(map[chan domain.OrderEvent]struct{}),
+			stop:        make(chan struct{}),
+		}
+		w.watches[orderID] = watch
+		go w.run(watch)
+	}
+	watch.subscribers[ch] = struct{}{}
+	if watch.last != nil {
+		snapshot := *watch.last
+		snapshot.Type = domain.OrderEventStatus
+		ch <- snapshot
+	}
+	w.mu.Unlock()
+
+	unsubscribe := func() {
+		w.mu.Lock()
+		defer w.mu.Unlock()
+		if _, ok := watch.subscribers[ch]; !ok {
+			return
+		}
+		delete(watch.subscribers, ch)
+		close(ch)
+		if len(watch.subscribers) == 0 && w.watches[orderID] == watch {
+			delete(w.watches, orderID)
+			close(watch.stop)
+		}
+	}
+	return ch, unsubscribe
+}
+
+func (w *OrderWatcher) run(watch *orderWatch) {
+	ticker := time.NewTicker(w.pollInterval)
+	defer ticker.Stop()
+
+	var previous *domain.Order
+	for {
+		response, err := w.orderClient.GetOrderByID(watch.orderID)
+		if err != nil {
+			log.Printf("order watcher: failed to get order %s: %v", watch.orderID, err)
+		} else {
+			current := orderToDomain(response.Order)
+			for _, event := range diffOrder(previous, current) {
+				w.broadcast(watch, event)
+			}
+			previous = current
+			if current.Status.IsFinal() {
+				w.finish(watch)
+				return
+			}
+		}
+
+		select {
+		case <-watch.stop:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func diffOrder(previous, current *domain.Order) []domain.OrderEvent {
+	var events []domain.OrderEvent
+	if previous == nil || previous.Status != current.Status {
+		events = append(events, newOrderEvent(domain.OrderEventStatus, current))
+	}
+	if previous != nil && (previous.Recalculated != current.Recalculated ||
+		previous.AmountFiat != current.AmountFiat ||
+		previous.AmountCrypto != current.AmountCrypto) {
+		events = append(events, newOrderEvent(domain.OrderEventRecalculated, current))
+	}
+	if !current.Status.IsTerminal() {
+		events = append(events, newOrderEvent(domain.OrderEventExpiry, current))
+	}
+	return events
+}
+
+func newOrderEvent(eventType domain.OrderEventType, order *domain.Order) domain.OrderEvent {
+	now := time.Now()
+	remaining := int64(order.ExpiresAt.Sub(now).Seconds())
+	if remaining < 0 {
+		remaining = 0
+	}
+	return domain.OrderEvent{
+		Type:             eventType,
+		OrderID:          order.ID,
+		Status:           order.Status,
+		AmountFiat:       order.AmountFiat,
+		AmountCrypto:     order.AmountCrypto,
+		CryptoRubRate:    order.CryptoRubRate,
+		Recalculated:     order.Recalculated,
+		ExpiresAt:        order.ExpiresAt.Unix(),
+		RemainingSeconds: remaining,
+		At:               now,
+	}
+}
+
+func (w *OrderWatcher) broadcast(watch *orderWatch, event domain.OrderEvent) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	watch.last = &event
+	for ch := range watch.subscribers {
+		select {
+		case ch <- event:
+		default:
+			// медленный клиент пропускает событие, следующее все равно содержит актуальное состояние
+		}
+	}
+}
+
+// finish закрывает всех подписчиков ордера, статус которого больше не изменится
+func (w *OrderWatcher) finish(watch *orderWatch) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	for ch := range watch.subscribers {
+		delete(watch.subscribers, ch)
+		close(ch)
+	}
+	if w.watches[watch.orderID] == watch {
+		delete(w.watches, watch.orderID)
+	}
+}
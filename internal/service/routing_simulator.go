@@ -0,0 +1,398 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"log"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/LavaJover/shvark-api-gateway/internal/client"
+	"github.com/LavaJover/shvark-api-gateway/internal/domain"
+	orderpb "github.com/LavaJover/shvark-order-service/proto/gen/order"
+)
+
+var (
+	ErrRoutingMerchantRequired = errors.New("merchant_id is required")
+	ErrRoutingAmount           = errors.New("amount_fiat must be positive")
+	ErrRoutingPaymentSystem    = errors.New("payment_system is required")
+)
+
+// routingBankDetailsPageSize размер страницы реквизитов трейдера
+const routingBankDetailsPageSize = 100
+
+// routingDevice статус устройства автоматики, к которому привязан реквизит
+type routingDevice struct {
+	enabled bool
+	online  bool
+}
+
+// RoutingSimulatorConfig сколько трейдеров проверяется параллельно и размер страницы трафика
+type RoutingSimulatorConfig struct {
+	Concurrency     int
+	TrafficPageSize int32
+	// RequireOnlineDevice реквизит, привязанный к выключенному или офлайн устройству, не получает ордер
+	RequireOnlineDevice bool
+}
+
+// RoutingSimulationParams параметры ордера, подбор реквизитов для которого симулируется.
+// Пустой BankCode - подходит реквизит любого банка.
+type RoutingSimulationParams struct {
+	MerchantID    string
+	AmountFiat    float64
+	PaymentSystem string
+	BankCode      string
+}
+
+// RoutingSimulatorService объясняет, почему ордер мерчанта не находит реквизиты: для каждого трейдера
+// с трафиком на мерчанта проверяет включенность и блокировки трафика, антифрод, реквизиты по платежной
+// системе и банку, их лимиты по сумме и обороту и устройства, к которым они привязаны. Ничего не меняет.
+// Это приближение логики order-service: очередь ордеров на реквизите и задержки между ордерами не учитываются.
+type RoutingSimulatorService struct {
+	orderClient  *client.OrderClient
+	deviceClient *client.DeviceClient
+	config       RoutingSimulatorConfig
+}
+
+func NewRoutingSimulatorService(
+	orderClient *client.OrderClient,
+	deviceClient *client.DeviceClient,
+	config RoutingSimulatorConfig,
+) *RoutingSimulatorService {
+	if config.Concurrency < 1 {
+		config.Concurrency = 1
+	}
+	if config.TrafficPageSize < 1 {
+		config.TrafficPageSize = 1000
+	}
+	return &RoutingSimulatorService{
+		orderClient:  orderClient,
+		deviceClient: deviceClient,
+		config:       config,
+	}
+}
+
+func (s *RoutingSimulatorService) Simulate(ctx context.Context, params RoutingSimulationParams) (*domain.RoutingSimulation, error) {
+	switch {
+	case params.MerchantID == "":
+		return nil, ErrRoutingMerchantRequired
+	case params.AmountFiat <= 0:
+		return nil, ErrRoutingAmount
+	case params.PaymentSystem == "":
+		return nil, ErrRoutingPaymentSystem
+	}
+
+	records, err := s.merchantTraffic(params.MerchantID)
+	if err != nil {
+		return nil, err
+	}
+
+	traders := make([]domain.RoutingTrader, len(records))
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < s.config.Concurrency; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for index := range jobs {
+				traders[index] = s.checkTrader(ctx, records[index], params)
+			}
+		}()
+	}
+	for index := range records {
+		jobs <- index
+	}
+	close(jobs)
+	wg.Wait()
+
+	simulation := &domain.RoutingSimulation{
+		MerchantID:    params.MerchantID,
+		AmountFiat:    params.AmountFiat,
+		PaymentSystem: params.PaymentSystem,
+		BankCode:      params.BankCode,
+		SimulatedAt:   time.Now(),
+		Traders:       traders,
+		Exclusions:    make(map[domain.RoutingExclusion]int),
+	}
+	for _, trader := range traders {
+		switch trader.Verdict() {
+		case domain.RoutingVerdictEligible:
+			simulation.Eligible++
+		case domain.RoutingVerdictExcluded:
+			simulation.Excluded++
+		default:
+			simulation.Unknown++
+		}
+		for _, reason := range trader.Reasons {
+			simulation.Exclusions[reason]++
+		}
+	}
+	// подходящие трейдеры первыми, внутри - по приоритету трафика
+	rank := map[domain.RoutingVerdict]int{
+		domain.RoutingVerdictEligible: 0,
+		domain.RoutingVerdictUnknown:  1,
+		domain.RoutingVerdictExcluded: 2,
+	}
+	sort.SliceStable(simulation.Traders, func(i, j int) bool {
+		a, b := simulation.Traders[i], simulation.Traders[j]
+		if rank[a.Verdict()] != rank[b.Verdict()] {
+			return rank[a.Verdict()] < rank[b.Verdict()]
+		}
+		if a.TraderPriority != b.TraderPriority {
+			return a.TraderPriority > b.TraderPriority
+		}
+		return a.TraderID < b.TraderID
+	})
+	return simulation, nil
+}
+
+// merchantTraffic все записи трафика мерчанта. order-service не фильтрует трафик по мерчанту,
+// поэтому записи читаются постранично целиком.
+func (s *RoutingSimulatorService) merchantTraffic(merchantID string) ([]*orderpb.Traffic, error) {
+	var records []*orderpb.Traffic
+	for page := int32(1); ; page++ {
+		batch, err := s.orderClient.GetTrafficRecords(page, s.config.TrafficPageSize)
+		if err != nil {
+			return nil, upstreamError(UpstreamOrder, err)
+		}
+		for _, record := range batch {
+			if record.GetMerchantId() == merchantID {
+				records = append(records, record)
+			}
+		}
+		if int32(len(batch)) < s.config.TrafficPageSize {
+			return records, nil
+		}
+	}
+}
+
+// checkTrader проверяет трафик одного трейдера. Проверки не прерываются на первой причине,
+// чтобы админ сразу видел все ограничения; ошибка одной проверки не мешает остальным.
+func (s *RoutingSimulatorService) checkTrader(ctx context.Context, record *orderpb.Traffic, params RoutingSimulationParams) domain.RoutingTrader {
+	trader := domain.RoutingTrader{
+		TraderID:            record.GetTraderId(),
+		TrafficID:           record.GetId(),
+		TraderPriority:      record.GetTraderPriority(),
+		TraderRewardPercent: record.GetTraderRewardPercent(),
+		AntifraudRequired:   record.GetAntifraudParams().GetAntifraudRequired(),
+	}
+	exclude := func(reason domain.RoutingExclusion) {
+		trader.Reasons = append(trader.Reasons, reason)
+	}
+	fail := func(check string, err error) {
+		log.Printf("routing simulator: trader %s: %s: %v", trader.TraderID, check, err)
+		trader.FailedChecks = append(trader.FailedChecks, check)
+	}
+
+	if !record.GetEnabled() {
+		exclude(domain.RoutingExclusionTrafficDisabled)
+	}
+
+	s.checkLocks(&trader, record, exclude, fail)
+
+	if trader.AntifraudRequired {
+		check, err := s.orderClient.CheckTrader(&orderpb.CheckTraderRequest{TraderId: trader.TraderID})
+		if err != nil {
+			fail(domain.RoutingCheckAntifraud, err)
+		} else if !check.GetAllPassed() {
+			trader.FailedRules = check.GetFailedRules()
+			exclude(domain.RoutingExclusionAntifraudFailed)
+		}
+	}
+
+	bankDetails, err := s.bankDetails(trader.TraderID, params)
+	if err != nil {
+		fail(domain.RoutingCheckBankDetails, err)
+		return trader
+	}
+	if len(bankDetails) == 0 {
+		exclude(domain.RoutingExclusionNoBankDetails)
+		return trader
+	}
+	trader.BankDetails = bankDetails
+
+	stats, err := s.orderClient.GetBankDetailsStatsByTraderID(&orderpb.GetBankDetailsStatsByTraderIDRequest{
+		TraderId: trader.TraderID,
+	})
+	if err != nil {
+		fail(domain.RoutingCheckBankDetailStats, err)
+	} else {
+		index := make(map[string]int, len(trader.BankDetails))
+		for i, bankDetail := range trader.BankDetails {
+			index[bankDetail.BankDetailID] = i
+		}
+		for _, stat := range stats.GetBankDetailStat() {
+			if i, ok := index[stat.GetBankDetailId()]; ok {
+				trader.BankDetails[i].AmountToday = stat.GetCurrentAmountToday()
+				trader.BankDetails[i].AmountMonth = stat.GetCurrentAmountMonth()
+				trader.BankDetails[i].CountToday = int64(stat.GetCurrentCountToday())
+				trader.BankDetails[i].CountMonth = int64(stat.GetCurrentCountMonth())
+			}
+		}
+	}
+
+	var devices map[string]routingDevice
+	if s.config.RequireOnlineDevice {
+		devicesCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+		response, err := s.deviceClient.GetTraderDevicesStatus(devicesCtx, &orderpb.GetTraderDevicesStatusRequest{
+			TraderId: trader.TraderID,
+		})
+		cancel()
+		if err != nil {
+			fail(domain.RoutingCheckDevices, err)
+		} else {
+			devices = make(map[string]routingDevice, len(response.GetDevices()))
+			for _, device := range response.GetDevices() {
+				devices[device.GetDeviceId()] = routingDevice{enabled: device.GetEnabled(), online: device.GetOnline()}
+			}
+		}
+	}
+
+	for i := range trader.BankDetails {
+		checkBankDetail(&trader.BankDetails[i], params.AmountFiat, devices, s.config.RequireOnlineDevice)
+	}
+	if trader.EligibleBankDetails() == 0 {
+		exclude(domain.RoutingExclusionNoEligibleBankDetails)
+	}
+	return trader
+}
+
+// checkLocks заполняет флаги блокировок трафика. Итог берется из CheckTrafficUnlocked, флаги только
+// объясняют его; если свежие флаги получить не удалось, используются флаги из записи трафика.
+func (s *RoutingSimulatorService) checkLocks(
+	trader *domain.RoutingTrader,
+	record *orderpb.Traffic,
+	exclude func(domain.RoutingExclusion),
+	fail func(string, error),
+) {
+	activity := record.GetActivityParams()
+	trader.Locks = domain.RoutingLocks{
+		MerchantUnlocked:  activity.GetMerchantUnlocked(),
+		TraderUnlocked:    activity.GetTraderUnlocked(),
+		AntifraudUnlocked: activity.GetAntifraudUnlocked(),
+		ManuallyUnlocked:  activity.GetManuallyUnlocked(),
+	}
+	if statuses, err := s.orderClient.GetTrafficLockStatuses(&orderpb.GetTrafficLockStatusesRequest{
+		TrafficId: trader.TrafficID,
+	}); err != nil {
+		fail(domain.RoutingCheckLockStatuses, err)
+	} else {
+		trader.Locks.MerchantUnlocked = statuses.GetMerchantUnlocked()
+		trader.Locks.TraderUnlocked = statuses.GetTraderUnlocked()
+		trader.Locks.AntifraudUnlocked = statuses.GetAntifraudUnlocked()
+		trader.Locks.ManuallyUnlocked = statuses.GetManuallyUnlocked()
+	}
+
+	unlocked, err := s.orderClient.CheckTrafficUnlocked(&orderpb.CheckTrafficUnlockedRequest{
+		TrafficId: trader.TrafficID,
+	})
+	if err != nil {
+		fail(domain.RoutingCheckLockStatuses, err)
+		return
+	}
+	trader.Locks.Unlocked = unlocked.GetUnlocked()
+	if trader.Locks.Unlocked {
+		return
+	}
+
+	explained := false
+	if !trader.Locks.MerchantUnlocked {
+		exclude(domain.RoutingExclusionMerchantLocked)
+		explained = true
+	}
+	if !trader.Locks.TraderUnlocked {
+		exclude(domain.RoutingExclusionTraderLocked)
+		explained = true
+	}
+	if !trader.Locks.AntifraudUnlocked {
+		exclude(domain.RoutingExclusionAntifraudLocked)
+		explained = true
+	}
+	if !explained {
+		exclude(domain.RoutingExclusionTrafficLocked)
+	}
+}
+
+// bankDetails реквизиты трейдера нужной платежной системы и банка, включая выключенные:
+// их тоже нужно показать админу с причиной
+func (s *RoutingSimulatorService) bankDetails(traderID string, params RoutingSimulationParams) ([]domain.RoutingBankDetail, error) {
+	request := &orderpb.GetBankDetailsRequest{
+		TraderId:      &traderID,
+		PaymentSystem: &params.PaymentSystem,
+		Limit:         routingBankDetailsPageSize,
+	}
+	if params.BankCode != "" {
+		request.BankCode = &params.BankCode
+	}
+
+	var bankDetails []domain.RoutingBankDetail
+	for page := int32(1); ; page++ {
+		request.Page = page
+		response, err := s.orderClient.GetBankDetails(request)
+		if err != nil {
+			return nil, upstreamError(UpstreamOrder, err)
+		}
+		for _, bankDetail := range response.GetBankDetails() {
+			bankDetails = append(bankDetails, domain.RoutingBankDetail{
+				BankDetailID:     bankDetail.GetBankDetailId(),
+				BankCode:         bankDetail.GetBankCode(),
+				PaymentSystem:    bankDetail.GetPaymentSystem(),
+				DeviceID:         bankDetail.GetDeviceId(),
+				Enabled:          bankDetail.GetEnabled(),
+				MinAmount:        bankDetail.GetMinAmount(),
+				MaxAmount:        bankDetail.GetMaxAmount(),
+				MaxAmountDay:     bankDetail.GetMaxAmountDay(),
+				MaxAmountMonth:   bankDetail.GetMaxAmountMonth(),
+				MaxQuantityDay:   int64(bankDetail.GetMaxQuantityDay()),
+				MaxQuantityMonth: int64(bankDetail.GetMaxQuantityMonth()),
+			})
+		}
+		if int64(page) >= response.GetPagination().GetTotalPages() || len(response.GetBankDetails()) == 0 {
+			return bankDetails, nil
+		}
+	}
+}
+
+// checkBankDetail проверяет, примет ли реквизит ордер на amount. devices == nil - статус устройств
+// неизвестен, и проверка устройства пропускается.
+func checkBankDetail(bankDetail *domain.RoutingBankDetail, amount float64, devices map[string]routingDevice, requireOnlineDevice bool) {
+	exclude := func(reason domain.RoutingExclusion) {
+		bankDetail.Reasons = append(bankDetail.Reasons, reason)
+	}
+
+	if !bankDetail.Enabled {
+		exclude(domain.RoutingExclusionBankDetailDisabled)
+	}
+	if amount < bankDetail.MinAmount {
+		exclude(domain.RoutingExclusionAmountBelowMin)
+	}
+	if bankDetail.MaxAmount > 0 && amount > bankDetail.MaxAmount {
+		exclude(domain.RoutingExclusionAmountAboveMax)
+	}
+	if bankDetail.MaxAmountDay > 0 && bankDetail.AmountToday+amount > bankDetail.MaxAmountDay {
+		exclude(domain.RoutingExclusionDayAmountLimit)
+	}
+	if bankDetail.MaxAmountMonth > 0 && bankDetail.AmountMonth+amount > bankDetail.MaxAmountMonth {
+		exclude(domain.RoutingExclusionMonthAmountLimit)
+	}
+	if bankDetail.MaxQuantityDay > 0 && bankDetail.CountToday+1 > bankDetail.MaxQuantityDay {
+		exclude(domain.RoutingExclusionDayQuantityLimit)
+	}
+	if bankDetail.MaxQuantityMonth > 0 && bankDetail.CountMonth+1 > bankDetail.MaxQuantityMonth {
+		exclude(domain.RoutingExclusionMonthQuantityLimit)
+	}
+
+	if !requireOnlineDevice || devices == nil || bankDetail.DeviceID == "" {
+		return
+	}
+	device, ok := devices[bankDetail.DeviceID]
+	switch {
+	case !ok:
+		exclude(domain.RoutingExclusionDeviceNotFound)
+	case !device.enabled:
+		exclude(domain.RoutingExclusionDeviceDisabled)
+	case !device.online:
+		exclude(domain.RoutingExclusionDeviceOffline)
+	}
+}
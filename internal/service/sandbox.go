@@ -0,0 +1,527 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/LavaJover/shvark-api-gateway/internal/domain"
+	"github.com/LavaJover/shvark-api-gateway/internal/service/netguard"
+	orderpb "github.com/LavaJover/shvark-order-service/proto/gen/order"
+	userpb "github.com/LavaJover/shvark-user-service/proto/gen"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+var (
+	ErrSandboxOrderNotFound     = errors.New("sandbox order not found")
+	ErrSandboxTransition        = errors.New("order status does not allow this transition")
+	ErrSandboxInsufficientFunds = errors.New("insufficient sandbox balance")
+)
+
+const sandboxAccountName = "sandbox"
+
+// SandboxRequisite фейковые реквизиты, которые песочница выдает в ордерах
+type SandboxRequisite struct {
+	BankName      string
+	BankCode      string
+	NspkCode      string
+	PaymentSystem string
+	CardNumber    string
+	Phone         string
+	Owner         string
+}
+
+// defaultSandboxRequisites тестовые реквизиты, если в конфиге свои не заданы
+var defaultSandboxRequisites = []SandboxRequisite{
+	{BankName: "Sandbox Bank", BankCode: "sandbox", NspkCode: "100000000000", PaymentSystem: "C2C", CardNumber: "2200000000000004", Owner: "SANDBOX TEST"},
+	{BankName: "Sandbox Bank", BankCode: "sandbox", NspkCode: "100000000000", PaymentSystem: "SBP", Phone: "+79000000000", Owner: "SANDBOX TEST"},
+}
+
+// SandboxConfig параметры симулятора
+type SandboxConfig struct {
+	CryptoRubRate        float64
+	MaxOrdersPerMerchant int
+	WebhookTimeout       time.Duration
+	Requisites           []SandboxRequisite
+}
+
+type sandboxOrder struct {
+	order       domain.Order
+	createdAt   time.Time
+	completedAt *time.Time
+}
+
+// SandboxBackend симулятор order-service, кошелька и аккаунтов для ключей песочницы. Реализует те же
+// интерфейсы, что и боевые клиенты, поэтому MerchantService над ним работает без изменений.
+// Данные живут только в памяти и не пересекаются с боевыми: ни один запрос не уходит в upstream.
+// Время жизни ордера не отслеживается, истечение вызывается явно через Expire.
+type SandboxBackend struct {
+	config   SandboxConfig
+	webhooks *http.Client
+
+	mu         sync.Mutex
+	orders     map[string]*sandboxOrder
+	byMerchant map[string][]string // ID ордеров мерчанта в порядке создания
+	balances   map[string]float64
+	nextID     int
+}
+
+func NewSandboxBackend(config SandboxConfig) *SandboxBackend {
+	if len(config.Requisites) == 0 {
+		config.Requisites = defaultSandboxRequisites
+	}
+	if config.CryptoRubRate <= 0 {
+		config.CryptoRubRate = 1
+	}
+	return &SandboxBackend{
+		config:     config,
+		webhooks:   netguard.NewClient(config.WebhookTimeout),
+		orders:     make(map[string]*sandboxOrder),
+		byMerchant: make(map[string][]string),
+		balances:   make(map[string]float64),
+	}
+}
+
+// CreatePayInOrder создает ордер с фейковыми реквизитами. Как и order-service, отвечает NotFound,
+// если подходящих реквизитов нет, и AlreadyExists на повтор ID ордера мерчанта.
+func (b *SandboxBackend) CreatePayInOrder(request *orderpb.CreatePayInOrderRequest) (*orderpb.CreatePayInOrderResponse, error) {
+	if request.AmountFiat <= 0 {
+		return nil, status.Error(codes.InvalidArgument, "amount must be positive")
+	}
+	requisite, ok := b.requisite(request.PaymentSystem, request.BankCode)
+	if !ok {
+		return nil, status.Error(codes.NotFound, "no available bank details")
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if request.MerchantOrderId != "" {
+		for _, id := range b.byMerchant[request.MerchantId] {
+			if b.orders[id].order.MerchantOrderID == request.MerchantOrderId {
+				return nil, status.Error(codes.AlreadyExists, "order with this merchant order id already exists")
+			}
+		}
+	}
+
+	b.nextID++
+	now := time.Now()
+	id := fmt.Sprintf("sbx-%d-%d", now.Unix(), b.nextID)
+	expiresAt := now.Add(defaultPayInTTL)
+	if request.ExpiresAt != nil {
+		expiresAt = request.ExpiresAt.AsTime()
+	}
+	entry := &sandboxOrder{
+		createdAt: now,
+		order: domain.Order{
+			ID:              id,
+			MerchantID:      request.MerchantId,
+			MerchantOrderID: request.MerchantOrderId,
+			AmountFiat:      request.AmountFiat,
+			AmountCrypto:    math.Round(request.AmountFiat/b.config.CryptoRubRate*1e6) / 1e6,
+			CryptoRubRate:   b.config.CryptoRubRate,
+			Currency:        request.Currency,
+			Country:         request.Country,
+			CallbackURL:     request.CallbackUrl,
+			Status:          domain.StatusCreated,
+			PaymentSystem:   requisite.PaymentSystem,
+			BankDetailsID:   "sbx-requisite",
+			ExpiresAt:       expiresAt,
+			BankDetail: &domain.BankDetail{
+				ID:            "sbx-requisite",
+				Country:       request.Country,
+				Currency:      request.Currency,
+				BankName:      requisite.BankName,
+				BankCode:      requisite.BankCode,
+				NspkCode:      requisite.NspkCode,
+				PaymentSystem: requisite.PaymentSystem,
+				Enabled:       true,
+				CardNumber:    requisite.CardNumber,
+				Phone:         requisite.Phone,
+				Owner:         requisite.Owner,
+			},
+		},
+	}
+	b.orders[id] = entry
+	b.byMerchant[request.MerchantId] = append(b.byMerchant[request.MerchantId], id)
+	b.trim(request.MerchantId)
+
+	return &orderpb.CreatePayInOrderResponse{Order: sandboxOrderToProto(entry.order)}, nil
+}
+
+// GetOrderByID без мерчанта ордер песочницы не ищется, чтобы ключ одного мерчанта не видел
+// ордера другого. MerchantService ищет ордера песочницы через GetMerchantOrderByID.
+func (b *SandboxBackend) GetOrderByID(orderID string) (*orderpb.GetOrderByIDResponse, error) {
+	return nil, status.Error(codes.NotFound, ErrSandboxOrderNotFound.Error())
+}
+
+// GetOrderByMerchantOrderID как GetOrderByID: ищет только GetMerchantOrderByMerchantOrderID
+func (b *SandboxBackend) GetOrderByMerchantOrderID(merchantOrderID string) (*orderpb.GetOrderByMerchantOrderIDResponse, error) {
+	return nil, status.Error(codes.NotFound, ErrSandboxOrderNotFound.Error())
+}
+
+// GetMerchantOrderByID ордер мерчанта по ID, ордера других мерчантов не находятся
+func (b *SandboxBackend) GetMerchantOrderByID(merchantID, orderID string) (*orderpb.GetOrderByIDResponse, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	entry, ok := b.orders[orderID]
+	if !ok || entry.order.MerchantID != merchantID {
+		return nil, status.Error(codes.NotFound, ErrSandboxOrderNotFound.Error())
+	}
+	return &orderpb.GetOrderByIDResponse{Order: sandboxOrderToProto(entry.order)}, nil
+}
+
+// GetMerchantOrderByMerchantOrderID ордер мерчанта по его ID ордера. Повтор ID ордера мерчанта
+// CreatePayInOrder отклоняет, поэтому совпадение среди ордеров мерчанта одно.
+func (b *SandboxBackend) GetMerchantOrderByMerchantOrderID(merchantID, merchantOrderID string) (*orderpb.GetOrderByMerchantOrderIDResponse, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for _, id := range b.byMerchant[merchantID] {
+		if entry := b.orders[id]; entry.order.MerchantOrderID == merchantOrderID {
+			return &orderpb.GetOrderByMerchantOrderIDResponse{Order: sandboxOrderToProto(entry.order)}, nil
+		}
+	}
+	return nil, status.Error(codes.NotFound, ErrSandboxOrderNotFound.Error())
+}
+
+// GetOrders страница ордеров мерчанта, от новых к старым. Сортировка из запроса не поддерживается.
+func (b *SandboxBackend) GetOrders(request *orderpb.GetOrdersRequest) (*orderpb.GetOrdersResponse, error) {
+	b.mu.Lock()
+	var matched []sandboxOrder
+	ids := b.byMerchant[request.MerchantId]
+	for i := len(ids) - 1; i >= 0; i-- {
+		entry := b.orders[ids[i]]
+		if sandboxOrderMatches(*entry, request) {
+			matched = append(matched, *entry)
+		}
+	}
+	b.mu.Unlock()
+
+	size := int(request.Size)
+	if size <= 0 {
+		size = defaultOrdersSize
+	}
+	page := int(request.Page)
+	if page < 0 {
+		page = 0
+	}
+	from := page * size
+	if from > len(matched) {
+		from = len(matched)
+	}
+	to := from + size
+	if to > len(matched) {
+		to = len(matched)
+	}
+
+	content := make([]map[string]interface{}, 0, to-from)
+	for _, entry := range matched[from:to] {
+		content = append(content, sandboxListItem(entry))
+	}
+	totalPages := (len(matched) + size - 1) / size
+	sortInfo := map[string]interface{}{"unsorted": false, "sorted": true, "empty": false}
+	// страница собирается через JSON-представление protobuf: так симулятор повторяет формат
+	// GetOrders без ручной сборки вложенных сообщений
+	raw, err := json.Marshal(map[string]interface{}{
+		"content": content,
+		"pageable": map[string]interface{}{
+			"sort":       sortInfo,
+			"pageNumber": page,
+			"pageSize":   size,
+			"offset":     from,
+			"paged":      true,
+			"unpaged":    false,
+		},
+		"totalElements":    len(matched),
+		"totalPages":       totalPages,
+		"last":             page >= totalPages-1,
+		"numberOfElements": len(content),
+		"size":             size,
+		"number":           page,
+		"sort":             sortInfo,
+		"first":            page == 0,
+		"empty":            len(content) == 0,
+	})
+	if err != nil {
+		return nil, err
+	}
+	var response orderpb.GetOrdersResponse
+	if err := protojson.Unmarshal(raw, &response); err != nil {
+		return nil, fmt.Errorf("build sandbox orders page: %w", err)
+	}
+	return &response, nil
+}
+
+// GetBalance баланс песочницы: сумма оплаченных ордеров за вычетом выводов
+func (b *SandboxBackend) GetBalance(merchantID string) (float64, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.balances[merchantID], nil
+}
+
+// Withdraw списывает баланс песочницы и возвращает фейковый хеш транзакции
+func (b *SandboxBackend) Withdraw(merchantID, toAddress string, amount float64) (string, error) {
+	if amount <= 0 {
+		return "", errors.New("amount must be positive")
+	}
+	if toAddress == "" {
+		return "", errors.New("to address is required")
+	}
+	txHash, err := randomHex(32)
+	if err != nil {
+		return "", err
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.balances[merchantID] < amount {
+		return "", ErrSandboxInsufficientFunds
+	}
+	b.balances[merchantID] -= amount
+	return "sandbox_" + txHash, nil
+}
+
+// GetUserByID данные аккаунта песочницы, user-service не вызывается
+func (b *SandboxBackend) GetUserByID(merchantID string) (*userpb.GetUserByIDResponse, error) {
+	return &userpb.GetUserByIDResponse{
+		Login:    merchantID,
+		Username: sandboxAccountName,
+	}, nil
+}
+
+// MarkPaid подтверждает оплату ордера: баланс мерчанта пополняется на сумму в USDT
+func (b *SandboxBackend) MarkPaid(merchantID, orderID string) (*domain.Order, domain.SandboxWebhook, error) {
+	return b.transition(merchantID, orderID, domain.StatusSucceed, func(order domain.Order) bool {
+		return order.Status == domain.StatusCreated
+	})
+}
+
+// Expire отменяет неоплаченный ордер, как по истечении времени жизни
+func (b *SandboxBackend) Expire(merchantID, orderID string) (*domain.Order, domain.SandboxWebhook, error) {
+	return b.transition(merchantID, orderID, domain.StatusCanceled, func(order domain.Order) bool {
+		return order.Status == domain.StatusCreated
+	})
+}
+
+// OpenDispute открывает спор по ордеру, не завершенному успешно
+func (b *SandboxBackend) OpenDispute(merchantID, orderID string) (*domain.Order, domain.SandboxWebhook, error) {
+	return b.transition(merchantID, orderID, domain.StatusDisputeCreated, func(order domain.Order) bool {
+		return order.Status == domain.StatusCreated || order.Status == domain.StatusCanceled
+	})
+}
+
+// transition меняет статус ордера и синхронно отправляет вебхук на CallbackURL ордера,
+// чтобы мерчант сразу видел результат доставки. Вебхуки на адреса внутренней сети
+// (loopback, RFC1918, link-local) не отправляются, ошибка попадает в результат доставки.
+func (b *SandboxBackend) transition(
+	merchantID, orderID string,
+	to domain.OrderStatus,
+	allowed func(domain.Order) bool,
+) (*domain.Order, domain.SandboxWebhook, error) {
+	b.mu.Lock()
+	entry, ok := b.orders[orderID]
+	if !ok || entry.order.MerchantID != merchantID {
+		b.mu.Unlock()
+		return nil, domain.SandboxWebhook{}, ErrSandboxOrderNotFound
+	}
+	if !allowed(entry.order) {
+		b.mu.Unlock()
+		return nil, domain.SandboxWebhook{}, fmt.Errorf("%w: %s -> %s", ErrSandboxTransition, entry.order.Status, to)
+	}
+	entry.order.Status = to
+	if to.IsTerminal() {
+		now := time.Now()
+		entry.completedAt = &now
+	}
+	if to == domain.StatusSucceed {
+		b.balances[merchantID] += entry.order.AmountCrypto
+	}
+	order := entry.order
+	b.mu.Unlock()
+
+	return &order, b.sendWebhook(order), nil
+}
+
+func (b *SandboxBackend) sendWebhook(order domain.Order) domain.SandboxWebhook {
+	webhook := domain.SandboxWebhook{URL: order.CallbackURL, SentAt: time.Now()}
+	if order.CallbackURL == "" {
+		webhook.Error = "order has no callback url"
+		return webhook
+	}
+
+	payload, err := json.Marshal(map[string]interface{}{
+		"order_id":          order.ID,
+		"merchant_order_id": order.MerchantOrderID,
+		"status":            order.Status,
+		"amount_fiat":       order.AmountFiat,
+		"amount_crypto":     order.AmountCrypto,
+		"currency":          order.Currency,
+		"sandbox":           true,
+		"timestamp":         webhook.SentAt.Unix(),
+	})
+	if err != nil {
+		webhook.Error = err.Error()
+		return webhook
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), b.config.WebhookTimeout)
+	defer cancel()
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, order.CallbackURL, bytes.NewReader(payload))
+	if err != nil {
+		webhook.Error = err.Error()
+		return webhook
+	}
+	request.Header.Set("Content-Type", "application/json")
+	request.Header.Set("X-Sandbox", "true")
+
+	response, err := b.webhooks.Do(request)
+	if err != nil {
+		webhook.Error = err.Error()
+		return webhook
+	}
+	defer response.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(response.Body, 64*1024))
+	webhook.StatusCode = response.StatusCode
+	if response.StatusCode < 200 || response.StatusCode >= 300 {
+		webhook.Error = fmt.Sprintf("callback responded %d", response.StatusCode)
+	}
+	return webhook
+}
+
+// requisite первые реквизиты под платежную систему и банк ордера, пустые значения - любые
+func (b *SandboxBackend) requisite(paymentSystem, bankCode string) (SandboxRequisite, bool) {
+	for _, requisite := range b.config.Requisites {
+		if paymentSystem != "" && !strings.EqualFold(requisite.PaymentSystem, paymentSystem) {
+			continue
+		}
+		if bankCode != "" && !strings.EqualFold(requisite.BankCode, bankCode) {
+			continue
+		}
+		return requisite, true
+	}
+	return SandboxRequisite{}, false
+}
+
+// trim удаляет самые старые ордера мерчанта сверх лимита, вызывается под b.mu
+func (b *SandboxBackend) trim(merchantID string) {
+	ids := b.byMerchant[merchantID]
+	if b.config.MaxOrdersPerMerchant <= 0 || len(ids) <= b.config.MaxOrdersPerMerchant {
+		return
+	}
+	excess := len(ids) - b.config.MaxOrdersPerMerchant
+	for _, id := range ids[:excess] {
+		delete(b.orders, id)
+	}
+	b.byMerchant[merchantID] = append([]string(nil), ids[excess:]...)
+}
+
+// sandboxListStatus статус ордера в терминах списка ордеров мерчанта
+func sandboxListStatus(status domain.OrderStatus) string {
+	switch status {
+	case domain.StatusSucceed:
+		return "COMPLETED"
+	case domain.StatusCanceled:
+		return "CANCELED"
+	case domain.StatusDisputeCreated, domain.StatusDisputeResolved:
+		return "DISPUTE"
+	default:
+		return "PENDING"
+	}
+}
+
+func sandboxOrderMatches(entry sandboxOrder, request *orderpb.GetOrdersRequest) bool {
+	order := entry.order
+	if request.DealId != nil && *request.DealId != order.ID {
+		return false
+	}
+	if request.Type != nil && *request.Type != "DEPOSIT" {
+		return false
+	}
+	if request.Status != nil && *request.Status != sandboxListStatus(order.Status) {
+		return false
+	}
+	if request.AmountMin != nil && order.AmountFiat < *request.AmountMin {
+		return false
+	}
+	if request.AmountMax != nil && order.AmountFiat > *request.AmountMax {
+		return false
+	}
+	if request.TimeOpeningStart != nil && entry.createdAt.Before(request.TimeOpeningStart.AsTime()) {
+		return false
+	}
+	if request.TimeOpeningEnd != nil && entry.createdAt.After(request.TimeOpeningEnd.AsTime()) {
+		return false
+	}
+	return true
+}
+
+func sandboxListItem(entry sandboxOrder) map[string]interface{} {
+	order := entry.order
+	item := map[string]interface{}{
+		"id":           order.ID,
+		"timeOpening":  entry.createdAt.UTC().Format(time.RFC3339),
+		"timeExpires":  order.ExpiresAt.UTC().Format(time.RFC3339),
+		"type":         "DEPOSIT",
+		"status":       sandboxListStatus(order.Status),
+		"currencyRate": order.CryptoRubRate,
+		"sumInvoice":   map[string]interface{}{"amount": order.AmountFiat, "currency": order.Currency},
+		"sumDeal":      map[string]interface{}{"amount": order.AmountCrypto, "currency": MerchantBalanceAsset},
+		"requisites": map[string]interface{}{
+			"issuer":      order.BankDetail.BankCode,
+			"holderName":  order.BankDetail.Owner,
+			"phoneNumber": order.BankDetail.Phone,
+			"cardNumber":  order.BankDetail.CardNumber,
+		},
+	}
+	if entry.completedAt != nil {
+		item["timeComplete"] = entry.completedAt.UTC().Format(time.RFC3339)
+	}
+	return item
+}
+
+// sandboxOrderToProto обратное к orderToDomain преобразование
+func sandboxOrderToProto(order domain.Order) *orderpb.Order {
+	result := &orderpb.Order{
+		OrderId:         order.ID,
+		MerchantId:      order.MerchantID,
+		MerchantOrderId: order.MerchantOrderID,
+		AmountFiat:      order.AmountFiat,
+		AmountCrypto:    order.AmountCrypto,
+		CryptoRubRate:   order.CryptoRubRate,
+		PlatformFee:     order.PlatformFee,
+		Recalculated:    order.Recalculated,
+		CallbackUrl:     order.CallbackURL,
+		Status:          string(order.Status),
+		ExpiresAt:       timestamppb.New(order.ExpiresAt),
+	}
+	if bd := order.BankDetail; bd != nil {
+		result.BankDetail = &orderpb.BankDetail{
+			BankDetailId:  bd.ID,
+			TraderId:      bd.TraderID,
+			Country:       bd.Country,
+			Currency:      bd.Currency,
+			BankName:      bd.BankName,
+			BankCode:      bd.BankCode,
+			NspkCode:      bd.NspkCode,
+			PaymentSystem: bd.PaymentSystem,
+			Enabled:       bd.Enabled,
+			CardNumber:    bd.CardNumber,
+			Phone:         bd.Phone,
+			Owner:         bd.Owner,
+			DeviceId:      bd.DeviceID,
+		}
+	}
+	return result
+}
@@ -0,0 +1,217 @@
+package service
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/LavaJover/shvark-api-gateway/internal/domain"
+	"github.com/LavaJover/shvark-api-gateway/internal/storage"
+)
+
+var (
+	ErrSandboxCredentialInvalid  = errors.New("invalid sandbox key")
+	ErrSandboxCredentialRevoked  = errors.New("sandbox key revoked")
+	ErrSandboxCredentialNotFound = errors.New("sandbox key not found")
+)
+
+const sandboxCredentialPrefix = "sbx_"
+
+// IsSandboxKey похож ли токен на ключ песочницы, по префиксу ключ отличается от токенов SSO
+func IsSandboxKey(token string) bool {
+	return strings.HasPrefix(token, sandboxCredentialPrefix)
+}
+
+// SandboxCredentialStore хранилище ключей песочницы
+type SandboxCredentialStore interface {
+	// Save создает или заменяет ключ по ID
+	Save(credential domain.SandboxCredential) error
+	ByID(id string) (domain.SandboxCredential, bool)
+	ByMerchant(merchantID string) []domain.SandboxCredential
+	// Touch отмечает время последнего запроса с ключом
+	Touch(id string, at time.Time)
+}
+
+// FileSandboxCredentialStore держит ключи в памяти и сохраняет их в JSON-файл при каждом изменении.
+// Время последнего запроса пишется в файл вместе со следующим изменением.
+type FileSandboxCredentialStore struct {
+	path string
+
+	mu          sync.RWMutex
+	credentials map[string]domain.SandboxCredential
+}
+
+// NewFileSandboxCredentialStore загружает ключи из файла, пустой путь - только память
+func NewFileSandboxCredentialStore(path string) (*FileSandboxCredentialStore, error) {
+	store := &FileSandboxCredentialStore{
+		path:        path,
+		credentials: make(map[string]domain.SandboxCredential),
+	}
+	if path == "" {
+		log.Printf("sandbox credentials file is not configured: sandbox keys will not survive restart")
+		return store, nil
+	}
+
+	var credentials []domain.SandboxCredential
+	if err := storage.ReadJSON(path, &credentials); err != nil {
+		return nil, err
+	}
+	for _, credential := range credentials {
+		store.credentials[credential.ID] = credential
+	}
+	return store, nil
+}
+
+func (s *FileSandboxCredentialStore) Save(credential domain.SandboxCredential) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	previous, existed := s.credentials[credential.ID]
+	s.credentials[credential.ID] = credential
+	if err := s.persist(); err != nil {
+		if existed {
+			s.credentials[credential.ID] = previous
+		} else {
+			delete(s.credentials, credential.ID)
+		}
+		return err
+	}
+	return nil
+}
+
+func (s *FileSandboxCredentialStore) ByID(id string) (domain.SandboxCredential, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	credential, ok := s.credentials[id]
+	return credential, ok
+}
+
+func (s *FileSandboxCredentialStore) ByMerchant(merchantID string) []domain.SandboxCredential {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var result []domain.SandboxCredential
+	for _, credential := range s.credentials {
+		if credential.MerchantID == merchantID {
+			result = append(result, credential)
+		}
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].CreatedAt.Before(result[j].CreatedAt)
+	})
+	return result
+}
+
+func (s *FileSandboxCredentialStore) Touch(id string, at time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if credential, ok := s.credentials[id]; ok {
+		credential.LastUsedAt = at
+		s.credentials[id] = credential
+	}
+}
+
+// persist перезаписывает файл снимком всех записей
+func (s *FileSandboxCredentialStore) persist() error {
+	if s.path == "" {
+		return nil
+	}
+
+	credentials := make([]domain.SandboxCredential, 0, len(s.credentials))
+	for _, credential := range s.credentials {
+		credentials = append(credentials, credential)
+	}
+	sort.Slice(credentials, func(i, j int) bool {
+		return credentials[i].ID < credentials[j].ID
+	})
+	return storage.WriteJSON(s.path, credentials)
+}
+
+// SandboxCredentialService выпускает и проверяет ключи песочницы мерчантов
+type SandboxCredentialService struct {
+	store SandboxCredentialStore
+}
+
+func NewSandboxCredentialService(store SandboxCredentialStore) *SandboxCredentialService {
+	return &SandboxCredentialService{store: store}
+}
+
+// Issue выпускает ключ песочницы мерчанту. Ключ возвращается один раз, в хранилище остается хеш.
+func (s *SandboxCredentialService) Issue(merchantID, name string) (string, domain.SandboxCredential, error) {
+	id, err := randomHex(12)
+	if err != nil {
+		return "", domain.SandboxCredential{}, err
+	}
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return "", domain.SandboxCredential{}, err
+	}
+	encodedSecret := base64.RawURLEncoding.EncodeToString(secret)
+
+	credential := domain.SandboxCredential{
+		ID:         id,
+		MerchantID: merchantID,
+		Name:       name,
+		SecretHash: hashDeviceSecret(encodedSecret),
+		CreatedAt:  time.Now(),
+	}
+	if err := s.store.Save(credential); err != nil {
+		return "", domain.SandboxCredential{}, fmt.Errorf("save sandbox credential: %w", err)
+	}
+	return sandboxCredentialPrefix + id + "." + encodedSecret, credential, nil
+}
+
+// Authenticate проверяет ключ из заголовка и отмечает время запроса
+func (s *SandboxCredentialService) Authenticate(key string) (domain.SandboxCredential, error) {
+	id, secret, ok := strings.Cut(strings.TrimPrefix(key, sandboxCredentialPrefix), ".")
+	if !ok || !IsSandboxKey(key) {
+		return domain.SandboxCredential{}, ErrSandboxCredentialInvalid
+	}
+
+	credential, exists := s.store.ByID(id)
+	if !exists || subtle.ConstantTimeCompare([]byte(credential.SecretHash), []byte(hashDeviceSecret(secret))) != 1 {
+		return domain.SandboxCredential{}, ErrSandboxCredentialInvalid
+	}
+	if !credential.Active() {
+		return domain.SandboxCredential{}, ErrSandboxCredentialRevoked
+	}
+
+	now := time.Now()
+	s.store.Touch(credential.ID, now)
+	credential.LastUsedAt = now
+	return credential, nil
+}
+
+// MerchantCredentials действующие ключи мерчанта
+func (s *SandboxCredentialService) MerchantCredentials(merchantID string) []domain.SandboxCredential {
+	var active []domain.SandboxCredential
+	for _, credential := range s.store.ByMerchant(merchantID) {
+		if credential.Active() {
+			active = append(active, credential)
+		}
+	}
+	return active
+}
+
+// Revoke отзывает ключ мерчанта
+func (s *SandboxCredentialService) Revoke(merchantID, id string) error {
+	credential, ok := s.store.ByID(id)
+	if !ok || credential.MerchantID != merchantID || !credential.Active() {
+		return ErrSandboxCredentialNotFound
+	}
+	now := time.Now()
+	credential.RevokedAt = &now
+	if err := s.store.Save(credential); err != nil {
+		return fmt.Errorf("revoke sandbox credential: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,192 @@
+package service
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	ErrSmsTooOld       = errors.New("received_at is older than allowed")
+	ErrSmsFromFuture   = errors.New("received_at is ahead of server time")
+	ErrSmsNoReceivedAt = errors.New("received_at is required")
+)
+
+// SmsProcessingRecord результат первой обработки уведомления, который получают его повторы
+type SmsProcessingRecord struct {
+	Fingerprint string
+	Done        bool // false - первая обработка еще идет
+	OrderID     string
+	Action      string
+	Processed   bool
+	StartedAt   time.Time
+	ProcessedAt time.Time
+}
+
+// SmsReplayStore хранилище отпечатков обработанных уведомлений. Реализация по умолчанию держит их в памяти процесса.
+type SmsReplayStore interface {
+	// Reserve занимает отпечаток. Если он уже есть и не истек, возвращает существующую запись и false.
+	Reserve(fingerprint string, at time.Time, ttl time.Duration) (SmsProcessingRecord, bool)
+	// Complete сохраняет результат обработки
+	Complete(record SmsProcessingRecord)
+	// Release освобождает отпечаток, если обработка не удалась и повтор должен пройти
+	Release(fingerprint string)
+}
+
+type smsReplayEntry struct {
+	record    SmsProcessingRecord
+	expiresAt time.Time
+}
+
+// InMemorySmsReplayStore хранит отпечатки до истечения TTL
+type InMemorySmsReplayStore struct {
+	mu        sync.Mutex
+	entries   map[string]smsReplayEntry
+	lastSweep time.Time
+}
+
+func NewInMemorySmsReplayStore() *InMemorySmsReplayStore {
+	return &InMemorySmsReplayStore{
+		entries: make(map[string]smsReplayEntry),
+	}
+}
+
+func (s *InMemorySmsReplayStore) Reserve(fingerprint string, at time.Time, ttl time.Duration) (SmsProcessingRecord, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.sweep(at, ttl)
+	if entry, exists := s.entries[fingerprint]; exists && at.Before(entry.expiresAt) {
+		return entry.record, false
+	}
+
+	record := SmsProcessingRecord{
+		Fingerprint: fingerprint,
+		StartedAt:   at,
+	}
+	s.entries[fingerprint] = smsReplayEntry{
+		record:    record,
+		expiresAt: at.Add(ttl),
+	}
+	return record, true
+}
+
+func (s *InMemorySmsReplayStore) Complete(record SmsProcessingRecord) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if entry, exists := s.entries[record.Fingerprint]; exists {
+		entry.record = record
+		s.entries[record.Fingerprint] = entry
+	}
+}
+
+func (s *InMemorySmsReplayStore) Release(fingerprint string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.entries, fingerprint)
+}
+
+// sweep удаляет истекшие записи не чаще раза за TTL, вызывается под s.mu
+func (s *InMemorySmsReplayStore) sweep(now time.Time, ttl time.Duration) {
+	if now.Sub(s.lastSweep) < ttl {
+		return
+	}
+	for fingerprint, entry := range s.entries {
+		if !now.Before(entry.expiresAt) {
+			delete(s.entries, fingerprint)
+		}
+	}
+	s.lastSweep = now
+}
+
+// SmsDeduplicator защищает обработку уведомлений от повторов: ретраев приложения, нескольких
+// пересылающих приложений и намеренного воспроизведения. Уведомления вне окна [now-maxAge, now+maxSkew]
+// отклоняются, поэтому отпечатки достаточно хранить не меньше ширины окна. Для уведомлений,
+// накопленных телефоном без сети, окно в прошлое шире - maxOfflineAge.
+type SmsDeduplicator struct {
+	store         SmsReplayStore
+	ttl           time.Duration
+	maxAge        time.Duration
+	maxOfflineAge time.Duration
+	maxSkew       time.Duration
+}
+
+func NewSmsDeduplicator(store SmsReplayStore, ttl, maxAge, maxOfflineAge, maxSkew time.Duration) *SmsDeduplicator {
+	if maxOfflineAge < maxAge {
+		maxOfflineAge = maxAge
+	}
+	if window := maxOfflineAge + maxSkew; ttl < window {
+		log.Printf("sms dedup ttl %v is shorter than the received_at window %v, using the window", ttl, window)
+		ttl = window
+	}
+	return &SmsDeduplicator{
+		store:         store,
+		ttl:           ttl,
+		maxAge:        maxAge,
+		maxOfflineAge: maxOfflineAge,
+		maxSkew:       maxSkew,
+	}
+}
+
+// CheckReceivedAt проверяет время получения уведомления телефоном по часам сервера,
+// флаг TooOld от клиента здесь не учитывается. buffered - уведомление из очереди телефона, пролежавшее без сети.
+func (d *SmsDeduplicator) CheckReceivedAt(receivedAt int64, now time.Time, buffered bool) error {
+	if receivedAt <= 0 {
+		return ErrSmsNoReceivedAt
+	}
+	maxAge := d.maxAge
+	if buffered {
+		maxAge = d.maxOfflineAge
+	}
+	at := receivedAtTime(receivedAt)
+	if at.Before(now.Add(-maxAge)) {
+		return ErrSmsTooOld
+	}
+	if at.After(now.Add(d.maxSkew)) {
+		return ErrSmsFromFuture
+	}
+	return nil
+}
+
+// Fingerprint отпечаток уведомления: устройство, время получения, текст без различий в регистре и пробелах, сумма
+func (d *SmsDeduplicator) Fingerprint(group string, receivedAt int64, text string, amount float64) string {
+	normalized := strings.Join(strings.Fields(strings.ToLower(text)), " ")
+	payload := fmt.Sprintf("%s|%d|%s|%s", group, receivedAtTime(receivedAt).UnixMilli(), normalized,
+		strconv.FormatFloat(amount, 'f', 2, 64))
+	sum := sha256.Sum256([]byte(payload))
+	return hex.EncodeToString(sum[:])
+}
+
+// Begin занимает отпечаток для обработки. Если уведомление уже обрабатывалось, возвращает
+// запись первой обработки и false.
+func (d *SmsDeduplicator) Begin(fingerprint string) (SmsProcessingRecord, bool) {
+	return d.store.Reserve(fingerprint, time.Now(), d.ttl)
+}
+
+// Complete сохраняет результат обработки для будущих повторов
+func (d *SmsDeduplicator) Complete(record SmsProcessingRecord) {
+	record.Done = true
+	record.ProcessedAt = time.Now()
+	d.store.Complete(record)
+}
+
+// Abort освобождает отпечаток после неудачной обработки, чтобы ретрай приложения прошел
+func (d *SmsDeduplicator) Abort(fingerprint string) {
+	d.store.Release(fingerprint)
+}
+
+// receivedAtTime приложения присылают время в миллисекундах, старые версии - в секундах
+func receivedAtTime(receivedAt int64) time.Time {
+	if receivedAt > 1e12 {
+		return time.UnixMilli(receivedAt)
+	}
+	return time.Unix(receivedAt, 0)
+}
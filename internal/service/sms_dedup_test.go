@@ -0,0 +1,112 @@
+package service
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func newTestDeduplicator() *SmsDeduplicator {
+	return NewSmsDeduplicator(NewInMemorySmsReplayStore(), 25*time.Hour, 10*time.Minute, 24*time.Hour, 2*time.Minute)
+}
+
+func TestSmsDeduplicatorFingerprint(t *testing.T) {
+	d := newTestDeduplicator()
+	const receivedAt = int64(1760000000000)
+	base := d.Fingerprint("device-1", receivedAt, "Перевод по СБП от Иван И. на 1 500,00 RUR", 1500)
+
+	tests := []struct {
+		name       string
+		group      string
+		receivedAt int64
+		text       string
+		amount     float64
+		wantSame   bool
+	}{
+		{name: "same notification", group: "device-1", receivedAt: receivedAt, text: "Перевод по СБП от Иван И. на 1 500,00 RUR", amount: 1500, wantSame: true},
+		{name: "case and spaces differ", group: "device-1", receivedAt: receivedAt, text: "  перевод по СБП  от ИВАН И.\nна 1 500,00 rur ", amount: 1500, wantSame: true},
+		{name: "received_at in seconds", group: "device-1", receivedAt: receivedAt / 1000, text: "Перевод по СБП от Иван И. на 1 500,00 RUR", amount: 1500, wantSame: true},
+		{name: "amount below cent", group: "device-1", receivedAt: receivedAt, text: "Перевод по СБП от Иван И. на 1 500,00 RUR", amount: 1500.001, wantSame: true},
+		{name: "other amount", group: "device-1", receivedAt: receivedAt, text: "Перевод по СБП от Иван И. на 1 500,00 RUR", amount: 1500.01},
+		{name: "other device", group: "device-2", receivedAt: receivedAt, text: "Перевод по СБП от Иван И. на 1 500,00 RUR", amount: 1500},
+		{name: "other received_at", group: "device-1", receivedAt: receivedAt + 1, text: "Перевод по СБП от Иван И. на 1 500,00 RUR", amount: 1500},
+		{name: "other text", group: "device-1", receivedAt: receivedAt, text: "Перевод по СБП от Петр П. на 1 500,00 RUR", amount: 1500},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := d.Fingerprint(tt.group, tt.receivedAt, tt.text, tt.amount)
+			if (got == base) != tt.wantSame {
+				t.Fatalf("Fingerprint() same = %v, want %v", got == base, tt.wantSame)
+			}
+		})
+	}
+}
+
+func TestSmsDeduplicatorCheckReceivedAt(t *testing.T) {
+	d := newTestDeduplicator()
+	// received_at приходит в миллисекундах, граница окна должна совпадать точно
+	now := time.UnixMilli(time.Now().UnixMilli())
+
+	tests := []struct {
+		name       string
+		receivedAt int64
+		buffered   bool
+		wantErr    error
+	}{
+		{name: "fresh", receivedAt: now.Add(-time.Minute).UnixMilli()},
+		{name: "fresh in seconds", receivedAt: now.Add(-time.Minute).Unix()},
+		{name: "at max age", receivedAt: now.Add(-10 * time.Minute).UnixMilli()},
+		{name: "stale", receivedAt: now.Add(-11 * time.Minute).UnixMilli(), wantErr: ErrSmsTooOld},
+		{name: "stale buffered", receivedAt: now.Add(-11 * time.Minute).UnixMilli(), buffered: true},
+		{name: "stale beyond offline age", receivedAt: now.Add(-25 * time.Hour).UnixMilli(), buffered: true, wantErr: ErrSmsTooOld},
+		{name: "within clock skew", receivedAt: now.Add(time.Minute).UnixMilli()},
+		{name: "future skewed", receivedAt: now.Add(3 * time.Minute).UnixMilli(), wantErr: ErrSmsFromFuture},
+		{name: "future skewed buffered", receivedAt: now.Add(3 * time.Minute).UnixMilli(), buffered: true, wantErr: ErrSmsFromFuture},
+		{name: "missing", receivedAt: 0, wantErr: ErrSmsNoReceivedAt},
+		{name: "negative", receivedAt: -1, wantErr: ErrSmsNoReceivedAt},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := d.CheckReceivedAt(tt.receivedAt, now, tt.buffered)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("CheckReceivedAt() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestSmsDeduplicatorBegin(t *testing.T) {
+	d := newTestDeduplicator()
+	fingerprint := d.Fingerprint("device-1", time.Now().UnixMilli(), "text", 100)
+
+	if _, first := d.Begin(fingerprint); !first {
+		t.Fatal("first notification is reported as duplicate")
+	}
+	// повтор во время первой обработки видит незавершенную запись
+	original, first := d.Begin(fingerprint)
+	if first || original.Done {
+		t.Fatalf("duplicate during processing: first = %v, record = %+v", first, original)
+	}
+
+	// после неудачной обработки ретрай проходит
+	d.Abort(fingerprint)
+	if _, first := d.Begin(fingerprint); !first {
+		t.Fatal("retry after abort is reported as duplicate")
+	}
+
+	d.Complete(SmsProcessingRecord{Fingerprint: fingerprint, OrderID: "ord-1", Action: "approved", Processed: true})
+	original, first = d.Begin(fingerprint)
+	if first || !original.Done || original.OrderID != "ord-1" {
+		t.Fatalf("duplicate after processing: first = %v, record = %+v", first, original)
+	}
+}
+
+func TestNewSmsDeduplicatorWidensTTL(t *testing.T) {
+	d := NewSmsDeduplicator(NewInMemorySmsReplayStore(), time.Minute, 10*time.Minute, time.Minute, 2*time.Minute)
+	if d.maxOfflineAge != 10*time.Minute {
+		t.Fatalf("maxOfflineAge = %v, want max age", d.maxOfflineAge)
+	}
+	if d.ttl != 12*time.Minute {
+		t.Fatalf("ttl = %v, want received_at window", d.ttl)
+	}
+}
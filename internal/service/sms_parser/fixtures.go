@@ -0,0 +1,97 @@
+package sms_parser
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"math"
+	"path"
+	"sort"
+)
+
+// Fixture уведомление из корпуса и ожидаемый результат разбора.
+// Expect == nil - уведомление не должно подходить ни под одно правило (коды подтверждения, реклама).
+type Fixture struct {
+	Name   string         `json:"name"`
+	Title  string         `json:"title"`
+	Text   string         `json:"text"`
+	Expect *FixtureExpect `json:"expect"`
+}
+
+// FixtureExpect ожидаемые поля, пустое поле не проверяется
+type FixtureExpect struct {
+	Bank       string   `json:"bank"`
+	Rule       string   `json:"rule,omitempty"`
+	Direction  string   `json:"direction"`
+	Amount     float64  `json:"amount"`
+	Currency   string   `json:"currency,omitempty"`
+	CardTail   string   `json:"card_tail,omitempty"`
+	SenderName string   `json:"sender,omitempty"`
+	Balance    *float64 `json:"balance,omitempty"`
+}
+
+func loadFixtures(fsys fs.FS) ([]Fixture, error) {
+	names, err := fs.Glob(fsys, "fixtures/*.json")
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(names)
+
+	var fixtures []Fixture
+	for _, name := range names {
+		raw, err := fs.ReadFile(fsys, name)
+		if err != nil {
+			return nil, err
+		}
+		var file []Fixture
+		if err := json.Unmarshal(raw, &file); err != nil {
+			return nil, fmt.Errorf("%s: %w", path.Base(name), err)
+		}
+		fixtures = append(fixtures, file...)
+	}
+	return fixtures, nil
+}
+
+// Check прогоняет корпус через правила и возвращает описания расхождений с ожиданиями
+func (p *Parser) Check(fixtures []Fixture) []string {
+	var failures []string
+	for _, fixture := range fixtures {
+		result, ok := p.Parse(fixture.Title, fixture.Text)
+		if fixture.Expect == nil {
+			if ok {
+				failures = append(failures, fmt.Sprintf("%s: expected no match, got %s/%s", fixture.Name, result.Bank, result.Rule))
+			}
+			continue
+		}
+		if !ok {
+			failures = append(failures, fmt.Sprintf("%s: no rule matched", fixture.Name))
+			continue
+		}
+		for _, problem := range fixture.Expect.diff(result) {
+			failures = append(failures, fmt.Sprintf("%s: %s", fixture.Name, problem))
+		}
+	}
+	return failures
+}
+
+func (e FixtureExpect) diff(result *Result) []string {
+	var problems []string
+	check := func(field, expected, actual string) {
+		if expected != "" && expected != actual {
+			problems = append(problems, fmt.Sprintf("%s: expected %q, got %q", field, expected, actual))
+		}
+	}
+	check("bank", e.Bank, result.Bank)
+	check("rule", e.Rule, result.Rule)
+	check("direction", e.Direction, result.Direction)
+	check("currency", e.Currency, result.Currency)
+	check("card_tail", e.CardTail, result.CardTail)
+	check("sender", e.SenderName, result.SenderName)
+	if math.Abs(e.Amount-result.Amount) > 0.001 {
+		problems = append(problems, fmt.Sprintf("amount: expected %.2f, got %.2f", e.Amount, result.Amount))
+	}
+	if e.Balance != nil && (!result.HasBalance || math.Abs(*e.Balance-result.Balance) > 0.001) {
+		problems = append(problems, fmt.Sprintf("balance: expected %.2f, got %.2f", *e.Balance, result.Balance))
+	}
+	return problems
+}
@@ -0,0 +1,299 @@
+package sms_parser
+
+import (
+	"embed"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Правила и корпус уведомлений, встроенные в бинарник, используются, если каталог правил не задан
+//
+//go:embed builtin
+var builtinFS embed.FS
+
+// Направления платежа, как их присылает устройство
+const (
+	DirectionIn  = "in"
+	DirectionOut = "out"
+)
+
+// macros подстановки в шаблонах правил, чтобы не повторять регулярные выражения сумм и валют в каждом файле
+var macros = map[string]string{
+	"{amount}":           `(?P<amount>` + numberPattern + `)`,
+	"{balance}":          `(?P<balance>` + numberPattern + `)`,
+	"{currency}":         `(?P<currency>` + currencyPattern + `)`,
+	"{balance_currency}": `(?:` + currencyPattern + `)`,
+	"{tail}":             `(?P<tail>\d{4})`,
+	"{sender}":           `(?P<sender>\p{Lu}[\p{L}-]*(?:\s\p{Lu}[\p{L}-]*)?\s\p{Lu}\.)`,
+}
+
+const (
+	numberPattern   = `\d{1,3}(?:[ \x{00a0}]?\d{3})*(?:[.,]\d{1,2})?`
+	currencyPattern = `₽|RUB|RUR|руб\.?|р\.?|USD|\$|EUR|€|KZT|₸|UZS`
+)
+
+var currencyCodes = map[string]string{
+	"₽": "RUB", "RUB": "RUB", "RUR": "RUB", "руб": "RUB", "руб.": "RUB", "р": "RUB", "р.": "RUB",
+	"USD": "USD", "$": "USD",
+	"EUR": "EUR", "€": "EUR",
+	"KZT": "KZT", "₸": "KZT",
+	"UZS": "UZS",
+}
+
+// ruleFile правила одного банка, хранятся в rules/<bank>.json
+type ruleFile struct {
+	Bank    string     `json:"bank"`
+	Senders []string   `json:"senders"` // отправители SMS и заголовки push-уведомлений банка
+	Rules   []ruleSpec `json:"rules"`
+}
+
+type ruleSpec struct {
+	Name          string `json:"name"`
+	Direction     string `json:"direction"`
+	PaymentSystem string `json:"payment_system,omitempty"` // если правило однозначно определяет платежную систему
+	Pattern       string `json:"pattern"`
+}
+
+type rule struct {
+	bank          string
+	name          string
+	direction     string
+	paymentSystem string
+	re            *regexp.Regexp
+}
+
+type bankRules struct {
+	bank    string
+	senders []string
+	rules   []rule
+}
+
+// Result поля, извлеченные из текста уведомления. Пустые поля правило не нашло.
+type Result struct {
+	Bank          string
+	Rule          string
+	Direction     string
+	PaymentSystem string
+	Amount        float64
+	Currency      string
+	CardTail      string
+	SenderName    string
+	Balance       float64
+	HasBalance    bool
+}
+
+// Parser разбирает SMS и push-уведомления банков по правилам
+type Parser struct {
+	banks []bankRules
+}
+
+// Load загружает правила из каталога (rules/*.json и fixtures/*.json), пустой путь - встроенные правила.
+// Правила проверяются на корпусе уведомлений при загрузке: набор, не прошедший корпус, не применяется.
+func Load(dir string) (*Parser, error) {
+	var fsys fs.FS
+	if dir == "" {
+		sub, err := fs.Sub(builtinFS, "builtin")
+		if err != nil {
+			return nil, err
+		}
+		fsys = sub
+	} else {
+		fsys = os.DirFS(dir)
+	}
+
+	parser, err := loadRules(fsys)
+	if err != nil {
+		return nil, err
+	}
+	fixtures, err := loadFixtures(fsys)
+	if err != nil {
+		return nil, err
+	}
+	if failures := parser.Check(fixtures); len(failures) > 0 {
+		return nil, fmt.Errorf("sms parser rules failed fixtures: %s", strings.Join(failures, "; "))
+	}
+	return parser, nil
+}
+
+func loadRules(fsys fs.FS) (*Parser, error) {
+	names, err := fs.Glob(fsys, "rules/*.json")
+	if err != nil {
+		return nil, err
+	}
+	if len(names) == 0 {
+		return nil, fmt.Errorf("no sms parser rules found")
+	}
+	sort.Strings(names)
+
+	parser := &Parser{}
+	for _, name := range names {
+		raw, err := fs.ReadFile(fsys, name)
+		if err != nil {
+			return nil, err
+		}
+		var file ruleFile
+		if err := json.Unmarshal(raw, &file); err != nil {
+			return nil, fmt.Errorf("%s: %w", path.Base(name), err)
+		}
+		bank, err := compileBank(file)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", path.Base(name), err)
+		}
+		parser.banks = append(parser.banks, bank)
+	}
+	return parser, nil
+}
+
+func compileBank(file ruleFile) (bankRules, error) {
+	if file.Bank == "" {
+		return bankRules{}, fmt.Errorf("bank is required")
+	}
+	bank := bankRules{bank: file.Bank}
+	for _, sender := range file.Senders {
+		bank.senders = append(bank.senders, strings.ToLower(sender))
+	}
+	for _, spec := range file.Rules {
+		if spec.Direction != DirectionIn && spec.Direction != DirectionOut {
+			return bankRules{}, fmt.Errorf("rule %s: direction must be in or out", spec.Name)
+		}
+		pattern := spec.Pattern
+		for macro, expansion := range macros {
+			pattern = strings.ReplaceAll(pattern, macro, expansion)
+		}
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return bankRules{}, fmt.Errorf("rule %s: %w", spec.Name, err)
+		}
+		if !hasGroup(re, "amount") {
+			return bankRules{}, fmt.Errorf("rule %s: pattern must capture {amount}", spec.Name)
+		}
+		bank.rules = append(bank.rules, rule{
+			bank:          file.Bank,
+			name:          spec.Name,
+			direction:     spec.Direction,
+			paymentSystem: spec.PaymentSystem,
+			re:            re,
+		})
+	}
+	return bank, nil
+}
+
+func hasGroup(re *regexp.Regexp, name string) bool {
+	return re.SubexpIndex(name) >= 0
+}
+
+// Parse разбирает уведомление. Если заголовок (отправитель SMS или заголовок push) относится
+// к известному банку, применяются только правила этого банка, иначе правила всех банков по порядку.
+func (p *Parser) Parse(title, text string) (*Result, bool) {
+	text = normalizeSpaces(text)
+	candidates := p.banksFor(title)
+	for _, bank := range candidates {
+		for _, r := range bank.rules {
+			if result, ok := r.apply(text); ok {
+				return result, true
+			}
+		}
+	}
+	return nil, false
+}
+
+func (p *Parser) banksFor(title string) []bankRules {
+	title = strings.ToLower(strings.TrimSpace(title))
+	if title == "" {
+		return p.banks
+	}
+	var matched []bankRules
+	for _, bank := range p.banks {
+		for _, sender := range bank.senders {
+			if strings.Contains(title, sender) {
+				matched = append(matched, bank)
+				break
+			}
+		}
+	}
+	if len(matched) == 0 {
+		return p.banks
+	}
+	return matched
+}
+
+func (r rule) apply(text string) (*Result, bool) {
+	match := r.re.FindStringSubmatch(text)
+	if match == nil {
+		return nil, false
+	}
+	group := func(name string) string {
+		if index := r.re.SubexpIndex(name); index >= 0 {
+			return strings.TrimSpace(match[index])
+		}
+		return ""
+	}
+
+	amount, err := parseNumber(group("amount"))
+	if err != nil {
+		return nil, false
+	}
+	result := &Result{
+		Bank:          r.bank,
+		Rule:          r.name,
+		Direction:     r.direction,
+		PaymentSystem: r.paymentSystem,
+		Amount:        amount,
+		Currency:      "RUB",
+		CardTail:      group("tail"),
+		SenderName:    group("sender"),
+	}
+	if currency := group("currency"); currency != "" {
+		if code, ok := currencyCodes[currency]; ok {
+			result.Currency = code
+		}
+	}
+	if balance := group("balance"); balance != "" {
+		if value, err := parseNumber(balance); err == nil {
+			result.Balance = value
+			result.HasBalance = true
+		}
+	}
+	return result, true
+}
+
+// parseNumber разбирает сумму в банковской записи: 1 500,50 / 1500.50 / 1 500
+func parseNumber(value string) (float64, error) {
+	value = strings.NewReplacer(" ", "", " ", "", ",", ".").Replace(value)
+	return strconv.ParseFloat(value, 64)
+}
+
+func normalizeSpaces(text string) string {
+	return strings.Join(strings.Fields(strings.ReplaceAll(text, " ", " ")), " ")
+}
+
+// Metadata поля результата для ProcessAutomaticPaymentRequest.Metadata
+func (r *Result) Metadata() map[string]string {
+	metadata := map[string]string{
+		"parsed_bank":      r.Bank,
+		"parsed_rule":      r.Rule,
+		"parsed_direction": r.Direction,
+		"parsed_amount":    strconv.FormatFloat(r.Amount, 'f', 2, 64),
+		"parsed_currency":  r.Currency,
+	}
+	if r.CardTail != "" {
+		metadata["parsed_card_tail"] = r.CardTail
+	}
+	if r.SenderName != "" {
+		metadata["parsed_sender"] = r.SenderName
+	}
+	if r.HasBalance {
+		metadata["parsed_balance"] = strconv.FormatFloat(r.Balance, 'f', 2, 64)
+	}
+	if r.PaymentSystem != "" {
+		metadata["parsed_payment_system"] = r.PaymentSystem
+	}
+	return metadata
+}
@@ -0,0 +1,231 @@
+package sms_parser
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const sberSBP = "СЧЁТ1234 10:15 Перевод по СБП 12 000р от Иван Иванович И. Баланс: 37 340.50р"
+
+func builtinRoot(t *testing.T) fs.FS {
+	t.Helper()
+	root, err := fs.Sub(builtinFS, "builtin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return root
+}
+
+func loadBuiltin(t *testing.T) *Parser {
+	t.Helper()
+	parser, err := Load("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return parser
+}
+
+func TestBuiltinRulesPassFixtures(t *testing.T) {
+	root := builtinRoot(t)
+	parser, err := loadRules(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fixtures, err := loadFixtures(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// у каждого банка с правилами должен быть корпус, иначе проверка при загрузке ничего не гарантирует
+	covered := map[string]bool{}
+	ignored := 0
+	for _, fixture := range fixtures {
+		if fixture.Expect == nil {
+			ignored++
+			continue
+		}
+		covered[fixture.Expect.Bank] = true
+	}
+	for _, bank := range parser.banks {
+		if !covered[bank.bank] {
+			t.Errorf("bank %s has no fixtures", bank.bank)
+		}
+	}
+	if ignored == 0 {
+		t.Error("corpus has no notifications that must be ignored")
+	}
+
+	if failures := parser.Check(fixtures); len(failures) > 0 {
+		t.Fatalf("builtin rules failed fixtures:\n%s", strings.Join(failures, "\n"))
+	}
+}
+
+func TestCheckReportsDifferences(t *testing.T) {
+	parser := loadBuiltin(t)
+	balance := 1.0
+
+	failures := parser.Check([]Fixture{
+		{
+			Name:  "wrong fields",
+			Title: "900",
+			Text:  sberSBP,
+			Expect: &FixtureExpect{
+				Bank:      "sberbank",
+				Rule:      "transfer_in",
+				Direction: "in",
+				Amount:    1200,
+				Balance:   &balance,
+			},
+		},
+		{
+			Name:   "unexpected match",
+			Title:  "900",
+			Text:   sberSBP,
+			Expect: nil,
+		},
+		{
+			Name:   "no match",
+			Title:  "900",
+			Text:   "Код: 123456",
+			Expect: &FixtureExpect{Bank: "sberbank", Direction: "in", Amount: 1},
+		},
+	})
+
+	want := []string{
+		`wrong fields: rule: expected "transfer_in", got "sbp_in"`,
+		"wrong fields: amount: expected 1200.00, got 12000.00",
+		"wrong fields: balance: expected 1.00, got 37340.50",
+		"unexpected match: expected no match, got sberbank/sbp_in",
+		"no match: no rule matched",
+	}
+	if !reflect.DeepEqual(failures, want) {
+		t.Fatalf("failures =\n%s\nwant\n%s", strings.Join(failures, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+func TestVerify(t *testing.T) {
+	parser := loadBuiltin(t)
+	reject := Policy{OnMismatch: ActionReject, OnUnmatched: ActionReject, AmountTolerance: 0.01}
+	flag := Policy{OnMismatch: ActionFlag, OnUnmatched: ActionFlag, AmountTolerance: 0.01}
+
+	tests := []struct {
+		name       string
+		text       string
+		reported   Reported
+		policy     Policy
+		wantStatus Status
+		wantFields []string
+		wantReject bool
+	}{
+		{
+			name:       "all fields match",
+			text:       sberSBP,
+			reported:   Reported{Amount: 12000, Direction: DirectionIn, PaymentSystem: "sbp", Balance: 37340.5},
+			policy:     reject,
+			wantStatus: StatusVerified,
+		},
+		{
+			name:       "amount within tolerance",
+			text:       sberSBP,
+			reported:   Reported{Amount: 12000.004},
+			policy:     reject,
+			wantStatus: StatusVerified,
+		},
+		{
+			name:       "amount differs",
+			text:       sberSBP,
+			reported:   Reported{Amount: 1200, Direction: DirectionIn},
+			policy:     reject,
+			wantStatus: StatusMismatch,
+			wantFields: []string{"amount"},
+			wantReject: true,
+		},
+		{
+			name:       "direction and balance differ",
+			text:       sberSBP,
+			reported:   Reported{Amount: 12000, Direction: DirectionOut, Balance: 100},
+			policy:     flag,
+			wantStatus: StatusMismatch,
+			wantFields: []string{"direction", "balance"},
+		},
+		{
+			name:       "payment system differs",
+			text:       sberSBP,
+			reported:   Reported{Amount: 12000, PaymentSystem: "C2C"},
+			policy:     reject,
+			wantStatus: StatusMismatch,
+			wantFields: []string{"payment_system"},
+			wantReject: true,
+		},
+		{
+			name:       "unmatched text is rejected",
+			text:       "Код: 123456. Никому не сообщайте его.",
+			reported:   Reported{Amount: 100},
+			policy:     reject,
+			wantStatus: StatusUnmatched,
+			wantReject: true,
+		},
+		{
+			name:       "unmatched text is flagged",
+			text:       "Код: 123456. Никому не сообщайте его.",
+			reported:   Reported{Amount: 100},
+			policy:     flag,
+			wantStatus: StatusUnmatched,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			verdict := parser.Verify("900", tt.text, tt.reported, tt.policy)
+			if verdict.Status != tt.wantStatus {
+				t.Fatalf("status = %s, want %s", verdict.Status, tt.wantStatus)
+			}
+			if verdict.Reject != tt.wantReject {
+				t.Errorf("reject = %v, want %v", verdict.Reject, tt.wantReject)
+			}
+			var fields []string
+			for _, mismatch := range verdict.Mismatches {
+				fields = append(fields, mismatch.Field)
+			}
+			if !reflect.DeepEqual(fields, tt.wantFields) {
+				t.Errorf("mismatched fields = %v, want %v", fields, tt.wantFields)
+			}
+
+			metadata := verdict.Metadata()
+			if metadata["parser_status"] != string(tt.wantStatus) {
+				t.Errorf("parser_status = %q", metadata["parser_status"])
+			}
+			if got := metadata["parser_mismatch"]; got != strings.Join(tt.wantFields, ",") {
+				t.Errorf("parser_mismatch = %q, want %q", got, strings.Join(tt.wantFields, ","))
+			}
+		})
+	}
+}
+
+func TestLoadRejectsRulesFailingFixtures(t *testing.T) {
+	dir := t.TempDir()
+	for _, sub := range []string{"rules", "fixtures"} {
+		if err := os.MkdirAll(filepath.Join(dir, sub), 0o755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	rules := `{"bank": "testbank", "senders": ["test"], "rules": [
+		{"name": "in", "direction": "in", "pattern": "Зачисление {amount} ?{currency}"}
+	]}`
+	fixtures := `[{"name": "test deposit", "title": "test", "text": "Поступление 100р",
+		"expect": {"bank": "testbank", "direction": "in", "amount": 100}}]`
+	if err := os.WriteFile(filepath.Join(dir, "rules", "testbank.json"), []byte(rules), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "fixtures", "testbank.json"), []byte(fixtures), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err := Load(dir)
+	if err == nil || !strings.Contains(err.Error(), "test deposit: no rule matched") {
+		t.Fatalf("Load() error = %v, want fixture failure", err)
+	}
+}
@@ -0,0 +1,142 @@
+package sms_parser
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Action реакция на уведомление, которое не удалось подтвердить разбором
+type Action string
+
+const (
+	ActionFlag   Action = "flag"   // обработать, пометив в Metadata
+	ActionReject Action = "reject" // не передавать в order-service
+)
+
+// Status итог сверки уведомления
+type Status string
+
+const (
+	StatusVerified  Status = "verified"
+	StatusMismatch  Status = "mismatch"
+	StatusUnmatched Status = "unmatched"
+)
+
+// Policy реакция на расхождения и на уведомления, которые не подошли ни под одно правило
+type Policy struct {
+	OnMismatch      Action
+	OnUnmatched     Action
+	AmountTolerance float64
+}
+
+// ParseAction разбирает действие из конфига, неизвестное значение - ошибка конфигурации
+func ParseAction(value string) (Action, error) {
+	switch Action(value) {
+	case ActionFlag, ActionReject:
+		return Action(value), nil
+	}
+	return "", fmt.Errorf("unknown sms parser action %q", value)
+}
+
+// Reported значения, которые разобрало само устройство
+type Reported struct {
+	Amount        float64
+	PaymentSystem string
+	Direction     string
+	Balance       float64
+}
+
+// Mismatch поле, в котором разбор устройства не совпал с разбором шлюза
+type Mismatch struct {
+	Field    string `json:"field"`
+	Reported string `json:"reported"`
+	Parsed   string `json:"parsed"`
+}
+
+// Verdict результат сверки: статус, разобранные поля и решение, передавать ли уведомление дальше
+type Verdict struct {
+	Status     Status
+	Result     *Result
+	Mismatches []Mismatch
+	Reject     bool
+}
+
+// Verify разбирает уведомление и сверяет его с данными устройства по политике
+func (p *Parser) Verify(title, text string, reported Reported, policy Policy) Verdict {
+	result, ok := p.Parse(title, text)
+	if !ok {
+		return Verdict{
+			Status: StatusUnmatched,
+			Reject: policy.OnUnmatched == ActionReject,
+		}
+	}
+
+	mismatches := result.Compare(reported, policy.AmountTolerance)
+	if len(mismatches) > 0 {
+		return Verdict{
+			Status:     StatusMismatch,
+			Result:     result,
+			Mismatches: mismatches,
+			Reject:     policy.OnMismatch == ActionReject,
+		}
+	}
+	return Verdict{Status: StatusVerified, Result: result}
+}
+
+// Compare сравнивает разобранные поля с данными устройства. Поля, которых нет в уведомлении
+// или которые устройство не прислало, не сравниваются.
+func (r *Result) Compare(reported Reported, tolerance float64) []Mismatch {
+	var mismatches []Mismatch
+	if math.Abs(r.Amount-reported.Amount) > tolerance {
+		mismatches = append(mismatches, Mismatch{
+			Field:    "amount",
+			Reported: formatAmount(reported.Amount),
+			Parsed:   formatAmount(r.Amount),
+		})
+	}
+	if reported.Direction != "" && reported.Direction != r.Direction {
+		mismatches = append(mismatches, Mismatch{
+			Field:    "direction",
+			Reported: reported.Direction,
+			Parsed:   r.Direction,
+		})
+	}
+	if r.HasBalance && reported.Balance > 0 && math.Abs(r.Balance-reported.Balance) > tolerance {
+		mismatches = append(mismatches, Mismatch{
+			Field:    "balance",
+			Reported: formatAmount(reported.Balance),
+			Parsed:   formatAmount(r.Balance),
+		})
+	}
+	if r.PaymentSystem != "" && reported.PaymentSystem != "" && !strings.EqualFold(r.PaymentSystem, reported.PaymentSystem) {
+		mismatches = append(mismatches, Mismatch{
+			Field:    "payment_system",
+			Reported: reported.PaymentSystem,
+			Parsed:   r.PaymentSystem,
+		})
+	}
+	return mismatches
+}
+
+// Metadata поля сверки для ProcessAutomaticPaymentRequest.Metadata
+func (v Verdict) Metadata() map[string]string {
+	metadata := map[string]string{}
+	if v.Result != nil {
+		metadata = v.Result.Metadata()
+	}
+	metadata["parser_status"] = string(v.Status)
+	if len(v.Mismatches) > 0 {
+		fields := make([]string, len(v.Mismatches))
+		for i, mismatch := range v.Mismatches {
+			fields[i] = mismatch.Field
+		}
+		metadata["parser_mismatch"] = strings.Join(fields, ",")
+	}
+	return metadata
+}
+
+func formatAmount(value float64) string {
+	return strconv.FormatFloat(value, 'f', 2, 64)
+}
@@ -0,0 +1,265 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/LavaJover/shvark-api-gateway/internal/client"
+	"github.com/LavaJover/shvark-api-gateway/internal/domain"
+	orderpb "github.com/LavaJover/shvark-order-service/proto/gen/order"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// ErrNotTeamLead у пользователя нет роли тимлида, статистики команды у него нет
+var ErrNotTeamLead = errors.New("user is not a team lead")
+
+type teamStatsEntry struct {
+	stats     *domain.TeamStats
+	expiresAt time.Time
+}
+
+// TeamStatsService собирает статистику команды тимлида: по каждому трейдеру из связей параллельно
+// (не больше concurrency трейдеров одновременно) запрашивает статистику ордеров, оборот реквизитов,
+// статус устройств и комиссионный доход, затем сводит их в сводку команды. Результат кешируется
+// на cacheTTL отдельно для каждого тимлида и периода.
+type TeamStatsService struct {
+	orderClient  *client.OrderClient
+	deviceClient *client.DeviceClient
+	walletClient *client.HTTPWalletClient
+	userClient   *client.UserClient
+	concurrency  int
+	cacheTTL     time.Duration
+
+	mu    sync.Mutex
+	cache map[string]teamStatsEntry
+	// inflight сборы, которые идут прямо сейчас: одинаковые запросы ждут их вместо повторного сбора
+	inflight map[string]chan struct{}
+}
+
+func NewTeamStatsService(
+	orderClient *client.OrderClient,
+	deviceClient *client.DeviceClient,
+	walletClient *client.HTTPWalletClient,
+	userClient *client.UserClient,
+	concurrency int,
+	cacheTTL time.Duration,
+) *TeamStatsService {
+	if concurrency < 1 {
+		concurrency = 1
+	}
+	return &TeamStatsService{
+		orderClient:  orderClient,
+		deviceClient: deviceClient,
+		walletClient: walletClient,
+		userClient:   userClient,
+		concurrency:  concurrency,
+		cacheTTL:     cacheTTL,
+		cache:        make(map[string]teamStatsEntry),
+		inflight:     make(map[string]chan struct{}),
+	}
+}
+
+// TeamStats возвращает статистику команды за период и признак того, что она взята из кеша.
+// Для пользователя без роли тимлида - ErrNotTeamLead.
+func (s *TeamStatsService) TeamStats(ctx context.Context, teamLeadID string, from, to time.Time) (*domain.TeamStats, bool, error) {
+	key := teamLeadID + "|" + strconv.FormatInt(from.Unix(), 10) + "|" + strconv.FormatInt(to.Unix(), 10)
+
+	var done chan struct{}
+	for done == nil {
+		s.mu.Lock()
+		if entry, ok := s.cache[key]; ok && time.Now().Before(entry.expiresAt) {
+			s.mu.Unlock()
+			return entry.stats, true, nil
+		}
+		if wait, ok := s.inflight[key]; ok {
+			s.mu.Unlock()
+			<-wait
+			continue
+		}
+		done = make(chan struct{})
+		s.inflight[key] = done
+		s.mu.Unlock()
+	}
+	defer func() {
+		s.mu.Lock()
+		delete(s.inflight, key)
+		s.mu.Unlock()
+		close(done)
+	}()
+
+	// результат уходит в кеш, поэтому отключение клиента не должно обрывать сбор на середине
+	stats, err := s.collect(context.WithoutCancel(ctx), teamLeadID, from, to)
+	if err != nil {
+		return nil, false, err
+	}
+
+	s.mu.Lock()
+	now := time.Now()
+	for cachedKey, entry := range s.cache {
+		if now.After(entry.expiresAt) {
+			delete(s.cache, cachedKey)
+		}
+	}
+	s.cache[key] = teamStatsEntry{stats: stats, expiresAt: now.Add(s.cacheTTL)}
+	s.mu.Unlock()
+	return stats, false, nil
+}
+
+func (s *TeamStatsService) collect(ctx context.Context, teamLeadID string, from, to time.Time) (*domain.TeamStats, error) {
+	// без проверки роли любой пользователь получал бы пустую команду вместо отказа
+	user, err := s.userClient.GetUserByID(teamLeadID)
+	if status.Code(err) == codes.NotFound {
+		return nil, ErrNotTeamLead
+	}
+	if err != nil {
+		return nil, upstreamError(UpstreamUser, err)
+	}
+	if user.Role != teamLeadRole {
+		return nil, ErrNotTeamLead
+	}
+
+	relations, err := s.orderClient.GetTeamRelationsByTeamLeadID(&orderpb.GetRelationsByTeamLeadIDRequest{
+		TeamLeadId: teamLeadID,
+	})
+	if err != nil {
+		return nil, upstreamError(UpstreamOrder, err)
+	}
+
+	var traders []domain.TeamTraderStats
+	seen := make(map[string]bool)
+	for _, relation := range relations.TeamRelations {
+		if seen[relation.TraderId] {
+			continue
+		}
+		seen[relation.TraderId] = true
+		traders = append(traders, domain.TeamTraderStats{
+			TraderID:   relation.TraderId,
+			Commission: relation.Commission,
+		})
+	}
+
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < s.concurrency; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for index := range jobs {
+				s.collectTrader(ctx, &traders[index], from, to)
+			}
+		}()
+	}
+	for index := range traders {
+		jobs <- index
+	}
+	close(jobs)
+	wg.Wait()
+
+	stats := &domain.TeamStats{
+		TeamLeadID:  teamLeadID,
+		From:        from,
+		To:          to,
+		GeneratedAt: time.Now(),
+		Traders:     traders,
+	}
+	stats.Summary.Traders = len(traders)
+	for _, trader := range traders {
+		failed := make(map[string]bool, len(trader.FailedSections))
+		for _, section := range trader.FailedSections {
+			failed[section] = true
+			stats.Partial = true
+		}
+		if !failed[domain.TeamStatsSectionOrders] {
+			stats.Summary.Orders.Add(trader.Orders)
+		}
+		if !failed[domain.TeamStatsSectionBankDetails] {
+			stats.Summary.BankDetails.Add(trader.BankDetails)
+		}
+		if !failed[domain.TeamStatsSectionDevices] {
+			stats.Summary.Devices.Add(trader.Devices)
+		}
+		if !failed[domain.TeamStatsSectionCommissionProfit] {
+			stats.Summary.CommissionProfit += trader.CommissionProfit
+		}
+	}
+	// самые крупные по обороту трейдеры первыми
+	sort.SliceStable(stats.Traders, func(i, j int) bool {
+		if stats.Traders[i].Orders.ProcessedAmountFiat != stats.Traders[j].Orders.ProcessedAmountFiat {
+			return stats.Traders[i].Orders.ProcessedAmountFiat > stats.Traders[j].Orders.ProcessedAmountFiat
+		}
+		return stats.Traders[i].TraderID < stats.Traders[j].TraderID
+	})
+	return stats, nil
+}
+
+// collectTrader заполняет разделы статистики трейдера. Ошибка одного раздела не мешает остальным.
+func (s *TeamStatsService) collectTrader(ctx context.Context, trader *domain.TeamTraderStats, from, to time.Time) {
+	fail := func(section string, err error) {
+		log.Printf("team stats: trader %s: %s: %v", trader.TraderID, section, err)
+		trader.FailedSections = append(trader.FailedSections, section)
+	}
+
+	if orders, err := s.orderClient.GetOrderStats(trader.TraderID, from, to); err != nil {
+		fail(domain.TeamStatsSectionOrders, err)
+	} else {
+		trader.Orders = domain.TeamOrderStats{
+			TotalOrders:           orders.TotalOrders,
+			SucceedOrders:         orders.SucceedOrders,
+			CanceledOrders:        orders.CanceledOrders,
+			ProcessedAmountFiat:   float64(orders.ProcessedAmountFiat),
+			ProcessedAmountCrypto: float64(orders.ProcessedAmountCrypto),
+			CanceledAmountFiat:    float64(orders.CanceledAmountFiat),
+			CanceledAmountCrypto:  float64(orders.CanceledAmountCrypto),
+			IncomeCrypto:          float64(orders.IncomeCrypto),
+		}
+	}
+
+	if bankDetails, err := s.orderClient.GetBankDetailsStatsByTraderID(&orderpb.GetBankDetailsStatsByTraderIDRequest{
+		TraderId: trader.TraderID,
+	}); err != nil {
+		fail(domain.TeamStatsSectionBankDetails, err)
+	} else {
+		trader.BankDetails.BankDetails = len(bankDetails.BankDetailStat)
+		for _, stat := range bankDetails.BankDetailStat {
+			trader.BankDetails.CountToday += int64(stat.CurrentCountToday)
+			trader.BankDetails.CountMonth += int64(stat.CurrentCountMonth)
+			trader.BankDetails.AmountToday += stat.CurrentAmountToday
+			trader.BankDetails.AmountMonth += stat.CurrentAmountMonth
+		}
+	}
+
+	devicesCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	devices, err := s.deviceClient.GetTraderDevicesStatus(devicesCtx, &orderpb.GetTraderDevicesStatusRequest{
+		TraderId: trader.TraderID,
+	})
+	cancel()
+	if err != nil {
+		fail(domain.TeamStatsSectionDevices, err)
+	} else {
+		for _, device := range devices.Devices {
+			trader.Devices.Total++
+			if device.Online {
+				trader.Devices.Online++
+			}
+			if device.Enabled {
+				trader.Devices.Enabled++
+				if !device.Online {
+					trader.Devices.Offline++
+				}
+			}
+		}
+	}
+
+	if profit, err := s.walletClient.GetCommissionProfit(trader.TraderID, from, to); err != nil {
+		fail(domain.TeamStatsSectionCommissionProfit, fmt.Errorf("wallet: %w", err))
+	} else {
+		trader.CommissionProfit = profit.TotalCommission
+	}
+}
@@ -0,0 +1,25 @@
+package service
+
+import (
+	"github.com/LavaJover/shvark-api-gateway/internal/domain"
+	"github.com/LavaJover/shvark-api-gateway/internal/service/notifications"
+)
+
+// NotifyingTraderEventSource отдает опубликованные события трейдеров еще и в рассылку уведомлений,
+// так продюсеры событий (споры, антифрод, трафик, автоматика, опрос устройств) становятся продюсерами уведомлений
+type NotifyingTraderEventSource struct {
+	TraderEventSource
+	notifier *notifications.Notifier
+}
+
+func NewNotifyingTraderEventSource(source TraderEventSource, notifier *notifications.Notifier) *NotifyingTraderEventSource {
+	return &NotifyingTraderEventSource{
+		TraderEventSource: source,
+		notifier:          notifier,
+	}
+}
+
+func (s *NotifyingTraderEventSource) Publish(event domain.TraderEvent) {
+	s.TraderEventSource.Publish(event)
+	s.notifier.Notify(event)
+}
@@ -0,0 +1,208 @@
+package service
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/LavaJover/shvark-api-gateway/internal/domain"
+	"github.com/LavaJover/shvark-api-gateway/internal/service/notifications"
+	orderpb "github.com/LavaJover/shvark-order-service/proto/gen/order"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+const traderPollOrdersLimit = 100
+
+// traderPolledEvents события, которые шлюз узнает только опросом order-service
+var traderPolledEvents = []domain.TraderEventType{
+	domain.TraderEventOrderAssigned,
+	domain.TraderEventOrderExpiring,
+	domain.TraderEventDeviceOffline,
+}
+
+// TraderOrdersReader ордера трейдера, которые опрашивает TraderEventPoller
+type TraderOrdersReader interface {
+	GetOrdersByTraderID(request *orderpb.GetOrdersByTraderIDRequest) (*orderpb.GetOrdersByTraderIDResponse, error)
+}
+
+// TraderDevicesReader статусы устройств трейдера, которые опрашивает TraderEventPoller
+type TraderDevicesReader interface {
+	GetTraderDevicesStatus(ctx context.Context, req *orderpb.GetTraderDevicesStatusRequest) (*orderpb.GetTraderDevicesStatusResponse, error)
+}
+
+// TraderEventPoller опрашивает order-service для трейдеров, подключенных к push-каналу или
+// подписанных на уведомления, и публикует события, которые шлюз не видит напрямую:
+// новые и истекающие ордера, отключение устройств.
+type TraderEventPoller struct {
+	source            TraderEventSource
+	notifier          *notifications.Notifier
+	orderClient       TraderOrdersReader
+	deviceClient      TraderDevicesReader
+	interval          time.Duration
+	expiringThreshold time.Duration
+
+	states map[string]*traderPollState
+}
+
+type traderPollState struct {
+	seenOrders     map[string]struct{}
+	expiringOrders map[string]struct{}
+	devicesOnline  map[string]bool
+}
+
+func NewTraderEventPoller(
+	source TraderEventSource,
+	notifier *notifications.Notifier,
+	orderClient TraderOrdersReader,
+	deviceClient TraderDevicesReader,
+	interval time.Duration,
+	expiringThreshold time.Duration,
+) *TraderEventPoller {
+	return &TraderEventPoller{
+		source:            source,
+		notifier:          notifier,
+		orderClient:       orderClient,
+		deviceClient:      deviceClient,
+		interval:          interval,
+		expiringThreshold: expiringThreshold,
+		states:            make(map[string]*traderPollState),
+	}
+}
+
+// Run опрашивает до отмены контекста
+func (p *TraderEventPoller) Run(ctx context.Context) {
+	ticker := time.NewTicker(p.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			p.poll(ctx)
+		}
+	}
+}
+
+func (p *TraderEventPoller) poll(ctx context.Context) {
+	watched := make(map[string]struct{})
+	for _, eventType := range traderPolledEvents {
+		for _, traderID := range p.notifier.Subscribers(eventType) {
+			watched[traderID] = struct{}{}
+		}
+	}
+	for _, traderID := range p.source.ActiveTraders() {
+		watched[traderID] = struct{}{}
+	}
+
+	for traderID := range watched {
+		state, exists := p.states[traderID]
+		if !exists {
+			state = &traderPollState{
+				seenOrders:     make(map[string]struct{}),
+				expiringOrders: make(map[string]struct{}),
+				devicesOnline:  make(map[string]bool),
+			}
+			p.states[traderID] = state
+		}
+		p.pollOrders(traderID, state, !exists)
+		p.pollDevices(ctx, traderID, state, !exists)
+	}
+
+	// трейдеры без подключений и подписок больше не опрашиваются
+	for traderID := range p.states {
+		if _, ok := watched[traderID]; !ok {
+			delete(p.states, traderID)
+		}
+	}
+}
+
+// pollOrders при первом опросе только запоминает текущие ордера, чтобы не слать по ним события повторно
+func (p *TraderEventPoller) pollOrders(traderID string, state *traderPollState, seed bool) {
+	response, err := p.orderClient.GetOrdersByTraderID(&orderpb.GetOrdersByTraderIDRequest{
+		TraderId:  traderID,
+		Page:      1,
+		Limit:     traderPollOrdersLimit,
+		SortBy:    "expires_at",
+		SortOrder: "asc",
+		Filters: &orderpb.OrderFilters{
+			Statuses: []string{string(domain.StatusCreated)},
+			DateFrom: timestamppb.New(time.Time{}),
+			DateTo:   timestamppb.New(time.Time{}),
+		},
+	})
+	if err != nil {
+		log.Printf("trader events: failed to get orders of trader %s: %v", traderID, err)
+		return
+	}
+
+	current := make(map[string]struct{}, len(response.Orders))
+	for _, order := range response.Orders {
+		current[order.OrderId] = struct{}{}
+
+		if _, seen := state.seenOrders[order.OrderId]; !seen {
+			state.seenOrders[order.OrderId] = struct{}{}
+			if !seed {
+				p.source.Publish(domain.TraderEvent{
+					Type:     domain.TraderEventOrderAssigned,
+					TraderID: traderID,
+					OrderID:  order.OrderId,
+				})
+			}
+		}
+
+		if order.ExpiresAt == nil {
+			continue
+		}
+		if _, notified := state.expiringOrders[order.OrderId]; notified {
+			continue
+		}
+		if remaining := time.Until(order.ExpiresAt.AsTime()); remaining > 0 && remaining <= p.expiringThreshold {
+			state.expiringOrders[order.OrderId] = struct{}{}
+			p.source.Publish(domain.TraderEvent{
+				Type:     domain.TraderEventOrderExpiring,
+				TraderID: traderID,
+				OrderID:  order.OrderId,
+				Data:     map[string]string{"expires_at": order.ExpiresAt.AsTime().UTC().Format(time.RFC3339)},
+			})
+		}
+	}
+
+	// забываем ордера, которые больше не ждут подтверждения
+	for orderID := range state.seenOrders {
+		if _, ok := current[orderID]; !ok {
+			delete(state.seenOrders, orderID)
+			delete(state.expiringOrders, orderID)
+		}
+	}
+}
+
+func (p *TraderEventPoller) pollDevices(ctx context.Context, traderID string, state *traderPollState, seed bool) {
+	reqCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	response, err := p.deviceClient.GetTraderDevicesStatus(reqCtx, &orderpb.GetTraderDevicesStatusRequest{
+		TraderId: traderID,
+	})
+	if err != nil {
+		log.Printf("trader events: failed to get devices of trader %s: %v", traderID, err)
+		return
+	}
+
+	for _, device := range response.Devices {
+		wasOnline, known := state.devicesOnline[device.DeviceId]
+		state.devicesOnline[device.DeviceId] = device.Online
+		if seed || !known || !wasOnline || device.Online || !device.Enabled {
+			continue
+		}
+		p.source.Publish(domain.TraderEvent{
+			Type:     domain.TraderEventDeviceOffline,
+			TraderID: traderID,
+			DeviceID: device.DeviceId,
+			Data: map[string]string{
+				"device_name": device.DeviceName,
+				"last_ping":   time.Unix(device.LastPing, 0).UTC().Format(time.RFC3339),
+			},
+		})
+	}
+}
@@ -0,0 +1,106 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/LavaJover/shvark-api-gateway/internal/domain"
+	"github.com/LavaJover/shvark-api-gateway/internal/service/notifications"
+	orderpb "github.com/LavaJover/shvark-order-service/proto/gen/order"
+	"google.golang.org/protobuf/encoding/protojson"
+)
+
+type fakeTraderOrders struct{}
+
+func (fakeTraderOrders) GetOrdersByTraderID(*orderpb.GetOrdersByTraderIDRequest) (*orderpb.GetOrdersByTraderIDResponse, error) {
+	return &orderpb.GetOrdersByTraderIDResponse{}, nil
+}
+
+// fakeTraderDevices одно устройство у каждого трейдера, онлайн задается тестом
+type fakeTraderDevices struct {
+	mu     sync.Mutex
+	online bool
+	polled map[string]int
+}
+
+func (f *fakeTraderDevices) GetTraderDevicesStatus(_ context.Context, req *orderpb.GetTraderDevicesStatusRequest) (*orderpb.GetTraderDevicesStatusResponse, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.polled[req.TraderId]++
+
+	response := &orderpb.GetTraderDevicesStatusResponse{}
+	raw := fmt.Sprintf(`{"devices":[{"deviceId":"device-1","deviceName":"Pixel","online":%t,"enabled":true,"lastPing":"1760000000"}]}`, f.online)
+	if err := protojson.Unmarshal([]byte(raw), response); err != nil {
+		return nil, err
+	}
+	return response, nil
+}
+
+func (f *fakeTraderDevices) setOnline(online bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.online = online
+}
+
+func waitSent(t *testing.T, channel *notifications.LocalChannel, n int) []notifications.Message {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for time.Now().Before(deadline) {
+		if sent := channel.Sent(); len(sent) >= n {
+			return sent
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("sent %d notifications, want %d", len(channel.Sent()), n)
+	return nil
+}
+
+// Трейдер без WebSocket-подключения получает device_offline, если подписан на уведомления
+func TestTraderEventPollerNotifiesOfflineSubscriber(t *testing.T) {
+	templates, err := notifications.LoadTemplates("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	preferences, err := notifications.NewFilePreferencesStore("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	channel := notifications.NewLocalChannel(notifications.NewWebhookChannel("", time.Second))
+	target := []domain.NotificationTarget{{Channel: channel.Name(), Address: "https://example.com/hook", Enabled: true}}
+	for _, item := range []domain.NotificationPreferences{
+		{UserID: "trader-1", Targets: target, EventTypes: []domain.TraderEventType{domain.TraderEventDeviceOffline}},
+		{UserID: "admin-1", Targets: target, AllTraders: true},
+	} {
+		if err := preferences.Save(item); err != nil {
+			t.Fatal(err)
+		}
+	}
+	notifier := notifications.NewNotifier([]notifications.Channel{channel}, templates, preferences,
+		notifications.NewInMemoryDeliveryHistory(10), notifications.Config{QueueSize: 10})
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go notifier.Run(ctx)
+
+	bus := NewInMemoryTraderEventBus(10)
+	devices := &fakeTraderDevices{online: true, polled: make(map[string]int)}
+	poller := NewTraderEventPoller(NewNotifyingTraderEventSource(bus, notifier), notifier,
+		fakeTraderOrders{}, devices, time.Minute, time.Minute)
+
+	poller.poll(ctx)
+	devices.setOnline(false)
+	poller.poll(ctx)
+
+	sent := waitSent(t, channel, 2)
+	for _, message := range sent {
+		if message.Event.Type != domain.TraderEventDeviceOffline || message.Event.TraderID != "trader-1" {
+			t.Fatalf("notification event = %+v, want device_offline of trader-1", message.Event)
+		}
+	}
+	// админ с подпиской на всех трейдеров сам не опрашивается
+	if devices.polled["admin-1"] != 0 || devices.polled["trader-1"] != 2 {
+		t.Fatalf("polled = %v, want only trader-1 twice", devices.polled)
+	}
+}
@@ -0,0 +1,238 @@
+package service
+
+import (
+	"log"
+	"sync"
+	"time"
+
+	"github.com/LavaJover/shvark-api-gateway/internal/client"
+	"github.com/LavaJover/shvark-api-gateway/internal/domain"
+)
+
+const traderEventsBuffer = 64
+
+// TraderEventSource шина событий трейдеров. Реализация по умолчанию хранит все в памяти процесса,
+// ее же удобно использовать в тестах.
+type TraderEventSource interface {
+	// Publish назначает событию ID и раздает его подписчикам трейдера
+	Publish(event domain.TraderEvent)
+	// Subscribe подписывает на события трейдера, начиная с события после lastEventID.
+	// Функция отписки закрывает канал.
+	Subscribe(traderID string, lastEventID uint64) (<-chan domain.TraderEvent, func())
+	// ActiveTraders возвращает трейдеров, у которых есть подписчики
+	ActiveTraders() []string
+}
+
+// InMemoryTraderEventBus хранит последние события каждого трейдера для возобновления по ID
+type InMemoryTraderEventBus struct {
+	historySize int
+
+	mu          sync.Mutex
+	nextID      uint64
+	history     map[string][]domain.TraderEvent
+	subscribers map[string]map[chan domain.TraderEvent]struct{}
+}
+
+func NewInMemoryTraderEventBus(historySize int) *InMemoryTraderEventBus {
+	return &InMemoryTraderEventBus{
+		historySize: historySize,
+		history:     make(map[string][]domain.TraderEvent),
+		subscribers: make(map[string]map[chan domain.TraderEvent]struct{}),
+	}
+}
+
+func (b *InMemoryTraderEventBus) Publish(event domain.TraderEvent) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.nextID++
+	event.ID = b.nextID
+	if event.At.IsZero() {
+		event.At = time.Now()
+	}
+
+	history := append(b.history[event.TraderID], event)
+	if len(history) > b.historySize {
+		history = history[len(history)-b.historySize:]
+	}
+	b.history[event.TraderID] = history
+
+	for ch := range b.subscribers[event.TraderID] {
+		select {
+		case ch <- event:
+		default:
+			log.Printf("trader events: subscriber of %s is too slow, event %d dropped", event.TraderID, event.ID)
+		}
+	}
+}
+
+func (b *InMemoryTraderEventBus) Subscribe(traderID string, lastEventID uint64) (<-chan domain.TraderEvent, func()) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	var missed []domain.TraderEvent
+	if lastEventID > 0 {
+		for _, event := range b.history[traderID] {
+			if event.ID > lastEventID {
+				missed = append(missed, event)
+			}
+		}
+	}
+
+	ch := make(chan domain.TraderEvent, traderEventsBuffer+len(missed))
+	for _, event := range missed {
+		ch <- event
+	}
+	if b.subscribers[traderID] == nil {
+		b.subscribers[traderID] = make(map[chan domain.TraderEvent]struct{})
+	}
+	b.subscribers[traderID][ch] = struct{}{}
+
+	unsubscribe := func() {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		if _, ok := b.subscribers[traderID][ch]; !ok {
+			return
+		}
+		delete(b.subscribers[traderID], ch)
+		close(ch)
+		if len(b.subscribers[traderID]) == 0 {
+			delete(b.subscribers, traderID)
+		}
+	}
+	return ch, unsubscribe
+}
+
+func (b *InMemoryTraderEventBus) ActiveTraders() []string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	traders := make([]string, 0, len(b.subscribers))
+	for traderID := range b.subscribers {
+		traders = append(traders, traderID)
+	}
+	return traders
+}
+
+// TraderEventPublisher публикует события, о которых шлюз узнает из собственных ручек
+// (споры, антифрод). Трейдер определяется по ордеру асинхронно, чтобы не задерживать ответ.
+type TraderEventPublisher struct {
+	source      TraderEventSource
+	orderClient *client.OrderClient
+}
+
+func NewTraderEventPublisher(source TraderEventSource, orderClient *client.OrderClient) *TraderEventPublisher {
+	return &TraderEventPublisher{
+		source:      source,
+		orderClient: orderClient,
+	}
+}
+
+// DisputeOpened сообщает трейдеру ордера об открытом споре
+func (p *TraderEventPublisher) DisputeOpened(orderID, disputeID string) {
+	go p.publishForOrder(domain.TraderEvent{
+		Type:      domain.TraderEventDisputeOpened,
+		OrderID:   orderID,
+		DisputeID: disputeID,
+	})
+}
+
+// DisputeFrozen сообщает трейдеру о заморозке спора
+func (p *TraderEventPublisher) DisputeFrozen(disputeID string) {
+	go func() {
+		dispute, err := p.orderClient.GetDisputeInfo(disputeID)
+		if err != nil {
+			log.Printf("trader events: failed to get dispute %s: %v", disputeID, err)
+			return
+		}
+		p.publishForOrder(domain.TraderEvent{
+			Type:      domain.TraderEventDisputeFrozen,
+			OrderID:   dispute.OrderID,
+			DisputeID: disputeID,
+		})
+	}()
+}
+
+// DisputeReminder напоминает трейдеру, что срок этапа спора скоро истекает
+func (p *TraderEventPublisher) DisputeReminder(orderID, disputeID string, stage domain.DisputeStage, deadline time.Time) {
+	go p.publishForOrder(domain.TraderEvent{
+		Type:      domain.TraderEventDisputeReminder,
+		OrderID:   orderID,
+		DisputeID: disputeID,
+		Data: map[string]string{
+			"stage":    string(stage),
+			"deadline": deadline.UTC().Format(time.RFC3339),
+		},
+	})
+}
+
+// DisputeEscalated сообщает, что срок этапа спора истек и спор передан админам.
+// Админы получают событие через подписку на уведомления всех трейдеров.
+func (p *TraderEventPublisher) DisputeEscalated(orderID, disputeID string, stage domain.DisputeStage, action string) {
+	go p.publishForOrder(domain.TraderEvent{
+		Type:      domain.TraderEventDisputeEscalated,
+		OrderID:   orderID,
+		DisputeID: disputeID,
+		Data: map[string]string{
+			"stage":  string(stage),
+			"action": action,
+		},
+	})
+}
+
+// AntifraudLocked сообщает трейдеру о блокировке трафика антифродом
+func (p *TraderEventPublisher) AntifraudLocked(traderID, reason string) {
+	event := domain.TraderEvent{
+		Type:     domain.TraderEventAntifraudLocked,
+		TraderID: traderID,
+	}
+	if reason != "" {
+		event.Data = map[string]string{"reason": reason}
+	}
+	p.source.Publish(event)
+}
+
+// TrafficLocked сообщает трейдеру, что админ вручную заблокировал его трафик
+func (p *TraderEventPublisher) TrafficLocked(traderID, reason string) {
+	event := domain.TraderEvent{
+		Type:     domain.TraderEventTrafficLocked,
+		TraderID: traderID,
+	}
+	if reason != "" {
+		event.Data = map[string]string{"reason": reason}
+	}
+	p.source.Publish(event)
+}
+
+// DeviceTrafficLocked сообщает трейдеру, что сторож заблокировал трафик из-за молчащего устройства
+func (p *TraderEventPublisher) DeviceTrafficLocked(traderID, deviceID string, mode domain.AutomaticLockMode) {
+	p.source.Publish(domain.TraderEvent{
+		Type:     domain.TraderEventDeviceLocked,
+		TraderID: traderID,
+		DeviceID: deviceID,
+		Data:     map[string]string{"mode": string(mode)},
+	})
+}
+
+// DeviceTrafficRestored сообщает трейдеру, что устройство снова на связи и трафик восстановлен
+func (p *TraderEventPublisher) DeviceTrafficRestored(traderID, deviceID string, mode domain.AutomaticLockMode) {
+	p.source.Publish(domain.TraderEvent{
+		Type:     domain.TraderEventDeviceRestored,
+		TraderID: traderID,
+		DeviceID: deviceID,
+		Data:     map[string]string{"mode": string(mode)},
+	})
+}
+
+func (p *TraderEventPublisher) publishForOrder(event domain.TraderEvent) {
+	response, err := p.orderClient.GetOrderByID(event.OrderID)
+	if err != nil {
+		log.Printf("trader events: failed to get order %s: %v", event.OrderID, err)
+		return
+	}
+	if response.Order.BankDetail == nil || response.Order.BankDetail.TraderId == "" {
+		return
+	}
+	event.TraderID = response.Order.BankDetail.TraderId
+	p.source.Publish(event)
+}
@@ -0,0 +1,178 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"github.com/LavaJover/shvark-api-gateway/internal/domain"
+)
+
+func publishOrder(bus TraderEventSource, traderID, orderID string) {
+	bus.Publish(domain.TraderEvent{
+		Type:     domain.TraderEventOrderAssigned,
+		TraderID: traderID,
+		OrderID:  orderID,
+	})
+}
+
+// receive забирает из канала ровно n событий и проверяет, что больше ничего не пришло
+func receive(t *testing.T, events <-chan domain.TraderEvent, n int) []domain.TraderEvent {
+	t.Helper()
+	received := make([]domain.TraderEvent, 0, n)
+	for len(received) < n {
+		select {
+		case event := <-events:
+			received = append(received, event)
+		case <-time.After(time.Second):
+			t.Fatalf("got %d events, want %d", len(received), n)
+		}
+	}
+	select {
+	case event := <-events:
+		t.Fatalf("unexpected event %d (%s)", event.ID, event.OrderID)
+	default:
+	}
+	return received
+}
+
+func orderIDs(events []domain.TraderEvent) []string {
+	ids := make([]string, len(events))
+	for i, event := range events {
+		ids[i] = event.OrderID
+	}
+	return ids
+}
+
+func assertOrderIDs(t *testing.T, events []domain.TraderEvent, want ...string) {
+	t.Helper()
+	got := orderIDs(events)
+	if len(got) != len(want) {
+		t.Fatalf("order IDs = %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("order IDs = %v, want %v", got, want)
+		}
+	}
+}
+
+func TestInMemoryTraderEventBusFanOut(t *testing.T) {
+	bus := NewInMemoryTraderEventBus(10)
+
+	first, unsubscribeFirst := bus.Subscribe("trader-1", 0)
+	defer unsubscribeFirst()
+	second, unsubscribeSecond := bus.Subscribe("trader-1", 0)
+	defer unsubscribeSecond()
+	other, unsubscribeOther := bus.Subscribe("trader-2", 0)
+	defer unsubscribeOther()
+
+	publishOrder(bus, "trader-1", "ord-1")
+	publishOrder(bus, "trader-2", "ord-2")
+	publishOrder(bus, "trader-1", "ord-3")
+
+	assertOrderIDs(t, receive(t, first, 2), "ord-1", "ord-3")
+	assertOrderIDs(t, receive(t, second, 2), "ord-1", "ord-3")
+	assertOrderIDs(t, receive(t, other, 1), "ord-2")
+}
+
+func TestInMemoryTraderEventBusAssignsIDs(t *testing.T) {
+	bus := NewInMemoryTraderEventBus(10)
+	events, unsubscribe := bus.Subscribe("trader-1", 0)
+	defer unsubscribe()
+
+	publishOrder(bus, "trader-1", "ord-1")
+	publishOrder(bus, "trader-2", "ord-2")
+	publishOrder(bus, "trader-1", "ord-3")
+
+	received := receive(t, events, 2)
+	// ID общий для всех трейдеров и только растет
+	if received[0].ID != 1 || received[1].ID != 3 {
+		t.Fatalf("IDs = %d, %d, want 1, 3", received[0].ID, received[1].ID)
+	}
+	if received[0].At.IsZero() {
+		t.Fatal("event time is not set")
+	}
+}
+
+func TestInMemoryTraderEventBusResume(t *testing.T) {
+	bus := NewInMemoryTraderEventBus(10)
+
+	publishOrder(bus, "trader-1", "ord-1")
+	publishOrder(bus, "trader-1", "ord-2")
+	publishOrder(bus, "trader-2", "ord-3")
+	publishOrder(bus, "trader-1", "ord-4")
+
+	tests := []struct {
+		name        string
+		lastEventID uint64
+		want        []string
+	}{
+		{name: "new subscriber gets no history", lastEventID: 0, want: nil},
+		{name: "resume after first event", lastEventID: 1, want: []string{"ord-2", "ord-4"}},
+		{name: "resume skips other traders", lastEventID: 2, want: []string{"ord-4"}},
+		{name: "nothing missed", lastEventID: 4, want: nil},
+		{name: "unknown future ID", lastEventID: 100, want: nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			events, unsubscribe := bus.Subscribe("trader-1", tt.lastEventID)
+			defer unsubscribe()
+			assertOrderIDs(t, receive(t, events, len(tt.want)), tt.want...)
+		})
+	}
+}
+
+func TestInMemoryTraderEventBusResumeThenLive(t *testing.T) {
+	bus := NewInMemoryTraderEventBus(10)
+	publishOrder(bus, "trader-1", "ord-1")
+	publishOrder(bus, "trader-1", "ord-2")
+
+	events, unsubscribe := bus.Subscribe("trader-1", 1)
+	defer unsubscribe()
+	publishOrder(bus, "trader-1", "ord-3")
+
+	assertOrderIDs(t, receive(t, events, 2), "ord-2", "ord-3")
+}
+
+func TestInMemoryTraderEventBusHistorySize(t *testing.T) {
+	bus := NewInMemoryTraderEventBus(3)
+	for _, orderID := range []string{"ord-1", "ord-2", "ord-3", "ord-4", "ord-5"} {
+		publishOrder(bus, "trader-1", orderID)
+	}
+	publishOrder(bus, "trader-2", "ord-6")
+
+	// из пяти событий трейдера хранятся три последних, старые при возобновлении теряются
+	events, unsubscribe := bus.Subscribe("trader-1", 1)
+	defer unsubscribe()
+	assertOrderIDs(t, receive(t, events, 3), "ord-3", "ord-4", "ord-5")
+
+	// история ограничивается для каждого трейдера отдельно
+	other, unsubscribeOther := bus.Subscribe("trader-2", 1)
+	defer unsubscribeOther()
+	assertOrderIDs(t, receive(t, other, 1), "ord-6")
+}
+
+func TestInMemoryTraderEventBusUnsubscribe(t *testing.T) {
+	bus := NewInMemoryTraderEventBus(10)
+	events, unsubscribe := bus.Subscribe("trader-1", 0)
+	kept, unsubscribeKept := bus.Subscribe("trader-1", 0)
+	defer unsubscribeKept()
+
+	unsubscribe()
+	// повторный вызов не должен паниковать на закрытом канале
+	unsubscribe()
+	if _, ok := <-events; ok {
+		t.Fatal("channel is not closed after unsubscribe")
+	}
+
+	publishOrder(bus, "trader-1", "ord-1")
+	assertOrderIDs(t, receive(t, kept, 1), "ord-1")
+
+	if active := bus.ActiveTraders(); len(active) != 1 || active[0] != "trader-1" {
+		t.Fatalf("active traders = %v, want [trader-1]", active)
+	}
+	unsubscribeKept()
+	if active := bus.ActiveTraders(); len(active) != 0 {
+		t.Fatalf("active traders = %v, want none", active)
+	}
+}
@@ -0,0 +1,92 @@
+// Package storage общие операции файловых хранилищ шлюза: атомарная перезапись
+// JSON-снимка и журналы в формате JSON Lines.
+package storage
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"log"
+	"os"
+	"path/filepath"
+)
+
+// maxJSONLine предел длины строки журнала при чтении
+const maxJSONLine = 4 * 1024 * 1024
+
+// WriteFile атомарно перезаписывает файл: пишет во временный файл рядом и переименовывает,
+// поэтому при падении на диске остается либо старое, либо новое содержимое
+func WriteFile(path string, data []byte, perm os.FileMode) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, perm); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// WriteJSON атомарно перезаписывает файл снимком value, доступ только у владельца
+func WriteJSON(path string, value any) error {
+	raw, err := json.MarshalIndent(value, "", "  ")
+	if err != nil {
+		return err
+	}
+	return WriteFile(path, raw, 0o600)
+}
+
+// ReadJSON читает снимок, записанный WriteJSON. Если файла нет, value не меняется.
+func ReadJSON(path string, value any) error {
+	raw, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(raw, value)
+}
+
+// AppendJSONLine дописывает value строкой в JSON Lines файл
+func AppendJSONLine(path string, value any) error {
+	line, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return err
+	}
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o600)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	_, err = file.Write(append(line, '\n'))
+	return err
+}
+
+// ReadJSONLines передает в fn записи JSON Lines файла по порядку. Отсутствующий файл - пустой журнал.
+// Строки, которые не разбираются, пропускаются: недописанная при падении строка не должна мешать старту.
+func ReadJSONLines[T any](path string, fn func(T)) error {
+	file, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxJSONLine)
+	for line := 1; scanner.Scan(); line++ {
+		var record T
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			log.Printf("%s: skipping broken line %d: %v", path, line, err)
+			continue
+		}
+		fn(record)
+	}
+	return scanner.Err()
+}
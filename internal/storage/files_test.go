@@ -0,0 +1,80 @@
+package storage
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+type record struct {
+	ID    string `json:"id"`
+	Count int    `json:"count"`
+}
+
+func TestWriteJSONReadJSON(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "nested", "records.json")
+	want := []record{{ID: "a", Count: 1}, {ID: "b", Count: 2}}
+
+	if err := WriteJSON(path, want); err != nil {
+		t.Fatal(err)
+	}
+	var got []record
+	if err := ReadJSON(path, &got); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("ReadJSON() = %+v, want %+v", got, want)
+	}
+	if _, err := os.Stat(path + ".tmp"); !os.IsNotExist(err) {
+		t.Fatalf("temporary file is left: %v", err)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if perm := info.Mode().Perm(); perm != 0o600 {
+		t.Fatalf("file mode = %o, want 600", perm)
+	}
+}
+
+func TestReadJSONMissingFile(t *testing.T) {
+	got := []record{{ID: "kept"}}
+	if err := ReadJSON(filepath.Join(t.TempDir(), "missing.json"), &got); err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 1 || got[0].ID != "kept" {
+		t.Fatalf("value changed for missing file: %+v", got)
+	}
+}
+
+func TestReadJSONLines(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "journal.jsonl")
+	for _, item := range []record{{ID: "a", Count: 1}, {ID: "b", Count: 2}} {
+		if err := AppendJSONLine(path, item); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// строка, недописанная при падении процесса
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0o600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	file.WriteString(`{"id":"c","cou`)
+	file.Close()
+
+	var got []record
+	if err := ReadJSONLines(path, func(item record) { got = append(got, item) }); err != nil {
+		t.Fatal(err)
+	}
+	want := []record{{ID: "a", Count: 1}, {ID: "b", Count: 2}}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("ReadJSONLines() = %+v, want %+v", got, want)
+	}
+
+	if err := ReadJSONLines(filepath.Join(t.TempDir(), "missing.jsonl"), func(record) {
+		t.Fatal("record read from missing file")
+	}); err != nil {
+		t.Fatal(err)
+	}
+}